@@ -0,0 +1,170 @@
+// In file: cmd/chat/client.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+)
+
+// gatewayClient sends generation requests to a gateway's /api/v1/generate endpoint.
+type gatewayClient struct {
+	baseURL    string
+	token      string
+	userID     string
+	tenantID   string
+	httpClient *http.Client
+}
+
+// streamHandler receives the events of a streamed answer as they arrive.
+type streamHandler struct {
+	onDelta    func(delta string)
+	onToolCall func(name, arguments string)
+	onRAG      func(rag api.RAGDecision)
+}
+
+// generate sends a request and decodes the gateway's JSON answer.
+func (g *gatewayClient) generate(ctx context.Context, req *api.GenerationRequest) (*api.GenerationResponse, error) {
+	resp, err := g.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result api.GenerationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode the gateway's response: %w", err)
+	}
+	return &result, nil
+}
+
+// stream sends a streaming request, passes its events to handler, and returns the summary of
+// the final "done" event. An "error" event ends the stream with its error.
+func (g *gatewayClient) stream(ctx context.Context, req *api.GenerationRequest, handler streamHandler) (*api.GenerationResponse, error) {
+	resp, err := g.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	var event string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "":
+			if event == "" && data.Len() == 0 {
+				continue
+			}
+			done, err := dispatchEvent(event, []byte(data.String()), handler)
+			if err != nil || done != nil {
+				return done, err
+			}
+			event = ""
+			data.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the stream: %w", err)
+	}
+	return nil, fmt.Errorf("the stream ended without a result")
+}
+
+// dispatchEvent handles one SSE event. It returns the summary of a "done" event, or the error
+// of an "error" event; other events return neither.
+func dispatchEvent(event string, data []byte, handler streamHandler) (*api.GenerationResponse, error) {
+	switch event {
+	case "content_delta":
+		var payload struct {
+			Delta string `json:"delta"`
+		}
+		if json.Unmarshal(data, &payload) == nil && handler.onDelta != nil {
+			handler.onDelta(payload.Delta)
+		}
+	case "tool_call_started":
+		var payload struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		}
+		if json.Unmarshal(data, &payload) == nil && handler.onToolCall != nil {
+			handler.onToolCall(payload.Name, payload.Arguments)
+		}
+	case "rag_context":
+		var rag api.RAGDecision
+		if json.Unmarshal(data, &rag) == nil && handler.onRAG != nil {
+			handler.onRAG(rag)
+		}
+	case "done":
+		var done api.GenerationResponse
+		if err := json.Unmarshal(data, &done); err != nil {
+			return nil, fmt.Errorf("failed to decode the stream's result: %w", err)
+		}
+		return &done, nil
+	case "error":
+		return nil, fmt.Errorf("%s", errorMessage(data))
+	}
+	return nil, nil
+}
+
+// post sends a request to the generate endpoint. Responses other than 200 are returned as
+// errors carrying the gateway's error message.
+func (g *gatewayClient) post(ctx context.Context, req *api.GenerationRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(g.baseURL, "/")+"/api/v1/generate", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if req.Config.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	if g.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+g.token)
+	}
+	if g.userID != "" {
+		httpReq.Header.Set("X-User-ID", g.userID)
+	}
+	if g.tenantID != "" {
+		httpReq.Header.Set("X-Tenant-ID", g.tenantID)
+	}
+
+	resp, err := g.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the gateway: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("the gateway answered %d: %s", resp.StatusCode, errorMessage(data))
+	}
+	return resp, nil
+}
+
+// errorMessage extracts the "error" field of a gateway error body, or returns the body as is.
+func errorMessage(data []byte) string {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &payload) == nil && payload.Error != "" {
+		return payload.Error
+	}
+	return strings.TrimSpace(string(data))
+}
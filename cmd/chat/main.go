@@ -0,0 +1,301 @@
+// In file: cmd/chat/main.go
+
+// Package main implements an interactive chat client for the LLM Gateway, for developers
+// exercising routing and tools against a local or remote gateway. It streams answers as they
+// are generated, keeps one conversation going across runs, and can show why each answer was
+// routed where it was.
+//
+// Usage:
+//
+//	chat [-url http://localhost:8080] [-model gpt-4o] [-preference cost] [-explain] [-new]
+//
+// Lines starting with "/" are commands; type /help for the list.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+)
+
+// session is the state of the REPL, which its commands change.
+type session struct {
+	client         *gatewayClient
+	conversationID string
+	// statePath is the file the conversation ID is saved to, so the next run continues it.
+	statePath  string
+	model      string
+	preference string
+	explain    bool
+	stream     bool
+}
+
+func main() {
+	log.SetFlags(0)
+	url := flag.String("url", envOr("GATEWAY_URL", "http://localhost:8080"), "Base URL of the gateway.")
+	token := flag.String("token", os.Getenv("GATEWAY_TOKEN"), "Bearer token sent with every request, when the gateway requires one.")
+	userID := flag.String("user", envOr("USER", "developer"), "User ID the conversation belongs to.")
+	tenantID := flag.String("tenant", "", "Tenant ID, when the gateway has tenant policies.")
+	model := flag.String("model", "", "Force every answer from this model instead of routing.")
+	preference := flag.String("preference", "", "Routing preference, e.g. cost, latency, or max_quality.")
+	explain := flag.Bool("explain", false, "Show how every answer was routed.")
+	noStream := flag.Bool("no-stream", false, "Wait for whole answers instead of streaming them.")
+	conversation := flag.String("conversation", "", "Continue this conversation instead of the saved one.")
+	newConversation := flag.Bool("new", false, "Start a new conversation instead of continuing the saved one.")
+	statePath := flag.String("state", defaultStatePath(), "File the conversation ID is saved to between runs.")
+	timeout := flag.Duration("timeout", 5*time.Minute, "Maximum time to wait for an answer.")
+	flag.Parse()
+
+	s := &session{
+		client: &gatewayClient{
+			baseURL:    *url,
+			token:      *token,
+			userID:     *userID,
+			tenantID:   *tenantID,
+			httpClient: &http.Client{Timeout: *timeout},
+		},
+		statePath:  *statePath,
+		model:      *model,
+		preference: *preference,
+		explain:    *explain,
+		stream:     !*noStream,
+	}
+	switch {
+	case *conversation != "":
+		s.conversationID = *conversation
+	case !*newConversation:
+		s.conversationID = s.loadConversationID()
+	}
+	if s.conversationID == "" {
+		s.conversationID = newConversationID()
+	}
+	s.saveConversationID()
+
+	fmt.Printf("Chatting with %s in conversation %s. Type /help for commands.\n", *url, s.conversationID)
+	s.run()
+}
+
+// run reads prompts and commands until the input ends or /quit.
+func (s *session) run() {
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			if !s.command(line) {
+				return
+			}
+			continue
+		}
+		s.ask(line)
+	}
+}
+
+// command runs a REPL command. It returns false when the REPL should end.
+func (s *session) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "/quit", "/exit":
+		return false
+	case "/help":
+		fmt.Println(`Commands:
+  /model <id>        force answers from a model; "/model auto" routes again
+  /preference <p>    set the routing preference; "/preference auto" lets the gateway pick
+  /explain on|off    show how every answer was routed
+  /stream on|off     stream answers as they are generated
+  /new               start a new conversation
+  /conversation [id] show the conversation ID, or switch to another conversation
+  /quit              leave`)
+	case "/model":
+		s.model = autoOr(arg)
+		fmt.Printf("Model: %s\n", orAuto(s.model))
+	case "/preference":
+		s.preference = autoOr(arg)
+		fmt.Printf("Preference: %s\n", orAuto(s.preference))
+	case "/explain":
+		s.explain = arg != "off"
+		fmt.Printf("Explain: %t\n", s.explain)
+	case "/stream":
+		s.stream = arg != "off"
+		fmt.Printf("Stream: %t\n", s.stream)
+	case "/new":
+		s.conversationID = newConversationID()
+		s.saveConversationID()
+		fmt.Printf("Conversation: %s\n", s.conversationID)
+	case "/conversation":
+		if arg != "" {
+			s.conversationID = arg
+			s.saveConversationID()
+		}
+		fmt.Printf("Conversation: %s\n", s.conversationID)
+	default:
+		fmt.Printf("Unknown command %s. Type /help for the list.\n", name)
+	}
+	return true
+}
+
+// ask sends a prompt and prints the answer, and its routing when explain is on. Ctrl-C
+// cancels the answer rather than the REPL.
+func (s *session) ask(prompt string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	req := &api.GenerationRequest{
+		Prompt:         prompt,
+		UserID:         s.client.userID,
+		ConversationID: s.conversationID,
+		Debug:          s.explain,
+		Config: api.GenerationConfig{
+			ForceModel: s.model,
+			Preference: s.preference,
+			Stream:     s.stream,
+		},
+	}
+
+	var resp *api.GenerationResponse
+	var err error
+	if s.stream {
+		resp, err = s.client.stream(ctx, req, streamHandler{
+			onDelta: func(delta string) { fmt.Print(delta) },
+			onToolCall: func(name, arguments string) {
+				fmt.Printf("[calling %s %s]\n", name, arguments)
+			},
+			onRAG: func(rag api.RAGDecision) {
+				if s.explain && rag.Used {
+					fmt.Printf("[knowledge base: %d chunks on %q, score %.2f]\n", rag.Chunks, rag.Topic, rag.Score)
+				}
+			},
+		})
+		fmt.Println()
+	} else {
+		resp, err = s.client.generate(ctx, req)
+		if err == nil {
+			fmt.Println(resp.Content)
+		}
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			fmt.Println("[cancelled]")
+			return
+		}
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	s.printSummary(resp)
+}
+
+// printSummary prints the model, latency, and cost of an answer, and with explain on, how it
+// was routed.
+func (s *session) printSummary(resp *api.GenerationResponse) {
+	fmt.Printf("[%s · %s · %d ms · $%.6f]\n", resp.ModelUsed, resp.CacheStatus, resp.LatencyMS, resp.CostUSD)
+	for _, warning := range resp.Warnings {
+		fmt.Printf("[warning: %s]\n", warning)
+	}
+	if resp.FailoverInfo != nil {
+		fmt.Printf("[failed over from %s: %s]\n", resp.FailoverInfo.OriginalModel, resp.FailoverInfo.Reason)
+	}
+	if !s.explain {
+		return
+	}
+	if r := resp.Routing; r != nil {
+		fmt.Printf("[intent %s", r.Intent)
+		if r.Preference != "" {
+			fmt.Printf(", preference %s (%s)", r.Preference, r.PreferenceSource)
+		}
+		if r.ComplexityScore != nil {
+			fmt.Printf(", complexity %d", *r.ComplexityScore)
+		}
+		fmt.Println("]")
+	}
+	if resp.Debug != nil {
+		trace, err := json.MarshalIndent(resp.Debug, "", "  ")
+		if err == nil {
+			fmt.Println(string(trace))
+		}
+	}
+}
+
+// loadConversationID returns the saved conversation ID, or "" if there is none.
+func (s *session) loadConversationID() string {
+	data, err := os.ReadFile(s.statePath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// saveConversationID saves the conversation ID for the next run. Failures are only reported,
+// as the chat works without it.
+func (s *session) saveConversationID() {
+	if s.statePath == "" {
+		return
+	}
+	err := os.MkdirAll(filepath.Dir(s.statePath), 0o700)
+	if err == nil {
+		err = os.WriteFile(s.statePath, []byte(s.conversationID+"\n"), 0o600)
+	}
+	if err != nil {
+		log.Printf("⚠️ Could not save the conversation ID: %v", err)
+	}
+}
+
+// defaultStatePath is the file in the user's config directory the conversation ID is saved to.
+func defaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "llm-gateway", "chat_conversation")
+}
+
+// newConversationID returns a random conversation ID.
+func newConversationID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "chat-" + hex.EncodeToString(b)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// autoOr returns arg, or "" for "auto", which leaves the choice to the gateway.
+func autoOr(arg string) string {
+	if arg == "auto" {
+		return ""
+	}
+	return arg
+}
+
+func orAuto(value string) string {
+	if value == "" {
+		return "auto"
+	}
+	return value
+}
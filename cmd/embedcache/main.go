@@ -0,0 +1,100 @@
+// In file: cmd/embedcache/main.go
+
+// Package main implements the embedding cache maintenance tool for the LLM Gateway.
+// It is an offline command-line tool with two jobs:
+//  1. "warm" pre-computes embeddings for a list of expected queries, so the first
+//     real users of a freshly deployed gateway don't all pay for embedding calls.
+//  2. "migrate" re-embeds every cached text with the current rag.embedding_model, so a
+//     model upgrade doesn't cause a mass cache-miss stampede against the API.
+//
+// Usage:
+//
+//	embedcache warm -file queries.txt
+//	embedcache migrate [-delete-old]
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dileep-u-k/llm-gateway/internal/config"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	config.LoadEnv()
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ragConfig, err := llm.LoadConfig()
+	if err != nil {
+		log.Fatalf("❌ Failed to load RAG Service config: %v", err)
+	}
+	ragService, err := llm.NewRAGService(ragConfig)
+	if err != nil {
+		log.Fatalf("❌ Failed to create RAG Service: %v", err)
+	}
+
+	ctx := context.Background()
+	var report llm.EmbeddingCacheReport
+	switch os.Args[1] {
+	case "warm":
+		fs := flag.NewFlagSet("warm", flag.ExitOnError)
+		file := fs.String("file", "", "Path to a file with one expected query per line.")
+		_ = fs.Parse(os.Args[2:])
+		if *file == "" {
+			log.Fatal("❌ warm requires -file")
+		}
+		queries, err := readLines(*file)
+		if err != nil {
+			log.Fatalf("❌ Failed to read queries: %v", err)
+		}
+		report, err = ragService.WarmEmbeddingCache(ctx, queries)
+		if err != nil {
+			log.Fatalf("❌ Warm-up failed: %v", err)
+		}
+	case "migrate":
+		fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+		deleteOld := fs.Bool("delete-old", false, "Delete cache entries created with previous embedding models.")
+		_ = fs.Parse(os.Args[2:])
+		report, err = ragService.MigrateEmbeddingCache(ctx, *deleteOld)
+		if err != nil {
+			log.Fatalf("❌ Migration failed: %v", err)
+		}
+	default:
+		usage()
+	}
+
+	log.Printf("✅ Done (model: %s). Scanned: %d | Cached: %d | Skipped: %d | Deleted: %d | Failures: %d",
+		ragConfig.EmbeddingModel, report.Scanned, report.Cached, report.Skipped, report.Deleted, report.Failures)
+	if report.Failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// readLines returns the lines of a file.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: embedcache warm -file <queries.txt> | embedcache migrate [-delete-old]")
+	os.Exit(2)
+}
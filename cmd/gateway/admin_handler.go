@@ -0,0 +1,338 @@
+// In file: cmd/gateway/admin_handler.go
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler serves the operator-facing /admin endpoints.
+// These endpoints expose fleet-wide state that is not meant for end users,
+// so every route is protected by requireAdminKey.
+type AdminHandler struct {
+	profiler   *llm.Profiler
+	router     *llm.Router
+	ragService *llm.RAGService
+	config     *AppConfig
+}
+
+func NewAdminHandler(profiler *llm.Profiler, router *llm.Router, ragService *llm.RAGService, config *AppConfig) *AdminHandler {
+	return &AdminHandler{
+		profiler:   profiler,
+		router:     router,
+		ragService: ragService,
+		config:     config,
+	}
+}
+
+// requireAdminKey only lets requests carrying "Authorization: Bearer <ADMIN_API_KEY>" through.
+// If no admin key is configured, the admin API is disabled entirely rather than left open.
+func requireAdminKey(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Admin API is disabled: ADMIN_API_KEY is not set."})
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin credentials."})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HandleDeprecations lists every deprecation notice providers have sent for enabled models.
+func (h *AdminHandler) HandleDeprecations(c *gin.Context) {
+	notices, err := h.profiler.GetDeprecations(c.Request.Context(), h.config.EnabledModels)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if notices == nil {
+		notices = []llm.DeprecationNotice{}
+	}
+	c.JSON(http.StatusOK, gin.H{"deprecations": notices})
+}
+
+// HandleCosts reports the month's spend grouped by model (the default), day, user, or conversation.
+// GET /admin/costs[/:dimension]?month=YYYY-MM&format=json|csv
+func (h *AdminHandler) HandleCosts(c *gin.Context) {
+	dimension := c.Param("dimension")
+	if dimension == "" {
+		dimension = llm.CostByModel
+	}
+	month := c.DefaultQuery("month", time.Now().UTC().Format("2006-01"))
+	if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid month '%s'. Use YYYY-MM.", month)})
+		return
+	}
+	switch dimension {
+	case llm.CostByModel, llm.CostByDay, llm.CostByUser, llm.CostByConversation:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown cost dimension '%s'. Use 'model', 'day', 'user', or 'conversation'.", dimension)})
+		return
+	}
+
+	report, err := h.profiler.CostReport(c.Request.Context(), dimension, month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		c.JSON(http.StatusOK, report)
+	case "csv":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="costs-%s-%s.csv"`, dimension, month))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", renderCostReportCSV(report))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported format '%s'. Use 'json' or 'csv'.", format)})
+	}
+}
+
+// HandleSpend reports the month's spend of one day, user, or conversation, so an expensive
+// user or thread can be looked up without the whole report.
+// GET /admin/costs/:dimension/:key?month=YYYY-MM
+func (h *AdminHandler) HandleSpend(c *gin.Context) {
+	dimension, key := c.Param("dimension"), c.Param("key")
+	month := c.DefaultQuery("month", time.Now().UTC().Format("2006-01"))
+	if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid month '%s'. Use YYYY-MM.", month)})
+		return
+	}
+	switch dimension {
+	case llm.CostByDay, llm.CostByUser, llm.CostByConversation:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown cost dimension '%s'. Use 'day', 'user', or 'conversation'.", dimension)})
+		return
+	}
+
+	spend, err := h.profiler.Spend(c.Request.Context(), dimension, key, month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response := gin.H{"dimension": dimension, "month": month, "key": key, "cost_usd": spend.CostUSD, "requests": spend.Requests}
+	if dimension == llm.CostByUser {
+		if budget := h.config.UserBudgets.BudgetFor(key); budget > 0 {
+			response["budget_usd"] = budget
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// renderCostReportCSV renders a cost report with one row per entry.
+func renderCostReportCSV(report *llm.CostReport) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{report.Dimension, "month", "cost_usd", "requests"})
+	for _, e := range report.Entries {
+		w.Write([]string{e.Key, report.Month, strconv.FormatFloat(e.CostUSD, 'f', 6, 64), strconv.FormatInt(e.Requests, 10)})
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// HandleUsage reports per-account usage for a month: every account, or only the one named
+// by the account query parameter.
+// GET /admin/usage?month=YYYY-MM&account=user:alice
+func (h *AdminHandler) HandleUsage(c *gin.Context) {
+	month := c.DefaultQuery("month", time.Now().UTC().Format("2006-01"))
+	if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid month '%s'. Use YYYY-MM.", month)})
+		return
+	}
+
+	if account := c.Query("account"); account != "" {
+		usage, err := h.profiler.GetAccountUsage(c.Request.Context(), account, month)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, usage)
+		return
+	}
+
+	usages, err := h.profiler.ListAccountUsage(c.Request.Context(), month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"month": month, "accounts": usages})
+}
+
+// HandleModels lists the configured models and whether each is currently in rotation.
+// GET /admin/models
+func (h *AdminHandler) HandleModels(c *gin.Context) {
+	disabled, err := h.profiler.DisabledModels(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	models := make([]gin.H, 0, len(h.config.EnabledModels))
+	for _, modelID := range h.config.EnabledModels {
+		models = append(models, gin.H{"model_id": modelID, "enabled": !disabled[modelID]})
+	}
+	c.JSON(http.StatusOK, gin.H{"models": models})
+}
+
+// HandleModelHistory returns the profile snapshots of a model over a recent window, 24 hours
+// unless the since query parameter names another duration.
+// GET /admin/models/:id/history?since=6h
+func (h *AdminHandler) HandleModelHistory(c *gin.Context) {
+	modelID := c.Param("id")
+	if !slices.Contains(h.config.EnabledModels, modelID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Unknown model '%s'.", modelID)})
+		return
+	}
+	window, err := time.ParseDuration(c.DefaultQuery("since", "24h"))
+	if err != nil || window <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid since '%s'. Use a duration such as 6h.", c.Query("since"))})
+		return
+	}
+	snapshots, err := h.profiler.ProfileHistory(c.Request.Context(), modelID, time.Now().Add(-window))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"model_id": modelID, "since": window.String(), "snapshots": snapshots})
+}
+
+// dashboardDecisions is how many recent routing decisions the dashboard returns by default.
+const dashboardDecisions = 20
+
+// HandleDashboard returns everything an ops dashboard shows in one document: the profiles of
+// the enabled models, their monthly spend against their budgets, today's cache hit rates,
+// and the most recent routing decisions (20 unless the decisions query parameter asks for
+// up to 100).
+// GET /admin/dashboard?decisions=50
+func (h *AdminHandler) HandleDashboard(c *gin.Context) {
+	ctx := c.Request.Context()
+	limit := dashboardDecisions
+	if raw := c.Query("decisions"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid decisions '%s'. Use a number from 0 to 100.", raw)})
+			return
+		}
+		limit = n
+	}
+
+	disabled, err := h.profiler.DisabledModels(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	models := make([]gin.H, 0, len(h.config.EnabledModels))
+	for _, modelID := range h.config.EnabledModels {
+		profile, err := h.profiler.GetProfile(ctx, modelID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		models = append(models, gin.H{"enabled": !disabled[modelID], "provider": h.router.ProviderOf(modelID), "profile": profile})
+	}
+
+	budgets := h.router.BudgetStates(ctx, h.config.EnabledModels, h.config.ModelBudgets)
+	var spent float64
+	for _, b := range budgets {
+		spent += b.SpentUSD
+	}
+
+	now := time.Now().UTC()
+	caches, err := h.ragService.CacheStats(ctx, now.Format(time.DateOnly))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	decisions, err := h.profiler.RecentRoutingDecisions(ctx, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"generated_at":     now,
+		"models":           models,
+		"spend":            gin.H{"month": now.Format("2006-01"), "total_usd": spent, "budgets": budgets},
+		"caches":           caches,
+		"recent_decisions": decisions,
+	})
+}
+
+// HandleExportProfiles exports the profiles of the enabled models, to seed another environment.
+// GET /admin/profiles/export
+func (h *AdminHandler) HandleExportProfiles(c *gin.Context) {
+	export, err := h.profiler.ExportProfiles(c.Request.Context(), h.config.EnabledModels)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+// HandleImportProfiles seeds model profiles from an export. Models that already have a
+// profile keep it unless overwrite=true.
+// POST /admin/profiles/import?overwrite=true
+func (h *AdminHandler) HandleImportProfiles(c *gin.Context) {
+	var export llm.ProfileExport
+	if err := c.ShouldBindJSON(&export); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile export: " + err.Error()})
+		return
+	}
+	overwrite := c.Query("overwrite") == "true"
+	imported, err := h.profiler.ImportProfiles(c.Request.Context(), export.Profiles, overwrite)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "imported": imported})
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "Imported model profiles", "imported", imported, "overwrite", overwrite)
+	c.JSON(http.StatusOK, gin.H{"imported": imported, "skipped": len(export.Profiles) - len(imported)})
+}
+
+// HandleEnableModel puts a model back into rotation.
+// POST /admin/models/:id/enable
+func (h *AdminHandler) HandleEnableModel(c *gin.Context) {
+	h.switchModel(c, true)
+}
+
+// HandleDisableModel takes a model out of rotation on every replica, without a redeploy.
+// POST /admin/models/:id/disable
+func (h *AdminHandler) HandleDisableModel(c *gin.Context) {
+	h.switchModel(c, false)
+}
+
+// switchModel enables or disables one of the models enabled in config.yaml. Other models have
+// no client, so they cannot be switched on at runtime.
+func (h *AdminHandler) switchModel(c *gin.Context, enable bool) {
+	modelID := c.Param("id")
+	if !slices.Contains(h.config.EnabledModels, modelID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Unknown model '%s'. Only models enabled in config.yaml can be switched.", modelID)})
+		return
+	}
+	var err error
+	if enable {
+		err = h.profiler.EnableModel(c.Request.Context(), modelID)
+	} else {
+		err = h.profiler.DisableModel(c.Request.Context(), modelID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "Model switched by an operator", "model", modelID, "enabled", enable)
+	c.JSON(http.StatusOK, gin.H{"model_id": modelID, "enabled": enable})
+}
@@ -0,0 +1,52 @@
+// In file: cmd/gateway/aliases.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/gin-gonic/gin"
+)
+
+// resolveModelAlias replaces an aliased or provider-prefixed force_model ("openai/gpt-4o")
+// with the model that serves it, and records alias resolutions in the trace. It runs before
+// the cache key is built, so every name of a model shares cached answers. Excluding an alias
+// excludes the model that serves it.
+func (h *GatewayHandler) resolveModelAlias(c *gin.Context, req *api.GenerationRequest, trace *api.DecisionTrace) {
+	for i, excluded := range req.Config.ExcludeModels {
+		req.Config.ExcludeModels[i], _ = h.config.RouterConfig.ResolveModel(excluded)
+	}
+	requested := req.Config.ForceModel
+	if requested == "" {
+		return
+	}
+	modelID, alias := h.config.RouterConfig.ResolveModel(requested)
+	if modelID == requested {
+		return
+	}
+	req.Config.ForceModel = modelID
+	if alias == nil {
+		slog.DebugContext(c.Request.Context(), "Stripped the provider prefix of the requested model", "requested", requested, "model", modelID)
+		return
+	}
+	trace.Alias = &api.AliasDecision{Requested: requested, Model: modelID, Deprecated: alias.Deprecated, Message: alias.Message}
+	if alias.Deprecated {
+		slog.WarnContext(c.Request.Context(), "Deprecated model alias requested", "alias", requested, "model", modelID)
+	} else {
+		slog.DebugContext(c.Request.Context(), "Resolved model alias", "alias", requested, "model", modelID)
+	}
+}
+
+// modelWarnings returns the warnings to send back for the model the client asked for.
+// They are derived from the trace rather than cached, so a cache hit warns like a live answer.
+func modelWarnings(trace *api.DecisionTrace) []string {
+	if trace.Alias == nil || !trace.Alias.Deprecated {
+		return nil
+	}
+	warning := fmt.Sprintf("model '%s' is deprecated and is served by '%s'; request '%s' instead", trace.Alias.Requested, trace.Alias.Model, trace.Alias.Model)
+	if trace.Alias.Message != "" {
+		warning += ". " + trace.Alias.Message
+	}
+	return []string{warning}
+}
@@ -0,0 +1,488 @@
+// In file: cmd/gateway/anthropic_compat.go
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =================================================================================
+// Anthropic Messages API compatibility
+// =================================================================================
+// POST /anthropic/v1/messages speaks Anthropic's Messages API, so tooling built on the
+// Anthropic SDK can use the gateway by pointing its base URL at /anthropic. A request is
+// translated into a generation request and served by HandleGeneration, so it is routed,
+// cached, moderated, and accounted for like any other; the answer, its stream, and errors are
+// translated back into Anthropic's formats.
+//
+// The model may be any enabled model, alias, or provider-prefixed name, which forces it;
+// "auto", which routes the request; or the name of a routing strategy, which routes it by that
+// preference. Only text content is supported: image, tool use, and tool result blocks, and
+// the tools parameter, are refused. The SDK's x-api-key header is accepted as a bearer token.
+
+// anthropicAutoModel is the model name that leaves the choice of model to the router.
+const anthropicAutoModel = "auto"
+
+// anthropicMessagesRequest is the subset of Anthropic's Messages API request the gateway serves.
+type anthropicMessagesRequest struct {
+	Model         string             `json:"model" binding:"required"`
+	Messages      []anthropicMessage `json:"messages" binding:"required"`
+	System        json.RawMessage    `json:"system,omitempty"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float32           `json:"temperature,omitempty"`
+	TopP          *float32           `json:"top_p,omitempty"`
+	TopK          *int               `json:"top_k,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Metadata      *struct {
+		UserID string `json:"user_id,omitempty"`
+	} `json:"metadata,omitempty"`
+	Thinking *struct {
+		Type         string `json:"type"`
+		BudgetTokens int    `json:"budget_tokens"`
+	} `json:"thinking,omitempty"`
+	Tools json.RawMessage `json:"tools,omitempty"`
+}
+
+// anthropicMessage is one message of a request. Its content is a string or a list of blocks.
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// anthropicContentBlock is a content block of a request or an answer.
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// anthropicUsage is the token usage of an answer.
+type anthropicUsage struct {
+	InputTokens          int `json:"input_tokens"`
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// anthropicMessagesResponse is an answer in Anthropic's format.
+type anthropicMessagesResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []anthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        anthropicUsage          `json:"usage"`
+}
+
+// anthropicErrorResponse is an error in Anthropic's format.
+type anthropicErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicResponses answers the requests of the Anthropic-compatible routes in Anthropic's
+// formats. It runs before authentication and the limits, so their refusals are translated too.
+func anthropicResponses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if key := c.GetHeader("x-api-key"); key != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+key)
+			}
+		}
+		w := &anthropicWriter{ResponseWriter: c.Writer, id: newAnthropicMessageID()}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.finish()
+	}
+}
+
+// HandleAnthropicMessages serves a Messages API request through HandleGeneration.
+// POST /anthropic/v1/messages
+func (h *GatewayHandler) HandleAnthropicMessages(c *gin.Context) {
+	if h.config.Limits.MaxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.config.Limits.MaxBodyBytes)
+	}
+	var msgReq anthropicMessagesRequest
+	if err := c.ShouldBindJSON(&msgReq); err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	req, status, err := h.toGenerationRequest(&msgReq)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if w, ok := c.Writer.(*anthropicWriter); ok {
+		w.model = msgReq.Model
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	h.HandleGeneration(c)
+}
+
+// toGenerationRequest translates a Messages API request. The last message is the prompt and
+// must be the user's; the earlier ones are the history.
+func (h *GatewayHandler) toGenerationRequest(msgReq *anthropicMessagesRequest) (*api.GenerationRequest, int, error) {
+	if len(msgReq.Tools) > 0 && string(msgReq.Tools) != "null" && string(msgReq.Tools) != "[]" {
+		return nil, http.StatusBadRequest, fmt.Errorf("tools are not supported by the Anthropic-compatible endpoint")
+	}
+	if len(msgReq.Messages) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("messages must not be empty")
+	}
+	req := &api.GenerationRequest{
+		Config: api.GenerationConfig{
+			MaxTokens:   msgReq.MaxTokens,
+			Temperature: msgReq.Temperature,
+			TopP:        msgReq.TopP,
+			TopK:        msgReq.TopK,
+			Stop:        msgReq.StopSequences,
+			Stream:      msgReq.Stream,
+		},
+	}
+	if msgReq.Metadata != nil {
+		req.UserID = msgReq.Metadata.UserID
+	}
+	if msgReq.Thinking != nil && msgReq.Thinking.Type == "enabled" {
+		req.Config.ThinkingBudget = msgReq.Thinking.BudgetTokens
+	}
+
+	system, err := anthropicText(msgReq.System)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("system: %w", err)
+	}
+	req.SystemPrompt = system
+
+	for i, msg := range msgReq.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			return nil, http.StatusBadRequest, fmt.Errorf("messages.%d: role must be 'user' or 'assistant'", i)
+		}
+		text, err := anthropicText(msg.Content)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("messages.%d: %w", i, err)
+		}
+		if i == len(msgReq.Messages)-1 {
+			if msg.Role != "user" {
+				return nil, http.StatusBadRequest, fmt.Errorf("the last message must be the user's")
+			}
+			req.Prompt = text
+			break
+		}
+		req.History = append(req.History, api.Message{Role: msg.Role, Content: text})
+	}
+
+	modelID, _ := h.config.RouterConfig.ResolveModel(msgReq.Model)
+	_, isStrategy := h.config.RouterConfig.Strategies[msgReq.Model]
+	switch {
+	case msgReq.Model == anthropicAutoModel:
+	case slices.Contains(h.config.EnabledModels, modelID):
+		req.Config.ForceModel = msgReq.Model
+	case isStrategy:
+		req.Config.Preference = msgReq.Model
+	default:
+		return nil, http.StatusNotFound, fmt.Errorf("model: %s", msgReq.Model)
+	}
+	return req, 0, nil
+}
+
+// anthropicText returns the text of content that is a string or a list of text blocks.
+func anthropicText(content json.RawMessage) (string, error) {
+	if len(content) == 0 || string(content) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text, nil
+	}
+	var blocks []anthropicContentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return "", fmt.Errorf("content must be a string or a list of content blocks")
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type != "text" {
+			return "", fmt.Errorf("content blocks of type '%s' are not supported; only text is", block.Type)
+		}
+		parts = append(parts, block.Text)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+func newAnthropicMessageID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "msg_" + hex.EncodeToString(b)
+}
+
+// anthropicErrorType maps a status to the error type Anthropic answers it with.
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusPaymentRequired, http.StatusUnprocessableEntity:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable:
+		return "overloaded_error"
+	}
+	return "api_error"
+}
+
+// anthropicError builds an error response from the body of a gateway error.
+func anthropicError(status int, body []byte) anthropicErrorResponse {
+	var gatewayErr struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &gatewayErr) == nil && gatewayErr.Error != "" {
+		message = gatewayErr.Error
+	}
+	resp := anthropicErrorResponse{Type: "error"}
+	resp.Error.Type, resp.Error.Message = anthropicErrorType(status), message
+	return resp
+}
+
+// anthropicWriter translates what the gateway writes into Anthropic's formats. JSON
+// responses are buffered and translated once the handler returns; SSE streams are
+// translated event by event as they are written.
+type anthropicWriter struct {
+	gin.ResponseWriter
+	id string
+	// model is the model the client asked for, which the stream starts with before the
+	// gateway has reported the model that answered.
+	model  string
+	status int
+	body   bytes.Buffer
+	// pending holds the bytes of the stream's incomplete event.
+	pending   bytes.Buffer
+	streaming bool
+	started   bool
+	// err is the error of the first event that could not be sent to the client. Nothing
+	// more is written once it is set.
+	err error
+}
+
+func (w *anthropicWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *anthropicWriter) WriteHeader(code int) {
+	if code > 0 && !w.streaming {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow is deferred until the response is translated.
+func (w *anthropicWriter) WriteHeaderNow() {}
+
+func (w *anthropicWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *anthropicWriter) Written() bool {
+	return w.status != 0 || w.body.Len() > 0 || w.streaming
+}
+
+func (w *anthropicWriter) Write(data []byte) (int, error) {
+	if !w.streaming && strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.streaming = true
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+	}
+	if !w.streaming {
+		return w.body.Write(data)
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.pending.Write(data)
+	for {
+		raw := w.pending.Bytes()
+		end := bytes.Index(raw, []byte("\n\n"))
+		if end < 0 {
+			break
+		}
+		if err := w.translateEvent(raw[:end]); err != nil {
+			w.err = err
+			return 0, err
+		}
+		w.pending.Next(end + 2)
+	}
+	return len(data), nil
+}
+
+func (w *anthropicWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush is deferred for buffered responses; translated events are flushed as they are sent.
+func (w *anthropicWriter) Flush() {}
+
+// translateEvent translates one event of the gateway's stream and sends it to the client.
+func (w *anthropicWriter) translateEvent(raw []byte) error {
+	var event string
+	var data []byte
+	for _, line := range strings.Split(string(raw), "\n") {
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			event = strings.TrimSpace(name)
+		} else if payload, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(payload, " ")...)
+		}
+	}
+
+	switch event {
+	case eventContentDelta:
+		var payload struct {
+			Delta string `json:"delta"`
+		}
+		if json.Unmarshal(data, &payload) != nil || payload.Delta == "" {
+			return nil
+		}
+		if err := w.start(); err != nil {
+			return err
+		}
+		return w.send("content_block_delta", gin.H{"type": "content_block_delta", "index": 0, "delta": gin.H{"type": "text_delta", "text": payload.Delta}})
+	case eventDone:
+		var done api.GenerationResponse
+		_ = json.Unmarshal(data, &done)
+		if err := w.start(); err != nil {
+			return err
+		}
+		if err := w.send("content_block_stop", gin.H{"type": "content_block_stop", "index": 0}); err != nil {
+			return err
+		}
+		if err := w.send("message_delta", gin.H{
+			"type":  "message_delta",
+			"delta": gin.H{"stop_reason": anthropicStopReason(done), "stop_sequence": nil},
+			"usage": anthropicUsageOf(done.Usage),
+		}); err != nil {
+			return err
+		}
+		return w.send("message_stop", gin.H{"type": "message_stop"})
+	case eventError:
+		return w.send("error", anthropicError(http.StatusInternalServerError, data))
+	default:
+		// RAG and tool progress have no counterpart; a ping keeps idle clients waiting.
+		if err := w.start(); err != nil {
+			return err
+		}
+		return w.send("ping", gin.H{"type": "ping"})
+	}
+}
+
+// start begins the message and its text block, once.
+func (w *anthropicWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if err := w.send("message_start", gin.H{"type": "message_start", "message": anthropicMessagesResponse{
+		ID:      w.id,
+		Type:    "message",
+		Role:    "assistant",
+		Model:   w.model,
+		Content: []anthropicContentBlock{},
+	}}); err != nil {
+		return err
+	}
+	return w.send("content_block_start", gin.H{"type": "content_block_start", "index": 0, "content_block": anthropicContentBlock{Type: "text"}})
+}
+
+// send writes one event of the translated stream.
+func (w *anthropicWriter) send(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode the %s event: %w", event, err)
+	}
+	if _, err := fmt.Fprintf(w.ResponseWriter, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	w.ResponseWriter.Flush()
+	return nil
+}
+
+// finish translates a buffered response once the handler has returned.
+func (w *anthropicWriter) finish() {
+	if w.streaming {
+		return
+	}
+	status := w.Status()
+	var out any
+	if status == http.StatusOK {
+		var resp api.GenerationResponse
+		if err := json.Unmarshal(w.body.Bytes(), &resp); err != nil {
+			status, out = http.StatusInternalServerError, anthropicError(http.StatusInternalServerError, []byte(err.Error()))
+		} else {
+			stopReason := anthropicStopReason(resp)
+			out = anthropicMessagesResponse{
+				ID:         w.id,
+				Type:       "message",
+				Role:       "assistant",
+				Model:      resp.ModelUsed,
+				Content:    []anthropicContentBlock{{Type: "text", Text: resp.Content}},
+				StopReason: &stopReason,
+				Usage:      anthropicUsageOf(resp.Usage),
+			}
+		}
+	} else {
+		out = anthropicError(status, w.body.Bytes())
+	}
+	payload, err := json.Marshal(out)
+	if err != nil {
+		payload = []byte(`{"type":"error","error":{"type":"api_error","message":"failed to encode the response"}}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.ResponseWriter.WriteHeader(status)
+	_, _ = w.ResponseWriter.Write(payload)
+}
+
+// anthropicStopReason gives the stop_reason of an answer: why its model stopped, in the
+// Messages API's terms.
+func anthropicStopReason(resp api.GenerationResponse) string {
+	if len(resp.PendingToolCalls) > 0 {
+		return "tool_use"
+	}
+	switch resp.FinishReason {
+	case llm.StopReasonLength:
+		return "max_tokens"
+	case llm.StopReasonToolCalls:
+		return "tool_use"
+	case llm.StopReasonContentFilter:
+		return "refusal"
+	}
+	return "end_turn"
+}
+
+func anthropicUsageOf(usage api.Usage) anthropicUsage {
+	return anthropicUsage{InputTokens: usage.PromptTokens, OutputTokens: usage.CompletionTokens, CacheReadInputTokens: usage.CachedTokens}
+}
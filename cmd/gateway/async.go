@@ -0,0 +1,362 @@
+// In file: cmd/gateway/async.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AsyncConfig is the `async` section of config.yaml.
+//
+// A request with a callback_url is answered 202 Accepted with a job ID as soon as it is
+// validated, and is then generated in the background like any other request. The outcome is
+// POSTed to the callback as an api.AsyncJobResult, signed like the CMS webhooks the gateway
+// receives: an X-Gateway-Signature header of "sha256=<hex HMAC of the body>" keyed by
+// ASYNC_CALLBACK_SECRET. Jobs are held in memory, so those still running when a replica shuts
+// down are lost.
+type AsyncConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxJobs is how many jobs a replica runs at once; requests beyond it are refused with
+	// 503. It defaults to 100.
+	MaxJobs int `yaml:"max_jobs"`
+	// Timeout bounds the generation of a job. It defaults to five minutes.
+	Timeout time.Duration `yaml:"timeout"`
+	// CallbackTimeout bounds each delivery of a result, and CallbackRetries is how many times
+	// a failed delivery is retried, with exponential backoff. They default to 10s and 3.
+	CallbackTimeout time.Duration `yaml:"callback_timeout"`
+	CallbackRetries int           `yaml:"callback_retries"`
+	// AllowedCallbackHosts lists the hosts results may be delivered to, and must not be empty
+	// when async is enabled. "*.example.com" allows every subdomain.
+	AllowedCallbackHosts []string `yaml:"allowed_callback_hosts"`
+	// SigningSecret keys the signature of the results, from ASYNC_CALLBACK_SECRET. Results are
+	// unsigned without it.
+	SigningSecret string `yaml:"-"`
+}
+
+// withDefaults fills in the settings that were left unset.
+func (c AsyncConfig) withDefaults() AsyncConfig {
+	if c.MaxJobs == 0 {
+		c.MaxJobs = 100
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Minute
+	}
+	if c.CallbackTimeout == 0 {
+		c.CallbackTimeout = 10 * time.Second
+	}
+	if c.CallbackRetries == 0 {
+		c.CallbackRetries = 3
+	}
+	return c
+}
+
+// Validate reports settings that cannot work.
+func (c AsyncConfig) Validate() error {
+	if c.MaxJobs < 0 || c.Timeout < 0 || c.CallbackTimeout < 0 || c.CallbackRetries < 0 {
+		return fmt.Errorf("max_jobs, timeout, callback_timeout, and callback_retries must not be negative")
+	}
+	if c.Enabled && len(c.AllowedCallbackHosts) == 0 {
+		return fmt.Errorf("allowed_callback_hosts must list at least one host when async is enabled")
+	}
+	for _, host := range c.AllowedCallbackHosts {
+		if strings.TrimPrefix(host, "*.") == "" || strings.Contains(host, "/") {
+			return fmt.Errorf("allowed callback host '%s' is not a host name", host)
+		}
+	}
+	return nil
+}
+
+// checkCallbackURL reports why results cannot be delivered to a callback URL.
+func (c AsyncConfig) checkCallbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("callback_url '%s' must be an absolute http or https URL", rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range c.AllowedCallbackHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("callback_url host '%s' is not allowed", host)
+}
+
+// asyncJobs bounds the jobs running on this replica and delivers their results.
+type asyncJobs struct {
+	config     AsyncConfig
+	slots      chan struct{}
+	httpClient *http.Client
+}
+
+func newAsyncJobs(config AsyncConfig) *asyncJobs {
+	config = config.withDefaults()
+	// An allowed host name can still resolve to an internal address, so the address is checked
+	// again when the connection is made, and a redirect can never lead a delivery elsewhere.
+	dialer := &net.Dialer{Timeout: config.CallbackTimeout, Control: rejectInternalAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &asyncJobs{
+		config: config,
+		slots:  make(chan struct{}, config.MaxJobs),
+		httpClient: &http.Client{
+			Timeout:   config.CallbackTimeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// rejectInternalAddress is a net.Dialer Control function that refuses to connect to loopback,
+// private, link-local (including cloud metadata endpoints), multicast, or unspecified addresses.
+func rejectInternalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("callback address %s is not a public address", host)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is not publicly routable.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// acceptAsync answers a request with a callback_url with 202 and a job ID, and generates it in
+// the background. The job is served by HandleGeneration on a copy of the request without the
+// callback_url, so it is routed, cached, and accounted for like any other request.
+func (h *GatewayHandler) acceptAsync(c *gin.Context, req *api.GenerationRequest) {
+	cfg := h.jobs.config
+	if !cfg.Enabled {
+		c.JSON(http.StatusBadRequest, &requestError{Message: "asynchronous requests are disabled", Code: codeInvalidParameter})
+		return
+	}
+	if req.Config.Stream {
+		c.JSON(http.StatusBadRequest, &requestError{Message: "callback_url cannot be combined with streaming", Code: codeInvalidParameter})
+		return
+	}
+	if err := cfg.checkCallbackURL(req.CallbackURL); err != nil {
+		c.JSON(http.StatusBadRequest, &requestError{Message: err.Error(), Code: codeInvalidParameter})
+		return
+	}
+	select {
+	case h.jobs.slots <- struct{}{}:
+	default:
+		c.JSON(http.StatusServiceUnavailable, &requestError{Message: "too many asynchronous requests are running; retry later", Code: codeTooManyJobs})
+		return
+	}
+
+	callbackURL := req.CallbackURL
+	req.CallbackURL = ""
+	body, err := json.Marshal(req)
+	if err != nil {
+		<-h.jobs.slots
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobID := newJobID()
+	// The job outlives the request, so it runs on a copy of the context whose request is
+	// detached from the client's connection but keeps its values, such as the log fields.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.Timeout)
+	job := c.Copy()
+	job.Request = c.Request.Clone(ctx)
+	job.Request.Body = io.NopCloser(bytes.NewReader(body))
+	job.Request.ContentLength = int64(len(body))
+	recorder := &jobRecorder{header: http.Header{}}
+	job.Writer = recorder
+
+	slog.InfoContext(ctx, "Accepted asynchronous request", "job_id", jobID)
+	c.JSON(http.StatusAccepted, api.AsyncJob{JobID: jobID, Status: "accepted"})
+
+	go func() {
+		defer func() { <-h.jobs.slots }()
+		defer cancel()
+		h.runJob(ctx, job)
+		h.jobs.deliver(context.WithoutCancel(ctx), callbackURL, jobResult(jobID, recorder))
+	}()
+}
+
+// runJob generates a job under a slot of the gateway-wide in-flight limit, like a synchronous
+// request, and charges the tokens it used to the caller's rate limit once it finishes, since
+// the request that accepted it has long returned by then. A panic is recovered and recorded
+// as the job's failure: there is no gin.Recovery on the job's goroutine to catch it.
+func (h *GatewayHandler) runJob(ctx context.Context, job *gin.Context) {
+	release, err := h.concurrency.Acquire(ctx, requestPriority(job, h.config))
+	if err != nil {
+		if errors.Is(err, ratelimit.ErrSaturated) {
+			rejectSaturated(job, h.concurrency, "gateway", err)
+		} else {
+			job.JSON(http.StatusGatewayTimeout, gin.H{"error": "The job timed out waiting for capacity."})
+		}
+		return
+	}
+	defer release()
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "Asynchronous job panicked", "panic", r, "stack", string(debug.Stack()))
+			if recorder, ok := job.Writer.(*jobRecorder); ok {
+				recorder.fail(http.StatusInternalServerError, "The job failed unexpectedly.")
+			}
+		}
+	}()
+	h.HandleGeneration(job)
+	if charge, ok := job.Value(tokenChargerKey).(tokenCharger); ok {
+		charge(ctx, job.GetInt(usedTokensKey))
+	}
+}
+
+// jobResult builds the result of a job from the response it was answered with.
+func jobResult(jobID string, recorder *jobRecorder) api.AsyncJobResult {
+	result := api.AsyncJobResult{JobID: jobID, Status: "completed", StatusCode: recorder.Status()}
+	if result.StatusCode == http.StatusOK {
+		var resp api.GenerationResponse
+		if err := json.Unmarshal(recorder.body.Bytes(), &resp); err == nil {
+			result.Response = &resp
+			return result
+		}
+		result.StatusCode = http.StatusInternalServerError
+	}
+	result.Status = "failed"
+	if json.Valid(recorder.body.Bytes()) {
+		result.Error = recorder.body.Bytes()
+	} else {
+		result.Error, _ = json.Marshal(gin.H{"error": strings.TrimSpace(recorder.body.String())})
+	}
+	return result
+}
+
+// deliver POSTs a job's result to its callback, retrying failed deliveries with exponential
+// backoff. Results that cannot be delivered are logged and dropped.
+func (j *asyncJobs) deliver(ctx context.Context, callbackURL string, result api.AsyncJobResult) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode asynchronous result", "job_id", result.JobID, "error", err)
+		return
+	}
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err = j.post(ctx, callbackURL, result.JobID, payload)
+		if err == nil {
+			slog.InfoContext(ctx, "Delivered asynchronous result", "job_id", result.JobID, "status", result.Status, "attempts", attempt+1)
+			return
+		}
+		if attempt >= j.config.CallbackRetries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	slog.ErrorContext(ctx, "Could not deliver asynchronous result", "job_id", result.JobID, "callback_url", callbackURL, "error", err)
+}
+
+// post delivers a result once. Deliveries that are not answered with a 2xx status fail.
+func (j *asyncJobs) post(ctx context.Context, callbackURL, jobID string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gateway-Job-ID", jobID)
+	if j.config.SigningSecret != "" {
+		mac := hmac.New(sha256.New, []byte(j.config.SigningSecret))
+		mac.Write(payload)
+		req.Header.Set("X-Gateway-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newJobID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "job_" + hex.EncodeToString(b)
+}
+
+// jobRecorder is the response writer of a job, which records the response for its callback.
+type jobRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *jobRecorder) Header() http.Header { return r.header }
+
+func (r *jobRecorder) WriteHeader(code int) {
+	if code > 0 && r.status == 0 {
+		r.status = code
+	}
+}
+
+func (r *jobRecorder) WriteHeaderNow() { r.WriteHeader(http.StatusOK) }
+
+func (r *jobRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+func (r *jobRecorder) WriteString(s string) (int, error) { return r.Write([]byte(s)) }
+
+// fail replaces whatever was recorded with an error response.
+func (r *jobRecorder) fail(status int, message string) {
+	r.status = status
+	r.body.Reset()
+	_ = json.NewEncoder(&r.body).Encode(gin.H{"error": message})
+}
+
+func (r *jobRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *jobRecorder) Size() int { return r.body.Len() }
+
+func (r *jobRecorder) Written() bool { return r.status != 0 }
+
+func (r *jobRecorder) Flush() {}
+
+func (r *jobRecorder) CloseNotify() <-chan bool { return make(chan bool) }
+
+func (r *jobRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("an asynchronous job has no connection")
+}
+
+func (r *jobRecorder) Pusher() http.Pusher { return nil }
@@ -0,0 +1,103 @@
+// In file: cmd/gateway/audit.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/audit"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/dileep-u-k/llm-gateway/internal/logging"
+	"github.com/dileep-u-k/llm-gateway/internal/pii"
+)
+
+// auditRecord is a single entry in the request audit trail. It captures the outcome
+// of a request together with the decisions that produced it, so that routing and tool
+// behaviour can be explained after the fact. Prompts are recorded only as a hash.
+type auditRecord struct {
+	Timestamp      time.Time          `json:"timestamp"`
+	UserID         string             `json:"user_id,omitempty"`
+	ConversationID string             `json:"conversation_id,omitempty"`
+	PromptHash     string             `json:"prompt_hash"`
+	ModelUsed      string             `json:"model_used"`
+	CacheStatus    string             `json:"cache_status"`
+	Usage          api.Usage          `json:"usage"`
+	LatencyMS      int64              `json:"latency_ms"`
+	FailoverInfo   *api.FailoverInfo  `json:"failover_info,omitempty"`
+	Decisions      *api.DecisionTrace `json:"decisions"`
+}
+
+// recordAudit writes the audit record for a completed request as a single structured log line
+// and, when a durable audit sink is configured, queues it for the audit store.
+func (h *GatewayHandler) recordAudit(ctx context.Context, req *api.GenerationRequest, resp *api.GenerationResponse, trace *api.DecisionTrace) {
+	if session := pii.SessionFrom(ctx); session != nil {
+		if findings := session.Findings(); len(findings) > 0 {
+			trace.PII = &api.PIIDecision{Mode: h.config.PII.Mode, Entities: findings}
+		}
+	}
+	record := auditRecord{
+		Timestamp:      time.Now().UTC(),
+		UserID:         req.UserID,
+		ConversationID: req.ConversationID,
+		PromptHash:     llm.GenerateCacheKey(req.Prompt),
+		ModelUsed:      resp.ModelUsed,
+		CacheStatus:    resp.CacheStatus,
+		Usage:          resp.Usage,
+		LatencyMS:      resp.LatencyMS,
+		FailoverInfo:   resp.FailoverInfo,
+		Decisions:      trace,
+	}
+	slog.InfoContext(ctx, "AUDIT", "audit", record)
+
+	if h.auditWriter == nil {
+		return
+	}
+	durable := audit.Record{
+		Timestamp:        record.Timestamp,
+		RequestID:        logging.Field(ctx, logging.RequestIDKey),
+		UserID:           record.UserID,
+		ConversationID:   record.ConversationID,
+		PromptHash:       record.PromptHash,
+		ModelUsed:        record.ModelUsed,
+		CacheStatus:      record.CacheStatus,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		LatencyMS:        record.LatencyMS,
+		FailoverInfo:     record.FailoverInfo,
+		Decisions:        trace,
+	}
+	// Cached and coalesced responses did not call a provider, so they cost nothing.
+	if resp.CacheStatus != "HIT" && resp.CacheStatus != "COALESCED" {
+		durable.CostUSD = llm.CallCost(resp.ModelUsed, resp.Usage)
+	}
+	if h.config.Audit.StoreFullText {
+		durable.Prompt = req.Prompt
+		durable.Response = resp.Content
+	}
+	h.auditWriter.Record(durable)
+}
+
+// initializeAuditWriter creates the asynchronous writer for the configured audit sink.
+// It returns nil when no durable sink is configured.
+func initializeAuditWriter(ctx context.Context, cfg AuditConfig) (*audit.Writer, error) {
+	var sink audit.Sink
+	var err error
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case "postgres":
+		sink, err = audit.NewPostgresSink(ctx, cfg.DSN, cfg.Table)
+	case "clickhouse":
+		sink, err = audit.NewClickHouseSink(ctx, cfg.ClickHouseURL, cfg.ClickHouseUser, cfg.ClickHousePassword, cfg.Table)
+	default:
+		return nil, fmt.Errorf("unknown audit sink '%s'", cfg.Sink)
+	}
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Durable audit log enabled", "sink", cfg.Sink, "table", cfg.Table, "full_text", cfg.StoreFullText)
+	return audit.NewWriter(sink, cfg.QueueSize, cfg.BatchSize, cfg.FlushInterval), nil
+}
@@ -0,0 +1,101 @@
+// In file: cmd/gateway/client_tools.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/dileep-u-k/llm-gateway/internal/tools"
+)
+
+// maxClientTools is the most tools every provider accepts in one request (OpenAI's limit).
+const maxClientTools = 128
+
+// clientToolName matches the function names every provider accepts.
+var clientToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// validateClientTools rejects client tools that cannot be offered to every provider. Tools
+// without parameters are given an empty object schema.
+func validateClientTools(req *api.GenerationRequest) error {
+	if len(req.Tools) == 0 {
+		return nil
+	}
+	if req.Config.Stream {
+		return errors.New("tools cannot be combined with streaming")
+	}
+	if len(req.Tools) > maxClientTools {
+		return fmt.Errorf("at most %d tools can be sent, got %d", maxClientTools, len(req.Tools))
+	}
+	seen := make(map[string]bool, len(req.Tools))
+	for i, tool := range req.Tools {
+		switch {
+		case !clientToolName.MatchString(tool.Name):
+			return fmt.Errorf("tool %d: the name must be 1 to 64 letters, digits, underscores, or dashes", i)
+		case seen[tool.Name]:
+			return fmt.Errorf("tool '%s' is declared more than once", tool.Name)
+		}
+		seen[tool.Name] = true
+		if len(tool.Parameters) == 0 {
+			req.Tools[i].Parameters = json.RawMessage(`{"type":"object"}`)
+			continue
+		}
+		var schema tools.JSONSchema
+		if err := json.Unmarshal(tool.Parameters, &schema); err != nil || schema.Type != "object" {
+			return fmt.Errorf("tool '%s': the parameters must be a JSON Schema describing an object", tool.Name)
+		}
+	}
+	return nil
+}
+
+// clientToolDefinitions converts a request's client tools for the provider clients.
+func clientToolDefinitions(clientTools []api.ClientTool) []tools.Tool {
+	definitions := make([]tools.Tool, 0, len(clientTools))
+	for _, tool := range clientTools {
+		var parameters tools.JSONSchema
+		// validateClientTools has checked that the parameters decode.
+		_ = json.Unmarshal(tool.Parameters, &parameters)
+		definitions = append(definitions, tools.NewFunctionTool(tool.Name, tool.Description, parameters))
+	}
+	return definitions
+}
+
+// hashClientTools condenses a request's client tools into a short hash for cache keys.
+func hashClientTools(clientTools []api.ClientTool) string {
+	if len(clientTools) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, tool := range clientTools {
+		fmt.Fprintf(&b, "%s\x00%s\x00%s\x00", tool.Name, tool.Description, tool.Parameters)
+	}
+	return llm.GenerateCacheKey(b.String())
+}
+
+// apiToolCalls converts a model's tool calls for the caller.
+func apiToolCalls(toolCalls []*tools.ToolCall) []api.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	calls := make([]api.ToolCall, len(toolCalls))
+	for i, call := range toolCalls {
+		calls[i] = api.ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments}
+	}
+	return calls
+}
+
+// llmToolCalls converts the tool calls of a caller's history message for the provider clients.
+func llmToolCalls(toolCalls []api.ToolCall) []*tools.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	calls := make([]*tools.ToolCall, len(toolCalls))
+	for i, call := range toolCalls {
+		calls[i] = &tools.ToolCall{ID: call.ID, Type: tools.ToolTypeFunction, Function: tools.ToolCallFunction{Name: call.Name, Arguments: call.Arguments}}
+	}
+	return calls
+}
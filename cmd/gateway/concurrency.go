@@ -0,0 +1,50 @@
+// In file: cmd/gateway/concurrency.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/dileep-u-k/llm-gateway/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitConcurrency holds a slot of the gateway-wide in-flight limit for the whole request.
+// When every slot is taken, the request waits in the queue, behind requests of more urgent
+// tenants; when the queue is full too, it is rejected with 429 and a Retry-After header.
+func limitConcurrency(limiter *ratelimit.ConcurrencyLimiter, config *AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, err := limiter.Acquire(c.Request.Context(), requestPriority(c, config))
+		if err != nil {
+			rejectSaturated(c, limiter, "gateway", err)
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
+
+// requestPriority returns the quality-of-service tier of the caller's tenant.
+func requestPriority(c *gin.Context, config *AppConfig) ratelimit.Priority {
+	return config.TenantFor(c.GetHeader(tenantHeader)).Priority
+}
+
+// rejectSaturated answers a request that did not get an in-flight slot of the named limit.
+// A request whose caller went away while it was queued gets no answer.
+func rejectSaturated(c *gin.Context, limiter *ratelimit.ConcurrencyLimiter, limit string, err error) {
+	if !errors.Is(err, ratelimit.ErrSaturated) {
+		c.Abort()
+		return
+	}
+	seconds := int(math.Ceil(limiter.RetryAfter().Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	slog.WarnContext(c.Request.Context(), "Concurrency limit reached", "limit", limit, "retry_after_s", seconds)
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error": fmt.Sprintf("The %s is handling too many requests. Retry in %d seconds.", limit, seconds),
+	})
+}
@@ -2,20 +2,42 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/dileep-u-k/llm-gateway/internal/auth"
+	"github.com/dileep-u-k/llm-gateway/internal/config"
+	"github.com/dileep-u-k/llm-gateway/internal/ingest"
 	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/dileep-u-k/llm-gateway/internal/moderation"
+	"github.com/dileep-u-k/llm-gateway/internal/pii"
+	"github.com/dileep-u-k/llm-gateway/internal/ratelimit"
+	"github.com/dileep-u-k/llm-gateway/internal/secrets"
+	"github.com/dileep-u-k/llm-gateway/internal/tools"
 
-	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
+// Gateway profiles select which subsystems are started.
+const (
+	// ProfileFull runs every feature: routing, caching, RAG, tools, and intent analysis.
+	ProfileFull = "full"
+	// ProfileMinimal runs only the multi-provider router with caching, budgets, and failover.
+	// It needs neither Pinecone nor an OpenAI embedding key.
+	ProfileMinimal = "minimal"
+)
+
 // AppConfig holds all configuration for the gateway, loaded from the environment and config files.
 type AppConfig struct {
+	// LogLevel is the minimum level of the structured logger: debug, info, warn, or error.
+	LogLevel string
+	// Profile is either ProfileFull or ProfileMinimal.
+	Profile       string
 	EnabledModels []string
 	APIKeys       map[string]string
 	ModelCosts    map[string]map[string]float64
@@ -24,90 +46,687 @@ type AppConfig struct {
 	RAGConfig     *llm.Config // Assuming RAG config is needed
 	RedisAddr     string
 	NewsAPIKey    string
+	CMSWebhooks   CMSWebhookConfig
+	// Chunking is how webhook-ingested documents are chunked, shared with the offline ingestor.
+	Chunking ingest.ChunkingConfig
+	// AdminAPIKey protects the /admin endpoints. They are disabled when it is empty.
+	AdminAPIKey string
+	// AuthMode is AuthModeNone or AuthModeOIDC and selects how /api/v1 callers are authenticated.
+	AuthMode string
+	// OIDC configures token validation when AuthMode is AuthModeOIDC.
+	OIDC auth.OIDCConfig
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies in front of the
+	// gateway, from server.trusted_proxies. Only their X-Forwarded-For headers are believed when
+	// working out a caller's address; with none, it is the connection's peer address.
+	TrustedProxies []string
+	// DeprecationDigestWebhookURL receives the daily digest of model deprecation notices.
+	DeprecationDigestWebhookURL string
+	// HTTPTools are operator-defined tools loaded from the `tools` section of config.yaml.
+	HTTPTools []tools.HTTPToolConfig
+	// CodeInterpreterEnabled registers the sandboxed code execution tool. It is off by default
+	// because it runs model-written code on the gateway host.
+	CodeInterpreterEnabled bool
+	CodeInterpreter        tools.CodeInterpreterConfig
+	// SessionStore selects where conversation-to-model pinning is kept: "redis" or "memory".
+	SessionStore string
+	// Sessions is the default session policy from the `sessions` section of config.yaml.
+	Sessions llm.SessionPolicy
+	// ConversationMaxMessages and ConversationTTL bound the server-side conversation history.
+	ConversationMaxMessages int
+	ConversationTTL         time.Duration
+	// TitleModel generates conversation titles after the first exchange. When empty, the
+	// enabled model with the lowest input cost is used. "off" disables automatic titling.
+	TitleModel string
+	// AnalysisCacheTTL is how long the intent and preference decisions about a prompt are
+	// cached, from server.analysis_cache_ttl. 0 disables the cache.
+	AnalysisCacheTTL time.Duration
+	// Tenants holds per-tenant settings from the `tenants` section of config.yaml.
+	Tenants map[string]TenantConfig
+	// Audit configures the durable audit log. It is disabled when Audit.Sink is empty.
+	Audit AuditConfig
+	// PII configures detection and masking of personal data sent to providers.
+	PII pii.Config
+	// RateLimit is the default per-account limit on /generate, from the `rate_limit` section
+	// of config.yaml. Tenants may override it.
+	RateLimit ratelimit.Limits
+	// Moderation configures content moderation of prompts and final answers.
+	Moderation moderation.Config
+	// ModerationAPIKey authenticates calls to the moderation provider, from MODERATION_API_KEY
+	// or, failing that, OPENAI_API_KEY.
+	ModerationAPIKey string
+	// Secrets resolves API keys given as secrets manager references (vault://, aws-sm://, or
+	// gcp-sm://) instead of raw values.
+	Secrets *secrets.Manager
+	// APIKeyRefs holds the secrets manager reference of each model whose key was given as one,
+	// so its client can follow key rotations. APIKeys always holds the resolved keys.
+	APIKeyRefs map[string]string
+	// SecretsRefreshInterval is how often referenced secrets are re-fetched, from
+	// server.secrets_refresh_interval. Zero disables refreshing.
+	SecretsRefreshInterval time.Duration
+	// Limits bounds the size of /generate requests, from the `limits` section of config.yaml.
+	Limits RequestLimits
+	// ShutdownDrainTimeout is how long a shutdown waits for in-flight requests and streams,
+	// from server.shutdown_drain_timeout. Streams still running when it expires are interrupted.
+	ShutdownDrainTimeout time.Duration
+	// Concurrency bounds the /generate requests in flight, from the `concurrency` section of
+	// config.yaml.
+	Concurrency ratelimit.ConcurrencyConfig
+	// HealthCheck schedules the proactive model health checks, from the `health_check`
+	// section of config.yaml.
+	HealthCheck HealthCheckConfig
+	// UserBudgets caps the monthly spend of each user, from the `user_budgets` section of
+	// config.yaml.
+	UserBudgets UserBudgetConfig
+	// ProfileHistory schedules the snapshots of the model profiles, from the `profile_history`
+	// section of config.yaml.
+	ProfileHistory ProfileHistoryConfig
+	// Streaming tunes how provider streams are relayed to clients, from the `streaming`
+	// section of config.yaml.
+	Streaming StreamingConfig
+	// Passthrough enables relaying native provider requests unchanged, from the `passthrough`
+	// section of config.yaml.
+	Passthrough PassthroughConfig
+	// Async enables requests answered through a callback, from the `async` section of
+	// config.yaml.
+	Async AsyncConfig
+	// Intents tunes how prompts are classified by the intent training examples, from the
+	// `intents` section of config.yaml.
+	Intents llm.IntentConfig
+	// PromptAnalysis tunes how a preference is selected for requests without one, from the
+	// `prompt_analysis` section of config.yaml.
+	PromptAnalysis llm.PromptAnalysisConfig
 }
 
-// LoadConfig loads all configuration from a .env file, environment variables, and config.yaml.
-func LoadConfig() (*AppConfig, error) {
-	//if err := godotenv.Load(); err != nil {
-	//  log.Println("WARNING: No .env file found, relying on system environment variables.")
-	//}
+// RequestLimits bounds what a single /generate request may send, so oversized requests are
+// rejected before they reach (and are billed by) a provider. A limit of 0 disables it.
+type RequestLimits struct {
+	// MaxBodyBytes limits the size of the request body.
+	MaxBodyBytes int64 `yaml:"max_request_bytes"`
+	// MaxPromptChars limits the prompt's length in characters.
+	MaxPromptChars int `yaml:"max_prompt_chars"`
+	// MaxHistoryMessages and MaxHistoryChars limit the history sent with the request.
+	MaxHistoryMessages int `yaml:"max_history_messages"`
+	MaxHistoryChars    int `yaml:"max_history_chars"`
+	// MaxTokens is the largest max_tokens a request may ask for.
+	MaxTokens int `yaml:"max_output_tokens"`
+}
+
+// AuditConfig selects where audit records are stored and how much of each request they retain.
+// The connection settings, which carry credentials, come from the environment.
+type AuditConfig struct {
+	// Sink is "postgres", "clickhouse", or empty to only log audit records.
+	Sink string `yaml:"sink"`
+	// DSN is the Postgres connection string, from AUDIT_DSN.
+	DSN string `yaml:"-"`
+	// ClickHouseURL is the base URL of ClickHouse's HTTP interface, with its credentials,
+	// from AUDIT_CLICKHOUSE_URL, AUDIT_CLICKHOUSE_USER, and AUDIT_CLICKHOUSE_PASSWORD.
+	ClickHouseURL      string `yaml:"-"`
+	ClickHouseUser     string `yaml:"-"`
+	ClickHousePassword string `yaml:"-"`
+	// Table is the table the records are written to. It is created if it does not exist.
+	Table string `yaml:"table"`
+	// StoreFullText records prompts and responses verbatim. By default only a hash of the
+	// prompt is kept, so the audit store never holds user content.
+	StoreFullText bool `yaml:"store_full_text"`
+	// QueueSize, BatchSize, and FlushInterval tune the asynchronous writer.
+	QueueSize     int           `yaml:"queue_size"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// DefaultTenant is the tenant applied to requests that do not identify a known tenant.
+const DefaultTenant = "default"
+
+// TenantConfig holds the settings that apply to every request made on behalf of a tenant.
+type TenantConfig struct {
+	// Tools restricts which tools the agent may expose and execute for the tenant.
+	Tools tools.ToolPolicy `yaml:",inline"`
+	// Sessions overrides individual fields of the default session policy.
+	Sessions *llm.SessionPolicy `yaml:"sessions"`
+	// RateLimit replaces the default per-account rate limits for the tenant's callers.
+	RateLimit *ratelimit.Limits `yaml:"rate_limit"`
+	// Priority is the quality-of-service tier of the tenant's requests when the gateway is
+	// saturated: "interactive" (the default) or "batch".
+	Priority ratelimit.Priority `yaml:"priority"`
+}
+
+// gatewayFileConfig holds the gateway-level sections of config.yaml.
+// The router's sections are parsed separately into llm.RouterConfig.
+type gatewayFileConfig struct {
+	Server          serverSettings          `yaml:"server"`
+	Auth            authSettings            `yaml:"auth"`
+	RateLimit       ratelimit.Limits        `yaml:"rate_limit"`
+	Limits          RequestLimits           `yaml:"limits"`
+	Conversations   conversationSettings    `yaml:"conversations"`
+	Audit           AuditConfig             `yaml:"audit"`
+	CodeInterpreter codeInterpreterSettings `yaml:"code_interpreter"`
+	Ingest          struct {
+		Webhooks webhookSettings `yaml:"webhooks"`
+	} `yaml:"ingest"`
+	Tools          []tools.HTTPToolConfig      `yaml:"tools"`
+	Tenants        map[string]TenantConfig     `yaml:"tenants"`
+	Sessions       *llm.SessionPolicy          `yaml:"sessions"`
+	PII            pii.Config                  `yaml:"pii"`
+	Moderation     moderation.Config           `yaml:"moderation"`
+	Concurrency    ratelimit.ConcurrencyConfig `yaml:"concurrency"`
+	HealthCheck    HealthCheckConfig           `yaml:"health_check"`
+	ProfileHistory ProfileHistoryConfig        `yaml:"profile_history"`
+	UserBudgets    UserBudgetConfig            `yaml:"user_budgets"`
+	Streaming      StreamingConfig             `yaml:"streaming"`
+	Passthrough    PassthroughConfig           `yaml:"passthrough"`
+	Async          AsyncConfig                 `yaml:"async"`
+	Intents        llm.IntentConfig            `yaml:"intents"`
+	PromptAnalysis llm.PromptAnalysisConfig    `yaml:"prompt_analysis"`
+}
+
+// serverSettings is the `server` section of config.yaml: how the gateway process runs.
+type serverSettings struct {
+	// LogLevel is the minimum level of the structured logger: debug, info, warn, or error.
+	LogLevel string `yaml:"log_level"`
+	// Profile is ProfileFull or ProfileMinimal.
+	Profile string `yaml:"profile"`
+	// SessionStore selects where conversation-to-model pinning is kept: "redis" or "memory".
+	SessionStore string `yaml:"session_store"`
+	// AnalysisCacheTTL is how long the intent and preference decisions about a prompt are
+	// cached. 0 disables the cache.
+	AnalysisCacheTTL time.Duration `yaml:"analysis_cache_ttl"`
+	// SecretsRefreshInterval is how often referenced secrets are re-fetched. 0 disables it.
+	SecretsRefreshInterval time.Duration `yaml:"secrets_refresh_interval"`
+	// ShutdownDrainTimeout is how long a shutdown waits for in-flight requests and streams.
+	ShutdownDrainTimeout time.Duration `yaml:"shutdown_drain_timeout"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies in front of the
+	// gateway.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// Validate reports settings that cannot work.
+func (s serverSettings) Validate() error {
+	if s.Profile != ProfileFull && s.Profile != ProfileMinimal {
+		return fmt.Errorf("unknown profile '%s' (expected '%s' or '%s')", s.Profile, ProfileFull, ProfileMinimal)
+	}
+	if s.SessionStore != "redis" && s.SessionStore != "memory" {
+		return fmt.Errorf("unknown session_store '%s' (expected 'redis' or 'memory')", s.SessionStore)
+	}
+	if s.AnalysisCacheTTL < 0 || s.SecretsRefreshInterval < 0 {
+		return fmt.Errorf("analysis_cache_ttl and secrets_refresh_interval must not be negative")
+	}
+	if s.ShutdownDrainTimeout <= 0 {
+		return fmt.Errorf("shutdown_drain_timeout must be positive")
+	}
+	return nil
+}
+
+// authSettings is the `auth` section of config.yaml.
+type authSettings struct {
+	// Mode is AuthModeNone or AuthModeOIDC.
+	Mode string          `yaml:"mode"`
+	OIDC auth.OIDCConfig `yaml:"oidc"`
+}
 
-	// --- THIS IS THE FIX ---
-	// Only attempt to load a .env file if we are in a local development environment.
-	// In Docker (where GIN_MODE="release"), configuration is provided directly as
-	// environment variables by Docker Compose.
-	if os.Getenv("GIN_MODE") != "release" {
-		if err := godotenv.Load(); err != nil {
-			log.Println("WARNING: No .env file found for local development.")
+// Validate reports an unknown mode, and an OIDC mode without an issuer or audience.
+func (a authSettings) Validate() error {
+	switch a.Mode {
+	case AuthModeNone:
+	case AuthModeOIDC:
+		if a.OIDC.Issuer == "" || a.OIDC.Audience == "" {
+			return fmt.Errorf("oidc.issuer and oidc.audience are required when the mode is '%s'", AuthModeOIDC)
+		}
+	default:
+		return fmt.Errorf("unknown mode '%s' (expected '%s' or '%s')", a.Mode, AuthModeNone, AuthModeOIDC)
+	}
+	return nil
+}
+
+// conversationSettings is the `conversations` section of config.yaml.
+type conversationSettings struct {
+	MaxMessages int           `yaml:"max_messages"`
+	TTL         time.Duration `yaml:"ttl"`
+	TitleModel  string        `yaml:"title_model"`
+}
+
+// codeInterpreterSettings is the `code_interpreter` section of config.yaml.
+type codeInterpreterSettings struct {
+	Enabled                     bool `yaml:"enabled"`
+	tools.CodeInterpreterConfig `yaml:",inline"`
+}
+
+// webhookSettings is the `ingest.webhooks` section of config.yaml. The webhook secrets and
+// the credentials used to fetch content stay in the environment.
+type webhookSettings struct {
+	GitHub struct {
+		Topic string `yaml:"topic"`
+		// Repositories are the "owner/name" repositories whose pushes are ingested.
+		Repositories []string `yaml:"repositories"`
+	} `yaml:"github"`
+	Notion struct {
+		Topic string `yaml:"topic"`
+	} `yaml:"notion"`
+	Confluence struct {
+		BaseURL string `yaml:"base_url"`
+	} `yaml:"confluence"`
+}
+
+// defaultGatewayFileConfig returns the defaults of the settings config.yaml may omit.
+func defaultGatewayFileConfig() gatewayFileConfig {
+	var f gatewayFileConfig
+	f.Server = serverSettings{
+		Profile:                defaultProfile,
+		SessionStore:           "redis",
+		AnalysisCacheTTL:       10 * time.Minute,
+		SecretsRefreshInterval: 5 * time.Minute,
+		// The default fits within Kubernetes' default 30s termination grace period.
+		ShutdownDrainTimeout: 25 * time.Second,
+	}
+	f.Auth.Mode = AuthModeNone
+	// Generous limits that still stop runaway requests.
+	f.Limits = RequestLimits{
+		MaxBodyBytes:       1 << 20,
+		MaxPromptChars:     32000,
+		MaxHistoryMessages: 100,
+		MaxHistoryChars:    200000,
+		MaxTokens:          16384,
+	}
+	f.Conversations = conversationSettings{MaxMessages: 50, TTL: 24 * time.Hour}
+	f.Audit = AuditConfig{
+		Table:         "gateway_audit",
+		QueueSize:     10000,
+		BatchSize:     100,
+		FlushInterval: 2 * time.Second,
+	}
+	return f
+}
+
+// applyLegacyEnv applies the deprecated environment variables that held these settings
+// before they moved into config.yaml, logging a warning for each one that is used. A
+// variable only applies while its setting is absent from the file, given as fileSettings.
+func (f *gatewayFileConfig) applyLegacyEnv(fileSettings map[string]any) error {
+	settings := []struct {
+		target  any
+		env     string
+		setting string
+	}{
+		{&f.Server.LogLevel, "LOG_LEVEL", "server.log_level"},
+		{&f.Server.Profile, "GATEWAY_PROFILE", "server.profile"},
+		{&f.Server.SessionStore, "SESSION_STORE", "server.session_store"},
+		{&f.Server.AnalysisCacheTTL, "ANALYSIS_CACHE_TTL", "server.analysis_cache_ttl"},
+		{&f.Server.SecretsRefreshInterval, "SECRETS_REFRESH_INTERVAL", "server.secrets_refresh_interval"},
+		{&f.Server.ShutdownDrainTimeout, "SHUTDOWN_DRAIN_TIMEOUT", "server.shutdown_drain_timeout"},
+		{&f.Auth.Mode, "AUTH_MODE", "auth.mode"},
+		{&f.Auth.OIDC.Issuer, "OIDC_ISSUER", "auth.oidc.issuer"},
+		{&f.Auth.OIDC.Audience, "OIDC_AUDIENCE", "auth.oidc.audience"},
+		{&f.Auth.OIDC.JWKSURL, "OIDC_JWKS_URL", "auth.oidc.jwks_url"},
+		{&f.Auth.OIDC.UserClaim, "OIDC_USER_CLAIM", "auth.oidc.user_claim"},
+		{&f.Auth.OIDC.TenantClaim, "OIDC_TENANT_CLAIM", "auth.oidc.tenant_claim"},
+		{&f.RateLimit.RequestsPerMinute, "RATE_LIMIT_RPM", "rate_limit.rpm"},
+		{&f.RateLimit.TokensPerMinute, "RATE_LIMIT_TPM", "rate_limit.tpm"},
+		{&f.Limits.MaxBodyBytes, "MAX_REQUEST_BYTES", "limits.max_request_bytes"},
+		{&f.Limits.MaxPromptChars, "MAX_PROMPT_CHARS", "limits.max_prompt_chars"},
+		{&f.Limits.MaxHistoryMessages, "MAX_HISTORY_MESSAGES", "limits.max_history_messages"},
+		{&f.Limits.MaxHistoryChars, "MAX_HISTORY_CHARS", "limits.max_history_chars"},
+		{&f.Limits.MaxTokens, "MAX_OUTPUT_TOKENS", "limits.max_output_tokens"},
+		{&f.Conversations.MaxMessages, "CONVERSATION_MAX_MESSAGES", "conversations.max_messages"},
+		{&f.Conversations.TTL, "CONVERSATION_TTL", "conversations.ttl"},
+		{&f.Conversations.TitleModel, "CONVERSATION_TITLE_MODEL", "conversations.title_model"},
+		{&f.Audit.Sink, "AUDIT_SINK", "audit.sink"},
+		{&f.Audit.Table, "AUDIT_TABLE", "audit.table"},
+		{&f.Audit.StoreFullText, "AUDIT_STORE_FULL_TEXT", "audit.store_full_text"},
+		{&f.Audit.QueueSize, "AUDIT_QUEUE_SIZE", "audit.queue_size"},
+		{&f.Audit.BatchSize, "AUDIT_BATCH_SIZE", "audit.batch_size"},
+		{&f.Audit.FlushInterval, "AUDIT_FLUSH_INTERVAL", "audit.flush_interval"},
+		{&f.CodeInterpreter.Enabled, "CODE_INTERPRETER_ENABLED", "code_interpreter.enabled"},
+		{&f.CodeInterpreter.PythonPath, "CODE_INTERPRETER_PYTHON", "code_interpreter.python_path"},
+		{&f.CodeInterpreter.NodePath, "CODE_INTERPRETER_NODE", "code_interpreter.node_path"},
+		{&f.CodeInterpreter.SandboxCommand, "CODE_INTERPRETER_SANDBOX", "code_interpreter.sandbox_command"},
+		{&f.CodeInterpreter.SandboxUID, "CODE_INTERPRETER_UID", "code_interpreter.sandbox_uid"},
+		{&f.CodeInterpreter.SandboxUsers, "CODE_INTERPRETER_USERS", "code_interpreter.sandbox_users"},
+		{&f.CodeInterpreter.SandboxGID, "CODE_INTERPRETER_GID", "code_interpreter.sandbox_gid"},
+		{&f.CodeInterpreter.MemoryLimitMB, "CODE_INTERPRETER_MEMORY_MB", "code_interpreter.memory_limit_mb"},
+		{&f.CodeInterpreter.MaxOutputBytes, "CODE_INTERPRETER_MAX_OUTPUT_BYTES", "code_interpreter.max_output_bytes"},
+		{&f.Ingest.Webhooks.GitHub.Topic, "GITHUB_INGEST_TOPIC", "ingest.webhooks.github.topic"},
+		{&f.Ingest.Webhooks.Notion.Topic, "NOTION_INGEST_TOPIC", "ingest.webhooks.notion.topic"},
+		{&f.Ingest.Webhooks.Confluence.BaseURL, "CONFLUENCE_BASE_URL", "ingest.webhooks.confluence.base_url"},
+	}
+	for _, s := range settings {
+		if hasSetting(fileSettings, s.setting) {
+			continue
+		}
+		if err := setLegacy(s.target, s.env, s.setting); err != nil {
+			return err
+		}
+	}
+	// These lists used to be comma-separated.
+	lists := []struct {
+		target  *[]string
+		env     string
+		setting string
+	}{
+		{&f.Server.TrustedProxies, "TRUSTED_PROXIES", "server.trusted_proxies"},
+		{&f.Ingest.Webhooks.GitHub.Repositories, "GITHUB_REPOSITORIES", "ingest.webhooks.github.repositories"},
+	}
+	for _, l := range lists {
+		if hasSetting(fileSettings, l.setting) {
+			continue
 		}
+		if value := config.Legacy("", l.env, l.setting); value != "" {
+			*l.target = splitList(value)
+		}
+	}
+	if hasSetting(fileSettings, "code_interpreter.timeout") {
+		return nil
+	}
+	// The timeout used to be given in whole seconds.
+	var seconds int
+	if err := setLegacy(&seconds, "CODE_INTERPRETER_TIMEOUT_SECONDS", "code_interpreter.timeout"); err != nil {
+		return err
 	}
-	// --- END OF FIX ---
+	if seconds > 0 {
+		f.CodeInterpreter.Timeout = time.Duration(seconds) * time.Second
+	}
+	return nil
+}
+
+// hasSetting reports whether the dotted setting, such as "server.log_level", is present in
+// the parsed settings.
+func hasSetting(settings map[string]any, setting string) bool {
+	section, rest, nested := strings.Cut(setting, ".")
+	value, ok := settings[section]
+	if !ok || !nested {
+		return ok
+	}
+	inner, _ := value.(map[string]any)
+	return hasSetting(inner, rest)
+}
+
+// setLegacy parses the deprecated environment variable env, if it is set, into target.
+func setLegacy(target any, env, setting string) error {
+	value := config.Legacy("", env, setting)
+	if value == "" {
+		return nil
+	}
+	var err error
+	switch t := target.(type) {
+	case *string:
+		*t = value
+	case *[]string:
+		*t = strings.Fields(value)
+	case *bool:
+		*t, err = strconv.ParseBool(value)
+	case *int:
+		*t, err = strconv.Atoi(value)
+	case *int64:
+		*t, err = strconv.ParseInt(value, 10, 64)
+	case *time.Duration:
+		*t, err = time.ParseDuration(value)
+	default:
+		panic(fmt.Sprintf("unsupported setting type %T", target))
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s '%s'", env, value)
+	}
+	return nil
+}
+
+// CMSWebhookConfig holds the credentials used to authenticate CMS webhooks and
+// to fetch changed content back from each CMS. A connector is only enabled when
+// its webhook secret and the credentials it needs to fetch content are present.
+type CMSWebhookConfig struct {
+	GitHubWebhookSecret     string
+	GitHubToken             string
+	GitHubTopic             string
+	GitHubRepositories      []string
+	NotionWebhookSecret     string
+	NotionAPIKey            string
+	NotionTopic             string
+	ConfluenceWebhookSecret string
+	ConfluenceBaseURL       string
+	ConfluenceUser          string
+	ConfluenceAPIToken      string
+}
+
+// LoadConfig loads all configuration from config.yaml (or CONFIG_FILE) and secrets from the
+// environment or a local .env file.
+func LoadConfig() (*AppConfig, error) {
+	config.LoadEnv()
 
 	cfg := &AppConfig{
 		APIKeys:      make(map[string]string),
+		APIKeyRefs:   make(map[string]string),
+		Secrets:      newSecretsManager(),
 		ModelCosts:   make(map[string]map[string]float64),
 		ModelBudgets: make(map[string]float64),
 		RedisAddr:    os.Getenv("REDIS_ADDR"),
 		NewsAPIKey:   os.Getenv("NEWS_API_KEY"),
+		AdminAPIKey:  os.Getenv("ADMIN_API_KEY"),
+
+		DeprecationDigestWebhookURL: os.Getenv("DEPRECATION_DIGEST_WEBHOOK_URL"),
+		CMSWebhooks: CMSWebhookConfig{
+			GitHubWebhookSecret:     os.Getenv("GITHUB_WEBHOOK_SECRET"),
+			GitHubToken:             os.Getenv("GITHUB_TOKEN"),
+			NotionWebhookSecret:     os.Getenv("NOTION_WEBHOOK_SECRET"),
+			NotionAPIKey:            os.Getenv("NOTION_API_KEY"),
+			ConfluenceWebhookSecret: os.Getenv("CONFLUENCE_WEBHOOK_SECRET"),
+			ConfluenceUser:          os.Getenv("CONFLUENCE_USER"),
+			ConfluenceAPIToken:      os.Getenv("CONFLUENCE_API_TOKEN"),
+		},
 	}
 
-	enabledModelsStr := os.Getenv("ENABLED_MODELS")
-	if enabledModelsStr == "" {
-		return nil, fmt.Errorf("ENABLED_MODELS environment variable is not set")
+	configFile, err := config.Read()
+	if err != nil {
+		return nil, err
+	}
+	fileCfg := defaultGatewayFileConfig()
+	if err := yaml.Unmarshal(configFile, &fileCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse gateway sections of config.yaml: %w", err)
+	}
+	var fileSettings map[string]any
+	if err := yaml.Unmarshal(configFile, &fileSettings); err != nil {
+		return nil, fmt.Errorf("failed to parse config.yaml: %w", err)
+	}
+	if err := fileCfg.applyLegacyEnv(fileSettings); err != nil {
+		return nil, err
 	}
-	cfg.EnabledModels = strings.Split(enabledModelsStr, ",")
 
-	for _, modelID := range cfg.EnabledModels {
+	server := fileCfg.Server
+	if err := server.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid server config: %w", err)
+	}
+	cfg.LogLevel = server.LogLevel
+	cfg.SessionStore = server.SessionStore
+	cfg.AnalysisCacheTTL = server.AnalysisCacheTTL
+	cfg.SecretsRefreshInterval = server.SecretsRefreshInterval
+	cfg.ShutdownDrainTimeout = server.ShutdownDrainTimeout
+	cfg.Profile = server.Profile
+	cfg.TrustedProxies = server.TrustedProxies
+	if profileLocked && cfg.Profile != defaultProfile {
+		return nil, fmt.Errorf("this binary was built with the '%s' profile and cannot run as '%s'", defaultProfile, cfg.Profile)
+	}
+
+	cfg.AuthMode = fileCfg.Auth.Mode
+	cfg.OIDC = fileCfg.Auth.OIDC
+	if err := fileCfg.Auth.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	cfg.CodeInterpreterEnabled = fileCfg.CodeInterpreter.Enabled
+	cfg.CodeInterpreter = fileCfg.CodeInterpreter.CodeInterpreterConfig
+
+	cfg.ConversationMaxMessages = fileCfg.Conversations.MaxMessages
+	cfg.ConversationTTL = fileCfg.Conversations.TTL
+	cfg.TitleModel = fileCfg.Conversations.TitleModel
+
+	cfg.Audit = fileCfg.Audit
+	cfg.Audit.DSN = os.Getenv("AUDIT_DSN")
+	cfg.Audit.ClickHouseURL = os.Getenv("AUDIT_CLICKHOUSE_URL")
+	cfg.Audit.ClickHouseUser = os.Getenv("AUDIT_CLICKHOUSE_USER")
+	cfg.Audit.ClickHousePassword = os.Getenv("AUDIT_CLICKHOUSE_PASSWORD")
+	if err := cfg.Audit.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid audit config: %w", err)
+	}
+
+	cfg.RateLimit = fileCfg.RateLimit
+	cfg.Limits = fileCfg.Limits
+	if err := cfg.Limits.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid limits config: %w", err)
+	}
 
+	webhooks := fileCfg.Ingest.Webhooks
+	cfg.CMSWebhooks.GitHubTopic = webhooks.GitHub.Topic
+	cfg.CMSWebhooks.GitHubRepositories = webhooks.GitHub.Repositories
+	cfg.CMSWebhooks.NotionTopic = webhooks.Notion.Topic
+	cfg.CMSWebhooks.ConfluenceBaseURL = webhooks.Confluence.BaseURL
+
+	// Load the router's configuration and the model catalog from the configuration file.
+	if err := yaml.Unmarshal(configFile, &cfg.RouterConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse router config.yaml: %w", err)
+	}
+	if err := cfg.RouterConfig.ValidateThresholds(); err != nil {
+		return nil, fmt.Errorf("invalid pre_check_thresholds in config.yaml: %w", err)
+	}
+	if err := cfg.RouterConfig.ValidateRequestPolicies(); err != nil {
+		return nil, fmt.Errorf("invalid request_policy in config.yaml: %w", err)
+	}
+	if err := cfg.RouterConfig.Transport.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid http_transport in config.yaml: %w", err)
+	}
+	if err := cfg.RouterConfig.Budget.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid budget_policy in config.yaml: %w", err)
+	}
+	if err := cfg.RouterConfig.ValidateExploration(); err != nil {
+		return nil, fmt.Errorf("invalid strategies in config.yaml: %w", err)
+	}
+	if err := cfg.RouterConfig.StaleStats.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid stale_stats in config.yaml: %w", err)
+	}
+	if err := cfg.RouterConfig.ValidateAliases(); err != nil {
+		return nil, fmt.Errorf("invalid aliases in config.yaml: %w", err)
+	}
+
+	cfg.EnabledModels = enabledModels(cfg.RouterConfig)
+	if len(cfg.EnabledModels) == 0 {
+		return nil, fmt.Errorf("no models are enabled: add them under `models` in config.yaml")
+	}
+
+	for _, modelID := range cfg.EnabledModels {
 		var apiKey string
-		// This switch statement maps model prefixes to the general API key name.
-		switch {
-		case strings.HasPrefix(modelID, "gpt"):
-			apiKey = os.Getenv("OPENAI_API_KEY")
-		case strings.HasPrefix(modelID, "claude"):
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
-		case strings.HasPrefix(modelID, "gemini"):
-			apiKey = os.Getenv("GEMINI_API_KEY")
-		case strings.HasPrefix(modelID, "mistral"):
-			apiKey = os.Getenv("MISTRAL_API_KEY")
+		if env := providerKeyEnv(cfg.RouterConfig.ProviderOf(modelID)); env != "" {
+			apiKey = os.Getenv(env)
 		}
 
+		if _, ok := secrets.ParseReference(apiKey); ok {
+			cfg.APIKeyRefs[modelID] = apiKey
+		}
+		apiKey, err := cfg.Secrets.Resolve(context.Background(), apiKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the API key for %s: %w", modelID, err)
+		}
 		if apiKey != "" {
 			cfg.APIKeys[modelID] = apiKey
 		}
 
-		// --- THIS IS THE FIX ---
-		// Sanitize the modelID to create a standard environment variable name.
-		// This now replaces both hyphens '-' and dots '.' with underscores '_'.
-		sanitizedModelID := strings.ReplaceAll(strings.ReplaceAll(modelID, "-", "_"), ".", "_")
-		// --- END OF FIX ---
-
-		// Costs (per million tokens)
-		envCostInput := fmt.Sprintf("%s_COST_INPUT", strings.ToUpper(sanitizedModelID))
-		envCostOutput := fmt.Sprintf("%s_COST_OUTPUT", strings.ToUpper(sanitizedModelID))
-		costInput, errI := strconv.ParseFloat(os.Getenv(envCostInput), 64)
-		costOutput, errO := strconv.ParseFloat(os.Getenv(envCostOutput), 64)
-		if errI == nil && errO == nil {
+		meta := cfg.RouterConfig.Models[modelID]
+		if meta.Costs == nil {
+			meta.Costs = legacyModelCosts(modelID)
+		}
+		if meta.Costs != nil {
 			cfg.ModelCosts[modelID] = map[string]float64{
-				"input":  costInput / 1_000_000,
-				"output": costOutput / 1_000_000,
+				"input":  meta.Costs.Input / 1_000_000,
+				"output": meta.Costs.Output / 1_000_000,
+			}
+			if meta.Costs.Reasoning > 0 {
+				cfg.ModelCosts[modelID]["reasoning"] = meta.Costs.Reasoning / 1_000_000
+			}
+			if meta.Costs.CachedInput > 0 {
+				cfg.ModelCosts[modelID]["cached_input"] = meta.Costs.CachedInput / 1_000_000
+			}
+			if meta.Costs.CacheWrite > 0 {
+				cfg.ModelCosts[modelID]["cache_write"] = meta.Costs.CacheWrite / 1_000_000
 			}
 		}
-
-		// Budgets (USD)
-		envBudget := fmt.Sprintf("%s_BUDGET_USD", strings.ToUpper(sanitizedModelID))
-		if budget, err := strconv.ParseFloat(os.Getenv(envBudget), 64); err == nil {
-			cfg.ModelBudgets[modelID] = budget
+		if meta.BudgetUSD == 0 {
+			meta.BudgetUSD = legacyModelBudget(modelID)
+		}
+		if meta.BudgetUSD > 0 {
+			cfg.ModelBudgets[modelID] = meta.BudgetUSD
 		}
 	}
 
-	// Load the router's configuration from its YAML file.
-	routerConfigFile, err := os.ReadFile("config.yaml")
+	cfg.HTTPTools = fileCfg.Tools
+	cfg.Tenants = fileCfg.Tenants
+	cfg.PII = fileCfg.PII
+	cfg.Concurrency = fileCfg.Concurrency
+	if err := cfg.Concurrency.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid concurrency config: %w", err)
+	}
+	cfg.HealthCheck = fileCfg.HealthCheck
+	if err := cfg.HealthCheck.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid health_check config: %w", err)
+	}
+	cfg.UserBudgets = fileCfg.UserBudgets
+	if err := cfg.UserBudgets.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid user_budgets config: %w", err)
+	}
+	cfg.ProfileHistory = fileCfg.ProfileHistory
+	if err := cfg.ProfileHistory.withDefaults().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid profile_history config: %w", err)
+	}
+	cfg.Intents = fileCfg.Intents.WithDefaults()
+	if err := cfg.Intents.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid intents config: %w", err)
+	}
+	cfg.PromptAnalysis = fileCfg.PromptAnalysis.WithDefaults()
+	if err := cfg.PromptAnalysis.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid prompt_analysis config: %w", err)
+	}
+	cfg.Async = fileCfg.Async
+	if err := cfg.Async.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid async config: %w", err)
+	}
+	cfg.Async.SigningSecret = os.Getenv("ASYNC_CALLBACK_SECRET")
+	cfg.Streaming = fileCfg.Streaming
+	if err := cfg.Streaming.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid streaming config: %w", err)
+	}
+	switch cfg.PII.Mode {
+	case "":
+		cfg.PII.Mode = pii.ModeOff
+	case pii.ModeOff, pii.ModeDetect, pii.ModeMask:
+	default:
+		return nil, fmt.Errorf("unknown pii mode '%s' (expected '%s', '%s', or '%s')", cfg.PII.Mode, pii.ModeOff, pii.ModeDetect, pii.ModeMask)
+	}
+	cfg.Moderation = fileCfg.Moderation
+	switch cfg.Moderation.Provider {
+	case "":
+		cfg.Moderation.Provider = moderation.ProviderNone
+	case moderation.ProviderNone, moderation.ProviderOpenAI, moderation.ProviderLlamaGuard:
+	default:
+		return nil, fmt.Errorf("unknown moderation provider '%s' (expected '%s', '%s', or '%s')", cfg.Moderation.Provider, moderation.ProviderNone, moderation.ProviderOpenAI, moderation.ProviderLlamaGuard)
+	}
+	cfg.Passthrough = fileCfg.Passthrough
+	if cfg.Passthrough.Enabled && (cfg.PII.Mode != pii.ModeOff || cfg.Moderation.Provider != moderation.ProviderNone) {
+		return nil, fmt.Errorf("passthrough cannot be enabled together with pii or moderation, which must read every request")
+	}
+	cfg.ModerationAPIKey, err = cfg.Secrets.Resolve(context.Background(), getEnvOrDefault("MODERATION_API_KEY", os.Getenv("OPENAI_API_KEY")))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read router config.yaml: %w", err)
+		return nil, fmt.Errorf("failed to resolve the moderation API key: %w", err)
 	}
-	if err := yaml.Unmarshal(routerConfigFile, &cfg.RouterConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse router config.yaml: %w", err)
+	cfg.Sessions = llm.DefaultSessionPolicy().Merge(fileCfg.Sessions)
+	if err := cfg.Sessions.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid sessions config: %w", err)
+	}
+	for name, tenant := range cfg.Tenants {
+		if err := cfg.Sessions.Merge(tenant.Sessions).Validate(); err != nil {
+			return nil, fmt.Errorf("invalid sessions config for tenant '%s': %w", name, err)
+		}
+		if err := tenant.Priority.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid priority for tenant '%s': %w", name, err)
+		}
+	}
+
+	// The minimal profile only uses the RAG service for its Redis response cache,
+	// so Pinecone and embedding credentials are not required.
+	if cfg.IsMinimal() {
+		cfg.RAGConfig = &llm.Config{RedisAddr: cfg.RedisAddr}
+		return cfg, nil
 	}
 
 	// Load RAG config (example)
@@ -115,7 +734,169 @@ func LoadConfig() (*AppConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load RAG config: %w", err)
 	}
+	// The embedding and Pinecone clients are created once, so their keys are resolved but not refreshed.
+	if ragCfg.OpenAIKey, err = cfg.Secrets.Resolve(context.Background(), ragCfg.OpenAIKey); err != nil {
+		return nil, fmt.Errorf("failed to resolve the embedding API key: %w", err)
+	}
+	if ragCfg.PineconeKey, err = cfg.Secrets.Resolve(context.Background(), ragCfg.PineconeKey); err != nil {
+		return nil, fmt.Errorf("failed to resolve the Pinecone API key: %w", err)
+	}
 	cfg.RAGConfig = ragCfg
+	if cfg.Chunking, err = ingest.LoadChunkingConfig(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
+
+// providerKeyEnv returns the environment variable that holds the API key for a provider,
+// or "" if the gateway has no client for the provider.
+func providerKeyEnv(provider string) string {
+	switch provider {
+	case "openai":
+		return "OPENAI_API_KEY"
+	case "anthropic":
+		return "ANTHROPIC_API_KEY"
+	case "google":
+		return "GEMINI_API_KEY"
+	case "mistral":
+		return "MISTRAL_API_KEY"
+	}
+	return ""
+}
+
+// splitList parses a comma-separated environment variable, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// costEnvPrefix returns the prefix of a model's deprecated cost and budget environment
+// variables, e.g. "GPT_4O" for gpt-4o.
+func costEnvPrefix(modelID string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(modelID, "-", "_"), ".", "_"))
+}
+
+// enabledModels returns the models in rotation: those under `models` in config.yaml that are
+// not disabled, in alphabetical order. The deprecated ENABLED_MODELS variable still takes
+// precedence, so existing deployments keep their selection while they migrate.
+func enabledModels(routerConfig *llm.RouterConfig) []string {
+	if legacy := config.Legacy("", "ENABLED_MODELS", "models.<id>.enabled"); legacy != "" {
+		return strings.Split(legacy, ",")
+	}
+	var models []string
+	for modelID, meta := range routerConfig.Models {
+		if meta.IsEnabled() {
+			models = append(models, modelID)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// legacyModelCosts reads a model's costs from the deprecated <MODEL>_COST_INPUT and
+// <MODEL>_COST_OUTPUT variables. It returns nil unless both are set.
+func legacyModelCosts(modelID string) *llm.ModelCosts {
+	envPrefix := costEnvPrefix(modelID)
+	input, errI := strconv.ParseFloat(config.Legacy("", envPrefix+"_COST_INPUT", "models."+modelID+".costs"), 64)
+	output, errO := strconv.ParseFloat(config.Legacy("", envPrefix+"_COST_OUTPUT", "models."+modelID+".costs"), 64)
+	if errI != nil || errO != nil {
+		return nil
+	}
+	return &llm.ModelCosts{Input: input, Output: output}
+}
+
+// legacyModelBudget reads a model's budget from the deprecated <MODEL>_BUDGET_USD variable.
+func legacyModelBudget(modelID string) float64 {
+	budget, _ := strconv.ParseFloat(config.Legacy("", costEnvPrefix(modelID)+"_BUDGET_USD", "models."+modelID+".budget_usd"), 64)
+	return budget
+}
+
+// IsMinimal reports whether the gateway runs the minimal routing-only profile.
+func (c *AppConfig) IsMinimal() bool {
+	return c.Profile == ProfileMinimal
+}
+
+// TenantFor returns the configuration of the named tenant. Unknown or empty tenant IDs
+// fall back to the "default" tenant, so omitting the tenant can never bypass a restriction.
+func (c *AppConfig) TenantFor(tenantID string) TenantConfig {
+	if tenant, ok := c.Tenants[tenantID]; ok && tenantID != "" {
+		return tenant
+	}
+	return c.Tenants[DefaultTenant]
+}
+
+// SessionPolicyFor returns the session policy of a tenant: the default policy with the
+// tenant's overrides applied.
+func (c *AppConfig) SessionPolicyFor(tenantID string) llm.SessionPolicy {
+	return c.Sessions.Merge(c.TenantFor(tenantID).Sessions)
+}
+
+// RateLimitFor returns the rate limits that apply to the named tenant's callers.
+func (c *AppConfig) RateLimitFor(tenantID string) ratelimit.Limits {
+	if limits := c.TenantFor(tenantID).RateLimit; limits != nil {
+		return *limits
+	}
+	return c.RateLimit
+}
+
+// getEnvOrDefault reads an environment variable, falling back to a default if it is unset or empty.
+func getEnvOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newSecretsManager registers a provider for each secrets manager. Vault and AWS need their
+// standard environment variables; Google Cloud uses Application Default Credentials.
+func newSecretsManager() *secrets.Manager {
+	manager := secrets.NewManager()
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" {
+		manager.Register(secrets.SchemeVault, secrets.NewVaultProvider(addr, os.Getenv("VAULT_TOKEN"), os.Getenv("VAULT_NAMESPACE")))
+	}
+	if region := getEnvOrDefault("AWS_REGION", os.Getenv("AWS_DEFAULT_REGION")); region != "" {
+		manager.Register(secrets.SchemeAWS, secrets.NewAWSProvider(region, secrets.AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}))
+	}
+	manager.Register(secrets.SchemeGCP, secrets.NewGCPProvider())
+	return manager
+}
+
+// Validate reports negative limits.
+func (l RequestLimits) Validate() error {
+	if l.MaxBodyBytes < 0 || l.MaxPromptChars < 0 || l.MaxHistoryMessages < 0 || l.MaxHistoryChars < 0 || l.MaxTokens < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+// Validate reports an unknown sink, a sink without its connection settings, and writer
+// settings that cannot work.
+func (a AuditConfig) Validate() error {
+	if a.QueueSize <= 0 || a.BatchSize <= 0 || a.FlushInterval <= 0 {
+		return fmt.Errorf("queue_size, batch_size, and flush_interval must be positive")
+	}
+	switch a.Sink {
+	case "":
+	case "postgres":
+		if a.DSN == "" {
+			return fmt.Errorf("AUDIT_DSN is required when the sink is 'postgres'")
+		}
+	case "clickhouse":
+		if a.ClickHouseURL == "" {
+			return fmt.Errorf("AUDIT_CLICKHOUSE_URL is required when the sink is 'clickhouse'")
+		}
+	default:
+		return fmt.Errorf("unknown sink '%s' (expected 'postgres' or 'clickhouse')", a.Sink)
+	}
+	return nil
+}
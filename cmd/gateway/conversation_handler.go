@@ -0,0 +1,169 @@
+// In file: cmd/gateway/conversation_handler.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userHeader identifies the end-user on whose behalf conversation endpoints are called.
+// Like the tenant header, it must be set by an authenticating proxy in front of the gateway.
+const userHeader = "X-User-ID"
+
+// ConversationHandler serves the conversation management API, so product frontends can
+// list, read, rename, delete, and export chats without keeping their own copy of them.
+// A user can only see and modify the conversations they own.
+type ConversationHandler struct {
+	conversations llm.ConversationStore
+}
+
+func NewConversationHandler(conversations llm.ConversationStore) *ConversationHandler {
+	return &ConversationHandler{
+		conversations: conversations,
+	}
+}
+
+// conversationResponse is returned when fetching a single conversation.
+type conversationResponse struct {
+	Conversation *llm.ConversationInfo `json:"conversation"`
+	Messages     []api.Message         `json:"messages"`
+}
+
+// HandleList lists the caller's conversations, most recently updated first.
+// GET /api/v1/conversations
+func (h *ConversationHandler) HandleList(c *gin.Context) {
+	userID := c.GetHeader(userHeader)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The %s header is required.", userHeader)})
+		return
+	}
+	conversations, err := h.conversations.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations})
+}
+
+// HandleGet returns a conversation's metadata and transcript.
+// GET /api/v1/conversations/:id
+func (h *ConversationHandler) HandleGet(c *gin.Context) {
+	info, ok := h.loadOwnedConversation(c)
+	if !ok {
+		return
+	}
+	messages, err := h.conversations.Load(c.Request.Context(), info.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, conversationResponse{Conversation: info, Messages: messages})
+}
+
+// HandleRename changes a conversation's title.
+// PATCH /api/v1/conversations/:id  {"title": "..."}
+func (h *ConversationHandler) HandleRename(c *gin.Context) {
+	var body struct {
+		Title string `json:"title" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	info, ok := h.loadOwnedConversation(c)
+	if !ok {
+		return
+	}
+	if err := h.conversations.Rename(c.Request.Context(), info.ID, body.Title); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	info.Title = body.Title
+	c.JSON(http.StatusOK, gin.H{"conversation": info})
+}
+
+// HandleDelete permanently removes a conversation.
+// DELETE /api/v1/conversations/:id
+func (h *ConversationHandler) HandleDelete(c *gin.Context) {
+	info, ok := h.loadOwnedConversation(c)
+	if !ok {
+		return
+	}
+	if err := h.conversations.Delete(c.Request.Context(), info.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// HandleExport downloads a conversation as JSON (default) or Markdown.
+// GET /api/v1/conversations/:id/export?format=json|markdown
+func (h *ConversationHandler) HandleExport(c *gin.Context) {
+	info, ok := h.loadOwnedConversation(c)
+	if !ok {
+		return
+	}
+	messages, err := h.conversations.Load(c.Request.Context(), info.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s.json"`, info.ID))
+		c.JSON(http.StatusOK, conversationResponse{Conversation: info, Messages: messages})
+	case "markdown", "md":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s.md"`, info.ID))
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(renderConversationMarkdown(info, messages)))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported export format '%s'. Use 'json' or 'markdown'.", format)})
+	}
+}
+
+// loadOwnedConversation fetches the conversation named in the URL and verifies that it belongs
+// to the caller. Conversations owned by someone else are reported as not found, so their
+// existence is not revealed. On failure an error response has already been sent.
+func (h *ConversationHandler) loadOwnedConversation(c *gin.Context) (*llm.ConversationInfo, bool) {
+	userID := c.GetHeader(userHeader)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The %s header is required.", userHeader)})
+		return nil, false
+	}
+	info, err := h.conversations.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, llm.ErrConversationNotFound) || (err == nil && info.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found."})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return info, true
+}
+
+// renderConversationMarkdown formats a transcript as a readable Markdown document.
+func renderConversationMarkdown(info *llm.ConversationInfo, messages []api.Message) string {
+	var b strings.Builder
+	title := info.Title
+	if title == "" {
+		title = "Conversation " + info.ID
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "_Started %s · Last updated %s_\n\n", info.CreatedAt.Format("2006-01-02 15:04 MST"), info.UpdatedAt.Format("2006-01-02 15:04 MST"))
+	for _, msg := range messages {
+		role := msg.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", role, msg.Content)
+	}
+	return b.String()
+}
@@ -0,0 +1,218 @@
+// In file: cmd/gateway/document_handler.go
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/ingest"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxDocumentBytes limits the size of an uploaded document.
+	maxDocumentBytes = 20 << 20
+	// defaultDocumentTopic is the topic of uploaded documents that do not name one.
+	defaultDocumentTopic = "uploads"
+)
+
+// DocumentHandler serves the document API, which adds knowledge to the RAG index without a
+// run of the offline ingestor. Uploads are chunked and embedded while the caller waits, and
+// each user can only list and delete the documents they uploaded.
+type DocumentHandler struct {
+	pipeline  *ingest.Pipeline
+	documents ingest.DocumentStore
+}
+
+func NewDocumentHandler(pipeline *ingest.Pipeline, documents ingest.DocumentStore) *DocumentHandler {
+	return &DocumentHandler{
+		pipeline:  pipeline,
+		documents: documents,
+	}
+}
+
+// textDocumentRequest is the JSON form of an upload.
+type textDocumentRequest struct {
+	Title string `json:"title"`
+	Topic string `json:"topic"`
+	Text  string `json:"text" binding:"required"`
+}
+
+// HandleUpload ingests a document sent either as a multipart file (field "file", with optional
+// "title" and "topic" fields) or as JSON text, and responds with the stored document.
+// POST /api/v1/documents
+func (h *DocumentHandler) HandleUpload(c *gin.Context) {
+	userID := c.GetHeader(userHeader)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The %s header is required.", userHeader)})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDocumentBytes)
+
+	doc := &ingest.Document{
+		ID:        newDocumentID(),
+		Owner:     userID,
+		Namespace: c.GetHeader(tenantHeader),
+		CreatedAt: time.Now().UTC(),
+	}
+	var pages []ingest.Page
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		var status int
+		var err error
+		if pages, status, err = h.readFile(c, doc); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		var req textDocumentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": "Invalid request: " + err.Error()})
+			return
+		}
+		doc.Title, doc.Topic, doc.Bytes = req.Title, req.Topic, len(req.Text)
+		pages = []ingest.Page{{Text: req.Text}}
+	}
+	if doc.Title == "" {
+		doc.Title = doc.Filename
+	}
+	if doc.Title == "" {
+		doc.Title = "Untitled document"
+	}
+	if doc.Topic == "" {
+		doc.Topic = defaultDocumentTopic
+	}
+
+	ctx := c.Request.Context()
+	err := h.pipeline.IngestDocument(ctx, doc, pages)
+	if errors.Is(err, ingest.ErrEmptyDocument) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The document contains no text to ingest."})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Document ingestion failed", "document_id", doc.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.documents.Save(ctx, doc); err != nil {
+		// Without a catalog entry nobody could delete the vectors, so remove them again.
+		if removeErr := h.pipeline.RemoveDocument(ctx, doc); removeErr != nil {
+			slog.ErrorContext(ctx, "Failed to remove vectors of an unsaved document", "document_id", doc.ID, "error", removeErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"document": doc})
+}
+
+// readFile reads and extracts the uploaded file, filling in the document's name, title,
+// topic, and size. On failure it returns the status to respond with.
+func (h *DocumentHandler) readFile(c *gin.Context, doc *ingest.Document) ([]ingest.Page, int, error) {
+	header, err := c.FormFile("file")
+	if status := uploadErrorStatus(err); status == http.StatusRequestEntityTooLarge {
+		return nil, status, fmt.Errorf("the document exceeds the limit of %d bytes", maxDocumentBytes)
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("a 'file' form field is required: %w", err)
+	}
+	doc.Filename = filepath.Base(header.Filename)
+	doc.Title = c.PostForm("title")
+	doc.Topic = c.PostForm("topic")
+
+	extractor, ok := ingest.ExtractorFor(doc.Filename)
+	if !ok {
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported file type '%s' (supported: %s)",
+			filepath.Ext(doc.Filename), strings.Join(ingest.SupportedExtensions(), ", "))
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("failed to read the uploaded file: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("failed to read the uploaded file: %w", err)
+	}
+	doc.Bytes = len(data)
+	pages, err := extractor.Extract(data)
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, fmt.Errorf("failed to extract text from %s: %w", doc.Filename, err)
+	}
+	return pages, http.StatusOK, nil
+}
+
+// HandleList lists the caller's documents, newest first.
+// GET /api/v1/documents
+func (h *DocumentHandler) HandleList(c *gin.Context) {
+	userID := c.GetHeader(userHeader)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The %s header is required.", userHeader)})
+		return
+	}
+	all, err := h.documents.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	namespace := c.GetHeader(tenantHeader)
+	documents := make([]ingest.Document, 0, len(all))
+	for _, doc := range all {
+		if doc.Namespace == namespace {
+			documents = append(documents, doc)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"documents": documents})
+}
+
+// HandleDelete removes a document's vectors from the index and the document itself.
+// DELETE /api/v1/documents/:id
+func (h *DocumentHandler) HandleDelete(c *gin.Context) {
+	userID := c.GetHeader(userHeader)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The %s header is required.", userHeader)})
+		return
+	}
+	ctx := c.Request.Context()
+	doc, err := h.documents.Get(ctx, c.Param("id"))
+	// Documents of other users or tenants are reported as not found, so their existence is not revealed.
+	if errors.Is(err, ingest.ErrDocumentNotFound) || (err == nil && (doc.Owner != userID || doc.Namespace != c.GetHeader(tenantHeader))) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.pipeline.RemoveDocument(ctx, doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.documents.Delete(ctx, doc.ID); err != nil && !errors.Is(err, ingest.ErrDocumentNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// uploadErrorStatus distinguishes an upload over the size limit from a malformed one.
+func uploadErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func newDocumentID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,113 @@
+// In file: cmd/gateway/drain.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shutdownCode is the error code of the terminal event sent to streams a shutdown interrupts,
+// and of streaming requests refused while the gateway drains. Clients should retry elsewhere.
+const shutdownCode = "server_shutting_down"
+
+// streamInterruptGrace is the part of the drain window kept back for interrupted streams to
+// send their terminal event and return before the server stops waiting for them.
+const streamInterruptGrace = 2 * time.Second
+
+// errServerShuttingDown is the cancellation cause of streams interrupted by a shutdown.
+var errServerShuttingDown = errors.New("the gateway is shutting down")
+
+// streamTracker tracks in-flight streaming responses, so a shutdown can let them finish and
+// interrupt the ones still running at the end of the drain window with a terminal event,
+// instead of cutting them off mid-token.
+type streamTracker struct {
+	mu       sync.Mutex
+	draining bool
+	nextID   uint64
+	active   map[uint64]context.CancelCauseFunc
+	wg       sync.WaitGroup
+}
+
+func newStreamTracker() *streamTracker {
+	return &streamTracker{active: make(map[uint64]context.CancelCauseFunc)}
+}
+
+// begin registers a stream. It returns the context the stream must run under and a function
+// to call when the stream ends. ok is false once draining has started; the stream must then
+// be refused.
+func (t *streamTracker) begin(ctx context.Context) (streamCtx context.Context, done func(), ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return ctx, nil, false
+	}
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	id := t.nextID
+	t.nextID++
+	t.active[id] = cancel
+	t.wg.Add(1)
+	return streamCtx, func() {
+		t.mu.Lock()
+		delete(t.active, id)
+		t.mu.Unlock()
+		cancel(nil)
+		t.wg.Done()
+	}, true
+}
+
+// count returns the number of streams in flight.
+func (t *streamTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// drain stops new streams from starting and waits until every active stream has finished or
+// ctx is done. It reports whether all streams finished.
+func (t *streamTracker) drain(ctx context.Context) bool {
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// interrupt cancels every stream still in flight with errServerShuttingDown.
+func (t *streamTracker) interrupt() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, cancel := range t.active {
+		cancel(errServerShuttingDown)
+	}
+}
+
+// interruptedByShutdown reports whether ctx was cancelled because the gateway is shutting down.
+func interruptedByShutdown(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errServerShuttingDown)
+}
+
+// writeShutdownEvent ends an interrupted stream with a terminal event telling the client to retry.
+func writeShutdownEvent(c *gin.Context) {
+	writeSSE(c, eventError, gin.H{"error": "The gateway is shutting down; retry the request.", "code": shutdownCode})
+}
+
+// refuseWhileDraining answers a streaming request that arrives after draining has started.
+func refuseWhileDraining(c *gin.Context) {
+	c.Header("Connection", "close")
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The gateway is shutting down; retry the request.", "code": shutdownCode})
+}
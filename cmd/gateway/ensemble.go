@@ -0,0 +1,319 @@
+// In file: cmd/gateway/ensemble.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/gin-gonic/gin"
+)
+
+// The methods an ensemble's answer can be selected with.
+const (
+	ensembleJudge     = "judge"
+	ensembleMerge     = "merge"
+	ensembleConsensus = "consensus"
+)
+
+const (
+	// maxEnsembleCandidates bounds how many answers, and so how many calls, one request costs.
+	maxEnsembleCandidates = 8
+	// defaultEnsembleSamples is how many answers the routed model gives when it answers alone.
+	defaultEnsembleSamples = 3
+	// ensembleJudgeMaxTokens is enough for the judge to name an answer.
+	ensembleJudgeMaxTokens = 10
+)
+
+// ensembleJudgeInstruction and ensembleMergeInstruction are the judge's instructions.
+const (
+	ensembleJudgeInstruction = "You are given several numbered answers to a user's message. Reply with only the number of the best answer: the most correct, complete, and helpful one."
+	ensembleMergeInstruction = "You are given several numbered answers to a user's message. Write the single best answer to the message, combining the correct and useful parts of the answers and leaving out their mistakes. Reply with that answer only."
+)
+
+// judgeVerdict finds the number the judge replied with.
+var judgeVerdict = regexp.MustCompile(`\d+`)
+
+// ensembleOutcome is the selected answer of an ensemble and what all its calls used.
+type ensembleOutcome struct {
+	answer    *llm.GenerationResult
+	modelUsed string
+	usage     api.Usage
+	cost      float64
+	result    *api.EnsembleResult
+}
+
+// validateEnsemble rejects ensembles that cannot be generated, and fills in the defaults of
+// the ones that can.
+func validateEnsemble(req *api.GenerationRequest, enabledModels []string) error {
+	opts := req.Ensemble
+	if opts == nil {
+		return nil
+	}
+	switch {
+	case req.Config.Stream:
+		return errors.New("ensemble cannot be combined with streaming")
+	case len(req.Tools) > 0:
+		return errors.New("ensemble cannot be combined with tools")
+	case opts.Samples < 0:
+		return errors.New("ensemble samples must not be negative")
+	}
+	if opts.Method == "" {
+		opts.Method = ensembleJudge
+	}
+	switch opts.Method {
+	case ensembleJudge, ensembleConsensus:
+	case ensembleMerge:
+		if len(answerSchema(req)) > 0 {
+			return errors.New("a merged ensemble answer cannot be held to a response schema; use the 'judge' method")
+		}
+	default:
+		return fmt.Errorf("unknown ensemble method '%s' (expected '%s', '%s', or '%s')", opts.Method, ensembleJudge, ensembleMerge, ensembleConsensus)
+	}
+	for _, modelID := range append(slices.Clone(opts.Models), opts.JudgeModel) {
+		if modelID != "" && !slices.Contains(enabledModels, modelID) {
+			return fmt.Errorf("ensemble model '%s' is not an enabled model", modelID)
+		}
+	}
+	if opts.Samples == 0 {
+		opts.Samples = 1
+		if len(opts.Models) == 0 {
+			opts.Samples = defaultEnsembleSamples
+		}
+	}
+	// The routed model is not known yet; it answers besides the listed models.
+	switch n := (len(opts.Models) + 1) * opts.Samples; {
+	case n < 2:
+		return errors.New("an ensemble needs at least two answers: list models, or ask for samples")
+	case n > maxEnsembleCandidates:
+		return fmt.Errorf("an ensemble can have at most %d answers, got %d", maxEnsembleCandidates, n)
+	}
+	return nil
+}
+
+// ensembleCacheKey identifies an ensemble in cache keys.
+func ensembleCacheKey(opts *api.EnsembleOptions) string {
+	if opts == nil {
+		return ""
+	}
+	return fmt.Sprintf("%q/%d/%s/%s/%t", opts.Models, opts.Samples, opts.Method, opts.JudgeModel, opts.ReturnCandidates)
+}
+
+// executeEnsemble has every candidate of the request's ensemble answer the (possibly
+// RAG-augmented) prompt concurrently, and selects the answer as the ensemble's method asks.
+// Every call is recorded against its own model's profile and spend.
+func (h *GatewayHandler) executeEnsemble(c *gin.Context, req api.GenerationRequest, modelID string, trace *api.DecisionTrace) (*ensembleOutcome, *api.RAGDecision, error) {
+	opts := req.Ensemble
+	finalPrompt, ragDecision, err := h.augmentPrompt(c, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	models := []string{modelID}
+	for _, m := range opts.Models {
+		if !slices.Contains(models, m) {
+			models = append(models, m)
+		}
+	}
+	result := &api.EnsembleResult{Method: opts.Method, Chosen: -1}
+	for _, m := range models {
+		for range opts.Samples {
+			result.Candidates = append(result.Candidates, api.EnsembleCandidate{Model: m})
+		}
+	}
+	slog.InfoContext(c.Request.Context(), "Generating ensemble", "candidates", len(result.Candidates), "models", models, "method", opts.Method)
+
+	answers := make([]*llm.GenerationResult, len(result.Candidates))
+	var wg sync.WaitGroup
+	for i := range result.Candidates {
+		// Only the first answer is traced, as the others are built the same way.
+		candidateTrace := trace
+		if i > 0 {
+			candidateTrace = &api.DecisionTrace{}
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			answers[i] = h.ensembleCandidate(c, req, &result.Candidates[i], modelID, finalPrompt, candidateTrace)
+		}(i)
+	}
+	wg.Wait()
+
+	outcome := &ensembleOutcome{result: result}
+	var answered []int
+	for i, candidate := range result.Candidates {
+		outcome.usage.Add(candidate.Usage)
+		outcome.cost += llm.CallCost(candidate.Model, candidate.Usage)
+		if answers[i] != nil {
+			answered = append(answered, i)
+		}
+	}
+	if len(answered) == 0 {
+		return nil, ragDecision, fmt.Errorf("no model of the ensemble answered: %s", result.Candidates[0].Error)
+	}
+
+	if len(answered) > 1 && opts.Method != ensembleConsensus {
+		judge := opts.JudgeModel
+		if judge == "" {
+			judge = modelID
+		}
+		result.JudgeModel = judge
+		h.judgeEnsemble(c, req, outcome, judge, modelID, finalPrompt, answers, answered)
+	}
+	if outcome.answer == nil {
+		if result.Chosen < 0 {
+			result.Chosen = consensusAnswer(answers, answered)
+		}
+		outcome.answer, outcome.modelUsed = answers[result.Chosen], result.Candidates[result.Chosen].Model
+	}
+	if opts.ReturnCandidates {
+		for _, i := range answered {
+			result.Candidates[i].Content = answers[i].Content
+		}
+	}
+	return outcome, ragDecision, nil
+}
+
+// ensembleCandidate has one candidate of an ensemble answer the prompt, recording the call.
+// It returns nil, with the candidate's Error set, if there is no answer.
+func (h *GatewayHandler) ensembleCandidate(c *gin.Context, req api.GenerationRequest, candidate *api.EnsembleCandidate, routedModel, finalPrompt string, trace *api.DecisionTrace) *llm.GenerationResult {
+	ctx := c.Request.Context()
+	// The request already holds a slot of the routed model.
+	if candidate.Model != routedModel {
+		release, err := h.concurrency.AcquireModel(ctx, candidate.Model, requestPriority(c, h.config))
+		if err != nil {
+			candidate.Error = err.Error()
+			return nil
+		}
+		defer release()
+	}
+	start := time.Now()
+	answer, usage, err := h.generateAnswer(c, req, candidate.Model, finalPrompt, trace)
+	latency := time.Since(start)
+	candidate.Usage, candidate.LatencyMS = usage, latency.Milliseconds()
+
+	// An answer that never matched the response schema was still generated and paid for.
+	var schemaErr *schemaValidationError
+	if err == nil || errors.As(err, &schemaErr) {
+		h.profiler.UpdateProfileOnSuccess(ctx, candidate.Model, latency, usage)
+		h.profiler.RecordSpend(ctx, candidate.Model, req.UserID, req.ConversationID, usage)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Ensemble candidate failed", "model", candidate.Model, "error", err)
+		candidate.Error = err.Error()
+		return nil
+	}
+	return answer
+}
+
+// judgeEnsemble has the judge pick the best of the answered candidates, or merge them,
+// recording the choice, or the merged answer, in the outcome. If the judge fails, the choice
+// is left to the consensus.
+func (h *GatewayHandler) judgeEnsemble(c *gin.Context, req api.GenerationRequest, outcome *ensembleOutcome, judge, routedModel, finalPrompt string, answers []*llm.GenerationResult, answered []int) {
+	ctx := c.Request.Context()
+	result := outcome.result
+	instruction, maxTokens := ensembleJudgeInstruction, ensembleJudgeMaxTokens
+	if result.Method == ensembleMerge {
+		instruction, maxTokens = ensembleMergeInstruction, req.Config.MaxTokens
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Message:\n%s", finalPrompt)
+	for n, i := range answered {
+		fmt.Fprintf(&b, "\n\nAnswer %d:\n%s", n+1, answers[i].Content)
+	}
+
+	verdict, err := func() (*llm.GenerationResult, error) {
+		client := h.clients[judge]
+		if client == nil {
+			return nil, fmt.Errorf("no client available for model %s", judge)
+		}
+		if judge != routedModel {
+			release, err := h.concurrency.AcquireModel(ctx, judge, requestPriority(c, h.config))
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+		messages := []llm.Message{
+			{Role: llm.RoleSystem, Content: instruction},
+			{Role: llm.RoleUser, Content: b.String()},
+		}
+		temperature := float32(0)
+		start := time.Now()
+		verdict, err := client.Generate(ctx, messages, &llm.GenerationConfig{Model: judge, MaxTokens: maxTokens, Temperature: &temperature}, nil)
+		if err != nil {
+			h.profiler.UpdateProfileOnFailure(ctx, judge)
+			return nil, fmt.Errorf("judge call failed: %w", err)
+		}
+		h.profiler.UpdateProfileOnSuccess(ctx, judge, time.Since(start), verdict.Usage)
+		h.profiler.RecordSpend(ctx, judge, req.UserID, req.ConversationID, verdict.Usage)
+		outcome.usage.Add(verdict.Usage)
+		outcome.cost += llm.CallCost(judge, verdict.Usage)
+		return verdict, nil
+	}()
+	if err == nil && result.Method == ensembleMerge {
+		if strings.TrimSpace(verdict.Content) != "" {
+			outcome.answer, outcome.modelUsed = verdict, judge
+			return
+		}
+		err = errors.New("the judge merged the answers into an empty answer")
+	}
+	if err == nil {
+		n, convErr := strconv.Atoi(judgeVerdict.FindString(verdict.Content))
+		if convErr != nil || n < 1 || n > len(answered) {
+			err = fmt.Errorf("the judge answered with no valid answer number: %q", verdict.Content)
+		} else {
+			result.Chosen = answered[n-1]
+			return
+		}
+	}
+	slog.WarnContext(ctx, "Ensemble judge failed. Selecting the answer by consensus", "judge", judge, "error", err)
+	result.JudgeError = err.Error()
+}
+
+// consensusAnswer returns the answered candidate whose answer shares the most words with the
+// others, on average. Ties go to the earliest candidate, the routed model's.
+func consensusAnswer(answers []*llm.GenerationResult, answered []int) int {
+	words := make([]map[string]bool, len(answers))
+	for _, i := range answered {
+		words[i] = make(map[string]bool)
+		for _, word := range strings.Fields(strings.ToLower(answers[i].Content)) {
+			words[i][word] = true
+		}
+	}
+	best, bestScore := answered[0], -1.0
+	for _, i := range answered {
+		score := 0.0
+		for _, j := range answered {
+			if i != j {
+				score += wordOverlap(words[i], words[j])
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// wordOverlap is the Jaccard similarity of two sets of words.
+func wordOverlap(a, b map[string]bool) float64 {
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	if union := len(a) + len(b) - shared; union > 0 {
+		return float64(shared) / float64(union)
+	}
+	return 0
+}
@@ -6,17 +6,26 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/audit"
 	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/dileep-u-k/llm-gateway/internal/logging"
+	"github.com/dileep-u-k/llm-gateway/internal/moderation"
+	"github.com/dileep-u-k/llm-gateway/internal/pii"
+	"github.com/dileep-u-k/llm-gateway/internal/ratelimit"
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
 	"github.com/dileep-u-k/llm-gateway/internal/tools"
 	cacheversion "github.com/dileep-u-k/llm-gateway/internal/version"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
 )
 
 // =================================================================================
@@ -29,11 +38,15 @@ import (
 // 1.  **Two Chat Modes:** Seamlessly supports both permanently "locked" chats (forced
 //     model for consistency) and "dynamic" chats (flexible model selection).
 // 2.  **Automatic Session Failover:** If a pinned model in any chat goes offline,
-//     the gateway automatically fails over to the next-best healthy model.
+//     the gateway automatically fails over to the next-best healthy model, optionally
+//     preferring another model from the same provider first.
 // 3.  **Conditional Re-routing:** In "dynamic" chats, users can override the
 //     current model by sending a new preference.
 // =================================================================================
 
+// tenantHeader identifies the tenant a request is made on behalf of.
+const tenantHeader = "X-Tenant-ID"
+
 type GatewayHandler struct {
 	clients        map[string]llm.LLMClient
 	profiler       *llm.Profiler
@@ -42,11 +55,19 @@ type GatewayHandler struct {
 	intentAnalyzer *llm.IntentAnalyzer
 	toolManager    *tools.ToolManager
 	promptAnalyzer *llm.PromptAnalyzer
+	conversations  llm.ConversationStore
+	sessions       llm.SessionStore
+	auditWriter    *audit.Writer
+	moderator      *moderation.Policy
+	streams        *streamTracker
+	jobs           *asyncJobs
+	concurrency    *ratelimit.ConcurrencyLimiter
 	config         *AppConfig
-	rdb            *redis.Client
+	// inflight coalesces identical requests that miss the cache while one is being generated.
+	inflight singleflight.Group
 }
 
-func NewGatewayHandler(clients map[string]llm.LLMClient, profiler *llm.Profiler, router *llm.Router, ragService *llm.RAGService, intentAnalyzer *llm.IntentAnalyzer, toolManager *tools.ToolManager, promptAnalyzer *llm.PromptAnalyzer, config *AppConfig, rdb *redis.Client) *GatewayHandler {
+func NewGatewayHandler(clients map[string]llm.LLMClient, profiler *llm.Profiler, router *llm.Router, ragService *llm.RAGService, intentAnalyzer *llm.IntentAnalyzer, toolManager *tools.ToolManager, promptAnalyzer *llm.PromptAnalyzer, conversations llm.ConversationStore, sessions llm.SessionStore, auditWriter *audit.Writer, moderator *moderation.Policy, concurrency *ratelimit.ConcurrencyLimiter, config *AppConfig) *GatewayHandler {
 	return &GatewayHandler{
 		clients:        clients,
 		profiler:       profiler,
@@ -55,116 +76,412 @@ func NewGatewayHandler(clients map[string]llm.LLMClient, profiler *llm.Profiler,
 		intentAnalyzer: intentAnalyzer,
 		toolManager:    toolManager,
 		promptAnalyzer: promptAnalyzer,
+		conversations:  conversations,
+		sessions:       sessions,
+		auditWriter:    auditWriter,
+		moderator:      moderator,
+		streams:        newStreamTracker(),
+		jobs:           newAsyncJobs(config.Async),
+		concurrency:    concurrency,
 		config:         config,
-		rdb:            rdb,
 	}
 }
 
 func (h *GatewayHandler) HandleGeneration(c *gin.Context) {
 	startTime := time.Now()
 	var req api.GenerationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+	if reqErr := bindGenerationRequest(c, h.config.Limits, &req); reqErr != nil {
+		c.JSON(reqErr.Status, reqErr)
+		return
+	}
+	// Oversized requests are refused here, before a provider is paid to process them.
+	if reqErr := h.config.Limits.checkLimits(&req); reqErr != nil {
+		slog.WarnContext(c.Request.Context(), "Request rejected", "code", reqErr.Code, "limit", reqErr.Limit, "actual", reqErr.Actual)
+		c.JSON(reqErr.Status, reqErr)
+		return
+	}
+	if err := normalizeResponseFormat(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &requestError{Message: err.Error(), Code: codeInvalidResponseFormat})
+		return
+	}
+	if err := validateResponseSchema(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &requestError{Message: err.Error(), Code: codeInvalidResponseSchema})
+		return
+	}
+	if err := validateClientTools(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &requestError{Message: err.Error(), Code: codeInvalidTools})
+		return
+	}
+	if err := validateEnsemble(&req, h.config.EnabledModels); err != nil {
+		c.JSON(http.StatusBadRequest, &requestError{Message: err.Error(), Code: codeInvalidRequest})
+		return
+	}
+	if err := normalizeSystemPrompt(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &requestError{Message: err.Error(), Code: codeInvalidRequest})
+		return
+	}
+	// The body may only name the caller itself.
+	callerID := callerUserID(c)
+	if req.UserID != "" && req.UserID != callerID {
+		c.JSON(http.StatusForbidden, &requestError{Message: fmt.Sprintf("user_id does not match the caller identified by the %s header or token.", userHeader), Code: codeUserMismatch})
+		return
+	}
+	req.UserID = callerID
+	if reqErr := h.checkUserBudget(c, req.UserID); reqErr != nil {
+		slog.WarnContext(c.Request.Context(), "Request rejected", "code", reqErr.Code, "user_id", req.UserID)
+		c.JSON(reqErr.Status, reqErr)
+		return
+	}
+	// Another user's conversation is reported as not found, as on the /conversations endpoints.
+	if req.ConversationID != "" {
+		if req.UserID == "" {
+			c.JSON(http.StatusBadRequest, &requestError{Message: fmt.Sprintf("The %s header is required to use a conversation.", userHeader), Code: codeInvalidRequest})
+			return
+		}
+		owned, err := h.ownsConversation(c.Request.Context(), req.ConversationID, req.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load conversation: " + err.Error()})
+			return
+		}
+		if !owned {
+			c.JSON(http.StatusNotFound, &requestError{Message: "Conversation not found.", Code: codeConversationNotFound})
+			return
+		}
+	}
+	if req.CallbackURL != "" {
+		h.acceptAsync(c, &req)
 		return
 	}
 
-	log.Printf("--- New Request (User: %s, Convo: %s, Prompt: '%.30s...') ---", req.UserID, req.ConversationID, req.Prompt)
+	// Every provider call made for this request shares one set of PII placeholders.
+	if h.config.PII.Mode != pii.ModeOff {
+		c.Request = c.Request.WithContext(pii.WithSession(c.Request.Context(), pii.NewSession()))
+	}
+
+	withLogFields(c, logging.UserIDKey, req.UserID, logging.ConversationIDKey, req.ConversationID)
+	slog.InfoContext(c.Request.Context(), "New request", "prompt_preview", fmt.Sprintf("%.30s", req.Prompt), "stream", req.Config.Stream)
+
+	// Clients may omit History for an existing conversation; it is then loaded from the store.
+	if req.ConversationID != "" && len(req.History) == 0 {
+		history, err := h.conversations.Load(c.Request.Context(), req.ConversationID)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Could not load conversation history", "error", err)
+		} else {
+			req.History = history
+		}
+	}
+
+	// A conversation's system prompt is stored with its session and applied on every turn.
+	h.resolveSystemPrompt(c, &req)
+
+	// The request's allow list can only narrow what the caller's tenant is permitted to use.
+	toolPolicy := h.config.TenantFor(c.GetHeader(tenantHeader)).Tools.Restrict(req.ToolsAllowed)
+
+	trace := &api.DecisionTrace{Cache: api.CacheDecision{Consulted: true, Status: "MISS"}}
+	h.resolveModelAlias(c, &req, trace)
+	if req.Config.ForceModel != "" && slices.Contains(req.Config.ExcludeModels, req.Config.ForceModel) {
+		c.JSON(http.StatusBadRequest, &requestError{Message: fmt.Sprintf("force_model '%s' is also in exclude_models", req.Config.ForceModel), Code: codeInvalidParameter})
+		return
+	}
+
+	cacheKey := cacheversion.GenerateVersionedCacheKey("llmcache", req.Prompt, cacheversion.CacheKeyParams{
+		Model:              req.Config.ForceModel,
+		Forced:             req.Config.ForceModel != "",
+		Preference:         req.Config.Preference,
+		Temperature:        req.Config.Temperature,
+		TopP:               req.Config.TopP,
+		MaxTokens:          req.Config.MaxTokens,
+		ToolPolicy:         toolPolicy.String(),
+		ClientToolsHash:    hashClientTools(req.Tools),
+		HistoryHash:        hashHistory(req.History),
+		SystemPromptHash:   hashSystemPrompt(req.SystemPrompt),
+		ResponseSchemaHash: hashResponseSchema(req.ResponseSchema),
+		ResponseFormat:     responseFormatType(&req),
+		Logprobs:           req.Config.Logprobs,
+		TopLogprobs:        req.Config.TopLogprobs,
+		Seed:               req.Config.Seed,
+		Stop:               req.Config.Stop,
+		FrequencyPenalty:   req.Config.FrequencyPenalty,
+		PresencePenalty:    req.Config.PresencePenalty,
+		TopK:               req.Config.TopK,
+		ReasoningEffort:    req.Config.ReasoningEffort,
+		ThinkingBudget:     req.Config.ThinkingBudget,
+		Ensemble:           ensembleCacheKey(req.Ensemble),
+		RAG:                ragCacheKey(req.RAG),
+		RAGScope:           callerUserID(c) + "/" + c.GetHeader(tenantHeader),
+		ExcludeModels:      req.Config.ExcludeModels,
+		MaxCostUSD:         req.Config.MaxCostUSD,
+	})
+
+	// Prompts are moderated before anything, including the cache, can answer them.
+	if refusal := h.moderate(c, moderation.StageInput, &req, "", trace); refusal != nil {
+		trace.Cache.Consulted = false
+		h.refuse(c, &req, &api.GenerationResponse{CacheStatus: "BLOCKED", LatencyMS: time.Since(startTime).Milliseconds()}, refusal, trace)
+		return
+	}
 
-	cacheKey := cacheversion.GenerateVersionedCacheKey("llmcache", req.Prompt)
 	if cachedVal, found := h.ragService.CheckCache(c.Request.Context(), cacheKey); found {
 		var cachedResp api.GenerationResponse
 		if json.Unmarshal([]byte(cachedVal), &cachedResp) == nil {
-			log.Println("✅ Cache HIT")
-			cachedResp.LatencyMS = time.Since(startTime).Milliseconds()
-			cachedResp.CacheStatus = "HIT"
-			c.JSON(http.StatusOK, cachedResp)
+			slog.InfoContext(c.Request.Context(), "Cache HIT")
+			h.respondWithCachedResponse(c, &req, cachedResp, "HIT", trace, startTime)
+			return
+		}
+	}
+	slog.InfoContext(c.Request.Context(), "Cache MISS")
+
+	// Identical requests that miss the cache while one of them is being generated (a cache
+	// stampede) wait for it and share its answer, so the provider is called once. Streams
+	// are never cached, so they are not coalesced either.
+	if !req.Config.Stream {
+		leader := false
+		result, _, _ := h.inflight.Do(cacheKey, func() (any, error) {
+			leader = true
+			return h.generate(c, &req, cacheKey, toolPolicy, trace, startTime), nil
+		})
+		if leader {
+			return
+		}
+		if sharedResp, ok := result.(*api.GenerationResponse); ok && sharedResp != nil {
+			slog.InfoContext(c.Request.Context(), "Shared the response of an identical in-flight request")
+			h.respondWithCachedResponse(c, &req, *sharedResp, "COALESCED", trace, startTime)
 			return
 		}
+		// The other request failed or was refused, possibly for reasons of its own such as a
+		// disconnected client, so this one is answered independently.
+	}
+	h.generate(c, &req, cacheKey, toolPolicy, trace, startTime)
+}
+
+// respondWithCachedResponse answers a request with a response generated for an identical
+// earlier request, taken from the cache ("HIT") or shared by a concurrent one ("COALESCED").
+func (h *GatewayHandler) respondWithCachedResponse(c *gin.Context, req *api.GenerationRequest, cachedResp api.GenerationResponse, status string, trace *api.DecisionTrace, startTime time.Time) {
+	cachedResp.LatencyMS = time.Since(startTime).Milliseconds()
+	cachedResp.CacheStatus = status
+	// A cache hit costs nothing, but it still counts as a request against the caller's account.
+	cachedResp.CostUSD = 0
+	cachedResp.AccountUsage = h.recordAccountUsage(c, req, api.Usage{}, 0)
+	cachedResp.Warnings = modelWarnings(trace)
+	trace.Cache.Status = status
+	cachedResp.Debug = nil
+	if req.Debug {
+		cachedResp.Debug = trace
 	}
-	log.Println("⚠️ Cache MISS")
+	setRoutingHeaders(c, cachedResp.Routing)
+	h.recordAudit(c.Request.Context(), req, &cachedResp, trace)
+	h.saveConversationTurn(c.Request.Context(), req, cachedResp.Content, cachedResp.PendingToolCalls)
+	if req.Config.Stream {
+		h.streamCachedResponse(c, cachedResp)
+		return
+	}
+	c.JSON(http.StatusOK, cachedResp)
+}
 
-	modelID, failoverInfo, err := h.determineModelID(c, &req)
+// generate answers a request that missed the cache and caches the answer. It returns the
+// cached response, or nil if nothing was cached.
+func (h *GatewayHandler) generate(c *gin.Context, req *api.GenerationRequest, cacheKey string, toolPolicy tools.ToolPolicy, trace *api.DecisionTrace, startTime time.Time) *api.GenerationResponse {
+	// The intent is analyzed first, as it may decide the model. In the minimal profile there is
+	// no intent analysis; every request is a plain generation.
+	intent := llm.IntentRAG
+	if h.intentAnalyzer != nil {
+		intentCtx, intentSpan := telemetry.StartSpan(c.Request.Context(), "intent.analyze")
+		intentDecision := h.intentAnalyzer.AnalyzeIntentDetailed(intentCtx, req.Prompt)
+		intent = intentDecision.Intent
+		intentSpan.SetAttributes(attribute.String("intent", intent), attribute.String("intent.matcher", intentDecision.Matcher), attribute.Float64("intent.confidence", intentDecision.Confidence), attribute.String("intent.language", intentDecision.Language))
+		intentSpan.End()
+		trace.Intent = &api.IntentDecision{Intent: intent, Matcher: intentDecision.Matcher, Pattern: intentDecision.Pattern, Confidence: intentDecision.Confidence, Language: intentDecision.Language}
+		withLogFields(c, logging.IntentKey, intent)
+		slog.InfoContext(c.Request.Context(), "Intent detected", "matcher", intentDecision.Matcher, "confidence", intentDecision.Confidence, "language", intentDecision.Language)
+	}
+
+	// Requests answered by the tool loop, and those offering the caller's own tools, need a
+	// model that can call tools.
+	intentPolicy, usesTools := h.intentToolPolicy(intent, toolPolicy)
+	usesTools = usesTools || len(req.Tools) > 0
+	modelID, failoverInfo, err := h.determineModelID(c, req, intent, usesTools, trace)
+	if err != nil {
+		return nil // An error response has already been sent.
+	}
+	// The model's in-flight slot is held until its answer, streamed or not, is complete.
+	release, err := h.concurrency.AcquireModel(c.Request.Context(), modelID, requestPriority(c, h.config))
 	if err != nil {
-		return // An error response has already been sent.
+		rejectSaturated(c, h.concurrency, "model "+modelID, err)
+		return nil
 	}
+	defer release()
+	telemetry.Annotate(c.Request.Context(), attribute.String("gateway.model", modelID), attribute.String("gateway.conversation_id", req.ConversationID))
+	withLogFields(c, logging.ModelKey, modelID)
 
-	intent := h.intentAnalyzer.AnalyzeIntent(req.Prompt)
-	log.Printf("🔍 Intent Detected: %s", intent)
+	routing := routingInfo(intent, req, trace)
+	setRoutingHeaders(c, routing)
 
-	var finalContent string
+	// Streaming requests report each phase as an SSE event and are not cached.
+	if req.Config.Stream {
+		h.handleStreamingGeneration(c, *req, intent, modelID, failoverInfo, toolPolicy, routing, trace, startTime)
+		return nil
+	}
+
+	var answer *llm.GenerationResult
 	var usage api.Usage
-	var ragContextUsed bool
+	var ragDecision *api.RAGDecision
+	var ensemble *ensembleOutcome
 
-	// This is the only change in this function: pass the history to the tool loop.
-	switch intent {
-	case llm.IntentWeather, llm.IntentCalculator, llm.IntentNews:
-		// --- THIS IS THE CHANGE ---
-		finalContent, usage, _, err = h.handleToolLoop(c, req)
+	switch {
+	case usesTools:
+		answer, usage, err = h.handleToolLoop(c, *req, modelID, intentPolicy, trace)
+	case req.Ensemble != nil:
+		ensemble, ragDecision, err = h.executeEnsemble(c, *req, modelID, trace)
+		if err == nil {
+			answer, usage = ensemble.answer, ensemble.usage
+		}
 	default:
-		finalContent, usage, ragContextUsed, err = h.executeRAGAndGenerate(c, req, modelID)
+		var retry *api.FailoverInfo
+		answer, usage, ragDecision, retry, err = h.executeRAGAndGenerate(c, *req, modelID, trace)
+		if retry != nil {
+			// The answer, or the final error, is the alternate model's.
+			if failoverInfo != nil {
+				retry.OriginalModel, retry.Reason = failoverInfo.OriginalModel, failoverInfo.Reason+" "+retry.Reason
+			}
+			modelID, failoverInfo = retry.NewModel, retry
+			withLogFields(c, logging.ModelKey, modelID)
+		}
 	}
+	trace.RAG = ragDecision
 
+	// The model answered, just never in the requested shape: the answers still count as
+	// successful, paid-for calls.
+	var schemaErr *schemaValidationError
+	if errors.As(err, &schemaErr) {
+		h.profiler.UpdateProfileOnSuccess(c.Request.Context(), modelID, time.Since(startTime), usage)
+		h.profiler.RecordSpend(c.Request.Context(), modelID, req.UserID, req.ConversationID, usage)
+		h.recordAccountUsage(c, req, usage, llm.CallCost(modelID, usage))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": schemaErr.Error(), "validation_errors": schemaErr.Problems, "attempts": schemaErr.Attempts})
+		return nil
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil
 	}
 
 	latency := time.Since(startTime)
-	h.profiler.UpdateProfileOnSuccess(c.Request.Context(), modelID, latency, usage)
+	cost := llm.CallCost(modelID, usage)
+	var ensembleResult *api.EnsembleResult
+	if ensemble != nil {
+		// Every call of the ensemble has been recorded against its own model.
+		modelID, cost, ensembleResult = ensemble.modelUsed, ensemble.cost, ensemble.result
+	} else {
+		h.profiler.UpdateProfileOnSuccess(c.Request.Context(), modelID, latency, usage)
+		h.profiler.RecordSpend(c.Request.Context(), modelID, req.UserID, req.ConversationID, usage)
+	}
 
+	finalContent := answer.Content
 	finalResponse := api.GenerationResponse{
-		Content:        finalContent,
-		ModelUsed:      modelID,
-		Usage:          usage,
-		LatencyMS:      latency.Milliseconds(),
-		RAGContextUsed: ragContextUsed,
-		CacheStatus:    "MISS",
-		FailoverInfo:   failoverInfo,
+		Content:           finalContent,
+		ModelUsed:         modelID,
+		Usage:             usage,
+		LatencyMS:         latency.Milliseconds(),
+		RAGContextUsed:    ragDecision != nil && ragDecision.Used,
+		RAG:               ragDecision,
+		Logprobs:          answer.Logprobs,
+		SystemFingerprint: answer.SystemFingerprint,
+		FinishReason:      answer.StopReason,
+		ToolCalls:         trace.ToolCalls,
+		PendingToolCalls:  apiToolCalls(answer.ToolCalls),
+		CacheStatus:       "MISS",
+		FailoverInfo:      failoverInfo,
+		Truncated:         trace.Context != nil && trace.Context.Truncated,
+		Routing:           routing,
+		Ensemble:          ensembleResult,
+	}
+
+	// A blocked answer has still been paid for, so it counts against the caller's account,
+	// but it is neither cached nor added to the conversation.
+	if refusal := h.moderate(c, moderation.StageOutput, req, finalContent, trace); refusal != nil {
+		h.recordAccountUsage(c, req, usage, cost)
+		h.refuse(c, req, &finalResponse, refusal, trace)
+		return nil
 	}
 
 	respBytes, err := json.Marshal(finalResponse)
 	if err != nil {
-		log.Printf("WARNING: Failed to marshal response for caching: %v", err)
+		slog.WarnContext(c.Request.Context(), "Failed to marshal response for caching", "error", err)
 	} else {
 		h.ragService.SetCache(c.Request.Context(), cacheKey, string(respBytes))
-		log.Println("✅ Response CACHED")
+		slog.InfoContext(c.Request.Context(), "Response cached")
 	}
+	cachedResp := finalResponse
 
+	h.saveConversationTurn(c.Request.Context(), req, finalContent, finalResponse.PendingToolCalls)
+
+	// The debug block and account totals are attached after caching so they never leak
+	// into other callers' cache hits.
+	if req.Debug {
+		finalResponse.Debug = trace
+	}
+	finalResponse.CostUSD = cost
+	finalResponse.AccountUsage = h.recordAccountUsage(c, req, usage, finalResponse.CostUSD)
+	finalResponse.Warnings = modelWarnings(trace)
+	h.recordAudit(c.Request.Context(), req, &finalResponse, trace)
+	h.recordRoutingDecision(c.Request.Context(), routing, modelID, failoverInfo, false, latency, cost)
 	c.JSON(http.StatusOK, finalResponse)
+	return &cachedResp
 }
 
-// determineModelID encapsulates the complete, final logic with all bug fixes.
-func (h *GatewayHandler) determineModelID(c *gin.Context, req *api.GenerationRequest) (string, *api.FailoverInfo, error) {
+// determineModelID encapsulates the complete, final logic with all bug fixes. Requests that
+// set no preference are routed by their intent's route, if it has one, and requests that use
+// tools only to models that can call them.
+func (h *GatewayHandler) determineModelID(c *gin.Context, req *api.GenerationRequest, intent string, usesTools bool, trace *api.DecisionTrace) (string, *api.FailoverInfo, error) {
 	var failoverInfo *api.FailoverInfo
+	failedModel := ""
+	sessionPolicy := h.config.SessionPolicyFor(c.GetHeader(tenantHeader))
+	sessionExists := false
 
 	// A. SESSION HANDLING: Check for an existing conversation first.
 	if req.ConversationID != "" {
-		sessionKey := fmt.Sprintf("session:%s", req.ConversationID)
-		sessionData, err := h.rdb.HGetAll(c.Request.Context(), sessionKey).Result()
+		session, err := h.sessions.Get(c.Request.Context(), req.ConversationID)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Failed to read session", "error", err)
+		}
 
-		if err == nil && len(sessionData) > 0 {
-			pinnedModel := sessionData["model_id"]
-			isForcedSession := sessionData["is_forced"] == "true"
+		if err == nil && session != nil {
+			sessionExists = true
+			// Sessions pinned before a model was retired follow its alias to the replacement.
+			pinnedModel, _ := h.config.RouterConfig.ResolveModel(session.ModelID)
+			isForcedSession := session.IsForced
 
 			if isForcedSession {
 				// --- FORCED SESSION LOGIC ---
-				log.Printf("... Detected a Forced Session. Verifying model health...")
-				profile, profilerErr := h.profiler.GetProfile(c.Request.Context(), pinnedModel)
-				if profilerErr == nil && profile.Status == "online" {
-					log.Printf("📌 Forced Session HIT. Reusing locked model: %s", pinnedModel)
-					h.refreshSessionTTL(c.Request.Context(), sessionKey)
+				slog.DebugContext(c.Request.Context(), "Detected a forced session. Verifying model health", "pinned_model", pinnedModel)
+				unavailable := h.unavailableFor(c.Request.Context(), req, pinnedModel, usesTools)
+				if unavailable == "" {
+					slog.InfoContext(c.Request.Context(), "Forced session HIT. Reusing locked model", "pinned_model", pinnedModel)
+					h.refreshSessionTTL(c.Request.Context(), req.ConversationID, sessionPolicy)
 					return pinnedModel, nil, nil
 				} else {
 					// FAILOVER for a forced session.
-					log.Printf("🚨 Forced-pinned model '%s' is offline. Failing over...", pinnedModel)
+					slog.WarnContext(c.Request.Context(), "Forced-pinned model is unavailable. Failing over", "pinned_model", pinnedModel, "reason", unavailable)
 					req.Config.Preference = "max_quality"
-					failoverInfo = &api.FailoverInfo{OriginalModel: pinnedModel, Reason: fmt.Sprintf("Model '%s' was offline.", pinnedModel)}
+					failoverInfo = &api.FailoverInfo{OriginalModel: pinnedModel, Reason: fmt.Sprintf("Model '%s' was %s.", pinnedModel, unavailable)}
+					failedModel = pinnedModel
 					// Let the request fall through to the router.
 				}
+			} else if session.Turns+1 < sessionPolicy.RerouteEveryTurns && req.Config.Preference == "" {
+				// --- DYNAMIC SESSION LOGIC: Stay on the pinned model until the re-route interval is reached,
+				// unless the user asked for a new preference or the model went offline or was disabled.
+				unavailable := h.unavailableFor(c.Request.Context(), req, pinnedModel, usesTools)
+				if unavailable == "" {
+					slog.InfoContext(c.Request.Context(), "Dynamic session HIT. Keeping pinned model", "pinned_model", pinnedModel, "turn", session.Turns+2, "reroute_every_turns", sessionPolicy.RerouteEveryTurns)
+					if err := h.sessions.RecordTurn(c.Request.Context(), req.ConversationID); err != nil {
+						slog.WarnContext(c.Request.Context(), "Failed to record session turn", "error", err)
+					}
+					h.refreshSessionTTL(c.Request.Context(), req.ConversationID, sessionPolicy)
+					return pinnedModel, nil, nil
+				}
+				slog.WarnContext(c.Request.Context(), "Dynamic session HIT, but pinned model is unavailable. Re-routing", "pinned_model", pinnedModel, "reason", unavailable)
+				failedModel = pinnedModel
 			} else {
-				// --- THIS IS THE FINAL, CORRECTED LOGIC ---
-				// --- DYNAMIC SESSION LOGIC: Always re-evaluate the model choice for every message.
-				log.Printf("🕵️ Dynamic Session HIT. Re-evaluating model for new prompt...")
+				// --- DYNAMIC SESSION LOGIC: Re-evaluate the model choice for this message.
+				slog.InfoContext(c.Request.Context(), "Dynamic session HIT. Re-evaluating model for new prompt")
 				// We don't return here. We let the request "fall through" to the main
 				// routing logic below, which will run the analyzer and router again.
 			}
@@ -177,49 +494,93 @@ func (h *GatewayHandler) determineModelID(c *gin.Context, req *api.GenerationReq
 	// Handle the creation of a NEW forced chat as a special, separate case.
 	if req.ConversationID != "" && req.Config.ForceModel != "" {
 		forcedModelID := req.Config.ForceModel
-		log.Printf("... User is attempting to force-start a new chat with model: %s", forcedModelID)
-		profile, err := h.profiler.GetProfile(c.Request.Context(), forcedModelID)
-		if err != nil || profile.Status != "online" {
-			h.suggestHealthyAlternatives(c, forcedModelID)
+		slog.InfoContext(c.Request.Context(), "Force-starting a new chat", "forced_model", forcedModelID)
+		if unavailable := h.unavailableFor(c.Request.Context(), req, forcedModelID, usesTools); unavailable != "" {
+			h.suggestHealthyAlternatives(c, forcedModelID, unavailable)
 			return "", nil, errors.New("response sent")
 		}
 		// Pin the new forced session and return immediately.
-		h.pinSession(c.Request.Context(), req.ConversationID, forcedModelID, true)
+		h.pinSession(c.Request.Context(), req.ConversationID, forcedModelID, true, sessionPolicy, sessionExists)
 		return forcedModelID, nil, nil
 	}
 
 	// This is the path for new dynamic chats, one-off queries, or any failover.
+	modelID, routedByIntent := "", false
 	if req.Config.Preference == "" {
-		req.Config.Preference = h.promptAnalyzer.Analyze(req.Prompt)
-		log.Printf("🤖 No preference specified. Auto-selected: '%s'", req.Config.Preference)
-	} else {
-		log.Printf("👤 User specified preference: '%s'", req.Config.Preference)
+		modelID, routedByIntent = h.routeByIntent(c.Request.Context(), req, intent, usesTools, trace)
 	}
-
-	// --- THIS IS THE FINAL ENHANCEMENT ---
-	// Calculate the total length of the prompt including all historical messages.
-	totalPromptLength := len(req.Prompt)
-	for _, msg := range req.History {
-		totalPromptLength += len(msg.Content)
+	switch {
+	case routedByIntent:
+		// The intent's route set the model or the preference.
+	case req.Config.Preference == "":
+		analysis := h.promptAnalyzer.AnalyzeFor(c.Request.Context(), req.Prompt, variantUnit(c, req))
+		req.Config.Preference = analysis.Preference
+		trace.Preference = &api.PreferenceDecision{Preference: analysis.Preference, Score: analysis.Score, Language: analysis.Language, Variant: analysis.Variant, Backend: analysis.Backend}
+		slog.InfoContext(c.Request.Context(), "No preference specified. Auto-selected one", "preference", req.Config.Preference, "score", analysis.Score, "variant", analysis.Variant, "backend", analysis.Backend)
+	default:
+		slog.InfoContext(c.Request.Context(), "User specified preference", "preference", req.Config.Preference)
 	}
-	// Use this more accurate total length for the token estimation.
-	estimatedTokens := totalPromptLength / 4
-	log.Printf("... Total estimated input tokens (including history): %d", estimatedTokens)
-	// --- END OF ENHANCEMENT ---
 
-	modelID, err := h.router.SelectOptimalModel(c.Request.Context(), h.config.EnabledModels, req.Config.Preference, estimatedTokens, h.config.ModelBudgets)
+	estimatedTokens := estimatePromptTokens(req)
+	slog.DebugContext(c.Request.Context(), "Estimated input tokens (including history)", "estimated_tokens", estimatedTokens)
+
+	candidates := h.config.EnabledModels
+	if usesTools {
+		candidates = h.router.ToolModels(candidates)
+		if len(candidates) == 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("no enabled model can call the tools the '%s' intent needs", intent)})
+			return "", nil, errors.New("response sent")
+		}
+	}
+	if len(req.Config.ExcludeModels) > 0 {
+		candidates = slices.DeleteFunc(slices.Clone(candidates), func(m string) bool { return slices.Contains(req.Config.ExcludeModels, m) })
+		if len(candidates) == 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "every model that could answer the request is in exclude_models"})
+			return "", nil, errors.New("response sent")
+		}
+	}
+	var err error
+	sameProvider := false
+	switch {
+	case modelID != "":
+		// Routed to the intent's model.
+	case failedModel != "":
+		// A pinned model went offline; the failover policy decides whether to stay with its provider.
+		modelID, sameProvider, err = h.router.SelectFailoverModel(c.Request.Context(), failedModel, candidates, req.Config.Preference, estimatedTokens, h.config.ModelBudgets, req.Config.MaxCostUSD)
+	default:
+		modelID, err = h.router.SelectOptimalModel(c.Request.Context(), candidates, req.Config.Preference, estimatedTokens, h.config.ModelBudgets, req.Config.MaxCostUSD)
+	}
+	var overCap *llm.CostCapError
+	if errors.As(err, &overCap) {
+		c.JSON(http.StatusUnprocessableEntity, &requestError{
+			Message:          err.Error(),
+			Code:             codeCostCapExceeded,
+			MaxCostUSD:       overCap.MaxCostUSD,
+			Model:            overCap.CheapestModel,
+			EstimatedCostUSD: overCap.CheapestCostUSD,
+		})
+		return "", nil, errors.New("response sent")
+	}
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 		return "", nil, errors.New("response sent")
 	}
 
+	if req.Debug && !routedByIntent {
+		// Explain how close the models the router chose among are to their budgets.
+		trace.Budgets = h.router.BudgetStates(c.Request.Context(), candidates, h.config.ModelBudgets)
+	}
+
 	if failoverInfo != nil {
 		failoverInfo.NewModel = modelID
+		if sameProvider {
+			failoverInfo.Reason += fmt.Sprintf(" Replaced with another '%s' model.", h.router.ProviderOf(modelID))
+		}
 	}
 
-	if req.ConversationID != "" {
+	if req.ConversationID != "" && sessionPolicy.PinsDynamicSessions() {
 		// Pin the session, ensuring isForced is false because we came through the dynamic path.
-		h.pinSession(c.Request.Context(), req.ConversationID, modelID, false)
+		h.pinSession(c.Request.Context(), req.ConversationID, modelID, false, sessionPolicy, sessionExists)
 	}
 
 	return modelID, failoverInfo, nil
@@ -227,36 +588,170 @@ func (h *GatewayHandler) determineModelID(c *gin.Context, req *api.GenerationReq
 
 // --- HELPER FUNCTIONS ---
 
-func (h *GatewayHandler) pinSession(ctx context.Context, conversationID, modelID string, isForced bool) {
-	sessionKey := fmt.Sprintf("session:%s", conversationID)
-	sessionData := map[string]interface{}{
-		"model_id":  modelID,
-		"is_forced": fmt.Sprintf("%v", isForced), // Converts true to "true"
+// pinSession pins a model to a conversation. Sessions with a fixed expiry keep the lifetime
+// they were created with, so re-pinning an existing session does not extend it.
+func (h *GatewayHandler) pinSession(ctx context.Context, conversationID, modelID string, isForced bool, policy llm.SessionPolicy, exists bool) {
+	ttl := policy.TTL
+	if exists && !policy.IsSliding() {
+		ttl = 0
 	}
-	if err := h.rdb.HSet(ctx, sessionKey, sessionData).Err(); err != nil {
-		log.Printf("WARNING: Failed to HSet session key in Redis: %v", err)
+	if err := h.sessions.Pin(ctx, conversationID, llm.Session{ModelID: modelID, IsForced: isForced}, ttl); err != nil {
+		slog.WarnContext(ctx, "Failed to pin session", "error", err)
 	} else {
-		h.refreshSessionTTL(ctx, sessionKey)
-		log.Printf("📌 Pinned model %s to conversation %s (Forced=%v).", modelID, conversationID, isForced)
+		slog.InfoContext(ctx, "Pinned model to conversation", "pinned_model", modelID, "forced", isForced)
 	}
 }
 
-func (h *GatewayHandler) refreshSessionTTL(ctx context.Context, sessionKey string) {
-	h.rdb.Expire(ctx, sessionKey, 1*time.Hour)
+// saveConversationTurn appends the user's prompt and the assistant's answer to the
+// server-side history, so the next request in the conversation can omit History.
+// The first exchange of a conversation is also titled in the background.
+func (h *GatewayHandler) saveConversationTurn(ctx context.Context, req *api.GenerationRequest, answer string, toolCalls []api.ToolCall) {
+	if req.ConversationID == "" {
+		return
+	}
+	if owned, err := h.ownsConversation(ctx, req.ConversationID, req.UserID); err != nil || !owned {
+		slog.WarnContext(ctx, "Not saving conversation turn", "owned", owned, "error", err)
+		return
+	}
+	err := h.conversations.Append(ctx, req.ConversationID, req.UserID,
+		api.Message{Role: string(llm.RoleUser), Content: req.Prompt},
+		api.Message{Role: string(llm.RoleAssistant), Content: answer, ToolCalls: toolCalls},
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to save conversation turn", "error", err)
+		return
+	}
+	h.maybeGenerateTitle(ctx, req, answer)
 }
 
-func (h *GatewayHandler) suggestHealthyAlternatives(c *gin.Context, failedModelID string) {
+// ownsConversation reports whether a conversation belongs to userID. A conversation that does
+// not exist yet is treated as owned, since the caller's first turn creates it under userID.
+// Anonymous callers own no conversation, or they would all share the ones created anonymously.
+func (h *GatewayHandler) ownsConversation(ctx context.Context, conversationID, userID string) (bool, error) {
+	if userID == "" {
+		return false, nil
+	}
+	info, err := h.conversations.Get(ctx, conversationID)
+	if errors.Is(err, llm.ErrConversationNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.UserID == userID, nil
+}
+
+// hashHistory condenses a conversation history into a short hash for cache keys, so a
+// follow-up like "and tomorrow?" is never answered from another conversation's cache entry.
+func hashHistory(history []api.Message) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, msg := range history {
+		b.WriteString(msg.Role)
+		b.WriteByte(0)
+		b.WriteString(msg.Content)
+		b.WriteByte(0)
+		// Tool calls and results are only hashed when present, so plain histories keep their keys.
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&b, "call\x00%s\x00%s\x00%s\x00", call.ID, call.Name, call.Arguments)
+		}
+		if msg.ToolCallID != "" {
+			fmt.Fprintf(&b, "result\x00%s\x00", msg.ToolCallID)
+		}
+	}
+	return llm.GenerateCacheKey(b.String())
+}
+
+// refreshSessionTTL extends a sliding session. Fixed-expiry sessions are left untouched.
+func (h *GatewayHandler) refreshSessionTTL(ctx context.Context, conversationID string, policy llm.SessionPolicy) {
+	if !policy.IsSliding() {
+		return
+	}
+	if err := h.sessions.Refresh(ctx, conversationID, policy.TTL); err != nil {
+		slog.WarnContext(ctx, "Failed to refresh session TTL", "error", err)
+	}
+}
+
+// routeByIntent applies the configured route of a request's intent: a strategy becomes the
+// request's preference, and a model is returned unless it cannot serve requests. It reports
+// whether the route was applied.
+func (h *GatewayHandler) routeByIntent(ctx context.Context, req *api.GenerationRequest, intent string, usesTools bool, trace *api.DecisionTrace) (string, bool) {
+	route, ok := h.config.Intents.Routing[intent]
+	if !ok {
+		return "", false
+	}
+	trace.IntentRoute = &api.IntentRouteDecision{Intent: intent, Model: route.Model, Strategy: route.Strategy}
+	if route.Strategy != "" {
+		req.Config.Preference = route.Strategy
+		slog.InfoContext(ctx, "Routing by the intent's strategy", "intent", intent, "strategy", route.Strategy)
+		return "", true
+	}
+	unavailable := "not enabled"
+	if _, enabled := h.clients[route.Model]; enabled {
+		unavailable = h.unavailableFor(ctx, req, route.Model, usesTools)
+	}
+	if unavailable != "" {
+		trace.IntentRoute.Skipped = fmt.Sprintf("model '%s' is %s", route.Model, unavailable)
+		slog.WarnContext(ctx, "The intent's model cannot serve requests. Routing by preference", "intent", intent, "model", route.Model, "reason", unavailable)
+		return "", false
+	}
+	slog.InfoContext(ctx, "Routing to the intent's model", "intent", intent, "model", route.Model)
+	return route.Model, true
+}
+
+// unavailableReason returns why a model cannot serve requests ("offline" or "disabled by an
+// operator"), or "" if it can.
+func (h *GatewayHandler) unavailableReason(ctx context.Context, modelID string) string {
+	if h.router.IsDisabled(ctx, modelID) {
+		return "disabled by an operator"
+	}
+	profile, err := h.profiler.GetProfile(ctx, modelID)
+	if err != nil || profile.Status != "online" {
+		return "offline"
+	}
+	return ""
+}
+
+// unavailableFor is unavailableReason for a request, which may exclude models and, if
+// usesTools is set, needs a model that can call tools.
+func (h *GatewayHandler) unavailableFor(ctx context.Context, req *api.GenerationRequest, modelID string, usesTools bool) string {
+	if slices.Contains(req.Config.ExcludeModels, modelID) {
+		return "excluded by the request"
+	}
+	if usesTools && !h.router.SupportsTools(modelID) {
+		return "unable to call tools"
+	}
+	if req.Config.MaxCostUSD > 0 {
+		if cost, err := h.router.EstimateCost(ctx, modelID, estimatePromptTokens(req)); err == nil && cost > req.Config.MaxCostUSD {
+			return fmt.Sprintf("estimated to cost $%.6f, over the request's max_cost_usd", cost)
+		}
+	}
+	return h.unavailableReason(ctx, modelID)
+}
+
+// estimatePromptTokens roughly estimates the input tokens of a request, history included,
+// at four characters per token.
+func estimatePromptTokens(req *api.GenerationRequest) int {
+	totalPromptLength := len(req.Prompt)
+	for _, msg := range req.History {
+		totalPromptLength += len(msg.Content)
+	}
+	return totalPromptLength / 4
+}
+
+func (h *GatewayHandler) suggestHealthyAlternatives(c *gin.Context, failedModelID, reason string) {
 	var healthyModels []string
 	for _, model := range h.config.EnabledModels {
 		if model == failedModelID {
 			continue
 		}
-		p, err := h.profiler.GetProfile(c.Request.Context(), model)
-		if err == nil && p.Status == "online" {
+		if h.unavailableReason(c.Request.Context(), model) == "" {
 			healthyModels = append(healthyModels, model)
 		}
 	}
-	errorMsg := fmt.Sprintf("The requested model '%s' is currently offline.", failedModelID)
+	errorMsg := fmt.Sprintf("The requested model '%s' is currently %s.", failedModelID, reason)
 	c.JSON(http.StatusFailedDependency, gin.H{
 		"error":            errorMsg,
 		"available_models": healthyModels,
@@ -265,103 +760,360 @@ func (h *GatewayHandler) suggestHealthyAlternatives(c *gin.Context, failedModelI
 
 // --- THIS FUNCTION IS NOW UPDATED ---
 // It now accepts the full request to handle conversation history.
-func (h *GatewayHandler) executeRAGAndGenerate(c *gin.Context, req api.GenerationRequest, modelID string) (string, api.Usage, bool, error) {
-	finalPrompt, ragContextUsed, err := h.performRAGRetrieval(c, req.Prompt, "relevance_threshold")
+// If the model fails with a retryable error, such as a provider outage or a timeout, the
+// request is retried once on the next-ranked model, and the hop is returned.
+func (h *GatewayHandler) executeRAGAndGenerate(c *gin.Context, req api.GenerationRequest, modelID string, trace *api.DecisionTrace) (*llm.GenerationResult, api.Usage, *api.RAGDecision, *api.FailoverInfo, error) {
+	finalPrompt, ragDecision, err := h.augmentPrompt(c, req)
 	if err != nil {
-		return "", api.Usage{}, false, fmt.Errorf("RAG retrieval failed: %w", err)
+		return nil, api.Usage{}, nil, nil, err
 	}
+	answer, usage, err := h.generateAnswer(c, req, modelID, finalPrompt, trace)
+	if err == nil || !llm.IsRetryable(err) {
+		return answer, usage, ragDecision, nil, err
+	}
+
+	ctx := c.Request.Context()
+	// A forced model is the caller's choice, and a request that has run out of time cannot be retried.
+	if req.Config.ForceModel != "" || ctx.Err() != nil {
+		return answer, usage, ragDecision, nil, err
+	}
+	candidates := slices.DeleteFunc(slices.Clone(h.config.EnabledModels), func(m string) bool {
+		return m == modelID || slices.Contains(req.Config.ExcludeModels, m)
+	})
+	alternate, selectErr := h.router.SelectOptimalModel(ctx, candidates, req.Config.Preference, estimatePromptTokens(&req), h.config.ModelBudgets, req.Config.MaxCostUSD)
+	if selectErr != nil {
+		slog.WarnContext(ctx, "No model to retry the failed generation on", "failed_model", modelID, "error", selectErr)
+		return answer, usage, ragDecision, nil, err
+	}
+	release, acquireErr := h.concurrency.AcquireModel(ctx, alternate, requestPriority(c, h.config))
+	if acquireErr != nil {
+		slog.WarnContext(ctx, "Could not retry the failed generation", "failed_model", modelID, "alternate_model", alternate, "error", acquireErr)
+		return answer, usage, ragDecision, nil, err
+	}
+	defer release()
+
+	slog.WarnContext(ctx, "Generation failed with a retryable error. Retrying on the next-ranked model", "failed_model", modelID, "alternate_model", alternate, "error", err)
+	retry := &api.FailoverInfo{OriginalModel: modelID, NewModel: alternate, Reason: fmt.Sprintf("Model '%s' failed to answer with a retryable error; retried on '%s'.", modelID, alternate)}
+	answer, usage, retryErr := h.generateAnswer(c, req, alternate, finalPrompt, trace)
+	if retryErr != nil {
+		retryErr = fmt.Errorf("%w (retried after: %v)", retryErr, err)
+	}
+	return answer, usage, ragDecision, retry, retryErr
+}
+
+// augmentPrompt adds the knowledge base's context to the prompt, if it has relevant context.
+// The minimal profile has no knowledge base, and requests can turn retrieval off.
+func (h *GatewayHandler) augmentPrompt(c *gin.Context, req api.GenerationRequest) (string, *api.RAGDecision, error) {
+	if h.config.IsMinimal() || (req.RAG != nil && req.RAG.Disable) {
+		return req.Prompt, nil, nil
+	}
+	finalPrompt, ragDecision, err := h.performRAGRetrieval(c, req.Prompt, req.RAG, "relevance_threshold")
+	if err != nil {
+		return "", nil, fmt.Errorf("RAG retrieval failed: %w", err)
+	}
+	return finalPrompt, ragDecision, nil
+}
+
+// generateAnswer has a model answer the (possibly RAG-augmented) prompt, in the shape the
+// request asks for.
+func (h *GatewayHandler) generateAnswer(c *gin.Context, req api.GenerationRequest, modelID, finalPrompt string, trace *api.DecisionTrace) (*llm.GenerationResult, api.Usage, error) {
 	client := h.clients[modelID]
 	if client == nil {
-		return "", api.Usage{}, false, fmt.Errorf("no client available for model %s", modelID)
+		return nil, api.Usage{}, fmt.Errorf("no client available for model %s", modelID)
 	}
 
-	// --- THIS IS THE NEW LOGIC ---
-	// Construct the full conversation history to give the model memory.
-	// Convert the API message history to the internal LLM message type.
-	messages := convertAPIMessagesToLLMMessages(req.History)
-	messages = append(messages, llm.Message{Role: llm.RoleUser, Content: finalPrompt})
-	// --- END OF NEW LOGIC ---
+	// Construct the conversation history to give the model memory, trimmed to its context window.
+	messages := h.buildMessages(c.Request.Context(), modelID, req, finalPrompt, trace)
 
 	llmConfig := &llm.GenerationConfig{
-		Model:       modelID,
-		MaxTokens:   req.Config.MaxTokens,
-		Temperature: req.Config.Temperature,
-		TopP:        req.Config.TopP,
-		Stream:      req.Config.Stream,
+		Model:            modelID,
+		MaxTokens:        req.Config.MaxTokens,
+		Temperature:      req.Config.Temperature,
+		TopP:             req.Config.TopP,
+		Stream:           req.Config.Stream,
+		ResponseFormat:   llmResponseFormat(req.ResponseFormat),
+		Logprobs:         req.Config.Logprobs,
+		TopLogprobs:      req.Config.TopLogprobs,
+		Seed:             req.Config.Seed,
+		Stop:             req.Config.Stop,
+		FrequencyPenalty: req.Config.FrequencyPenalty,
+		PresencePenalty:  req.Config.PresencePenalty,
+		TopK:             req.Config.TopK,
+		ReasoningEffort:  req.Config.ReasoningEffort,
+		ThinkingBudget:   req.Config.ThinkingBudget,
 	}
 
 	// Pass the complete message history to the LLM.
 	result, err := client.Generate(c.Request.Context(), messages, llmConfig, nil)
 	if err != nil {
 		h.profiler.UpdateProfileOnFailure(c.Request.Context(), modelID)
-		return "", api.Usage{}, ragContextUsed, fmt.Errorf("LLM generation failed for model %s: %w", modelID, err)
+		return nil, api.Usage{}, fmt.Errorf("LLM generation failed for model %s: %w", modelID, err)
+	}
+	if result.Deprecation != nil {
+		h.profiler.RecordDeprecation(c.Request.Context(), result.Deprecation)
 	}
-	return result.Content, result.Usage, ragContextUsed, nil
+	answer, extraUsage, err := h.conformToSchema(c.Request.Context(), req, client, messages, llmConfig, result, trace)
+	usage := result.Usage
+	usage.Add(extraUsage)
+	return answer, usage, err
 }
 
-func (h *GatewayHandler) performRAGRetrieval(c *gin.Context, prompt string, thresholdKey string) (string, bool, error) {
-	contextText, score, err := h.ragService.RetrieveContext(c.Request.Context(), prompt, 2)
-	if err != nil {
-		return prompt, false, err
+// defaultRAGTopK is how many knowledge base chunks are retrieved unless the request asks otherwise.
+const defaultRAGTopK = 2
+
+// ragCacheKey identifies a request's retrieval options in cache keys.
+func ragCacheKey(opts *api.RAGOptions) string {
+	if opts == nil {
+		return ""
+	}
+	minScore := "-"
+	if opts.MinScore != nil {
+		minScore = fmt.Sprintf("%g", *opts.MinScore)
 	}
+	return fmt.Sprintf("%d/%s/%t", opts.TopK, minScore, opts.Disable)
+}
+
+// performRAGRetrieval retrieves the knowledge base's context for a prompt. The request's
+// options, if any, override how many chunks are retrieved and the score they must reach.
+// Besides the shared knowledge base, only documents the caller uploaded within its tenant are
+// searched. The caller is identified as on the /documents endpoints that own the uploads.
+func (h *GatewayHandler) performRAGRetrieval(c *gin.Context, prompt string, opts *api.RAGOptions, thresholdKey string) (string, *api.RAGDecision, error) {
+	topK := defaultRAGTopK
 	threshold := h.config.RouterConfig.Thresholds[thresholdKey].(float64)
+	if opts != nil {
+		if opts.TopK > 0 {
+			topK = opts.TopK
+		}
+		if opts.MinScore != nil {
+			threshold = *opts.MinScore
+		}
+	}
+	scope := llm.RetrievalScope{Owner: callerUserID(c), Namespace: c.GetHeader(tenantHeader)}
+	retrieved, err := h.ragService.RetrieveContext(c.Request.Context(), prompt, topK, scope)
+	if err != nil {
+		return prompt, nil, err
+	}
+	score := retrieved.Score
+	decision := &api.RAGDecision{TopK: topK, Score: score, Threshold: threshold, Topic: retrieved.Topic, Chunks: retrieved.Chunks}
 	if score >= threshold {
-		log.Printf("📝 RAG context found (score %.2f >= %.2f). Augmenting prompt.", score, threshold)
-		return fmt.Sprintf("Using the following context, answer the question.\n\nContext:\n%s\n\nQuestion: %s", contextText, prompt), true, nil
+		slog.InfoContext(c.Request.Context(), "RAG context found. Augmenting prompt", "score", score, "threshold", threshold, "topic", retrieved.Topic, "chunks", retrieved.Chunks)
+		decision.Used = true
+		return fmt.Sprintf("Using the following context, answer the question.\n\nContext:\n%s\n\nQuestion: %s", retrieved.Text, prompt), decision, nil
 	}
-	log.Printf("RAG context score (%.2f) is below threshold (%.2f). Proceeding with original prompt.", score, threshold)
-	return prompt, false, nil
+	slog.InfoContext(c.Request.Context(), "RAG context score is below threshold. Proceeding with original prompt", "score", score, "threshold", threshold)
+	return prompt, decision, nil
 }
 
 // --- THIS FUNCTION IS NOW UPDATED ---
 // It now accepts the full request to handle conversation history.
-func (h *GatewayHandler) handleToolLoop(c *gin.Context, req api.GenerationRequest) (string, api.Usage, string, error) {
-	log.Println("Entering tool loop...")
+// Only tools permitted by the policy are shown to the model, and any other tool it
+// tries to call is refused rather than executed.
+// intentToolPolicy reports whether an intent is answered by the tool loop, and with which
+// tools: every permitted tool for the built-in tool intents, and only its own tools for a
+// custom intent.
+func (h *GatewayHandler) intentToolPolicy(intent string, policy tools.ToolPolicy) (tools.ToolPolicy, bool) {
+	switch intent {
+	case llm.IntentWeather, llm.IntentCalculator, llm.IntentNews, llm.IntentCode:
+		return policy, true
+	}
+	if custom, ok := h.config.Intents.CustomIntent(intent); ok {
+		return policy.Restrict(custom.Tools), true
+	}
+	return policy, false
+}
+
+// handleToolLoop answers a request with the gateway's tools the policy permits, executing
+// the model's calls until it answers. A request with client tools offers those instead, and
+// the model's first calls of them are returned unexecuted.
+func (h *GatewayHandler) handleToolLoop(c *gin.Context, req api.GenerationRequest, modelID string, policy tools.ToolPolicy, trace *api.DecisionTrace) (*llm.GenerationResult, api.Usage, error) {
+	slog.DebugContext(c.Request.Context(), "Entering tool loop")
 	const maxToolCalls = 5
 	var cumulativeUsage api.Usage
-	modelID := "gpt-4o"
 	client, ok := h.clients[modelID]
 	if !ok {
-		return "", api.Usage{}, "", fmt.Errorf("tool-use model '%s' is not available or enabled", modelID)
+		return nil, api.Usage{}, fmt.Errorf("tool-use model '%s' is not available or enabled", modelID)
 	}
 
-	// --- THIS IS THE NEW LOGIC ---
-	// Construct the full conversation history for the tool-using agent.
-	// Convert the API message history to the internal LLM message type.
-	messages := convertAPIMessagesToLLMMessages(req.History)
-	messages = append(messages, llm.Message{Role: llm.RoleUser, Content: req.Prompt})
-	// --- END OF NEW LOGIC ---
+	// Construct the conversation history for the tool-using agent, trimmed to its context window.
+	messages := h.buildMessages(c.Request.Context(), modelID, req, req.Prompt, trace)
 
 	llmConfig := &llm.GenerationConfig{
-		Model:       modelID,
-		MaxTokens:   req.Config.MaxTokens,
-		Temperature: req.Config.Temperature,
-		TopP:        req.Config.TopP,
-		Stream:      req.Config.Stream,
+		Model:            modelID,
+		MaxTokens:        req.Config.MaxTokens,
+		Temperature:      req.Config.Temperature,
+		TopP:             req.Config.TopP,
+		Stream:           req.Config.Stream,
+		ResponseFormat:   llmResponseFormat(req.ResponseFormat),
+		Logprobs:         req.Config.Logprobs,
+		TopLogprobs:      req.Config.TopLogprobs,
+		Seed:             req.Config.Seed,
+		Stop:             req.Config.Stop,
+		FrequencyPenalty: req.Config.FrequencyPenalty,
+		PresencePenalty:  req.Config.PresencePenalty,
+		TopK:             req.Config.TopK,
+		ReasoningEffort:  req.Config.ReasoningEffort,
+		ThinkingBudget:   req.Config.ThinkingBudget,
 	}
 
+	var definitions []tools.Tool
+	if len(req.Tools) > 0 {
+		// The caller's own tools replace the gateway's, and their calls are the caller's to execute.
+		definitions = clientToolDefinitions(req.Tools)
+	} else {
+		definitions = h.toolManager.GetDefinitionsFor(policy)
+	}
 	for i := 0; i < maxToolCalls; i++ {
-		result, err := client.Generate(c.Request.Context(), messages, llmConfig, h.toolManager.GetDefinitions())
+		result, err := client.Generate(c.Request.Context(), messages, llmConfig, definitions)
 		if err != nil {
 			h.profiler.UpdateProfileOnFailure(c.Request.Context(), modelID)
-			return "", api.Usage{}, "", fmt.Errorf("LLM generation failed during tool loop: %w", err)
+			return nil, api.Usage{}, fmt.Errorf("LLM generation failed during tool loop: %w", err)
 		}
 		cumulativeUsage.Add(result.Usage)
+		if result.Deprecation != nil {
+			h.profiler.RecordDeprecation(c.Request.Context(), result.Deprecation)
+		}
 		if len(result.ToolCalls) == 0 {
-			log.Println("LLM provided final answer. Exiting tool loop.")
-			return result.Content, cumulativeUsage, modelID, nil
+			slog.DebugContext(c.Request.Context(), "LLM provided final answer. Exiting tool loop")
+			answer, extraUsage, err := h.conformToSchema(c.Request.Context(), req, client, messages, llmConfig, result, trace)
+			cumulativeUsage.Add(extraUsage)
+			return answer, cumulativeUsage, err
+		}
+		if len(req.Tools) > 0 {
+			slog.InfoContext(c.Request.Context(), "Model called client tools. Returning the calls to the caller", "calls", len(result.ToolCalls))
+			return result, cumulativeUsage, nil
 		}
 		messages = append(messages, llm.Message{Role: llm.RoleAssistant, Content: result.Content, ToolCalls: result.ToolCalls})
 		for _, toolCall := range result.ToolCalls {
-			log.Printf("🛠️ Executing tool: %s (ID: %s) with args: %s", toolCall.Function.Name, toolCall.ID, toolCall.Function.Arguments)
-			toolResult, err := h.toolManager.Execute(toolCall.Function.Name, toolCall.Function.Arguments)
-			if err != nil {
-				toolResult = fmt.Sprintf("Error executing tool %s: %v", toolCall.Function.Name, err)
-			}
+			toolResult, executed := h.executeToolCall(c.Request.Context(), toolCall, policy)
+			trace.ToolCalls = append(trace.ToolCalls, executed)
 			messages = append(messages, llm.Message{Role: llm.RoleTool, ToolCallID: toolCall.ID, Content: toolResult})
 		}
 	}
-	return "", api.Usage{}, "", errors.New("exceeded maximum number of tool calls")
+	return nil, api.Usage{}, errors.New("exceeded maximum number of tool calls")
+}
+
+// toolTraceResultLimit bounds, in characters, the result of a tool call reported to the caller.
+const toolTraceResultLimit = 1000
+
+// executeToolCall executes a model's tool call if the policy permits it. It returns the
+// result to send back to the model and the call's record for the response.
+func (h *GatewayHandler) executeToolCall(ctx context.Context, toolCall *tools.ToolCall, policy tools.ToolPolicy) (string, api.ExecutedToolCall) {
+	executed := api.ExecutedToolCall{ID: toolCall.ID, Name: toolCall.Function.Name, Args: toolCall.Function.Arguments}
+	start := time.Now()
+	var toolResult string
+	if !policy.Permits(toolCall.Function.Name) {
+		slog.WarnContext(ctx, "Tool is not permitted for this request. Refusing to execute it", "tool", toolCall.Function.Name)
+		toolResult = fmt.Sprintf("Error: tool %s is not permitted for this request.", toolCall.Function.Name)
+		executed.Error = "the tool is not permitted for this request"
+	} else {
+		slog.InfoContext(ctx, "Executing tool", "tool", toolCall.Function.Name, "tool_call_id", toolCall.ID, "arguments", toolCall.Function.Arguments)
+		var err error
+		toolResult, err = h.toolManager.Execute(ctx, toolCall.Function.Name, toolCall.Function.Arguments)
+		if err != nil {
+			toolResult = toolErrorResult(ctx, toolCall.Function.Name, err)
+			executed.Error = err.Error()
+		}
+	}
+	executed.DurationMS = time.Since(start).Milliseconds()
+	executed.Result = toolResult
+	if runes := []rune(toolResult); len(runes) > toolTraceResultLimit {
+		executed.Result, executed.Truncated = string(runes[:toolTraceResultLimit]), true
+	}
+	return toolResult, executed
+}
+
+// toolErrorResult is the tool message that reports a failed tool call to the model. Invalid
+// arguments are reported as a structured list of problems, so the model can correct its call.
+func toolErrorResult(ctx context.Context, name string, err error) string {
+	var argErr *tools.ArgumentError
+	if errors.As(err, &argErr) {
+		slog.WarnContext(ctx, "Tool arguments do not match the tool's parameters. Asking the model to correct them", "tool", name, "problems", len(argErr.Problems))
+		return argErr.Feedback()
+	}
+	return fmt.Sprintf("Error executing tool %s: %v", name, err)
+}
+
+// buildMessages assembles the messages sent to the model: the conversation history followed
+// by the (possibly RAG-augmented) prompt. The oldest history messages are dropped when the
+// total would not fit into the model's context window, and the decision is recorded in the trace.
+func (h *GatewayHandler) buildMessages(ctx context.Context, modelID string, req api.GenerationRequest, finalPrompt string, trace *api.DecisionTrace) []llm.Message {
+	contextWindow := h.router.ContextWindow(modelID)
+	fixedTokens := llm.EstimateTokens(finalPrompt)
+	if req.SystemPrompt != "" {
+		fixedTokens += llm.EstimateTokens(req.SystemPrompt)
+	}
+	if schema := answerSchema(&req); len(schema) > 0 {
+		fixedTokens += llm.EstimateTokens(schemaInstruction(schema))
+	}
+	fit := llm.TruncateHistory(convertAPIMessagesToLLMMessages(req.History), fixedTokens, contextWindow, req.Config.MaxTokens)
+	trace.Context = &api.ContextDecision{
+		ContextWindow:   contextWindow,
+		EstimatedTokens: fit.EstimatedTokens,
+		DroppedMessages: fit.Dropped,
+		Truncated:       fit.Dropped > 0,
+	}
+	if fit.Dropped > 0 {
+		slog.InfoContext(ctx, "Dropped oldest history messages to fit the context window", "dropped", fit.Dropped, "context_window", contextWindow)
+	}
+	messages := make([]llm.Message, 0, len(fit.Messages)+3)
+	if req.SystemPrompt != "" {
+		messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: req.SystemPrompt})
+	}
+	if schema := answerSchema(&req); len(schema) > 0 {
+		messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: schemaInstruction(schema)})
+	}
+	messages = append(messages, fit.Messages...)
+	return append(messages, llm.Message{Role: llm.RoleUser, Content: finalPrompt})
+}
+
+// normalizeSystemPrompt moves a system prompt sent as `system` to SystemPrompt, which the
+// providers are sent as their system instruction.
+func normalizeSystemPrompt(req *api.GenerationRequest) error {
+	if req.System == "" {
+		return nil
+	}
+	if req.SystemPrompt != "" && req.SystemPrompt != req.System {
+		return errors.New("system and system_prompt are the same field; set only one of them")
+	}
+	req.SystemPrompt, req.System = req.System, ""
+	return nil
+}
+
+// resolveSystemPrompt stores a system prompt sent with a conversation, or loads the stored one
+// when the request omits it. Requests without a ConversationID use their system prompt as-is.
+// Only the conversation's owner may set or read its system prompt.
+func (h *GatewayHandler) resolveSystemPrompt(c *gin.Context, req *api.GenerationRequest) {
+	if req.ConversationID == "" {
+		return
+	}
+	ctx := c.Request.Context()
+	if owned, err := h.ownsConversation(ctx, req.ConversationID, req.UserID); err != nil || !owned {
+		slog.WarnContext(ctx, "Ignoring system prompt of a conversation the caller does not own", "owned", owned, "error", err)
+		return
+	}
+	if req.SystemPrompt != "" {
+		policy := h.config.SessionPolicyFor(c.GetHeader(tenantHeader))
+		if err := h.sessions.SetSystemPrompt(ctx, req.ConversationID, req.SystemPrompt, policy.TTL); err != nil {
+			slog.WarnContext(ctx, "Failed to store system prompt", "error", err)
+		}
+		return
+	}
+	session, err := h.sessions.Get(ctx, req.ConversationID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load system prompt", "error", err)
+		return
+	}
+	if session != nil {
+		req.SystemPrompt = session.SystemPrompt
+	}
+}
+
+// hashSystemPrompt condenses a system prompt for cache keys.
+func hashSystemPrompt(systemPrompt string) string {
+	if systemPrompt == "" {
+		return ""
+	}
+	return llm.GenerateCacheKey(systemPrompt)
 }
 
 // --- NEW HELPER FUNCTION ---
@@ -370,8 +1122,10 @@ func convertAPIMessagesToLLMMessages(apiMessages []api.Message) []llm.Message {
 	llmMessages := make([]llm.Message, len(apiMessages))
 	for i, msg := range apiMessages {
 		llmMessages[i] = llm.Message{
-			Role:    llm.Role(msg.Role), // Cast the role string to the llm.Role type
-			Content: msg.Content,
+			Role:       llm.Role(msg.Role), // Cast the role string to the llm.Role type
+			Content:    msg.Content,
+			ToolCalls:  llmToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
 		}
 	}
 	return llmMessages
@@ -0,0 +1,127 @@
+// In file: cmd/gateway/health.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// healthCheckTimeout bounds each dependency check so a hung dependency cannot hang the probe.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthHandler serves the Kubernetes-style probe endpoints:
+//   - /healthz (liveness) reports that the process is running and able to serve HTTP.
+//   - /readyz (readiness) reports whether the gateway can serve traffic: Redis answers, at least
+//     one enabled model is online, and (in the full profile) Pinecone is reachable.
+//   - /startupz (startup) reports whether initialization has finished.
+type HealthHandler struct {
+	rdb        *redis.Client
+	profiler   *llm.Profiler
+	ragService *llm.RAGService
+	config     *AppConfig
+	started    atomic.Bool
+	draining   atomic.Bool
+}
+
+func NewHealthHandler(rdb *redis.Client, profiler *llm.Profiler, ragService *llm.RAGService, config *AppConfig) *HealthHandler {
+	return &HealthHandler{
+		rdb:        rdb,
+		profiler:   profiler,
+		ragService: ragService,
+		config:     config,
+	}
+}
+
+// MarkStarted flips /startupz to healthy. It is called once every service and background
+// process has been initialized.
+func (h *HealthHandler) MarkStarted() {
+	h.started.Store(true)
+}
+
+// MarkDraining flips /readyz to unhealthy for the rest of the process's life, so load
+// balancers stop sending traffic while in-flight requests finish.
+func (h *HealthHandler) MarkDraining() {
+	h.draining.Store(true)
+}
+
+// healthCheckResult is the outcome of a single readiness check.
+type healthCheckResult struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HandleLiveness always succeeds: if the handler runs, the process is alive.
+func (h *HealthHandler) HandleLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// HandleStartup succeeds once initialization has finished.
+func (h *HealthHandler) HandleStartup(c *gin.Context) {
+	if !h.started.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "starting"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "started"})
+}
+
+// HandleReadiness runs every dependency check and reports 503 if any of them fails.
+func (h *HealthHandler) HandleReadiness(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	checks := map[string]func(context.Context) error{
+		"redis":  h.checkRedis,
+		"models": h.checkModels,
+	}
+	if !h.config.IsMinimal() {
+		checks["pinecone"] = h.ragService.PingPinecone
+	}
+
+	results := make(map[string]healthCheckResult, len(checks))
+	ready := h.started.Load()
+	for name, check := range checks {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		err := check(ctx)
+		cancel()
+		if err != nil {
+			ready = false
+			results[name] = healthCheckResult{OK: false, Error: err.Error()}
+			continue
+		}
+		results[name] = healthCheckResult{OK: true}
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "checks": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": results})
+}
+
+func (h *HealthHandler) checkRedis(ctx context.Context) error {
+	return h.rdb.Ping(ctx).Err()
+}
+
+// checkModels passes when at least one enabled model is online. Models without a profile
+// have not failed yet and count as online, matching how the router treats them.
+func (h *HealthHandler) checkModels(ctx context.Context) error {
+	for _, modelID := range h.config.EnabledModels {
+		status, err := h.profiler.GetStatus(ctx, modelID)
+		if err != nil {
+			return err
+		}
+		if status == "" || status == "online" {
+			return nil
+		}
+	}
+	return errors.New("no enabled model is online")
+}
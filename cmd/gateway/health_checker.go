@@ -0,0 +1,264 @@
+// In file: cmd/gateway/health_checker.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+)
+
+const (
+	// probeGenerate asks the model for a few tokens, proving that generation works.
+	probeGenerate = "generate"
+	// probePing fetches the model from the provider's model list, which costs nothing.
+	probePing = "ping"
+)
+
+// HealthCheckConfig is the `health_check` section of config.yaml. Each model is probed on its
+// own schedule, so a provider that hangs never delays the checks of the others.
+type HealthCheckConfig struct {
+	// Interval is the time between two probes of a model.
+	Interval time.Duration `yaml:"interval"`
+	// Jitter is the largest random delay added to each interval, which spreads the probes of
+	// models that share an interval. It defaults to a tenth of the interval.
+	Jitter time.Duration `yaml:"jitter"`
+	// Timeout bounds a single probe.
+	Timeout time.Duration `yaml:"timeout"`
+	// Probe is "generate" or "ping". Clients that cannot ping are probed with "generate".
+	Probe string `yaml:"probe"`
+	// Prompt and MaxTokens are the request of a "generate" probe.
+	Prompt    string `yaml:"prompt"`
+	MaxTokens int    `yaml:"max_tokens"`
+	// FailureThreshold is how many probes in a row must fail before the model is marked
+	// offline. Until then, a failed probe marks it degraded. It defaults to 1.
+	FailureThreshold int `yaml:"failure_threshold"`
+	// Providers overrides the settings of every model of a provider, and Models those of
+	// individual models, which take precedence.
+	Providers map[string]ModelHealthCheck `yaml:"providers"`
+	Models    map[string]ModelHealthCheck `yaml:"models"`
+}
+
+// ModelHealthCheck overrides the health check settings of a provider or model. Unset
+// settings are inherited.
+type ModelHealthCheck struct {
+	Interval         time.Duration `yaml:"interval"`
+	Timeout          time.Duration `yaml:"timeout"`
+	Probe            string        `yaml:"probe"`
+	Prompt           string        `yaml:"prompt"`
+	MaxTokens        int           `yaml:"max_tokens"`
+	FailureThreshold int           `yaml:"failure_threshold"`
+}
+
+// validate reports override settings that cannot work.
+func (m ModelHealthCheck) validate() error {
+	if m.Interval < 0 || m.Timeout < 0 || m.MaxTokens < 0 || m.FailureThreshold < 0 {
+		return fmt.Errorf("interval, timeout, max_tokens, and failure_threshold must not be negative")
+	}
+	return validateProbe(m.Probe)
+}
+
+// withDefaults fills in the settings that were left unset.
+func (c HealthCheckConfig) withDefaults() HealthCheckConfig {
+	if c.Interval == 0 {
+		c.Interval = 5 * time.Minute
+	}
+	if c.Jitter == 0 {
+		c.Jitter = c.Interval / 10
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.Probe == "" {
+		c.Probe = probeGenerate
+	}
+	if c.Prompt == "" {
+		c.Prompt = "What is the capital of India?"
+	}
+	if c.MaxTokens == 0 {
+		c.MaxTokens = 5
+	}
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 1
+	}
+	return c
+}
+
+// settingsFor returns the settings of a model: its own overrides, over its provider's, over
+// the defaults.
+func (c HealthCheckConfig) settingsFor(modelID, provider string) ModelHealthCheck {
+	s := ModelHealthCheck{
+		Interval:         c.Interval,
+		Timeout:          c.Timeout,
+		Probe:            c.Probe,
+		Prompt:           c.Prompt,
+		MaxTokens:        c.MaxTokens,
+		FailureThreshold: c.FailureThreshold,
+	}
+	for _, override := range []ModelHealthCheck{c.Providers[provider], c.Models[modelID]} {
+		if override.Interval > 0 {
+			s.Interval = override.Interval
+		}
+		if override.Timeout > 0 {
+			s.Timeout = override.Timeout
+		}
+		if override.Probe != "" {
+			s.Probe = override.Probe
+		}
+		if override.Prompt != "" {
+			s.Prompt = override.Prompt
+		}
+		if override.MaxTokens > 0 {
+			s.MaxTokens = override.MaxTokens
+		}
+		if override.FailureThreshold > 0 {
+			s.FailureThreshold = override.FailureThreshold
+		}
+	}
+	return s
+}
+
+// Validate reports settings that cannot work.
+func (c HealthCheckConfig) Validate() error {
+	if c.Jitter < 0 {
+		return fmt.Errorf("jitter must not be negative")
+	}
+	defaults := ModelHealthCheck{Interval: c.Interval, Timeout: c.Timeout, Probe: c.Probe, MaxTokens: c.MaxTokens, FailureThreshold: c.FailureThreshold}
+	if err := defaults.validate(); err != nil {
+		return err
+	}
+	for provider, m := range c.Providers {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("provider %s: %w", provider, err)
+		}
+	}
+	for modelID, m := range c.Models {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("model %s: %w", modelID, err)
+		}
+	}
+	return nil
+}
+
+func validateProbe(probe string) error {
+	switch probe {
+	case "", probeGenerate, probePing:
+		return nil
+	}
+	return fmt.Errorf("unknown probe '%s' (expected '%s' or '%s')", probe, probeGenerate, probePing)
+}
+
+// modelProber probes the health of one model.
+type modelProber struct {
+	modelID  string
+	client   llm.LLMClient
+	settings ModelHealthCheck
+	jitter   time.Duration
+	profiler *llm.Profiler
+	// running is set while a probe is in flight, so a hung provider is never probed twice at once.
+	running atomic.Bool
+	// failures counts the probes that failed in a row. Only check, guarded by running, uses it.
+	failures int
+}
+
+// startHealthChecker proactively checks the health of every model, each on its own jittered
+// schedule. When several replicas are deployed, only the elected leader runs the probes;
+// results are written to the shared Redis profiles, so followers route on the same health data.
+func startHealthChecker(cfg HealthCheckConfig, routerConfig *llm.RouterConfig, models []string, clients map[string]llm.LLMClient, profiler *llm.Profiler, leader *llm.LeaderElector) {
+	cfg = cfg.withDefaults()
+	var probers []*modelProber
+	for _, modelID := range models {
+		client, ok := clients[modelID]
+		if !ok {
+			continue
+		}
+		p := &modelProber{
+			modelID:  modelID,
+			client:   client,
+			settings: cfg.settingsFor(modelID, routerConfig.ProviderOf(modelID)),
+			jitter:   cfg.Jitter,
+			profiler: profiler,
+		}
+		probers = append(probers, p)
+		go p.run(leader)
+	}
+	slog.Info("Health checker started.", "models", len(probers), "interval", cfg.Interval, "probe", cfg.Probe)
+
+	// A newly elected leader probes immediately, so profiles never go stale for longer
+	// than the lease TTL after the previous leader disappears.
+	for range leader.Elected() {
+		for _, p := range probers {
+			go p.check()
+		}
+	}
+}
+
+// run probes the model after every interval plus a random jitter, starting at a random
+// point of the first interval so models do not all start at once.
+func (p *modelProber) run(leader *llm.LeaderElector) {
+	time.Sleep(randomDuration(p.settings.Interval))
+	for {
+		if leader.IsLeader() {
+			go p.check()
+		} else {
+			slog.Debug("Not the health-check leader. Skipping proactive health check.", "model", p.modelID)
+		}
+		time.Sleep(p.settings.Interval + randomDuration(p.jitter))
+	}
+}
+
+// check probes the model once and records the result in its profile. A failed probe only
+// marks the model offline once FailureThreshold probes in a row have failed.
+func (p *modelProber) check() {
+	if !p.running.CompareAndSwap(false, true) {
+		slog.Warn("Previous health check still running. Skipping.", "model", p.modelID)
+		return
+	}
+	defer p.running.Store(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.settings.Timeout)
+	defer cancel()
+	probe := p.settings.Probe
+	var err error
+	if probe == probePing {
+		if err = llm.Ping(ctx, p.client, p.modelID); errors.Is(err, llm.ErrPingUnsupported) {
+			probe = probeGenerate
+		}
+	}
+	if probe == probeGenerate {
+		config := &llm.GenerationConfig{Model: p.modelID, MaxTokens: p.settings.MaxTokens}
+		healthCheckPrompt := []llm.Message{{Role: llm.RoleUser, Content: p.settings.Prompt}}
+		_, err = p.client.Generate(ctx, healthCheckPrompt, config, nil)
+	}
+
+	isHealthy := err == nil
+	status := "online"
+	if isHealthy {
+		p.failures = 0
+	} else {
+		p.failures++
+		status = "degraded"
+		if p.failures >= p.settings.FailureThreshold {
+			status = "offline"
+		}
+	}
+	p.profiler.UpdateProfileOnHealthCheck(context.Background(), p.modelID, status)
+	if isHealthy {
+		slog.Info("Health check finished", "model", p.modelID, "probe", probe, "healthy", isHealthy)
+	} else {
+		slog.Warn("Health check finished", "model", p.modelID, "probe", probe, "healthy", isHealthy, "status", status, "consecutive_failures", p.failures, "error", err)
+	}
+}
+
+// randomDuration returns a random duration in [0, limit).
+func randomDuration(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit)
+}
@@ -0,0 +1,173 @@
+// In file: cmd/gateway/limits.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes of rejected /generate requests.
+const (
+	codeInvalidRequest        = "invalid_request"
+	codeRequestTooLarge       = "request_too_large"
+	codePromptTooLong         = "prompt_too_long"
+	codeHistoryTooLong        = "history_too_long"
+	codeMaxTokensTooLarge     = "max_tokens_too_large"
+	codeInvalidMaxTokens      = "invalid_max_tokens"
+	codeInvalidParameter      = "invalid_parameter"
+	codeInvalidResponseSchema = "invalid_response_schema"
+	codeInvalidResponseFormat = "invalid_response_format"
+	codeInvalidTools          = "invalid_tools"
+	codeCostCapExceeded       = "cost_cap_exceeded"
+	codeUserBudgetExceeded    = "user_budget_exceeded"
+	codeTooManyJobs           = "too_many_jobs"
+	codeConversationNotFound  = "conversation_not_found"
+	codeUserMismatch          = "user_mismatch"
+	codeUserRequired          = "user_required"
+)
+
+// maxTopLogprobs is the largest top_logprobs a provider accepts.
+const maxTopLogprobs = 20
+
+// maxRAGTopK is the most knowledge base chunks a request can retrieve.
+const maxRAGTopK = 20
+
+// maxStopSequences is the most stop sequences every provider accepts (OpenAI's limit).
+const maxStopSequences = 4
+
+// requestError describes why a request was rejected. Limit and Actual are set for
+// violated size limits, so clients can tell how far over the limit they were.
+type requestError struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+	Code    string `json:"code"`
+	Limit   int64  `json:"limit,omitempty"`
+	Actual  int64  `json:"actual,omitempty"`
+	// MaxCostUSD, Model, and EstimatedCostUSD are set when no model fits the request's cost
+	// cap: the cap, and the cheapest model with its estimated cost.
+	MaxCostUSD       float64 `json:"max_cost_usd,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"`
+	// BudgetUSD and SpentUSD are set when the user has spent their monthly budget.
+	BudgetUSD float64 `json:"budget_usd,omitempty"`
+	SpentUSD  float64 `json:"spent_usd,omitempty"`
+}
+
+func (e *requestError) Error() string { return e.Message }
+
+// limitExceeded builds the error for a size limit that was exceeded.
+func limitExceeded(status int, code, what string, limit, actual int) *requestError {
+	return &requestError{
+		Status:  status,
+		Message: fmt.Sprintf("%s exceeds the limit of %d (got %d)", what, limit, actual),
+		Code:    code,
+		Limit:   int64(limit),
+		Actual:  int64(actual),
+	}
+}
+
+// bindGenerationRequest decodes a /generate body, refusing bodies larger than the limit
+// before they are read in full.
+func bindGenerationRequest(c *gin.Context, limits RequestLimits, req *api.GenerationRequest) *requestError {
+	if limits.MaxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes)
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &requestError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("request body exceeds the limit of %d bytes", tooLarge.Limit),
+				Code:    codeRequestTooLarge,
+				Limit:   tooLarge.Limit,
+			}
+		}
+		return &requestError{Status: http.StatusBadRequest, Message: "Invalid request: " + err.Error(), Code: codeInvalidRequest}
+	}
+	return nil
+}
+
+// checkLimits validates the prompt, history, and generation parameters of a request.
+func (l RequestLimits) checkLimits(req *api.GenerationRequest) *requestError {
+	if n := utf8.RuneCountInString(req.Prompt); l.MaxPromptChars > 0 && n > l.MaxPromptChars {
+		return limitExceeded(http.StatusRequestEntityTooLarge, codePromptTooLong, "prompt length in characters", l.MaxPromptChars, n)
+	}
+	if n := len(req.History); l.MaxHistoryMessages > 0 && n > l.MaxHistoryMessages {
+		return limitExceeded(http.StatusRequestEntityTooLarge, codeHistoryTooLong, "number of history messages", l.MaxHistoryMessages, n)
+	}
+	if l.MaxHistoryChars > 0 {
+		n := 0
+		for _, msg := range req.History {
+			n += utf8.RuneCountInString(msg.Content)
+		}
+		if n > l.MaxHistoryChars {
+			return limitExceeded(http.StatusRequestEntityTooLarge, codeHistoryTooLong, "history length in characters", l.MaxHistoryChars, n)
+		}
+	}
+
+	if req.Config.MaxTokens < 0 {
+		return &requestError{Status: http.StatusBadRequest, Message: "max_tokens must not be negative", Code: codeInvalidMaxTokens}
+	}
+	if l.MaxTokens > 0 && req.Config.MaxTokens > l.MaxTokens {
+		return limitExceeded(http.StatusBadRequest, codeMaxTokensTooLarge, "max_tokens", l.MaxTokens, req.Config.MaxTokens)
+	}
+	if t := req.Config.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return &requestError{Status: http.StatusBadRequest, Message: "temperature must be between 0 and 2", Code: codeInvalidParameter}
+	}
+	if p := req.Config.TopP; p != nil && (*p < 0 || *p > 1) {
+		return &requestError{Status: http.StatusBadRequest, Message: "top_p must be between 0 and 1", Code: codeInvalidParameter}
+	}
+	if p := req.Config.FrequencyPenalty; p != nil && (*p < -2 || *p > 2) {
+		return &requestError{Status: http.StatusBadRequest, Message: "frequency_penalty must be between -2 and 2", Code: codeInvalidParameter}
+	}
+	if p := req.Config.PresencePenalty; p != nil && (*p < -2 || *p > 2) {
+		return &requestError{Status: http.StatusBadRequest, Message: "presence_penalty must be between -2 and 2", Code: codeInvalidParameter}
+	}
+	if k := req.Config.TopK; k != nil && *k < 1 {
+		return &requestError{Status: http.StatusBadRequest, Message: "top_k must be at least 1", Code: codeInvalidParameter}
+	}
+	switch req.Config.ReasoningEffort {
+	case "", llm.ReasoningEffortLow, llm.ReasoningEffortMedium, llm.ReasoningEffortHigh:
+	default:
+		return &requestError{Status: http.StatusBadRequest, Message: "reasoning_effort must be low, medium, or high", Code: codeInvalidParameter}
+	}
+	if b := req.Config.ThinkingBudget; b != 0 && b < llm.MinThinkingBudget {
+		return &requestError{Status: http.StatusBadRequest, Message: fmt.Sprintf("thinking_budget must be at least %d", llm.MinThinkingBudget), Code: codeInvalidParameter}
+	}
+	if n := req.Config.TopLogprobs; n < 0 || n > maxTopLogprobs {
+		return &requestError{Status: http.StatusBadRequest, Message: fmt.Sprintf("top_logprobs must be between 0 and %d", maxTopLogprobs), Code: codeInvalidParameter}
+	}
+	if req.Config.TopLogprobs > 0 && !req.Config.Logprobs {
+		return &requestError{Status: http.StatusBadRequest, Message: "top_logprobs requires logprobs", Code: codeInvalidParameter}
+	}
+	if n := len(req.Config.Stop); n > maxStopSequences {
+		return &requestError{Status: http.StatusBadRequest, Message: fmt.Sprintf("stop accepts at most %d sequences (got %d)", maxStopSequences, n), Code: codeInvalidParameter}
+	}
+	for _, stop := range req.Config.Stop {
+		if stop == "" {
+			return &requestError{Status: http.StatusBadRequest, Message: "stop sequences must not be empty", Code: codeInvalidParameter}
+		}
+	}
+	if req.Config.MaxCostUSD < 0 {
+		return &requestError{Status: http.StatusBadRequest, Message: "max_cost_usd must not be negative", Code: codeInvalidParameter}
+	}
+	if rag := req.RAG; rag != nil {
+		if rag.TopK < 0 || rag.TopK > maxRAGTopK {
+			return &requestError{Status: http.StatusBadRequest, Message: fmt.Sprintf("rag.top_k must be between 1 and %d", maxRAGTopK), Code: codeInvalidParameter}
+		}
+		if s := rag.MinScore; s != nil && (*s < 0 || *s > 1) {
+			return &requestError{Status: http.StatusBadRequest, Message: "rag.min_score must be between 0 and 1", Code: codeInvalidParameter}
+		}
+	}
+	if req.Config.Logprobs && req.Config.Stream {
+		return &requestError{Status: http.StatusBadRequest, Message: "logprobs cannot be combined with streaming", Code: codeInvalidParameter}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// In file: cmd/gateway/logging.go
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader carries the request ID. An ID sent by the caller (e.g. a load balancer)
+// is reused; otherwise one is generated. It is echoed back on the response.
+const requestIDHeader = "X-Request-ID"
+
+// probePaths are the health probe endpoints, whose successful requests are logged at debug level.
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true, "/startupz": true}
+
+// requestLogger attaches a request ID to the request's context, so every log line made while
+// serving it carries the ID, and writes one structured access log line per request.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+		withLogFields(c, logging.RequestIDKey, requestID)
+
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		} else if probePaths[c.FullPath()] {
+			// Probes hit the gateway every few seconds; only their failures are worth an info line.
+			level = slog.LevelDebug
+		}
+		slog.Log(c.Request.Context(), level, "Request completed",
+			"method", c.Request.Method,
+			"path", c.FullPath(),
+			"status", c.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// withLogFields adds correlation fields to the request's context for all later log lines.
+func withLogFields(c *gin.Context, keyValues ...string) {
+	c.Request = c.Request.WithContext(logging.WithFields(c.Request.Context(), keyValues...))
+}
+
+// fatal logs an error and exits. It stands in for log.Fatalf, which slog has no equivalent of.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -2,10 +2,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,105 +15,309 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dileep-u-k/llm-gateway/internal/auth"
+	"github.com/dileep-u-k/llm-gateway/internal/ingest"
 	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/dileep-u-k/llm-gateway/internal/logging"
+	"github.com/dileep-u-k/llm-gateway/internal/pii"
+	"github.com/dileep-u-k/llm-gateway/internal/ratelimit"
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
 	"github.com/dileep-u-k/llm-gateway/internal/tools"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // main is the entry point for the application.
 // Its primary role is the "Composition Root": it loads configuration,
 // initializes all services, injects dependencies, and starts the server.
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+	logging.Setup()
 	buildInfo := GetBuildInfo()
-	log.Printf("🚀 Starting LLM Gateway | Version: %s | Commit: %s", buildInfo.Version, buildInfo.GitCommit)
+	slog.Info("Starting LLM Gateway", "version", buildInfo.Version, "commit", buildInfo.GitCommit)
 
 	// 1. LOAD CONFIGURATION
 	cfg, err := LoadConfig()
 	if err != nil {
-		log.Fatalf("❌ FATAL: Configuration Error: %v", err)
+		fatal("Configuration error", "error", err)
 	}
+	logging.SetLevel(cfg.LogLevel)
 	llm.InitializeModelCosts(cfg.ModelCosts)
-	log.Println("✅ Configuration loaded.")
+	slog.Info("Configuration loaded.")
+
+	shutdownTracing, err := telemetry.Setup(context.Background(), "llm-gateway", buildInfo.Version)
+	if err != nil {
+		fatal("Could not initialize tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	// 2. INITIALIZE SERVICES
 	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
 	if _, err := rdb.Ping(context.Background()).Result(); err != nil {
-		log.Fatalf("❌ FATAL: Could not connect to Redis: %v", err)
+		fatal("Could not connect to Redis", "error", err)
 	}
 
 	llmClients, err := initializeLLMClients(cfg)
 	if err != nil {
-		log.Fatalf("❌ FATAL: %v", err)
+		fatal("Could not initialize LLM clients", "error", err)
 	}
 
 	profiler := llm.NewProfiler(rdb)
 	ragService, err := llm.NewRAGService(cfg.RAGConfig)
 	if err != nil {
-		log.Fatalf("❌ FATAL: Could not create RAG service: %v", err)
+		fatal("Could not create RAG service", "error", err)
 	}
 
-	intentAnalyzer := llm.NewIntentAnalyzer()
 	router := llm.NewRouter(profiler, cfg.RouterConfig)
-	toolManager, err := initializeToolManager(cfg)
-	if err != nil {
-		log.Fatalf("❌ FATAL: %v", err)
+
+	// The minimal profile is a pure routing core: no intent analysis, tools, or RAG ingestion.
+	decisions := decisionCache(cfg, rdb)
+	var intentAnalyzer *llm.IntentAnalyzer
+	var toolManager *tools.ToolManager
+	var ingestPipeline *ingest.Pipeline
+	if cfg.IsMinimal() {
+		slog.Info("Running the minimal gateway profile (RAG, tools, and intent analysis disabled).")
+	} else {
+		intentAnalyzer, err = initializeIntentAnalyzer(cfg, ragService, llmClients, rdb)
+		if err != nil {
+			fatal("Could not initialize intent analysis", "error", err)
+		}
+		if decisions != nil {
+			intentAnalyzer.SetDecisionCache(decisions)
+		}
+		toolManager, err = initializeToolManager(cfg)
+		if err != nil {
+			fatal("Could not initialize tools", "error", err)
+		}
+		for _, custom := range cfg.Intents.Custom {
+			for _, name := range custom.Tools {
+				if !toolManager.HasTool(name) {
+					fatal("A custom intent uses a tool that is not registered", "intent", custom.Name, "tool", name)
+				}
+			}
+		}
+		ingestPipeline = initializeIngestPipeline(cfg, ragService)
 	}
 
 	// *** NEW: Initialize the PromptAnalyzer service. ***
 	// This service will automatically select a routing preference if the user does not provide one.
-	promptAnalyzer := llm.NewPromptAnalyzer()
+	promptAnalyzer, err := initializePromptAnalyzer(cfg, llmClients, rdb)
+	if err != nil {
+		fatal("Could not initialize the prompt analyzer", "error", err)
+	}
+	if decisions != nil {
+		promptAnalyzer.SetDecisionCache(decisions)
+	}
+
+	conversations := llm.NewRedisConversationStore(rdb, cfg.ConversationMaxMessages, cfg.ConversationTTL)
+	sessions := initializeSessionStore(cfg, rdb)
+	auditWriter, err := initializeAuditWriter(context.Background(), cfg.Audit)
+	if err != nil {
+		fatal("Could not initialize the audit log", "error", err)
+	}
+	moderator, err := initializeModeration(cfg)
+	if err != nil {
+		fatal("Could not initialize content moderation", "error", err)
+	}
+
+	concurrency := ratelimit.NewConcurrencyLimiter(cfg.Concurrency)
 
 	// *** MODIFIED: Inject the new promptAnalyzer into the GatewayHandler. ***
-	gatewayHandler := NewGatewayHandler(llmClients, profiler, router, ragService, intentAnalyzer, toolManager, promptAnalyzer, cfg, rdb)
-	log.Println("✅ All services initialized.")
+	gatewayHandler := NewGatewayHandler(llmClients, profiler, router, ragService, intentAnalyzer, toolManager, promptAnalyzer, conversations, sessions, auditWriter, moderator, concurrency, cfg)
+	conversationHandler := NewConversationHandler(conversations)
+	adminHandler := NewAdminHandler(profiler, router, ragService, cfg)
+	metricsHandler := NewMetricsHandler(profiler, router, cfg)
+	healthHandler := NewHealthHandler(rdb, profiler, ragService, cfg)
+
+	var authMiddleware []gin.HandlerFunc
+	if cfg.AuthMode == AuthModeOIDC {
+		verifier, err := auth.NewVerifier(context.Background(), cfg.OIDC)
+		if err != nil {
+			fatal("Could not initialize OIDC authentication", "error", err)
+		}
+		authMiddleware = append(authMiddleware, requireOIDCToken(verifier))
+		slog.Info("OIDC authentication enabled", "issuer", cfg.OIDC.Issuer)
+	}
+	slog.Info("All services initialized.")
 
 	// 3. START BACKGROUND PROCESSES
-	go startHealthChecker(cfg.EnabledModels, llmClients, profiler)
+	// Only the replica holding the lease probes providers; the others read the shared profiles.
+	healthLeader := llm.NewLeaderElector(rdb, "leader:health-checker", 30*time.Second)
+	go healthLeader.Run(context.Background())
+	go startHealthChecker(cfg.HealthCheck, cfg.RouterConfig, cfg.EnabledModels, llmClients, profiler, healthLeader)
+	go startDeprecationDigest(cfg, profiler, healthLeader)
+	go startProfileSnapshots(cfg.ProfileHistory, cfg.EnabledModels, profiler, healthLeader)
+	if ingestPipeline != nil {
+		ingestPipeline.Start(context.Background(), 2)
+	}
+	if cfg.SecretsRefreshInterval > 0 && len(cfg.APIKeyRefs) > 0 {
+		go cfg.Secrets.Run(context.Background(), cfg.SecretsRefreshInterval)
+	}
 
 	// 4. SETUP AND RUN THE WEB SERVER
 	gin.SetMode(os.Getenv("GIN_MODE"))
-	engine := gin.Default()
+	engine := gin.New()
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("Invalid server.trusted_proxies", "error", err)
+	}
+	engine.Use(gin.Recovery(), otelgin.Middleware("llm-gateway"), requestLogger())
 	v1 := engine.Group("/api/v1")
 	{
-		v1.POST("/generate", gatewayHandler.HandleGeneration)
+		// Webhooks are registered outside this group: they authenticate with their own signatures.
+		callers := v1.Group("", authMiddleware...)
+		callers.POST("/generate", limitConcurrency(concurrency, cfg), rateLimit(ratelimit.NewLimiter(rdb), cfg), gatewayHandler.HandleGeneration)
+		if cfg.Passthrough.Enabled {
+			callers.POST("/passthrough/*model", limitConcurrency(concurrency, cfg), rateLimit(ratelimit.NewLimiter(rdb), cfg), gatewayHandler.HandlePassthrough)
+		}
+		callers.POST("/tokens/count", rateLimit(ratelimit.NewLimiter(rdb), cfg), gatewayHandler.HandleCountTokens)
+		callers.GET("/conversations", conversationHandler.HandleList)
+		callers.GET("/conversations/:id", conversationHandler.HandleGet)
+		callers.PATCH("/conversations/:id", conversationHandler.HandleRename)
+		callers.DELETE("/conversations/:id", conversationHandler.HandleDelete)
+		callers.GET("/conversations/:id/export", conversationHandler.HandleExport)
+		if ingestPipeline != nil {
+			webhookHandler := NewWebhookHandler(ingestPipeline)
+			v1.POST("/webhooks/ingest/:source", webhookHandler.HandleIngestWebhook)
+			documentHandler := NewDocumentHandler(ingestPipeline, ingest.NewRedisDocumentStore(rdb))
+			// Uploads are parsed and embedded while the caller waits, so documents are limited like generations.
+			documents := callers.Group("/documents", limitConcurrency(concurrency, cfg), rateLimit(ratelimit.NewLimiter(rdb), cfg))
+			documents.POST("", documentHandler.HandleUpload)
+			documents.GET("", documentHandler.HandleList)
+			documents.DELETE("/:id", documentHandler.HandleDelete)
+		}
+	}
+	// The Anthropic-compatible surface; SDKs take http://<gateway>/anthropic as their base URL.
+	anthropicCompat := engine.Group("/anthropic", append([]gin.HandlerFunc{anthropicResponses()}, authMiddleware...)...)
+	anthropicCompat.POST("/v1/messages", limitConcurrency(concurrency, cfg), rateLimit(ratelimit.NewLimiter(rdb), cfg), gatewayHandler.HandleAnthropicMessages)
+	admin := engine.Group("/admin", requireAdminKey(cfg.AdminAPIKey))
+	{
+		admin.GET("/deprecations", adminHandler.HandleDeprecations)
+		admin.GET("/costs", adminHandler.HandleCosts)
+		admin.GET("/costs/:dimension", adminHandler.HandleCosts)
+		admin.GET("/costs/:dimension/:key", adminHandler.HandleSpend)
+		admin.GET("/usage", adminHandler.HandleUsage)
+		admin.GET("/dashboard", adminHandler.HandleDashboard)
+		admin.GET("/models", adminHandler.HandleModels)
+		admin.GET("/models/:id/history", adminHandler.HandleModelHistory)
+		admin.GET("/profiles/export", adminHandler.HandleExportProfiles)
+		admin.POST("/profiles/import", adminHandler.HandleImportProfiles)
+		admin.POST("/models/:id/enable", adminHandler.HandleEnableModel)
+		admin.POST("/models/:id/disable", adminHandler.HandleDisableModel)
 	}
+	openAPIHandler, err := NewOpenAPIHandler(cfg, ingestPipeline != nil)
+	if err != nil {
+		fatal("Could not generate the OpenAPI specification", "error", err)
+	}
+	engine.GET("/openapi.json", openAPIHandler.HandleSpec)
+	engine.GET("/docs", openAPIHandler.HandleDocs)
+	engine.GET("/metrics", metricsHandler.HandleMetrics)
+	engine.GET("/healthz", healthHandler.HandleLiveness)
+	engine.GET("/readyz", healthHandler.HandleReadiness)
+	engine.GET("/startupz", healthHandler.HandleStartup)
 
 	srv := &http.Server{Addr: fmt.Sprintf(":%s", os.Getenv("PORT")), Handler: engine}
-	runServerWithGracefulShutdown(srv)
+	healthHandler.MarkStarted()
+	runServerWithGracefulShutdown(srv, cfg.ShutdownDrainTimeout, gatewayHandler.streams, healthHandler)
+
+	// Apply the profile updates and flush the audit records of the requests that completed
+	// during shutdown.
+	profiler.Close()
+	if auditWriter != nil {
+		if err := auditWriter.Close(); err != nil {
+			slog.Error("Failed to close the audit log", "error", err)
+		}
+	}
 }
 
 // initializeLLMClients creates instances of the LLM clients based on config.
 func initializeLLMClients(cfg *AppConfig) (map[string]llm.LLMClient, error) {
 	clients := make(map[string]llm.LLMClient)
 	var err error
+
+	var detector *pii.Detector
+	if cfg.PII.Mode != pii.ModeOff {
+		if detector, err = pii.NewDetector(cfg.PII); err != nil {
+			return nil, fmt.Errorf("invalid pii config: %w", err)
+		}
+		slog.Info("PII redaction enabled", "mode", cfg.PII.Mode)
+	}
+	// One pooled transport is shared by every provider client, so connections are reused
+	// across models of the same provider instead of re-established per call.
+	transport := llm.NewTransport(cfg.RouterConfig.Transport)
 	for modelID := range cfg.APIKeys {
-		apiKey := cfg.APIKeys[modelID]
+		policy := cfg.RouterConfig.RequestPolicyFor(modelID)
+		provider := cfg.RouterConfig.ProviderOf(modelID)
+		build := func(apiKey string) (llm.LLMClient, error) {
+			return newProviderClient(provider, modelID, apiKey, policy, transport)
+		}
 		var client llm.LLMClient
-		switch {
-		case strings.HasPrefix(modelID, "gpt"):
-			client, err = llm.NewOpenAIClient(apiKey)
-		case strings.HasPrefix(modelID, "claude"):
-			client, err = llm.NewAnthropicClient(apiKey)
-		case strings.HasPrefix(modelID, "gemini"):
-			client, err = llm.NewGeminiClient(apiKey, modelID)
-		case strings.HasPrefix(modelID, "mistral"):
-			client, err = llm.NewMistralClient(apiKey)
-		default:
-			log.Printf("WARNING: Unknown model provider for %s, skipping.", modelID)
+		// Keys held in a secrets manager may be rotated; the client is rebuilt when they are.
+		if ref, ok := cfg.APIKeyRefs[modelID]; ok {
+			var rotating *llm.RotatingClient
+			rotating, err = llm.NewRotatingClient(cfg.APIKeys[modelID], build)
+			if err == nil {
+				cfg.Secrets.Watch(ref, func(apiKey string) {
+					if err := rotating.Rotate(apiKey); err != nil {
+						slog.Error("Failed to rebuild client with the rotated API key", "model", modelID, "error", err)
+					}
+				})
+				client = rotating
+			}
+		} else {
+			client, err = build(cfg.APIKeys[modelID])
+		}
+		if errors.Is(err, errUnknownProvider) {
+			slog.Warn("Unknown model provider, skipping", "model", modelID)
 			continue
 		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to create client for %s: %w", modelID, err)
 		}
+		client = llm.NewTracedClient(client, modelID)
+		// Redaction wraps tracing, so spans never describe unredacted messages.
+		if detector != nil {
+			client = llm.NewRedactingClient(client, detector, cfg.PII.Mode == pii.ModeMask)
+		}
 		clients[modelID] = client
 	}
-	log.Printf("✅ %d LLM clients initialized.", len(clients))
+	slog.Info("LLM clients initialized", "clients", len(clients))
 	return clients, nil
 }
 
+// errUnknownProvider is returned for models whose provider the gateway has no client for.
+var errUnknownProvider = errors.New("unknown model provider")
+
+// newProviderClient creates the provider client for a model, with its request policy and the
+// shared transport.
+func newProviderClient(provider, modelID, apiKey string, policy llm.RequestPolicy, transport http.RoundTripper) (llm.LLMClient, error) {
+	switch provider {
+	case "openai":
+		return llm.NewOpenAIClient(apiKey, policy, transport)
+	case "anthropic":
+		return llm.NewAnthropicClient(apiKey, policy, transport)
+	case "google":
+		return llm.NewGeminiClient(apiKey, modelID, policy, transport)
+	case "mistral":
+		return llm.NewMistralClient(apiKey, policy, transport)
+	default:
+		return nil, errUnknownProvider
+	}
+}
+
+// initializeSessionStore creates the configured session store. The in-memory store is only
+// suitable for a single replica, since pinning would otherwise differ between replicas.
+func initializeSessionStore(cfg *AppConfig, rdb *redis.Client) llm.SessionStore {
+	if cfg.SessionStore == "memory" {
+		slog.Info("Using the in-memory session store. Sessions are not shared between replicas.")
+		return llm.NewInMemorySessionStore()
+	}
+	return llm.NewRedisSessionStore(rdb)
+}
+
 // initializeToolManager creates and registers all available tools.
 func initializeToolManager(cfg *AppConfig) (*tools.ToolManager, error) {
 	manager := tools.NewToolManager()
@@ -127,49 +333,171 @@ func initializeToolManager(cfg *AppConfig) (*tools.ToolManager, error) {
 		manager.Register(newsTool)
 	}
 
-	log.Printf("✅ Tool Manager initialized with %d tools.", manager.ToolCount())
+	if cfg.CodeInterpreterEnabled {
+		codeTool, err := tools.NewCodeInterpreterTool(cfg.CodeInterpreter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create code interpreter tool: %w", err)
+		}
+		manager.Register(codeTool)
+		slog.Info("Sandboxed code interpreter tool enabled.")
+	}
+
+	for _, toolCfg := range cfg.HTTPTools {
+		httpTool, err := tools.NewHTTPTool(toolCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create http tool: %w", err)
+		}
+		manager.Register(httpTool)
+		slog.Info("Registered config-defined HTTP tool", "tool", toolCfg.Name, "url", toolCfg.URL)
+	}
+
+	slog.Info("Tool Manager initialized", "tools", manager.ToolCount())
 	return manager, nil
 }
 
-// startHealthChecker runs a background goroutine to proactively check model health.
-func startHealthChecker(models []string, clients map[string]llm.LLMClient, profiler *llm.Profiler) {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	log.Println("🩺 Health checker started.")
+// initializeIntentAnalyzer creates the intent analyzer from the training examples the
+// ingestor stored. Without any, or if they cannot be loaded, it uses the keyword rules alone.
+// Prompts neither is sure of are classified by the fallback model, unless it is off.
+func initializeIntentAnalyzer(cfg *AppConfig, ragService *llm.RAGService, clients map[string]llm.LLMClient, rdb *redis.Client) (*llm.IntentAnalyzer, error) {
+	analyzer, err := llm.NewIntentAnalyzer(cfg.Intents)
+	if err != nil {
+		return nil, err
+	}
+	examples, err := ragService.LoadIntentExamples(context.Background())
+	switch {
+	case err != nil:
+		slog.Warn("Could not load intent training examples. Classifying intents by keyword.", "error", err)
+	case len(examples) == 0:
+		slog.Info("No intent training examples stored; run the ingestor to embed data/intents. Classifying intents by keyword.")
+	default:
+		slog.Info("Intent analyzer loaded training examples", "examples", len(examples), "neighbors", cfg.Intents.Neighbors, "min_confidence", cfg.Intents.MinConfidence)
+		analyzer.SetExamples(ragService, examples)
+	}
 
-	runChecks := func() {
-		log.Println("🩺 Running proactive health checks...")
-		for _, modelID := range models {
-			client, ok := clients[modelID]
-			if !ok {
-				continue
-			}
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			config := &llm.GenerationConfig{Model: modelID, MaxTokens: 5}
-			healthCheckPrompt := []llm.Message{{Role: llm.RoleUser, Content: "What is the capital of India?"}}
+	modelID := cfg.Intents.FallbackModel
+	if modelID == llm.IntentFallbackOff {
+		return analyzer, nil
+	}
+	if modelID == "" {
+		modelID = cheapestModel(cfg, clients)
+	}
+	client, ok := clients[modelID]
+	if !ok {
+		slog.Warn("No model available for the intent fallback classifier.", "fallback_model", cfg.Intents.FallbackModel)
+		return analyzer, nil
+	}
+	analyzer.SetFallbackClassifier(llm.NewLLMIntentClassifier(client, modelID, rdb, cfg.Intents))
+	slog.Info("Intent fallback classifier enabled", "model", modelID)
+	return analyzer, nil
+}
 
-			_, err := client.Generate(ctx, healthCheckPrompt, config, nil)
-			cancel()
+// decisionCache returns the cache of the analyzers' decisions, or nil if it is disabled.
+func decisionCache(cfg *AppConfig, rdb *redis.Client) *llm.DecisionCache {
+	if cfg.AnalysisCacheTTL == 0 {
+		return nil
+	}
+	return llm.NewDecisionCache(rdb, cfg.AnalysisCacheTTL)
+}
 
-			isHealthy := err == nil
-			profiler.UpdateProfileOnHealthCheck(context.Background(), modelID, isHealthy)
-			log.Printf("Health check for %s: Healthy = %v", modelID, isHealthy)
+// initializePromptAnalyzer creates the prompt analyzer with the configured scoring and, unless
+// the backend is the rules, the classifier that selects preferences instead.
+func initializePromptAnalyzer(cfg *AppConfig, clients map[string]llm.LLMClient, rdb *redis.Client) (*llm.PromptAnalyzer, error) {
+	analyzer, err := llm.NewPromptAnalyzer(cfg.PromptAnalysis)
+	if err != nil {
+		return nil, err
+	}
+	classifierCfg := cfg.PromptAnalysis.Classifier
+	switch cfg.PromptAnalysis.Backend {
+	case llm.PromptBackendLLM:
+		modelID := classifierCfg.Model
+		if modelID == "" {
+			modelID = cheapestModel(cfg, clients)
 		}
+		client, ok := clients[modelID]
+		if !ok {
+			return nil, fmt.Errorf("no model available for the llm backend (model '%s')", classifierCfg.Model)
+		}
+		analyzer.SetClassifier(llm.NewLLMPreferenceClassifier(client, modelID, rdb, classifierCfg))
+		slog.Info("Prompt analyzer selects preferences with a model", "model", modelID)
+	case llm.PromptBackendHTTP:
+		analyzer.SetClassifier(llm.NewHTTPPreferenceClassifier(classifierCfg))
+		slog.Info("Prompt analyzer selects preferences with an HTTP classifier", "url", classifierCfg.URL)
 	}
+	return analyzer, nil
+}
+
+// initializeIngestPipeline registers a CMS connector for every source whose credentials are configured.
+// The webhook route is unauthenticated apart from the signature, so a source without a webhook
+// secret is never registered.
+func initializeIngestPipeline(cfg *AppConfig, ragService *llm.RAGService) *ingest.Pipeline {
+	const ingestQueueSize = 1000
+	var connectors []ingest.Connector
+	wh := cfg.CMSWebhooks
+
+	// Public repositories can be fetched without a token, but only allowlisted ones are ingested.
+	switch {
+	case wh.GitHubWebhookSecret == "":
+	case len(wh.GitHubRepositories) == 0:
+		slog.Warn("ingest.webhooks.github.repositories is not set. GitHub ingestion is disabled.")
+	default:
+		connectors = append(connectors, ingest.NewGitHubConnector(wh.GitHubWebhookSecret, wh.GitHubToken, wh.GitHubTopic, wh.GitHubRepositories))
+	}
+	if wh.NotionWebhookSecret != "" && wh.NotionAPIKey != "" {
+		connectors = append(connectors, ingest.NewNotionConnector(wh.NotionWebhookSecret, wh.NotionAPIKey, wh.NotionTopic))
+	}
+	if wh.ConfluenceWebhookSecret != "" && wh.ConfluenceBaseURL != "" && wh.ConfluenceAPIToken != "" {
+		connectors = append(connectors, ingest.NewConfluenceConnector(wh.ConfluenceWebhookSecret, wh.ConfluenceBaseURL, wh.ConfluenceUser, wh.ConfluenceAPIToken))
+	}
+
+	return ingest.NewPipeline(ragService, ingestQueueSize, cfg.Chunking, connectors...)
+}
+
+// startDeprecationDigest periodically summarizes provider deprecation notices for operators.
+// The digest is always logged and, if configured, posted to a Slack-compatible webhook.
+// Like the health checker, it only runs on the leader so operators get one digest per fleet.
+func startDeprecationDigest(cfg *AppConfig, profiler *llm.Profiler, leader *llm.LeaderElector) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
 
-	go runChecks()
 	for range ticker.C {
-		runChecks()
+		if !leader.IsLeader() {
+			continue
+		}
+		notices, err := profiler.GetDeprecations(context.Background(), cfg.EnabledModels)
+		if err != nil {
+			slog.Error("Error building deprecation digest", "error", err)
+			continue
+		}
+		if len(notices) == 0 {
+			continue
+		}
+
+		var digest strings.Builder
+		digest.WriteString(fmt.Sprintf("⚠️ %d routed model(s) reported deprecation by their provider:\n", len(notices)))
+		for _, n := range notices {
+			digest.WriteString(fmt.Sprintf("- %s (deprecation: %s, sunset: %s) %s\n", n.ModelID, n.DeprecatedAt, n.SunsetAt, n.Message))
+		}
+		slog.Warn("Deprecation digest", "digest", digest.String())
+
+		if cfg.DeprecationDigestWebhookURL == "" {
+			continue
+		}
+		payload, _ := json.Marshal(map[string]string{"text": digest.String()})
+		resp, err := http.Post(cfg.DeprecationDigestWebhookURL, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			slog.Error("Error posting deprecation digest", "error", err)
+			continue
+		}
+		resp.Body.Close()
 	}
 }
 
 // runServerWithGracefulShutdown handles the server lifecycle.
-func runServerWithGracefulShutdown(srv *http.Server) {
+func runServerWithGracefulShutdown(srv *http.Server, drainTimeout time.Duration, streams *streamTracker, health *HealthHandler) {
 	go func() {
-		log.Printf("👂 Gateway is listening on http://localhost%s", srv.Addr)
+		slog.Info("Gateway is listening", "addr", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("❌ Listen error: %s\n", err)
+			fatal("Listen error", "error", err)
 		}
 	}()
 
@@ -177,13 +505,27 @@ func runServerWithGracefulShutdown(srv *http.Server) {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("🛑 Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	slog.Info("Shutting down server", "drain_timeout", drainTimeout, "active_streams", streams.count())
+	health.MarkDraining()
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("❌ Server shutdown failed:", err)
+	// Shutdown stops accepting connections and waits for in-flight requests, streams included.
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown(ctx) }()
+
+	// Streams may finish on their own until shortly before the window closes; the rest are
+	// ended with a terminal event so clients know to retry rather than seeing a cut-off answer.
+	streamCtx, cancelStreams := context.WithTimeout(ctx, max(drainTimeout-streamInterruptGrace, drainTimeout/2))
+	defer cancelStreams()
+	if !streams.drain(streamCtx) {
+		slog.Warn("Drain window expired. Interrupting streams", "active_streams", streams.count())
+		streams.interrupt()
 	}
 
-	log.Println("👋 Server exited gracefully.")
+	if err := <-shutdownErr; err != nil {
+		slog.Error("Server shutdown did not complete", "error", err)
+		return
+	}
+	slog.Info("Server exited gracefully.")
 }
@@ -0,0 +1,115 @@
+// In file: cmd/gateway/metrics.go
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler exposes model profiles in the Prometheus text exposition format.
+// The metrics are read from the shared Redis profiles at scrape time, so every
+// replica reports the same fleet-wide view.
+type MetricsHandler struct {
+	profiler *llm.Profiler
+	router   *llm.Router
+	config   *AppConfig
+}
+
+func NewMetricsHandler(profiler *llm.Profiler, router *llm.Router, config *AppConfig) *MetricsHandler {
+	return &MetricsHandler{
+		profiler: profiler,
+		router:   router,
+		config:   config,
+	}
+}
+
+// HandleMetrics renders the current metrics for all enabled models.
+func (h *MetricsHandler) HandleMetrics(c *gin.Context) {
+	ctx := c.Request.Context()
+	var b strings.Builder
+
+	writeHeader := func(name, metricType, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+	}
+
+	profiles := make([]*llm.ModelProfile, 0, len(h.config.EnabledModels))
+	for _, modelID := range h.config.EnabledModels {
+		profile, err := h.profiler.GetProfile(ctx, modelID)
+		if err != nil {
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+
+	writeHeader("llm_gateway_model_online", "gauge", "Whether the model is currently online (1) or not (0).")
+	for _, p := range profiles {
+		online := 0
+		if p.Status == "online" {
+			online = 1
+		}
+		fmt.Fprintf(&b, "llm_gateway_model_online{model=%q,status=%q} %d\n", p.ModelID, p.Status, online)
+	}
+	disabled, _ := h.profiler.DisabledModels(ctx)
+	writeHeader("llm_gateway_model_enabled", "gauge", "Whether the model is in rotation (1) or disabled by an operator (0).")
+	for _, p := range profiles {
+		enabled := 1
+		if disabled[p.ModelID] {
+			enabled = 0
+		}
+		fmt.Fprintf(&b, "llm_gateway_model_enabled{model=%q} %d\n", p.ModelID, enabled)
+	}
+	writeHeader("llm_gateway_model_avg_latency_ms", "gauge", "Exponentially weighted average latency of the model.")
+	for _, p := range profiles {
+		fmt.Fprintf(&b, "llm_gateway_model_avg_latency_ms{model=%q} %d\n", p.ModelID, p.AvgLatencyMS)
+	}
+	writeHeader("llm_gateway_model_error_rate", "gauge", "Fraction of failed requests for the model.")
+	for _, p := range profiles {
+		fmt.Fprintf(&b, "llm_gateway_model_error_rate{model=%q} %g\n", p.ModelID, p.ErrorRate)
+	}
+	writeHeader("llm_gateway_model_requests_total", "counter", "Total requests sent to the model by outcome.")
+	for _, p := range profiles {
+		fmt.Fprintf(&b, "llm_gateway_model_requests_total{model=%q,outcome=\"success\"} %d\n", p.ModelID, p.TotalSuccesses)
+		fmt.Fprintf(&b, "llm_gateway_model_requests_total{model=%q,outcome=\"failure\"} %d\n", p.ModelID, p.TotalFailures)
+	}
+	writeHeader("llm_gateway_model_cost_monthly_usd", "gauge", "Spend on the model in the current month.")
+	for _, p := range profiles {
+		fmt.Fprintf(&b, "llm_gateway_model_cost_monthly_usd{model=%q} %g\n", p.ModelID, p.CostSpentMonthly)
+	}
+
+	writeHeader("llm_gateway_request_latency_seconds", "histogram", "Full-request latency by provider and model.")
+	for _, p := range profiles {
+		h.writeHistogram(&b, "llm_gateway_request_latency_seconds", p.ModelID, p.Latency)
+	}
+	writeHeader("llm_gateway_time_to_first_token_seconds", "histogram", "Time to first token of streaming requests by provider and model.")
+	for _, p := range profiles {
+		h.writeHistogram(&b, "llm_gateway_time_to_first_token_seconds", p.ModelID, p.TTFT)
+	}
+
+	notices, _ := h.profiler.GetDeprecations(ctx, h.config.EnabledModels)
+	writeHeader("llm_gateway_model_deprecated", "gauge", "Set to 1 when the provider has signalled that the model is deprecated.")
+	for _, n := range notices {
+		fmt.Fprintf(&b, "llm_gateway_model_deprecated{model=%q,sunset=%q} 1\n", n.ModelID, n.SunsetAt)
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// writeHistogram renders a model's latency histogram as cumulative Prometheus buckets in seconds.
+func (h *MetricsHandler) writeHistogram(b *strings.Builder, name, modelID string, hist llm.LatencyHistogram) {
+	labels := fmt.Sprintf("provider=%q,model=%q", h.router.ProviderOf(modelID), modelID)
+	var cumulative int64
+	for i, bound := range llm.LatencyBucketsMS {
+		if i < len(hist.Counts) {
+			cumulative += hist.Counts[i]
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"%g\"} %d\n", name, labels, float64(bound)/1000, cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, hist.Count)
+	fmt.Fprintf(b, "%s_sum{%s} %g\n", name, labels, float64(hist.SumMS)/1000)
+	fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, hist.Count)
+}
@@ -0,0 +1,73 @@
+// In file: cmd/gateway/moderation.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/moderation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// refusalCode identifies moderation refusals in error responses and SSE error events.
+const refusalCode = "content_blocked"
+
+// initializeModeration creates the moderation policy for the configured provider.
+// It returns nil when moderation is disabled.
+func initializeModeration(cfg *AppConfig) (*moderation.Policy, error) {
+	if cfg.Moderation.Provider == moderation.ProviderNone {
+		return nil, nil
+	}
+	policy, err := moderation.NewPolicy(cfg.Moderation, cfg.ModerationAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Content moderation enabled", "provider", cfg.Moderation.Provider,
+		"check_input", cfg.Moderation.CheckInput, "check_output", cfg.Moderation.CheckOutput)
+	return policy, nil
+}
+
+// moderate checks the prompt, or the answer to it, when moderation is enabled for the stage,
+// and records the check in the trace. It returns a refusal when the content is blocked.
+// Moderation fails open: if the provider cannot be reached, the request proceeds.
+func (h *GatewayHandler) moderate(c *gin.Context, stage string, req *api.GenerationRequest, answer string, trace *api.DecisionTrace) *api.ModerationRefusal {
+	if h.moderator == nil {
+		return nil
+	}
+	if (stage == moderation.StageInput && !h.moderator.ChecksInput()) || (stage == moderation.StageOutput && !h.moderator.ChecksOutput()) {
+		return nil
+	}
+
+	check, err := h.moderator.Check(c.Request.Context(), stage, req.Prompt, answer)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Moderation unavailable, allowing content", "stage", stage, "error", err)
+		return nil
+	}
+	trace.Moderation = append(trace.Moderation, *check)
+	if !check.Blocked {
+		return nil
+	}
+
+	slog.WarnContext(c.Request.Context(), "Content blocked by moderation", "stage", stage, "categories", check.Categories)
+	subject := "prompt"
+	if stage == moderation.StageOutput {
+		subject = "generated answer"
+	}
+	return &api.ModerationRefusal{
+		Error:      fmt.Sprintf("The %s was blocked by content moderation (%s).", subject, strings.Join(check.Categories, ", ")),
+		Code:       refusalCode,
+		Stage:      stage,
+		Categories: check.Categories,
+	}
+}
+
+// refuse answers a blocked request with the structured refusal and audits it.
+func (h *GatewayHandler) refuse(c *gin.Context, req *api.GenerationRequest, resp *api.GenerationResponse, refusal *api.ModerationRefusal, trace *api.DecisionTrace) {
+	resp.Content = ""
+	h.recordAudit(c.Request.Context(), req, resp, trace)
+	c.JSON(http.StatusBadRequest, refusal)
+}
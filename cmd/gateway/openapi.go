@@ -0,0 +1,440 @@
+// In file: cmd/gateway/openapi.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/ingest"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIHandler serves the OpenAPI 3 specification of the gateway's endpoints, generated
+// from the request and response structs, and a Swagger UI to browse it. Client teams can
+// generate SDKs from /openapi.json instead of reading the Go structs.
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+// NewOpenAPIHandler generates the specification once. Endpoints that are not registered,
+// such as pass-through when it is disabled, are left out.
+func NewOpenAPIHandler(config *AppConfig, documents bool) (*OpenAPIHandler, error) {
+	spec, err := json.Marshal(buildOpenAPISpec(config, documents))
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{spec: spec}, nil
+}
+
+// HandleSpec serves the specification.
+// GET /openapi.json
+func (h *OpenAPIHandler) HandleSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// HandleDocs serves a Swagger UI for the specification. The UI's assets are loaded from a CDN.
+// GET /docs
+func (h *OpenAPIHandler) HandleDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>LLM Gateway API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// openAPIOperation describes one endpoint. Request and Response are zero values of the body
+// types; nil means no body, or a body of any shape.
+type openAPIOperation struct {
+	Method, Path, Summary, Tag string
+	Request, Response          any
+	// Status is the status of a successful response, 200 unless set.
+	Status int
+	// Query lists the query parameters.
+	Query []string
+	// Security is "caller" for the endpoints behind the caller authentication, "admin" for the
+	// admin API, and "" for public ones.
+	Security string
+	// Stream marks endpoints that answer with Server-Sent Events when the request asks to stream.
+	Stream bool
+}
+
+// openAPIOperations returns the gateway's endpoints. Keep it in step with the routes
+// registered in main.
+func openAPIOperations(config *AppConfig, documents bool) []openAPIOperation {
+	type renameRequest struct {
+		Title string `json:"title" binding:"required"`
+	}
+	type conversationEnvelope struct {
+		Conversation llm.ConversationInfo `json:"conversation"`
+	}
+	type modelSwitch struct {
+		ModelID string `json:"model_id"`
+		Enabled bool   `json:"enabled"`
+	}
+	type statusResponse struct {
+		Status string                       `json:"status"`
+		Checks map[string]healthCheckResult `json:"checks,omitempty"`
+	}
+
+	ops := []openAPIOperation{
+		{Method: http.MethodPost, Path: "/api/v1/generate", Tag: "generation", Security: "caller", Stream: true,
+			Summary: "Route a prompt to the best model and answer it", Request: api.GenerationRequest{}, Response: api.GenerationResponse{}},
+		{Method: http.MethodPost, Path: "/anthropic/v1/messages", Tag: "generation", Security: "caller", Stream: true,
+			Summary: "Answer a request in Anthropic's Messages API format", Request: anthropicMessagesRequest{}, Response: anthropicMessagesResponse{}},
+		{Method: http.MethodPost, Path: "/api/v1/tokens/count", Tag: "generation", Security: "caller",
+			Summary: "Count the input tokens of messages for a model", Request: api.TokenCountRequest{}, Response: api.TokenCountResponse{}},
+		{Method: http.MethodGet, Path: "/api/v1/conversations", Tag: "conversations", Security: "caller",
+			Summary: "List the caller's conversations", Response: struct {
+				Conversations []llm.ConversationInfo `json:"conversations"`
+			}{}},
+		{Method: http.MethodGet, Path: "/api/v1/conversations/:id", Tag: "conversations", Security: "caller",
+			Summary: "Get a conversation and its transcript", Response: conversationResponse{}},
+		{Method: http.MethodPatch, Path: "/api/v1/conversations/:id", Tag: "conversations", Security: "caller",
+			Summary: "Rename a conversation", Request: renameRequest{}, Response: conversationEnvelope{}},
+		{Method: http.MethodDelete, Path: "/api/v1/conversations/:id", Tag: "conversations", Security: "caller",
+			Summary: "Delete a conversation", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/api/v1/conversations/:id/export", Tag: "conversations", Security: "caller",
+			Summary: "Download a conversation as JSON or Markdown", Query: []string{"format"}, Response: conversationResponse{}},
+		{Method: http.MethodGet, Path: "/admin/deprecations", Tag: "admin", Security: "admin",
+			Summary: "List the deprecation notices of enabled models", Response: struct {
+				Deprecations []llm.DeprecationNotice `json:"deprecations"`
+			}{}},
+		{Method: http.MethodGet, Path: "/admin/costs", Tag: "admin", Security: "admin",
+			Summary: "Report the month's spend by model", Query: []string{"month", "format"}, Response: llm.CostReport{}},
+		{Method: http.MethodGet, Path: "/admin/costs/:dimension", Tag: "admin", Security: "admin",
+			Summary: "Report the month's spend by model, day, user, or conversation", Query: []string{"month", "format"}, Response: llm.CostReport{}},
+		{Method: http.MethodGet, Path: "/admin/costs/:dimension/:key", Tag: "admin", Security: "admin",
+			Summary: "Get the month's spend of one day, user, or conversation", Query: []string{"month"}, Response: struct {
+				Dimension string  `json:"dimension"`
+				Month     string  `json:"month"`
+				Key       string  `json:"key"`
+				CostUSD   float64 `json:"cost_usd"`
+				Requests  int64   `json:"requests"`
+				BudgetUSD float64 `json:"budget_usd,omitempty"`
+			}{}},
+		{Method: http.MethodGet, Path: "/admin/usage", Tag: "admin", Security: "admin",
+			Summary: "Report per-account usage; with account set, only that account's", Query: []string{"month", "account"}, Response: struct {
+				Month    string             `json:"month"`
+				Accounts []api.AccountUsage `json:"accounts"`
+			}{}},
+		{Method: http.MethodGet, Path: "/admin/dashboard", Tag: "admin", Security: "admin",
+			Summary: "Get profiles, spend, cache hit rates, and recent routing in one document", Query: []string{"decisions"}, Response: struct {
+				GeneratedAt time.Time `json:"generated_at"`
+				Models      []struct {
+					Enabled  bool             `json:"enabled"`
+					Provider string           `json:"provider"`
+					Profile  llm.ModelProfile `json:"profile"`
+				} `json:"models"`
+				Spend struct {
+					Month    string            `json:"month"`
+					TotalUSD float64           `json:"total_usd"`
+					Budgets  []api.BudgetState `json:"budgets"`
+				} `json:"spend"`
+				Caches          []llm.CacheStats      `json:"caches"`
+				RecentDecisions []llm.RoutingDecision `json:"recent_decisions"`
+			}{}},
+		{Method: http.MethodGet, Path: "/admin/models", Tag: "admin", Security: "admin",
+			Summary: "List the configured models and whether each is in rotation", Response: struct {
+				Models []modelSwitch `json:"models"`
+			}{}},
+		{Method: http.MethodGet, Path: "/admin/models/:id/history", Tag: "admin", Security: "admin",
+			Summary: "Get a model's profile snapshots over a recent window", Query: []string{"since"}, Response: struct {
+				ModelID   string                `json:"model_id"`
+				Since     string                `json:"since"`
+				Snapshots []llm.ProfileSnapshot `json:"snapshots"`
+			}{}},
+		{Method: http.MethodGet, Path: "/admin/profiles/export", Tag: "admin", Security: "admin",
+			Summary: "Export the profiles of the enabled models", Response: llm.ProfileExport{}},
+		{Method: http.MethodPost, Path: "/admin/profiles/import", Tag: "admin", Security: "admin",
+			Summary: "Seed model profiles from an export", Query: []string{"overwrite"}, Request: llm.ProfileExport{}, Response: struct {
+				Imported []string `json:"imported"`
+				Skipped  int      `json:"skipped"`
+			}{}},
+		{Method: http.MethodPost, Path: "/admin/models/:id/enable", Tag: "admin", Security: "admin",
+			Summary: "Put a model back into rotation", Response: modelSwitch{}},
+		{Method: http.MethodPost, Path: "/admin/models/:id/disable", Tag: "admin", Security: "admin",
+			Summary: "Take a model out of rotation", Response: modelSwitch{}},
+		{Method: http.MethodGet, Path: "/healthz", Tag: "health", Summary: "Liveness", Response: statusResponse{}},
+		{Method: http.MethodGet, Path: "/readyz", Tag: "health", Summary: "Readiness of Redis and the model profiles", Response: statusResponse{}},
+		{Method: http.MethodGet, Path: "/startupz", Tag: "health", Summary: "Whether startup has finished", Response: statusResponse{}},
+	}
+	if config.Passthrough.Enabled {
+		ops = append(ops, openAPIOperation{Method: http.MethodPost, Path: "/api/v1/passthrough/:model", Tag: "generation", Security: "caller",
+			Summary: "Relay a request in the provider's own format to a model"})
+	}
+	if documents {
+		ops = append(ops,
+			openAPIOperation{Method: http.MethodPost, Path: "/api/v1/documents", Tag: "documents", Security: "caller", Status: http.StatusCreated,
+				Summary: "Upload a document to the knowledge base, as JSON text or a multipart file", Request: textDocumentRequest{}, Response: struct {
+					Document ingest.Document `json:"document"`
+				}{}},
+			openAPIOperation{Method: http.MethodGet, Path: "/api/v1/documents", Tag: "documents", Security: "caller",
+				Summary: "List the caller's documents", Response: struct {
+					Documents []ingest.Document `json:"documents"`
+				}{}},
+			openAPIOperation{Method: http.MethodDelete, Path: "/api/v1/documents/:id", Tag: "documents", Security: "caller", Status: http.StatusNoContent,
+				Summary: "Delete a document"},
+			openAPIOperation{Method: http.MethodPost, Path: "/api/v1/webhooks/ingest/:source", Tag: "documents", Status: http.StatusAccepted,
+				Summary: "Queue the document changes of a CMS webhook, authenticated by its signature", Response: struct {
+					Accepted int             `json:"accepted"`
+					Changes  []ingest.Change `json:"changes"`
+				}{}},
+		)
+	}
+	return ops
+}
+
+// pathParam matches the gin parameters of a route, such as :id.
+var pathParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// buildOpenAPISpec generates the OpenAPI document of the gateway's endpoints.
+func buildOpenAPISpec(config *AppConfig, documents bool) map[string]any {
+	schemas := &openAPISchemas{components: map[string]any{}, names: map[reflect.Type]string{}}
+	schemas.components["Error"] = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"error": map[string]any{"type": "string"}},
+	}
+	errorResponse := map[string]any{
+		"description": "The request failed; error explains why.",
+		"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}}},
+	}
+
+	paths := map[string]any{}
+	for _, op := range openAPIOperations(config, documents) {
+		operation := map[string]any{
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+			"operationId": operationID(op.Method, op.Path),
+		}
+
+		var params []any
+		for _, name := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]any{"name": name[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		for _, name := range op.Query {
+			params = append(params, map[string]any{"name": name, "in": "query", "schema": map[string]any{"type": "string"}})
+		}
+		if strings.HasPrefix(op.Path, "/api/v1/conversations") || strings.HasPrefix(op.Path, "/api/v1/documents") {
+			params = append(params, map[string]any{"name": userHeader, "in": "header", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(op.Request))}},
+			}
+		} else if op.Method == http.MethodPost && op.Response == nil {
+			operation["requestBody"] = map[string]any{"content": map[string]any{"application/json": map[string]any{"schema": map[string]any{}}}}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		if status != http.StatusNoContent {
+			schema := map[string]any{}
+			if op.Response != nil {
+				schema = schemas.schemaFor(reflect.TypeOf(op.Response))
+			}
+			content := map[string]any{"application/json": map[string]any{"schema": schema}}
+			if op.Stream {
+				success["description"] = "The answer or, when the request asks to stream, a stream of Server-Sent Events."
+				content["text/event-stream"] = map[string]any{"schema": map[string]any{"type": "string"}}
+			}
+			success["content"] = content
+		}
+		operation["responses"] = map[string]any{
+			strconv.Itoa(status): success,
+			"default":            errorResponse,
+		}
+
+		switch op.Security {
+		case "admin":
+			operation["security"] = []any{map[string]any{"adminKey": []string{}}}
+		case "caller":
+			if config.AuthMode == AuthModeOIDC {
+				operation["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+			}
+		}
+
+		route := pathParam.ReplaceAllString(op.Path, "{$1}")
+		item, _ := paths[route].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[route] = item
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "LLM Gateway API",
+			"version":     version,
+			"description": "Routes prompts to the best model by cost, latency, and quality, with RAG, tools, caching, and failover.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"adminKey":   map[string]any{"type": "http", "scheme": "bearer", "description": "The ADMIN_API_KEY."},
+			},
+		},
+	}
+}
+
+// operationID names an operation after its method and path, e.g. getAdminModelsIdHistory.
+func operationID(method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(route, func(r rune) bool { return r == '/' || r == ':' || r == '_' || r == '-' }) {
+		if part == "api" || part == "v1" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// openAPISchemas generates the schemas of Go types. Named structs become components,
+// referenced by name; other types are inlined.
+type openAPISchemas struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawJSONType  = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaFor returns the schema of a type, as it is encoded by encoding/json.
+func (s *openAPISchemas) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "integer", "format": "int64", "description": "Nanoseconds."}
+	case rawJSONType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name, ok := s.names[t]
+		if !ok {
+			name = s.componentName(t)
+			s.names[t] = name
+			// The name is reserved before the fields are generated, so recursive types refer
+			// to themselves.
+			s.components[name] = map[string]any{}
+			s.components[name] = s.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// componentName names a struct's component after the type, capitalized, and qualified by its
+// package when another package has a type of the same name.
+func (s *openAPISchemas) componentName(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := s.components[name]; !taken {
+		return name
+	}
+	pkg := path.Base(t.PkgPath())
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
+
+// structSchema generates the object schema of a struct's JSON fields, including those of
+// embedded structs. Fields bound as required are required.
+func (s *openAPISchemas) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	s.addFields(t, properties, &required)
+	schema := map[string]any{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s *openAPISchemas) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.schemaFor(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// In file: cmd/gateway/passthrough.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/dileep-u-k/llm-gateway/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PassthroughConfig is the `passthrough` section of config.yaml.
+//
+// In pass-through mode a caller that needs nothing but a specific model sends a request in
+// the provider's own format to /api/v1/passthrough/:model, and the gateway relays the bytes
+// in both directions without decoding or re-encoding them. There is no routing, RAG, tool
+// use, caching, or failover; the gateway only authenticates, limits, and counts the tokens
+// and cost the provider reports. Since nothing reads the request, pass-through cannot be
+// combined with PII redaction or moderation.
+type PassthroughConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// passthroughBufferSize is the size of the reads relayed from the provider to the client.
+const passthroughBufferSize = 32 << 10
+
+// HandlePassthrough relays a native request for one model to its provider and streams the
+// provider's response back unchanged.
+// POST /api/v1/passthrough/:model
+func (h *GatewayHandler) HandlePassthrough(c *gin.Context) {
+	startTime := time.Now()
+	ctx := c.Request.Context()
+	// The model is a wildcard parameter, so provider-prefixed names ("anthropic/claude-...")
+	// reach the handler whole.
+	modelID := h.config.RouterConfig.StripProviderPrefix(strings.TrimPrefix(c.Param("model"), "/"))
+	client, ok := h.clients[modelID]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("model '%s' is not available or enabled", modelID)})
+		return
+	}
+	// A model an operator disabled or the health checker took offline is not relayed to either.
+	if unavailable := h.unavailableReason(ctx, modelID); unavailable != "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("The requested model '%s' is currently %s.", modelID, unavailable)})
+		return
+	}
+	// The caller is identified as on /generate.
+	userID := callerUserID(c)
+	if reqErr := h.checkUserBudget(c, userID); reqErr != nil {
+		slog.WarnContext(ctx, "Request rejected", "code", reqErr.Code, "user_id", userID)
+		c.JSON(reqErr.Status, reqErr)
+		return
+	}
+
+	if h.config.Limits.MaxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.config.Limits.MaxBodyBytes)
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if status := uploadErrorStatus(err); err != nil {
+		c.JSON(status, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	// Only the model is read from the body: it must be the model the caller is billed for.
+	var native struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &native); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if native.Model != modelID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The request body must set \"model\" to '%s'.", modelID)})
+		return
+	}
+
+	release, err := h.concurrency.AcquireModel(ctx, modelID, requestPriority(c, h.config))
+	if err != nil {
+		rejectSaturated(c, h.concurrency, "model "+modelID, err)
+		return
+	}
+	defer release()
+	withLogFields(c, logging.ModelKey, modelID)
+
+	resp, err := llm.Forward(ctx, client, body)
+	if errors.Is(err, llm.ErrForwardUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": fmt.Sprintf("Pass-through is not supported for model '%s'.", modelID)})
+		return
+	}
+	if err != nil {
+		h.profiler.UpdateProfileOnFailure(ctx, modelID)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	c.Header("Content-Type", resp.Header.Get("Content-Type"))
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+	}
+	c.Status(resp.StatusCode)
+	copyErr := relayFlushing(c, resp.Body)
+
+	latency := time.Since(startTime)
+	usage := resp.Usage()
+	switch {
+	case resp.StatusCode >= 500:
+		h.profiler.UpdateProfileOnFailure(ctx, modelID)
+	case resp.StatusCode < 300 && copyErr == nil:
+		h.profiler.UpdateProfileOnSuccess(ctx, modelID, latency, usage)
+	}
+	// The provider bills whatever it generated, even when the client did not read all of it.
+	req := &api.GenerationRequest{Prompt: string(body), UserID: userID}
+	cost := llm.CallCost(modelID, usage)
+	if usage.TotalTokens > 0 {
+		h.profiler.RecordSpend(ctx, modelID, req.UserID, "", usage)
+	}
+	h.recordAccountUsage(c, req, usage, cost)
+	h.recordAudit(ctx, req, &api.GenerationResponse{
+		ModelUsed:   modelID,
+		Usage:       usage,
+		LatencyMS:   latency.Milliseconds(),
+		CacheStatus: "PASSTHROUGH",
+	}, &api.DecisionTrace{})
+	if copyErr != nil {
+		slog.WarnContext(ctx, "Pass-through response interrupted", "status", resp.StatusCode, "error", copyErr)
+	}
+}
+
+// relayFlushing copies the provider's response to the client, flushing after every read so
+// streamed events reach the client as soon as they arrive.
+func relayFlushing(c *gin.Context, body io.Reader) error {
+	buf := make([]byte, passthroughBufferSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+				return writeErr
+			}
+			c.Writer.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,13 @@
+// In file: cmd/gateway/profile_full.go
+
+//go:build !minimal
+
+package main
+
+// defaultProfile is the profile used when server.profile is not set.
+// Standard builds run the full gateway but can be switched to the minimal
+// profile at runtime with `profile: minimal` in the server section of config.yaml.
+const defaultProfile = ProfileFull
+
+// profileLocked reports whether server.profile is allowed to override defaultProfile.
+const profileLocked = false
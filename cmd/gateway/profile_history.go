@@ -0,0 +1,67 @@
+// In file: cmd/gateway/profile_history.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+)
+
+// ProfileHistoryConfig is the `profile_history` section of config.yaml. It schedules the
+// snapshots of every model's profile that make trends and regressions visible.
+type ProfileHistoryConfig struct {
+	// Interval is the time between two snapshots. It defaults to one minute.
+	Interval time.Duration `yaml:"interval"`
+	// Retention is how long snapshots are kept. It defaults to seven days.
+	Retention time.Duration `yaml:"retention"`
+	// Disabled turns the snapshots off.
+	Disabled bool `yaml:"disabled"`
+}
+
+// withDefaults fills in the settings that were left unset.
+func (c ProfileHistoryConfig) withDefaults() ProfileHistoryConfig {
+	if c.Interval == 0 {
+		c.Interval = time.Minute
+	}
+	if c.Retention == 0 {
+		c.Retention = 7 * 24 * time.Hour
+	}
+	return c
+}
+
+// Validate reports settings that cannot work.
+func (c ProfileHistoryConfig) Validate() error {
+	if c.Interval < 0 || c.Retention < 0 {
+		return fmt.Errorf("interval and retention must not be negative")
+	}
+	if c.Retention > 0 && c.Retention < c.Interval {
+		return fmt.Errorf("retention (%s) must be at least the interval (%s)", c.Retention, c.Interval)
+	}
+	return nil
+}
+
+// startProfileSnapshots periodically records a snapshot of every model's profile. Like the
+// health checker, it only runs on the leader, so the fleet records one series per model.
+func startProfileSnapshots(cfg ProfileHistoryConfig, models []string, profiler *llm.Profiler, leader *llm.LeaderElector) {
+	if cfg.Disabled {
+		return
+	}
+	cfg = cfg.withDefaults()
+	slog.Info("Profile snapshots started.", "models", len(models), "interval", cfg.Interval, "retention", cfg.Retention)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !leader.IsLeader() {
+			continue
+		}
+		for _, modelID := range models {
+			if err := profiler.RecordSnapshot(context.Background(), modelID, cfg.Retention); err != nil {
+				slog.Error("Error recording profile snapshot", "model", modelID, "error", err)
+			}
+		}
+	}
+}
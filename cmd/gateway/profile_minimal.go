@@ -0,0 +1,12 @@
+// In file: cmd/gateway/profile_minimal.go
+
+//go:build minimal
+
+package main
+
+// defaultProfile is the profile used when server.profile is not set.
+// Binaries built with `-tags minimal` always run the pure routing core.
+const defaultProfile = ProfileMinimal
+
+// profileLocked reports whether server.profile is allowed to override defaultProfile.
+const profileLocked = true
@@ -0,0 +1,91 @@
+// In file: cmd/gateway/profiles_cli.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/redis/go-redis/v9"
+)
+
+// =================================================================================
+// `gateway profiles export|import`
+// =================================================================================
+// Exports the model profiles in Redis to JSON, and imports them into another Redis, so a
+// new environment starts from measured latencies and error rates rather than the default
+// 2000ms latency guess. `import` only seeds models without a profile unless --overwrite
+// is given.
+// =================================================================================
+
+const profilesUsage = "usage: gateway profiles export [file] | gateway profiles import <file> [--overwrite]"
+
+// runProfilesCommand runs `gateway profiles` and returns the process exit code.
+func runProfilesCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, profilesUsage)
+		return 2
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "configuration error: %v\n", err)
+		return 1
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+	defer rdb.Close()
+	profiler := llm.NewProfiler(rdb)
+	defer profiler.Close()
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "export" && len(args) <= 2:
+		export, err := profiler.ExportProfiles(ctx, cfg.EnabledModels)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "export failed: %v\n", err)
+			return 1
+		}
+		out := io.Writer(os.Stdout)
+		if len(args) == 2 {
+			f, err := os.Create(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "export failed: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			out = f
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(export); err != nil {
+			fmt.Fprintf(os.Stderr, "export failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d profiles.\n", len(export.Profiles))
+		return 0
+
+	case args[0] == "import" && (len(args) == 2 || (len(args) == 3 && args[2] == "--overwrite")):
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import failed: %v\n", err)
+			return 1
+		}
+		var export llm.ProfileExport
+		if err := json.Unmarshal(data, &export); err != nil {
+			fmt.Fprintf(os.Stderr, "import failed: %s is not a profile export: %v\n", args[1], err)
+			return 1
+		}
+		imported, err := profiler.ImportProfiles(ctx, export.Profiles, len(args) == 3)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d of %d profiles: %s\n", len(imported), len(export.Profiles), strings.Join(imported, ", "))
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, profilesUsage)
+	return 2
+}
@@ -0,0 +1,88 @@
+// In file: cmd/gateway/ratelimit.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/dileep-u-k/llm-gateway/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usedTokensKey is the gin context key under which a handler reports the tokens a request used,
+// so the rate limiter can charge them to the caller's token window.
+const usedTokensKey = "ratelimit.used_tokens"
+
+// tokenChargerKey is the gin context key of the tokenCharger of a rate-limited request, which
+// asynchronous jobs call themselves once they finish.
+const tokenChargerKey = "ratelimit.token_charger"
+
+// tokenCharger charges tokens to the caller's token window.
+type tokenCharger func(ctx context.Context, tokens int)
+
+// rateLimitAccount identifies the caller a request is limited as: its user, from the verified
+// token or, in AuthModeNone, the X-User-ID header set by the authenticating proxy in front of
+// the gateway; else its tenant, if it is a configured one. An unverified bearer token is
+// never used, since a caller could start a fresh bucket just by changing it. Callers with
+// neither are limited by their address, so anonymous traffic is still bounded; it is only
+// taken from X-Forwarded-For behind server.trusted_proxies.
+func rateLimitAccount(c *gin.Context, config *AppConfig) string {
+	if userID := callerUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	if tenantID := c.GetHeader(tenantHeader); tenantID != "" {
+		if _, ok := config.Tenants[tenantID]; ok {
+			return "tenant:" + tenantID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimit enforces the tenant's per-account request and token limits. Rejected requests get
+// 429 with a Retry-After header. If Redis is unavailable the request is let through rather
+// than taking the gateway down with it.
+func rateLimit(limiter *ratelimit.Limiter, config *AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limits := config.RateLimitFor(c.GetHeader(tenantHeader))
+		if !limits.Enabled() {
+			c.Next()
+			return
+		}
+
+		account := rateLimitAccount(c, config)
+
+		decision, err := limiter.Allow(c.Request.Context(), account, limits)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Rate limiter unavailable. Allowing request", "error", err)
+			c.Next()
+			return
+		}
+		if !decision.Allowed {
+			seconds := int(decision.RetryAfter.Seconds())
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			slog.InfoContext(c.Request.Context(), "Rate limit exceeded", "account", account, "limit", decision.Limit, "retry_after_s", seconds)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": fmt.Sprintf("Rate limit exceeded (%s). Retry in %d seconds.", decision.Limit, seconds),
+			})
+			return
+		}
+
+		charge := func(ctx context.Context, tokens int) {
+			if limits.TokensPerMinute <= 0 {
+				return
+			}
+			if err := limiter.RecordTokens(ctx, account, tokens); err != nil {
+				slog.WarnContext(ctx, "Failed to record rate-limited tokens", "error", err)
+			}
+		}
+		c.Set(tokenChargerKey, tokenCharger(charge))
+
+		c.Next()
+
+		charge(c.Request.Context(), c.GetInt(usedTokensKey))
+	}
+}
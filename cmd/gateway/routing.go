@@ -0,0 +1,72 @@
+// In file: cmd/gateway/routing.go
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers that repeat the routing summary, for clients that only log headers.
+const (
+	intentHeader          = "X-Gateway-Intent"
+	preferenceHeader      = "X-Gateway-Preference"
+	complexityScoreHeader = "X-Gateway-Complexity-Score"
+)
+
+// routingInfo summarises why a request was answered the way it was: its intent, and the
+// preference its model was routed by, with the complexity score when the prompt analyzer
+// selected it. A model forced by the request, pinned by its session, or set by the intent's
+// route has no preference.
+func routingInfo(intent string, req *api.GenerationRequest, trace *api.DecisionTrace) *api.RoutingInfo {
+	routing := &api.RoutingInfo{Intent: intent, Preference: req.Config.Preference}
+	switch {
+	case trace.IntentRoute != nil && trace.IntentRoute.Skipped == "":
+		routing.PreferenceSource = "intent"
+	case trace.Preference != nil:
+		routing.PreferenceSource = "analyzer"
+		score := trace.Preference.Score
+		routing.ComplexityScore = &score
+	case routing.Preference != "":
+		routing.PreferenceSource = "request"
+	}
+	return routing
+}
+
+// setRoutingHeaders sends the routing summary as response headers. It must be called before
+// the response body is written.
+func setRoutingHeaders(c *gin.Context, routing *api.RoutingInfo) {
+	if routing == nil {
+		return
+	}
+	c.Header(intentHeader, routing.Intent)
+	if routing.Preference != "" {
+		c.Header(preferenceHeader, routing.Preference)
+	}
+	if routing.ComplexityScore != nil {
+		c.Header(complexityScoreHeader, strconv.Itoa(*routing.ComplexityScore))
+	}
+}
+
+// recordRoutingDecision adds an answered request to the recent routing decisions shown by
+// the dashboard.
+func (h *GatewayHandler) recordRoutingDecision(ctx context.Context, routing *api.RoutingInfo, modelID string, failoverInfo *api.FailoverInfo, stream bool, latency time.Duration, cost float64) {
+	decision := llm.RoutingDecision{
+		Time:      time.Now().UTC(),
+		Model:     modelID,
+		Stream:    stream,
+		LatencyMS: latency.Milliseconds(),
+		CostUSD:   cost,
+	}
+	if routing != nil {
+		decision.Intent, decision.Preference, decision.PreferenceSource = routing.Intent, routing.Preference, routing.PreferenceSource
+	}
+	if failoverInfo != nil {
+		decision.FailoverFrom = failoverInfo.OriginalModel
+	}
+	h.profiler.RecordRoutingDecision(ctx, decision)
+}
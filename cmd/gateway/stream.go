@@ -0,0 +1,449 @@
+// In file: cmd/gateway/stream.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/dileep-u-k/llm-gateway/internal/moderation"
+	"github.com/dileep-u-k/llm-gateway/internal/tools"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =================================================================================
+// Streaming Generation (Server-Sent Events)
+// =================================================================================
+// When a request sets "stream": true, the gateway answers with an SSE stream instead
+// of a single JSON document. Every phase of the agent loop is reported as it happens,
+// so chat UIs can render "calling weather tool…" instead of a long silent wait:
+//
+//   event: rag_search_started data: {}
+//   event: rag_context        data: {"used": true, "topic": "billing", "score": 0.82, "chunks": 2, ...}
+//   event: tool_call_started  data: {"id": "...", "name": "getCurrentWeather", "arguments": "..."}
+//   event: tool_result        data: {"id": "...", "name": "getCurrentWeather", "result": "..."}
+//   event: content_delta      data: {"delta": "It is 21°C"}
+//   event: done               data: {"model_used": "gpt-4o", "usage": {...}, "latency_ms": 812, ...}
+//   event: error              data: {"error": "..."}
+//
+// If moderation blocks the finished answer, the stream ends with an error event carrying
+// the refusal ({"error": "...", "code": "content_blocked", ...}) instead of "done".
+// =================================================================================
+
+// SSE event names emitted by the streaming endpoint.
+const (
+	eventRAGSearchStarted = "rag_search_started"
+	eventRAGContext       = "rag_context"
+	eventToolCallStarted  = "tool_call_started"
+	eventToolResult       = "tool_result"
+	eventContentDelta     = "content_delta"
+	eventDone             = "done"
+	eventError            = "error"
+)
+
+// StreamingConfig is the `streaming` section of config.yaml. It tunes how provider streams are
+// relayed to clients.
+type StreamingConfig struct {
+	// BufferSize is how many provider chunks are buffered while the client is being written
+	// to, so a briefly slow client does not stall the provider connection. 0 is unbuffered.
+	BufferSize int `yaml:"buffer_size"`
+	// FlushInterval coalesces content deltas: they are sent at most this often, as one event.
+	// 0 sends every delta as it arrives.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// FlushBytes sends the coalesced deltas early once this many bytes are pending; 0 waits
+	// for the interval.
+	FlushBytes int `yaml:"flush_bytes"`
+	// WriteTimeout bounds writing one event to the client. A client that cannot take an event
+	// within it is considered gone: the stream ends and the provider call is aborted, so it
+	// stops generating (and billing) tokens nobody reads. 0 waits indefinitely.
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+}
+
+// Validate reports settings that cannot work.
+func (c StreamingConfig) Validate() error {
+	if c.BufferSize < 0 || c.FlushBytes < 0 {
+		return fmt.Errorf("buffer_size and flush_bytes must not be negative")
+	}
+	if c.FlushInterval < 0 || c.WriteTimeout < 0 {
+		return fmt.Errorf("flush_interval and write_timeout must not be negative")
+	}
+	return nil
+}
+
+// errSlowClient is returned when a client does not read the stream fast enough.
+var errSlowClient = errors.New("the client is not reading the stream fast enough")
+
+// streamDoneEvent is the payload of the final "done" event.
+type streamDoneEvent struct {
+	ModelUsed      string             `json:"model_used"`
+	Usage          api.Usage          `json:"usage"`
+	LatencyMS      int64              `json:"latency_ms"`
+	RAGContextUsed bool               `json:"rag_context_used"`
+	RAG            *api.RAGDecision   `json:"rag,omitempty"`
+	FinishReason   string             `json:"finish_reason,omitempty"`
+	CacheStatus    string             `json:"cache_status"`
+	FailoverInfo   *api.FailoverInfo  `json:"failover_info,omitempty"`
+	Truncated      bool               `json:"truncated,omitempty"`
+	Debug          *api.DecisionTrace `json:"debug,omitempty"`
+	CostUSD        float64            `json:"cost_usd"`
+	AccountUsage   *api.AccountUsage  `json:"account_usage,omitempty"`
+	Warnings       []string           `json:"warnings,omitempty"`
+	Routing        *api.RoutingInfo   `json:"routing,omitempty"`
+	// ToolCalls are the tool calls executed during the stream, as announced by its events.
+	ToolCalls []api.ExecutedToolCall `json:"tool_calls,omitempty"`
+}
+
+// handleStreamingGeneration serves a request over SSE. Tool intents run the streaming agent
+// loop with the permitted tools; every other intent streams a (RAG-augmented) answer.
+func (h *GatewayHandler) handleStreamingGeneration(c *gin.Context, req api.GenerationRequest, intent, modelID string, failoverInfo *api.FailoverInfo, policy tools.ToolPolicy, routing *api.RoutingInfo, trace *api.DecisionTrace, startTime time.Time) {
+	// Streams are tracked so a shutdown can wait for them, and end them cleanly if they outlast
+	// the drain window.
+	ctx, endStream, ok := h.streams.begin(c.Request.Context())
+	if !ok {
+		refuseWhileDraining(c)
+		return
+	}
+	defer endStream()
+	c.Request = c.Request.WithContext(ctx)
+	startSSE(c)
+
+	var messages []llm.Message
+	var toolDefs []tools.Tool
+	if intentPolicy, ok := h.intentToolPolicy(intent, policy); ok {
+		policy = intentPolicy
+		toolDefs = h.toolManager.GetDefinitionsFor(policy)
+		messages = h.buildMessages(c.Request.Context(), modelID, req, req.Prompt, trace)
+	} else {
+		finalPrompt := req.Prompt
+		if !h.config.IsMinimal() && (req.RAG == nil || !req.RAG.Disable) {
+			// The search is announced, so UIs can show it while it runs.
+			writeSSE(c, eventRAGSearchStarted, gin.H{})
+			var err error
+			finalPrompt, trace.RAG, err = h.performRAGRetrieval(c, req.Prompt, req.RAG, "relevance_threshold")
+			if err != nil && interruptedByShutdown(c.Request.Context()) {
+				writeShutdownEvent(c)
+				return
+			}
+			if err != nil {
+				writeSSE(c, eventError, gin.H{"error": fmt.Sprintf("RAG retrieval failed: %v", err)})
+				return
+			}
+			writeSSE(c, eventRAGContext, trace.RAG)
+		}
+		messages = h.buildMessages(c.Request.Context(), modelID, req, finalPrompt, trace)
+	}
+
+	answer, err := h.runStreamingAgentLoop(c, req, modelID, messages, toolDefs, policy, trace)
+	if err != nil && interruptedByShutdown(c.Request.Context()) {
+		slog.WarnContext(c.Request.Context(), "Stream interrupted by shutdown", "model", modelID)
+		writeShutdownEvent(c)
+		return
+	}
+	if errors.Is(err, errSlowClient) {
+		// The connection is unusable, so no error event is sent.
+		slog.WarnContext(c.Request.Context(), "Stream aborted for a slow client", "model", modelID, "write_timeout", h.config.Streaming.WriteTimeout)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Streaming generation failed", "error", err)
+		writeSSE(c, eventError, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, usage := answer.Content, answer.Usage
+	latency := time.Since(startTime)
+	h.profiler.UpdateProfileOnSuccess(c.Request.Context(), modelID, latency, usage)
+	h.profiler.RecordSpend(c.Request.Context(), modelID, req.UserID, req.ConversationID, usage)
+
+	// The answer has already been streamed, so a blocked answer ends the stream with a refusal
+	// instead of "done"; clients must discard the content they received.
+	if refusal := h.moderate(c, moderation.StageOutput, &req, content, trace); refusal != nil {
+		h.recordAccountUsage(c, &req, usage, llm.CallCost(modelID, usage))
+		writeSSE(c, eventError, refusal)
+		h.recordAudit(c.Request.Context(), &req, &api.GenerationResponse{
+			ModelUsed:    modelID,
+			Usage:        usage,
+			LatencyMS:    latency.Milliseconds(),
+			CacheStatus:  "MISS",
+			FailoverInfo: failoverInfo,
+		}, trace)
+		return
+	}
+
+	done := streamDoneEvent{
+		ModelUsed:      modelID,
+		Usage:          usage,
+		LatencyMS:      latency.Milliseconds(),
+		RAGContextUsed: trace.RAG != nil && trace.RAG.Used,
+		RAG:            trace.RAG,
+		FinishReason:   answer.StopReason,
+		CacheStatus:    "MISS",
+		FailoverInfo:   failoverInfo,
+		Truncated:      trace.Context != nil && trace.Context.Truncated,
+		CostUSD:        llm.CallCost(modelID, usage),
+		Warnings:       modelWarnings(trace),
+		Routing:        routing,
+		ToolCalls:      trace.ToolCalls,
+	}
+	if req.Debug {
+		done.Debug = trace
+	}
+	done.AccountUsage = h.recordAccountUsage(c, &req, usage, done.CostUSD)
+	writeSSE(c, eventDone, done)
+	h.recordRoutingDecision(c.Request.Context(), routing, modelID, failoverInfo, true, latency, done.CostUSD)
+	h.saveConversationTurn(c.Request.Context(), &req, content, nil)
+
+	h.recordAudit(c.Request.Context(), &req, &api.GenerationResponse{
+		Content:        content,
+		ModelUsed:      modelID,
+		Usage:          usage,
+		LatencyMS:      done.LatencyMS,
+		RAGContextUsed: done.RAGContextUsed,
+		RAG:            done.RAG,
+		FinishReason:   done.FinishReason,
+		CacheStatus:    done.CacheStatus,
+		FailoverInfo:   failoverInfo,
+		Truncated:      done.Truncated,
+		ToolCalls:      done.ToolCalls,
+	}, trace)
+}
+
+// runStreamingAgentLoop is the streaming counterpart of handleToolLoop. Content is forwarded
+// to the client as it arrives; tool calls are accumulated from the stream, announced,
+// executed, and their results fed back to the model for the next round. The answer's usage
+// covers every round.
+func (h *GatewayHandler) runStreamingAgentLoop(c *gin.Context, req api.GenerationRequest, modelID string, messages []llm.Message, toolDefs []tools.Tool, policy tools.ToolPolicy, trace *api.DecisionTrace) (*llm.GenerationResult, error) {
+	const maxToolCalls = 5
+	var cumulativeUsage api.Usage
+
+	client, ok := h.clients[modelID]
+	if !ok {
+		return nil, fmt.Errorf("model '%s' is not available or enabled", modelID)
+	}
+	llmConfig := &llm.GenerationConfig{
+		Model:            modelID,
+		MaxTokens:        req.Config.MaxTokens,
+		Temperature:      req.Config.Temperature,
+		TopP:             req.Config.TopP,
+		Stream:           true,
+		StreamBuffer:     h.config.Streaming.BufferSize,
+		Seed:             req.Config.Seed,
+		Stop:             req.Config.Stop,
+		FrequencyPenalty: req.Config.FrequencyPenalty,
+		PresencePenalty:  req.Config.PresencePenalty,
+		TopK:             req.Config.TopK,
+		ReasoningEffort:  req.Config.ReasoningEffort,
+		ThinkingBudget:   req.Config.ThinkingBudget,
+	}
+
+	for i := 0; i < maxToolCalls; i++ {
+		callStart := time.Now()
+		// Each call can be aborted on its own, so a slow client stops the provider's generation.
+		callCtx, cancelCall := context.WithCancel(c.Request.Context())
+		stream, err := client.GenerateStream(callCtx, messages, llmConfig, toolDefs)
+		if err != nil {
+			cancelCall()
+			h.profiler.UpdateProfileOnFailure(c.Request.Context(), modelID)
+			return nil, fmt.Errorf("LLM stream failed for model %s: %w", modelID, err)
+		}
+
+		onFirstToken := func() {
+			h.profiler.RecordTimeToFirstToken(c.Request.Context(), modelID, time.Since(callStart))
+		}
+		answer, err := h.forwardStream(c, stream, cancelCall, onFirstToken)
+		cancelCall()
+		if errors.Is(err, errSlowClient) {
+			return nil, err
+		}
+		if err != nil {
+			h.profiler.UpdateProfileOnFailure(c.Request.Context(), modelID)
+			return nil, err
+		}
+		toolCalls := llm.UnmaskToolCalls(c.Request.Context(), answer.ToolCalls)
+		cumulativeUsage.Add(answer.Usage)
+
+		if len(toolCalls) == 0 {
+			answer.Usage = cumulativeUsage
+			return answer, nil
+		}
+
+		messages = append(messages, llm.Message{Role: llm.RoleAssistant, Content: answer.Content, ToolCalls: toolCalls})
+		for _, toolCall := range toolCalls {
+			writeSSE(c, eventToolCallStarted, gin.H{"id": toolCall.ID, "name": toolCall.Function.Name, "arguments": toolCall.Function.Arguments})
+			toolResult, executed := h.executeToolCall(c.Request.Context(), toolCall, policy)
+			trace.ToolCalls = append(trace.ToolCalls, executed)
+			writeSSE(c, eventToolResult, gin.H{"id": toolCall.ID, "name": toolCall.Function.Name, "result": toolResult})
+			messages = append(messages, llm.Message{Role: llm.RoleTool, ToolCallID: toolCall.ID, Content: toolResult})
+		}
+	}
+	return nil, errors.New("exceeded maximum number of tool calls")
+}
+
+// forwardStream relays content deltas to the client and reassembles the answer: its content,
+// tool calls, usage, and stop reason.
+//
+// Providers stream a tool call as a first chunk carrying its ID and name followed by
+// chunks carrying only argument fragments, so fragments are appended to the last call.
+// onFirstToken is called when the first content or tool call chunk arrives. Deltas are
+// coalesced as the streaming config asks; if the client stops keeping up, abort is called
+// to end the provider call and errSlowClient is returned.
+func (h *GatewayHandler) forwardStream(c *gin.Context, stream <-chan *llm.StreamingResult, abort func(), onFirstToken func()) (*llm.GenerationResult, error) {
+	var content []byte
+	answer := &llm.GenerationResult{}
+	firstToken := true
+	cfg := h.config.Streaming
+
+	// Always drain the channel, so the provider goroutine can exit even if we stop early.
+	defer func() {
+		for range stream {
+		}
+	}()
+
+	// Deltas waiting to be sent, and the timer that sends them when the interval is up.
+	var pending []byte
+	var flushTimer *time.Timer
+	var flushDue <-chan time.Time
+	defer func() {
+		if flushTimer != nil {
+			flushTimer.Stop()
+		}
+	}()
+	flush := func() error {
+		flushDue = nil
+		if len(pending) == 0 {
+			return nil
+		}
+		delta := string(pending)
+		pending = pending[:0]
+		if !writeSSEWithin(c, cfg.WriteTimeout, eventContentDelta, gin.H{"delta": delta}) {
+			abort()
+			return errSlowClient
+		}
+		return nil
+	}
+
+	for {
+		var chunk *llm.StreamingResult
+		var ok bool
+		select {
+		case chunk, ok = <-stream:
+		case <-flushDue:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !ok {
+			break
+		}
+		if chunk.Err != nil {
+			return nil, fmt.Errorf("error while streaming from provider: %w", chunk.Err)
+		}
+		if firstToken && (chunk.ContentDelta != "" || chunk.ToolCallChunk != nil) {
+			firstToken = false
+			onFirstToken()
+		}
+		if chunk.ContentDelta != "" {
+			content = append(content, chunk.ContentDelta...)
+			pending = append(pending, chunk.ContentDelta...)
+			if cfg.FlushInterval == 0 || (cfg.FlushBytes > 0 && len(pending) >= cfg.FlushBytes) {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			} else if flushDue == nil {
+				if flushTimer == nil {
+					flushTimer = time.NewTimer(cfg.FlushInterval)
+				} else {
+					flushTimer.Reset(cfg.FlushInterval)
+				}
+				flushDue = flushTimer.C
+			}
+		}
+		if tc := chunk.ToolCallChunk; tc != nil {
+			if tc.ID != "" || len(answer.ToolCalls) == 0 {
+				call := *tc
+				answer.ToolCalls = append(answer.ToolCalls, &call)
+			} else {
+				last := answer.ToolCalls[len(answer.ToolCalls)-1]
+				last.Function.Name += tc.Function.Name
+				last.Function.Arguments += tc.Function.Arguments
+			}
+		}
+		if chunk.Usage != nil {
+			answer.Usage.Add(*chunk.Usage)
+		}
+		if chunk.StopReason != "" {
+			answer.StopReason = chunk.StopReason
+		}
+		if chunk.Deprecation != nil {
+			h.profiler.RecordDeprecation(c.Request.Context(), chunk.Deprecation)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	answer.Content = string(content)
+	return answer, nil
+}
+
+// streamCachedResponse replays a cached response to a streaming client as a single delta.
+func (h *GatewayHandler) streamCachedResponse(c *gin.Context, resp api.GenerationResponse) {
+	startSSE(c)
+	writeSSE(c, eventContentDelta, gin.H{"delta": resp.Content})
+	writeSSE(c, eventDone, streamDoneEvent{
+		ModelUsed:      resp.ModelUsed,
+		Usage:          resp.Usage,
+		LatencyMS:      resp.LatencyMS,
+		RAGContextUsed: resp.RAGContextUsed,
+		FinishReason:   resp.FinishReason,
+		CacheStatus:    resp.CacheStatus,
+		Truncated:      resp.Truncated,
+		Debug:          resp.Debug,
+		CostUSD:        resp.CostUSD,
+		AccountUsage:   resp.AccountUsage,
+		Warnings:       resp.Warnings,
+		Routing:        resp.Routing,
+	})
+}
+
+// startSSE writes the response headers for an event stream.
+func startSSE(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (e.g., nginx) so events arrive immediately.
+	c.Status(http.StatusOK)
+}
+
+// writeSSE sends a single JSON-encoded event and flushes it to the client.
+func writeSSE(c *gin.Context, event string, data interface{}) {
+	c.SSEvent(event, data)
+	c.Writer.Flush()
+}
+
+// writeSSEWithin is writeSSE for a client that must take the event within timeout. It
+// reports false if the client did not, or the write failed, after which the connection must
+// not be written to again. A timeout of 0 waits indefinitely.
+func writeSSEWithin(c *gin.Context, timeout time.Duration, event string, data interface{}) bool {
+	if timeout == 0 {
+		writeSSE(c, event, data)
+		// gin aborts the request when rendering the event fails.
+		return !c.IsAborted()
+	}
+	// The deadline makes a write to a client that stopped reading fail instead of blocking.
+	rc := http.NewResponseController(c.Writer)
+	start := time.Now()
+	_ = rc.SetWriteDeadline(start.Add(timeout))
+	writeSSE(c, event, data)
+	if time.Since(start) >= timeout || c.IsAborted() {
+		return false
+	}
+	_ = rc.SetWriteDeadline(time.Time{})
+	return true
+}
@@ -0,0 +1,201 @@
+// In file: cmd/gateway/structured_output.go
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/jsonschema"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+)
+
+// Bounds on how often an answer that does not match the response schema is re-prompted.
+const (
+	defaultSchemaRetries = 2
+	maxSchemaRetries     = 5
+)
+
+// anyObjectSchema is the schema a json_object answer is held to.
+var anyObjectSchema = json.RawMessage(`{"type":"object"}`)
+
+// responseFormatName matches the schema names every provider accepts.
+var responseFormatName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// schemaValidationError is returned when no answer matched the response schema. The answers
+// were still generated, so the usage they incurred travels with the error.
+type schemaValidationError struct {
+	Attempts int
+	Problems []string
+}
+
+func (e *schemaValidationError) Error() string {
+	return fmt.Sprintf("the answer did not match the response schema after %d attempts", e.Attempts)
+}
+
+// validateResponseSchema rejects response schemas that cannot be honoured.
+func validateResponseSchema(req *api.GenerationRequest) error {
+	if len(req.ResponseSchema) == 0 {
+		return nil
+	}
+	if req.Config.Stream {
+		return errors.New("response_schema cannot be combined with streaming")
+	}
+	if _, err := jsonschema.Compile(req.ResponseSchema); err != nil {
+		return fmt.Errorf("invalid response_schema: %w", err)
+	}
+	return nil
+}
+
+// normalizeResponseFormat rejects response formats that cannot be honoured. A text format is
+// dropped, and a json_schema format's schema becomes the request's response schema, so the
+// answer is validated exactly like one that declared response_schema.
+func normalizeResponseFormat(req *api.GenerationRequest) error {
+	format := req.ResponseFormat
+	if format == nil {
+		return nil
+	}
+	switch format.Type {
+	case "", llm.ResponseFormatText, llm.ResponseFormatJSONObject:
+		if len(format.Schema) > 0 {
+			return errors.New("a response_format schema requires the type 'json_schema'")
+		}
+		if format.Type != llm.ResponseFormatJSONObject {
+			req.ResponseFormat = nil
+			return nil
+		}
+	case llm.ResponseFormatJSONSchema:
+		if len(req.ResponseSchema) > 0 {
+			return errors.New("set either response_schema or a 'json_schema' response_format, not both")
+		}
+		var root struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(format.Schema, &root); err != nil || root.Type != "object" {
+			return errors.New("the response_format schema must describe a JSON object")
+		}
+		req.ResponseSchema = format.Schema
+	default:
+		return fmt.Errorf("unknown response_format type '%s' (expected 'text', 'json_object', or 'json_schema')", format.Type)
+	}
+	if format.Name != "" && !responseFormatName.MatchString(format.Name) {
+		return errors.New("the response_format name must be 1 to 64 letters, digits, underscores, or dashes")
+	}
+	if req.Config.Stream {
+		return errors.New("response_format cannot be combined with streaming")
+	}
+	return nil
+}
+
+// responseFormatType identifies the response format in cache keys.
+func responseFormatType(req *api.GenerationRequest) string {
+	if req.ResponseFormat == nil {
+		return ""
+	}
+	return req.ResponseFormat.Type
+}
+
+// llmResponseFormat converts a request's response format for the provider clients.
+func llmResponseFormat(format *api.ResponseFormat) *llm.ResponseFormat {
+	if format == nil {
+		return nil
+	}
+	return &llm.ResponseFormat{Type: format.Type, Name: format.Name, Schema: format.Schema}
+}
+
+// answerSchema returns the JSON Schema the answer must conform to, or nil for free text.
+func answerSchema(req *api.GenerationRequest) json.RawMessage {
+	if len(req.ResponseSchema) > 0 {
+		return req.ResponseSchema
+	}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == llm.ResponseFormatJSONObject {
+		return anyObjectSchema
+	}
+	return nil
+}
+
+// hashResponseSchema identifies a response schema in cache keys.
+func hashResponseSchema(schema []byte) string {
+	if len(schema) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(schema)
+	return hex.EncodeToString(sum[:8])
+}
+
+// schemaInstruction is the system message that asks the model for a conforming JSON answer.
+func schemaInstruction(schema []byte) string {
+	return "Respond only with a JSON value that conforms to the following JSON Schema. " +
+		"Do not add any text, explanation, or Markdown around the JSON.\n\nJSON Schema:\n" + string(schema)
+}
+
+// schemaRetries returns how many times a non-conforming answer is re-prompted.
+func schemaRetries(req *api.GenerationRequest) int {
+	if req.Config.SchemaRetries == nil {
+		return defaultSchemaRetries
+	}
+	return max(0, min(*req.Config.SchemaRetries, maxSchemaRetries))
+}
+
+// conformToSchema validates the model's answer against the request's answer schema. A
+// non-conforming answer is sent back to the model together with the validation errors, and
+// the model is asked again in JSON mode, until an answer conforms or the retries run out.
+// It returns the conforming answer, its content reduced to the JSON, and the usage of the
+// extra attempts.
+func (h *GatewayHandler) conformToSchema(ctx context.Context, req api.GenerationRequest, client llm.LLMClient, messages []llm.Message, llmConfig *llm.GenerationConfig, result *llm.GenerationResult, trace *api.DecisionTrace) (*llm.GenerationResult, api.Usage, error) {
+	var usage api.Usage
+	rawSchema := answerSchema(&req)
+	if len(rawSchema) == 0 {
+		return result, usage, nil
+	}
+	schema, err := jsonschema.Compile(rawSchema)
+	if err != nil {
+		return nil, usage, fmt.Errorf("invalid response_schema: %w", err)
+	}
+	decision := &api.StructuredOutputDecision{}
+	trace.StructuredOutput = decision
+
+	retryConfig := *llmConfig
+	retryConfig.JSONMode = true
+	retries := schemaRetries(&req)
+	for attempt := 0; ; attempt++ {
+		decision.Attempts++
+		doc, err := jsonschema.ExtractJSON(result.Content)
+		var problems []string
+		if err != nil {
+			problems = []string{"$: " + err.Error()}
+		} else {
+			problems = schema.ValidateJSON(doc)
+		}
+		if len(problems) == 0 {
+			decision.Valid = true
+			decision.Problems = nil
+			conforming := *result
+			conforming.Content = doc
+			return &conforming, usage, nil
+		}
+		decision.Problems = problems
+		if attempt == retries {
+			return nil, usage, &schemaValidationError{Attempts: decision.Attempts, Problems: problems}
+		}
+
+		slog.InfoContext(ctx, "Answer does not match the response schema, re-prompting", "attempt", decision.Attempts, "problems", len(problems))
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: result.Content},
+			llm.Message{Role: llm.RoleUser, Content: "Your answer does not conform to the JSON Schema:\n- " + strings.Join(problems, "\n- ") +
+				"\n\nRespond again with only the corrected JSON value."},
+		)
+		result, err = client.Generate(ctx, messages, &retryConfig, nil)
+		if err != nil {
+			return nil, usage, fmt.Errorf("LLM generation failed while re-prompting for the response schema: %w", err)
+		}
+		usage.Add(result.Usage)
+	}
+}
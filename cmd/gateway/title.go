@@ -0,0 +1,149 @@
+// In file: cmd/gateway/title.go
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+)
+
+const (
+	// titleTimeout bounds the background title request, which outlives the HTTP request.
+	titleTimeout = 20 * time.Second
+	// titleMaxTokens keeps generated titles to a short sidebar label.
+	titleMaxTokens = 20
+	// titleMaxLength truncates titles from models that ignore the length instruction.
+	titleMaxLength = 80
+	// titleInputLimit caps how much of each message is sent to the title model.
+	titleInputLimit = 1000
+	// titleModelOff disables automatic titling when set as the title model.
+	titleModelOff = "off"
+)
+
+const titleInstruction = "Write a short title (at most 6 words) for the conversation below. " +
+	"Reply with the title only, without quotes or trailing punctuation."
+
+// maybeGenerateTitle titles a conversation in the background once its first exchange has been
+// saved. Conversations that already have a title, including ones renamed by the user, are left alone.
+func (h *GatewayHandler) maybeGenerateTitle(ctx context.Context, req *api.GenerationRequest, answer string) {
+	if req.ConversationID == "" || len(req.History) > 0 || h.config.TitleModel == titleModelOff {
+		return
+	}
+	conversationID, userID, prompt := req.ConversationID, req.UserID, req.Prompt
+	go func() {
+		// Keep the request's log fields and trace, but not its cancellation.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), titleTimeout)
+		defer cancel()
+
+		modelID := h.titleModel(ctx)
+		if modelID == "" {
+			return
+		}
+		info, err := h.conversations.Get(ctx, conversationID)
+		if err != nil || info.Title != "" {
+			return
+		}
+		title, err := h.generateTitle(ctx, modelID, userID, conversationID, prompt, answer)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to generate a conversation title", "title_model", modelID, "error", err)
+			return
+		}
+		if title == "" {
+			return
+		}
+		// Re-check so a rename made while the title was being generated is not overwritten.
+		if info, err := h.conversations.Get(ctx, conversationID); err != nil || info.Title != "" {
+			return
+		}
+		if err := h.conversations.Rename(ctx, conversationID, title); err != nil {
+			slog.WarnContext(ctx, "Failed to save the conversation title", "error", err)
+			return
+		}
+		slog.InfoContext(ctx, "Titled conversation", "title_model", modelID, "title", title)
+	}()
+}
+
+// generateTitle asks the title model to summarise the first exchange of a conversation. The
+// call is charged to the conversation's user and counts towards the model's profile.
+func (h *GatewayHandler) generateTitle(ctx context.Context, modelID, userID, conversationID, prompt, answer string) (string, error) {
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: titleInstruction},
+		{Role: llm.RoleUser, Content: "User: " + truncateForTitle(prompt) + "\n\nAssistant: " + truncateForTitle(answer)},
+	}
+	start := time.Now()
+	result, err := h.clients[modelID].Generate(ctx, messages, &llm.GenerationConfig{Model: modelID, MaxTokens: titleMaxTokens}, nil)
+	if err != nil {
+		h.profiler.UpdateProfileOnFailure(ctx, modelID)
+		return "", err
+	}
+	h.profiler.UpdateProfileOnSuccess(ctx, modelID, time.Since(start), result.Usage)
+	h.profiler.RecordSpend(ctx, modelID, userID, conversationID, result.Usage)
+	return cleanTitle(result.Content), nil
+}
+
+// titleModel returns the model used for titling, or "" if titling is disabled or no model is
+// available. Without an explicit choice, the available enabled model with the lowest input
+// cost is used. Models that are disabled by an operator or offline are never used.
+func (h *GatewayHandler) titleModel(ctx context.Context) string {
+	if h.config.TitleModel == titleModelOff {
+		return ""
+	}
+	if h.config.TitleModel != "" {
+		if _, ok := h.clients[h.config.TitleModel]; ok && h.unavailableReason(ctx, h.config.TitleModel) == "" {
+			return h.config.TitleModel
+		}
+		return ""
+	}
+	available := make(map[string]llm.LLMClient, len(h.clients))
+	for modelID, client := range h.clients {
+		if h.unavailableReason(ctx, modelID) == "" {
+			available[modelID] = client
+		}
+	}
+	return cheapestModel(h.config, available)
+}
+
+// cheapestModel returns the enabled model with the lowest input cost, or "" if no model has
+// configured costs.
+func cheapestModel(cfg *AppConfig, clients map[string]llm.LLMClient) string {
+	cheapest := ""
+	for _, modelID := range cfg.EnabledModels {
+		if _, ok := clients[modelID]; !ok {
+			continue
+		}
+		costs, ok := cfg.ModelCosts[modelID]
+		if !ok {
+			continue
+		}
+		if cheapest == "" || costs["input"] < cfg.ModelCosts[cheapest]["input"] {
+			cheapest = modelID
+		}
+	}
+	return cheapest
+}
+
+// truncateForTitle shortens a message to the part that is sent to the title model.
+func truncateForTitle(s string) string {
+	if runes := []rune(s); len(runes) > titleInputLimit {
+		return string(runes[:titleInputLimit])
+	}
+	return s
+}
+
+// cleanTitle strips the quotes, labels, and punctuation models tend to wrap titles in.
+func cleanTitle(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimPrefix(s, "Title:")
+	s = strings.Trim(strings.TrimSpace(s), "\"'*`.")
+	if runes := []rune(s); len(runes) > titleMaxLength {
+		s = strings.TrimSpace(string(runes[:titleMaxLength]))
+	}
+	return s
+}
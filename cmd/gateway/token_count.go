@@ -0,0 +1,63 @@
+// In file: cmd/gateway/token_count.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Methods of counting reported by HandleCountTokens.
+const (
+	countMethodProvider = "provider"
+	countMethodEstimate = "estimate"
+)
+
+// HandleCountTokens counts the input tokens of a message list for a model, so clients can
+// check that a request fits the model's context window before sending it. Providers that
+// count tokens for free are asked; the tokens for the others are estimated.
+// POST /api/v1/tokens/count
+func (h *GatewayHandler) HandleCountTokens(c *gin.Context) {
+	if h.config.Limits.MaxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.config.Limits.MaxBodyBytes)
+	}
+	var req api.TokenCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if len(req.Messages) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: messages must not be empty"})
+		return
+	}
+	if req.MaxTokens < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: max_tokens must not be negative"})
+		return
+	}
+	req.Model = h.config.RouterConfig.StripProviderPrefix(req.Model)
+	client, ok := h.clients[req.Model]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("model '%s' is not available or enabled", req.Model)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	messages := convertAPIMessagesToLLMMessages(req.Messages)
+	resp := api.TokenCountResponse{Model: req.Model, Method: countMethodProvider, ContextWindow: h.router.ContextWindow(req.Model)}
+	count, err := llm.CountTokens(ctx, client, req.Model, messages)
+	if err != nil {
+		if !errors.Is(err, llm.ErrCountUnsupported) {
+			slog.WarnContext(ctx, "Provider token count failed. Estimating instead", "model", req.Model, "error", err)
+		}
+		count, resp.Method = llm.EstimateMessageTokensFor(h.router.ProviderOf(req.Model), messages), countMethodEstimate
+	}
+	resp.InputTokens = count
+	resp.Fits = count+req.MaxTokens <= resp.ContextWindow
+	c.JSON(http.StatusOK, resp)
+}
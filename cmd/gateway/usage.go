@@ -0,0 +1,42 @@
+// In file: cmd/gateway/usage.go
+package main
+
+import (
+	"log/slog"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usageAccount identifies who a request is billed to: the caller's user, from its verified
+// token or, in AuthModeNone, the X-User-ID header, as for user budgets. An unverified bearer
+// token is never used, since any caller could bill its usage to a made-up account with it.
+func usageAccount(c *gin.Context) string {
+	if userID := callerUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	return "anonymous"
+}
+
+// variantUnit is the unit a request is assigned a prompt scoring variant by: its caller's
+// account, so a caller sees consistent routing, or its conversation when the caller is
+// anonymous.
+func variantUnit(c *gin.Context, req *api.GenerationRequest) string {
+	if account := usageAccount(c); account != "anonymous" || req.ConversationID == "" {
+		return account
+	}
+	return "conversation:" + req.ConversationID
+}
+
+// recordAccountUsage charges a request to the caller's account and returns the account's
+// updated monthly totals. Accounting failures are logged and never fail the request.
+func (h *GatewayHandler) recordAccountUsage(c *gin.Context, req *api.GenerationRequest, usage api.Usage, cost float64) *api.AccountUsage {
+	c.Set(usedTokensKey, usage.TotalTokens)
+	accountUsage, err := h.profiler.RecordAccountUsage(c.Request.Context(), usageAccount(c), usage, cost)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to record account usage", "error", err)
+		return nil
+	}
+	return accountUsage
+}
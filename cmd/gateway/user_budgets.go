@@ -0,0 +1,84 @@
+// In file: cmd/gateway/user_budgets.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+	"github.com/gin-gonic/gin"
+)
+
+// UserBudgetConfig is the `user_budgets` section of config.yaml. It caps what each user may
+// spend in a calendar month, as attributed by the profiler's spend counters.
+type UserBudgetConfig struct {
+	// DefaultUSD is the monthly budget of every user without their own. Zero means none.
+	DefaultUSD float64 `yaml:"default_usd"`
+	// Users sets the monthly budgets of individual users, by user ID.
+	Users map[string]float64 `yaml:"users"`
+}
+
+// Validate reports settings that cannot work.
+func (c UserBudgetConfig) Validate() error {
+	if c.DefaultUSD < 0 {
+		return errors.New("default_usd must not be negative")
+	}
+	for userID, budget := range c.Users {
+		if budget < 0 {
+			return fmt.Errorf("the budget of user %s must not be negative", userID)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether any user has a budget.
+func (c UserBudgetConfig) Enabled() bool {
+	return c.DefaultUSD > 0 || len(c.Users) > 0
+}
+
+// BudgetFor returns a user's monthly budget, or 0 if the user has none.
+func (c UserBudgetConfig) BudgetFor(userID string) float64 {
+	if budget, ok := c.Users[userID]; ok {
+		return budget
+	}
+	return c.DefaultUSD
+}
+
+// checkUserBudget refuses requests of users who have spent their monthly budget. userID must
+// come from callerUserID, never from a request body. While budgets are enabled, anonymous
+// requests are refused, since they could not be charged to anyone. The request is let through
+// if the spend cannot be read.
+func (h *GatewayHandler) checkUserBudget(c *gin.Context, userID string) *requestError {
+	if userID == "" {
+		if !h.config.UserBudgets.Enabled() {
+			return nil
+		}
+		return &requestError{
+			Status:  http.StatusUnauthorized,
+			Message: fmt.Sprintf("the %s header or a token is required while user budgets are enabled", userHeader),
+			Code:    codeUserRequired,
+		}
+	}
+	budget := h.config.UserBudgets.BudgetFor(userID)
+	if budget <= 0 {
+		return nil
+	}
+	spend, err := h.profiler.Spend(c.Request.Context(), llm.CostByUser, userID, time.Now().UTC().Format("2006-01"))
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Could not read user spend. Skipping the budget check", "error", err)
+		return nil
+	}
+	if spend.CostUSD < budget {
+		return nil
+	}
+	return &requestError{
+		Status:    http.StatusPaymentRequired,
+		Message:   fmt.Sprintf("user '%s' has spent their monthly budget ($%.2f of $%.2f)", userID, spend.CostUSD, budget),
+		Code:      codeUserBudgetExceeded,
+		BudgetUSD: budget,
+		SpentUSD:  spend.CostUSD,
+	}
+}
@@ -0,0 +1,202 @@
+// In file: cmd/gateway/validate.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// =================================================================================
+// `gateway config validate`
+// =================================================================================
+// Loads .env and config.yaml exactly as the server would and reports every problem
+// that would otherwise only surface at runtime: a threshold of the wrong type, an
+// enabled model without an API key, costs, or router metadata, or a missing strategy.
+// Run it before a deploy; it exits non-zero if anything fails.
+// =================================================================================
+
+// runCommand runs a CLI subcommand and returns the process exit code.
+func runCommand(args []string) int {
+	if len(args) == 2 && args[0] == "config" && args[1] == "validate" {
+		return runConfigValidate(os.Stdout)
+	}
+	if args[0] == "profiles" {
+		return runProfilesCommand(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown command: %s\nusage: gateway [config validate | profiles export|import]\n", strings.Join(args, " "))
+	return 2
+}
+
+// configReport collects the outcome of each configuration check.
+type configReport struct {
+	out      io.Writer
+	failures int
+	warnings int
+}
+
+func (r *configReport) section(title string) { fmt.Fprintf(r.out, "%s\n", title) }
+func (r *configReport) ok(format string, args ...any) {
+	fmt.Fprintf(r.out, "  OK    %s\n", fmt.Sprintf(format, args...))
+}
+func (r *configReport) warn(format string, args ...any) {
+	r.warnings++
+	fmt.Fprintf(r.out, "  WARN  %s\n", fmt.Sprintf(format, args...))
+}
+func (r *configReport) fail(format string, args ...any) {
+	r.failures++
+	fmt.Fprintf(r.out, "  FAIL  %s\n", fmt.Sprintf(format, args...))
+}
+
+// runConfigValidate prints the configuration report and returns 1 if any check failed.
+func runConfigValidate(out io.Writer) int {
+	report := &configReport{out: out}
+	report.section("Configuration")
+	cfg, err := LoadConfig()
+	if err != nil {
+		// LoadConfig stops at the first invalid setting; fix it and run the check again.
+		// Joined errors, such as those of several thresholds, are listed one per line.
+		for _, line := range strings.Split(err.Error(), "\n") {
+			report.fail("%s", line)
+		}
+		fmt.Fprintln(out, "\nConfiguration is invalid.")
+		return 1
+	}
+	report.ok("environment and config.yaml loaded (profile %s)", cfg.Profile)
+	report.ok("pre_check_thresholds have the expected types")
+	validateStrategies(report, cfg)
+	validateAliases(report, cfg)
+	validateIntentRoutes(report, cfg)
+	validateToolModels(report, cfg)
+	for _, modelID := range cfg.EnabledModels {
+		validateModel(report, cfg, modelID)
+	}
+
+	fmt.Fprintf(out, "\n%d failed, %d warnings.\n", report.failures, report.warnings)
+	if report.failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+// validateStrategies checks the strategies the router falls back to.
+func validateStrategies(report *configReport, cfg *AppConfig) {
+	if _, ok := cfg.RouterConfig.Strategies["default"]; ok {
+		report.ok("strategy 'default' is defined")
+	} else {
+		report.fail("strategy 'default' is missing; requests with an unknown preference will fail")
+	}
+	// smart-balanced switches between these two; a missing one scores every model as 0.
+	for _, name := range []string{"latency-focused-balanced", "quality-focused-balanced"} {
+		if _, ok := cfg.RouterConfig.Strategies[name]; !ok {
+			report.warn("strategy '%s' is missing; the smart-balanced preference will not rank models", name)
+		}
+	}
+}
+
+// validateIntentRoutes checks that every intent route leads to a model or strategy the router
+// can use.
+func validateIntentRoutes(report *configReport, cfg *AppConfig) {
+	intents := make([]string, 0, len(cfg.Intents.Routing))
+	for intent := range cfg.Intents.Routing {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+	for _, intent := range intents {
+		route := cfg.Intents.Routing[intent]
+		_, strategyDefined := cfg.RouterConfig.Strategies[route.Strategy]
+		switch {
+		case route.Model != "" && !slices.Contains(cfg.EnabledModels, route.Model):
+			report.warn("intent '%s' is routed to '%s', which is not an enabled model; its requests are routed by preference", intent, route.Model)
+		case route.Strategy != "" && route.Strategy != "smart-balanced" && !strategyDefined:
+			report.warn("intent '%s' is routed by strategy '%s', which is not defined; the default strategy is used", intent, route.Strategy)
+		case route.Model != "":
+			report.ok("intent '%s' -> model '%s'", intent, route.Model)
+		default:
+			report.ok("intent '%s' -> strategy '%s'", intent, route.Strategy)
+		}
+	}
+}
+
+// validateToolModels checks that requests answered with tools have a model to be routed to.
+func validateToolModels(report *configReport, cfg *AppConfig) {
+	var toolModels []string
+	for _, modelID := range cfg.EnabledModels {
+		if cfg.RouterConfig.Models[modelID].SupportsTools {
+			toolModels = append(toolModels, modelID)
+		}
+	}
+	if len(toolModels) == 0 {
+		report.warn("no enabled model sets supports_tools; requests answered with tools will fail")
+		return
+	}
+	report.ok("models that can call tools: %s", strings.Join(toolModels, ", "))
+}
+
+// validateModel checks that an enabled model can be called, priced, and routed to.
+func validateModel(report *configReport, cfg *AppConfig, modelID string) {
+	report.section("Model " + modelID)
+	if trimmed := strings.TrimSpace(modelID); trimmed != modelID || trimmed == "" {
+		report.fail("enabled model '%s' has surrounding whitespace or is empty", modelID)
+		return
+	}
+
+	keyEnv := providerKeyEnv(cfg.RouterConfig.ProviderOf(modelID))
+	switch {
+	case keyEnv == "":
+		report.fail("unknown provider; the gateway has no client for this model")
+	case cfg.APIKeys[modelID] == "":
+		report.fail("API key missing: set %s", keyEnv)
+	case cfg.APIKeyRefs[modelID] != "":
+		report.ok("API key resolved from %s", cfg.APIKeyRefs[modelID])
+	default:
+		report.ok("API key set (%s)", keyEnv)
+	}
+
+	if costs, ok := cfg.ModelCosts[modelID]; ok {
+		report.ok("costs set ($%.2f / $%.2f per million input / output tokens)", costs["input"]*1_000_000, costs["output"]*1_000_000)
+		if reasoning, ok := costs["reasoning"]; ok {
+			report.ok("reasoning tokens cost $%.2f per million", reasoning*1_000_000)
+		}
+	} else {
+		report.fail("costs missing: set `costs` (input and output, USD per million tokens) under models.%s in config.yaml", modelID)
+	}
+	if budget, ok := cfg.ModelBudgets[modelID]; ok {
+		report.ok("monthly budget $%.2f", budget)
+	}
+
+	meta, ok := cfg.RouterConfig.Models[modelID]
+	if !ok {
+		report.fail("no entry under `models` in config.yaml; the router will never select this model")
+		return
+	}
+	report.ok("router metadata (quality %.1f, coding %.1f)", meta.QualityScore, meta.CodingScore)
+	if meta.ContextWindow <= 0 {
+		report.warn("context_window is not set; a conservative default is used for history truncation")
+	}
+	policy := cfg.RouterConfig.RequestPolicyFor(modelID)
+	report.ok("request timeout %s, %d retries starting after %s", policy.Timeout, *policy.MaxRetries, policy.InitialRetryDelay)
+}
+
+// validateAliases checks that every alias leads to a model requests can be sent to.
+func validateAliases(report *configReport, cfg *AppConfig) {
+	names := make([]string, 0, len(cfg.RouterConfig.Aliases))
+	for name := range cfg.RouterConfig.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		alias := cfg.RouterConfig.Aliases[name]
+		switch {
+		case !slices.Contains(cfg.EnabledModels, alias.Target):
+			report.warn("alias '%s' points at '%s', which is not an enabled model; requests for it will fail", name, alias.Target)
+		case alias.Deprecated:
+			report.ok("alias '%s' -> '%s' (deprecated)", name, alias.Target)
+		default:
+			report.ok("alias '%s' -> '%s'", name, alias.Target)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// In file: cmd/gateway/webhook_handler.go
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/dileep-u-k/llm-gateway/internal/ingest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler accepts content-change webhooks from CMS platforms (GitHub, Notion,
+// Confluence) and enqueues incremental ingestion of the changed documents, keeping
+// the RAG knowledge base fresh without manual ingestor runs.
+type WebhookHandler struct {
+	pipeline *ingest.Pipeline
+}
+
+func NewWebhookHandler(pipeline *ingest.Pipeline) *WebhookHandler {
+	return &WebhookHandler{pipeline: pipeline}
+}
+
+// HandleIngestWebhook authenticates and parses a webhook for the connector named
+// in the URL, then queues the resulting changes and responds with 202 Accepted.
+func (h *WebhookHandler) HandleIngestWebhook(c *gin.Context) {
+	connectorName := c.Param("source")
+	connector, ok := h.pipeline.Connector(connectorName)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No ingestion connector configured for source '" + connectorName + "'."})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read webhook body: " + err.Error()})
+		return
+	}
+	if err := connector.VerifySignature(c.Request.Header, body); err != nil {
+		slog.WarnContext(c.Request.Context(), "Rejected webhook", "connector", connectorName, "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, err := connector.ParseWebhook(c.Request.Header, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accepted, err := h.pipeline.Enqueue(changes)
+	if errors.Is(err, ingest.ErrQueueFull) {
+		// Ask the CMS to redeliver later; most platforms retry on 5xx responses.
+		slog.WarnContext(c.Request.Context(), "Ingestion queue full", "connector", connectorName, "accepted", accepted, "changes", len(changes))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "accepted": accepted})
+		return
+	}
+
+	slog.InfoContext(c.Request.Context(), "Queued document changes from webhook", "connector", connectorName, "accepted", accepted)
+	c.JSON(http.StatusAccepted, gin.H{"accepted": accepted, "changes": changes})
+}
@@ -0,0 +1,124 @@
+// In file: cmd/ingestor/checkpoint.go
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// defaultCheckpointFile is where progress is kept when ingest.checkpoint_file is not set.
+const defaultCheckpointFile = ".ingest-checkpoint.json"
+
+// checkpoint records how many embedding batches of each topic have been upserted, so an
+// interrupted run resumes after the last successful batch instead of starting over. A
+// topic's entry is only honoured while its chunks are unchanged, and is removed once the
+// topic is complete; the file itself is removed when no topic is left unfinished.
+type checkpoint struct {
+	path   string
+	mu     sync.Mutex
+	Topics map[string]topicProgress `json:"topics"`
+}
+
+// topicProgress is the saved progress of one topic.
+type topicProgress struct {
+	// Fingerprint identifies the chunks and settings the batches were computed from.
+	Fingerprint string `json:"fingerprint"`
+	// Batches is the number of batches upserted, counted from the first.
+	Batches int `json:"batches"`
+}
+
+// loadCheckpoint reads the checkpoint file. A missing file is an empty checkpoint.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{path: path, Topics: make(map[string]topicProgress)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s (delete it to start over): %w", path, err)
+	}
+	if c.Topics == nil {
+		c.Topics = make(map[string]topicProgress)
+	}
+	return c, nil
+}
+
+// resumeFrom returns the number of batches of a topic that are already upserted.
+func (c *checkpoint) resumeFrom(topic, fingerprint string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.Topics[topic]; ok && p.Fingerprint == fingerprint {
+		return p.Batches
+	}
+	return 0
+}
+
+// record saves that the first batches of a topic are upserted.
+func (c *checkpoint) record(topic, fingerprint string, batches int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Topics[topic] = topicProgress{Fingerprint: fingerprint, Batches: batches}
+	return c.save()
+}
+
+// finish forgets a completed topic.
+func (c *checkpoint) finish(topic string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.Topics[topic]; !ok {
+		return nil
+	}
+	delete(c.Topics, topic)
+	return c.save()
+}
+
+// save writes the checkpoint atomically, so an interruption mid-write never corrupts it.
+// The caller must hold c.mu.
+func (c *checkpoint) save() error {
+	if len(c.Topics) == 0 {
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove checkpoint %s: %w", c.path, err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint %s: %w", c.path, err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// chunksFingerprint identifies a topic's chunks, their metadata, and the settings that shape
+// the upserted vectors. Any change to the sources or settings restarts the topic.
+func chunksFingerprint(chunks []documentChunk, embeddingModel string, batchSize int) string {
+	h := sha256.New()
+	// Every field is length-prefixed, so different chunk lists never hash alike.
+	write := func(field string) {
+		fmt.Fprintf(h, "%d:%s", len(field), field)
+	}
+	write(embeddingModel)
+	write(strconv.Itoa(batchSize))
+	for _, chunk := range chunks {
+		write(chunk.document)
+		write(strconv.Itoa(chunk.Page))
+		write(chunk.Text)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
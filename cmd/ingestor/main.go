@@ -13,19 +13,24 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/dileep-u-k/llm-gateway/internal/config"
+	"github.com/dileep-u-k/llm-gateway/internal/ingest"
 	"github.com/dileep-u-k/llm-gateway/internal/llm"
-
-	"github.com/joho/godotenv"
 )
 
 // =================================================================================
@@ -33,61 +38,108 @@ import (
 // =================================================================================
 
 const (
-	defaultEmbeddingModel = "text-embedding-3-small"
-	defaultOpenAIAPIURL   = "https://api.openai.com/v1/embeddings"
-	defaultSourceDataDir  = "./data"
-	pineconeUpsertPath    = "/vectors/upsert"
-	upsertBatchSize       = 100
-	maxRetries            = 3
-	initialRetryDelay     = 2 * time.Second
+	defaultSourceDataDir = "./data"
+	pineconeUpsertPath   = "/vectors/upsert"
+	upsertBatchSize      = 100
+	maxRetries           = 3
+	initialRetryDelay    = 2 * time.Second
+	// embeddingBatchSize is the number of chunks embedded and upserted together. Progress is
+	// checkpointed after every batch.
+	embeddingBatchSize = 500
+	// defaultConcurrency is the number of topics ingested at once.
+	defaultConcurrency = 4
+	// intentsDir is the directory of the source data that holds intent training examples
+	// rather than a knowledge topic.
+	intentsDir = "intents"
 )
 
-// Config is now simplified, as Redis is no longer needed by the ingestor.
+// Config holds the ingestor's settings. The Pinecone index comes from the shared RAG
+// configuration, so the ingestor always writes to the index the gateway reads.
 type Config struct {
-	OpenAIKey      string
-	PineconeKey    string
-	PineconeHost   string
+	PineconeKey   string
+	PineconeHost  string
+	SourceDataDir string
+	// Chunking is shared with the gateway's webhook ingestion, so both chunk a topic alike.
+	Chunking ingest.ChunkingConfig
+	// Sync deletes vectors whose source files or chunks no longer exist after ingesting.
+	Sync bool
+	// CheckpointFile records progress, so an interrupted run resumes where it stopped.
+	CheckpointFile string
+	// EmbeddingModel is part of the checkpoint fingerprint: switching models restarts a topic.
 	EmbeddingModel string
-	OpenAIAPIURL   string
-	SourceDataDir  string
+	// Concurrency is the number of topics ingested at once.
+	Concurrency int
+	// EmbeddingRequestsPerMinute throttles embedding requests across all topics; 0 is unlimited.
+	EmbeddingRequestsPerMinute int
+	// IntentNames are the intents, built-in and custom, training examples may be given for.
+	IntentNames []string
+}
+
+// fileConfig is the part of the shared configuration file that only the ingestor reads.
+type fileConfig struct {
+	Ingest struct {
+		SourceDataDir              string `yaml:"source_data_dir"`
+		CheckpointFile             string `yaml:"checkpoint_file"`
+		Concurrency                int    `yaml:"concurrency"`
+		EmbeddingRequestsPerMinute int    `yaml:"embedding_requests_per_minute"`
+	} `yaml:"ingest"`
+	Intents llm.IntentConfig `yaml:"intents"`
 }
 
-// loadConfig is simplified.
-func loadConfig() (*Config, error) {
-	if err := godotenv.Load(".env"); err != nil {
-		log.Println("Warning: .env file not found. Relying on environment variables.")
+// loadConfig loads the ingestor's settings and the RAG configuration from the shared
+// configuration file, with keys from the environment. A dry run needs no keys.
+func loadConfig(dryRun bool) (*Config, *llm.Config, error) {
+	config.LoadEnv()
+	var file fileConfig
+	if err := config.Load(&file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	chunking, err := ingest.LoadChunkingConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	loadRAGConfig := llm.LoadConfig
+	if dryRun {
+		loadRAGConfig = llm.LoadSettings
+	}
+	ragConfig, err := loadRAGConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load RAG config: %w", err)
 	}
 	cfg := &Config{
-		OpenAIKey:      os.Getenv("OPENAI_API_KEY"),
-		PineconeKey:    os.Getenv("PINECONE_API_KEY"),
-		PineconeHost:   os.Getenv("PINECONE_INDEX_HOST"),
-		EmbeddingModel: getEnv("EMBEDDING_MODEL", defaultEmbeddingModel),
-		OpenAIAPIURL:   getEnv("OPENAI_API_URL", defaultOpenAIAPIURL),
-		SourceDataDir:  getEnv("SOURCE_DATA_DIR", defaultSourceDataDir),
+		PineconeKey:                ragConfig.PineconeKey,
+		PineconeHost:               ragConfig.PineconeHost,
+		SourceDataDir:              config.Legacy(file.Ingest.SourceDataDir, "SOURCE_DATA_DIR", "ingest.source_data_dir"),
+		Chunking:                   chunking,
+		CheckpointFile:             file.Ingest.CheckpointFile,
+		EmbeddingModel:             ragConfig.EmbeddingModel,
+		Concurrency:                file.Ingest.Concurrency,
+		EmbeddingRequestsPerMinute: file.Ingest.EmbeddingRequestsPerMinute,
+		IntentNames:                file.Intents.Names(),
 	}
-	if cfg.OpenAIKey == "" || cfg.PineconeKey == "" || cfg.PineconeHost == "" {
-		return nil, errors.New("OPENAI_API_KEY, PINECONE_API_KEY, and PINECONE_INDEX_HOST must be set")
+	if cfg.SourceDataDir == "" {
+		cfg.SourceDataDir = defaultSourceDataDir
 	}
-	return cfg, nil
-}
-
-// getEnv is a helper to read an env var or return a default value.
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+	if cfg.CheckpointFile == "" {
+		cfg.CheckpointFile = defaultCheckpointFile
 	}
-	return fallback
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	return cfg, ragConfig, nil
 }
 
 // =================================================================================
 // Ingestor Service
 // =================================================================================
 
-// Ingestor is now a dedicated Pinecone pipeline.
+// Ingestor loads knowledge topics into Pinecone and intent training examples into Redis.
 type Ingestor struct {
 	config     *Config
 	httpClient *http.Client
 	ragService *llm.RAGService
+	checkpoint *checkpoint
+	progress   *progress
 }
 
 // NewIngestor is simpler without Redis dependencies.
@@ -102,51 +154,196 @@ func NewIngestor(cfg *Config, ragService *llm.RAGService) (*Ingestor, error) {
 // main is simplified to reflect the ingestor's new focus.
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	cfg, err := loadConfig()
+	strategy := flag.String("chunk-strategy", "", "default chunking strategy: heading, fixed, recursive, or sentence-window (overrides ingest.chunking.strategy)")
+	size := flag.Int("chunk-size", 0, "default chunk size in tokens (overrides ingest.chunking.chunk_size)")
+	overlap := flag.Int("chunk-overlap", -1, "default chunk overlap in tokens (overrides ingest.chunking.chunk_overlap)")
+	syncIndex := flag.Bool("sync", false, "after ingesting, delete vectors of removed or rewritten documents and of removed topics")
+	dryRun := flag.Bool("dry-run", false, "preview chunks, token counts, and embedding cost per topic without calling OpenAI or Pinecone")
+	concurrency := flag.Int("concurrency", 0, "number of topics ingested at once (overrides ingest.concurrency)")
+	embeddingRPM := flag.Int("embedding-rpm", -1, "embedding requests per minute across all topics, 0 for unlimited (overrides ingest.embedding_requests_per_minute)")
+	flag.Parse()
+
+	cfg, ragConfig, err := loadConfig(*dryRun)
 	if err != nil {
 		log.Fatalf("❌ Configuration Error: %v", err)
 	}
-	// The RAGService is still needed to get embeddings consistently.
-	ragConfig, err := llm.LoadConfig()
-	if err != nil {
-		log.Fatalf("❌ Failed to load RAG Service config: %v", err)
+	// Flags replace the configured defaults for this run; per-topic overrides still apply.
+	if *strategy != "" {
+		cfg.Chunking.Strategy = *strategy
 	}
+	if *size > 0 {
+		cfg.Chunking.Size = *size
+	}
+	if *overlap >= 0 {
+		cfg.Chunking.Overlap = overlap
+	}
+	if err := cfg.Chunking.Validate(); err != nil {
+		log.Fatalf("❌ Configuration Error: invalid chunking flags: %v", err)
+	}
+	cfg.Sync = *syncIndex
+	if *concurrency > 0 {
+		cfg.Concurrency = *concurrency
+	}
+	if *embeddingRPM >= 0 {
+		cfg.EmbeddingRequestsPerMinute = *embeddingRPM
+	}
+	if cfg.Concurrency < 1 || cfg.EmbeddingRequestsPerMinute < 0 {
+		log.Fatalf("❌ Configuration Error: concurrency must be at least 1 and embedding_requests_per_minute at least 0")
+	}
+	if *dryRun {
+		if err := preview(os.Stdout, cfg, ragConfig); err != nil {
+			log.Fatalf("❌ Dry run failed: %v", err)
+		}
+		return
+	}
+	// The RAGService is still needed to get embeddings consistently.
 	ragService, err := llm.NewRAGService(ragConfig)
 	if err != nil {
 		log.Fatalf("❌ Failed to create RAG Service: %v", err)
 	}
+	ragService.SetEmbeddingRateLimit(cfg.EmbeddingRequestsPerMinute)
 	ingestor, err := NewIngestor(cfg, ragService)
 	if err != nil {
 		log.Fatalf("❌ Failed to create ingestor: %v", err)
 	}
-	if err := ingestor.Run(); err != nil {
+	// Ctrl-C stops the run after the batch in flight; the checkpoint lets the next run resume.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := ingestor.Run(ctx); err != nil {
 		log.Fatalf("❌ Ingestion process failed: %v", err)
 	}
 }
 
 // Run is now a simpler loop that only processes RAG topics for Pinecone.
-func (i *Ingestor) Run() error {
+func (i *Ingestor) Run(ctx context.Context) error {
+	if err := i.ingestIntentExamples(ctx); err != nil {
+		return err
+	}
 	log.Println("🚀 Starting RAG data ingestion process for Pinecone...")
 	topics, err := i.discoverTopics()
 	if err != nil {
 		return fmt.Errorf("failed to discover document topics: %w", err)
 	}
-	var wg sync.WaitGroup
+	if i.checkpoint, err = loadCheckpoint(i.config.CheckpointFile); err != nil {
+		return err
+	}
+	i.progress = newProgress()
+	// A fixed pool of workers bounds how many topics call the embedding API at once.
+	queue := make(chan string, len(topics))
 	for _, topic := range topics {
+		queue <- topic
+	}
+	close(queue)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	written := make(map[string]map[string]bool, len(topics))
+	for w := 0; w < min(i.config.Concurrency, len(topics)); w++ {
 		wg.Add(1)
-		go func(t string) {
+		go func() {
 			defer wg.Done()
-			if err := i.ingestTopicToPinecone(t); err != nil {
-				log.Printf("❌ Error ingesting topic %s to Pinecone: %v", t, err)
+			for t := range queue {
+				ids, err := i.ingestTopicToPinecone(ctx, t)
+				if err != nil {
+					log.Printf("❌ Error ingesting topic %s to Pinecone: %v", t, err)
+					i.progress.fail(t)
+					continue
+				}
+				mu.Lock()
+				written[t] = ids
+				mu.Unlock()
 			}
-		}(topic)
+		}()
 	}
 	wg.Wait()
+	i.progress.summary()
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted; progress is saved in %s, re-run to resume", i.config.CheckpointFile)
+	}
 	log.Println("✅ Data ingestion complete.")
+	if i.config.Sync {
+		return i.syncIndex(ctx, topics, written)
+	}
+	return nil
+}
+
+// syncIndex deletes the vectors this run did not write: those of chunks that no longer exist
+// in a topic, and all vectors of topics whose directory is gone. Topics that failed to ingest
+// are left untouched, so a transient error never empties a topic. Vectors ingested through CMS
+// webhooks are not keyed by topic and are left to their connectors.
+func (i *Ingestor) syncIndex(ctx context.Context, topics []string, written map[string]map[string]bool) error {
+	if len(topics) == 0 {
+		log.Printf("⚠️  No topics found in %s; skipping sync rather than deleting every topic.", i.config.SourceDataDir)
+		return nil
+	}
+	ids, err := i.ragService.ListVectorIDs(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list vectors for sync: %w", err)
+	}
+	var stale []string
+	for _, id := range ids {
+		topic, ok := vectorTopic(id, topics)
+		if !ok {
+			continue
+		}
+		if kept, ingested := written[topic]; kept[id] || (!ingested && slices.Contains(topics, topic)) {
+			continue
+		}
+		stale = append(stale, id)
+	}
+	if len(stale) == 0 {
+		log.Println("🔄 Sync complete: the index matches the source documents.")
+		return nil
+	}
+	if err := i.ragService.DeleteVectors(ctx, stale); err != nil {
+		return fmt.Errorf("failed to delete stale vectors: %w", err)
+	}
+	log.Printf("🔄 Sync complete: deleted %d stale vectors.", len(stale))
+	return nil
+}
+
+// vectorTopic returns the topic an ingestor-written vector belongs to. A current topic is
+// matched by its full prefix, so topic names may themselves contain the separator.
+func vectorTopic(id string, topics []string) (string, bool) {
+	best := ""
+	for _, topic := range topics {
+		if strings.HasPrefix(id, llm.TopicVectorPrefix(topic)) && len(topic) > len(best) {
+			best = topic
+		}
+	}
+	if best != "" {
+		return best, true
+	}
+	topic, _, ok := strings.Cut(id, "#")
+	return topic, ok
+}
+
+// ingestIntentExamples embeds the intent training examples in the intents directory and
+// stores them for the gateway's intent analyzer, replacing the previous ones. The gateway
+// picks them up when it next starts.
+func (i *Ingestor) ingestIntentExamples(ctx context.Context) error {
+	dir := filepath.Join(i.config.SourceDataDir, intentsDir)
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		log.Printf("No %s directory; the gateway will classify intents by keyword.", dir)
+		return nil
+	}
+	examples, err := llm.ReadIntentExamples(dir, i.config.IntentNames)
+	if err != nil {
+		return fmt.Errorf("failed to read intent examples: %w", err)
+	}
+	if len(examples) == 0 {
+		log.Printf("No intent examples found in %s, skipping.", dir)
+		return nil
+	}
+	log.Printf("🧭 Embedding intent training examples from %s...", dir)
+	count, err := i.ragService.StoreIntentExamples(ctx, examples)
+	if err != nil {
+		return fmt.Errorf("failed to ingest intent examples: %w", err)
+	}
+	log.Printf("✅ Stored %d intent examples for %d intents.", count, len(examples))
 	return nil
 }
 
-// discoverTopics now explicitly ignores the 'intents' folder.
+// discoverTopics lists the knowledge topics, ignoring the intents directory.
 func (i *Ingestor) discoverTopics() ([]string, error) {
 	var topics []string
 	entries, err := os.ReadDir(i.config.SourceDataDir)
@@ -154,46 +351,81 @@ func (i *Ingestor) discoverTopics() ([]string, error) {
 		return nil, err
 	}
 	for _, entry := range entries {
-		if entry.IsDir() && entry.Name() != "intents" {
+		if entry.IsDir() && entry.Name() != intentsDir {
 			topics = append(topics, entry.Name())
 		}
 	}
 	return topics, nil
 }
 
-func (i *Ingestor) ingestTopicToPinecone(topic string) error {
+// ingestTopicToPinecone ingests every document of a topic and returns the IDs of its vectors.
+// Batches a previous, interrupted run already upserted are skipped.
+func (i *Ingestor) ingestTopicToPinecone(ctx context.Context, topic string) (map[string]bool, error) {
 	topicPath := filepath.Join(i.config.SourceDataDir, topic)
 	log.Printf("📚 Processing RAG topic for Pinecone: '%s'", topic)
-	allChunks, err := i.extractChunksFromPath(topicPath)
+	allChunks, err := i.extractChunksFromPath(topicPath, i.config.Chunking.ChunkerFor(topic))
 	if err != nil {
-		return fmt.Errorf("error extracting chunks for topic %s: %w", topic, err)
+		return nil, fmt.Errorf("error extracting chunks for topic %s: %w", topic, err)
 	}
+	written := make(map[string]bool, len(allChunks))
 	if len(allChunks) == 0 {
 		log.Printf("No chunks found for topic %s, skipping.", topic)
-		return nil
+		return written, i.checkpoint.finish(topic)
+	}
+
+	fingerprint := chunksFingerprint(allChunks, i.config.EmbeddingModel, embeddingBatchSize)
+	totalBatches := (len(allChunks) + embeddingBatchSize - 1) / embeddingBatchSize
+	resumeFrom := min(i.checkpoint.resumeFrom(topic, fingerprint), totalBatches)
+	skipped := min(resumeFrom*embeddingBatchSize, len(allChunks))
+	for _, chunk := range allChunks[:skipped] {
+		written[llm.TopicVectorID(topic, chunk.Text)] = true
+	}
+	i.progress.addTopic(len(allChunks), skipped)
+	if resumeFrom > 0 {
+		log.Printf("Resuming topic '%s' after batch %d of %d.", topic, resumeFrom, totalBatches)
 	}
+
 	log.Printf("Found %d total text chunks for topic '%s'. Processing in batches...", len(allChunks), topic)
-	const embeddingBatchSize = 500
-	for j := 0; j < len(allChunks); j += embeddingBatchSize {
+	for j := skipped; j < len(allChunks); j += embeddingBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		end := j + embeddingBatchSize
 		if end > len(allChunks) {
 			end = len(allChunks)
 		}
 		chunkBatch := allChunks[j:end]
+		texts := make([]string, len(chunkBatch))
+		for k, chunk := range chunkBatch {
+			texts[k] = chunk.Text
+		}
 		batchNum := (j / embeddingBatchSize) + 1
-		totalBatches := (len(allChunks) + embeddingBatchSize - 1) / embeddingBatchSize
 		log.Printf("  -> Processing batch %d of %d for topic '%s'", batchNum, totalBatches, topic)
 
 		// CORRECTED: Use the single, consistent RAGService for embeddings.
-		vectors, err := i.ragService.GenerateVectorsForChunks(context.Background(), chunkBatch, topic)
+		vectors, err := i.ragService.GenerateVectorsForChunks(ctx, texts, topic)
 		if err != nil {
-			return fmt.Errorf("failed to generate embeddings for batch %d of topic %s: %w", batchNum, topic, err)
+			return nil, fmt.Errorf("failed to generate embeddings for batch %d of topic %s: %w", batchNum, topic, err)
+		}
+		// Chunks from paged documents record where they came from, so answers can cite the page.
+		for k, chunk := range chunkBatch {
+			if chunk.Page > 0 {
+				vectors[k].Metadata["document"] = chunk.document
+				vectors[k].Metadata["page"] = chunk.Page
+			}
 		}
-		if err := i.upsertToPinecone(vectors); err != nil {
-			return fmt.Errorf("failed to upsert vectors for batch %d of topic %s: %w", batchNum, topic, err)
+		if err := i.upsertToPinecone(ctx, vectors); err != nil {
+			return nil, fmt.Errorf("failed to upsert vectors for batch %d of topic %s: %w", batchNum, topic, err)
 		}
+		for _, v := range vectors {
+			written[v.ID] = true
+		}
+		if err := i.checkpoint.record(topic, fingerprint, batchNum); err != nil {
+			return nil, err
+		}
+		i.progress.advance(len(chunkBatch))
 	}
-	return nil
+	return written, i.checkpoint.finish(topic)
 }
 
 // =================================================================================
@@ -201,94 +433,61 @@ func (i *Ingestor) ingestTopicToPinecone(topic string) error {
 // =================================================================================
 // (These functions are kept from the previous version as they are still needed)
 
+// documentChunk is a chunk together with the file it was extracted from.
+type documentChunk struct {
+	ingest.Chunk
+	document string
+}
+
 // extractChunksFromPath walks a directory and extracts all text chunks from valid files.
-func (i *Ingestor) extractChunksFromPath(rootPath string) ([]string, error) {
-	var chunks []string
+func (i *Ingestor) extractChunksFromPath(rootPath string, chunker *ingest.Chunker) ([]documentChunk, error) {
+	var chunks []documentChunk
 	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() {
-			fileChunks, err := extractChunksFromFile(path)
+			fileChunks, err := extractChunksFromFile(path, chunker)
 			if err != nil {
 				log.Printf("⚠️  Could not extract chunks from file %s: %v", path, err)
 				return nil
 			}
-			chunks = append(chunks, fileChunks...)
+			document, _ := filepath.Rel(rootPath, path)
+			for _, chunk := range fileChunks {
+				chunks = append(chunks, documentChunk{Chunk: chunk, document: document})
+			}
 		}
 		return nil
 	})
 	return chunks, err
 }
 
-// extractChunksFromFile uses a hybrid strategy for the most robust chunking.
-func extractChunksFromFile(path string) ([]string, error) {
+// extractChunksFromFile reads a file with the extractor registered for its extension and
+// chunks it. Extraction and chunking live in the ingest package so that the gateway's
+// webhook-driven ingestion chunks text exactly the same way.
+func extractChunksFromFile(path string, chunker *ingest.Chunker) ([]ingest.Chunk, error) {
+	extractor, ok := ingest.ExtractorFor(path)
+	if !ok {
+		log.Printf("Unsupported file type: %s. Skipping.", path)
+		return nil, nil
+	}
 	content, err := os.ReadFile(path)
 	if err != nil {
-		// Only process supported files, otherwise skip.
-		if os.IsNotExist(err) || !strings.HasSuffix(path, ".md") && !strings.HasSuffix(path, ".txt") {
-			log.Printf("Unsupported file type or not found: %s. Skipping.", path)
-			return nil, nil
-		}
 		return nil, err
 	}
-
-	finalChunks := []string{}
-
-	// --- Pass 1: Semantic Chunking ---
-	// First, split the document by major headings to respect semantic boundaries.
-	sections := strings.Split(string(content), "\n# ")
-
-	// --- Pass 2: Fixed-Size Chunking (if needed) ---
-	const targetTokensPerChunk = 500
-	const overlapTokens = 50
-
-	for i, section := range sections {
-		// Add the heading back to all sections after the first one.
-		if i > 0 {
-			section = "# " + section
-		}
-
-		// If the semantic section is already a good size, just add it.
-		if len(section)/4 <= targetTokensPerChunk {
-			if strings.TrimSpace(section) != "" {
-				finalChunks = append(finalChunks, strings.TrimSpace(section))
-			}
-			continue
-		}
-
-		// If the section is too long, apply fixed-size chunking to it.
-		var currentChunk strings.Builder
-		lines := strings.Split(section, "\n")
-
-		for _, line := range lines {
-			lineTokenCount := len(line) / 4
-			currentChunkTokenCount := len(currentChunk.String()) / 4
-
-			if currentChunkTokenCount+lineTokenCount > targetTokensPerChunk && currentChunk.Len() > 0 {
-				finalChunks = append(finalChunks, currentChunk.String())
-
-				lastChunk := currentChunk.String()
-				overlapStart := len(lastChunk) - (overlapTokens * 4)
-				if overlapStart < 0 {
-					overlapStart = 0
-				}
-
-				currentChunk.Reset()
-				currentChunk.WriteString(lastChunk[overlapStart:])
-			}
-			currentChunk.WriteString(line + "\n")
-		}
-		if currentChunk.Len() > 0 {
-			finalChunks = append(finalChunks, currentChunk.String())
-		}
+	pages, err := extractor.Extract(content)
+	if err != nil {
+		return nil, err
 	}
-
-	return finalChunks, nil
+	chunks := chunker.ChunkPages(pages)
+	if len(chunks) == 0 {
+		log.Printf("No extractable text in %s. Skipping.", path)
+	}
+	return chunks, nil
 }
 
 // upsertToPinecone sends batches of vectors to the Pinecone API.
-func (i *Ingestor) upsertToPinecone(vectors []llm.Vector) error {
+func (i *Ingestor) upsertToPinecone(ctx context.Context, vectors []llm.Vector) error {
 	type APIRequest struct {
 		Vectors []llm.Vector `json:"vectors"`
 	}
@@ -311,7 +510,7 @@ func (i *Ingestor) upsertToPinecone(vectors []llm.Vector) error {
 		}
 
 		upsertURL := i.config.PineconeHost + pineconeUpsertPath
-		req, err := http.NewRequest("POST", upsertURL, bytes.NewBuffer(payloadBytes))
+		req, err := http.NewRequestWithContext(ctx, "POST", upsertURL, bytes.NewBuffer(payloadBytes))
 		if err != nil {
 			return fmt.Errorf("failed to create Pinecone request for batch %d: %w", batchNumber, err)
 		}
@@ -325,13 +524,17 @@ func (i *Ingestor) upsertToPinecone(vectors []llm.Vector) error {
 	return nil
 }
 
-// doRequestWithRetry performs a robust HTTP request with retries.
+// doRequestWithRetry performs a robust HTTP request with retries. Rate-limited responses
+// wait as long as Pinecone's Retry-After header asks.
 func (i *Ingestor) doRequestWithRetry(req *http.Request) ([]byte, error) {
 	var body []byte
 	var err error
 	delay := initialRetryDelay
 
 	for k := 0; k < maxRetries; k++ {
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		// Clone the request so we can reuse it in case of a retry.
 		reqClone := req.Clone(req.Context())
 		if req.Body != nil {
@@ -361,8 +564,9 @@ func (i *Ingestor) doRequestWithRetry(req *http.Request) ([]byte, error) {
 
 		// Handle non-successful status codes.
 		err = fmt.Errorf("API returned non-2xx status: %d %s - %s", resp.StatusCode, resp.Status, string(body))
-		log.Printf("Request failed (attempt %d/%d): %v. Retrying in %v...", k+1, maxRetries, err, delay)
-		time.Sleep(delay)
+		wait := llm.RetryAfter(resp.Header, delay)
+		log.Printf("Request failed (attempt %d/%d): %v. Retrying in %v...", k+1, maxRetries, err, wait)
+		time.Sleep(wait)
 		delay *= 2
 	}
 	return nil, fmt.Errorf("request failed after %d attempts: %w", maxRetries, err)
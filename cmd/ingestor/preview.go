@@ -0,0 +1,112 @@
+// In file: cmd/ingestor/preview.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/ingest"
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+)
+
+const (
+	// previewSamples is the number of chunks shown per topic in a dry run.
+	previewSamples = 3
+	// previewSampleChars is how much of each sample chunk is shown.
+	previewSampleChars = 240
+)
+
+// preview is the dry run: it chunks every topic exactly as ingestion would and reports chunk
+// counts, the token distribution, the estimated embedding cost, and sample chunks, without
+// calling the embedding API or the vector store.
+func preview(w io.Writer, cfg *Config, ragConfig *llm.Config) error {
+	i := &Ingestor{config: cfg}
+	topics, err := i.discoverTopics()
+	if err != nil {
+		return fmt.Errorf("failed to discover document topics: %w", err)
+	}
+	fmt.Fprintf(w, "Dry run of %s: nothing is embedded or written.\n", cfg.SourceDataDir)
+
+	totalChunks, totalTokens := 0, 0
+	for _, topic := range topics {
+		opts := cfg.Chunking.For(topic)
+		chunks, err := i.extractChunksFromPath(filepath.Join(cfg.SourceDataDir, topic), cfg.Chunking.ChunkerFor(topic))
+		if err != nil {
+			return fmt.Errorf("error extracting chunks for topic %s: %w", topic, err)
+		}
+		documents := make(map[string]bool)
+		counts := make([]int, len(chunks))
+		tokens := 0
+		for k, chunk := range chunks {
+			documents[chunk.document] = true
+			counts[k] = ingest.CountTokens(chunk.Text)
+			tokens += counts[k]
+		}
+		totalChunks += len(chunks)
+		totalTokens += tokens
+
+		fmt.Fprintf(w, "\nTopic '%s' (%s, chunk size %d, overlap %d)\n", topic, opts.Strategy, opts.Size, *opts.Overlap)
+		fmt.Fprintf(w, "  %d documents, %d chunks, %d tokens, %s\n", len(documents), len(chunks), tokens, formatEmbeddingCost(tokens, ragConfig))
+		if len(chunks) == 0 {
+			continue
+		}
+		sorted := slices.Clone(counts)
+		slices.Sort(sorted)
+		fmt.Fprintf(w, "  tokens per chunk: min %d, median %d, p90 %d, max %d\n",
+			sorted[0], percentile(sorted, 50), percentile(sorted, 90), sorted[len(sorted)-1])
+		fmt.Fprintln(w, "  sample chunks:")
+		for _, k := range sampleIndexes(len(chunks), previewSamples) {
+			chunk := chunks[k]
+			where := chunk.document
+			if chunk.Page > 0 {
+				where += fmt.Sprintf(" p.%d", chunk.Page)
+			}
+			fmt.Fprintf(w, "    [%d] %s, %d tokens: %q\n", k+1, where, counts[k], truncate(chunk.Text, previewSampleChars))
+		}
+	}
+	fmt.Fprintf(w, "\nTotal: %d topics, %d chunks, %d tokens, %s\n", len(topics), totalChunks, totalTokens, formatEmbeddingCost(totalTokens, ragConfig))
+	return nil
+}
+
+// formatEmbeddingCost describes the cost of embedding a number of tokens. Chunks already in
+// the embedding cache are not re-embedded, so this is an upper bound.
+func formatEmbeddingCost(tokens int, ragConfig *llm.Config) string {
+	if ragConfig.EmbeddingCost == 0 {
+		return fmt.Sprintf("embedding cost unknown (set rag.embedding_cost for %s)", ragConfig.EmbeddingModel)
+	}
+	return fmt.Sprintf("at most $%.6f to embed with %s", float64(tokens)*ragConfig.EmbeddingCost/1e6, ragConfig.EmbeddingModel)
+}
+
+// percentile returns the p-th percentile of sorted values (nearest rank).
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// sampleIndexes picks up to n indexes spread evenly over a list of the given length.
+func sampleIndexes(length, n int) []int {
+	if length <= n {
+		indexes := make([]int, length)
+		for k := range indexes {
+			indexes[k] = k
+		}
+		return indexes
+	}
+	indexes := make([]int, n)
+	for k := range indexes {
+		indexes[k] = k * (length - 1) / (n - 1)
+	}
+	return indexes
+}
+
+// truncate shortens text to at most limit bytes on a rune boundary.
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := strings.ToValidUTF8(text[:limit], "")
+	return cut + "…"
+}
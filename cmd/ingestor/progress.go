@@ -0,0 +1,82 @@
+// In file: cmd/ingestor/progress.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of cells in the progress bar.
+const progressBarWidth = 30
+
+// progress tracks chunks across all topics, which are ingested concurrently, and reports the
+// overall completion, rate, and estimated time remaining.
+type progress struct {
+	mu      sync.Mutex
+	start   time.Time
+	total   int // Chunks discovered so far.
+	done    int // Chunks upserted by this run.
+	resumed int // Chunks skipped because a previous run upserted them.
+	failed  []string
+}
+
+func newProgress() *progress {
+	return &progress{start: time.Now()}
+}
+
+// addTopic registers a topic's chunks, of which resumed were upserted by a previous run.
+func (p *progress) addTopic(chunks, resumed int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += chunks
+	p.resumed += resumed
+}
+
+// advance records upserted chunks and logs the progress.
+func (p *progress) advance(chunks int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += chunks
+	log.Printf("Progress %s", p.line())
+}
+
+// fail records a topic that did not complete.
+func (p *progress) fail(topic string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failed = append(p.failed, topic)
+}
+
+// line renders the bar, counts, rate, and ETA. The caller must hold p.mu.
+func (p *progress) line() string {
+	finished := p.done + p.resumed
+	fraction := 1.0
+	if p.total > 0 {
+		fraction = float64(finished) / float64(p.total)
+	}
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat(".", progressBarWidth-filled)
+
+	elapsed := time.Since(p.start)
+	rate := float64(p.done) / elapsed.Seconds()
+	eta := "unknown"
+	if rate > 0 {
+		eta = (time.Duration(float64(p.total-finished)/rate) * time.Second).Round(time.Second).String()
+	}
+	return fmt.Sprintf("[%s] %3.0f%% %d/%d chunks, %.1f chunks/s, ETA %s", bar, fraction*100, finished, p.total, rate, eta)
+}
+
+// summary logs the outcome of the run.
+func (p *progress) summary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	elapsed := time.Since(p.start).Round(time.Second)
+	log.Printf("Summary: upserted %d chunks in %s (%.1f chunks/s), resumed past %d chunks upserted earlier, %d of %d chunks done.",
+		p.done, elapsed, float64(p.done)/max(elapsed.Seconds(), 1), p.resumed, p.done+p.resumed, p.total)
+	if len(p.failed) > 0 {
+		log.Printf("Summary: %d topics did not complete: %s. Re-run to resume them.", len(p.failed), strings.Join(p.failed, ", "))
+	}
+}
@@ -5,12 +5,17 @@
 // serving as a stable, versioned interface for all client interactions.
 package api
 
+import "encoding/json"
 
 // Message defines the structure for a single message in a conversation history.
 // This is part of the public API and is used in the GenerationRequest.
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls are the calls of client tools an assistant message made, and ToolCallID
+	// identifies the call a "tool" message carries the result of.
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
 }
 
 // GenerationRequest defines the structure for an incoming request to the /generate endpoint.
@@ -19,6 +24,8 @@ type GenerationRequest struct {
 	// Prompt is the user's query or instruction.
 	Prompt string `json:"prompt" binding:"required"`
 	// UserID is an identifier for the end-user, crucial for logging, auditing, and rate-limiting.
+	// The gateway takes it from the X-User-ID header or the caller's token; if it is set here
+	// too, it must name the same user.
 	UserID string `json:"user_id"`
 	// --- ADD THIS LINE ---
 	// ConversationID links multiple requests together into a single chat session,
@@ -27,8 +34,99 @@ type GenerationRequest struct {
 	// --- THIS FIELD IS NEW ---
 	// History contains the list of previous messages in the conversation for context.
 	History        []Message      `json:"history,omitempty"`
+	// SystemPrompt sets persona instructions for the conversation. When sent with a
+	// ConversationID it is stored and automatically applied to every later turn.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// System is another name for SystemPrompt, as most provider APIs call it. A request may
+	// set either, or both to the same text.
+	System string `json:"system,omitempty"`
 	// Config holds all the parameters that control how the gateway processes and routes the request.
 	Config GenerationConfig `json:"config"`
+	// ToolsAllowed limits which tools the agent may use for this request. It can only
+	// narrow the caller's tenant policy, never widen it. Omit it to allow every permitted tool.
+	ToolsAllowed []string `json:"tools_allowed,omitempty"`
+	// Debug asks the gateway to include a DecisionTrace in the response explaining
+	// how the intent, RAG context, and cache lookup were decided.
+	Debug bool `json:"debug,omitempty"`
+	// CallbackURL makes the request asynchronous: it is answered 202 Accepted with an
+	// AsyncJob, and its outcome is POSTed to the URL as an AsyncJobResult once it is ready.
+	// It cannot be combined with streaming.
+	CallbackURL string `json:"callback_url,omitempty"`
+	// ResponseSchema is a JSON Schema the answer must conform to. The model is asked to answer
+	// with JSON only and is re-prompted, in JSON mode, until its answer validates or
+	// Config.SchemaRetries is exhausted. It cannot be combined with streaming.
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+	// ResponseFormat constrains the answer to JSON with the native mechanism of whichever
+	// provider the request is routed to. The answer is then validated and re-prompted like
+	// one with a ResponseSchema. It cannot be combined with streaming.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// Tools are the caller's own functions the model may call. They replace the gateway's
+	// tools, and their calls are not executed but returned as PendingToolCalls; the caller
+	// sends the results back in History as "tool" messages. They cannot be combined with
+	// streaming.
+	Tools []ClientTool `json:"tools,omitempty"`
+	// Ensemble has several models, or several samples of one, answer the prompt, and returns
+	// the best answer. It costs as much as all the answers, and cannot be combined with
+	// streaming or client tools. Requests answered with the gateway's tools ignore it.
+	Ensemble *EnsembleOptions `json:"ensemble,omitempty"`
+	// RAG tunes the knowledge base retrieval for this request. Omit it to use the defaults.
+	RAG *RAGOptions `json:"rag,omitempty"`
+}
+
+// RAGOptions tune the knowledge base retrieval of a request.
+type RAGOptions struct {
+	// TopK is how many chunks are retrieved. It defaults to 2.
+	TopK int `json:"top_k,omitempty"`
+	// MinScore is the relevance score the best match must reach for its context to be used.
+	// It defaults to the configured relevance_threshold.
+	MinScore *float64 `json:"min_score,omitempty"`
+	// Disable skips retrieval, e.g. for creative prompts the knowledge base has nothing to add to.
+	Disable bool `json:"disable,omitempty"`
+}
+
+// EnsembleOptions configure an ensemble generation.
+type EnsembleOptions struct {
+	// Models answer the prompt besides the model the request is routed to.
+	Models []string `json:"models,omitempty"`
+	// Samples is how many answers each model gives. It defaults to 1, or to 3 when no other
+	// models are listed. Samples differ only if the sampling is random, so a seed makes them
+	// the same.
+	Samples int `json:"samples,omitempty"`
+	// Method selects the answer: "judge" (the default) has the judge model pick the best one,
+	// "merge" has it combine them into one, and "consensus" picks the answer most like the
+	// others without another model call.
+	Method string `json:"method,omitempty"`
+	// JudgeModel is the judge. It defaults to the model the request is routed to.
+	JudgeModel string `json:"judge_model,omitempty"`
+	// ReturnCandidates includes every answer in the response, not just the selected one.
+	ReturnCandidates bool `json:"return_candidates,omitempty"`
+}
+
+// ClientTool is a function of the caller's that the model may call.
+type ClientTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON Schema of the function's arguments. Its root must be an object.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a model's call of a client tool.
+type ToolCall struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Arguments is the JSON object of the call's arguments.
+	Arguments string `json:"arguments"`
+}
+
+// ResponseFormat selects the format of the answer.
+type ResponseFormat struct {
+	// Type is "text" (the default), "json_object" for any JSON object, or "json_schema" for
+	// a JSON object that conforms to Schema.
+	Type string `json:"type"`
+	// Name labels the schema for providers that ask for one. It defaults to "response".
+	Name string `json:"name,omitempty"`
+	// Schema is the JSON Schema of a "json_schema" answer. Its root must be an object.
+	Schema json.RawMessage `json:"schema,omitempty"`
 }
 
 // GenerationConfig holds all user-configurable parameters for a single LLM request.
@@ -50,6 +148,57 @@ type GenerationConfig struct {
 	TopP *float32 `json:"top_p,omitempty"`
 	// Stream determines whether to send back a single response or a stream of events.
 	Stream bool `json:"stream,omitempty"`
+	// SchemaRetries is how many times the model is re-prompted when its answer does not match
+	// ResponseSchema. It defaults to 2 and is capped at 5.
+	SchemaRetries *int `json:"schema_retries,omitempty"`
+	// Logprobs asks for the log probability of every token of the answer. Only providers
+	// that report them (OpenAI) return any. It cannot be combined with streaming.
+	Logprobs bool `json:"logprobs,omitempty"`
+	// TopLogprobs is how many of the most likely tokens to report at each position, up to 20.
+	// It requires Logprobs.
+	TopLogprobs int `json:"top_logprobs,omitempty"`
+	// Seed asks the provider to sample deterministically, so repeated requests with the same
+	// seed and parameters return the same answer where the provider supports it (OpenAI and
+	// Mistral).
+	Seed *int64 `json:"seed,omitempty"`
+	// Stop lists up to 4 sequences at which the model stops generating. The answer does not
+	// include the sequence that stopped it.
+	Stop []string `json:"stop,omitempty"`
+	// FrequencyPenalty and PresencePenalty, between -2 and 2, discourage repeating tokens in
+	// proportion to how often they already appeared, or at all. OpenAI and Mistral apply
+	// them; other providers ignore them.
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	// TopK samples only from the K most likely tokens. Anthropic and Gemini apply it; other
+	// providers ignore it.
+	TopK *int `json:"top_k,omitempty"`
+	// ReasoningEffort is "low", "medium", or "high" and sets how long a reasoning model
+	// thinks before answering. OpenAI's o-series take it as is; Anthropic turns it into an
+	// extended thinking budget. Other models ignore it.
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	// ThinkingBudget is the most tokens Anthropic's extended thinking may spend, at least
+	// 1024. It takes precedence over ReasoningEffort and is billed as completion tokens.
+	ThinkingBudget int `json:"thinking_budget,omitempty"`
+	// ExcludeModels are models the router must not select, e.g. the one whose answer is being
+	// regenerated. A conversation pinned to an excluded model is routed again.
+	ExcludeModels []string `json:"exclude_models,omitempty"`
+	// MaxCostUSD caps the estimated cost of the call. The router passes over models estimated
+	// to cost more, and the request is rejected if every model would.
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty"`
+}
+
+// TokenLogprob is the log probability of one token of the answer.
+type TokenLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	// TopLogprobs are the most likely tokens at this position, the chosen one among them.
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// TopLogprob is one of the most likely tokens at a position of the answer.
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
 }
 
 // FailoverInfo provides details about an automatic model failover event.
@@ -72,20 +221,302 @@ type GenerationResponse struct {
 	LatencyMS int64 `json:"latency_ms"`
 	// RAGContextUsed indicates whether context from the RAG system was used to augment the prompt.
 	RAGContextUsed bool `json:"rag_context_used"`
-	// ToolCalls provides a log of any tools that were executed by the agent during the request.
+	// RAG describes what the knowledge base returned, when it was searched.
+	RAG *RAGDecision `json:"rag,omitempty"`
+	// ToolCalls provides a log of any tools that were executed by the agent during the request,
+	// in the order they were called.
 	ToolCalls []ExecutedToolCall `json:"tool_calls,omitempty"`
-	// CacheStatus indicates whether the response was served from the cache ("HIT") or generated live ("MISS").
+	// PendingToolCalls are the calls of the request's client tools the model made instead of
+	// answering. The caller executes them and sends their results in a follow-up request.
+	PendingToolCalls []ToolCall `json:"pending_tool_calls,omitempty"`
+	// Logprobs holds the log probability of every token of the answer, when the request
+	// asked for them and the model's provider reports them.
+	Logprobs []TokenLogprob `json:"logprobs,omitempty"`
+	// SystemFingerprint identifies the provider's backend configuration that generated the
+	// answer, where the provider reports it (OpenAI). Answers to the same seed are only
+	// reproducible while the fingerprint stays the same.
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	// FinishReason is why the model stopped generating: "stop", "length" (the max_tokens
+	// limit was reached), "tool_calls" (see PendingToolCalls), or "content_filter". It is
+	// empty when the provider did not say.
+	FinishReason string `json:"finish_reason,omitempty"`
+	// CacheStatus indicates whether the response was served from the cache ("HIT"), generated live ("MISS"),
+	// or shared from an identical request that was being generated at the same time ("COALESCED").
+	// Requests relayed in pass-through mode are audited as "PASSTHROUGH".
 	CacheStatus string `json:"cache_status"`
 	// --- ADD THIS LINE ---
 	// FailoverInfo will be populated if a session failover occurred during the request.
 	FailoverInfo   *FailoverInfo `json:"failover_info,omitempty"`
+	// Truncated is true when the oldest history messages were dropped to fit the model's context window.
+	Truncated bool `json:"truncated,omitempty"`
+	// Debug is only populated when the request sets "debug": true.
+	Debug *DecisionTrace `json:"debug,omitempty"`
+	// CostUSD is what the request cost. Cache hits are free.
+	CostUSD float64 `json:"cost_usd"`
+	// AccountUsage is the caller's running usage for the current month, including this request.
+	AccountUsage *AccountUsage `json:"account_usage,omitempty"`
+	// Warnings tells the client about things it should change, such as requesting a model
+	// under a deprecated name.
+	Warnings []string `json:"warnings,omitempty"`
+	// Routing explains why the request was answered by a tool, by RAG, or by its model. A
+	// cached answer carries the routing of the request that generated it.
+	Routing *RoutingInfo `json:"routing,omitempty"`
+	// Ensemble explains how the answer of an ensemble generation was selected.
+	Ensemble *EnsembleResult `json:"ensemble,omitempty"`
+}
+
+// AsyncJob acknowledges a request with a CallbackURL.
+type AsyncJob struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+}
+
+// AsyncJobResult is POSTed to the CallbackURL of an asynchronous request. Status is
+// "completed" with the Response, or "failed" with the Error and the StatusCode the request
+// would have been answered with.
+type AsyncJobResult struct {
+	JobID      string              `json:"job_id"`
+	Status     string              `json:"status"`
+	StatusCode int                 `json:"status_code"`
+	Response   *GenerationResponse `json:"response,omitempty"`
+	// Error is the error body, e.g. {"error": "...", "code": "..."}.
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// EnsembleResult describes the answers of an ensemble generation and how one was selected.
+type EnsembleResult struct {
+	Method string `json:"method"`
+	// Chosen is the index of the candidate whose answer was returned, or -1 when the answers
+	// were merged.
+	Chosen     int    `json:"chosen"`
+	JudgeModel string `json:"judge_model,omitempty"`
+	// JudgeError is why the judge failed, in which case the consensus selected the answer.
+	JudgeError string              `json:"judge_error,omitempty"`
+	Candidates []EnsembleCandidate `json:"candidates"`
+}
+
+// EnsembleCandidate is one answer of an ensemble generation.
+type EnsembleCandidate struct {
+	Model string `json:"model"`
+	// Content is only included when the request asked for the candidates.
+	Content   string `json:"content,omitempty"`
+	Usage     Usage  `json:"usage"`
+	LatencyMS int64  `json:"latency_ms"`
+	// Error is why the model gave no answer.
+	Error string `json:"error,omitempty"`
+}
+
+// RoutingInfo summarises how a request was routed, for client teams debugging why a query
+// hit a tool versus RAG versus a particular model tier. The debug block has the details.
+type RoutingInfo struct {
+	// Intent is the detected intent, e.g. "weather" or "rag_knowledge_query".
+	Intent string `json:"intent"`
+	// Preference is the routing preference the model was selected by. It is empty when the
+	// model was forced by the request or pinned by its session.
+	Preference string `json:"preference,omitempty"`
+	// PreferenceSource is "request" when the client set the preference, "intent" when the
+	// intent's configured route set the model or the preference, or "analyzer" when the
+	// preference was selected from the prompt's complexity.
+	PreferenceSource string `json:"preference_source,omitempty"`
+	// ComplexityScore is the prompt's complexity score, when the analyzer selected the
+	// preference.
+	ComplexityScore *int `json:"complexity_score,omitempty"`
+}
+
+// AccountUsage is the usage accumulated by one user during a calendar month.
+// It is the basis for chargeback and quota enforcement.
+type AccountUsage struct {
+	// Account is "user:<id>" for identified users, or "anonymous".
+	Account          string  `json:"account"`
+	Month            string  `json:"month"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// DecisionTrace records every decision the gateway made while processing a request.
+// The same trace is written to the audit log, so "why did it call the weather tool?"
+// can be answered without digging through server logs.
+type DecisionTrace struct {
+	Intent  *IntentDecision  `json:"intent,omitempty"`
+	RAG     *RAGDecision     `json:"rag,omitempty"`
+	Context *ContextDecision `json:"context,omitempty"`
+	Cache   CacheDecision    `json:"cache"`
+	PII     *PIIDecision     `json:"pii,omitempty"`
+	// Moderation holds one check per moderated stage ("input", then "output").
+	Moderation []ModerationCheck `json:"moderation,omitempty"`
+	// StructuredOutput is set when the request declared a ResponseSchema or a JSON ResponseFormat.
+	StructuredOutput *StructuredOutputDecision `json:"structured_output,omitempty"`
+	// Alias is set when the requested model name was an alias.
+	Alias *AliasDecision `json:"alias,omitempty"`
+	// Preference is set when the routing preference was selected by analyzing the prompt.
+	Preference *PreferenceDecision `json:"preference,omitempty"`
+	// IntentRoute is set when the request's intent has a configured route.
+	IntentRoute *IntentRouteDecision `json:"intent_route,omitempty"`
+	// ToolCalls are the tool calls the agent executed, in order. They are reported in the
+	// response's ToolCalls rather than in the debug block.
+	ToolCalls []ExecutedToolCall `json:"-"`
+	// Budgets are the budget states of the models the router chose among, when it ran.
+	Budgets []BudgetState `json:"budgets,omitempty"`
+}
+
+// BudgetState describes how far a model is into its monthly budget. State is "ok",
+// "throttled" (past the soft cap, so its routing score is reduced by Penalty), or
+// "exhausted" (at the hard cap, so it is not routed to).
+type BudgetState struct {
+	Model     string  `json:"model"`
+	SpentUSD  float64 `json:"spent_usd"`
+	BudgetUSD float64 `json:"budget_usd,omitempty"`
+	State     string  `json:"state"`
+	Penalty   float64 `json:"penalty,omitempty"`
+}
+
+// IntentRouteDecision records the configured route of a request's intent.
+type IntentRouteDecision struct {
+	Intent   string `json:"intent"`
+	Model    string `json:"model,omitempty"`
+	Strategy string `json:"strategy,omitempty"`
+	// Skipped explains why the route's model was not used, e.g. because it was offline.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// PreferenceDecision records how the routing preference of a request without one was
+// selected, so scoring variants can be compared from the audit log.
+type PreferenceDecision struct {
+	Preference string `json:"preference"`
+	// Score is the prompt's complexity score.
+	Score    int    `json:"score"`
+	Language string `json:"language,omitempty"`
+	// Variant is the scoring variant being A/B tested that scored the prompt, or "control".
+	Variant string `json:"variant,omitempty"`
+	// Backend is what selected the preference: "rules", "llm", or "http".
+	Backend string `json:"backend"`
+}
+
+// AliasDecision records how a requested model name was resolved through a configured alias.
+type AliasDecision struct {
+	Requested string `json:"requested"`
+	Model     string `json:"model"`
+	// Deprecated is true when the requested name is retired and clients should switch to Model.
+	Deprecated bool   `json:"deprecated"`
+	Message    string `json:"message,omitempty"`
+}
+
+// StructuredOutputDecision records how an answer was made to conform to the response schema.
+type StructuredOutputDecision struct {
+	// Attempts counts the answers generated, including the first one.
+	Attempts int  `json:"attempts"`
+	Valid    bool `json:"valid"`
+	// Problems lists the validation errors of the last answer that failed validation.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// ModerationCheck is the outcome of moderating a prompt or an answer.
+type ModerationCheck struct {
+	// Stage is "input" or "output".
+	Stage   string `json:"stage"`
+	Blocked bool   `json:"blocked"`
+	// Categories lists the categories whose score reached their threshold, alphabetically.
+	Categories []string           `json:"categories,omitempty"`
+	Scores     map[string]float64 `json:"scores,omitempty"`
+}
+
+// ModerationRefusal is the response body returned instead of an answer when moderation
+// blocks a request.
+type ModerationRefusal struct {
+	Error string `json:"error"`
+	// Code is always "content_blocked", so clients can tell refusals from other errors.
+	Code       string   `json:"code"`
+	Stage      string   `json:"stage"`
+	Categories []string `json:"categories"`
+}
+
+// PIIDecision records the personal data detected in what was sent to providers.
+// Only entity counts are kept, never the values themselves.
+type PIIDecision struct {
+	// Mode is "detect" or "mask".
+	Mode string `json:"mode"`
+	// Entities counts the detected values by entity type, e.g. {"EMAIL": 2}.
+	Entities map[string]int `json:"entities"`
+}
+
+// IntentDecision describes which rule produced the detected intent.
+type IntentDecision struct {
+	Intent string `json:"intent"`
+	// Matcher is the kind of rule that fired: "embedding", "keyword", "regex", "llm", or
+	// "default".
+	Matcher string `json:"matcher"`
+	// Pattern is the nearest training example, the keyword or regular expression that
+	// matched, or the model that classified the prompt, if any.
+	Pattern string `json:"pattern,omitempty"`
+	// Confidence is how strongly the nearest training examples agree on the intent, from 0
+	// to 1, when they decided it.
+	Confidence float64 `json:"confidence,omitempty"`
+	// Language is the detected language of the prompt (ISO 639-1), if it could be told.
+	Language string `json:"language,omitempty"`
+}
+
+// RAGDecision describes the knowledge-base retrieval and whether its context was used.
+type RAGDecision struct {
+	TopK      int     `json:"top_k"`
+	Score     float64 `json:"score"`
+	Threshold float64 `json:"threshold"`
+	// Used is true when Score met Threshold and the prompt was augmented with context.
+	Used bool `json:"used"`
+	// Topic is the knowledge base topic of the best match, and Chunks the number of chunks
+	// that matched.
+	Topic  string `json:"topic,omitempty"`
+	Chunks int    `json:"chunks"`
+}
+
+// ContextDecision describes how the conversation was fitted into the model's context window.
+type ContextDecision struct {
+	ContextWindow   int `json:"context_window"`
+	EstimatedTokens int `json:"estimated_tokens"`
+	// DroppedMessages is the number of oldest history messages removed to fit the window.
+	DroppedMessages int  `json:"dropped_messages"`
+	Truncated       bool `json:"truncated"`
+}
+
+// CacheDecision describes the response cache lookup for the request.
+type CacheDecision struct {
+	Consulted bool   `json:"consulted"`
+	Status    string `json:"status"`
+}
+
+// TokenCountRequest is the body of a token count request.
+type TokenCountRequest struct {
+	Model    string    `json:"model" binding:"required"`
+	Messages []Message `json:"messages" binding:"required"`
+	// MaxTokens is the room the request would leave for the answer. It defaults to none.
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// TokenCountResponse reports the input tokens of a message list and whether it fits the
+// model's context window.
+type TokenCountResponse struct {
+	Model       string `json:"model"`
+	InputTokens int    `json:"input_tokens"`
+	// Method is "provider" when the model's provider counted the tokens exactly, and
+	// "estimate" when the gateway estimated them with the provider's tokenizer family.
+	Method        string `json:"method"`
+	ContextWindow int    `json:"context_window"`
+	// Fits reports whether the input tokens plus MaxTokens fit the context window.
+	Fits bool `json:"fits"`
 }
 
 // ExecutedToolCall provides a transparent record of a tool that was executed by the agent.
 type ExecutedToolCall struct {
+	ID     string `json:"id,omitempty"`
 	Name   string `json:"name"`
 	Args   string `json:"args"`
 	Result string `json:"result"`
+	// Truncated is true when Result was cut short; the model was given the whole result.
+	Truncated  bool  `json:"truncated,omitempty"`
+	DurationMS int64 `json:"duration_ms"`
+	// Error is why the call failed or was refused. The model was told, and could retry.
+	Error string `json:"error,omitempty"`
 }
 
 // Usage mirrors the token usage structure from providers like OpenAI and Anthropic.
@@ -93,6 +524,13 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	// ReasoningTokens are the completion tokens a reasoning model spent thinking rather than
+	// answering. They are included in CompletionTokens.
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	// CachedTokens are the prompt tokens the provider read from its prompt cache, and
+	// CacheWriteTokens those it wrote to it. Both are included in PromptTokens.
+	CachedTokens     int `json:"cached_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
 }
 
 // Add accumulates the token usage from another Usage struct into this one.
@@ -101,4 +539,7 @@ func (u *Usage) Add(other Usage) {
 	u.PromptTokens += other.PromptTokens
 	u.CompletionTokens += other.CompletionTokens
 	u.TotalTokens += other.TotalTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.CachedTokens += other.CachedTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
 }
\ No newline at end of file
@@ -0,0 +1,158 @@
+// In file: internal/audit/clickhouse.go
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+// ClickHouseSink stores audit records in a ClickHouse table through ClickHouse's HTTP
+// interface, so no native driver is needed. The table is created if it does not exist.
+type ClickHouseSink struct {
+	baseURL    string
+	user       string
+	password   string
+	table      string
+	httpClient *http.Client
+}
+
+// Statically verify that ClickHouseSink implements the Sink interface.
+var _ Sink = (*ClickHouseSink)(nil)
+
+// clickHouseRow is the JSONEachRow shape of a Record. Nested structures are stored as JSON strings.
+type clickHouseRow struct {
+	Timestamp        string  `json:"ts"`
+	RequestID        string  `json:"request_id"`
+	UserID           string  `json:"user_id"`
+	ConversationID   string  `json:"conversation_id"`
+	PromptHash       string  `json:"prompt_hash"`
+	Prompt           string  `json:"prompt"`
+	Response         string  `json:"response"`
+	ModelUsed        string  `json:"model_used"`
+	CacheStatus      string  `json:"cache_status"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	LatencyMS        int64   `json:"latency_ms"`
+	FailoverInfo     string  `json:"failover_info"`
+	Decisions        string  `json:"decisions"`
+}
+
+// NewClickHouseSink connects to ClickHouse at baseURL (e.g. http://clickhouse:8123) and
+// ensures the audit table exists.
+func NewClickHouseSink(ctx context.Context, baseURL, user, password, table string) (*ClickHouseSink, error) {
+	if !tableNameRegex.MatchString(table) {
+		return nil, fmt.Errorf("invalid audit table name '%s'", table)
+	}
+	s := &ClickHouseSink{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		user:       user,
+		password:   password,
+		table:      table,
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: telemetry.Transport()},
+	}
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		ts                DateTime64(3, 'UTC'),
+		request_id        String,
+		user_id           String,
+		conversation_id   String,
+		prompt_hash       String,
+		prompt            String,
+		response          String,
+		model_used        LowCardinality(String),
+		cache_status      LowCardinality(String),
+		prompt_tokens     UInt32,
+		completion_tokens UInt32,
+		cost_usd          Float64,
+		latency_ms        UInt64,
+		failover_info     String,
+		decisions         String
+	) ENGINE = MergeTree ORDER BY (ts, model_used)`, table)
+	if err := s.exec(ctx, ddl, nil); err != nil {
+		return nil, fmt.Errorf("failed to create audit table %s: %w", table, err)
+	}
+	return s, nil
+}
+
+// Write inserts a batch of records with a single INSERT ... FORMAT JSONEachRow request.
+func (s *ClickHouseSink) Write(ctx context.Context, records []Record) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, r := range records {
+		row := clickHouseRow{
+			Timestamp:        r.Timestamp.UTC().Format("2006-01-02 15:04:05.000"),
+			RequestID:        r.RequestID,
+			UserID:           r.UserID,
+			ConversationID:   r.ConversationID,
+			PromptHash:       r.PromptHash,
+			Prompt:           r.Prompt,
+			Response:         r.Response,
+			ModelUsed:        r.ModelUsed,
+			CacheStatus:      r.CacheStatus,
+			PromptTokens:     r.PromptTokens,
+			CompletionTokens: r.CompletionTokens,
+			CostUSD:          r.CostUSD,
+			LatencyMS:        r.LatencyMS,
+		}
+		if failoverInfo, err := nullableJSON(r.FailoverInfo); err != nil {
+			return err
+		} else if failoverInfo != nil {
+			row.FailoverInfo = *failoverInfo
+		}
+		if decisions, err := nullableJSON(r.Decisions); err != nil {
+			return err
+		} else if decisions != nil {
+			row.Decisions = *decisions
+		}
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("failed to encode audit record: %w", err)
+		}
+	}
+	if err := s.exec(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", s.table), &body); err != nil {
+		return fmt.Errorf("failed to insert audit records: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op; the HTTP client holds no resources that need releasing.
+func (s *ClickHouseSink) Close() error {
+	return nil
+}
+
+// exec runs a query. For INSERTs, the query goes in the URL and the rows in the body.
+func (s *ClickHouseSink) exec(ctx context.Context, query string, data io.Reader) error {
+	target := s.baseURL + "/"
+	body := data
+	if data == nil {
+		body = strings.NewReader(query)
+	} else {
+		target += "?query=" + url.QueryEscape(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", target, body)
+	if err != nil {
+		return err
+	}
+	if s.user != "" {
+		req.Header.Set("X-ClickHouse-User", s.user)
+		req.Header.Set("X-ClickHouse-Key", s.password)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("clickhouse returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
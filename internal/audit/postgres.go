@@ -0,0 +1,104 @@
+// In file: internal/audit/postgres.go
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tableNameRegex restricts configurable table names to plain (optionally schema-qualified)
+// identifiers, since they are interpolated into SQL.
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PostgresSink stores audit records in a Postgres table, which is created if it does not exist.
+// The failover info and decision trace are stored as JSONB.
+type PostgresSink struct {
+	pool  *pgxpool.Pool
+	table string
+}
+
+// Statically verify that PostgresSink implements the Sink interface.
+var _ Sink = (*PostgresSink)(nil)
+
+// NewPostgresSink connects to Postgres and ensures the audit table exists.
+func NewPostgresSink(ctx context.Context, dsn, table string) (*PostgresSink, error) {
+	if !tableNameRegex.MatchString(table) {
+		return nil, fmt.Errorf("invalid audit table name '%s'", table)
+	}
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	_, err = pool.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id                BIGSERIAL PRIMARY KEY,
+		ts                TIMESTAMPTZ NOT NULL,
+		request_id        TEXT,
+		user_id           TEXT,
+		conversation_id   TEXT,
+		prompt_hash       TEXT NOT NULL,
+		prompt            TEXT,
+		response          TEXT,
+		model_used        TEXT,
+		cache_status      TEXT,
+		prompt_tokens     INTEGER,
+		completion_tokens INTEGER,
+		cost_usd          DOUBLE PRECISION,
+		latency_ms        BIGINT,
+		failover_info     JSONB,
+		decisions         JSONB
+	)`, table))
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create audit table %s: %w", table, err)
+	}
+	return &PostgresSink{pool: pool, table: table}, nil
+}
+
+// Write inserts a batch of records in a single round trip.
+func (s *PostgresSink) Write(ctx context.Context, records []Record) error {
+	query := fmt.Sprintf(`INSERT INTO %s (ts, request_id, user_id, conversation_id, prompt_hash, prompt, response,
+		model_used, cache_status, prompt_tokens, completion_tokens, cost_usd, latency_ms, failover_info, decisions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14::jsonb, $15::jsonb)`, s.table)
+
+	batch := &pgx.Batch{}
+	for _, r := range records {
+		failoverInfo, err := nullableJSON(r.FailoverInfo)
+		if err != nil {
+			return err
+		}
+		decisions, err := nullableJSON(r.Decisions)
+		if err != nil {
+			return err
+		}
+		batch.Queue(query, r.Timestamp, r.RequestID, r.UserID, r.ConversationID, r.PromptHash, r.Prompt, r.Response,
+			r.ModelUsed, r.CacheStatus, r.PromptTokens, r.CompletionTokens, r.CostUSD, r.LatencyMS, failoverInfo, decisions)
+	}
+	if err := s.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert audit records: %w", err)
+	}
+	return nil
+}
+
+// Close releases the connection pool.
+func (s *PostgresSink) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+// nullableJSON encodes v as a JSON string, or returns nil (SQL NULL) if v is nil.
+func nullableJSON[T any](v *T) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit field: %w", err)
+	}
+	s := string(encoded)
+	return &s, nil
+}
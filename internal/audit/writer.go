@@ -0,0 +1,136 @@
+// In file: internal/audit/writer.go
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+)
+
+// Record is a single entry in the durable audit log. Prompt and Response are only
+// filled in when the privacy configuration allows storing full text; PromptHash is
+// always set, so requests can be correlated without retaining their content.
+type Record struct {
+	Timestamp        time.Time          `json:"timestamp"`
+	RequestID        string             `json:"request_id,omitempty"`
+	UserID           string             `json:"user_id,omitempty"`
+	ConversationID   string             `json:"conversation_id,omitempty"`
+	PromptHash       string             `json:"prompt_hash"`
+	Prompt           string             `json:"prompt,omitempty"`
+	Response         string             `json:"response,omitempty"`
+	ModelUsed        string             `json:"model_used"`
+	CacheStatus      string             `json:"cache_status"`
+	PromptTokens     int                `json:"prompt_tokens"`
+	CompletionTokens int                `json:"completion_tokens"`
+	CostUSD          float64            `json:"cost_usd"`
+	LatencyMS        int64              `json:"latency_ms"`
+	FailoverInfo     *api.FailoverInfo  `json:"failover_info,omitempty"`
+	Decisions        *api.DecisionTrace `json:"decisions,omitempty"`
+}
+
+// Sink persists batches of audit records, e.g. to Postgres or ClickHouse.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// Writer buffers audit records and writes them to a Sink in batches from a background
+// goroutine, so request handling never waits on the audit store. When the buffer is
+// full, records are dropped and counted rather than blocking the request path.
+type Writer struct {
+	sink          Sink
+	queue         chan Record
+	batchSize     int
+	flushInterval time.Duration
+	writeTimeout  time.Duration
+	dropped       atomic.Int64
+	stop          chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewWriter starts a writer that flushes whenever batchSize records are buffered or
+// flushInterval has passed, whichever comes first.
+func NewWriter(sink Sink, queueSize, batchSize int, flushInterval time.Duration) *Writer {
+	w := &Writer{
+		sink:          sink,
+		queue:         make(chan Record, queueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		writeTimeout:  10 * time.Second,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Record queues a record for writing. It never blocks.
+func (w *Writer) Record(record Record) {
+	select {
+	case w.queue <- record:
+	default:
+		if dropped := w.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			slog.Warn("Audit queue is full. Dropping records", "dropped_total", dropped)
+		}
+	}
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (w *Writer) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Close flushes the records that are still queued and closes the sink.
+func (w *Writer) Close() error {
+	w.closeOnce.Do(func() { close(w.stop) })
+	<-w.done
+	return w.sink.Close()
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, w.batchSize)
+	for {
+		select {
+		case record := <-w.queue:
+			batch = append(batch, record)
+			if len(batch) >= w.batchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		case <-w.stop:
+			for {
+				select {
+				case record := <-w.queue:
+					batch = append(batch, record)
+				default:
+					w.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes a batch and returns an empty slice to reuse. A failed batch is logged and
+// discarded; retrying would let a down audit store grow the gateway's memory without bound.
+func (w *Writer) flush(batch []Record) []Record {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.writeTimeout)
+	defer cancel()
+	if err := w.sink.Write(ctx, batch); err != nil {
+		slog.Error("Failed to write audit records", "records", len(batch), "error", err)
+	}
+	return batch[:0]
+}
@@ -0,0 +1,161 @@
+// In file: internal/auth/jwt.go
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Claims are the decoded claims of a verified token.
+type Claims map[string]interface{}
+
+// String returns a string claim, or "" if it is missing or not a string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// jwtHeader is the JOSE header of a token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// signingAlgorithms maps the supported JWS algorithms to their hash functions.
+// Symmetric algorithms are deliberately absent: an OIDC provider signs with a private key.
+var signingAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// parsedToken is a token split into its parts, before its signature has been checked.
+type parsedToken struct {
+	header       jwtHeader
+	claims       Claims
+	signingInput string
+	signature    []byte
+}
+
+// parseToken decodes a compact JWS without verifying it.
+func parseToken(token string) (*parsedToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	t := &parsedToken{signingInput: parts[0] + "." + parts[1], signature: signature}
+	if err := json.Unmarshal(headerJSON, &t.header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	if err := json.Unmarshal(claimsJSON, &t.claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	return t, nil
+}
+
+// verifySignature checks the token's signature with the given public key.
+func (t *parsedToken) verifySignature(key crypto.PublicKey) error {
+	hash, ok := signingAlgorithms[t.header.Alg]
+	if !ok {
+		return fmt.Errorf("unsupported signing algorithm '%s'", t.header.Alg)
+	}
+	h := hash.New()
+	h.Write([]byte(t.signingInput))
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(t.header.Alg, "RS") {
+			return fmt.Errorf("algorithm '%s' does not match the RSA key", t.header.Alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, t.signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(t.header.Alg, "ES") {
+			return fmt.Errorf("algorithm '%s' does not match the EC key", t.header.Alg)
+		}
+		// JWS encodes ECDSA signatures as the fixed-size concatenation of r and s.
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+	default:
+		return errors.New("unsupported key type")
+	}
+	return nil
+}
+
+// validateClaims checks the registered claims: issuer, audience, and validity window.
+func (c Claims) validateClaims(issuer, audience string, now time.Time, leeway time.Duration) error {
+	if c.String("iss") != issuer {
+		return fmt.Errorf("unexpected issuer '%s'", c.String("iss"))
+	}
+	if !c.hasAudience(audience) {
+		return errors.New("token is not intended for this audience")
+	}
+	exp, ok := c.numericDate("exp")
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(exp.Add(leeway)) {
+		return errors.New("token has expired")
+	}
+	if nbf, ok := c.numericDate("nbf"); ok && now.Add(leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
+
+// hasAudience reports whether the "aud" claim, a string or an array of strings, contains audience.
+func (c Claims) hasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numericDate reads a JWT NumericDate claim (seconds since the epoch).
+func (c Claims) numericDate(name string) (time.Time, bool) {
+	seconds, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
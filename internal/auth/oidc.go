@@ -0,0 +1,129 @@
+// In file: internal/auth/oidc.go
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+// clockLeeway tolerates small clock differences between the gateway and the identity provider.
+const clockLeeway = time.Minute
+
+// OIDCConfig configures validation of tokens issued by an OpenID Connect provider.
+type OIDCConfig struct {
+	// Issuer is the provider's issuer URL; tokens must carry it as their "iss" claim.
+	Issuer string `yaml:"issuer"`
+	// Audience must appear in the token's "aud" claim, so tokens the provider issued to
+	// other applications are not accepted.
+	Audience string `yaml:"audience"`
+	// JWKSURL overrides the key set URL found through OIDC discovery.
+	JWKSURL string `yaml:"jwks_url"`
+	// UserClaim names the claim holding the user ID. It defaults to "sub".
+	UserClaim string `yaml:"user_claim"`
+	// TenantClaim names the claim holding the tenant ID. Tokens without it use the default tenant.
+	TenantClaim string `yaml:"tenant_claim"`
+}
+
+// Identity is the caller established by a verified token.
+type Identity struct {
+	UserID   string
+	TenantID string
+	Claims   Claims
+}
+
+// Verifier validates bearer tokens against an OIDC provider's signing keys.
+type Verifier struct {
+	config OIDCConfig
+	keys   *keySet
+}
+
+// NewVerifier creates a verifier for the configured issuer. Unless a JWKS URL is configured,
+// it is discovered from the issuer's /.well-known/openid-configuration document.
+func NewVerifier(ctx context.Context, config OIDCConfig) (*Verifier, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("OIDC issuer cannot be empty")
+	}
+	if config.Audience == "" {
+		return nil, errors.New("OIDC audience cannot be empty")
+	}
+	if config.UserClaim == "" {
+		config.UserClaim = "sub"
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport()}
+
+	jwksURL := config.JWKSURL
+	if jwksURL == "" {
+		var err error
+		jwksURL, err = discoverJWKSURL(ctx, httpClient, config.Issuer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Verifier{config: config, keys: newKeySet(jwksURL, httpClient)}, nil
+}
+
+// Verify checks a token's signature and claims and returns the identity it asserts.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	parsed, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	key, err := v.keys.key(ctx, parsed.header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := parsed.verifySignature(key); err != nil {
+		return nil, err
+	}
+	if err := parsed.claims.validateClaims(v.config.Issuer, v.config.Audience, time.Now(), clockLeeway); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{UserID: parsed.claims.String(v.config.UserClaim), Claims: parsed.claims}
+	if identity.UserID == "" {
+		return nil, fmt.Errorf("token has no '%s' claim", v.config.UserClaim)
+	}
+	if v.config.TenantClaim != "" {
+		identity.TenantID = parsed.claims.String(v.config.TenantClaim)
+	}
+	return identity, nil
+}
+
+// discoverJWKSURL reads the JWKS URL from the issuer's discovery document.
+func discoverJWKSURL(ctx context.Context, httpClient *http.Client, issuer string) (string, error) {
+	discoveryURL := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, "GET", discoveryURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC discovery failed: status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to decode OIDC discovery document: %w", err)
+	}
+	if doc.Issuer != issuer {
+		return "", fmt.Errorf("OIDC discovery returned issuer '%s', expected '%s'", doc.Issuer, issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", errors.New("OIDC discovery document has no jwks_uri")
+	}
+	return doc.JWKSURI, nil
+}
@@ -0,0 +1,242 @@
+// In file: internal/auth/oidc_test.go
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "llm-gateway"
+)
+
+// testKeys are the signing keys published by the test JWKS server.
+type testKeys struct {
+	rsa   *rsa.PrivateKey
+	ec    *ecdsa.PrivateKey
+	other *rsa.PrivateKey // Not published.
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, ec: ecKey, other: otherKey}
+}
+
+// serveJWKS starts a JWKS endpoint publishing the RSA key as "rsa" and the EC key as "ec".
+func (k *testKeys) serveJWKS(t *testing.T) string {
+	t.Helper()
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	doc := map[string]any{"keys": []jsonWebKey{
+		{Kty: "RSA", Kid: "rsa", Use: "sig", N: encode(k.rsa.N), E: encode(big.NewInt(int64(k.rsa.E)))},
+		{Kty: "EC", Kid: "ec", Crv: "P-256", X: encode(k.ec.X), Y: encode(k.ec.Y)},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// sign returns a compact JWS of the claims, signed with key under the given algorithm.
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := crypto.SHA256.New()
+	hash.Write([]byte(input))
+	digest := hash.Sum(nil)
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifierVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	jwksURL := keys.serveJWKS(t)
+	now := time.Now()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss": testIssuer,
+			"aud": testAudience,
+			"sub": "user-1",
+			"org": "tenant-1",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for name, value := range overrides {
+			if value == nil {
+				delete(c, name)
+			} else {
+				c[name] = value
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantUser   string
+		wantTenant string
+		wantErr    string
+	}{
+		{
+			name:     "RS256",
+			token:    sign(t, "RS256", "rsa", keys.rsa, claims(nil)),
+			wantUser: "user-1", wantTenant: "tenant-1",
+		},
+		{
+			name:     "ES256",
+			token:    sign(t, "ES256", "ec", keys.ec, claims(nil)),
+			wantUser: "user-1", wantTenant: "tenant-1",
+		},
+		{
+			name:     "audience in a list",
+			token:    sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"aud": []string{"other", testAudience}})),
+			wantUser: "user-1", wantTenant: "tenant-1",
+		},
+		{
+			name:     "expired within the leeway",
+			token:    sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"exp": now.Add(-clockLeeway / 2).Unix()})),
+			wantUser: "user-1", wantTenant: "tenant-1",
+		},
+		{
+			name:    "other audience",
+			token:   sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"aud": "other"})),
+			wantErr: "not intended for this audience",
+		},
+		{
+			name:    "no audience",
+			token:   sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"aud": nil})),
+			wantErr: "not intended for this audience",
+		},
+		{
+			name:    "other issuer",
+			token:   sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"iss": "https://evil.example.com"})),
+			wantErr: "unexpected issuer",
+		},
+		{
+			name:    "expired",
+			token:   sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"exp": now.Add(-2 * clockLeeway).Unix()})),
+			wantErr: "expired",
+		},
+		{
+			name:    "no expiry",
+			token:   sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"exp": nil})),
+			wantErr: "no expiry",
+		},
+		{
+			name:    "not valid yet",
+			token:   sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"nbf": now.Add(2 * clockLeeway).Unix()})),
+			wantErr: "not valid yet",
+		},
+		{
+			name:    "signed with another key",
+			token:   sign(t, "RS256", "rsa", keys.other, claims(nil)),
+			wantErr: "invalid token signature",
+		},
+		{
+			name:    "algorithm does not match the key",
+			token:   sign(t, "ES256", "rsa", keys.ec, claims(nil)),
+			wantErr: "does not match the RSA key",
+		},
+		{
+			name:    "symmetric algorithm",
+			token:   sign(t, "HS256", "rsa", keys.rsa, claims(nil)),
+			wantErr: "unsupported signing algorithm",
+		},
+		{
+			name:    "unknown key",
+			token:   sign(t, "RS256", "rotated", keys.rsa, claims(nil)),
+			wantErr: "unknown signing key",
+		},
+		{
+			name:    "no key ID with several keys",
+			token:   sign(t, "RS256", "", keys.rsa, claims(nil)),
+			wantErr: "unknown signing key",
+		},
+		{
+			name:    "no user claim",
+			token:   sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"sub": nil})),
+			wantErr: "no 'sub' claim",
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-token",
+			wantErr: "malformed token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVerifier(context.Background(), OIDCConfig{
+				Issuer:      testIssuer,
+				Audience:    testAudience,
+				JWKSURL:     jwksURL,
+				TenantClaim: "org",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := v.Verify(context.Background(), tt.token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if identity.UserID != tt.wantUser || identity.TenantID != tt.wantTenant {
+				t.Fatalf("identity = %s/%s, want %s/%s", identity.UserID, identity.TenantID, tt.wantUser, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestNewVerifierRequiresAudience(t *testing.T) {
+	_, err := NewVerifier(context.Background(), OIDCConfig{Issuer: testIssuer, JWKSURL: "https://issuer.example.com/jwks"})
+	if err == nil {
+		t.Fatal("NewVerifier() accepted a configuration without an audience")
+	}
+}
@@ -0,0 +1,73 @@
+// In file: internal/config/config.go
+
+// Package config loads the configuration file shared by the gateway and the offline tools
+// (ingestor, embedcache). Every non-secret setting lives in this one YAML file; environment
+// variables are reserved for secrets and for deployment wiring such as REDIS_ADDR.
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file used when CONFIG_FILE is not set.
+const DefaultPath = "config.yaml"
+
+// Path returns the configuration file in use: CONFIG_FILE, or config.yaml in the working directory.
+func Path() string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	return DefaultPath
+}
+
+// LoadEnv loads secrets from a local .env file for development. In release mode
+// (GIN_MODE=release) they are provided directly by the container environment.
+func LoadEnv() {
+	if os.Getenv("GIN_MODE") == "release" {
+		return
+	}
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("No .env file found for local development.")
+	}
+}
+
+// Read returns the raw contents of the configuration file.
+func Read() ([]byte, error) {
+	data, err := os.ReadFile(Path())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", Path(), err)
+	}
+	return data, nil
+}
+
+// Load decodes the configuration file into out. Only the sections out declares are read,
+// so each program picks the sections it uses. A missing file is reported as fs.ErrNotExist.
+func Load(out any) error {
+	data, err := Read()
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", Path(), err)
+	}
+	return nil
+}
+
+// Legacy returns value or, when it is empty, the deprecated environment variable env.
+// Reading a deprecated variable logs a warning naming the setting that replaces it, so
+// existing deployments keep working while they migrate.
+func Legacy(value, env, setting string) string {
+	if value != "" {
+		return value
+	}
+	legacy := os.Getenv(env)
+	if legacy != "" {
+		slog.Warn("Configuration from an environment variable is deprecated", "env", env, "use", setting+" in "+Path())
+	}
+	return legacy
+}
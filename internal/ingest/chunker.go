@@ -0,0 +1,337 @@
+// In file: internal/ingest/chunker.go
+
+// Package ingest contains the document ingestion pipeline shared by the offline
+// ingestor and the gateway's webhook-driven incremental ingestion.
+// Keeping chunking in one place guarantees that a document produces identical
+// vectors no matter which entry point ingested it.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dileep-u-k/llm-gateway/internal/config"
+)
+
+// Chunking strategies.
+const (
+	// StrategyHeading splits a document at its Markdown headings to respect semantic
+	// boundaries, then packs the lines of any section that is still too long.
+	StrategyHeading = "heading"
+	// StrategyFixed cuts a document into windows of exactly the chunk size.
+	StrategyFixed = "fixed"
+	// StrategyRecursive splits at paragraphs, then lines, then sentences, then words,
+	// going only as fine as needed, and packs the pieces into chunks.
+	StrategyRecursive = "recursive"
+	// StrategySentenceWindow packs whole sentences into chunks, so no sentence is ever cut.
+	StrategySentenceWindow = "sentence-window"
+)
+
+const (
+	// defaultChunkSize is the target size of a chunk, in tokens.
+	defaultChunkSize = 500
+	// defaultChunkOverlap is carried over between consecutive chunks to preserve context, in tokens.
+	defaultChunkOverlap = 50
+)
+
+// recursiveSeparators are the boundaries the recursive strategy tries, coarsest first.
+var recursiveSeparators = []string{"\n\n", "\n", ". ", " "}
+
+// markdownHeading matches the start of a Markdown heading line.
+var markdownHeading = regexp.MustCompile(`(?m)^#{1,6}[ \t]`)
+
+// ChunkOptions configures how documents are chunked. Unset fields inherit the defaults.
+type ChunkOptions struct {
+	Strategy string `yaml:"strategy"`
+	// Size is the maximum size of a chunk, in tokens.
+	Size int `yaml:"chunk_size"`
+	// Overlap is how much of the end of a chunk is repeated at the start of the next, in tokens.
+	Overlap *int `yaml:"chunk_overlap"`
+}
+
+// inherit fills the unset fields of o from base.
+func (o ChunkOptions) inherit(base ChunkOptions) ChunkOptions {
+	if o.Strategy == "" {
+		o.Strategy = base.Strategy
+	}
+	if o.Size == 0 {
+		o.Size = base.Size
+	}
+	if o.Overlap == nil {
+		o.Overlap = base.Overlap
+	}
+	return o
+}
+
+// validate checks fully inherited options.
+func (o ChunkOptions) validate() error {
+	switch o.Strategy {
+	case StrategyHeading, StrategyFixed, StrategyRecursive, StrategySentenceWindow:
+	default:
+		return fmt.Errorf("unknown chunking strategy '%s' (want %s, %s, %s, or %s)", o.Strategy, StrategyHeading, StrategyFixed, StrategyRecursive, StrategySentenceWindow)
+	}
+	if o.Size <= 0 {
+		return fmt.Errorf("chunk_size must be positive, got %d", o.Size)
+	}
+	if *o.Overlap < 0 || *o.Overlap >= o.Size {
+		return fmt.Errorf("chunk_overlap must be at least 0 and less than chunk_size (%d), got %d", o.Size, *o.Overlap)
+	}
+	return nil
+}
+
+// ChunkingConfig is the chunking section of the configuration file: default options
+// and per-topic overrides, whose unset fields inherit the defaults.
+type ChunkingConfig struct {
+	ChunkOptions `yaml:",inline"`
+	Topics       map[string]ChunkOptions `yaml:"topics"`
+}
+
+// defaultChunkOptions returns the built-in options.
+func defaultChunkOptions() ChunkOptions {
+	overlap := defaultChunkOverlap
+	return ChunkOptions{Strategy: StrategyHeading, Size: defaultChunkSize, Overlap: &overlap}
+}
+
+// For returns the options for a topic.
+func (c ChunkingConfig) For(topic string) ChunkOptions {
+	base := c.ChunkOptions.inherit(defaultChunkOptions())
+	if override, ok := c.Topics[topic]; ok {
+		return override.inherit(base)
+	}
+	return base
+}
+
+// Validate checks the defaults and every topic override.
+func (c ChunkingConfig) Validate() error {
+	if err := c.For("").validate(); err != nil {
+		return err
+	}
+	topics := make([]string, 0, len(c.Topics))
+	for topic := range c.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	var errs []error
+	for _, topic := range topics {
+		if err := c.For(topic).validate(); err != nil {
+			errs = append(errs, fmt.Errorf("topic '%s': %w", topic, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ChunkerFor returns the chunker for a topic. The configuration must have been validated.
+func (c ChunkingConfig) ChunkerFor(topic string) *Chunker {
+	chunker, err := NewChunker(c.For(topic), DefaultTokenizer)
+	if err != nil {
+		// Unreachable for a validated configuration; fall back rather than drop the document.
+		chunker, _ = NewChunker(defaultChunkOptions(), DefaultTokenizer)
+	}
+	return chunker
+}
+
+// LoadChunkingConfig reads ingest.chunking from the shared configuration file. Without a
+// file, the built-in defaults apply.
+func LoadChunkingConfig() (ChunkingConfig, error) {
+	var file struct {
+		Ingest struct {
+			Chunking ChunkingConfig `yaml:"chunking"`
+		} `yaml:"ingest"`
+	}
+	if err := config.Load(&file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ChunkingConfig{}, err
+	}
+	if err := file.Ingest.Chunking.Validate(); err != nil {
+		return ChunkingConfig{}, fmt.Errorf("invalid ingest.chunking: %w", err)
+	}
+	return file.Ingest.Chunking, nil
+}
+
+// Chunker splits documents into chunks measured in tokens.
+type Chunker struct {
+	strategy  string
+	size      int
+	overlap   int
+	tokenizer Tokenizer
+}
+
+// NewChunker creates a chunker. Unset options take the built-in defaults.
+func NewChunker(opts ChunkOptions, tokenizer Tokenizer) (*Chunker, error) {
+	opts = opts.inherit(defaultChunkOptions())
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Chunker{strategy: opts.Strategy, size: opts.Size, overlap: *opts.Overlap, tokenizer: tokenizer}, nil
+}
+
+// ChunkPages chunks every page separately, so each chunk keeps the number of its page.
+func (c *Chunker) ChunkPages(pages []Page) []Chunk {
+	var chunks []Chunk
+	for _, page := range pages {
+		for _, text := range c.Split(page.Text) {
+			chunks = append(chunks, Chunk{Text: text, Page: page.Number})
+		}
+	}
+	return chunks
+}
+
+// Split splits a text into chunks using the chunker's strategy.
+func (c *Chunker) Split(text string) []string {
+	switch c.strategy {
+	case StrategyFixed:
+		return c.windows(text, c.overlap)
+	case StrategyRecursive:
+		return c.pack(c.recursiveUnits(text, recursiveSeparators))
+	case StrategySentenceWindow:
+		return c.pack(c.fit(splitSentences(text)))
+	default:
+		return c.splitByHeading(text)
+	}
+}
+
+// splitByHeading keeps every section under a heading together when it fits, and packs
+// the lines of longer sections.
+func (c *Chunker) splitByHeading(text string) []string {
+	var chunks []string
+	starts := markdownHeading.FindAllStringIndex(text, -1)
+	bounds := []int{0}
+	for _, loc := range starts {
+		if loc[0] > 0 {
+			bounds = append(bounds, loc[0])
+		}
+	}
+	bounds = append(bounds, len(text))
+	for i := 0; i+1 < len(bounds); i++ {
+		section := text[bounds[i]:bounds[i+1]]
+		if countTokens(c.tokenizer, section) <= c.size {
+			chunks = appendChunk(chunks, section)
+			continue
+		}
+		chunks = append(chunks, c.pack(c.fit(strings.SplitAfter(section, "\n")))...)
+	}
+	return chunks
+}
+
+// recursiveUnits splits text at the coarsest separator that yields pieces within the
+// chunk size, recursing into pieces that are still too long.
+func (c *Chunker) recursiveUnits(text string, separators []string) []string {
+	if countTokens(c.tokenizer, text) <= c.size {
+		return []string{text}
+	}
+	if len(separators) == 0 {
+		return c.windows(text, 0)
+	}
+	var units []string
+	for _, piece := range strings.SplitAfter(text, separators[0]) {
+		units = append(units, c.recursiveUnits(piece, separators[1:])...)
+	}
+	return units
+}
+
+// fit cuts any unit longer than the chunk size into overlapping windows, so pack can place
+// every unit.
+func (c *Chunker) fit(units []string) []string {
+	fitted := make([]string, 0, len(units))
+	for _, unit := range units {
+		if countTokens(c.tokenizer, unit) > c.size {
+			fitted = append(fitted, c.windows(unit, c.overlap)...)
+			continue
+		}
+		fitted = append(fitted, unit)
+	}
+	return fitted
+}
+
+// pack greedily combines consecutive units into chunks of at most the chunk size. Each
+// chunk starts with the trailing units of the previous one, up to the overlap.
+func (c *Chunker) pack(units []string) []string {
+	var chunks []string
+	var current []string
+	var counts []int
+	total := 0
+	for _, unit := range units {
+		n := countTokens(c.tokenizer, unit)
+		if total+n > c.size && len(current) > 0 {
+			chunks = appendChunk(chunks, strings.Join(current, ""))
+			// Carry over whole trailing units within the overlap that still leave room for this unit.
+			keep, kept := len(current), 0
+			for keep > 0 && kept+counts[keep-1] <= c.overlap && kept+counts[keep-1]+n <= c.size {
+				keep--
+				kept += counts[keep]
+			}
+			current, counts, total = append([]string(nil), current[keep:]...), append([]int(nil), counts[keep:]...), kept
+		}
+		current = append(current, unit)
+		counts = append(counts, n)
+		total += n
+	}
+	if len(current) > 0 {
+		chunks = appendChunk(chunks, strings.Join(current, ""))
+	}
+	return chunks
+}
+
+// windows cuts text into consecutive windows of the chunk size that share overlap tokens.
+func (c *Chunker) windows(text string, overlap int) []string {
+	tokens := c.tokenizer.Tokenize(text)
+	var chunks []string
+	for start := 0; start < len(tokens); start += c.size - overlap {
+		end := min(start+c.size, len(tokens))
+		chunks = appendChunk(chunks, joinTokens(tokens[start:end]))
+		if end == len(tokens) {
+			break
+		}
+	}
+	return chunks
+}
+
+// appendChunk adds a chunk without its surrounding whitespace, skipping empty ones.
+func appendChunk(chunks []string, chunk string) []string {
+	if chunk = strings.TrimSpace(chunk); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// splitSentences splits text after sentence-ending punctuation and at blank lines. The
+// whitespace after a sentence stays with it, so joining the sentences gives back the text.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		paragraphEnd := r == '\n' && strings.HasPrefix(text[i:], "\n")
+		if r != '.' && r != '!' && r != '?' && !paragraphEnd {
+			continue
+		}
+		// Closing quotes and brackets belong to the sentence they end.
+		for i < len(text) && strings.IndexByte(`"')]`, text[i]) >= 0 {
+			i++
+		}
+		if i < len(text) {
+			if next, _ := utf8.DecodeRuneInString(text[i:]); !unicode.IsSpace(next) {
+				continue
+			}
+		}
+		for i < len(text) {
+			next, size := utf8.DecodeRuneInString(text[i:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			i += size
+		}
+		sentences = append(sentences, text[start:i])
+		start = i
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
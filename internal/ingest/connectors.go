@@ -0,0 +1,404 @@
+// In file: internal/ingest/connectors.go
+package ingest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// =================================================================================
+// Shared Helpers
+// =================================================================================
+
+var (
+	htmlTagRegex    = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+)
+
+// verifyHMACSignature checks a "sha256=<hex>" signature header against the body.
+// An empty secret rejects every delivery rather than accepting unauthenticated ones.
+func verifyHMACSignature(secret, signature string, body []byte) error {
+	if secret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+	if signature == "" {
+		return errors.New("missing webhook signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("webhook signature mismatch")
+	}
+	return nil
+}
+
+// fetchBody performs a GET request and returns the body of a successful response.
+func fetchBody(ctx context.Context, httpClient *http.Client, rawURL string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// stripHTML reduces an HTML document to plain text, turning block-level tags into line breaks.
+func stripHTML(html string) string {
+	replacer := strings.NewReplacer("</p>", "\n\n", "<br>", "\n", "<br/>", "\n", "</li>", "\n", "</h1>", "\n\n", "</h2>", "\n\n", "</h3>", "\n\n")
+	text := htmlTagRegex.ReplaceAllString(replacer.Replace(html), "")
+	text = strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", "\"", "&#39;", "'").Replace(text)
+	return strings.TrimSpace(blankLinesRegex.ReplaceAllString(text, "\n\n"))
+}
+
+// =================================================================================
+// GitHub Connector
+// =================================================================================
+
+// GitHubConnector ingests Markdown and text files changed by GitHub push events.
+type GitHubConnector struct {
+	webhookSecret string
+	token         string
+	topic         string
+	repositories  map[string]bool
+	httpClient    *http.Client
+}
+
+var _ Connector = (*GitHubConnector)(nil)
+
+// NewGitHubConnector creates a connector for GitHub push webhooks.
+// If topic is empty, the repository name is used as the knowledge-base topic.
+// Only pushes to the listed repositories ("owner/name") are ingested, since the
+// content is fetched from whichever repository the payload names.
+func NewGitHubConnector(webhookSecret, token, topic string, repositories []string) *GitHubConnector {
+	allowed := make(map[string]bool, len(repositories))
+	for _, repo := range repositories {
+		allowed[strings.ToLower(repo)] = true
+	}
+	return &GitHubConnector{
+		webhookSecret: webhookSecret,
+		token:         token,
+		topic:         topic,
+		repositories:  allowed,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (g *GitHubConnector) Name() string { return "github" }
+
+func (g *GitHubConnector) VerifySignature(headers http.Header, body []byte) error {
+	return verifyHMACSignature(g.webhookSecret, headers.Get("X-Hub-Signature-256"), body)
+}
+
+func (g *GitHubConnector) ParseWebhook(headers http.Header, body []byte) ([]Change, error) {
+	if event := headers.Get("X-GitHub-Event"); event != "" && event != "push" {
+		slog.Info("Ignoring GitHub event", "event", event)
+		return nil, nil
+	}
+	var payload struct {
+		After      string `json:"after"`
+		Repository struct {
+			Name     string `json:"name"`
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Commits []struct {
+			Added    []string `json:"added"`
+			Modified []string `json:"modified"`
+			Removed  []string `json:"removed"`
+		} `json:"commits"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid GitHub push payload: %w", err)
+	}
+	if !g.repositories[strings.ToLower(payload.Repository.FullName)] {
+		return nil, fmt.Errorf("repository %q is not allowed for ingestion", payload.Repository.FullName)
+	}
+
+	topic := g.topic
+	if topic == "" {
+		topic = payload.Repository.Name
+	}
+
+	// Later commits in a push win, so the final action for each path is what gets applied.
+	actions := make(map[string]ChangeAction)
+	var order []string
+	record := func(path string, action ChangeAction) {
+		if !isSupportedTextFile(path) {
+			return
+		}
+		if _, seen := actions[path]; !seen {
+			order = append(order, path)
+		}
+		actions[path] = action
+	}
+	for _, commit := range payload.Commits {
+		for _, path := range commit.Added {
+			record(path, ActionUpsert)
+		}
+		for _, path := range commit.Modified {
+			record(path, ActionUpsert)
+		}
+		for _, path := range commit.Removed {
+			record(path, ActionDelete)
+		}
+	}
+
+	changes := make([]Change, 0, len(order))
+	for _, path := range order {
+		changes = append(changes, Change{
+			Connector: g.Name(),
+			SourceID:  fmt.Sprintf("github:%s/%s", payload.Repository.FullName, path),
+			Topic:     topic,
+			Title:     path,
+			Action:    actions[path],
+			Ref:       fmt.Sprintf("%s/%s/%s", payload.Repository.FullName, payload.After, path),
+		})
+	}
+	return changes, nil
+}
+
+func (g *GitHubConnector) FetchContent(ctx context.Context, change Change) (string, error) {
+	headers := map[string]string{"User-Agent": "LLM-Gateway-Agent/1.0"}
+	if g.token != "" {
+		headers["Authorization"] = "Bearer " + g.token
+	}
+	body, err := fetchBody(ctx, g.httpClient, "https://raw.githubusercontent.com/"+change.Ref, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch GitHub file: %w", err)
+	}
+	return string(body), nil
+}
+
+// =================================================================================
+// Notion Connector
+// =================================================================================
+
+const (
+	notionAPIURL  = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+)
+
+// NotionConnector ingests Notion pages reported by Notion's integration webhooks.
+type NotionConnector struct {
+	webhookSecret string
+	apiKey        string
+	topic         string
+	httpClient    *http.Client
+}
+
+var _ Connector = (*NotionConnector)(nil)
+
+// NewNotionConnector creates a connector for Notion page webhooks.
+func NewNotionConnector(webhookSecret, apiKey, topic string) *NotionConnector {
+	if topic == "" {
+		topic = "notion"
+	}
+	return &NotionConnector{
+		webhookSecret: webhookSecret,
+		apiKey:        apiKey,
+		topic:         topic,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (n *NotionConnector) Name() string { return "notion" }
+
+func (n *NotionConnector) VerifySignature(headers http.Header, body []byte) error {
+	return verifyHMACSignature(n.webhookSecret, headers.Get("X-Notion-Signature"), body)
+}
+
+func (n *NotionConnector) ParseWebhook(headers http.Header, body []byte) ([]Change, error) {
+	var payload struct {
+		Type   string `json:"type"`
+		Entity struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"entity"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid Notion payload: %w", err)
+	}
+	if payload.Entity.Type != "page" || payload.Entity.ID == "" {
+		return nil, nil
+	}
+
+	action := ActionUpsert
+	if payload.Type == "page.deleted" {
+		action = ActionDelete
+	}
+	return []Change{{
+		Connector: n.Name(),
+		SourceID:  "notion:" + payload.Entity.ID,
+		Topic:     n.topic,
+		Title:     payload.Entity.ID,
+		Action:    action,
+		Ref:       payload.Entity.ID,
+	}}, nil
+}
+
+func (n *NotionConnector) FetchContent(ctx context.Context, change Change) (string, error) {
+	type richText struct {
+		PlainText string `json:"plain_text"`
+	}
+	var page struct {
+		Results []map[string]json.RawMessage `json:"results"`
+	}
+
+	body, err := fetchBody(ctx, n.httpClient, fmt.Sprintf("%s/blocks/%s/children?page_size=100", notionAPIURL, url.PathEscape(change.Ref)), map[string]string{
+		"Authorization":  "Bearer " + n.apiKey,
+		"Notion-Version": notionVersion,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch Notion blocks: %w", err)
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return "", fmt.Errorf("failed to parse Notion blocks: %w", err)
+	}
+
+	// Every text-bearing block type stores its content under a key named after the type.
+	var builder strings.Builder
+	for _, block := range page.Results {
+		var blockType string
+		if err := json.Unmarshal(block["type"], &blockType); err != nil {
+			continue
+		}
+		var content struct {
+			RichText []richText `json:"rich_text"`
+		}
+		if err := json.Unmarshal(block[blockType], &content); err != nil || len(content.RichText) == 0 {
+			continue
+		}
+		if strings.HasPrefix(blockType, "heading_") {
+			builder.WriteString("# ")
+		}
+		for _, rt := range content.RichText {
+			builder.WriteString(rt.PlainText)
+		}
+		builder.WriteString("\n\n")
+	}
+	return strings.TrimSpace(builder.String()), nil
+}
+
+// =================================================================================
+// Confluence Connector
+// =================================================================================
+
+// ConfluenceConnector ingests Confluence pages reported by page webhooks.
+type ConfluenceConnector struct {
+	webhookSecret string
+	baseURL       string
+	user          string
+	apiToken      string
+	httpClient    *http.Client
+}
+
+var _ Connector = (*ConfluenceConnector)(nil)
+
+// NewConfluenceConnector creates a connector for Confluence page webhooks.
+// Pages are filed under a topic named after their space key.
+func NewConfluenceConnector(webhookSecret, baseURL, user, apiToken string) *ConfluenceConnector {
+	return &ConfluenceConnector{
+		webhookSecret: webhookSecret,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		user:          user,
+		apiToken:      apiToken,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *ConfluenceConnector) Name() string { return "confluence" }
+
+func (c *ConfluenceConnector) VerifySignature(headers http.Header, body []byte) error {
+	return verifyHMACSignature(c.webhookSecret, headers.Get("X-Hub-Signature"), body)
+}
+
+func (c *ConfluenceConnector) ParseWebhook(headers http.Header, body []byte) ([]Change, error) {
+	var payload struct {
+		Event string `json:"event"`
+		Page  struct {
+			ID       json.Number `json:"id"`
+			Title    string      `json:"title"`
+			SpaceKey string      `json:"spaceKey"`
+		} `json:"page"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid Confluence payload: %w", err)
+	}
+	pageID := payload.Page.ID.String()
+	if pageID == "" {
+		return nil, nil
+	}
+
+	action := ActionUpsert
+	if payload.Event == "page_removed" || payload.Event == "page_trashed" {
+		action = ActionDelete
+	}
+	topic := strings.ToLower(payload.Page.SpaceKey)
+	if topic == "" {
+		topic = "confluence"
+	}
+	return []Change{{
+		Connector: c.Name(),
+		SourceID:  "confluence:" + pageID,
+		Topic:     topic,
+		Title:     payload.Page.Title,
+		Action:    action,
+		Ref:       pageID,
+	}}, nil
+}
+
+func (c *ConfluenceConnector) FetchContent(ctx context.Context, change Change) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/rest/api/content/%s?expand=body.storage", c.baseURL, url.PathEscape(change.Ref)), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Confluence request: %w", err)
+	}
+	req.SetBasicAuth(c.user, c.apiToken)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch Confluence page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("confluence API returned status %d", resp.StatusCode)
+	}
+
+	var page struct {
+		Title string `json:"title"`
+		Body  struct {
+			Storage struct {
+				Value string `json:"value"`
+			} `json:"storage"`
+		} `json:"body"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return "", fmt.Errorf("failed to parse Confluence page: %w", err)
+	}
+	return "# " + page.Title + "\n" + stripHTML(page.Body.Storage.Value), nil
+}
@@ -0,0 +1,147 @@
+// In file: internal/ingest/documents.go
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrDocumentNotFound is returned when an uploaded document does not exist.
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrEmptyDocument is returned when an uploaded document contains no text to embed.
+var ErrEmptyDocument = errors.New("document contains no text")
+
+// uploadSourcePrefix marks the source of vectors ingested through the document API, keeping
+// them apart from documents ingested from a CMS webhook.
+const uploadSourcePrefix = "upload:"
+
+// Document describes a document uploaded through the gateway's document API.
+type Document struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Filename is the name of the uploaded file, or empty for documents sent as text.
+	Filename string `json:"filename,omitempty"`
+	Topic    string `json:"topic"`
+	// Owner is the user who uploaded the document; only they can list or delete it.
+	Owner string `json:"owner"`
+	// Namespace is the tenant the document belongs to, if any.
+	Namespace string    `json:"namespace,omitempty"`
+	Bytes     int       `json:"bytes"`
+	Chunks    int       `json:"chunks"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SourceID is the source recorded in the metadata of the document's vectors, which lets
+// them be deleted together.
+func (d *Document) SourceID() string {
+	return uploadSourcePrefix + d.ID
+}
+
+// DocumentStore keeps track of uploaded documents. The vectors themselves live in the
+// vector store; this is the catalog that lets owners list and delete them.
+type DocumentStore interface {
+	// Save records a document, replacing any document with the same ID.
+	Save(ctx context.Context, doc *Document) error
+	// Get returns a document, or ErrDocumentNotFound.
+	Get(ctx context.Context, id string) (*Document, error)
+	// List returns a user's documents, newest first.
+	List(ctx context.Context, owner string) ([]Document, error)
+	// Delete removes a document from the catalog.
+	Delete(ctx context.Context, id string) error
+}
+
+// RedisDocumentStore keeps each document as a JSON value and indexes them by owner.
+type RedisDocumentStore struct {
+	rdb *redis.Client
+}
+
+// Statically verify that RedisDocumentStore implements the DocumentStore interface.
+var _ DocumentStore = (*RedisDocumentStore)(nil)
+
+// NewRedisDocumentStore creates a document store backed by Redis.
+func NewRedisDocumentStore(rdb *redis.Client) *RedisDocumentStore {
+	return &RedisDocumentStore{rdb: rdb}
+}
+
+func (s *RedisDocumentStore) getDocumentKey(id string) string {
+	return fmt.Sprintf("document:%s", id)
+}
+
+func (s *RedisDocumentStore) getOwnerIndexKey(owner string) string {
+	return fmt.Sprintf("user:%s:documents", owner)
+}
+
+// Save records a document and adds it to its owner's index.
+func (s *RedisDocumentStore) Save(ctx context.Context, doc *Document) error {
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, s.getDocumentKey(doc.ID), encoded, 0)
+	pipe.ZAdd(ctx, s.getOwnerIndexKey(doc.Owner), redis.Z{Score: float64(doc.CreatedAt.Unix()), Member: doc.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save document %s: %w", doc.ID, err)
+	}
+	return nil
+}
+
+// Get returns a document.
+func (s *RedisDocumentStore) Get(ctx context.Context, id string) (*Document, error) {
+	data, err := s.rdb.Get(ctx, s.getDocumentKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document %s: %w", id, err)
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("corrupt document %s: %w", id, err)
+	}
+	return &doc, nil
+}
+
+// List returns a user's documents, newest first. Index entries of documents that no longer
+// exist are pruned as they are encountered.
+func (s *RedisDocumentStore) List(ctx context.Context, owner string) ([]Document, error) {
+	indexKey := s.getOwnerIndexKey(owner)
+	ids, err := s.rdb.ZRevRange(ctx, indexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents for user %s: %w", owner, err)
+	}
+	documents := make([]Document, 0, len(ids))
+	for _, id := range ids {
+		doc, err := s.Get(ctx, id)
+		if errors.Is(err, ErrDocumentNotFound) {
+			s.rdb.ZRem(ctx, indexKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, *doc)
+	}
+	return documents, nil
+}
+
+// Delete removes a document and its index entry.
+func (s *RedisDocumentStore) Delete(ctx context.Context, id string) error {
+	doc, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, s.getDocumentKey(id))
+	pipe.ZRem(ctx, s.getOwnerIndexKey(doc.Owner), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// In file: internal/ingest/docx.go
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterExtractor(".docx", ExtractorFunc(func(data []byte) ([]Page, error) {
+		text, err := ExtractDOCXText(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Page{{Text: text}}, nil
+	}))
+}
+
+// docxMainPart is the part of a DOCX package that holds the document body.
+const docxMainPart = "word/document.xml"
+
+// maxDOCXBodySize bounds the decompressed size of the document body, so a zip bomb cannot
+// exhaust the ingestor's memory.
+const maxDOCXBodySize = 64 << 20
+
+// docxHeadingStyle matches the built-in heading style IDs ("Heading1", "heading 2", ...).
+var docxHeadingStyle = regexp.MustCompile(`(?i)^heading\s*([1-9])$`)
+
+// ExtractDOCXText returns the text of a Word document as Markdown-like plain text, one line
+// per paragraph. Paragraphs styled as headings (or the title) become Markdown headings and
+// list items become bullets, so the chunker can split the document on its headings.
+func ExtractDOCXText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open DOCX package: %w", err)
+	}
+	var part *zip.File
+	for _, f := range archive.File {
+		if f.Name == docxMainPart {
+			part = f
+			break
+		}
+	}
+	if part == nil {
+		return "", errors.New("DOCX package has no " + docxMainPart)
+	}
+	rc, err := part.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", docxMainPart, err)
+	}
+	defer rc.Close()
+
+	var out strings.Builder
+	var paragraph strings.Builder
+	prefix := ""
+	inText := false
+	decoder := xml.NewDecoder(io.LimitReader(rc, maxDOCXBodySize))
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", docxMainPart, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				prefix = ""
+			case "pStyle":
+				prefix = docxParagraphPrefix(docxAttr(t, "val"), prefix)
+			case "numPr":
+				if prefix == "" {
+					prefix = "- "
+				}
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteByte('\t')
+			case "br", "cr":
+				paragraph.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				if text == "" {
+					continue
+				}
+				if strings.HasPrefix(prefix, "#") {
+					// Headings get a blank line before them, so "\n# " boundaries survive for the chunker.
+					out.WriteString("\n")
+				}
+				out.WriteString(prefix + text + "\n")
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		}
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// docxParagraphPrefix returns the Markdown prefix for a paragraph style, keeping current for
+// styles that carry no structure.
+func docxParagraphPrefix(style, current string) string {
+	if strings.EqualFold(style, "Title") {
+		return "# "
+	}
+	if m := docxHeadingStyle.FindStringSubmatch(style); m != nil {
+		level, _ := strconv.Atoi(m[1])
+		return strings.Repeat("#", level) + " "
+	}
+	if strings.HasPrefix(strings.ToLower(style), "list") {
+		return "- "
+	}
+	return current
+}
+
+func docxAttr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+// In file: internal/ingest/extractor.go
+package ingest
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Page is text extracted from a document.
+type Page struct {
+	// Number is the 1-based page number in paged formats such as PDF, or 0.
+	Number int
+	Text   string
+}
+
+// Chunk is a piece of a document ready to be embedded.
+type Chunk struct {
+	Text string
+	// Page is the 1-based page the chunk was taken from, or 0 for documents without pages.
+	Page int
+}
+
+// Extractor turns the raw contents of a file in one format into text. Paged formats return
+// one Page per page; other formats return a single Page. Chunking is left to the caller, so
+// every format is chunked with the options of the topic it belongs to.
+type Extractor interface {
+	Extract(data []byte) ([]Page, error)
+}
+
+// ExtractorFunc adapts an ordinary function to the Extractor interface.
+type ExtractorFunc func(data []byte) ([]Page, error)
+
+// Extract calls f(data).
+func (f ExtractorFunc) Extract(data []byte) ([]Page, error) {
+	return f(data)
+}
+
+// extractors maps a lower-case file extension to the extractor for that format.
+// Each format registers itself from the file that implements it, so supporting a new
+// format is a matter of adding one file.
+var extractors = map[string]Extractor{}
+
+func init() {
+	plainText := ExtractorFunc(func(data []byte) ([]Page, error) {
+		return []Page{{Text: string(data)}}, nil
+	})
+	RegisterExtractor(".md", plainText)
+	RegisterExtractor(".txt", plainText)
+}
+
+// RegisterExtractor makes e the extractor for files with the extension ext (e.g. ".pdf"),
+// replacing any extractor previously registered for it. It is meant to be called from init.
+func RegisterExtractor(ext string, e Extractor) {
+	extractors[strings.ToLower(ext)] = e
+}
+
+// ExtractorFor returns the extractor registered for the extension of path.
+func ExtractorFor(path string) (Extractor, bool) {
+	e, ok := extractors[strings.ToLower(filepath.Ext(path))]
+	return e, ok
+}
+
+// SupportedExtensions returns the registered file extensions in sorted order.
+func SupportedExtensions() []string {
+	exts := make([]string, 0, len(extractors))
+	for ext := range extractors {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
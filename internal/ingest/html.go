@@ -0,0 +1,197 @@
+// In file: internal/ingest/html.go
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func init() {
+	htmlExtractor := ExtractorFunc(func(data []byte) ([]Page, error) {
+		text, err := ExtractHTMLText(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Page{{Text: text}}, nil
+	})
+	RegisterExtractor(".html", htmlExtractor)
+	RegisterExtractor(".htm", htmlExtractor)
+}
+
+// htmlBoilerplate lists elements that carry page chrome or code rather than content.
+var htmlBoilerplate = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Form: true,
+	atom.Button: true, atom.Iframe: true, atom.Svg: true, atom.Canvas: true,
+}
+
+// htmlBoilerplateRoles lists ARIA landmark roles used for the same chrome on generic elements.
+var htmlBoilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "search": true,
+}
+
+// htmlHeadingLevels maps heading elements to their level.
+var htmlHeadingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// htmlBlocks lists elements that start a new line of text.
+var htmlBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Li: true, atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Tr: true, atom.Blockquote: true, atom.Pre: true, atom.Figcaption: true,
+	atom.Br: true, atom.Hr: true,
+}
+
+// ExtractHTMLText returns the readable text of an HTML page as Markdown-like plain text.
+// Navigation, headers, footers, scripts, and similar boilerplate are dropped; when the
+// page marks its content with <main> or <article>, only that part is kept. Headings become
+// Markdown headings so the chunker can split the page on them.
+func ExtractHTMLText(data []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	root := doc
+	if content := findHTMLContent(doc); content != nil {
+		root = content
+	}
+	var w htmlTextWriter
+	w.walk(root)
+	return strings.TrimSpace(w.String()), nil
+}
+
+// findHTMLContent returns the page's <main> element, or its first <article>, if any.
+func findHTMLContent(doc *html.Node) *html.Node {
+	var main, article *html.Node
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.DataAtom == atom.Main && main == nil:
+				main = n
+			case n.DataAtom == atom.Article && article == nil:
+				article = n
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	if main != nil {
+		return main
+	}
+	return article
+}
+
+// htmlTextWriter renders a node tree as text.
+type htmlTextWriter struct {
+	bytes.Buffer
+	pre int
+}
+
+func (w *htmlTextWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+		if htmlBoilerplate[n.DataAtom] || htmlBoilerplateRoles[htmlAttr(n, "role")] || htmlAttr(n, "aria-hidden") == "true" {
+			return
+		}
+	}
+
+	if level, ok := htmlHeadingLevels[n.DataAtom]; ok && n.Type == html.ElementNode {
+		// Headings are written on their own paragraph, so "\n# " boundaries survive for the chunker.
+		w.paragraph()
+		w.WriteString(strings.Repeat("#", level) + " ")
+		w.children(n)
+		w.paragraph()
+		return
+	}
+
+	block := n.Type == html.ElementNode && htmlBlocks[n.DataAtom]
+	if block {
+		w.newline()
+	}
+	switch n.DataAtom {
+	case atom.Li:
+		w.WriteString("- ")
+	case atom.Pre:
+		w.pre++
+		defer func() { w.pre-- }()
+	case atom.Td, atom.Th:
+		if b := w.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+			w.WriteString(" | ")
+		}
+	}
+	w.children(n)
+	if block {
+		w.newline()
+	}
+}
+
+func (w *htmlTextWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+}
+
+// text writes a text node, collapsing whitespace outside <pre>.
+func (w *htmlTextWriter) text(s string) {
+	if w.pre > 0 {
+		w.WriteString(s)
+		return
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			w.space()
+		}
+		return
+	}
+	if s[0] == ' ' || s[0] == '\t' || s[0] == '\n' || s[0] == '\r' {
+		w.space()
+	}
+	w.WriteString(strings.Join(fields, " "))
+	if last := s[len(s)-1]; last == ' ' || last == '\t' || last == '\n' || last == '\r' {
+		w.space()
+	}
+}
+
+func (w *htmlTextWriter) space() {
+	if b := w.Bytes(); len(b) > 0 && b[len(b)-1] != ' ' && b[len(b)-1] != '\n' {
+		w.WriteByte(' ')
+	}
+}
+
+func (w *htmlTextWriter) newline() {
+	b := w.Bytes()
+	trimmed := bytes.TrimRight(b, " ")
+	w.Truncate(len(trimmed))
+	if len(trimmed) > 0 && trimmed[len(trimmed)-1] != '\n' {
+		w.WriteByte('\n')
+	}
+}
+
+// paragraph ends the current line and leaves a blank line after it.
+func (w *htmlTextWriter) paragraph() {
+	w.newline()
+	if b := w.Bytes(); len(b) > 0 && !bytes.HasSuffix(b, []byte("\n\n")) {
+		w.WriteByte('\n')
+	}
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.ToLower(strings.TrimSpace(a.Val))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,926 @@
+// In file: internal/ingest/pdf.go
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// =================================================================================
+// PDF Text Extraction
+// =================================================================================
+// Most enterprise knowledge bases are PDFs. This is a small reader for the part of the
+// format that carries text: indirect objects (including compressed object streams),
+// Flate/ASCII85/ASCIIHex-encoded streams, the page tree, and fonts' ToUnicode maps.
+// It does not render anything: scanned PDFs (images only) and text drawn inside form
+// XObjects yield no text, and encrypted PDFs are rejected.
+// =================================================================================
+
+var (
+	// ErrNotPDF is returned for data that does not start with a PDF header.
+	ErrNotPDF = errors.New("not a PDF document")
+	// ErrEncryptedPDF is returned for password-protected or otherwise encrypted PDFs.
+	ErrEncryptedPDF = errors.New("encrypted PDFs are not supported")
+)
+
+// tjSpaceThreshold is the TJ adjustment, in thousandths of an em, beyond which a gap
+// between two strings is treated as a word break.
+const tjSpaceThreshold = 200
+
+// maxRefDepth bounds how many indirect references are followed for a single value,
+// so a malformed document with a reference cycle cannot hang ingestion.
+const maxRefDepth = 32
+
+// maxPDFStreamSize bounds the decompressed size of a single stream, so a small document
+// cannot inflate into gigabytes.
+const maxPDFStreamSize = 64 << 20
+
+func init() {
+	RegisterExtractor(".pdf", ExtractorFunc(extractPDF))
+}
+
+// extractPDF returns the pages of a PDF. Pages without extractable text are dropped but
+// keep their numbering.
+func extractPDF(data []byte) ([]Page, error) {
+	texts, err := ExtractPDFPages(data)
+	if err != nil {
+		return nil, err
+	}
+	var pages []Page
+	for i, text := range texts {
+		if text != "" {
+			pages = append(pages, Page{Number: i + 1, Text: text})
+		}
+	}
+	return pages, nil
+}
+
+// ExtractPDFPages returns the text of every page of a PDF, in page order.
+func ExtractPDFPages(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return nil, ErrEncryptedPDF
+	}
+	doc := loadPDF(data)
+	catalog := doc.catalog()
+	if catalog == nil {
+		return nil, errors.New("PDF has no document catalog")
+	}
+
+	var pages []string
+	visited := make(map[uintptr]bool)
+	var walk func(node pdfDict, resources pdfDict, depth int)
+	walk = func(node pdfDict, resources pdfDict, depth int) {
+		if node == nil || visited[node.id()] || depth > maxRefDepth {
+			return
+		}
+		visited[node.id()] = true
+		// Resources are inherited from ancestors in the page tree.
+		if own := doc.dict(node["Resources"]); own != nil {
+			resources = own
+		}
+		if kids, ok := doc.resolve(node["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(doc.dict(kid), resources, depth+1)
+			}
+			return
+		}
+		pages = append(pages, doc.pageText(node, resources))
+	}
+	walk(doc.dict(catalog["Pages"]), nil, 0)
+	return pages, nil
+}
+
+// --- Object model ---
+
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfDocument holds every object of a document by object number. Later definitions win,
+// which applies incremental updates appended to the file.
+type pdfDocument struct {
+	objects map[int]any
+	fonts   map[uintptr]*pdfFont
+}
+
+// id identifies a dictionary object, so shared objects can be recognised when revisited.
+func (d pdfDict) id() uintptr {
+	return reflect.ValueOf(d).Pointer()
+}
+
+var objectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// loadPDF scans the file for indirect objects rather than trusting the cross-reference
+// table, which makes it tolerant of the damaged offsets common in real-world files.
+func loadPDF(data []byte) *pdfDocument {
+	doc := &pdfDocument{objects: make(map[int]any), fonts: make(map[uintptr]*pdfFont)}
+	for pos := 0; pos < len(data); {
+		loc := objectHeader.FindIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		num, value, end, err := parseIndirectObject(data, start)
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		doc.objects[num] = value
+		pos = end
+	}
+
+	// Objects packed into object streams are added unless the file defines them directly.
+	direct := make(map[int]bool, len(doc.objects))
+	var objectStreams []*pdfStream
+	for num, value := range doc.objects {
+		direct[num] = true
+		if s, ok := value.(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			objectStreams = append(objectStreams, s)
+		}
+	}
+	for _, s := range objectStreams {
+		for num, value := range doc.unpackObjectStream(s) {
+			if !direct[num] {
+				doc.objects[num] = value
+			}
+		}
+	}
+	return doc
+}
+
+// unpackObjectStream returns the objects stored in an object stream.
+func (d *pdfDocument) unpackObjectStream(s *pdfStream) map[int]any {
+	data, err := d.decodeStream(s)
+	if err != nil {
+		return nil
+	}
+	count, _ := d.resolve(s.dict["N"]).(float64)
+	first, _ := d.resolve(s.dict["First"]).(float64)
+	if int(first) > len(data) {
+		return nil
+	}
+	header := &pdfLexer{data: data[:int(first)]}
+	objects := make(map[int]any, int(count))
+	for i := 0; i < int(count); i++ {
+		num, err1 := header.token()
+		offset, err2 := header.token()
+		n, ok1 := num.(float64)
+		off, ok2 := offset.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			break
+		}
+		start := int(first) + int(off)
+		if start >= len(data) {
+			continue
+		}
+		value, err := (&pdfLexer{data: data, pos: start}).object()
+		if err == nil {
+			objects[int(n)] = value
+		}
+	}
+	return objects
+}
+
+// parseIndirectObject parses "num gen obj ... endobj" at pos and returns the offset after it.
+func parseIndirectObject(data []byte, pos int) (int, any, int, error) {
+	l := &pdfLexer{data: data, pos: pos}
+	num, _ := l.token()
+	_, _ = l.token()
+	if kw, _ := l.token(); kw != pdfKeyword("obj") {
+		return 0, nil, 0, errors.New("malformed object header")
+	}
+	n, ok := num.(float64)
+	if !ok {
+		return 0, nil, 0, errors.New("malformed object number")
+	}
+	value, err := l.object()
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	afterValue := l.pos
+	if kw, _ := l.token(); kw != pdfKeyword("stream") {
+		return int(n), value, afterValue, nil
+	}
+	dict, ok := value.(pdfDict)
+	if !ok {
+		return 0, nil, 0, errors.New("stream without a dictionary")
+	}
+	start := l.pos
+	if strings.HasPrefix(string(data[start:min(start+2, len(data))]), "\r\n") {
+		start += 2
+	} else if start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+	// Use the declared length when it is direct and lands on "endstream"; otherwise search.
+	end := -1
+	if length, ok := dict["Length"].(float64); ok && start+int(length) <= len(data) {
+		tail := data[start+int(length) : min(start+int(length)+32, len(data))]
+		if bytes.HasPrefix(bytes.TrimLeft(tail, "\r\n \t"), []byte("endstream")) {
+			end = start + int(length)
+		}
+	}
+	if end < 0 {
+		idx := bytes.Index(data[start:], []byte("endstream"))
+		if idx < 0 {
+			return 0, nil, 0, errors.New("unterminated stream")
+		}
+		end = start + idx
+		for end > start && (data[end-1] == '\n' || data[end-1] == '\r') {
+			end--
+		}
+	}
+	after := bytes.Index(data[end:], []byte("endstream")) + end + len("endstream")
+	return int(n), &pdfStream{dict: dict, raw: data[start:end]}, after, nil
+}
+
+// resolve follows indirect references until it reaches a direct value.
+func (d *pdfDocument) resolve(v any) any {
+	for i := 0; i < maxRefDepth; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves v and returns it as a dictionary, or nil. A stream yields its dictionary.
+func (d *pdfDocument) dict(v any) pdfDict {
+	switch value := d.resolve(v).(type) {
+	case pdfDict:
+		return value
+	case *pdfStream:
+		return value.dict
+	}
+	return nil
+}
+
+// catalog returns the document catalog, the root of the page tree.
+func (d *pdfDocument) catalog() pdfDict {
+	var catalog pdfDict
+	highest := -1
+	for num, value := range d.objects {
+		if dict, ok := value.(pdfDict); ok && dict["Type"] == pdfName("Catalog") && num > highest {
+			catalog, highest = dict, num
+		}
+	}
+	return catalog
+}
+
+// decodeStream applies a stream's filters.
+func (d *pdfDocument) decodeStream(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case pdfArray:
+		filters = f
+	}
+	data := s.raw
+	for _, f := range filters {
+		name, _ := d.resolve(f).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodeASCIIHex(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s stream: %w", name, err)
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data. Truncated streams are common, so whatever could be
+// decompressed before the error is kept. Streams larger than maxPDFStreamSize are refused.
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxPDFStreamSize+1))
+	if len(out) > maxPDFStreamSize {
+		return nil, fmt.Errorf("stream inflates to more than %d bytes", maxPDFStreamSize)
+	}
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if idx := bytes.Index(data, []byte("~>")); idx >= 0 {
+		data = data[:idx]
+	}
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	if idx := bytes.IndexByte(data, '>'); idx >= 0 {
+		data = data[:idx]
+	}
+	digits := bytes.Map(func(r rune) rune {
+		if isPDFSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, data)
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	return hex.DecodeString(string(digits))
+}
+
+// --- Pages and text ---
+
+// pageText extracts the text drawn by a page's content streams.
+func (d *pdfDocument) pageText(page pdfDict, resources pdfDict) string {
+	var content []byte
+	var streams []any
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		streams = []any{c}
+	case pdfArray:
+		streams = c
+	}
+	for _, ref := range streams {
+		if s, ok := d.resolve(ref).(*pdfStream); ok {
+			if data, err := d.decodeStream(s); err == nil {
+				content = append(append(content, data...), '\n')
+			}
+		}
+	}
+
+	fonts := make(map[pdfName]*pdfFont)
+	for name, ref := range d.dict(resources["Font"]) {
+		fonts[name] = d.font(d.dict(ref))
+	}
+	return interpretContent(content, fonts)
+}
+
+// interpretContent runs the text operators of a content stream and returns the text shown.
+func interpretContent(content []byte, fonts map[pdfName]*pdfFont) string {
+	var out textWriter
+	var operands []any
+	var font *pdfFont
+	lastY, haveY := 0.0, false
+	l := &pdfLexer{data: content}
+	for {
+		tok, err := l.object()
+		if err != nil {
+			break
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok {
+			operands = append(operands, tok)
+			continue
+		}
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = fonts[name]
+				}
+			}
+		case "Tj":
+			if s, ok := lastOperand(operands).(pdfString); ok {
+				out.write(font.decode(s))
+			}
+		case "'", "\"":
+			out.newline()
+			if s, ok := lastOperand(operands).(pdfString); ok {
+				out.write(font.decode(s))
+			}
+		case "TJ":
+			if items, ok := lastOperand(operands).(pdfArray); ok {
+				for _, item := range items {
+					switch v := item.(type) {
+					case pdfString:
+						out.write(font.decode(v))
+					case float64:
+						if v < -tjSpaceThreshold {
+							out.space()
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+					out.newline()
+				}
+			}
+		case "T*":
+			out.newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				if y, ok := operands[len(operands)-1].(float64); ok {
+					if haveY && y != lastY {
+						out.newline()
+					}
+					lastY, haveY = y, true
+				}
+			}
+		case "BI":
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+	return strings.TrimSpace(out.String())
+}
+
+func lastOperand(operands []any) any {
+	if len(operands) == 0 {
+		return nil
+	}
+	return operands[len(operands)-1]
+}
+
+// textWriter assembles extracted text without doubled spaces or blank lines.
+type textWriter struct {
+	strings.Builder
+}
+
+func (w *textWriter) write(s string) {
+	for _, r := range s {
+		if r == '\n' || r == '\r' || r == '\t' {
+			r = ' '
+		}
+		if r == ' ' {
+			w.space()
+			continue
+		}
+		w.WriteRune(r)
+	}
+}
+
+func (w *textWriter) space() {
+	if s := w.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) newline() {
+	if s := w.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		w.WriteByte('\n')
+	}
+}
+
+// --- Fonts ---
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	// composite fonts (Type0) use multi-byte codes that mean nothing without a ToUnicode map.
+	composite bool
+	toUnicode map[uint32]string
+	codeWidth int
+}
+
+// font returns the decoder of a font dictionary, parsing its ToUnicode map once.
+func (d *pdfDocument) font(dict pdfDict) *pdfFont {
+	if dict == nil {
+		return nil
+	}
+	if f, ok := d.fonts[dict.id()]; ok {
+		return f
+	}
+	f := &pdfFont{composite: dict["Subtype"] == pdfName("Type0"), codeWidth: 1}
+	if f.composite {
+		f.codeWidth = 2
+	}
+	if s, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(s); err == nil {
+			f.toUnicode, f.codeWidth = parseToUnicode(data, f.codeWidth)
+		}
+	}
+	d.fonts[dict.id()] = f
+	return f
+}
+
+// decode converts a shown string to text. Simple fonts without a ToUnicode map are read as
+// WinAnsiEncoding, which covers the standard fonts most generators use.
+func (f *pdfFont) decode(s pdfString) string {
+	if f == nil || (f.toUnicode == nil && !f.composite) {
+		return decodeWinAnsi(s)
+	}
+	if f.toUnicode == nil {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i+f.codeWidth <= len(s); i += f.codeWidth {
+		code := codeValue(s[i : i+f.codeWidth])
+		if text, ok := f.toUnicode[code]; ok {
+			b.WriteString(text)
+		} else if f.codeWidth == 1 {
+			b.WriteString(decodeWinAnsi(s[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// parseToUnicode reads the bfchar and bfrange mappings of a ToUnicode CMap and the width
+// of its character codes.
+func parseToUnicode(data []byte, defaultWidth int) (map[uint32]string, int) {
+	cmap := make(map[uint32]string)
+	width := 0
+	var operands []any
+	l := &pdfLexer{data: data}
+	for {
+		tok, err := l.object()
+		if err != nil {
+			break
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok {
+			operands = append(operands, tok)
+			continue
+		}
+		switch op {
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					width = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					if width == 0 {
+						width = len(src)
+					}
+					cmap[codeValue(src)] = decodeUTF16(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || codeValue(hi) < codeValue(lo) || codeValue(hi)-codeValue(lo) > 0xFFFF {
+					continue
+				}
+				if width == 0 {
+					width = len(lo)
+				}
+				start, end := codeValue(lo), codeValue(hi)
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					units := utf16Units(dst)
+					for code := start; code <= end && len(units) > 0; code++ {
+						next := append([]uint16(nil), units...)
+						next[len(next)-1] += uint16(code - start)
+						cmap[code] = string(utf16.Decode(next))
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+uint32(j) <= end {
+							cmap[start+uint32(j)] = decodeUTF16(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	if width == 0 {
+		width = defaultWidth
+	}
+	return cmap, width
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func utf16Units(b []byte) []uint16 {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return units
+}
+
+func decodeUTF16(b []byte) string {
+	return string(utf16.Decode(utf16Units(b)))
+}
+
+// winAnsiHigh maps the WinAnsiEncoding codes 0x80-0x9F that differ from Latin-1.
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ',
+	0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“',
+	0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›',
+	0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+func decodeWinAnsi(s []byte) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case winAnsiHigh[c] != 0:
+			b.WriteRune(winAnsiHigh[c])
+		case c >= 0x20 && c != 0x7F && (c < 0x80 || c >= 0xA0):
+			b.WriteRune(rune(c))
+		case c == '\n' || c == '\r' || c == '\t':
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// --- Lexer ---
+
+// pdfLexer tokenizes PDF objects and content streams.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token returns the next token: a number (float64), pdfString, pdfName, bool, nil, or a
+// pdfKeyword for operators, structural delimiters, and other bare words.
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		return l.hexString(), nil
+	case c == '>':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+			return pdfKeyword(">>"), nil
+		}
+		return pdfKeyword(">"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(c), nil
+	case c == '/':
+		l.pos++
+		return pdfName(l.name()), nil
+	case c == ')':
+		l.pos++
+		return pdfKeyword(")"), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && strings.IndexAny(word[:1], "+-.0123456789") == 0 {
+		return n, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// object returns the next complete object, assembling arrays, dictionaries, and
+// "num gen R" references. Operators and other keywords are returned as pdfKeyword.
+func (l *pdfLexer) object() (any, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "[":
+			arr := pdfArray{}
+			for {
+				item, err := l.object()
+				if err != nil {
+					return nil, err
+				}
+				if item == pdfKeyword("]") {
+					return arr, nil
+				}
+				arr = append(arr, item)
+			}
+		case "<<":
+			dict := pdfDict{}
+			for {
+				key, err := l.object()
+				if err != nil {
+					return nil, err
+				}
+				if key == pdfKeyword(">>") {
+					return dict, nil
+				}
+				value, err := l.object()
+				if err != nil {
+					return nil, err
+				}
+				if name, ok := key.(pdfName); ok {
+					dict[name] = value
+				}
+			}
+		}
+	case float64:
+		// Look ahead for an indirect reference: "num gen R".
+		if t == float64(int(t)) && t >= 0 {
+			save := l.pos
+			gen, err1 := l.token()
+			kw, err2 := l.token()
+			if g, ok := gen.(float64); ok && err1 == nil && err2 == nil && kw == pdfKeyword("R") {
+				return pdfRef{num: int(t), gen: int(g)}, nil
+			}
+			l.pos = save
+		}
+	}
+	return tok, nil
+}
+
+func (l *pdfLexer) name() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	raw := string(l.data[start:l.pos])
+	if !strings.Contains(raw, "#") {
+		return raw
+	}
+	// Names may escape bytes as #xx.
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if v, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(raw[i])
+	}
+	return b.String()
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // Opening parenthesis.
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // Opening angle bracket.
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		end = len(l.data) - l.pos
+	}
+	raw := l.data[l.pos : l.pos+end]
+	l.pos += end + 1
+	decoded, _ := decodeASCIIHex(raw)
+	return decoded
+}
+
+// skipInlineImage moves past the binary data of an inline image (BI ... ID <data> EI).
+func (l *pdfLexer) skipInlineImage() {
+	idx := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if idx < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += idx + 2
+	for l.pos < len(l.data) {
+		idx := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if idx < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		at := l.pos + idx
+		l.pos = at + 2
+		before := at == 0 || isPDFSpace(l.data[at-1])
+		after := l.pos >= len(l.data) || isPDFSpace(l.data[l.pos])
+		if before && after {
+			return
+		}
+	}
+}
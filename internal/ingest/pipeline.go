@@ -0,0 +1,214 @@
+// In file: internal/ingest/pipeline.go
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dileep-u-k/llm-gateway/internal/llm"
+)
+
+// =================================================================================
+// Core Data Structures
+// =================================================================================
+
+// ChangeAction describes what happened to a document in the upstream CMS.
+type ChangeAction string
+
+const (
+	ActionUpsert ChangeAction = "upsert"
+	ActionDelete ChangeAction = "delete"
+)
+
+// ErrQueueFull is returned by Enqueue when the ingestion backlog is at capacity.
+var ErrQueueFull = errors.New("ingestion queue is full")
+
+// Change is a single document change reported by a CMS webhook.
+type Change struct {
+	// Connector is the name of the connector that produced this change (e.g., "github").
+	Connector string `json:"connector"`
+	// SourceID is a stable identifier for the document. It is stored as vector metadata
+	// so that all chunks of a document can be replaced or deleted together.
+	SourceID string `json:"source_id"`
+	// Topic is the knowledge-base topic the document's chunks are filed under.
+	Topic string `json:"topic"`
+	// Title is a human-readable name for the document, used only for logging.
+	Title string `json:"title,omitempty"`
+	// Action indicates whether the document should be (re-)ingested or removed.
+	Action ChangeAction `json:"action"`
+	// Ref holds connector-specific data needed to fetch the content (a commit SHA and path, a page ID, ...).
+	Ref string `json:"-"`
+}
+
+// Connector adapts a content management system to the ingestion pipeline.
+// Each implementation knows how to authenticate the CMS's webhooks, translate
+// their payloads into Changes, and fetch the current content of a changed document.
+type Connector interface {
+	// Name returns the connector's identifier, which is also the webhook path segment.
+	Name() string
+	// VerifySignature authenticates an incoming webhook delivery.
+	VerifySignature(headers http.Header, body []byte) error
+	// ParseWebhook converts a webhook payload into the list of changed documents.
+	ParseWebhook(headers http.Header, body []byte) ([]Change, error)
+	// FetchContent retrieves the latest plain-text content of a changed document.
+	FetchContent(ctx context.Context, change Change) (string, error)
+}
+
+// =================================================================================
+// Pipeline Service
+// =================================================================================
+
+// Pipeline performs incremental ingestion of CMS changes into the RAG knowledge base.
+// Changes are queued and processed by background workers, so webhook deliveries are
+// acknowledged immediately and never block on embedding or vector store calls.
+type Pipeline struct {
+	ragService *llm.RAGService
+	chunking   ChunkingConfig
+	connectors map[string]Connector
+	queue      chan Change
+}
+
+// NewPipeline creates a new pipeline with a bounded queue and the given connectors.
+// Documents are chunked with the options of their topic, exactly as the offline ingestor does.
+func NewPipeline(ragService *llm.RAGService, queueSize int, chunking ChunkingConfig, connectors ...Connector) *Pipeline {
+	p := &Pipeline{
+		ragService: ragService,
+		chunking:   chunking,
+		connectors: make(map[string]Connector),
+		queue:      make(chan Change, queueSize),
+	}
+	for _, c := range connectors {
+		p.connectors[c.Name()] = c
+	}
+	return p
+}
+
+// Connector returns the registered connector with the given name.
+func (p *Pipeline) Connector(name string) (Connector, bool) {
+	c, ok := p.connectors[name]
+	return c, ok
+}
+
+// Enqueue adds changes to the ingestion queue without blocking.
+// It returns the number of changes accepted and ErrQueueFull if any had to be dropped.
+func (p *Pipeline) Enqueue(changes []Change) (int, error) {
+	for i, change := range changes {
+		select {
+		case p.queue <- change:
+		default:
+			return i, ErrQueueFull
+		}
+	}
+	return len(changes), nil
+}
+
+// Start launches the background workers. They stop when the context is cancelled.
+func (p *Pipeline) Start(ctx context.Context, workers int) {
+	for w := 0; w < workers; w++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case change := <-p.queue:
+					if err := p.process(ctx, change); err != nil {
+						slog.ErrorContext(ctx, "Incremental ingestion failed", "source_id", change.SourceID, "connector", change.Connector, "error", err)
+					}
+				}
+			}
+		}()
+	}
+	slog.InfoContext(ctx, "Ingestion pipeline started", "workers", workers, "connectors", len(p.connectors))
+}
+
+// process applies a single change to the vector store.
+// Existing chunks of the document are always removed first, so an edited document
+// never leaves orphaned chunks from its previous revision behind.
+func (p *Pipeline) process(ctx context.Context, change Change) error {
+	if err := p.ragService.DeleteVectorsBySource(ctx, change.SourceID); err != nil {
+		return fmt.Errorf("failed to remove previous chunks: %w", err)
+	}
+	if change.Action == ActionDelete {
+		slog.InfoContext(ctx, "Removed document", "source_id", change.SourceID, "topic", change.Topic)
+		return nil
+	}
+
+	connector, ok := p.connectors[change.Connector]
+	if !ok {
+		return fmt.Errorf("no connector registered for '%s'", change.Connector)
+	}
+	content, err := connector.FetchContent(ctx, change)
+	if err != nil {
+		return fmt.Errorf("failed to fetch content: %w", err)
+	}
+	chunks := p.chunking.ChunkerFor(change.Topic).Split(content)
+	if len(chunks) == 0 {
+		slog.InfoContext(ctx, "No chunks found for document, skipping", "source_id", change.SourceID)
+		return nil
+	}
+
+	vectors, err := p.ragService.GenerateVectorsForSource(ctx, chunks, change.Topic, change.SourceID)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if err := p.ragService.UpsertVectors(ctx, vectors); err != nil {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+	slog.InfoContext(ctx, "Ingested document", "chunks", len(vectors), "title", change.Title, "source_id", change.SourceID, "topic", change.Topic)
+	return nil
+}
+
+// IngestDocument chunks, embeds, and upserts an uploaded document right away, rather than
+// through the queue, so the caller learns whether it succeeded. Its vectors carry the
+// document's source, owner, and namespace in their metadata, and doc.Chunks is set to
+// their number. A document without text yields ErrEmptyDocument.
+func (p *Pipeline) IngestDocument(ctx context.Context, doc *Document, pages []Page) error {
+	chunks := p.chunking.ChunkerFor(doc.Topic).ChunkPages(pages)
+	if len(chunks) == 0 {
+		return ErrEmptyDocument
+	}
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+
+	vectors, err := p.ragService.GenerateVectorsForSource(ctx, texts, doc.Topic, doc.SourceID())
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	for i, chunk := range chunks {
+		metadata := vectors[i].Metadata
+		metadata["document"] = doc.Title
+		metadata["owner"] = doc.Owner
+		if doc.Namespace != "" {
+			metadata["namespace"] = doc.Namespace
+		}
+		if chunk.Page > 0 {
+			metadata["page"] = chunk.Page
+		}
+	}
+	if err := p.ragService.UpsertVectors(ctx, vectors); err != nil {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+	doc.Chunks = len(vectors)
+	slog.InfoContext(ctx, "Ingested uploaded document", "chunks", len(vectors), "title", doc.Title, "document_id", doc.ID, "topic", doc.Topic)
+	return nil
+}
+
+// RemoveDocument deletes every vector of an uploaded document.
+func (p *Pipeline) RemoveDocument(ctx context.Context, doc *Document) error {
+	if err := p.ragService.DeleteVectorsBySource(ctx, doc.SourceID()); err != nil {
+		return fmt.Errorf("failed to remove chunks: %w", err)
+	}
+	slog.InfoContext(ctx, "Removed uploaded document", "document_id", doc.ID, "topic", doc.Topic)
+	return nil
+}
+
+// isSupportedTextFile reports whether a repository file should be ingested.
+func isSupportedTextFile(path string) bool {
+	return strings.HasSuffix(path, ".md") || strings.HasSuffix(path, ".txt")
+}
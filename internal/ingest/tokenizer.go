@@ -0,0 +1,112 @@
+// In file: internal/ingest/tokenizer.go
+package ingest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// subwordRunes is the longest run of letters counted as one token. Common words are single
+	// tokens in BPE vocabularies; longer and rarer words are split into pieces of about this size.
+	subwordRunes = 6
+	// maxDigitsPerToken matches the digit grouping of current BPE tokenizers.
+	maxDigitsPerToken = 3
+	// maxSymbolsPerToken bounds runs of punctuation such as "===" or "-->".
+	maxSymbolsPerToken = 3
+)
+
+// Tokenizer splits text into tokens. Joining the tokens must give back the original text,
+// so chunks can be cut at token boundaries without losing characters.
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// DefaultTokenizer approximates the BPE tokenizers of current chat and embedding models.
+// It reproduces their pre-tokenization (words carry their leading space, digits are grouped
+// in threes, punctuation and whitespace form their own tokens) and splits long words into
+// sub-word pieces. That tracks real token counts far more closely than characters/4,
+// especially for code, numbers, and non-Latin scripts.
+var DefaultTokenizer Tokenizer = approxTokenizer{}
+
+type approxTokenizer struct{}
+
+var _ Tokenizer = approxTokenizer{}
+
+// Tokenize implements Tokenizer.
+func (approxTokenizer) Tokenize(text string) []string {
+	var tokens []string
+	for i := 0; i < len(text); {
+		start := i
+		r, size := utf8.DecodeRuneInString(text[i:])
+		// A single space belongs to the word or punctuation that follows it.
+		if r == ' ' && i+1 < len(text) {
+			if next, nextSize := utf8.DecodeRuneInString(text[i+1:]); !unicode.IsSpace(next) {
+				i++
+				r, size = next, nextSize
+			}
+		}
+		switch {
+		case isIdeograph(r):
+			i += size
+		case isWordRune(r):
+			i = scanRun(text, i, subwordRunes, func(r rune) bool { return isWordRune(r) && !isIdeograph(r) })
+		case unicode.IsNumber(r):
+			i = scanRun(text, i, maxDigitsPerToken, unicode.IsNumber)
+		case unicode.IsSpace(r):
+			i = scanRun(text, i, len(text), unicode.IsSpace)
+			// Leave the last space of a longer run to the token that follows it.
+			if i < len(text) && i-start > 1 && text[i-1] == ' ' {
+				i--
+			}
+		default:
+			i = scanRun(text, i, maxSymbolsPerToken, func(r rune) bool {
+				return !unicode.IsSpace(r) && !isWordRune(r) && !unicode.IsNumber(r)
+			})
+		}
+		tokens = append(tokens, text[start:i])
+	}
+	return tokens
+}
+
+// scanRun advances from i over at most limit runes satisfying match and returns the new offset.
+// The rune at i is always consumed.
+func scanRun(text string, i, limit int, match func(rune) bool) int {
+	_, size := utf8.DecodeRuneInString(text[i:])
+	i += size
+	for n := 1; n < limit && i < len(text); n++ {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !match(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// isIdeograph reports whether r belongs to a script that tokenizers encode about one
+// character per token.
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// CountTokens returns the number of tokens in text according to DefaultTokenizer, the
+// measure chunk sizes are configured in.
+func CountTokens(text string) int {
+	return countTokens(DefaultTokenizer, text)
+}
+
+// countTokens returns the number of tokens in text.
+func countTokens(t Tokenizer, text string) int {
+	return len(t.Tokenize(text))
+}
+
+// joinTokens reassembles a run of tokens into text.
+func joinTokens(tokens []string) string {
+	return strings.Join(tokens, "")
+}
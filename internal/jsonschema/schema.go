@@ -0,0 +1,372 @@
+// In file: internal/jsonschema/schema.go
+
+// Package jsonschema validates JSON values against the commonly used subset of JSON Schema:
+// type, enum, const, properties, required, additionalProperties, items, the numeric, string,
+// and array bounds, pattern, and the allOf/anyOf/oneOf combinators. Other keywords, such as
+// $ref and format, are accepted but not enforced.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	Types                []string
+	Enum                 []interface{}
+	Const                *interface{}
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties *Schema
+	NoAdditional         bool
+	Items                *Schema
+	Minimum, Maximum     *float64
+	ExclusiveMinimum     *float64
+	ExclusiveMaximum     *float64
+	MinLength, MaxLength *int
+	MinItems, MaxItems   *int
+	Pattern              *regexp.Regexp
+	AllOf, AnyOf, OneOf  []*Schema
+}
+
+// Compile parses a JSON Schema document.
+func Compile(raw []byte) (*Schema, error) {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	return compile(doc, "$")
+}
+
+func compile(doc interface{}, path string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		// true accepts everything; false accepts nothing.
+		if b {
+			return &Schema{}, nil
+		}
+		return &Schema{Enum: []interface{}{}}, nil
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: a schema must be an object or a boolean", path)
+	}
+
+	s := &Schema{}
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.Types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s.type: type names must be strings", path)
+			}
+			s.Types = append(s.Types, name)
+		}
+	default:
+		return nil, fmt.Errorf("%s.type: must be a string or an array of strings", path)
+	}
+	if enum, ok := m["enum"].([]interface{}); ok {
+		s.Enum = enum
+	}
+	if c, ok := m["const"]; ok {
+		s.Const = &c
+	}
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		s.Properties = make(map[string]*Schema, len(props))
+		for name, sub := range props {
+			compiled, err := compile(sub, path+".properties."+name)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[name] = compiled
+		}
+	}
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, v := range required {
+			if name, ok := v.(string); ok {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	switch ap := m["additionalProperties"].(type) {
+	case bool:
+		s.NoAdditional = !ap
+	case map[string]interface{}:
+		compiled, err := compile(ap, path+".additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+		s.AdditionalProperties = compiled
+	}
+	if items, ok := m["items"]; ok {
+		compiled, err := compile(items, path+".items")
+		if err != nil {
+			return nil, err
+		}
+		s.Items = compiled
+	}
+	s.Minimum = number(m, "minimum")
+	s.Maximum = number(m, "maximum")
+	s.ExclusiveMinimum = number(m, "exclusiveMinimum")
+	s.ExclusiveMaximum = number(m, "exclusiveMaximum")
+	s.MinLength = count(m, "minLength")
+	s.MaxLength = count(m, "maxLength")
+	s.MinItems = count(m, "minItems")
+	s.MaxItems = count(m, "maxItems")
+	if pattern, ok := m["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s.pattern: %w", path, err)
+		}
+		s.Pattern = re
+	}
+	for keyword, target := range map[string]*[]*Schema{"allOf": &s.AllOf, "anyOf": &s.AnyOf, "oneOf": &s.OneOf} {
+		subs, ok := m[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, sub := range subs {
+			compiled, err := compile(sub, fmt.Sprintf("%s.%s[%d]", path, keyword, i))
+			if err != nil {
+				return nil, err
+			}
+			*target = append(*target, compiled)
+		}
+	}
+	return s, nil
+}
+
+func number(m map[string]interface{}, key string) *float64 {
+	if f, ok := m[key].(float64); ok {
+		return &f
+	}
+	return nil
+}
+
+func count(m map[string]interface{}, key string) *int {
+	if f, ok := m[key].(float64); ok {
+		n := int(f)
+		return &n
+	}
+	return nil
+}
+
+// ValidateJSON parses a JSON document and validates it. It returns one message per problem
+// found, each prefixed with the path of the offending value.
+func (s *Schema) ValidateJSON(doc string) []string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(doc), &value); err != nil {
+		return []string{"$: not valid JSON: " + err.Error()}
+	}
+	return s.Validate(value)
+}
+
+// Validate validates a decoded JSON value.
+func (s *Schema) Validate(value interface{}) []string {
+	var problems []string
+	s.validate(value, "$", &problems)
+	return problems
+}
+
+func (s *Schema) validate(value interface{}, path string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Types) > 0 && !matchesAnyType(value, s.Types) {
+		report("expected %s, got %s", strings.Join(s.Types, " or "), typeOf(value))
+		return
+	}
+	if s.Enum != nil && !contains(s.Enum, value) {
+		report("value is not one of the allowed values")
+	}
+	if s.Const != nil && !reflect.DeepEqual(*s.Const, value) {
+		report("value does not equal the required constant")
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				report("missing required property '%s'", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := path + "." + name
+			if sub, ok := s.Properties[name]; ok {
+				sub.validate(v[name], child, problems)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(v[name], child, problems)
+			} else if s.NoAdditional {
+				report("unexpected property '%s'", name)
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			report("expected at least %d items, got %d", *s.MinItems, len(v))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			report("expected at most %d items, got %d", *s.MaxItems, len(v))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			report("expected at least %d characters, got %d", *s.MinLength, length)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			report("expected at most %d characters, got %d", *s.MaxLength, length)
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(v) {
+			report("does not match the pattern '%s'", s.Pattern.String())
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			report("must be at least %g", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			report("must be at most %g", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+			report("must be greater than %g", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+			report("must be less than %g", *s.ExclusiveMaximum)
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		sub.validate(value, path, problems)
+	}
+	if len(s.AnyOf) > 0 && countMatches(s.AnyOf, value) == 0 {
+		report("does not match any of the allowed schemas")
+	}
+	if len(s.OneOf) > 0 {
+		if n := countMatches(s.OneOf, value); n != 1 {
+			report("must match exactly one of the allowed schemas, matches %d", n)
+		}
+	}
+}
+
+func countMatches(schemas []*Schema, value interface{}) int {
+	n := 0
+	for _, sub := range schemas {
+		if len(sub.Validate(value)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(value interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "unknown"
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNoJSON is returned by ExtractJSON when a text contains no JSON value.
+var ErrNoJSON = errors.New("no JSON value found")
+
+// ExtractJSON returns the JSON value in a model's answer. Models often wrap JSON in a
+// Markdown code fence or surround it with a sentence; both are stripped.
+func ExtractJSON(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+			text = text[newline+1:]
+		}
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+	}
+	if json.Valid([]byte(text)) {
+		return text, nil
+	}
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return "", ErrNoJSON
+	}
+	closing := byte('}')
+	if text[start] == '[' {
+		closing = ']'
+	}
+	end := strings.LastIndexByte(text, closing)
+	if end <= start || !json.Valid([]byte(text[start:end+1])) {
+		return "", ErrNoJSON
+	}
+	return text[start : end+1], nil
+}
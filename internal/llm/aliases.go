@@ -0,0 +1,100 @@
+// In file: internal/llm/aliases.go
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ModelAlias maps a model name that clients may send to the model that serves it. Aliases
+// give clients stable names ("fast") and keep retired model IDs working after a provider
+// withdraws them.
+type ModelAlias struct {
+	// Target is the model ID requests for the alias are sent to.
+	Target string `yaml:"target"`
+	// Deprecated marks the alias as a retired name; responses then carry a warning so that
+	// clients can move to the target before the alias is removed.
+	Deprecated bool `yaml:"deprecated"`
+	// Message is added to the deprecation warning, e.g. the date the alias will be removed.
+	Message string `yaml:"message"`
+}
+
+// providerNames maps the provider names that other gateways put in front of model IDs, as in
+// LiteLLM's "anthropic/claude-3-5-sonnet" or OpenRouter's "mistralai/mistral-large", to the
+// gateway's providers.
+var providerNames = map[string]string{
+	"openai":    "openai",
+	"anthropic": "anthropic",
+	"google":    "google",
+	"gemini":    "google",
+	"mistral":   "mistral",
+	"mistralai": "mistral",
+}
+
+// StripProviderPrefix returns a model name without its provider prefix, "gpt-4o" for
+// "openai/gpt-4o", when the prefix names the provider of the model or alias that follows it.
+// Other names, including configured model IDs that contain a slash, are returned unchanged.
+func (c *RouterConfig) StripProviderPrefix(name string) string {
+	prefix, rest, ok := strings.Cut(name, "/")
+	if !ok {
+		return name
+	}
+	if _, configured := c.Models[name]; configured {
+		return name
+	}
+	provider, known := providerNames[strings.ToLower(prefix)]
+	if !known {
+		return name
+	}
+	target := rest
+	if alias, ok := c.Aliases[rest]; ok {
+		target = alias.Target
+	}
+	if c.ProviderOf(target) != provider {
+		return name
+	}
+	return rest
+}
+
+// ResolveModel returns the model that serves the given name and, if the name is an alias,
+// the alias it was resolved through. A provider prefix is stripped first, so
+// "openai/gpt-4o" resolves to "gpt-4o". Other names are returned unchanged.
+func (c *RouterConfig) ResolveModel(name string) (string, *ModelAlias) {
+	name = c.StripProviderPrefix(name)
+	alias, ok := c.Aliases[name]
+	if !ok {
+		return name, nil
+	}
+	return alias.Target, &alias
+}
+
+// ValidateAliases checks that every alias points at a model rather than at another alias or
+// at nothing, and that no alias hides a model configured under the same name.
+func (c *RouterConfig) ValidateAliases() error {
+	names := make([]string, 0, len(c.Aliases))
+	for name := range c.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		alias := c.Aliases[name]
+		switch {
+		case alias.Target == "":
+			errs = append(errs, fmt.Errorf("alias %s has no target", name))
+		case alias.Target == name:
+			errs = append(errs, fmt.Errorf("alias %s points at itself", name))
+		default:
+			if _, ok := c.Aliases[alias.Target]; ok {
+				errs = append(errs, fmt.Errorf("alias %s points at another alias (%s); point it at a model", name, alias.Target))
+			}
+		}
+		if _, ok := c.Models[name]; ok {
+			errs = append(errs, fmt.Errorf("alias %s has the same name as a configured model", name))
+		}
+	}
+	return errors.Join(errs...)
+}
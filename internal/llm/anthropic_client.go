@@ -9,10 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
 
 	"github.com/dileep-u-k/llm-gateway/internal/api"
 	"github.com/dileep-u-k/llm-gateway/internal/tools"
@@ -22,24 +22,71 @@ const (
 	anthropicAPIURL  = "https://api.anthropic.com/v1/messages"
 	anthropicVersion = "2023-06-01"
 	defaultMaxTokens = 4096
+
+	// anthropicFormatTool is the tool Anthropic is made to call when the answer must be JSON;
+	// its input is the answer.
+	anthropicFormatTool = "structured_response"
+
+	// anthropicModelsURL is the model list; a model is fetched by appending its ID.
+	anthropicModelsURL = "https://api.anthropic.com/v1/models/"
+	// anthropicCountTokensURL counts the input tokens of a messages request.
+	anthropicCountTokensURL = "https://api.anthropic.com/v1/messages/count_tokens"
 )
 
 // --- API Data Structures ---
 
+// anthropicUsage is Anthropic's token usage. Its input tokens leave out those read from or
+// written to the prompt cache.
 type anthropicUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// promptUsage returns the usage of the prompt, cached parts included.
+func (u anthropicUsage) promptUsage() api.Usage {
+	return api.Usage{
+		PromptTokens:     u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens,
+		CachedTokens:     u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
 }
 
 type anthropicRequest struct {
-	Model       string             `json:"model"`
-	Messages    []anthropicMessage `json:"messages"`
-	System      string             `json:"system,omitempty"`
-	Tools       []anthropicTool    `json:"tools,omitempty"`
-	MaxTokens   int                `json:"max_tokens"`
-	Stream      bool               `json:"stream"`
-	Temperature *float32           `json:"temperature,omitempty"`
+	Model         string                 `json:"model"`
+	Messages      []anthropicMessage     `json:"messages"`
+	System        []anthropicSystemBlock `json:"system,omitempty"`
+	Tools         []anthropicTool        `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice   `json:"tool_choice,omitempty"`
+	MaxTokens     int                    `json:"max_tokens"`
+	Stream        bool                   `json:"stream"`
+	Temperature   *float32               `json:"temperature,omitempty"`
+	StopSequences []string               `json:"stop_sequences,omitempty"`
+	TopK          *int                   `json:"top_k,omitempty"`
+	Thinking      *anthropicThinking     `json:"thinking,omitempty"`
+}
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// anthropicSystemBlock is a block of the system prompt.
+type anthropicSystemBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicCacheControl marks the end of a prompt prefix for Anthropic to cache. Everything
+// before the mark, tools included, is read from the cache by later requests sharing it.
+type anthropicCacheControl struct {
+	Type string `json:"type"`
 }
+
+// ephemeralCache is the cache control of Anthropic's only (five-minute) cache.
+var ephemeralCache = &anthropicCacheControl{Type: "ephemeral"}
+
 type anthropicMessage struct {
 	Role    string      `json:"role"`
 	Content interface{} `json:"content"`
@@ -49,6 +96,10 @@ type anthropicTool struct {
 	Description string      `json:"description"`
 	InputSchema interface{} `json:"input_schema"`
 }
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
 type anthropicContentBlock struct {
 	Type      string          `json:"type"`
 	Text      string          `json:"text,omitempty"`
@@ -57,36 +108,51 @@ type anthropicContentBlock struct {
 	Input     json.RawMessage `json:"input,omitempty"`
 	Content   string          `json:"content,omitempty"`
 	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	// CacheControl marks the end of a cacheable prefix of the conversation.
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
 }
 type anthropicResponse struct {
-	Content []anthropicContentBlock `json:"content"`
-	Usage   anthropicUsage          `json:"usage"`
+	Content    []anthropicContentBlock `json:"content"`
+	Usage      anthropicUsage          `json:"usage"`
+	StopReason string                  `json:"stop_reason"`
 }
 type anthropicStreamEvent struct {
 	Type  string          `json:"type"`
 	Delta json.RawMessage `json:"delta"`
-	Usage anthropicUsage  `json:"usage"`
+	// Usage is set on message_delta events and carries the output token count so far.
+	Usage anthropicUsage `json:"usage"`
+	// Message is set on the message_start event and carries the input token count.
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+}
+type anthropicStreamMessageDelta struct {
+	StopReason string `json:"stop_reason"`
 }
 type anthropicStreamTextDelta struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Thinking string `json:"thinking"`
 }
 
 // --- Main Client ---
 type AnthropicClient struct {
 	apiKey     string
 	httpClient *http.Client
+	policy     RequestPolicy
 }
 
 var _ LLMClient = (*AnthropicClient)(nil)
 
-func NewAnthropicClient(apiKey string) (*AnthropicClient, error) {
+func NewAnthropicClient(apiKey string, policy RequestPolicy, transport http.RoundTripper) (*AnthropicClient, error) {
 	if apiKey == "" {
 		return nil, errors.New("anthropic API key cannot be empty")
 	}
 	return &AnthropicClient{
 		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: defaultTimeout},
+		httpClient: &http.Client{Timeout: policy.Timeout, Transport: clientTransport(transport)},
+		policy:     policy,
 	}, nil
 }
 
@@ -95,11 +161,19 @@ func (c *AnthropicClient) Generate(ctx context.Context, messages []Message, conf
 	if err != nil {
 		return nil, fmt.Errorf("failed to build anthropic request payload: %w", err)
 	}
-	respBody, err := c.doRequest(ctx, payload)
+	respBody, deprecation, err := c.doRequest(ctx, payload, config.Model)
 	if err != nil {
 		return nil, err
 	}
-	return parseAnthropicResponse(respBody)
+	result, err := parseAnthropicResponse(respBody)
+	if err != nil {
+		return nil, err
+	}
+	if config.ResponseFormat.isJSON() {
+		takeFormatToolAnswer(result)
+	}
+	result.Deprecation = deprecation
+	return result, nil
 }
 
 func (c *AnthropicClient) GenerateStream(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (<-chan *StreamingResult, error) {
@@ -107,12 +181,12 @@ func (c *AnthropicClient) GenerateStream(ctx context.Context, messages []Message
 	if err != nil {
 		return nil, fmt.Errorf("failed to build anthropic stream payload: %w", err)
 	}
-	respBody, err := c.doRequestStream(ctx, payload)
+	respBody, deprecation, err := c.doRequestStream(ctx, payload, config.Model)
 	if err != nil {
 		return nil, err
 	}
-	outChan := make(chan *StreamingResult)
-	go c.processStream(respBody, outChan)
+	outChan := newStreamChannel(config)
+	go c.processStream(respBody, deprecation, outChan)
 	return outChan, nil
 }
 
@@ -125,17 +199,42 @@ func (c *AnthropicClient) buildRequestPayload(messages []Message, config *Genera
 	}
 
 	req := anthropicRequest{
-		Model:       config.Model,
-		Messages:    anthropicMsgs,
-		System:      systemPrompt,
-		Tools:       anthropicTools,
-		MaxTokens:   defaultMaxTokens,
-		Stream:      stream,
-		Temperature: config.Temperature,
+		Model:         config.Model,
+		Messages:      anthropicMsgs,
+		System:        anthropicSystem(systemPrompt),
+		Tools:         anthropicTools,
+		MaxTokens:     defaultMaxTokens,
+		Stream:        stream,
+		Temperature:   config.Temperature,
+		StopSequences: config.Stop,
+		TopK:          config.TopK,
 	}
 	if config.MaxTokens > 0 {
 		req.MaxTokens = config.MaxTokens
 	}
+	markHistoryCacheable(req.Messages)
+	// Anthropic has no JSON mode, so a JSON answer is obtained by forcing a tool whose input
+	// schema is the answer's. JSONMode alone is left to the prompt, as its answer need not be
+	// an object. With other tools present, the model must call one of them or answer.
+	if format := config.ResponseFormat; format.isJSON() {
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        anthropicFormatTool,
+			Description: "Give the final answer as this tool's input.",
+			InputSchema: format.schema(),
+		})
+		req.ToolChoice = &anthropicToolChoice{Type: "tool", Name: anthropicFormatTool}
+		if len(anthropicTools) > 0 {
+			req.ToolChoice = &anthropicToolChoice{Type: "any"}
+		}
+	}
+	// Extended thinking cannot be combined with a forced tool, so a JSON answer goes without.
+	// Its budget counts towards max_tokens, so it is added to the tokens left for the answer,
+	// and it rules out setting the temperature or top_k.
+	if budget := config.thinkingBudget(); budget > 0 && req.ToolChoice == nil {
+		req.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		req.MaxTokens += budget
+		req.Temperature, req.TopK = nil, nil
+	}
 	payloadBytes, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
@@ -148,7 +247,11 @@ func toAnthropicMessages(messages []Message) (string, []anthropicMessage) {
 	var anthropicMsgs []anthropicMessage
 	for _, msg := range messages {
 		if msg.Role == RoleSystem {
-			systemPrompt = msg.Content
+			// Anthropic takes a single top-level system prompt, so multiple system messages are joined.
+			if systemPrompt != "" {
+				systemPrompt += "\n\n"
+			}
+			systemPrompt += msg.Content
 			continue
 		}
 		aMsg := anthropicMessage{Role: string(msg.Role)}
@@ -167,6 +270,34 @@ func toAnthropicMessages(messages []Message) (string, []anthropicMessage) {
 	return systemPrompt, anthropicMsgs
 }
 
+// anthropicSystem turns the system prompt into a single block marked for caching: the system
+// prompt, and the tools before it, are the same for every request of a conversation, or of
+// an application.
+func anthropicSystem(systemPrompt string) []anthropicSystemBlock {
+	if systemPrompt == "" {
+		return nil
+	}
+	return []anthropicSystemBlock{{Type: "text", Text: systemPrompt, CacheControl: ephemeralCache}}
+}
+
+// markHistoryCacheable marks the conversation history, everything before the last message,
+// for caching, so the next turn of the conversation reads it from the cache. A single
+// message has no history, and an empty text cannot be marked.
+func markHistoryCacheable(messages []anthropicMessage) {
+	if len(messages) < 2 {
+		return
+	}
+	msg := &messages[len(messages)-2]
+	switch content := msg.Content.(type) {
+	case string:
+		if strings.TrimSpace(content) != "" {
+			msg.Content = []anthropicContentBlock{{Type: "text", Text: content, CacheControl: ephemeralCache}}
+		}
+	case []anthropicContentBlock:
+		content[len(content)-1].CacheControl = ephemeralCache
+	}
+}
+
 func toAnthropicTools(toolsToConvert []tools.Tool) ([]anthropicTool, error) {
 	if len(toolsToConvert) == 0 {
 		return nil, nil
@@ -199,12 +330,14 @@ func parseAnthropicResponse(body []byte) (*GenerationResult, error) {
 	if len(anthropicResp.Content) == 0 {
 		return nil, errors.New("no content returned from Anthropic")
 	}
-	var contentBuilder strings.Builder
+	var contentBuilder, thinking strings.Builder
 	var toolCalls []*tools.ToolCall
 	for _, block := range anthropicResp.Content {
 		switch block.Type {
 		case "text":
 			contentBuilder.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
 		case "tool_use":
 			toolCalls = append(toolCalls, &tools.ToolCall{
 				ID:   block.ID,
@@ -216,27 +349,71 @@ func parseAnthropicResponse(body []byte) (*GenerationResult, error) {
 			})
 		}
 	}
-	usage := api.Usage{
-		PromptTokens:     anthropicResp.Usage.InputTokens,
-		CompletionTokens: anthropicResp.Usage.OutputTokens,
-		TotalTokens:      anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens,
-	}
+	usage := anthropicResp.Usage.promptUsage()
+	usage.CompletionTokens = anthropicResp.Usage.OutputTokens
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	// Anthropic bills thinking as output without counting it apart, so it is estimated.
+	usage.ReasoningTokens = estimateReasoningTokens(thinking.String(), anthropicResp.Usage.OutputTokens)
 
 	return &GenerationResult{
-		Content:   strings.TrimSpace(contentBuilder.String()),
-		ToolCalls: toolCalls,
-		Usage:     usage,
+		Content:    strings.TrimSpace(contentBuilder.String()),
+		ToolCalls:  toolCalls,
+		Usage:      usage,
+		StopReason: anthropicStopReason(anthropicResp.StopReason),
 	}, nil
 }
 
-func (c *AnthropicClient) processStream(body io.ReadCloser, outChan chan<- *StreamingResult) {
+// anthropicStopReason maps a stop_reason of the Messages API onto a StopReason constant.
+func anthropicStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence", "pause_turn":
+		return StopReasonStop
+	case "max_tokens":
+		return StopReasonLength
+	case "tool_use":
+		return StopReasonToolCalls
+	case "refusal":
+		return StopReasonContentFilter
+	}
+	return ""
+}
+
+// takeFormatToolAnswer turns a call of the forced format tool into the answer's content.
+func takeFormatToolAnswer(result *GenerationResult) {
+	toolCalls := result.ToolCalls[:0]
+	for _, call := range result.ToolCalls {
+		if call.Function.Name == anthropicFormatTool {
+			result.Content = call.Function.Arguments
+			continue
+		}
+		toolCalls = append(toolCalls, call)
+	}
+	result.ToolCalls = toolCalls
+	if len(result.ToolCalls) == 0 {
+		result.ToolCalls = nil
+		// The model stopped to call the format tool, which is how it answers.
+		if result.StopReason == StopReasonToolCalls {
+			result.StopReason = StopReasonStop
+		}
+	}
+}
+
+func (c *AnthropicClient) processStream(body io.ReadCloser, deprecation *DeprecationNotice, outChan chan<- *StreamingResult) {
 	defer func() {
 		if err := body.Close(); err != nil {
-			log.Printf("Error closing anthropic stream body: %v", err)
+			slog.Warn("Error closing stream body", "provider", "anthropic", "error", err)
 		}
 		close(outChan)
 	}()
+	if deprecation != nil {
+		outChan <- &StreamingResult{Deprecation: deprecation}
+	}
 
+	// Anthropic reports input tokens when the message starts and output tokens in the
+	// message_delta events, so usage is assembled across the stream and sent at the end.
+	var usage api.Usage
+	var stopReason string
+	var thinking strings.Builder
 	scanner := bufio.NewScanner(body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -247,22 +424,35 @@ func (c *AnthropicClient) processStream(body io.ReadCloser, outChan chan<- *Stre
 			}
 			var event anthropicStreamEvent
 			if err := json.Unmarshal([]byte(data), &event); err != nil {
-				log.Printf("Error unmarshalling stream event: %v, data: %s", err, data)
+				slog.Warn("Error unmarshalling stream event", "provider", "anthropic", "error", err, "data", data)
 				continue
 			}
 			switch event.Type {
+			case "message_start":
+				if event.Message != nil {
+					usage = event.Message.Usage.promptUsage()
+				}
+			case "message_delta":
+				usage.CompletionTokens = event.Usage.OutputTokens
+				var delta anthropicStreamMessageDelta
+				if json.Unmarshal(event.Delta, &delta) == nil && delta.StopReason != "" {
+					stopReason = anthropicStopReason(delta.StopReason)
+				}
 			case "content_block_delta":
 				var textDelta anthropicStreamTextDelta
-				if json.Unmarshal(event.Delta, &textDelta) == nil && textDelta.Type == "text_delta" {
+				if json.Unmarshal(event.Delta, &textDelta) != nil {
+					continue
+				}
+				switch textDelta.Type {
+				case "text_delta":
 					outChan <- &StreamingResult{ContentDelta: textDelta.Text}
+				case "thinking_delta":
+					thinking.WriteString(textDelta.Thinking)
 				}
 			case "message_stop":
-				usage := &api.Usage{
-					PromptTokens:     event.Usage.InputTokens,
-					CompletionTokens: event.Usage.OutputTokens,
-					TotalTokens:      event.Usage.InputTokens + event.Usage.OutputTokens,
-				}
-				outChan <- &StreamingResult{Usage: usage}
+				usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+				usage.ReasoningTokens = estimateReasoningTokens(thinking.String(), usage.CompletionTokens)
+				outChan <- &StreamingResult{Usage: &usage, StopReason: stopReason}
 				return
 			}
 		}
@@ -273,62 +463,75 @@ func (c *AnthropicClient) processStream(body io.ReadCloser, outChan chan<- *Stre
 }
 
 // FIX 2: Check the error returned from body.Close() and handle it.
-func (c *AnthropicClient) doRequest(ctx context.Context, payload *bytes.Buffer) ([]byte, error) {
+func (c *AnthropicClient) doRequest(ctx context.Context, payload *bytes.Buffer, modelID string) ([]byte, *DeprecationNotice, error) {
 	var lastErr error
-	delay := initialRetryDelay
-	for i := 0; i < maxRetries; i++ {
+	attempts := c.policy.attempts()
+	delay := c.policy.InitialRetryDelay
+	for i := 0; i < attempts; i++ {
 		req, err := c.createRequest(ctx, bytes.NewReader(payload.Bytes()))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", i+1, maxRetries, err)
-			time.Sleep(delay)
-			delay *= 2
+			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", i+1, attempts, err)
+			if i+1 < attempts {
+				if err := sleepBeforeRetry(ctx, delay); err != nil {
+					return nil, nil, err
+				}
+				delay *= 2
+			}
 			continue
 		}
 		body, readErr := io.ReadAll(resp.Body)
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("Warning: Failed to close response body: %v", err)
+			slog.WarnContext(ctx, "Failed to close response body", "error", err)
 		}
 		if readErr != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", readErr)
+			return nil, nil, fmt.Errorf("failed to read response body: %w", readErr)
 		}
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return body, nil
+			return body, parseDeprecationHeaders(modelID, resp.Header), nil
 		}
-		lastErr = fmt.Errorf("anthropic API error (attempt %d/%d): status %d, body: %s", i+1, maxRetries, resp.StatusCode, string(body))
+		lastErr = &APIError{Provider: "anthropic", StatusCode: resp.StatusCode, Body: string(body), Attempt: i + 1, Attempts: attempts}
 		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-			return nil, lastErr
+			return nil, nil, lastErr
+		}
+		if i+1 < attempts {
+			if err := sleepBeforeRetry(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+			delay *= 2
 		}
-		time.Sleep(delay)
-		delay *= 2
 	}
-	return nil, lastErr
+	return nil, nil, lastErr
 }
 
-func (c *AnthropicClient) doRequestStream(ctx context.Context, payload *bytes.Buffer) (io.ReadCloser, error) {
+func (c *AnthropicClient) doRequestStream(ctx context.Context, payload *bytes.Buffer, modelID string) (io.ReadCloser, *DeprecationNotice, error) {
 	req, err := c.createRequest(ctx, payload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start stream request: %w", err)
+		return nil, nil, fmt.Errorf("failed to start stream request: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("Warning: Failed to close stream response body: %v", err)
+			slog.WarnContext(ctx, "Failed to close stream response body", "error", err)
 		}
-		return nil, fmt.Errorf("anthropic API stream error: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, nil, fmt.Errorf("anthropic API stream error: status %d, body: %s", resp.StatusCode, string(body))
 	}
-	return resp.Body, nil
+	return resp.Body, parseDeprecationHeaders(modelID, resp.Header), nil
 }
 
 func (c *AnthropicClient) createRequest(ctx context.Context, body io.Reader) (*http.Request, error) {
-	req, err := http.NewRequestWithContext(ctx, "POST", anthropicAPIURL, body)
+	return c.createRequestTo(ctx, anthropicAPIURL, body)
+}
+
+func (c *AnthropicClient) createRequestTo(ctx context.Context, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create http request: %w", err)
 	}
@@ -337,3 +540,56 @@ func (c *AnthropicClient) createRequest(ctx context.Context, body io.Reader) (*h
 	req.Header.Set("content-type", "application/json")
 	return req, nil
 }
+
+// Ping checks that the model is available by fetching it from Anthropic's model list.
+func (c *AnthropicClient) Ping(ctx context.Context, modelID string) error {
+	return pingModelURL(ctx, c.httpClient, anthropicModelsURL+url.PathEscape(modelID), map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": anthropicVersion,
+	})
+}
+
+// Forward sends a messages request body, in Anthropic's format, to Anthropic unchanged.
+func (c *AnthropicClient) Forward(ctx context.Context, body []byte) (*ForwardedResponse, error) {
+	req, err := c.createRequest(ctx, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return forwardRequest(c.httpClient, req)
+}
+
+// CountTokens counts the input tokens of the messages with Anthropic's count_tokens endpoint.
+func (c *AnthropicClient) CountTokens(ctx context.Context, modelID string, messages []Message) (int, error) {
+	systemPrompt, anthropicMsgs := toAnthropicMessages(messages)
+	payload, err := json.Marshal(struct {
+		Model    string             `json:"model"`
+		Messages []anthropicMessage `json:"messages"`
+		System   string             `json:"system,omitempty"`
+	}{Model: modelID, Messages: anthropicMsgs, System: systemPrompt})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal count_tokens payload: %w", err)
+	}
+	req, err := c.createRequestTo(ctx, anthropicCountTokensURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("count_tokens request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read count_tokens response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("anthropic count_tokens error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+	var counted struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(body, &counted); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal count_tokens response: %w", err)
+	}
+	return counted.InputTokens, nil
+}
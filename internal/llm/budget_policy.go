@@ -0,0 +1,70 @@
+// In file: internal/llm/budget_policy.go
+package llm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+)
+
+// The budget states of a model.
+const (
+	BudgetOK        = "ok"
+	BudgetThrottled = "throttled"
+	BudgetExhausted = "exhausted"
+)
+
+// BudgetPolicy throttles models gradually as they spend their monthly budget, instead of
+// routing to them at full weight until the budget runs out. It is configured in the
+// `budget_policy` section of config.yaml.
+type BudgetPolicy struct {
+	// SoftCap is the share of the budget, between 0 and 1, from which a model's routing score
+	// is penalized. Zero turns throttling off.
+	SoftCap float64 `yaml:"soft_cap"`
+	// MaxPenalty is the share of its score a model loses just before its budget runs out. The
+	// penalty grows linearly from the soft cap; at the budget, the hard cap, the model is excluded.
+	MaxPenalty float64 `yaml:"max_penalty"`
+}
+
+// Validate reports settings that cannot work.
+func (p BudgetPolicy) Validate() error {
+	if p.SoftCap < 0 || p.SoftCap >= 1 {
+		return errors.New("soft_cap must be at least 0 and less than 1")
+	}
+	if p.MaxPenalty < 0 || p.MaxPenalty > 1 {
+		return errors.New("max_penalty must be between 0 and 1")
+	}
+	return nil
+}
+
+// State reports how far a model with the given monthly spend and budget is into its budget,
+// and the share of its score it loses for it. Models without a budget are never throttled.
+func (p BudgetPolicy) State(modelID string, spent, budget float64) api.BudgetState {
+	state := api.BudgetState{Model: modelID, SpentUSD: spent, BudgetUSD: budget, State: BudgetOK}
+	if budget <= 0 {
+		return state
+	}
+	switch used := spent / budget; {
+	case used >= 1:
+		state.State = BudgetExhausted
+	case p.SoftCap > 0 && used >= p.SoftCap:
+		state.State = BudgetThrottled
+		state.Penalty = p.MaxPenalty * (used - p.SoftCap) / (1 - p.SoftCap)
+	}
+	return state
+}
+
+// BudgetStates reports the budget state of each model, for explaining a routing decision.
+// Models whose profile cannot be read are left out.
+func (r *Router) BudgetStates(ctx context.Context, models []string, modelBudgets map[string]float64) []api.BudgetState {
+	states := make([]api.BudgetState, 0, len(models))
+	for _, modelID := range models {
+		profile, err := r.profiler.GetProfile(ctx, modelID)
+		if err != nil {
+			continue
+		}
+		states = append(states, r.config.Budget.State(modelID, profile.CostSpentMonthly, modelBudgets[modelID]))
+	}
+	return states
+}
@@ -0,0 +1,67 @@
+// In file: internal/llm/cache_stats.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// The caches whose lookups are counted.
+const (
+	CacheResponse  = "response"
+	CacheEmbedding = "embedding"
+)
+
+// cacheStatsRetention is how long a day's cache lookup counts are kept.
+const cacheStatsRetention = 8 * 24 * time.Hour
+
+// CacheStats counts one cache's lookups during a day (UTC).
+type CacheStats struct {
+	Cache   string  `json:"cache"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+func cacheStatsKey(day string) string {
+	return fmt.Sprintf("cache_stats:%s", day)
+}
+
+// recordCacheLookup counts a hit or a miss of a cache. Failures are only logged, so counting
+// never fails a lookup.
+func (s *RAGService) recordCacheLookup(ctx context.Context, cache string, hit bool) {
+	field := cache + ":misses"
+	if hit {
+		field = cache + ":hits"
+	}
+	key := cacheStatsKey(time.Now().UTC().Format(time.DateOnly))
+	pipe := s.redisClient.Pipeline()
+	pipe.HIncrBy(ctx, key, field, 1)
+	pipe.Expire(ctx, key, cacheStatsRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to count cache lookup", "cache", cache, "error", err)
+	}
+}
+
+// CacheStats returns the lookup counts of the response and embedding caches on a day
+// (YYYY-MM-DD, UTC).
+func (s *RAGService) CacheStats(ctx context.Context, day string) ([]CacheStats, error) {
+	counts, err := s.redisClient.HGetAll(ctx, cacheStatsKey(day)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache stats: %w", err)
+	}
+	stats := make([]CacheStats, 0, 2)
+	for _, cache := range []string{CacheResponse, CacheEmbedding} {
+		hits, _ := strconv.ParseInt(counts[cache+":hits"], 10, 64)
+		misses, _ := strconv.ParseInt(counts[cache+":misses"], 10, 64)
+		entry := CacheStats{Cache: cache, Hits: hits, Misses: misses}
+		if total := hits + misses; total > 0 {
+			entry.HitRate = float64(hits) / float64(total)
+		}
+		stats = append(stats, entry)
+	}
+	return stats, nil
+}
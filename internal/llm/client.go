@@ -46,6 +46,43 @@ type GenerationConfig struct {
 	// Indicates whether to use streaming. The client implementation uses this to
 	// decide which underlying API method to call.
 	Stream bool
+	// JSONMode asks the provider to constrain the answer to a JSON value, where it supports
+	// doing so (OpenAI, Mistral, and Gemini). Other providers rely on the prompt alone.
+	JSONMode bool
+	// ResponseFormat constrains the answer to JSON on every provider. It takes precedence
+	// over JSONMode.
+	ResponseFormat *ResponseFormat
+	// Logprobs asks for the log probability of every token of the answer, and TopLogprobs
+	// for that many of the most likely tokens at each position. Providers that do not report
+	// them ignore both.
+	Logprobs    bool
+	TopLogprobs int
+	// Seed asks for deterministic sampling, where the provider supports it (OpenAI and Mistral).
+	Seed *int64
+	// Stop lists sequences at which the model stops generating.
+	Stop []string
+	// FrequencyPenalty and PresencePenalty discourage repetition (OpenAI and Mistral).
+	FrequencyPenalty *float32
+	PresencePenalty  *float32
+	// TopK samples only from the K most likely tokens (Anthropic and Gemini).
+	TopK *int
+	// ReasoningEffort is ReasoningEffortLow, ReasoningEffortMedium, or ReasoningEffortHigh,
+	// for OpenAI's reasoning models and Anthropic's extended thinking.
+	ReasoningEffort string
+	// ThinkingBudget is the token budget of Anthropic's extended thinking. It takes
+	// precedence over ReasoningEffort.
+	ThinkingBudget int
+	// StreamBuffer is how many chunks a streaming call buffers ahead of its reader, so the
+	// provider connection keeps being read while the client is written to. 0 is unbuffered.
+	StreamBuffer int
+}
+
+// newStreamChannel creates the channel a streaming call returns, buffered as config asks.
+func newStreamChannel(config *GenerationConfig) chan *StreamingResult {
+	if config == nil || config.StreamBuffer <= 0 {
+		return make(chan *StreamingResult)
+	}
+	return make(chan *StreamingResult, config.StreamBuffer)
 }
 
 // GenerationResult holds the complete, non-streamed output from an LLM call.
@@ -57,6 +94,16 @@ type GenerationResult struct {
 	ToolCalls []*tools.ToolCall
 	// Token usage statistics for the generation request.
 	Usage api.Usage
+	// Deprecation is set when the provider signalled that the model is being sunset.
+	Deprecation *DeprecationNotice
+	// Logprobs holds the log probability of every token of Content, if they were asked for
+	// and the provider reports them.
+	Logprobs []api.TokenLogprob
+	// SystemFingerprint identifies the provider's backend configuration, if it reports one.
+	SystemFingerprint string
+	// StopReason is why the model stopped generating, one of the StopReason constants, or
+	// empty if the provider did not say.
+	StopReason string
 }
 
 // StreamingResult holds a chunk of a streamed response from an LLM.
@@ -71,8 +118,25 @@ type StreamingResult struct {
 	Usage *api.Usage
 	// An error that may have occurred during the stream.
 	Err error
+	// Deprecation is set, on a result of its own, when the provider signalled that the
+	// model is being sunset.
+	Deprecation *DeprecationNotice
+	// StopReason is set once the provider reports why the model stopped generating.
+	StopReason string
 }
 
+// Why a model stopped generating. Each client maps its provider's reasons onto these.
+const (
+	// StopReasonStop means the model finished its answer or reached a stop sequence.
+	StopReasonStop = "stop"
+	// StopReasonLength means the answer was cut off at the max_tokens limit.
+	StopReasonLength = "length"
+	// StopReasonToolCalls means the model stopped to call tools.
+	StopReasonToolCalls = "tool_calls"
+	// StopReasonContentFilter means the provider withheld or cut off the answer for safety.
+	StopReasonContentFilter = "content_filter"
+)
+
 // =================================================================================
 // LLM Client Interface
 // =================================================================================
@@ -5,8 +5,12 @@ import "time"
 
 // This file centralizes constants shared across multiple clients and services
 // in the llm package to avoid redeclaration errors.
+// The request policy defaults; config.yaml's request_policy section overrides them per
+// provider and model.
 const (
     defaultTimeout      = 120 * time.Second
-    maxRetries          = 3
+    defaultMaxRetries   = 2
     initialRetryDelay   = 2 * time.Second
+    // maxRetries bounds the attempts of calls that have no request policy, such as embeddings.
+    maxRetries          = defaultMaxRetries + 1
 )
\ No newline at end of file
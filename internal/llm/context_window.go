@@ -0,0 +1,87 @@
+// In file: internal/llm/context_window.go
+package llm
+
+const (
+	// defaultContextWindow is assumed for models whose context_window is not configured.
+	// It is deliberately small, so an unconfigured model is never overfilled.
+	defaultContextWindow = 8192
+	// defaultReservedOutputTokens is kept free for the answer when a request sets no max_tokens.
+	defaultReservedOutputTokens = 1024
+	// messageOverheadTokens approximates the per-message formatting tokens added by providers.
+	messageOverheadTokens = 4
+)
+
+// EstimateTokens approximates the token count of a text using the common heuristic of
+// roughly four characters per token. It errs on the side of overestimating short texts.
+func EstimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+// EstimateMessageTokens approximates the total token count of a message list.
+func EstimateMessageTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg.Content) + messageOverheadTokens
+	}
+	return total
+}
+
+// ContextWindow returns the configured context window of a model, in tokens.
+func (r *Router) ContextWindow(modelID string) int {
+	if meta, ok := r.config.Models[modelID]; ok && meta.ContextWindow > 0 {
+		return meta.ContextWindow
+	}
+	return defaultContextWindow
+}
+
+// TruncationResult describes how a history was fitted into a model's context window.
+type TruncationResult struct {
+	// Messages is the history that fits, oldest first.
+	Messages []Message
+	// EstimatedTokens is the estimated size of the kept history plus the fixed content.
+	EstimatedTokens int
+	// Dropped is the number of history messages that were removed.
+	Dropped int
+}
+
+// TruncateHistory drops the oldest history messages until the history, the fixed content
+// (the prompt and any RAG context), and the tokens reserved for the answer fit into the
+// context window. System messages are always kept, since they carry instructions rather
+// than conversation. The result is deterministic for a given input, which keeps cache
+// keys and audit records stable.
+func TruncateHistory(history []Message, fixedTokens, contextWindow, maxOutputTokens int) TruncationResult {
+	if maxOutputTokens <= 0 {
+		maxOutputTokens = defaultReservedOutputTokens
+	}
+	budget := contextWindow - maxOutputTokens - fixedTokens
+	total := EstimateMessageTokens(history)
+	if total <= budget {
+		return TruncationResult{Messages: history, EstimatedTokens: total + fixedTokens}
+	}
+
+	// Walk from the oldest message, dropping non-system messages until the rest fits.
+	keep := make([]bool, len(history))
+	for i := range keep {
+		keep[i] = true
+	}
+	dropped := 0
+	for i, msg := range history {
+		if total <= budget {
+			break
+		}
+		if msg.Role == RoleSystem {
+			continue
+		}
+		keep[i] = false
+		total -= EstimateTokens(msg.Content) + messageOverheadTokens
+		dropped++
+	}
+
+	kept := make([]Message, 0, len(history)-dropped)
+	for i, msg := range history {
+		if keep[i] {
+			kept = append(kept, msg)
+		}
+	}
+	return TruncationResult{Messages: kept, EstimatedTokens: total + fixedTokens, Dropped: dropped}
+}
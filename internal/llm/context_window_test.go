@@ -0,0 +1,81 @@
+// In file: internal/llm/context_window_test.go
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+// testMessage returns a message estimated at 15 tokens, whose content starts with id.
+func testMessage(role Role, id string) Message {
+	return Message{Role: role, Content: id + strings.Repeat("x", 40-len(id))}
+}
+
+func TestTruncateHistory(t *testing.T) {
+	sys := testMessage(RoleSystem, "sys")
+	u1 := testMessage(RoleUser, "u1")
+	a1 := testMessage(RoleAssistant, "a1")
+	u2 := testMessage(RoleUser, "u2")
+	a2 := testMessage(RoleAssistant, "a2")
+
+	tests := []struct {
+		name            string
+		history         []Message
+		fixedTokens     int
+		contextWindow   int
+		maxOutputTokens int
+		want            []Message
+		wantTokens      int
+		wantDropped     int
+	}{
+		{
+			name:    "fits",
+			history: []Message{u1, a1, u2}, fixedTokens: 10, contextWindow: 2000, maxOutputTokens: 100,
+			want: []Message{u1, a1, u2}, wantTokens: 55,
+		},
+		{
+			name:    "drops the oldest messages",
+			history: []Message{u1, a1, u2, a2}, fixedTokens: 10, contextWindow: 1000, maxOutputTokens: 950,
+			want: []Message{u2, a2}, wantTokens: 40, wantDropped: 2,
+		},
+		{
+			name:    "keeps system messages",
+			history: []Message{sys, u1, a1, u2}, fixedTokens: 10, contextWindow: 1000, maxOutputTokens: 950,
+			want: []Message{sys, u2}, wantTokens: 40, wantDropped: 2,
+		},
+		{
+			name:    "reserves the default output tokens",
+			history: []Message{u1, a1, u2, a2}, fixedTokens: 10, contextWindow: defaultReservedOutputTokens + 40,
+			want: []Message{u2, a2}, wantTokens: 40, wantDropped: 2,
+		},
+		{
+			name:    "drops everything but system messages",
+			history: []Message{sys, u1, a1}, fixedTokens: 10, contextWindow: 100, maxOutputTokens: 100,
+			want: []Message{sys}, wantTokens: 25, wantDropped: 2,
+		},
+		{
+			name:    "empty history",
+			history: nil, fixedTokens: 10, contextWindow: 100, maxOutputTokens: 50,
+			want: nil, wantTokens: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateHistory(tt.history, tt.fixedTokens, tt.contextWindow, tt.maxOutputTokens)
+			if len(got.Messages) != len(tt.want) {
+				t.Fatalf("kept %d messages, want %d", len(got.Messages), len(tt.want))
+			}
+			for i := range tt.want {
+				if got.Messages[i].Role != tt.want[i].Role || got.Messages[i].Content != tt.want[i].Content {
+					t.Fatalf("message %d = %q, want %q", i, got.Messages[i].Content, tt.want[i].Content)
+				}
+			}
+			if got.EstimatedTokens != tt.wantTokens {
+				t.Errorf("EstimatedTokens = %d, want %d", got.EstimatedTokens, tt.wantTokens)
+			}
+			if got.Dropped != tt.wantDropped {
+				t.Errorf("Dropped = %d, want %d", got.Dropped, tt.wantDropped)
+			}
+		})
+	}
+}
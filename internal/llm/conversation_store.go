@@ -0,0 +1,208 @@
+// In file: internal/llm/conversation_store.go
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrConversationNotFound is returned when a conversation does not exist or has expired.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationStore persists the messages of each conversation on the server, so that
+// clients only have to send a ConversationID instead of resending the full history.
+type ConversationStore interface {
+	// Load returns the stored messages of a conversation, oldest first.
+	// An unknown conversation yields an empty history, not an error.
+	Load(ctx context.Context, conversationID string) ([]api.Message, error)
+	// Append adds messages to the end of a conversation owned by userID, creating it if needed.
+	Append(ctx context.Context, conversationID, userID string, messages ...api.Message) error
+	// Get returns a conversation's metadata, or ErrConversationNotFound.
+	Get(ctx context.Context, conversationID string) (*ConversationInfo, error)
+	// List returns a user's conversations, most recently updated first.
+	List(ctx context.Context, userID string) ([]ConversationInfo, error)
+	// Rename sets a conversation's title.
+	Rename(ctx context.Context, conversationID, title string) error
+	// Delete removes a conversation and its messages.
+	Delete(ctx context.Context, conversationID string) error
+}
+
+// ConversationInfo is the metadata kept alongside a conversation's messages.
+type ConversationInfo struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	MessageCount int       `json:"message_count"`
+}
+
+// RedisConversationStore keeps each conversation as a Redis list of JSON-encoded messages.
+// Only the most recent maxMessages are retained, and idle conversations expire after ttl.
+type RedisConversationStore struct {
+	rdb         *redis.Client
+	maxMessages int
+	ttl         time.Duration
+}
+
+// Statically verify that RedisConversationStore implements the ConversationStore interface.
+var _ ConversationStore = (*RedisConversationStore)(nil)
+
+// NewRedisConversationStore creates a conversation store backed by Redis.
+func NewRedisConversationStore(rdb *redis.Client, maxMessages int, ttl time.Duration) *RedisConversationStore {
+	return &RedisConversationStore{
+		rdb:         rdb,
+		maxMessages: maxMessages,
+		ttl:         ttl,
+	}
+}
+
+func (s *RedisConversationStore) getConversationKey(conversationID string) string {
+	return fmt.Sprintf("conversation:%s:messages", conversationID)
+}
+
+func (s *RedisConversationStore) getMetaKey(conversationID string) string {
+	return fmt.Sprintf("conversation:%s:meta", conversationID)
+}
+
+func (s *RedisConversationStore) getUserIndexKey(userID string) string {
+	return fmt.Sprintf("user:%s:conversations", userID)
+}
+
+// Load returns the retained messages of a conversation.
+func (s *RedisConversationStore) Load(ctx context.Context, conversationID string) ([]api.Message, error) {
+	raw, err := s.rdb.LRange(ctx, s.getConversationKey(conversationID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation %s: %w", conversationID, err)
+	}
+	messages := make([]api.Message, 0, len(raw))
+	for _, item := range raw {
+		var msg api.Message
+		if err := json.Unmarshal([]byte(item), &msg); err != nil {
+			return nil, fmt.Errorf("corrupt message in conversation %s: %w", conversationID, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// Append adds messages to a conversation, trims it to the retention limit, updates its metadata
+// and its owner's index, and refreshes its TTL, all in a single round trip.
+func (s *RedisConversationStore) Append(ctx context.Context, conversationID, userID string, messages ...api.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+		values = append(values, encoded)
+	}
+
+	key := s.getConversationKey(conversationID)
+	metaKey := s.getMetaKey(conversationID)
+	now := time.Now()
+
+	pipe := s.rdb.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	if s.maxMessages > 0 {
+		pipe.LTrim(ctx, key, int64(-s.maxMessages), -1)
+	}
+	pipe.HSetNX(ctx, metaKey, "created_at", now.Format(time.RFC3339Nano))
+	pipe.HSetNX(ctx, metaKey, "user_id", userID)
+	pipe.HSet(ctx, metaKey, "updated_at", now.Format(time.RFC3339Nano))
+	pipe.HIncrBy(ctx, metaKey, "message_count", int64(len(messages)))
+	if userID != "" {
+		pipe.ZAdd(ctx, s.getUserIndexKey(userID), redis.Z{Score: float64(now.Unix()), Member: conversationID})
+	}
+	if s.ttl > 0 {
+		pipe.Expire(ctx, key, s.ttl)
+		pipe.Expire(ctx, metaKey, s.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append to conversation %s: %w", conversationID, err)
+	}
+	return nil
+}
+
+// Get returns a conversation's metadata.
+func (s *RedisConversationStore) Get(ctx context.Context, conversationID string) (*ConversationInfo, error) {
+	data, err := s.rdb.HGetAll(ctx, s.getMetaKey(conversationID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation %s: %w", conversationID, err)
+	}
+	if len(data) == 0 {
+		return nil, ErrConversationNotFound
+	}
+	info := &ConversationInfo{
+		ID:     conversationID,
+		UserID: data["user_id"],
+		Title:  data["title"],
+	}
+	info.CreatedAt, _ = time.Parse(time.RFC3339Nano, data["created_at"])
+	info.UpdatedAt, _ = time.Parse(time.RFC3339Nano, data["updated_at"])
+	info.MessageCount, _ = strconv.Atoi(data["message_count"])
+	return info, nil
+}
+
+// List returns a user's conversations, most recently updated first. Conversations that have
+// expired are pruned from the user's index as they are encountered.
+func (s *RedisConversationStore) List(ctx context.Context, userID string) ([]ConversationInfo, error) {
+	indexKey := s.getUserIndexKey(userID)
+	ids, err := s.rdb.ZRevRange(ctx, indexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations for user %s: %w", userID, err)
+	}
+	conversations := make([]ConversationInfo, 0, len(ids))
+	for _, id := range ids {
+		info, err := s.Get(ctx, id)
+		if errors.Is(err, ErrConversationNotFound) {
+			s.rdb.ZRem(ctx, indexKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, *info)
+	}
+	return conversations, nil
+}
+
+// Rename sets a conversation's title.
+func (s *RedisConversationStore) Rename(ctx context.Context, conversationID, title string) error {
+	metaKey := s.getMetaKey(conversationID)
+	exists, err := s.rdb.Exists(ctx, metaKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read conversation %s: %w", conversationID, err)
+	}
+	if exists == 0 {
+		return ErrConversationNotFound
+	}
+	return s.rdb.HSet(ctx, metaKey, "title", title).Err()
+}
+
+// Delete removes a conversation's messages, metadata, and index entry.
+func (s *RedisConversationStore) Delete(ctx context.Context, conversationID string) error {
+	info, err := s.Get(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, s.getConversationKey(conversationID), s.getMetaKey(conversationID))
+	if info.UserID != "" {
+		pipe.ZRem(ctx, s.getUserIndexKey(info.UserID), conversationID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete conversation %s: %w", conversationID, err)
+	}
+	return nil
+}
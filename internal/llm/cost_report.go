@@ -0,0 +1,172 @@
+// In file: internal/llm/cost_report.go
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Dimensions a cost report can be grouped by.
+const (
+	CostByModel        = "model"
+	CostByDay          = "day"
+	CostByUser         = "user"
+	CostByConversation = "conversation"
+)
+
+// costRetention is how long spend is kept for reporting. It covers a full year of
+// month-over-month comparisons.
+const costRetention = 400 * 24 * time.Hour
+
+// CostEntry is one row of a cost report: the spend attributed to a model, day, user,
+// or conversation.
+type CostEntry struct {
+	Key      string  `json:"key"`
+	CostUSD  float64 `json:"cost_usd"`
+	Requests int64   `json:"requests,omitempty"`
+}
+
+// CostReport is the spend for one month grouped by a single dimension, most expensive first.
+type CostReport struct {
+	Dimension string      `json:"dimension"`
+	Month     string      `json:"month"`
+	TotalUSD  float64     `json:"total_usd"`
+	Entries   []CostEntry `json:"entries"`
+}
+
+// spendKey returns the Redis hash holding a month's spend for a dimension. Fields are the
+// dimension's values (days, user IDs, conversation IDs) and values are USD amounts.
+// Per-model spend lives in the cost:<model>:<month> keys used for budget enforcement.
+func (p *Profiler) spendKey(dimension, month string) string {
+	return fmt.Sprintf("spend:%s:%s", dimension, month)
+}
+
+// RecordSpend attributes the cost of a successful request to its day, user, and conversation,
+// so that spend can be reported along those dimensions. Requests without a user or
+// conversation are only counted by day.
+func (p *Profiler) RecordSpend(ctx context.Context, modelID, userID, conversationID string, usage api.Usage) {
+	cost := CallCost(modelID, usage)
+	if cost == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	month := now.Format("2006-01")
+
+	pipe := p.rdb.Pipeline()
+	add := func(dimension, field string) {
+		key := p.spendKey(dimension, month)
+		pipe.HIncrByFloat(ctx, key, field, cost)
+		pipe.HIncrBy(ctx, key+":requests", field, 1)
+		pipe.Expire(ctx, key, costRetention)
+		pipe.Expire(ctx, key+":requests", costRetention)
+	}
+	add(CostByDay, now.Format("2006-01-02"))
+	if userID != "" {
+		add(CostByUser, userID)
+	}
+	if conversationID != "" {
+		add(CostByConversation, conversationID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error recording spend", "model", modelID, "error", err)
+	}
+}
+
+// CostReport aggregates the spend for a month ("2006-01") by the given dimension.
+func (p *Profiler) CostReport(ctx context.Context, dimension, month string) (*CostReport, error) {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, fmt.Errorf("invalid month '%s' (expected YYYY-MM)", month)
+	}
+
+	var entries []CostEntry
+	var err error
+	switch dimension {
+	case CostByModel:
+		entries, err = p.modelCosts(ctx, month)
+	case CostByDay, CostByUser, CostByConversation:
+		entries, err = p.spendEntries(ctx, dimension, month)
+	default:
+		return nil, fmt.Errorf("unknown cost dimension '%s'", dimension)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CostReport{Dimension: dimension, Month: month, Entries: entries}
+	for _, e := range entries {
+		report.TotalUSD += e.CostUSD
+	}
+	if dimension == CostByDay {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	} else {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].CostUSD > entries[j].CostUSD })
+	}
+	return report, nil
+}
+
+// modelCosts reads the per-model monthly cost keys. Every model with recorded spend is
+// included, not only the currently enabled ones, so retired models still show up.
+func (p *Profiler) modelCosts(ctx context.Context, month string) ([]CostEntry, error) {
+	entries := []CostEntry{}
+	iter := p.rdb.Scan(ctx, 0, fmt.Sprintf("cost:*:%s", month), 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		modelID := strings.TrimSuffix(strings.TrimPrefix(key, "cost:"), ":"+month)
+		cost, err := p.rdb.Get(ctx, key).Float64()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, CostEntry{Key: modelID, CostUSD: cost})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan model costs: %w", err)
+	}
+	return entries, nil
+}
+
+// Spend returns the spend attributed to one day, user, or conversation in a month. It is
+// zero if nothing has been attributed to it.
+func (p *Profiler) Spend(ctx context.Context, dimension, key, month string) (CostEntry, error) {
+	spendKey := p.spendKey(dimension, month)
+	pipe := p.rdb.Pipeline()
+	cost := pipe.HGet(ctx, spendKey, key)
+	requests := pipe.HGet(ctx, spendKey+":requests", key)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return CostEntry{}, fmt.Errorf("failed to read %s spend: %w", dimension, err)
+	}
+	entry := CostEntry{Key: key}
+	entry.CostUSD, _ = strconv.ParseFloat(cost.Val(), 64)
+	entry.Requests, _ = strconv.ParseInt(requests.Val(), 10, 64)
+	return entry, nil
+}
+
+// spendEntries reads the spend hash for a dimension together with its request counts.
+func (p *Profiler) spendEntries(ctx context.Context, dimension, month string) ([]CostEntry, error) {
+	key := p.spendKey(dimension, month)
+	costs, err := p.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s costs: %w", dimension, err)
+	}
+	requests, err := p.rdb.HGetAll(ctx, key+":requests").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s request counts: %w", dimension, err)
+	}
+
+	entries := make([]CostEntry, 0, len(costs))
+	for field, value := range costs {
+		cost, _ := strconv.ParseFloat(value, 64)
+		count, _ := strconv.ParseInt(requests[field], 10, 64)
+		entries = append(entries, CostEntry{Key: field, CostUSD: cost, Requests: count})
+	}
+	return entries, nil
+}
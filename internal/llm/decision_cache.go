@@ -0,0 +1,75 @@
+// In file: internal/llm/decision_cache.go
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// decisionCacheKeyPrefix, followed by the analyzer, its version, and the prompt's hash, is
+// the Redis key of a cached analysis decision.
+const decisionCacheKeyPrefix = "decision:"
+
+// analysisLogicVersion is part of every analyzer version. Bump it when a change to the
+// analysis code changes its decisions, so decisions cached by older replicas are not reused.
+const analysisLogicVersion = "1"
+
+// DecisionCache caches the analyzers' decisions about prompts, so repeated identical prompts
+// skip the regex, embedding, and model classification work. Decisions are keyed by the
+// analyzer's version, which changes with its configuration, so a configuration change is
+// never answered with stale decisions.
+type DecisionCache struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+// NewDecisionCache creates a cache that keeps decisions in Redis for ttl.
+func NewDecisionCache(rdb *redis.Client, ttl time.Duration) *DecisionCache {
+	return &DecisionCache{rdb: rdb, ttl: ttl}
+}
+
+// get loads the decision cached under key into decision, reporting whether there was one.
+func (c *DecisionCache) get(ctx context.Context, key string, decision any) bool {
+	data, err := c.rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			slog.WarnContext(ctx, "Redis GET error for cached decision", "error", err)
+		}
+		return false
+	}
+	return json.Unmarshal(data, decision) == nil
+}
+
+// set caches decision under key.
+func (c *DecisionCache) set(ctx context.Context, key string, decision any) {
+	data, err := json.Marshal(decision)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to marshal decision for caching", "error", err)
+		return
+	}
+	if err := c.rdb.Set(ctx, key, data, c.ttl).Err(); err != nil {
+		slog.WarnContext(ctx, "Failed to cache decision", "error", err)
+	}
+}
+
+// decisionKey returns the key of an analyzer's decision about prompt.
+func decisionKey(analyzer, version, prompt string) string {
+	return decisionCacheKeyPrefix + analyzer + ":" + version + ":" + GenerateCacheKey(prompt)
+}
+
+// analyzerVersion fingerprints everything an analyzer's decisions depend on.
+func analyzerVersion(parts ...any) string {
+	data, err := json.Marshal(append([]any{analysisLogicVersion}, parts...))
+	if err != nil {
+		// Unversioned decisions could outlive the configuration that made them.
+		data = []byte(time.Now().String())
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,92 @@
+// In file: internal/llm/deprecation.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DeprecationNotice is a provider's signal that a model (or the API surface used to call it)
+// is being sunset. Providers communicate this through the standard `Deprecation` and
+// `Sunset` response headers and through `Warning` headers, usually long before the model
+// actually starts returning errors.
+type DeprecationNotice struct {
+	ModelID string `json:"model_id"`
+	// DeprecatedAt is the raw value of the Deprecation header (a date or "true").
+	DeprecatedAt string `json:"deprecated_at,omitempty"`
+	// SunsetAt is the raw value of the Sunset header: the date the model stops working.
+	SunsetAt string `json:"sunset_at,omitempty"`
+	// Message is the human-readable warning supplied by the provider, if any.
+	Message   string    `json:"message,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// parseDeprecationHeaders extracts a deprecation notice from provider response headers.
+// It returns nil if the response carries no deprecation signal.
+func parseDeprecationHeaders(modelID string, header http.Header) *DeprecationNotice {
+	notice := &DeprecationNotice{
+		ModelID:      modelID,
+		DeprecatedAt: header.Get("Deprecation"),
+		SunsetAt:     header.Get("Sunset"),
+	}
+	for _, warning := range header.Values("Warning") {
+		if strings.Contains(strings.ToLower(warning), "deprecat") {
+			notice.Message = warning
+			break
+		}
+	}
+	if notice.DeprecatedAt == "" && notice.SunsetAt == "" && notice.Message == "" {
+		return nil
+	}
+	return notice
+}
+
+func (p *Profiler) getDeprecationKey(modelID string) string {
+	return fmt.Sprintf("deprecation:%s", modelID)
+}
+
+// RecordDeprecation stores a deprecation notice for a model so that every replica's
+// admin API, metrics, and digest report the same information.
+func (p *Profiler) RecordDeprecation(ctx context.Context, notice *DeprecationNotice) {
+	key := p.getDeprecationKey(notice.ModelID)
+	now := time.Now().Format(time.RFC3339Nano)
+
+	pipe := p.rdb.Pipeline()
+	pipe.HSetNX(ctx, key, "first_seen", now)
+	pipe.HSet(ctx, key, "last_seen", now, "deprecated_at", notice.DeprecatedAt, "sunset_at", notice.SunsetAt, "message", notice.Message)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.ErrorContext(ctx, "Error recording deprecation notice", "model", notice.ModelID, "error", err)
+		return
+	}
+	slog.WarnContext(ctx, "Provider reported model deprecation", "model", notice.ModelID, "deprecated_at", notice.DeprecatedAt, "sunset_at", notice.SunsetAt)
+}
+
+// GetDeprecations returns the stored deprecation notices for the given models.
+// Models without a notice are omitted.
+func (p *Profiler) GetDeprecations(ctx context.Context, modelIDs []string) ([]DeprecationNotice, error) {
+	var notices []DeprecationNotice
+	for _, modelID := range modelIDs {
+		data, err := p.rdb.HGetAll(ctx, p.getDeprecationKey(modelID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		notice := DeprecationNotice{
+			ModelID:      modelID,
+			DeprecatedAt: data["deprecated_at"],
+			SunsetAt:     data["sunset_at"],
+			Message:      data["message"],
+		}
+		notice.FirstSeen, _ = time.Parse(time.RFC3339Nano, data["first_seen"])
+		notice.LastSeen, _ = time.Parse(time.RFC3339Nano, data["last_seen"])
+		notices = append(notices, notice)
+	}
+	return notices, nil
+}
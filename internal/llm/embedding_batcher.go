@@ -0,0 +1,112 @@
+// In file: internal/llm/embedding_batcher.go
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultEmbeddingBatchWindow is how long the first text of a batch waits for others to join it.
+const defaultEmbeddingBatchWindow = 5 * time.Millisecond
+
+// embeddingBatcher coalesces concurrent embedding requests. The first text to arrive opens a
+// batch and waits up to the batch window for others; the batch is then embedded with a single
+// API call, or as soon as it is full. Under RAG-heavy traffic this trades a few milliseconds
+// for far fewer calls against the embedding rate limit and fewer connection round trips.
+type embeddingBatcher struct {
+	window  time.Duration
+	maxSize int
+	embed   func(ctx context.Context, texts []string) ([][]float32, error)
+
+	mu      sync.Mutex
+	pending []*embeddingCall
+	timer   *time.Timer
+	// ctx is the context of the text that opened the pending batch, which the API call is
+	// traced under. Its cancellation is ignored, since other callers still wait for the batch.
+	ctx context.Context
+}
+
+// embeddingCall is a single text waiting for its batch to be embedded.
+type embeddingCall struct {
+	text      string
+	done      chan struct{}
+	embedding []float32
+	err       error
+}
+
+func newEmbeddingBatcher(window time.Duration, maxSize int, embed func(ctx context.Context, texts []string) ([][]float32, error)) *embeddingBatcher {
+	return &embeddingBatcher{
+		window:  window,
+		maxSize: maxSize,
+		embed:   embed,
+	}
+}
+
+// Embed adds text to the pending batch and waits for its embedding. A caller that gives up
+// returns at once; the rest of its batch is still embedded.
+func (b *embeddingBatcher) Embed(ctx context.Context, text string) ([]float32, error) {
+	call := &embeddingCall{text: text, done: make(chan struct{})}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, call)
+	if len(b.pending) == 1 {
+		b.ctx = context.WithoutCancel(ctx)
+		b.timer = time.AfterFunc(b.window, b.flushPending)
+	}
+	if len(b.pending) >= b.maxSize {
+		b.timer.Stop()
+		batch, batchCtx := b.takeLocked()
+		b.mu.Unlock()
+		go b.flush(batchCtx, batch)
+	} else {
+		b.mu.Unlock()
+	}
+
+	select {
+	case <-call.done:
+		return call.embedding, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flushPending embeds the pending batch when its window has passed.
+func (b *embeddingBatcher) flushPending() {
+	b.mu.Lock()
+	batch, ctx := b.takeLocked()
+	b.mu.Unlock()
+	if len(batch) > 0 {
+		b.flush(ctx, batch)
+	}
+}
+
+// takeLocked removes and returns the pending batch. The caller must hold b.mu.
+func (b *embeddingBatcher) takeLocked() ([]*embeddingCall, context.Context) {
+	batch, ctx := b.pending, b.ctx
+	b.pending, b.ctx, b.timer = nil, nil, nil
+	return batch, ctx
+}
+
+// flush embeds a batch with one API call and hands every caller its result. Identical texts
+// in the batch are embedded once.
+func (b *embeddingBatcher) flush(ctx context.Context, batch []*embeddingCall) {
+	index := make(map[string]int, len(batch))
+	var texts []string
+	for _, call := range batch {
+		if _, ok := index[call.text]; !ok {
+			index[call.text] = len(texts)
+			texts = append(texts, call.text)
+		}
+	}
+
+	embeddings, err := b.embed(ctx, texts)
+	for _, call := range batch {
+		if err != nil {
+			call.err = err
+		} else {
+			call.embedding = embeddings[index[call.text]]
+		}
+		close(call.done)
+	}
+}
@@ -0,0 +1,211 @@
+// In file: internal/llm/embedding_cache.go
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// =================================================================================
+// Embedding Cache Maintenance
+// =================================================================================
+// The embedding cache is keyed by embedding model as well as by text, so vectors
+// produced by different models can never be mixed. Each entry also stores its source
+// text, which is what allows the cache to be rebuilt for a new rag.embedding_model ahead
+// of a rollout instead of letting every query miss at once after the upgrade.
+// =================================================================================
+
+// embeddingBatchSize bounds the number of inputs sent in a single embeddings API call.
+const embeddingBatchSize = 100
+
+// embeddingCacheEntry is the value stored under each embedding cache key.
+type embeddingCacheEntry struct {
+	Model     string    `json:"model"`
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbeddingCacheReport summarizes the outcome of a warm-up or migration run.
+type EmbeddingCacheReport struct {
+	Scanned  int `json:"scanned"`
+	Cached   int `json:"cached"`
+	Skipped  int `json:"skipped"`
+	Deleted  int `json:"deleted"`
+	Failures int `json:"failures"`
+}
+
+// embeddingCacheKey builds the cache key for a text embedded with the given model.
+func (s *RAGService) embeddingCacheKey(model, text string) string {
+	return embeddingCachePrefix + model + ":" + GenerateCacheKey(text)
+}
+
+// storeEmbedding writes a single embedding to the cache.
+func (s *RAGService) storeEmbedding(ctx context.Context, model, text string, embedding []float32) error {
+	entryBytes, err := json.Marshal(embeddingCacheEntry{Model: model, Text: text, Embedding: embedding})
+	if err != nil {
+		return fmt.Errorf("failed to marshal embedding cache entry: %w", err)
+	}
+	return s.redisClient.Set(ctx, s.embeddingCacheKey(model, text), entryBytes, embeddingCacheTTL).Err()
+}
+
+// embedBatch calls the OpenAI embeddings endpoint once for a batch of texts.
+func (s *RAGService) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	type APIRequest struct {
+		Input []string `json:"input"`
+		Model string   `json:"model"`
+	}
+	type APIResponse struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	payloadBytes, err := json.Marshal(APIRequest{Input: texts, Model: s.config.EmbeddingModel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAI request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.OpenAIAPIURL, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenAI request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.config.OpenAIKey)
+
+	if s.embeddingLimiter != nil {
+		if err := s.embeddingLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("embedding rate limiter: %w", err)
+		}
+	}
+	body, err := s.doRequestWithRetry(req)
+	if err != nil {
+		return nil, err
+	}
+	var apiResp APIResponse
+	if json.Unmarshal(body, &apiResp) != nil {
+		return nil, fmt.Errorf("failed to unmarshal OpenAI embedding response")
+	}
+	if len(apiResp.Data) != len(texts) {
+		return nil, errors.New("mismatch between chunks and embeddings count")
+	}
+	embeddings := make([][]float32, len(texts))
+	for i, d := range apiResp.Data {
+		embeddings[i] = d.Embedding
+	}
+	return embeddings, nil
+}
+
+// embedAndCache embeds texts in batches with the current model and stores every result.
+func (s *RAGService) embedAndCache(ctx context.Context, texts []string, report *EmbeddingCacheReport) {
+	for j := 0; j < len(texts); j += embeddingBatchSize {
+		end := j + embeddingBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch := texts[j:end]
+		embeddings, err := s.embedBatch(ctx, batch)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to embed batch", "batch_size", len(batch), "error", err)
+			report.Failures += len(batch)
+			continue
+		}
+		for i, text := range batch {
+			if err := s.storeEmbedding(ctx, s.config.EmbeddingModel, text, embeddings[i]); err != nil {
+				slog.WarnContext(ctx, "Failed to set embedding cache in Redis", "error", err)
+				report.Failures++
+				continue
+			}
+			report.Cached++
+		}
+		slog.InfoContext(ctx, "Cached embeddings", "cached", report.Cached, "total", len(texts))
+	}
+}
+
+// WarmEmbeddingCache pre-computes embeddings for the given queries with the current
+// embedding model. Queries that are already cached are skipped, so it is safe to re-run.
+func (s *RAGService) WarmEmbeddingCache(ctx context.Context, queries []string) (EmbeddingCacheReport, error) {
+	report := EmbeddingCacheReport{}
+	seen := make(map[string]bool)
+	var missing []string
+	for _, q := range queries {
+		q = strings.TrimSpace(q)
+		if q == "" || seen[q] {
+			continue
+		}
+		seen[q] = true
+		report.Scanned++
+
+		exists, err := s.redisClient.Exists(ctx, s.embeddingCacheKey(s.config.EmbeddingModel, q)).Result()
+		if err != nil {
+			return report, fmt.Errorf("failed to check embedding cache: %w", err)
+		}
+		if exists > 0 {
+			report.Skipped++
+			continue
+		}
+		missing = append(missing, q)
+	}
+
+	slog.InfoContext(ctx, "Warming embedding cache", "queries", report.Scanned, "already_cached", report.Skipped)
+	s.embedAndCache(ctx, missing, &report)
+	return report, nil
+}
+
+// MigrateEmbeddingCache re-embeds every cached text that was embedded with a model other
+// than the current rag.embedding_model and stores it under the current model's key.
+// Entries written before texts were stored in the cache cannot be migrated and are skipped.
+// When deleteOld is set, the old entries (including unmigratable ones) are removed.
+func (s *RAGService) MigrateEmbeddingCache(ctx context.Context, deleteOld bool) (EmbeddingCacheReport, error) {
+	report := EmbeddingCacheReport{}
+	currentPrefix := embeddingCachePrefix + s.config.EmbeddingModel + ":"
+	var texts []string
+	var oldKeys []string
+
+	iter := s.redisClient.Scan(ctx, 0, embeddingCachePrefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if strings.HasPrefix(key, currentPrefix) {
+			continue
+		}
+		report.Scanned++
+		oldKeys = append(oldKeys, key)
+
+		raw, err := s.redisClient.Get(ctx, key).Bytes()
+		if err != nil {
+			report.Failures++
+			continue
+		}
+		var entry embeddingCacheEntry
+		if json.Unmarshal(raw, &entry) != nil || entry.Text == "" {
+			report.Skipped++ // A legacy entry that holds only the vector.
+			continue
+		}
+		texts = append(texts, entry.Text)
+	}
+	if err := iter.Err(); err != nil {
+		return report, fmt.Errorf("failed to scan embedding cache: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Migrating cached embeddings", "embeddings", len(texts), "embedding_model", s.config.EmbeddingModel, "legacy_skipped", report.Skipped)
+	s.embedAndCache(ctx, texts, &report)
+
+	if deleteOld {
+		for j := 0; j < len(oldKeys); j += embeddingBatchSize {
+			end := j + embeddingBatchSize
+			if end > len(oldKeys) {
+				end = len(oldKeys)
+			}
+			deleted, err := s.redisClient.Del(ctx, oldKeys[j:end]...).Result()
+			if err != nil {
+				return report, fmt.Errorf("failed to delete old embedding cache entries: %w", err)
+			}
+			report.Deleted += int(deleted)
+		}
+	}
+	return report, nil
+}
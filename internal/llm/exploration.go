@@ -0,0 +1,110 @@
+// In file: internal/llm/exploration.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// The exploration modes of a routing strategy.
+const (
+	// ExploreEpsilonGreedy routes a share of the requests to a random contender other than
+	// the best one.
+	ExploreEpsilonGreedy = "epsilon_greedy"
+	// ExploreThompson scores contenders with a reliability sampled from their success and
+	// failure counts rather than their error rate, so models measured over few requests
+	// are sometimes ranked first.
+	ExploreThompson = "thompson"
+)
+
+// ExplorationPolicy has a strategy occasionally route to contenders other than the best one,
+// which keeps their latency and error profiles fresh. It is the `exploration` of a strategy
+// in config.yaml.
+type ExplorationPolicy struct {
+	// Mode is "epsilon_greedy" or "thompson". Empty turns exploration off.
+	Mode string `yaml:"mode"`
+	// Rate is the share of requests, between 0 and 1, that explore. Thompson sampling
+	// defaults to every request.
+	Rate float64 `yaml:"rate"`
+}
+
+// Validate reports settings that cannot work.
+func (p ExplorationPolicy) Validate() error {
+	switch p.Mode {
+	case "", ExploreEpsilonGreedy, ExploreThompson:
+	default:
+		return fmt.Errorf("unknown exploration mode '%s' (expected '%s' or '%s')", p.Mode, ExploreEpsilonGreedy, ExploreThompson)
+	}
+	if p.Rate < 0 || p.Rate > 1 {
+		return fmt.Errorf("exploration rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// explores decides whether a request explores.
+func (p ExplorationPolicy) explores() bool {
+	rate := p.Rate
+	if p.Mode == ExploreThompson && rate == 0 {
+		rate = 1
+	}
+	return p.Mode != "" && rand.Float64() < rate
+}
+
+// ValidateExploration checks the exploration of every strategy.
+func (c *RouterConfig) ValidateExploration() error {
+	names := make([]string, 0, len(c.Strategies))
+	for name := range c.Strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := c.Strategies[name].Exploration.Validate(); err != nil {
+			return fmt.Errorf("strategy %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// exploreOtherThan picks a random contender other than the best one.
+func exploreOtherThan(ctx context.Context, contenders map[string]contender, best string) string {
+	others := make([]string, 0, len(contenders)-1)
+	for modelID := range contenders {
+		if modelID != best {
+			others = append(others, modelID)
+		}
+	}
+	sort.Strings(others)
+	modelID := others[rand.IntN(len(others))]
+	slog.InfoContext(ctx, "Exploring a contender other than the best", "selected_model", modelID, "best_model", best)
+	return modelID
+}
+
+// sampledReliability draws a model's reliability from the Beta distribution of its success
+// rate, Beta(successes+1, failures+1).
+func sampledReliability(profile *ModelProfile) float64 {
+	x := sampleGamma(float64(max(profile.TotalSuccesses, 0)) + 1)
+	y := sampleGamma(float64(max(profile.TotalFailures, 0)) + 1)
+	return x / (x + y)
+}
+
+// sampleGamma draws from Gamma(shape, 1), for shape >= 1, with Marsaglia and Tsang's method.
+func sampleGamma(shape float64) float64 {
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rand.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rand.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// In file: internal/llm/failover.go
+package llm
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// FailoverPolicy controls how a replacement is chosen when a pinned model goes offline.
+// It is configured in the `failover` section of config.yaml.
+type FailoverPolicy struct {
+	// PreferSameProvider first looks for a replacement from the failed model's provider, which
+	// keeps the conversation's style and tool-calling behaviour as consistent as possible.
+	// Other providers are only considered if no model from the same provider is available.
+	PreferSameProvider bool `yaml:"prefer_same_provider"`
+}
+
+// providerPrefixes infers a provider from a model ID when config.yaml does not name one.
+// It mirrors the prefixes used to create the LLM clients.
+var providerPrefixes = map[string]string{
+	"gpt":     "openai",
+	"claude":  "anthropic",
+	"gemini":  "google",
+	"mistral": "mistral",
+}
+
+// ProviderOf returns the provider of a model, or "" if it is unknown.
+func (r *Router) ProviderOf(modelID string) string {
+	return r.config.ProviderOf(modelID)
+}
+
+// ProviderOf returns the provider of a model: its configured provider, or the one inferred
+// from its ID. It returns "" if the provider is unknown.
+func (c *RouterConfig) ProviderOf(modelID string) string {
+	if meta, ok := c.Models[modelID]; ok && meta.Provider != "" {
+		return meta.Provider
+	}
+	for prefix, provider := range providerPrefixes {
+		if strings.HasPrefix(modelID, prefix) {
+			return provider
+		}
+	}
+	return ""
+}
+
+// SelectFailoverModel picks a replacement for a model that has gone offline. The failed model is
+// never selected. If the failover policy prefers the same provider, the router first runs over
+// that provider's models only, and only widens the search to every model if none of them is
+// healthy and within budget. The second return value reports whether the replacement came from
+// the same provider. maxCostUSD caps the estimated cost of the call as in SelectOptimalModel.
+func (r *Router) SelectFailoverModel(ctx context.Context, failedModel string, availableModels []string, preference string, promptTokens int, modelBudgets map[string]float64, maxCostUSD float64) (string, bool, error) {
+	candidates := make([]string, 0, len(availableModels))
+	for _, modelID := range availableModels {
+		if modelID != failedModel {
+			candidates = append(candidates, modelID)
+		}
+	}
+
+	provider := r.ProviderOf(failedModel)
+	if r.config.Failover.PreferSameProvider && provider != "" {
+		var sameProvider []string
+		for _, modelID := range candidates {
+			if r.ProviderOf(modelID) == provider {
+				sameProvider = append(sameProvider, modelID)
+			}
+		}
+		if len(sameProvider) > 0 {
+			modelID, err := r.SelectOptimalModel(ctx, sameProvider, preference, promptTokens, modelBudgets, maxCostUSD)
+			if err == nil {
+				slog.InfoContext(ctx, "Failing over within the same provider", "failed_model", failedModel, "selected_model", modelID, "provider", provider)
+				return modelID, true, nil
+			}
+			slog.InfoContext(ctx, "No same-provider replacement, trying other providers", "failed_model", failedModel, "provider", provider, "error", err)
+		}
+	}
+
+	modelID, err := r.SelectOptimalModel(ctx, candidates, preference, promptTokens, modelBudgets, maxCostUSD)
+	if err != nil {
+		return "", false, err
+	}
+	return modelID, provider != "" && r.ProviderOf(modelID) == provider, nil
+}
@@ -0,0 +1,222 @@
+// In file: internal/llm/forward.go
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+)
+
+// ErrForwardUnsupported is returned by Forward for clients that cannot forward requests in
+// the provider's own format.
+var ErrForwardUnsupported = errors.New("the client cannot forward native requests")
+
+// maxForwardedBodyTally bounds how much of a non-streamed forwarded response is kept to read
+// its usage from. Larger responses are passed through without being tallied.
+const maxForwardedBodyTally = 4 << 20
+
+// Forwarder is implemented by clients that can send a request body in the provider's own
+// format to the provider unchanged, and hand back its response as it arrives. Nothing is
+// decoded or re-encoded on the way, which is what makes the gateway's pass-through mode cheap.
+type Forwarder interface {
+	Forward(ctx context.Context, body []byte) (*ForwardedResponse, error)
+}
+
+// Forward sends a native request body through client, or returns ErrForwardUnsupported if
+// the client cannot forward.
+func Forward(ctx context.Context, client LLMClient, body []byte) (*ForwardedResponse, error) {
+	if forwarder, ok := client.(Forwarder); ok {
+		return forwarder.Forward(ctx, body)
+	}
+	return nil, ErrForwardUnsupported
+}
+
+// ForwardedResponse is a provider's response to a forwarded request. Reading Body yields the
+// provider's bytes unchanged, while the token usage they report is tallied for Usage.
+type ForwardedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       io.ReadCloser
+	tally      *usageTally
+}
+
+// Usage returns the token usage reported in the part of the body read so far. Read the body
+// to its end first. A streamed response only reports usage if the request asked for it (for
+// OpenAI, with stream_options.include_usage).
+func (r *ForwardedResponse) Usage() api.Usage {
+	return r.tally.usage()
+}
+
+// forwardRequest sends a prepared native request and wraps the response for tallying.
+func forwardRequest(httpClient *http.Client, req *http.Request) (*ForwardedResponse, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("forwarded request failed: %w", err)
+	}
+	tally := &usageTally{}
+	return &ForwardedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       &tallyingReader{body: resp.Body, tally: tally},
+		tally:      tally,
+	}, nil
+}
+
+// forwardedUsage holds the usage fields of the providers' responses and stream events:
+// OpenAI and Mistral report prompt and completion tokens, Anthropic input and output tokens,
+// the latter in a nested message for the first event of a stream.
+type forwardedUsage struct {
+	Usage   *forwardedUsageFields `json:"usage"`
+	Message *struct {
+		Usage *forwardedUsageFields `json:"usage"`
+	} `json:"message"`
+}
+
+// forwardedUsageFields also holds the prompt cache counts: OpenAI's are part of its prompt
+// tokens, while Anthropic's come on top of its input tokens.
+type forwardedUsageFields struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// cachedTokens returns the prompt tokens read from the cache.
+func (f *forwardedUsageFields) cachedTokens() int {
+	if f.PromptTokensDetails != nil {
+		return max(f.PromptTokensDetails.CachedTokens, f.CacheReadInputTokens)
+	}
+	return f.CacheReadInputTokens
+}
+
+// usageTally reads token usage from a response as it passes through. Streamed responses are
+// read event by event; any other body is read as a whole once it ends. Providers report
+// running totals, so the largest count seen is kept rather than a sum.
+type usageTally struct {
+	mu        sync.Mutex
+	line      []byte
+	body      []byte
+	streamed  bool
+	overflow  bool
+	prompt    int
+	completed int
+	cached    int
+	written   int
+}
+
+func (t *usageTally) write(p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.streamed && !t.overflow {
+		if len(t.body)+len(p) > maxForwardedBodyTally {
+			t.overflow, t.body = true, nil
+		} else {
+			t.body = append(t.body, p...)
+		}
+	}
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			if len(t.line)+len(p) <= maxForwardedBodyTally {
+				t.line = append(t.line, p...)
+			}
+			return
+		}
+		t.line = append(t.line, p[:i]...)
+		t.readLine(bytes.TrimSpace(t.line))
+		t.line = t.line[:0]
+		p = p[i+1:]
+	}
+}
+
+// readLine tallies one line of an event stream.
+func (t *usageTally) readLine(line []byte) {
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	t.streamed, t.body = true, nil
+	t.read(bytes.TrimSpace(data))
+}
+
+// read tallies the usage reported in one JSON document.
+func (t *usageTally) read(doc []byte) {
+	var u forwardedUsage
+	if json.Unmarshal(doc, &u) != nil {
+		return
+	}
+	for _, fields := range []*forwardedUsageFields{u.Usage, messageUsage(u)} {
+		if fields == nil {
+			continue
+		}
+		anthropicPrompt := fields.InputTokens + fields.CacheReadInputTokens + fields.CacheCreationInputTokens
+		t.prompt = max(t.prompt, fields.PromptTokens, anthropicPrompt)
+		t.completed = max(t.completed, fields.CompletionTokens, fields.OutputTokens)
+		t.cached = max(t.cached, fields.cachedTokens())
+		t.written = max(t.written, fields.CacheCreationInputTokens)
+	}
+}
+
+func messageUsage(u forwardedUsage) *forwardedUsageFields {
+	if u.Message == nil {
+		return nil
+	}
+	return u.Message.Usage
+}
+
+// finish tallies a body that was not an event stream, once it has been read to its end.
+func (t *usageTally) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.streamed && !t.overflow && len(t.body) > 0 {
+		t.read(t.body)
+	}
+	t.body = nil
+}
+
+func (t *usageTally) usage() api.Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return api.Usage{
+		PromptTokens:     t.prompt,
+		CompletionTokens: t.completed,
+		TotalTokens:      t.prompt + t.completed,
+		CachedTokens:     t.cached,
+		CacheWriteTokens: t.written,
+	}
+}
+
+// tallyingReader passes a response body through while feeding it to a usage tally.
+type tallyingReader struct {
+	body  io.ReadCloser
+	tally *usageTally
+	done  bool
+}
+
+func (r *tallyingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		r.tally.write(p[:n])
+	}
+	if err == io.EOF && !r.done {
+		r.done = true
+		r.tally.finish()
+	}
+	return n, err
+}
+
+func (r *tallyingReader) Close() error {
+	return r.body.Close()
+}
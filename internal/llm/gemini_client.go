@@ -6,34 +6,53 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"strings"
+	"sync"
 
+	"github.com/dileep-u-k/llm-gateway/internal/api"
 	"github.com/dileep-u-k/llm-gateway/internal/tools"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // GeminiClient is the client for interacting with Google's Gemini models.
 type GeminiClient struct {
 	client *genai.GenerativeModel
+	policy RequestPolicy
 }
 
 var _ LLMClient = (*GeminiClient)(nil)
 
-func NewGeminiClient(apiKey, modelID string) (*GeminiClient, error) {
+// NewGeminiClient creates a client for one Gemini model. The policy bounds each call and
+// sets how often transient failures are retried. Requests go through transport, or
+// http.DefaultTransport if it is nil.
+func NewGeminiClient(apiKey, modelID string, policy RequestPolicy, transport http.RoundTripper) (*GeminiClient, error) {
 	if apiKey == "" {
 		return nil, errors.New("gemini API key cannot be empty")
 	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
 	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	// The SDK ignores the API key option when given an HTTP client, so the key is added to
+	// each request instead. The transport also captures the response headers, which the SDK
+	// does not expose, for deprecation notices.
+	opts := []option.ClientOption{
+		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(&http.Client{Transport: &geminiKeyTransport{apiKey: apiKey, base: transport}}),
+	}
+	client, err := genai.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 	model := client.GenerativeModel(modelID)
-	return &GeminiClient{client: model}, nil
+	return &GeminiClient{client: model, policy: policy}, nil
 }
 
 // Generate performs a standard, blocking request to the Gemini API.
@@ -43,16 +62,41 @@ func (c *GeminiClient) Generate(
 	config *GenerationConfig,
 	availableTools []tools.Tool,
 ) (*GenerationResult, error) {
+	ctx, cancel := c.policy.withTimeout(ctx)
+	defer cancel()
+	ctx, headers := withResponseHeaders(ctx)
 	c.configureModel(config, availableTools)
-	chat := c.client.StartChat()
-	chat.History = toGeminiContentHistory(messages)
-
+	c.client.SystemInstruction = toGeminiSystemInstruction(messages)
 	lastMessage := messages[len(messages)-1]
-	resp, err := chat.SendMessage(ctx, genai.Text(lastMessage.Content))
+
+	var resp *genai.GenerateContentResponse
+	var err error
+	attempts := c.policy.attempts()
+	delay := c.policy.InitialRetryDelay
+	for i := 0; i < attempts; i++ {
+		// A chat session keeps the message even when sending it fails, so every attempt
+		// starts a fresh session from the same history.
+		chat := c.client.StartChat()
+		chat.History = toGeminiContentHistory(messages)
+		resp, err = chat.SendMessage(ctx, genai.Text(lastMessage.Content))
+		if err == nil || !isRetryableGeminiError(err) || i+1 == attempts {
+			break
+		}
+		slog.WarnContext(ctx, "Gemini call failed, retrying", "attempt", i+1, "error", err)
+		if sleepErr := sleepBeforeRetry(ctx, delay); sleepErr != nil {
+			break
+		}
+		delay *= 2
+	}
 	if err != nil {
 		return nil, fmt.Errorf("gemini API call failed: %w", err)
 	}
-	return parseGeminiResponse(ctx, c.client, resp)
+	result, err := parseGeminiResponse(ctx, c.client, resp)
+	if err != nil {
+		return nil, err
+	}
+	result.Deprecation = parseDeprecationHeaders(config.Model, headers.get())
+	return result, nil
 }
 
 // GenerateStream performs a streaming request to the Gemini API.
@@ -62,16 +106,23 @@ func (c *GeminiClient) GenerateStream(
 	config *GenerationConfig,
 	availableTools []tools.Tool,
 ) (<-chan *StreamingResult, error) {
+	ctx, cancel := c.policy.withTimeout(ctx)
+	ctx, headers := withResponseHeaders(ctx)
 	c.configureModel(config, availableTools)
+	c.client.SystemInstruction = toGeminiSystemInstruction(messages)
 	chat := c.client.StartChat()
 	chat.History = toGeminiContentHistory(messages)
 	lastMessage := messages[len(messages)-1]
 
-	outChan := make(chan *StreamingResult)
+	outChan := newStreamChannel(config)
 	go func() {
+		defer cancel()
 		defer close(outChan)
 		iter := chat.SendMessageStream(ctx, genai.Text(lastMessage.Content))
-		for {
+		var content strings.Builder
+		var usageMetadata *genai.UsageMetadata
+		var stopReason string
+		for first := true; ; first = false {
 			resp, err := iter.Next()
 			if err == iterator.Done {
 				break
@@ -80,6 +131,18 @@ func (c *GeminiClient) GenerateStream(
 				outChan <- &StreamingResult{Err: fmt.Errorf("gemini stream error: %w", err)}
 				return
 			}
+			// The request is only sent by the first Next, so its headers are known from then on.
+			if first {
+				if deprecation := parseDeprecationHeaders(config.Model, headers.get()); deprecation != nil {
+					outChan <- &StreamingResult{Deprecation: deprecation}
+				}
+			}
+			if resp != nil && resp.UsageMetadata != nil {
+				usageMetadata = resp.UsageMetadata
+			}
+			if resp != nil && len(resp.Candidates) > 0 && resp.Candidates[0].FinishReason != genai.FinishReasonUnspecified {
+				stopReason = geminiStopReason(resp.Candidates[0].FinishReason, false)
+			}
 			if resp != nil && len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 				var contentBuilder strings.Builder
 				for _, part := range resp.Candidates[0].Content.Parts {
@@ -87,13 +150,57 @@ func (c *GeminiClient) GenerateStream(
 						contentBuilder.WriteString(string(txt))
 					}
 				}
+				content.WriteString(contentBuilder.String())
 				outChan <- &StreamingResult{ContentDelta: contentBuilder.String()}
 			}
 		}
+		usage := c.streamUsage(ctx, usageMetadata, messages, content.String())
+		outChan <- &StreamingResult{Usage: &usage, StopReason: stopReason}
 	}()
 	return outChan, nil
 }
 
+// isRetryableGeminiError reports whether a failed call may succeed if repeated: the service
+// was unavailable, overloaded, or failed internally.
+func isRetryableGeminiError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Internal:
+		return true
+	}
+	return false
+}
+
+// streamUsage builds the usage of a finished stream. Gemini normally reports it on the last
+// chunk; any count it leaves out is filled in with CountTokens.
+func (c *GeminiClient) streamUsage(ctx context.Context, metadata *genai.UsageMetadata, messages []Message, content string) api.Usage {
+	var usage api.Usage
+	if metadata != nil {
+		usage.PromptTokens = int(metadata.PromptTokenCount)
+		usage.CompletionTokens = int(metadata.CandidatesTokenCount)
+		usage.CachedTokens = int(metadata.CachedContentTokenCount)
+	}
+	if usage.PromptTokens == 0 {
+		parts := make([]genai.Part, 0, len(messages))
+		for _, msg := range messages {
+			parts = append(parts, genai.Text(msg.Content))
+		}
+		if countResp, err := c.client.CountTokens(ctx, parts...); err != nil {
+			slog.WarnContext(ctx, "Failed to count streamed prompt tokens", "error", err)
+		} else {
+			usage.PromptTokens = int(countResp.TotalTokens)
+		}
+	}
+	if usage.CompletionTokens == 0 && content != "" {
+		if countResp, err := c.client.CountTokens(ctx, genai.Text(content)); err != nil {
+			slog.WarnContext(ctx, "Failed to count streamed completion tokens", "error", err)
+		} else {
+			usage.CompletionTokens = int(countResp.TotalTokens)
+		}
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
+
 // configureModel applies dynamic settings using the SDK's setter methods for safety.
 func (c *GeminiClient) configureModel(config *GenerationConfig, availableTools []tools.Tool) {
 	// CORRECTED: Use SDK setter methods to safely handle configuration.
@@ -115,6 +222,24 @@ func (c *GeminiClient) configureModel(config *GenerationConfig, availableTools [
 		c.client.SetMaxOutputTokens(4096)
 	}
 
+	c.client.StopSequences = nil
+	c.client.TopK = nil
+	if config != nil {
+		c.client.StopSequences = config.Stop
+		if config.TopK != nil {
+			c.client.SetTopK(int32(*config.TopK))
+		}
+	}
+
+	c.client.ResponseMIMEType = ""
+	c.client.ResponseSchema = nil
+	if format := config.jsonFormat(); format != nil {
+		c.client.ResponseMIMEType = "application/json"
+		if format.Type == ResponseFormatJSONSchema {
+			c.client.ResponseSchema = toGeminiSchema(format.Schema)
+		}
+	}
+
 	if len(availableTools) > 0 {
 		c.client.Tools = toGeminiTools(availableTools)
 	} else {
@@ -144,6 +269,10 @@ func convertSchema(s tools.JSONSchema) *genai.Schema {
 	genaiSchema := &genai.Schema{
 		Description: s.Description,
 		Required:    s.Required,
+		Enum:        s.Enum,
+	}
+	if len(s.Enum) > 0 {
+		genaiSchema.Format = "enum"
 	}
 	switch s.Type {
 	case "object":
@@ -154,6 +283,13 @@ func convertSchema(s tools.JSONSchema) *genai.Schema {
 		genaiSchema.Type = genai.TypeNumber
 	case "integer":
 		genaiSchema.Type = genai.TypeInteger
+	case "boolean":
+		genaiSchema.Type = genai.TypeBoolean
+	case "array":
+		genaiSchema.Type = genai.TypeArray
+	}
+	if s.Items != nil {
+		genaiSchema.Items = convertSchema(*s.Items)
 	}
 	if s.Properties != nil {
 		genaiSchema.Properties = make(map[string]*genai.Schema)
@@ -164,11 +300,87 @@ func convertSchema(s tools.JSONSchema) *genai.Schema {
 	return genaiSchema
 }
 
+// jsonSchemaNode is the part of a JSON Schema that Gemini understands.
+type jsonSchemaNode struct {
+	Type        json.RawMessage            `json:"type"`
+	Description string                     `json:"description"`
+	Enum        []any                      `json:"enum"`
+	Items       *jsonSchemaNode            `json:"items"`
+	Properties  map[string]*jsonSchemaNode `json:"properties"`
+	Required    []string                   `json:"required"`
+}
+
+// toGeminiSchema converts a JSON Schema to a Gemini response schema, or returns nil if the
+// schema cannot be read. Keywords Gemini does not know are dropped; the gateway validates
+// the answer against the full schema anyway.
+func toGeminiSchema(raw json.RawMessage) *genai.Schema {
+	var node jsonSchemaNode
+	if json.Unmarshal(raw, &node) != nil {
+		return nil
+	}
+	return node.toGemini()
+}
+
+func (n *jsonSchemaNode) toGemini() *genai.Schema {
+	s := &genai.Schema{Description: n.Description, Required: n.Required}
+	// The type is a name or a list of names, where "null" makes the value nullable.
+	var types []string
+	var single string
+	if json.Unmarshal(n.Type, &single) == nil {
+		types = []string{single}
+	} else {
+		_ = json.Unmarshal(n.Type, &types)
+	}
+	for _, t := range types {
+		switch t {
+		case "object":
+			s.Type = genai.TypeObject
+		case "array":
+			s.Type = genai.TypeArray
+		case "string":
+			s.Type = genai.TypeString
+		case "number":
+			s.Type = genai.TypeNumber
+		case "integer":
+			s.Type = genai.TypeInteger
+		case "boolean":
+			s.Type = genai.TypeBoolean
+		case "null":
+			s.Nullable = true
+		}
+	}
+	// Gemini only takes enums of strings.
+	for _, v := range n.Enum {
+		str, ok := v.(string)
+		if !ok {
+			s.Enum = nil
+			break
+		}
+		s.Enum = append(s.Enum, str)
+	}
+	if n.Items != nil {
+		s.Items = n.Items.toGemini()
+	}
+	if n.Properties != nil {
+		s.Properties = make(map[string]*genai.Schema, len(n.Properties))
+		for name, prop := range n.Properties {
+			if prop != nil {
+				s.Properties[name] = prop.toGemini()
+			}
+		}
+	}
+	return s
+}
+
 // toGeminiContentHistory converts our message history to the Gemini SDK's format.
 func toGeminiContentHistory(messages []Message) []*genai.Content {
 	var history []*genai.Content
 	// The last message is the new prompt, so we exclude it from history
 	for _, msg := range messages[:len(messages)-1] {
+		// System messages are sent separately as the model's system instruction.
+		if msg.Role == RoleSystem {
+			continue
+		}
 		role := "user"
 		if msg.Role == RoleAssistant {
 			role = "model"
@@ -181,6 +393,38 @@ func toGeminiContentHistory(messages []Message) []*genai.Content {
 	return history
 }
 
+// toGeminiSystemInstruction combines all system messages into Gemini's system instruction.
+// It returns nil when the conversation has no system messages.
+func toGeminiSystemInstruction(messages []Message) *genai.Content {
+	var parts []genai.Part
+	for _, msg := range messages {
+		if msg.Role == RoleSystem {
+			parts = append(parts, genai.Text(msg.Content))
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return &genai.Content{Parts: parts}
+}
+
+// geminiStopReason maps a Gemini finish reason onto a StopReason constant. Gemini reports a
+// natural stop when the model calls functions, so calledTools tells the two apart.
+func geminiStopReason(reason genai.FinishReason, calledTools bool) string {
+	switch reason {
+	case genai.FinishReasonStop:
+		if calledTools {
+			return StopReasonToolCalls
+		}
+		return StopReasonStop
+	case genai.FinishReasonMaxTokens:
+		return StopReasonLength
+	case genai.FinishReasonSafety, genai.FinishReasonRecitation:
+		return StopReasonContentFilter
+	}
+	return ""
+}
+
 // parseGeminiResponse converts a Gemini API response into our internal GenerationResult.
 func parseGeminiResponse(
 	ctx context.Context, // ADDED: Pass context for the new API call
@@ -202,7 +446,7 @@ func parseGeminiResponse(
 		case genai.FunctionCall:
 			argsMap, err := json.Marshal(v.Args)
 			if err != nil {
-				log.Printf("Warning: could not marshal tool call args: %v", err)
+				slog.WarnContext(ctx, "Could not marshal tool call args", "error", err)
 				continue
 			}
 			toolCalls = append(toolCalls, &tools.ToolCall{
@@ -217,8 +461,9 @@ func parseGeminiResponse(
 	}
 
 	result := &GenerationResult{
-		Content:   strings.TrimSpace(contentBuilder.String()),
-		ToolCalls: toolCalls,
+		Content:    strings.TrimSpace(contentBuilder.String()),
+		ToolCalls:  toolCalls,
+		StopReason: geminiStopReason(candidate.FinishReason, len(toolCalls) > 0),
 	}
 
 	// *** THIS IS THE FIX ***
@@ -227,6 +472,7 @@ func parseGeminiResponse(
 		result.Usage.PromptTokens = int(resp.UsageMetadata.PromptTokenCount)
 		result.Usage.CompletionTokens = int(resp.UsageMetadata.CandidatesTokenCount)
 		result.Usage.TotalTokens = int(resp.UsageMetadata.TotalTokenCount)
+		result.Usage.CachedTokens = int(resp.UsageMetadata.CachedContentTokenCount)
 	}
 
 	// Fallback: If completion tokens are zero but we have content, count them manually.
@@ -235,7 +481,7 @@ func parseGeminiResponse(
 		// log.Println("Gemini completion tokens were 0, performing manual count...")
 		countResp, err := client.CountTokens(ctx, genai.Text(result.Content))
 		if err != nil {
-			log.Printf("Warning: Failed to manually count completion tokens: %v", err)
+			slog.WarnContext(ctx, "Failed to manually count completion tokens", "error", err)
 		} else {
 			result.Usage.CompletionTokens = int(countResp.TotalTokens)
 			// Recalculate total
@@ -245,3 +491,70 @@ func parseGeminiResponse(
 
 	return result, nil
 }
+
+// geminiKeyTransport authenticates Gemini API requests made through a custom transport.
+type geminiKeyTransport struct {
+	apiKey string
+	base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *geminiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("x-goog-api-key", t.apiKey)
+	resp, err := t.base.RoundTrip(req)
+	if recorder, ok := req.Context().Value(responseHeadersKey{}).(*responseHeaders); ok && err == nil {
+		recorder.set(resp.Header)
+	}
+	return resp, err
+}
+
+// responseHeadersKey is the context key of the responseHeaders of a Gemini call.
+type responseHeadersKey struct{}
+
+// responseHeaders holds the headers of the last response to a Gemini call.
+type responseHeaders struct {
+	mu     sync.Mutex
+	header http.Header
+}
+
+// withResponseHeaders returns a context in which geminiKeyTransport records response headers.
+func withResponseHeaders(ctx context.Context) (context.Context, *responseHeaders) {
+	recorder := &responseHeaders{}
+	return context.WithValue(ctx, responseHeadersKey{}, recorder), recorder
+}
+
+func (r *responseHeaders) set(header http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header = header
+}
+
+func (r *responseHeaders) get() http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.header
+}
+
+// Ping checks that the client's model is available by fetching its metadata from Gemini.
+// Each Gemini client serves a single model, so modelID is not used.
+func (c *GeminiClient) Ping(ctx context.Context, modelID string) error {
+	if _, err := c.client.Info(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+// CountTokens counts the input tokens of the messages with Gemini's countTokens method.
+// Every message, including system messages, is counted as text.
+func (c *GeminiClient) CountTokens(ctx context.Context, modelID string, messages []Message) (int, error) {
+	parts := make([]genai.Part, 0, len(messages))
+	for _, msg := range messages {
+		parts = append(parts, genai.Text(msg.Content))
+	}
+	resp, err := c.client.CountTokens(ctx, parts...)
+	if err != nil {
+		return 0, fmt.Errorf("countTokens failed: %w", err)
+	}
+	return int(resp.TotalTokens), nil
+}
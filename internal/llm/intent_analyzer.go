@@ -2,9 +2,15 @@
 package llm
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Define constants for the different intents we can detect.
@@ -12,47 +18,329 @@ const (
 	IntentWeather    = "weather"
 	IntentCalculator = "calculator"
 	IntentNews       = "news"
+	IntentCode       = "code_execution"
 	IntentRAG        = "rag_knowledge_query"
 )
 
-// calculatorRegex is a simple regex to detect mathematical expressions.
-var calculatorRegex = regexp.MustCompile(`\d+\s*[\+\-\*\/]\s*\d+`)
+// knownIntents are the built-in intents the gateway acts on.
+var knownIntents = []string{IntentWeather, IntentCalculator, IntentNews, IntentCode, IntentRAG}
 
-// IntentAnalyzer is now a simpler service. It no longer needs Redis.
-type IntentAnalyzer struct{}
+// customIntentName is the form of a custom intent's name.
+var customIntentName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
 
-// NewIntentAnalyzer is now simpler and has no dependencies.
-func NewIntentAnalyzer() *IntentAnalyzer {
-	return &IntentAnalyzer{}
+// CustomIntent is an intent defined in config.yaml. A prompt classified as it is answered by
+// a tool loop restricted to its tools, so a team adding e.g. a Jira tool can route "create a
+// ticket for..." prompts to it. Besides its own keywords and patterns, which are tried before
+// the other rules, it is matched by training examples in data/intents/<name>.txt and chosen
+// by the fallback classifier according to its description.
+type CustomIntent struct {
+	Name string `yaml:"name"`
+	// Description tells the fallback classifier which prompts the intent is for.
+	Description string `yaml:"description"`
+	// Tools are the tools its tool loop may use, within the request's tool policy.
+	Tools    []string `yaml:"tools"`
+	Keywords []string `yaml:"keywords"`
+	Patterns []string `yaml:"patterns"`
 }
 
-// AnalyzeIntent now only performs fast checks for tool intents.
-// If no tool is found, it defaults to assuming the user is asking a knowledge question.
-func (ia *IntentAnalyzer) AnalyzeIntent(prompt string) string {
-	lowerPrompt := strings.ToLower(prompt)
+// IntentRoute routes the requests of an intent that set no preference: to a model, or by a
+// strategy in place of the preference the prompt analyzer would select. Exactly one of the two
+// is set.
+type IntentRoute struct {
+	Model    string `yaml:"model"`
+	Strategy string `yaml:"strategy"`
+}
+
+// IntentFallbackOff disables the model fallback when set as the fallback model.
+const IntentFallbackOff = "off"
 
-	// --- Fast Path: Keyword and Regex Checks for Tools ---
-	weatherKeywords := []string{"weather", "forecast", "temperature", "how hot is it", "is it raining"}
-	for _, keyword := range weatherKeywords {
-		if strings.Contains(lowerPrompt, keyword) {
-			log.Printf("Intent detected by keyword '%s': %s", keyword, IntentWeather)
-			return IntentWeather
+// IntentConfig is the `intents` section of config.yaml. It defines the keyword rules, and
+// tunes how prompts are classified by their nearest training examples, and by a model when
+// neither they nor the keyword rules are sure.
+type IntentConfig struct {
+	// Rules are the keyword and pattern rules, tried in order. When unset, the built-in
+	// rules are used; an empty list disables keyword matching.
+	Rules []IntentRule `yaml:"rules"`
+	// Custom defines intents beyond the built-in ones.
+	Custom []CustomIntent `yaml:"custom"`
+	// Routing maps intents to the model or strategy their requests are routed by, e.g. news
+	// to the cheapest model. Intents without a route are routed like any other request.
+	Routing map[string]IntentRoute `yaml:"routing"`
+	// Neighbors is how many of the most similar examples vote on a prompt's intent.
+	Neighbors int `yaml:"neighbors"`
+	// MinConfidence is the confidence below which the vote is ignored and the keyword rules
+	// decide instead.
+	MinConfidence float64 `yaml:"min_confidence"`
+	// FallbackModel classifies the prompts that neither the examples nor the keyword rules
+	// are sure of. It defaults to the enabled model with the lowest input cost; "off"
+	// disables the fallback.
+	FallbackModel string `yaml:"fallback_model"`
+	// FallbackTimeout bounds a fallback call. A prompt whose call fails or times out is
+	// treated as a knowledge query.
+	FallbackTimeout time.Duration `yaml:"fallback_timeout"`
+	// FallbackCacheTTL is how long the fallback's answer for a prompt is cached.
+	FallbackCacheTTL time.Duration `yaml:"fallback_cache_ttl"`
+}
+
+// WithDefaults fills in the settings that were left unset.
+func (c IntentConfig) WithDefaults() IntentConfig {
+	if c.Neighbors == 0 {
+		c.Neighbors = 5
+	}
+	if c.MinConfidence == 0 {
+		c.MinConfidence = 0.45
+	}
+	if c.FallbackTimeout == 0 {
+		c.FallbackTimeout = 3 * time.Second
+	}
+	if c.FallbackCacheTTL == 0 {
+		c.FallbackCacheTTL = 24 * time.Hour
+	}
+	return c
+}
+
+// Validate reports settings that cannot work.
+func (c IntentConfig) Validate() error {
+	if c.Neighbors < 0 {
+		return fmt.Errorf("neighbors must not be negative")
+	}
+	if c.MinConfidence < 0 || c.MinConfidence > 1 {
+		return fmt.Errorf("min_confidence must be between 0 and 1")
+	}
+	if c.FallbackTimeout < 0 || c.FallbackCacheTTL < 0 {
+		return fmt.Errorf("fallback_timeout and fallback_cache_ttl must not be negative")
+	}
+	seen := make(map[string]bool, len(c.Custom))
+	for _, custom := range c.Custom {
+		switch {
+		case !customIntentName.MatchString(custom.Name):
+			return fmt.Errorf("custom intent '%s': the name must be lowercase letters, digits, and underscores", custom.Name)
+		case slices.Contains(knownIntents, custom.Name) || seen[custom.Name]:
+			return fmt.Errorf("custom intent '%s' is defined twice or shadows a built-in intent", custom.Name)
+		case len(custom.Tools) == 0:
+			return fmt.Errorf("custom intent '%s' must list its tools", custom.Name)
 		}
+		seen[custom.Name] = true
+	}
+	names := c.Names()
+	for intent, route := range c.Routing {
+		switch {
+		case !slices.Contains(names, intent):
+			return fmt.Errorf("routing: unknown intent '%s' (expected one of %s)", intent, strings.Join(names, ", "))
+		case (route.Model == "") == (route.Strategy == ""):
+			return fmt.Errorf("routing of intent '%s' must set either a model or a strategy", intent)
+		}
+	}
+	_, err := compileIntentRules(c)
+	return err
+}
+
+// Names returns the names of every intent, built-in and custom.
+func (c IntentConfig) Names() []string {
+	names := slices.Clone(knownIntents)
+	for _, custom := range c.Custom {
+		names = append(names, custom.Name)
 	}
-	newsKeywords := []string{"news", "headlines", "latest on", "what's happening in"}
-	for _, keyword := range newsKeywords {
-		if strings.Contains(lowerPrompt, keyword) {
-			log.Printf("Intent detected by keyword '%s': %s", keyword, IntentNews)
-			return IntentNews
+	return names
+}
+
+// CustomIntent returns the custom intent with the given name.
+func (c IntentConfig) CustomIntent(name string) (CustomIntent, bool) {
+	for _, custom := range c.Custom {
+		if custom.Name == name {
+			return custom, true
 		}
 	}
-	if calculatorRegex.MatchString(lowerPrompt) {
-		log.Printf("Intent detected by regex: %s", IntentCalculator)
-		return IntentCalculator
+	return CustomIntent{}, false
+}
+
+// IntentEmbedder embeds prompts so they can be compared with the training examples. The
+// RAGService is one, and caches the embedding for the retrieval that usually follows.
+type IntentEmbedder interface {
+	GetEmbedding(ctx context.Context, text string) ([]float32, error)
+}
+
+// IntentAnalyzer detects what a prompt asks for. With training examples it classifies a
+// prompt by its most similar examples, and falls back to keyword rules when it has none,
+// when the prompt cannot be embedded, or when the examples do not agree well enough. A
+// prompt no keyword matches either is left to the fallback classifier, if there is one.
+type IntentAnalyzer struct {
+	rules      []intentRule
+	embedder   IntentEmbedder
+	examples   []IntentExample
+	config     IntentConfig
+	classifier *LLMIntentClassifier
+	cache      *DecisionCache
+	version    string
+}
+
+// NewIntentAnalyzer creates an analyzer that uses the configured keyword rules.
+func NewIntentAnalyzer(cfg IntentConfig) (*IntentAnalyzer, error) {
+	rules, err := compileIntentRules(cfg)
+	if err != nil {
+		return nil, err
 	}
+	return &IntentAnalyzer{rules: rules, config: cfg.WithDefaults()}, nil
+}
+
+// SetExamples makes the analyzer classify prompts by their nearest training examples, as
+// stored by the ingestor, before trying the keyword rules. It must be called before the
+// analyzer is used.
+func (ia *IntentAnalyzer) SetExamples(embedder IntentEmbedder, examples []IntentExample) {
+	ia.embedder, ia.examples = embedder, examples
+}
+
+// SetFallbackClassifier makes the analyzer ask classifier for the intent of prompts it is
+// unsure of. It must be called before the analyzer is used.
+func (ia *IntentAnalyzer) SetFallbackClassifier(classifier *LLMIntentClassifier) {
+	ia.classifier = classifier
+}
 
-	// If no specific tool is detected, default to a RAG knowledge query.
-	// The RAG system's own confidence score will then decide if the context is used.
-	log.Println("No tool intent detected. Defaulting to RAG knowledge query.")
-	return IntentRAG
-}
\ No newline at end of file
+// SetDecisionCache makes the analyzer cache its decision about each prompt. It must be called
+// after SetExamples and SetFallbackClassifier, as their examples and model are part of the
+// cached decisions' version, and before the analyzer is used.
+func (ia *IntentAnalyzer) SetDecisionCache(cache *DecisionCache) {
+	examples := make([]string, len(ia.examples))
+	for i, example := range ia.examples {
+		examples[i] = example.Intent + ":" + example.Text
+	}
+	classifier := ""
+	if ia.classifier != nil {
+		classifier = ia.classifier.Model()
+	}
+	ia.cache, ia.version = cache, analyzerVersion(ia.config, examples, classifier)
+}
+
+// IntentDecision explains how an intent was chosen, so that a tool call can be traced
+// back to the exact example, keyword, or pattern that triggered it.
+type IntentDecision struct {
+	// Intent is the detected intent (one of the Intent* constants).
+	Intent string
+	// Matcher is the kind of rule that fired: "embedding", "keyword", "regex", "llm", or
+	// "default".
+	Matcher string
+	// Pattern is the nearest training example, the keyword or regular expression that
+	// matched, or the model that classified the prompt, if any.
+	Pattern string
+	// Confidence is how strongly the nearest examples agree on the intent, from 0 to 1. It
+	// is only set by the "embedding" matcher.
+	Confidence float64
+	// Language is the detected language of the prompt, or "" if it could not be told.
+	Language string
+}
+
+// AnalyzeIntent returns the intent of a prompt. If no tool is asked for, it defaults to
+// assuming the user is asking a knowledge question.
+func (ia *IntentAnalyzer) AnalyzeIntent(ctx context.Context, prompt string) string {
+	return ia.AnalyzeIntentDetailed(ctx, prompt).Intent
+}
+
+// AnalyzeIntentDetailed performs the same checks as AnalyzeIntent but also reports
+// which rule produced the decision, for the audit trail and debug responses.
+func (ia *IntentAnalyzer) AnalyzeIntentDetailed(ctx context.Context, prompt string) IntentDecision {
+	var key string
+	if ia.cache != nil {
+		key = decisionKey("intent", ia.version, prompt)
+		var cached IntentDecision
+		if ia.cache.get(ctx, key, &cached) {
+			slog.DebugContext(ctx, "Intent decision served from cache", "intent", cached.Intent)
+			return cached
+		}
+	}
+	language := DetectLanguage(prompt)
+	decision, final := ia.analyze(ctx, prompt, language)
+	decision.Language = language
+	if ia.cache != nil && final {
+		ia.cache.set(ctx, key, decision)
+	}
+	return decision
+}
+
+// analyze decides the intent of a prompt. The decision is not final when it was made without
+// the examples or the classifier because they failed, and must not outlive the request.
+func (ia *IntentAnalyzer) analyze(ctx context.Context, prompt, language string) (IntentDecision, bool) {
+	decision, ok, err := ia.classifyByExamples(ctx, prompt)
+	if ok {
+		slog.DebugContext(ctx, "Intent detected by training examples", "intent", decision.Intent, "confidence", decision.Confidence)
+		return decision, true
+	}
+	final := err == nil
+	if err != nil {
+		slog.WarnContext(ctx, "Could not embed the prompt for intent analysis; using keywords", "error", err)
+	}
+	decision = matchRules(ia.rules, prompt, language)
+	if decision.Matcher != "default" || ia.classifier == nil {
+		return decision, final
+	}
+	intent, err := ia.classifier.Classify(ctx, prompt)
+	if err != nil {
+		slog.WarnContext(ctx, "Intent classifier failed; treating the prompt as a knowledge query", "model", ia.classifier.Model(), "error", err)
+		return decision, false
+	}
+	slog.DebugContext(ctx, "Intent detected by classifier model", "intent", intent, "model", ia.classifier.Model())
+	return IntentDecision{Intent: intent, Matcher: "llm", Pattern: ia.classifier.Model()}, final
+}
+
+// exampleMatch is a training example and its similarity to a prompt.
+type exampleMatch struct {
+	example    *IntentExample
+	similarity float64
+}
+
+// classifyByExamples lets the training examples most similar to the prompt vote on its
+// intent, each with its similarity. The confidence is the winning intent's share of the
+// neighbors, weighted by similarity: it is high only when the nearest examples are both
+// close and in agreement. It fails only if the prompt cannot be embedded.
+func (ia *IntentAnalyzer) classifyByExamples(ctx context.Context, prompt string) (IntentDecision, bool, error) {
+	if len(ia.examples) == 0 || ia.embedder == nil {
+		return IntentDecision{}, false, nil
+	}
+	embedding, err := ia.embedder.GetEmbedding(ctx, prompt)
+	if err != nil {
+		return IntentDecision{}, false, err
+	}
+	matches := make([]exampleMatch, 0, len(ia.examples))
+	for i := range ia.examples {
+		if len(ia.examples[i].Embedding) != len(embedding) {
+			continue
+		}
+		matches = append(matches, exampleMatch{example: &ia.examples[i], similarity: cosineSimilarity(embedding, ia.examples[i].Embedding)})
+	}
+	if len(matches) == 0 {
+		return IntentDecision{}, false, nil
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].similarity > matches[j].similarity })
+	neighbors := matches[:min(ia.config.Neighbors, len(matches))]
+
+	votes := make(map[string]float64)
+	for _, m := range neighbors {
+		votes[m.example.Intent] += max(m.similarity, 0)
+	}
+	var decision IntentDecision
+	for _, m := range neighbors {
+		// Neighbors are visited from the nearest, so ties go to the intent of the nearest.
+		if votes[m.example.Intent] > votes[decision.Intent] {
+			decision = IntentDecision{Intent: m.example.Intent, Matcher: "embedding", Pattern: m.example.Text}
+		}
+	}
+	decision.Confidence = votes[decision.Intent] / float64(len(neighbors))
+	if decision.Intent == "" || decision.Confidence < ia.config.MinConfidence {
+		slog.DebugContext(ctx, "Training examples are not confident about the intent; using keywords", "intent", decision.Intent, "confidence", decision.Confidence)
+		return IntentDecision{}, false, nil
+	}
+	return decision, true, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors of equal length.
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
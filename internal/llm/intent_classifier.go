@@ -0,0 +1,141 @@
+// In file: internal/llm/intent_classifier.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// intentClassifierKeyPrefix, followed by the model and the prompt's hash, caches the
+	// classifier's answer for a prompt.
+	intentClassifierKeyPrefix = "intent_llm:"
+	// intentClassifierMaxTokens is enough for the longest intent label.
+	intentClassifierMaxTokens = 10
+	// intentClassifierInputLimit caps how much of the prompt is sent to the classifier.
+	intentClassifierInputLimit = 2000
+)
+
+// builtinIntentDescriptions tell the classifier when to choose each built-in intent, in the
+// order they are listed to it. The knowledge query comes last, as the catch-all.
+var builtinIntentDescriptions = []struct{ intent, description string }{
+	{IntentWeather, "needs current or forecast weather for a place, even if weather is not named (e.g. \"do I need an umbrella today?\")."},
+	{IntentNews, "needs recent news, headlines, or current events."},
+	{IntentCalculator, "needs arithmetic or a numeric calculation."},
+	{IntentCode, "needs code to be run or data to be analysed by running code."},
+}
+
+// intentClassifierInstruction constrains the classifier to answer with one intent label,
+// custom intents included.
+func intentClassifierInstruction(custom []CustomIntent) string {
+	var b strings.Builder
+	b.WriteString("Classify the user's message, which may be in any language, by what the assistant must do to answer it. ")
+	b.WriteString("Reply with exactly one of these labels and nothing else:\n")
+	for _, d := range builtinIntentDescriptions {
+		fmt.Fprintf(&b, "%s: %s\n", d.intent, d.description)
+	}
+	for _, c := range custom {
+		description := c.Description
+		if description == "" {
+			// Without a description, the label's words are all the model has to go on.
+			description = "about " + strings.ReplaceAll(c.Name, "_", " ") + "."
+		}
+		fmt.Fprintf(&b, "%s: %s\n", c.Name, description)
+	}
+	fmt.Fprintf(&b, "%s: anything else, such as a knowledge question or a conversation.", IntentRAG)
+	return b.String()
+}
+
+// LLMIntentClassifier asks a (cheap) model for the intent of a prompt the other matchers are
+// unsure of. Its answers are cached by prompt, so a prompt is only classified once.
+type LLMIntentClassifier struct {
+	client      LLMClient
+	modelID     string
+	rdb         *redis.Client
+	cacheTTL    time.Duration
+	timeout     time.Duration
+	instruction string
+	labels      []string
+}
+
+// NewLLMIntentClassifier creates a classifier that asks modelID through client to choose
+// between the intents of cfg, caching its answers in Redis for cfg.FallbackCacheTTL. Each
+// call is bounded by cfg.FallbackTimeout.
+func NewLLMIntentClassifier(client LLMClient, modelID string, rdb *redis.Client, cfg IntentConfig) *LLMIntentClassifier {
+	cfg = cfg.WithDefaults()
+	return &LLMIntentClassifier{
+		client:      client,
+		modelID:     modelID,
+		rdb:         rdb,
+		cacheTTL:    cfg.FallbackCacheTTL,
+		timeout:     cfg.FallbackTimeout,
+		instruction: intentClassifierInstruction(cfg.Custom),
+		labels:      cfg.Names(),
+	}
+}
+
+// Model returns the model that classifies prompts.
+func (c *LLMIntentClassifier) Model() string {
+	return c.modelID
+}
+
+// Classify returns the intent of prompt, from the cache or by asking the model.
+func (c *LLMIntentClassifier) Classify(ctx context.Context, prompt string) (string, error) {
+	// The instruction is part of the key, so defining a new intent reclassifies every prompt.
+	key := intentClassifierKeyPrefix + c.modelID + ":" + GenerateCacheKey(c.instruction+"\x00"+prompt)
+	if intent, err := c.rdb.Get(ctx, key).Result(); err == nil {
+		return intent, nil
+	} else if err != redis.Nil {
+		slog.WarnContext(ctx, "Redis GET error for classified intent", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	if runes := []rune(prompt); len(runes) > intentClassifierInputLimit {
+		prompt = string(runes[:intentClassifierInputLimit])
+	}
+	messages := []Message{
+		{Role: RoleSystem, Content: c.instruction},
+		{Role: RoleUser, Content: prompt},
+	}
+	temperature := float32(0)
+	result, err := c.client.Generate(ctx, messages, &GenerationConfig{Model: c.modelID, MaxTokens: intentClassifierMaxTokens, Temperature: &temperature}, nil)
+	if err != nil {
+		return "", fmt.Errorf("intent classifier call failed: %w", err)
+	}
+	intent, ok := parseLabel(result.Content, c.labels)
+	if !ok {
+		return "", fmt.Errorf("intent classifier answered with an unknown label %q", result.Content)
+	}
+	if err := c.rdb.Set(ctx, key, intent, c.cacheTTL).Err(); err != nil {
+		slog.WarnContext(ctx, "Failed to cache classified intent", "error", err)
+	}
+	return intent, nil
+}
+
+// parseLabel finds the label in a classifier's answer, tolerating the quotes, punctuation,
+// and capitalisation models tend to add.
+func parseLabel(answer string, labels []string) (string, bool) {
+	label := strings.ToLower(strings.Trim(strings.TrimSpace(answer), "\"'`*.:"))
+	for _, l := range labels {
+		if label == l {
+			return l, true
+		}
+	}
+	// Some models answer with a sentence; accept it if it names exactly one label.
+	found := ""
+	for _, l := range labels {
+		if strings.Contains(label, l) {
+			if found != "" {
+				return "", false
+			}
+			found = l
+		}
+	}
+	return found, found != ""
+}
@@ -0,0 +1,121 @@
+// In file: internal/llm/intent_examples.go
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// intentExamplesKeyPrefix, followed by the embedding model, is the Redis key of the embedded
+// intent training examples. Keying by model keeps the gateway from comparing prompts with
+// examples embedded by another model.
+const intentExamplesKeyPrefix = "intent_examples:"
+
+// IntentExample is a training example of an intent, with its embedding.
+type IntentExample struct {
+	Intent    string    `json:"intent"`
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// ReadIntentExamples reads the training examples in dir, which holds one file per intent
+// named after it (weather.txt) with one example prompt per line. Blank lines and lines
+// starting with # are skipped. Files of intents other than the given ones are an error,
+// since the gateway could not act on them.
+func ReadIntentExamples(dir string, intents []string) (map[string][]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	examples := make(map[string][]string, len(files))
+	for _, path := range files {
+		intent := strings.TrimSuffix(filepath.Base(path), ".txt")
+		if !slices.Contains(intents, intent) {
+			return nil, fmt.Errorf("%s: unknown intent '%s' (expected one of %s)", path, intent, strings.Join(intents, ", "))
+		}
+		lines, err := readExampleLines(path)
+		if err != nil {
+			return nil, err
+		}
+		examples[intent] = lines
+	}
+	return examples, nil
+}
+
+func readExampleLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// StoreIntentExamples embeds the training examples of every intent and replaces the stored
+// examples with them. It returns the number of examples stored.
+func (s *RAGService) StoreIntentExamples(ctx context.Context, examples map[string][]string) (int, error) {
+	intents := make([]string, 0, len(examples))
+	for intent := range examples {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+	var stored []IntentExample
+	for _, intent := range intents {
+		texts := examples[intent]
+		for start := 0; start < len(texts); start += embeddingBatchSize {
+			batch := texts[start:min(start+embeddingBatchSize, len(texts))]
+			embeddings, err := s.embedBatch(ctx, batch)
+			if err != nil {
+				return 0, fmt.Errorf("failed to embed the examples of intent %s: %w", intent, err)
+			}
+			for i, text := range batch {
+				stored = append(stored, IntentExample{Intent: intent, Text: text, Embedding: embeddings[i]})
+			}
+		}
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal intent examples: %w", err)
+	}
+	if err := s.redisClient.Set(ctx, intentExamplesKeyPrefix+s.config.EmbeddingModel, data, 0).Err(); err != nil {
+		return 0, fmt.Errorf("failed to store intent examples: %w", err)
+	}
+	return len(stored), nil
+}
+
+// LoadIntentExamples returns the training examples the ingestor stored for the configured
+// embedding model, or none if it has not stored any.
+func (s *RAGService) LoadIntentExamples(ctx context.Context) ([]IntentExample, error) {
+	data, err := s.redisClient.Get(ctx, intentExamplesKeyPrefix+s.config.EmbeddingModel).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load intent examples: %w", err)
+	}
+	var examples []IntentExample
+	if err := json.Unmarshal(data, &examples); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal intent examples: %w", err)
+	}
+	return examples, nil
+}
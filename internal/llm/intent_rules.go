@@ -0,0 +1,139 @@
+// In file: internal/llm/intent_rules.go
+package llm
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// IntentRule triggers an intent when a prompt contains one of its keywords or matches one of
+// its patterns. Both are matched against the lowercased prompt, so keywords are written in
+// lowercase; a rule may hold keywords of several languages.
+type IntentRule struct {
+	Intent string `yaml:"intent"`
+	// Language restricts the rule to prompts detected as that language (an ISO 639-1 code
+	// such as "es"), for keywords that mean something else in other languages. A rule
+	// without one applies to every prompt.
+	Language string   `yaml:"language"`
+	Keywords []string `yaml:"keywords"`
+	// Patterns are regular expressions in Go's syntax.
+	Patterns []string `yaml:"patterns"`
+}
+
+// defaultIntentRules are the rules used when config.yaml sets none: the English rules,
+// followed by a pack of keywords for each language DetectLanguage knows.
+var defaultIntentRules = []IntentRule{
+	{Intent: IntentWeather, Keywords: []string{"weather", "forecast", "temperature", "how hot is it", "is it raining"}},
+	{Intent: IntentNews, Keywords: []string{"news", "headlines", "latest on", "what's happening in"}},
+	{Intent: IntentCode, Keywords: []string{"run this code", "execute this code", "run the following", "write and run", "analyze this data", "analyse this data"}},
+	{Intent: IntentCalculator, Patterns: []string{`\d+\s*[\+\-\*\/]\s*\d+`}},
+
+	{Intent: IntentWeather, Language: LanguageSpanish, Keywords: []string{"clima", "qué tiempo hace", "el tiempo en", "pronóstico", "temperatura", "está lloviendo", "va a llover"}},
+	{Intent: IntentNews, Language: LanguageSpanish, Keywords: []string{"noticias", "titulares", "qué está pasando en"}},
+	{Intent: IntentCode, Language: LanguageSpanish, Keywords: []string{"ejecuta este código", "ejecutar este código", "analiza estos datos"}},
+	{Intent: IntentWeather, Language: LanguageFrench, Keywords: []string{"météo", "quel temps fait", "prévisions", "température", "il pleut", "va-t-il pleuvoir"}},
+	{Intent: IntentNews, Language: LanguageFrench, Keywords: []string{"actualités", "nouvelles", "gros titres", "ce qui se passe"}},
+	{Intent: IntentCode, Language: LanguageFrench, Keywords: []string{"exécute ce code", "exécuter ce code", "analyse ces données"}},
+	{Intent: IntentWeather, Language: LanguageGerman, Keywords: []string{"wetter", "vorhersage", "temperatur", "regnet es"}},
+	{Intent: IntentNews, Language: LanguageGerman, Keywords: []string{"nachrichten", "schlagzeilen", "neuigkeiten", "was passiert in"}},
+	{Intent: IntentCode, Language: LanguageGerman, Keywords: []string{"führe diesen code aus", "code ausführen", "analysiere diese daten"}},
+	{Intent: IntentWeather, Language: LanguagePortuguese, Keywords: []string{"clima", "previsão do tempo", "temperatura", "está chovendo", "vai chover"}},
+	{Intent: IntentNews, Language: LanguagePortuguese, Keywords: []string{"notícias", "manchetes", "o que está acontecendo em"}},
+	{Intent: IntentCode, Language: LanguagePortuguese, Keywords: []string{"execute este código", "executar este código", "analise estes dados"}},
+	{Intent: IntentWeather, Language: LanguageItalian, Keywords: []string{"meteo", "che tempo fa", "previsioni", "temperatura", "sta piovendo", "pioverà"}},
+	{Intent: IntentNews, Language: LanguageItalian, Keywords: []string{"notizie", "cosa succede a"}},
+	{Intent: IntentCode, Language: LanguageItalian, Keywords: []string{"esegui questo codice", "eseguire questo codice", "analizza questi dati"}},
+	{Intent: IntentWeather, Language: LanguageChinese, Keywords: []string{"天气", "气温", "下雨"}},
+	{Intent: IntentNews, Language: LanguageChinese, Keywords: []string{"新闻", "头条"}},
+	{Intent: IntentWeather, Language: LanguageJapanese, Keywords: []string{"天気", "気温", "雨が降"}},
+	{Intent: IntentNews, Language: LanguageJapanese, Keywords: []string{"ニュース"}},
+	{Intent: IntentWeather, Language: LanguageKorean, Keywords: []string{"날씨", "기온"}},
+	{Intent: IntentNews, Language: LanguageKorean, Keywords: []string{"뉴스"}},
+	{Intent: IntentWeather, Language: LanguageRussian, Keywords: []string{"погода", "погоду", "прогноз", "температура"}},
+	{Intent: IntentNews, Language: LanguageRussian, Keywords: []string{"новости", "заголовки"}},
+}
+
+// ruleLanguage is the form of a rule's language.
+var ruleLanguage = regexp.MustCompile(`^[a-z]{2}$`)
+
+// intentRule is an IntentRule with its patterns compiled.
+type intentRule struct {
+	intent   string
+	language string
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+// compileIntentRules checks and compiles the rules of the custom intents, followed by the
+// configured rules, or the default rules if none are configured.
+func compileIntentRules(cfg IntentConfig) ([]intentRule, error) {
+	var rules []IntentRule
+	for _, custom := range cfg.Custom {
+		if len(custom.Keywords) > 0 || len(custom.Patterns) > 0 {
+			rules = append(rules, IntentRule{Intent: custom.Name, Keywords: custom.Keywords, Patterns: custom.Patterns})
+		}
+	}
+	if cfg.Rules == nil {
+		rules = append(rules, defaultIntentRules...)
+	} else {
+		rules = append(rules, cfg.Rules...)
+	}
+	names := cfg.Names()
+	compiled := make([]intentRule, 0, len(rules))
+	for i, rule := range rules {
+		if !slices.Contains(names, rule.Intent) {
+			return nil, fmt.Errorf("rule %d: unknown intent '%s' (expected one of %s)", i+1, rule.Intent, strings.Join(names, ", "))
+		}
+		if rule.Language != "" && !ruleLanguage.MatchString(rule.Language) {
+			return nil, fmt.Errorf("rule %d (%s): language '%s' is not a two-letter ISO 639-1 code", i+1, rule.Intent, rule.Language)
+		}
+		r := intentRule{intent: rule.Intent, language: rule.Language}
+		for _, keyword := range rule.Keywords {
+			if keyword == "" {
+				return nil, fmt.Errorf("rule %d (%s): keywords must not be empty", i+1, rule.Intent)
+			}
+			r.keywords = append(r.keywords, strings.ToLower(keyword))
+		}
+		for _, pattern := range rule.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d (%s): invalid pattern: %w", i+1, rule.Intent, err)
+			}
+			r.patterns = append(r.patterns, re)
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// matchRules performs fast keyword and regex checks for tool intents, rule by rule in their
+// configured order, skipping the rules of languages other than the prompt's. If no rule
+// matches, it defaults to a knowledge question.
+func matchRules(rules []intentRule, prompt, language string) IntentDecision {
+	lowerPrompt := strings.ToLower(prompt)
+	for _, rule := range rules {
+		if rule.language != "" && rule.language != language {
+			continue
+		}
+		for _, keyword := range rule.keywords {
+			if strings.Contains(lowerPrompt, keyword) {
+				slog.Debug("Intent detected by keyword", "keyword", keyword, "intent", rule.intent)
+				return IntentDecision{Intent: rule.intent, Matcher: "keyword", Pattern: keyword}
+			}
+		}
+		for _, pattern := range rule.patterns {
+			if pattern.MatchString(lowerPrompt) {
+				slog.Debug("Intent detected by regex", "pattern", pattern.String(), "intent", rule.intent)
+				return IntentDecision{Intent: rule.intent, Matcher: "regex", Pattern: pattern.String()}
+			}
+		}
+	}
+
+	// If no specific tool is detected, default to a RAG knowledge query.
+	// The RAG system's own confidence score will then decide if the context is used.
+	slog.Debug("No tool intent detected. Defaulting to RAG knowledge query.")
+	return IntentDecision{Intent: IntentRAG, Matcher: "default"}
+}
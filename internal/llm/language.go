@@ -0,0 +1,133 @@
+// In file: internal/llm/language.go
+package llm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Languages DetectLanguage tells apart, as ISO 639-1 codes. Latin-script languages are told
+// apart by their most common words; the others by their script alone.
+const (
+	LanguageEnglish    = "en"
+	LanguageSpanish    = "es"
+	LanguageFrench     = "fr"
+	LanguageGerman     = "de"
+	LanguagePortuguese = "pt"
+	LanguageItalian    = "it"
+	LanguageChinese    = "zh"
+	LanguageJapanese   = "ja"
+	LanguageKorean     = "ko"
+	LanguageRussian    = "ru"
+	LanguageArabic     = "ar"
+	LanguageHindi      = "hi"
+)
+
+// stopwords are the most frequent words of each Latin-script language. Words shared by
+// several languages count for each of them; the distinctive ones decide.
+var stopwords = map[string][]string{
+	LanguageEnglish:    {"the", "is", "are", "was", "what", "and", "of", "to", "in", "how", "you", "it", "do", "does", "for", "this", "that", "with", "me", "my", "can", "i"},
+	LanguageSpanish:    {"el", "la", "los", "las", "es", "qué", "que", "de", "y", "en", "cómo", "un", "una", "por", "para", "del", "está", "hoy", "mi", "me", "cuál", "puedes", "hay", "con", "sus", "muy", "también", "dónde"},
+	LanguageFrench:     {"le", "la", "les", "est", "et", "de", "des", "un", "une", "que", "quel", "quelle", "comment", "pour", "dans", "du", "je", "vous", "il", "aujourd'hui", "peux", "moi", "ce", "sur", "au", "aux", "pas", "avec", "mon", "ma", "c'est", "où"},
+	LanguageGerman:     {"der", "die", "das", "ist", "und", "ein", "eine", "wie", "was", "nicht", "ich", "sie", "mit", "für", "zu", "den", "dem", "auf", "heute", "mir", "kannst", "es"},
+	LanguagePortuguese: {"o", "a", "os", "as", "é", "e", "de", "do", "da", "que", "como", "um", "uma", "para", "em", "não", "está", "você", "hoje", "meu", "qual", "pode", "com", "são", "mais", "muito", "também"},
+	LanguageItalian:    {"il", "lo", "la", "è", "e", "di", "che", "come", "un", "una", "per", "non", "sono", "del", "della", "qual", "cosa", "oggi", "mi", "puoi", "quanto", "cos'è", "gli", "nel", "questo", "questa", "perché", "anche"},
+}
+
+// distinctiveLetters are letters that only some of the Latin-script languages use.
+var distinctiveLetters = map[rune][]string{
+	'ñ': {LanguageSpanish},
+	'¿': {LanguageSpanish},
+	'¡': {LanguageSpanish},
+	'ã': {LanguagePortuguese},
+	'õ': {LanguagePortuguese},
+	'ç': {LanguagePortuguese, LanguageFrench},
+	'ß': {LanguageGerman},
+	'ä': {LanguageGerman},
+	'ö': {LanguageGerman},
+	'ü': {LanguageGerman},
+	'è': {LanguageItalian, LanguageFrench},
+	'ò': {LanguageItalian},
+	'ì': {LanguageItalian},
+	'ê': {LanguageFrench, LanguagePortuguese},
+	'û': {LanguageFrench},
+	'œ': {LanguageFrench},
+}
+
+// DetectLanguage guesses the language of a text, or returns "" when it cannot tell, e.g. for
+// a few words shared by several languages. Callers treat "" like English.
+func DetectLanguage(text string) string {
+	if lang := scriptLanguage(text); lang != "" {
+		return lang
+	}
+	scores := make(map[string]int)
+	for _, r := range strings.ToLower(text) {
+		for _, lang := range distinctiveLetters[r] {
+			scores[lang] += 2
+		}
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for lang, list := range stopwords {
+			for _, stopword := range list {
+				if word == stopword {
+					scores[lang]++
+					break
+				}
+			}
+		}
+	}
+	best, bestScore, tied := "", 0, false
+	for _, lang := range []string{LanguageEnglish, LanguageSpanish, LanguageFrench, LanguageGerman, LanguagePortuguese, LanguageItalian} {
+		switch score := scores[lang]; {
+		case score > bestScore:
+			best, bestScore, tied = lang, score, false
+		case score == bestScore && score > 0:
+			tied = true
+		}
+	}
+	if tied {
+		return ""
+	}
+	return best
+}
+
+// scriptLanguage returns the language of a text written mostly in a script used by a single
+// language, or "" for Latin and mixed scripts. Kana decides Japanese over Chinese, as
+// Japanese also uses Han characters.
+func scriptLanguage(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts[LanguageJapanese] += 2
+		case unicode.Is(unicode.Han, r):
+			counts[LanguageChinese]++
+		case unicode.Is(unicode.Hangul, r):
+			counts[LanguageKorean]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts[LanguageRussian]++
+		case unicode.Is(unicode.Arabic, r):
+			counts[LanguageArabic]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts[LanguageHindi]++
+		}
+	}
+	if counts[LanguageJapanese] > 0 {
+		counts[LanguageJapanese] += counts[LanguageChinese]
+		counts[LanguageChinese] = 0
+	}
+	for lang, count := range counts {
+		if count*2 > letters {
+			return lang
+		}
+	}
+	return ""
+}
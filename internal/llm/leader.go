@@ -0,0 +1,123 @@
+// In file: internal/llm/leader.go
+package llm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// renewLeaseScript extends the lease only if this replica still owns it.
+// Doing the ownership check and the expiry update atomically in Lua prevents a
+// replica whose lease already expired from extending another replica's lease.
+var renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseLeaseScript deletes the lease only if this replica still owns it.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// LeaderElector implements Redis-backed leader election using a lease key.
+// Exactly one replica holds the lease at a time; if the leader dies, its lease
+// expires and another replica acquires it on its next attempt.
+type LeaderElector struct {
+	rdb      *redis.Client
+	key      string
+	id       string
+	ttl      time.Duration
+	isLeader atomic.Bool
+	elected  chan struct{}
+}
+
+// NewLeaderElector creates an elector competing for the given lease key.
+// Each replica gets a unique identity so that ownership checks are unambiguous.
+func NewLeaderElector(rdb *redis.Client, key string, ttl time.Duration) *LeaderElector {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return &LeaderElector{
+		rdb:     rdb,
+		key:     key,
+		id:      hostname + "-" + hex.EncodeToString(suffix),
+		ttl:     ttl,
+		elected: make(chan struct{}, 1),
+	}
+}
+
+// IsLeader reports whether this replica currently holds the lease.
+func (e *LeaderElector) IsLeader() bool {
+	return e.isLeader.Load()
+}
+
+// Elected returns a channel that receives a value every time this replica becomes leader.
+func (e *LeaderElector) Elected() <-chan struct{} {
+	return e.elected
+}
+
+// Run campaigns for and renews the lease until the context is cancelled,
+// at which point the lease is released so another replica can take over immediately.
+func (e *LeaderElector) Run(ctx context.Context) {
+	// Renew well before expiry so that a single slow round-trip doesn't cost the lease.
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	e.campaign(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			if e.isLeader.Load() {
+				releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := releaseLeaseScript.Run(releaseCtx, e.rdb, []string{e.key}, e.id).Err(); err != nil {
+					slog.Error("Error releasing leader lease", "lease", e.key, "error", err)
+				}
+				cancel()
+				e.isLeader.Store(false)
+			}
+			return
+		case <-ticker.C:
+			e.campaign(ctx)
+		}
+	}
+}
+
+// campaign renews the lease if held, or tries to acquire it otherwise.
+func (e *LeaderElector) campaign(ctx context.Context) {
+	var acquired bool
+	if e.isLeader.Load() {
+		renewed, err := renewLeaseScript.Run(ctx, e.rdb, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+		if err != nil {
+			slog.ErrorContext(ctx, "Error renewing leader lease", "lease", e.key, "error", err)
+		}
+		acquired = err == nil && renewed == 1
+	} else {
+		ok, err := e.rdb.SetNX(ctx, e.key, e.id, e.ttl).Result()
+		if err != nil {
+			slog.ErrorContext(ctx, "Error acquiring leader lease", "lease", e.key, "error", err)
+		}
+		acquired = err == nil && ok
+	}
+
+	wasLeader := e.isLeader.Swap(acquired)
+	switch {
+	case acquired && !wasLeader:
+		slog.InfoContext(ctx, "Replica became leader", "replica", e.id, "lease", e.key)
+		select {
+		case e.elected <- struct{}{}:
+		default:
+		}
+	case !acquired && wasLeader:
+		slog.InfoContext(ctx, "Replica lost leadership", "replica", e.id, "lease", e.key)
+	}
+}
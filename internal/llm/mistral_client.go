@@ -9,10 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
 
 	"github.com/dileep-u-k/llm-gateway/internal/api"
 	"github.com/dileep-u-k/llm-gateway/internal/tools"
@@ -20,18 +20,26 @@ import (
 
 const (
 	mistralAPIURL = "https://api.mistral.ai/v1/chat/completions"
+	// mistralModelsURL is the model list; a model is fetched by appending its ID.
+	mistralModelsURL = "https://api.mistral.ai/v1/models/"
 )
 
 // --- API Data Structures ---
 type mistralRequest struct {
-	Model       string           `json:"model"`
-	Messages    []mistralMessage `json:"messages"`
-	Tools       []mistralTool    `json:"tools,omitempty"`
-	ToolChoice  string           `json:"tool_choice,omitempty"`
-	Stream      bool             `json:"stream,omitempty"`
-	MaxTokens   int              `json:"max_tokens,omitempty"`
-	Temperature *float32         `json:"temperature,omitempty"`
-	TopP        *float32         `json:"top_p,omitempty"`
+	Model            string           `json:"model"`
+	Messages         []mistralMessage `json:"messages"`
+	Tools            []mistralTool    `json:"tools,omitempty"`
+	ToolChoice       string           `json:"tool_choice,omitempty"`
+	Stream           bool             `json:"stream,omitempty"`
+	MaxTokens        int              `json:"max_tokens,omitempty"`
+	Temperature      *float32         `json:"temperature,omitempty"`
+	TopP             *float32         `json:"top_p,omitempty"`
+	RandomSeed       *int64           `json:"random_seed,omitempty"`
+	Stop             []string         `json:"stop,omitempty"`
+	FrequencyPenalty *float32         `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32         `json:"presence_penalty,omitempty"`
+	// ResponseFormat enables JSON mode, e.g. {"type": "json_object"}.
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
 }
 type mistralMessage struct {
 	Role      string            `json:"role"`
@@ -52,7 +60,8 @@ type mistralTool struct {
 }
 type mistralResponse struct {
 	Choices []struct {
-		Message mistralMessage `json:"message"`
+		Message      mistralMessage `json:"message"`
+		FinishReason string         `json:"finish_reason"`
 	} `json:"choices"`
 	Usage api.Usage `json:"usage"`
 }
@@ -62,24 +71,29 @@ type mistralStreamChunk struct {
 			Content   string            `json:"content"`
 			ToolCalls []mistralToolCall `json:"tool_calls"`
 		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	// Usage is set on the final chunk of the stream.
+	Usage *api.Usage `json:"usage"`
 }
 
 // --- Main Client ---
 type MistralClient struct {
 	apiKey     string
 	httpClient *http.Client
+	policy     RequestPolicy
 }
 
 var _ LLMClient = (*MistralClient)(nil)
 
-func NewMistralClient(apiKey string) (*MistralClient, error) {
+func NewMistralClient(apiKey string, policy RequestPolicy, transport http.RoundTripper) (*MistralClient, error) {
 	if apiKey == "" {
 		return nil, errors.New("mistral API key cannot be empty")
 	}
 	return &MistralClient{
 		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: defaultTimeout},
+		httpClient: &http.Client{Timeout: policy.Timeout, Transport: clientTransport(transport)},
+		policy:     policy,
 	}, nil
 }
 
@@ -88,11 +102,16 @@ func (c *MistralClient) Generate(ctx context.Context, messages []Message, config
 	if err != nil {
 		return nil, fmt.Errorf("failed to build mistral request payload: %w", err)
 	}
-	respBody, err := c.doRequest(ctx, payload)
+	respBody, deprecation, err := c.doRequest(ctx, payload, config.Model)
 	if err != nil {
 		return nil, err
 	}
-	return parseMistralResponse(respBody)
+	result, err := parseMistralResponse(respBody)
+	if err != nil {
+		return nil, err
+	}
+	result.Deprecation = deprecation
+	return result, nil
 }
 
 func (c *MistralClient) GenerateStream(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (<-chan *StreamingResult, error) {
@@ -100,12 +119,12 @@ func (c *MistralClient) GenerateStream(ctx context.Context, messages []Message,
 	if err != nil {
 		return nil, fmt.Errorf("failed to build mistral stream payload: %w", err)
 	}
-	respBody, err := c.doRequestStream(ctx, payload)
+	respBody, deprecation, err := c.doRequestStream(ctx, payload, config.Model)
 	if err != nil {
 		return nil, err
 	}
-	outChan := make(chan *StreamingResult)
-	go c.processStream(respBody, outChan)
+	outChan := newStreamChannel(config)
+	go c.processStream(respBody, deprecation, outChan)
 	return outChan, nil
 }
 
@@ -125,6 +144,14 @@ func (c *MistralClient) buildRequestPayload(messages []Message, config *Generati
 	if len(mistralTools) > 0 {
 		req.ToolChoice = "auto"
 	}
+	req.RandomSeed = config.Seed
+	req.Stop = config.Stop
+	req.FrequencyPenalty = config.FrequencyPenalty
+	req.PresencePenalty = config.PresencePenalty
+	// Mistral's JSON mode takes no schema; the prompt carries it instead.
+	if config.jsonFormat() != nil {
+		req.ResponseFormat = map[string]string{"type": "json_object"}
+	}
 	payloadBytes, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
@@ -132,55 +159,64 @@ func (c *MistralClient) buildRequestPayload(messages []Message, config *Generati
 	return bytes.NewBuffer(payloadBytes), nil
 }
 
-func (c *MistralClient) doRequest(ctx context.Context, payload *bytes.Buffer) ([]byte, error) {
+func (c *MistralClient) doRequest(ctx context.Context, payload *bytes.Buffer, modelID string) ([]byte, *DeprecationNotice, error) {
 	// ... (Implementation is the same as the Anthropic client's doRequest)
 	var lastErr error
-	delay := initialRetryDelay
-	for i := 0; i < maxRetries; i++ {
+	attempts := c.policy.attempts()
+	delay := c.policy.InitialRetryDelay
+	for i := 0; i < attempts; i++ {
 		req, err := c.createRequest(ctx, bytes.NewReader(payload.Bytes()))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", i+1, maxRetries, err)
-			time.Sleep(delay)
-			delay *= 2
+			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", i+1, attempts, err)
+			if i+1 < attempts {
+				if err := sleepBeforeRetry(ctx, delay); err != nil {
+					return nil, nil, err
+				}
+				delay *= 2
+			}
 			continue
 		}
 		body, readErr := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		if readErr != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", readErr)
+			return nil, nil, fmt.Errorf("failed to read response body: %w", readErr)
 		}
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return body, nil
+			return body, parseDeprecationHeaders(modelID, resp.Header), nil
 		}
-		lastErr = fmt.Errorf("anthropic API error (attempt %d/%d): status %d, body: %s", i+1, maxRetries, resp.StatusCode, string(body))
+		lastErr = &APIError{Provider: "mistral", StatusCode: resp.StatusCode, Body: string(body), Attempt: i + 1, Attempts: attempts}
 		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-			return nil, lastErr
+			return nil, nil, lastErr
+		}
+		if i+1 < attempts {
+			if err := sleepBeforeRetry(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+			delay *= 2
 		}
-		time.Sleep(delay)
-		delay *= 2
 	}
-	return nil, lastErr
+	return nil, nil, lastErr
 }
-func (c *MistralClient) doRequestStream(ctx context.Context, payload *bytes.Buffer) (io.ReadCloser, error) {
+func (c *MistralClient) doRequestStream(ctx context.Context, payload *bytes.Buffer, modelID string) (io.ReadCloser, *DeprecationNotice, error) {
 	// ... (Implementation is the same as the Anthropic client's doRequestStream)
 	req, err := c.createRequest(ctx, payload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start stream request: %w", err)
+		return nil, nil, fmt.Errorf("failed to start stream request: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("anthropic API stream error: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, nil, fmt.Errorf("anthropic API stream error: status %d, body: %s", resp.StatusCode, string(body))
 	}
-	return resp.Body, nil
+	return resp.Body, parseDeprecationHeaders(modelID, resp.Header), nil
 }
 func (c *MistralClient) createRequest(ctx context.Context, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, "POST", mistralAPIURL, body)
@@ -193,18 +229,18 @@ func (c *MistralClient) createRequest(ctx context.Context, body io.Reader) (*htt
 	return req, nil
 }
 
-func (c *MistralClient) processStream(body io.ReadCloser, outChan chan<- *StreamingResult) {
+func (c *MistralClient) processStream(body io.ReadCloser, deprecation *DeprecationNotice, outChan chan<- *StreamingResult) {
 
 	// FIX: Check the error from body.Close().
 	defer func() {
 		if err := body.Close(); err != nil {
-			log.Printf("Error closing mistral stream body: %v", err)
+			slog.Warn("Error closing stream body", "provider", "mistral", "error", err)
 		}
 		close(outChan)
 	}()
-
-	defer body.Close()
-	defer close(outChan)
+	if deprecation != nil {
+		outChan <- &StreamingResult{Deprecation: deprecation}
+	}
 	scanner := bufio.NewScanner(body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -222,7 +258,7 @@ func (c *MistralClient) processStream(body io.ReadCloser, outChan chan<- *Stream
 		}
 		if len(chunk.Choices) > 0 {
 			delta := chunk.Choices[0].Delta
-			result := &StreamingResult{}
+			result := &StreamingResult{StopReason: openAIStopReason(chunk.Choices[0].FinishReason)}
 			if delta.Content != "" {
 				result.ContentDelta = delta.Content
 			}
@@ -239,6 +275,9 @@ func (c *MistralClient) processStream(body io.ReadCloser, outChan chan<- *Stream
 			}
 			outChan <- result
 		}
+		if chunk.Usage != nil {
+			outChan <- &StreamingResult{Usage: chunk.Usage}
+		}
 	}
 	if err := scanner.Err(); err != nil {
 		outChan <- &StreamingResult{Err: fmt.Errorf("error reading stream: %w", err)}
@@ -280,8 +319,9 @@ func parseMistralResponse(body []byte) (*GenerationResult, error) {
 	}
 	choice := mistralResp.Choices[0]
 	result := &GenerationResult{
-		Content: choice.Message.Content,
-		Usage:   mistralResp.Usage,
+		Content:    choice.Message.Content,
+		Usage:      mistralResp.Usage,
+		StopReason: openAIStopReason(choice.FinishReason),
 	}
 	if len(choice.Message.ToolCalls) > 0 {
 		result.ToolCalls = make([]*tools.ToolCall, 0, len(choice.Message.ToolCalls))
@@ -298,3 +338,20 @@ func parseMistralResponse(body []byte) (*GenerationResult, error) {
 	}
 	return result, nil
 }
+
+// Ping checks that the model is available by fetching it from Mistral's model list.
+func (c *MistralClient) Ping(ctx context.Context, modelID string) error {
+	return pingModelURL(ctx, c.httpClient, mistralModelsURL+url.PathEscape(modelID), map[string]string{
+		"Authorization": "Bearer " + c.apiKey,
+		"Accept":        "application/json",
+	})
+}
+
+// Forward sends a chat completions request body, in Mistral's format, to Mistral unchanged.
+func (c *MistralClient) Forward(ctx context.Context, body []byte) (*ForwardedResponse, error) {
+	req, err := c.createRequest(ctx, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return forwardRequest(c.httpClient, req)
+}
@@ -0,0 +1,51 @@
+// In file: internal/llm/model_switch.go
+package llm
+
+import (
+	"context"
+	"log/slog"
+)
+
+// disabledModelsKey is the Redis set of models an operator has taken out of rotation.
+// Keeping it in Redis makes a switch take effect on every replica at once and survive restarts.
+const disabledModelsKey = "models:disabled"
+
+// DisableModel takes a model out of rotation: the router stops selecting it and sessions
+// pinned to it fail over, exactly as if it were offline.
+func (p *Profiler) DisableModel(ctx context.Context, modelID string) error {
+	return p.rdb.SAdd(ctx, disabledModelsKey, modelID).Err()
+}
+
+// EnableModel puts a disabled model back into rotation.
+func (p *Profiler) EnableModel(ctx context.Context, modelID string) error {
+	return p.rdb.SRem(ctx, disabledModelsKey, modelID).Err()
+}
+
+// DisabledModels returns the set of models an operator has disabled.
+func (p *Profiler) DisabledModels(ctx context.Context) (map[string]bool, error) {
+	members, err := p.rdb.SMembers(ctx, disabledModelsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	disabled := make(map[string]bool, len(members))
+	for _, modelID := range members {
+		disabled[modelID] = true
+	}
+	return disabled, nil
+}
+
+// disabledModels returns the disabled set for routing. If it cannot be read, no model is
+// treated as disabled, so a Redis hiccup never takes every model out of rotation.
+func (r *Router) disabledModels(ctx context.Context) map[string]bool {
+	disabled, err := r.profiler.DisabledModels(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Could not read disabled models, treating all as enabled", "error", err)
+		return nil
+	}
+	return disabled
+}
+
+// IsDisabled reports whether an operator has taken a model out of rotation.
+func (r *Router) IsDisabled(ctx context.Context, modelID string) bool {
+	return r.disabledModels(ctx)[modelID]
+}
@@ -9,10 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
 
 	"github.com/dileep-u-k/llm-gateway/internal/api"
 	"github.com/dileep-u-k/llm-gateway/internal/tools"
@@ -20,14 +20,47 @@ import (
 
 // openAIRequest defines the top-level structure for an OpenAI API call.
 type openAIRequest struct {
-	Model       string          `json:"model"`
-	Messages    []openAIMessage `json:"messages"`
-	Tools       []openAITool    `json:"tools,omitempty"`
-	ToolChoice  string          `json:"tool_choice,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature *float32        `json:"temperature,omitempty"`
-	TopP        *float32        `json:"top_p,omitempty"`
+	Model      string          `json:"model"`
+	Messages   []openAIMessage `json:"messages"`
+	Tools      []openAITool    `json:"tools,omitempty"`
+	ToolChoice string          `json:"tool_choice,omitempty"`
+	Stream     bool            `json:"stream,omitempty"`
+	// StreamOptions asks for a final chunk carrying the token usage; OpenAI omits it otherwise.
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+	// ResponseFormat enables JSON mode, or constrains the answer to a JSON Schema.
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	// MaxCompletionTokens replaces MaxTokens for reasoning models, as it also bounds the
+	// tokens spent reasoning.
+	MaxCompletionTokens int      `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string   `json:"reasoning_effort,omitempty"`
+	Temperature         *float32 `json:"temperature,omitempty"`
+	TopP                *float32 `json:"top_p,omitempty"`
+	Logprobs            bool     `json:"logprobs,omitempty"`
+	TopLogprobs         int      `json:"top_logprobs,omitempty"`
+	Seed                *int64   `json:"seed,omitempty"`
+	Stop                []string `json:"stop,omitempty"`
+	FrequencyPenalty    *float32 `json:"frequency_penalty,omitempty"`
+	PresencePenalty     *float32 `json:"presence_penalty,omitempty"`
+}
+
+// openAIResponseFormat selects the format of the answer, e.g. {"type": "json_object"}.
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// openAIJSONSchema is the schema of a json_schema answer. Strict mode is left off, as it
+// only accepts schemas that close every object and require every property.
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict"`
+}
+
+// openAIStreamOptions configures a streaming response.
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // openAIMessage represents a single message in a conversation.
@@ -47,9 +80,35 @@ type openAITool struct {
 // openAIResponse is the structure of a successful non-streaming response from the API.
 type openAIResponse struct {
 	Choices []struct {
-		Message openAIMessage `json:"message"`
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+		Logprobs     *struct {
+			Content []api.TokenLogprob `json:"content"`
+		} `json:"logprobs"`
 	} `json:"choices"`
-	Usage api.Usage `json:"usage"`
+	Usage             openAIUsage `json:"usage"`
+	SystemFingerprint string      `json:"system_fingerprint"`
+}
+
+// openAIUsage is OpenAI's token usage, which breaks down the prompt tokens read from its
+// prompt cache and the completion tokens reasoning models spent reasoning. OpenAI caches the
+// prefixes of long prompts on its own, which is why the gateway puts the system prompt and
+// history before the (possibly RAG-augmented) prompt.
+type openAIUsage struct {
+	api.Usage
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u openAIUsage) usage() api.Usage {
+	usage := u.Usage
+	usage.CachedTokens = u.PromptTokensDetails.CachedTokens
+	usage.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	return usage
 }
 
 // openAIStreamChunk is the structure of a single event in a streaming response.
@@ -59,13 +118,19 @@ type openAIStreamChunk struct {
 			Content   string           `json:"content"`
 			ToolCalls []tools.ToolCall `json:"tool_calls"`
 		} `json:"delta"`
+		// FinishReason is set on the last chunk of the choice.
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	// Usage is only set on the final chunk, which has no choices.
+	Usage *openAIUsage `json:"usage"`
 }
 
 // --- END OF STRUCTS TO PASTE ---
 
 const (
 	openAIAPIURL = "https://api.openai.com/v1/chat/completions"
+	// openAIModelsURL is the model list; a model is fetched by appending its ID.
+	openAIModelsURL = "https://api.openai.com/v1/models/"
 )
 
 // OpenAIClient is the client for interacting with OpenAI models like GPT-4.
@@ -73,6 +138,7 @@ const (
 type OpenAIClient struct {
 	apiKey     string
 	httpClient *http.Client
+	policy     RequestPolicy
 }
 
 // Statically verify that OpenAIClient implements the LLMClient interface.
@@ -80,15 +146,19 @@ var _ LLMClient = (*OpenAIClient)(nil)
 
 // NewOpenAIClient creates a new, configured client for the OpenAI API.
 // The modelID is now specified per-request via GenerationConfig, not on the client itself.
-func NewOpenAIClient(apiKey string) (*OpenAIClient, error) {
+// The policy sets the client's request timeout and retries. Requests go through transport,
+// or the default transport if it is nil.
+func NewOpenAIClient(apiKey string, policy RequestPolicy, transport http.RoundTripper) (*OpenAIClient, error) {
 	if apiKey == "" {
 		return nil, errors.New("openAI API key cannot be empty")
 	}
 	return &OpenAIClient{
 		apiKey: apiKey,
 		httpClient: &http.Client{
-			Timeout: defaultTimeout, // Set a default timeout for all HTTP requests.
+			Timeout:   policy.Timeout,
+			Transport: clientTransport(transport),
 		},
+		policy: policy,
 	}, nil
 }
 
@@ -106,13 +176,18 @@ func (c *OpenAIClient) Generate(
 	}
 
 	// Make the API call with our robust retry mechanism.
-	respBody, err := c.doRequest(ctx, payload)
+	respBody, deprecation, err := c.doRequest(ctx, payload, config.Model)
 	if err != nil {
 		return nil, err // The error from doRequest is already descriptive.
 	}
 
 	// Parse the complete JSON response body.
-	return parseOpenAIResponse(respBody)
+	result, err := parseOpenAIResponse(respBody)
+	if err != nil {
+		return nil, err
+	}
+	result.Deprecation = deprecation
+	return result, nil
 }
 
 // GenerateStream performs a streaming request to the OpenAI API.
@@ -129,16 +204,16 @@ func (c *OpenAIClient) GenerateStream(
 	}
 
 	// The streaming version of the request returns a response body to be processed.
-	respBody, err := c.doRequestStream(ctx, payload)
+	respBody, deprecation, err := c.doRequestStream(ctx, payload, config.Model)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create the channel to stream results back to the caller.
-	outChan := make(chan *StreamingResult)
+	outChan := newStreamChannel(config)
 
 	// Start a goroutine to process the Server-Sent Events (SSE) stream.
-	go c.processStream(respBody, outChan)
+	go c.processStream(respBody, deprecation, outChan)
 
 	return outChan, nil
 }
@@ -155,6 +230,9 @@ func (c *OpenAIClient) buildRequestPayload(messages []Message, config *Generatio
 		Tools:    openAITools,
 		Stream:   stream,
 	}
+	if stream {
+		req.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
 
 	// Apply generation parameters from the config.
 	if config.MaxTokens > 0 {
@@ -166,6 +244,26 @@ func (c *OpenAIClient) buildRequestPayload(messages []Message, config *Generatio
 	if config.TopP != nil {
 		req.TopP = config.TopP
 	}
+	// Reasoning models reject sampling parameters, and their token limit covers reasoning.
+	if isOpenAIReasoningModel(config.Model) {
+		req.MaxCompletionTokens, req.MaxTokens = req.MaxTokens, 0
+		req.Temperature, req.TopP = nil, nil
+		req.ReasoningEffort = config.ReasoningEffort
+	}
+	req.Seed = config.Seed
+	req.Stop = config.Stop
+	req.FrequencyPenalty = config.FrequencyPenalty
+	req.PresencePenalty = config.PresencePenalty
+	if config.Logprobs {
+		req.Logprobs = true
+		req.TopLogprobs = config.TopLogprobs
+	}
+	if format := config.jsonFormat(); format != nil {
+		req.ResponseFormat = &openAIResponseFormat{Type: format.Type}
+		if format.Type == ResponseFormatJSONSchema {
+			req.ResponseFormat.JSONSchema = &openAIJSONSchema{Name: format.name(), Schema: format.schema()}
+		}
+	}
 
 	// OpenAI allows forcing a tool call.
 	if len(openAITools) > 0 {
@@ -181,71 +279,80 @@ func (c *OpenAIClient) buildRequestPayload(messages []Message, config *Generatio
 }
 
 // doRequest performs the HTTP call with retries for non-streaming requests.
-func (c *OpenAIClient) doRequest(ctx context.Context, payload *bytes.Buffer) ([]byte, error) {
+func (c *OpenAIClient) doRequest(ctx context.Context, payload *bytes.Buffer, modelID string) ([]byte, *DeprecationNotice, error) {
 	var lastErr error
-	delay := initialRetryDelay
+	attempts := c.policy.attempts()
+	delay := c.policy.InitialRetryDelay
 
-	for i := 0; i < maxRetries; i++ {
+	for i := 0; i < attempts; i++ {
 		// Use a bytes.Reader so the request body can be re-read on retry.
 		req, err := c.createRequest(ctx, bytes.NewReader(payload.Bytes()))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", i+1, maxRetries, err)
-			time.Sleep(delay)
-			delay *= 2
+			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", i+1, attempts, err)
+			if i+1 < attempts {
+				if err := sleepBeforeRetry(ctx, delay); err != nil {
+					return nil, nil, err
+				}
+				delay *= 2
+			}
 			continue
 		}
 
 		body, readErr := io.ReadAll(resp.Body)
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("Warning: Failed to close response body: %v", err)
+			slog.WarnContext(ctx, "Failed to close response body", "error", err)
 		}
 		if readErr != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", readErr)
+			return nil, nil, fmt.Errorf("failed to read response body: %w", readErr)
 		}
 
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return body, nil // Success!
+			return body, parseDeprecationHeaders(modelID, resp.Header), nil // Success!
 		}
 
-		lastErr = fmt.Errorf("openai API error (attempt %d/%d): status %d, body: %s", i+1, maxRetries, resp.StatusCode, string(body))
+		lastErr = &APIError{Provider: "openai", StatusCode: resp.StatusCode, Body: string(body), Attempt: i + 1, Attempts: attempts}
 
 		// Do not retry on client errors (e.g., 400 Bad Request).
 		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-			return nil, lastErr
+			return nil, nil, lastErr
 		}
 
-		time.Sleep(delay)
-		delay *= 2
+		if i+1 < attempts {
+			if err := sleepBeforeRetry(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+			delay *= 2
+		}
 	}
-	return nil, lastErr
+	return nil, nil, lastErr
 }
 
 // doRequestStream prepares and executes the HTTP request for streaming.
-func (c *OpenAIClient) doRequestStream(ctx context.Context, payload *bytes.Buffer) (io.ReadCloser, error) {
+func (c *OpenAIClient) doRequestStream(ctx context.Context, payload *bytes.Buffer, modelID string) (io.ReadCloser, *DeprecationNotice, error) {
 	req, err := c.createRequest(ctx, payload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start stream request: %w", err)
+		return nil, nil, fmt.Errorf("failed to start stream request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("Warning: Failed to close stream response body: %v", err)
+			slog.WarnContext(ctx, "Failed to close stream response body", "error", err)
 		}
-		return nil, fmt.Errorf("openai API stream error: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, nil, fmt.Errorf("openai API stream error: status %d, body: %s", resp.StatusCode, string(body))
 	}
 
-	return resp.Body, nil
+	return resp.Body, parseDeprecationHeaders(modelID, resp.Header), nil
 }
 
 // createRequest is a helper to build the common parts of an http.Request.
@@ -260,18 +367,18 @@ func (c *OpenAIClient) createRequest(ctx context.Context, body io.Reader) (*http
 }
 
 // processStream reads the SSE stream from the response body and sends results to a channel.
-func (c *OpenAIClient) processStream(body io.ReadCloser, outChan chan<- *StreamingResult) {
+func (c *OpenAIClient) processStream(body io.ReadCloser, deprecation *DeprecationNotice, outChan chan<- *StreamingResult) {
 
 	// FIX: Check the error from body.Close().
 	defer func() {
 		if err := body.Close(); err != nil {
-			log.Printf("Error closing openai stream body: %v", err)
+			slog.Warn("Error closing stream body", "provider", "openai", "error", err)
 		}
 		close(outChan)
 	}()
-
-	defer body.Close()
-	defer close(outChan)
+	if deprecation != nil {
+		outChan <- &StreamingResult{Deprecation: deprecation}
+	}
 
 	scanner := bufio.NewScanner(body)
 	for scanner.Scan() {
@@ -293,7 +400,7 @@ func (c *OpenAIClient) processStream(body io.ReadCloser, outChan chan<- *Streami
 
 		if len(chunk.Choices) > 0 {
 			delta := chunk.Choices[0].Delta
-			result := &StreamingResult{}
+			result := &StreamingResult{StopReason: openAIStopReason(chunk.Choices[0].FinishReason)}
 
 			if delta.Content != "" {
 				result.ContentDelta = delta.Content
@@ -313,6 +420,10 @@ func (c *OpenAIClient) processStream(body io.ReadCloser, outChan chan<- *Streami
 			}
 			outChan <- result
 		}
+		if chunk.Usage != nil {
+			usage := chunk.Usage.usage()
+			outChan <- &StreamingResult{Usage: &usage}
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -320,6 +431,22 @@ func (c *OpenAIClient) processStream(body io.ReadCloser, outChan chan<- *Streami
 	}
 }
 
+// openAIStopReason maps a finish_reason of OpenAI's API, or of Mistral's, which uses the
+// same values, onto a StopReason constant.
+func openAIStopReason(reason string) string {
+	switch reason {
+	case "stop":
+		return StopReasonStop
+	case "length", "model_length":
+		return StopReasonLength
+	case "tool_calls", "function_call":
+		return StopReasonToolCalls
+	case "content_filter":
+		return StopReasonContentFilter
+	}
+	return ""
+}
+
 // toOpenAIMessages converts our internal message slice to the OpenAI API format.
 
 func toOpenAIMessages(messages []Message) []openAIMessage {
@@ -375,8 +502,13 @@ func parseOpenAIResponse(body []byte) (*GenerationResult, error) {
 
 	choice := openAIResp.Choices[0]
 	result := &GenerationResult{
-		Content: choice.Message.Content,
-		Usage:   openAIResp.Usage,
+		Content:           choice.Message.Content,
+		Usage:             openAIResp.Usage.usage(),
+		SystemFingerprint: openAIResp.SystemFingerprint,
+		StopReason:        openAIStopReason(choice.FinishReason),
+	}
+	if choice.Logprobs != nil {
+		result.Logprobs = choice.Logprobs.Content
 	}
 
 	if len(choice.Message.ToolCalls) > 0 {
@@ -396,3 +528,19 @@ func parseOpenAIResponse(body []byte) (*GenerationResult, error) {
 
 	return result, nil
 }
+
+// Ping checks that the model is available by fetching it from OpenAI's model list.
+func (c *OpenAIClient) Ping(ctx context.Context, modelID string) error {
+	return pingModelURL(ctx, c.httpClient, openAIModelsURL+url.PathEscape(modelID), map[string]string{
+		"Authorization": "Bearer " + c.apiKey,
+	})
+}
+
+// Forward sends a chat completions request body, in OpenAI's format, to OpenAI unchanged.
+func (c *OpenAIClient) Forward(ctx context.Context, body []byte) (*ForwardedResponse, error) {
+	req, err := c.createRequest(ctx, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return forwardRequest(c.httpClient, req)
+}
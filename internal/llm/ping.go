@@ -0,0 +1,51 @@
+// In file: internal/llm/ping.go
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrPingUnsupported is returned by Ping for clients that cannot check a model without
+// generating with it.
+var ErrPingUnsupported = errors.New("the client cannot ping models")
+
+// Pinger is implemented by clients that can check that a model is available without
+// generating with it, by fetching the model from the provider's model list. Such a probe
+// costs no tokens, but it does not prove that generation works.
+type Pinger interface {
+	Ping(ctx context.Context, modelID string) error
+}
+
+// Ping checks that a model is available through client, or returns ErrPingUnsupported if
+// the client cannot ping.
+func Ping(ctx context.Context, client LLMClient, modelID string) error {
+	if pinger, ok := client.(Pinger); ok {
+		return pinger.Ping(ctx, modelID)
+	}
+	return ErrPingUnsupported
+}
+
+// pingModelURL fetches a provider's model resource and reports a non-2xx answer as an error.
+func pingModelURL(ctx context.Context, httpClient *http.Client, url string, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create http request: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ping failed: status %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// In file: internal/llm/preference_classifier.go
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The backends a PromptAnalyzer can select preferences with.
+const (
+	// PromptBackendRules scores prompts with the archetype patterns.
+	PromptBackendRules = "rules"
+	// PromptBackendLLM asks a (cheap) model, caching its answer for each prompt.
+	PromptBackendLLM = "llm"
+	// PromptBackendHTTP asks a classifier served over HTTP, such as a small local model.
+	PromptBackendHTTP = "http"
+)
+
+const (
+	// preferenceClassifierKeyPrefix, followed by the model and the prompt's hash, caches the
+	// model's preference for a prompt.
+	preferenceClassifierKeyPrefix = "preference_llm:"
+	// preferenceClassifierMaxTokens is enough for the longest preference.
+	preferenceClassifierMaxTokens = 10
+)
+
+// analyzerPreferences are the preferences a classifier chooses between, with when to choose
+// them, in the order they are listed to the model.
+var analyzerPreferences = []struct{ preference, description string }{
+	{"cost", "a simple factual question or lookup with a short answer."},
+	{"balanced", "an ordinary request of moderate difficulty, such as an explanation or a summary."},
+	{"default", "a complex request needing analysis, comparison, or careful reasoning."},
+	{"max_quality", "a very demanding request: long-form or creative writing, planning, design, proofs, or role-play."},
+	{"best-for-coding", "writing, fixing, or explaining code, or questions about programming tools."},
+}
+
+// PromptClassifierConfig configures the backend of a PromptAnalyzer that does not use the
+// archetype rules. A prompt the backend fails on is scored by the rules.
+type PromptClassifierConfig struct {
+	// Model is the llm backend's model. It defaults to the enabled model with the lowest
+	// input cost.
+	Model string `yaml:"model"`
+	// URL is where the http backend is sent {"text": prompt}. It answers
+	// {"label": preference, "confidence": 0-1}.
+	URL string `yaml:"url"`
+	// MinConfidence is the confidence below which the http backend's answer is ignored.
+	MinConfidence float64 `yaml:"min_confidence"`
+	// Timeout bounds a call to the backend.
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long the llm backend's answer for a prompt is cached.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// PreferenceClassifier selects a routing preference for a prompt by other means than the
+// archetype rules.
+type PreferenceClassifier interface {
+	// ClassifyPreference returns one of the preferences the PromptAnalyzer selects.
+	ClassifyPreference(ctx context.Context, prompt string) (string, error)
+	// Backend names the kind of classifier, e.g. PromptBackendLLM.
+	Backend() string
+}
+
+// preferenceLabels returns the preferences a classifier may answer with.
+func preferenceLabels() []string {
+	labels := make([]string, len(analyzerPreferences))
+	for i, p := range analyzerPreferences {
+		labels[i] = p.preference
+	}
+	return labels
+}
+
+// preferenceClassifierInstruction constrains the model to answer with one preference.
+func preferenceClassifierInstruction() string {
+	var b strings.Builder
+	b.WriteString("Classify the user's message, which may be in any language, by how capable a model must be to answer it well. ")
+	b.WriteString("Reply with exactly one of these labels and nothing else:\n")
+	for _, p := range analyzerPreferences {
+		fmt.Fprintf(&b, "%s: %s\n", p.preference, p.description)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// LLMPreferenceClassifier asks a (cheap) model for the preference of a prompt. Its answers are
+// cached by prompt, so a prompt is only classified once.
+type LLMPreferenceClassifier struct {
+	client   LLMClient
+	modelID  string
+	rdb      *redis.Client
+	cacheTTL time.Duration
+	timeout  time.Duration
+}
+
+// NewLLMPreferenceClassifier creates a classifier that asks modelID through client, caching
+// its answers in Redis for cfg.CacheTTL.
+func NewLLMPreferenceClassifier(client LLMClient, modelID string, rdb *redis.Client, cfg PromptClassifierConfig) *LLMPreferenceClassifier {
+	return &LLMPreferenceClassifier{client: client, modelID: modelID, rdb: rdb, cacheTTL: cfg.CacheTTL, timeout: cfg.Timeout}
+}
+
+// Backend returns PromptBackendLLM.
+func (c *LLMPreferenceClassifier) Backend() string {
+	return PromptBackendLLM
+}
+
+// ClassifyPreference returns the preference of prompt, from the cache or by asking the model.
+func (c *LLMPreferenceClassifier) ClassifyPreference(ctx context.Context, prompt string) (string, error) {
+	instruction := preferenceClassifierInstruction()
+	key := preferenceClassifierKeyPrefix + c.modelID + ":" + GenerateCacheKey(instruction+"\x00"+prompt)
+	if preference, err := c.rdb.Get(ctx, key).Result(); err == nil {
+		return preference, nil
+	} else if err != redis.Nil {
+		slog.WarnContext(ctx, "Redis GET error for classified preference", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	if runes := []rune(prompt); len(runes) > intentClassifierInputLimit {
+		prompt = string(runes[:intentClassifierInputLimit])
+	}
+	messages := []Message{
+		{Role: RoleSystem, Content: instruction},
+		{Role: RoleUser, Content: prompt},
+	}
+	temperature := float32(0)
+	result, err := c.client.Generate(ctx, messages, &GenerationConfig{Model: c.modelID, MaxTokens: preferenceClassifierMaxTokens, Temperature: &temperature}, nil)
+	if err != nil {
+		return "", fmt.Errorf("preference classifier call failed: %w", err)
+	}
+	preference, ok := parseLabel(result.Content, preferenceLabels())
+	if !ok {
+		return "", fmt.Errorf("preference classifier answered with an unknown label %q", result.Content)
+	}
+	if err := c.rdb.Set(ctx, key, preference, c.cacheTTL).Err(); err != nil {
+		slog.WarnContext(ctx, "Failed to cache classified preference", "error", err)
+	}
+	return preference, nil
+}
+
+// HTTPPreferenceClassifier asks a classifier served over HTTP, such as a small fine-tuned
+// model running next to the gateway, for the preference of a prompt.
+type HTTPPreferenceClassifier struct {
+	url           string
+	minConfidence float64
+	client        *http.Client
+}
+
+// NewHTTPPreferenceClassifier creates a classifier that posts prompts to cfg.URL.
+func NewHTTPPreferenceClassifier(cfg PromptClassifierConfig) *HTTPPreferenceClassifier {
+	return &HTTPPreferenceClassifier{url: cfg.URL, minConfidence: cfg.MinConfidence, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Backend returns PromptBackendHTTP.
+func (c *HTTPPreferenceClassifier) Backend() string {
+	return PromptBackendHTTP
+}
+
+// ClassifyPreference returns the classifier's preference for prompt. An answer less
+// confident than the configured minimum is an error, so the rules decide instead.
+func (c *HTTPPreferenceClassifier) ClassifyPreference(ctx context.Context, prompt string) (string, error) {
+	body, err := json.Marshal(map[string]string{"text": prompt})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("preference classifier request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("preference classifier returned status %d", resp.StatusCode)
+	}
+	var answer struct {
+		Label      string  `json:"label"`
+		Confidence float64 `json:"confidence"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return "", fmt.Errorf("failed to decode the preference classifier's answer: %w", err)
+	}
+	preference, ok := parseLabel(answer.Label, preferenceLabels())
+	if !ok {
+		return "", fmt.Errorf("preference classifier answered with an unknown label %q", answer.Label)
+	}
+	if answer.Confidence < c.minConfidence {
+		return "", fmt.Errorf("preference classifier is not confident about %s (%.2f)", preference, answer.Confidence)
+	}
+	return preference, nil
+}
@@ -0,0 +1,104 @@
+// In file: internal/llm/profile_export.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProfileExport is a set of model profiles exported from one environment, to seed another.
+type ProfileExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Profiles   []ModelProfile `json:"profiles"`
+}
+
+// ExportProfiles exports the profiles of the given models. Models without a profile yet are
+// left out rather than given the default one.
+func (p *Profiler) ExportProfiles(ctx context.Context, models []string) (*ProfileExport, error) {
+	export := &ProfileExport{ExportedAt: time.Now().UTC(), Profiles: []ModelProfile{}}
+	for _, modelID := range models {
+		exists, err := p.rdb.Exists(ctx, p.getProfileKey(modelID)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile of %s: %w", modelID, err)
+		}
+		if exists == 0 {
+			continue
+		}
+		profile, err := p.GetProfile(ctx, modelID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile of %s: %w", modelID, err)
+		}
+		export.Profiles = append(export.Profiles, *profile)
+	}
+	return export, nil
+}
+
+// ImportProfiles seeds model profiles from an export: their latencies and TTFTs, histograms,
+// error rates, request and token counts, and per-token costs. Models that already have a
+// profile keep it unless overwrite is set. Imported models start online with a fresh health
+// check, as the exporting environment's health says nothing about this one's; monthly spend
+// is never imported. It returns the models that were imported.
+func (p *Profiler) ImportProfiles(ctx context.Context, profiles []ModelProfile, overwrite bool) ([]string, error) {
+	imported := []string{}
+	for _, profile := range profiles {
+		if profile.ModelID == "" {
+			return imported, fmt.Errorf("a profile has no model_id")
+		}
+		key := p.getProfileKey(profile.ModelID)
+		if !overwrite {
+			exists, err := p.rdb.Exists(ctx, key).Result()
+			if err != nil {
+				return imported, fmt.Errorf("failed to read profile of %s: %w", profile.ModelID, err)
+			}
+			if exists > 0 {
+				continue
+			}
+		}
+
+		fields := map[string]any{
+			"model_id":              profile.ModelID,
+			"avg_latency_ms":        profile.AvgLatencyMS,
+			"cost_per_input_token":  profile.CostPerInputToken,
+			"cost_per_output_token": profile.CostPerOutputToken,
+			"status":                "online",
+			"error_rate":            profile.ErrorRate,
+			"total_successes":       profile.TotalSuccesses,
+			"total_failures":        profile.TotalFailures,
+			"total_input_tokens":    profile.TotalInputTokens,
+			"total_output_tokens":   profile.TotalOutputTokens,
+			"last_health_check":     time.Now().Format(time.RFC3339Nano),
+		}
+		if profile.AvgTTFTMS > 0 {
+			fields["avg_ttft_ms"] = profile.AvgTTFTMS
+		}
+		addLatencyHistogram(fields, "latency", profile.Latency)
+		addLatencyHistogram(fields, "ttft", profile.TTFT)
+
+		pipe := p.rdb.TxPipeline()
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, fields)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return imported, fmt.Errorf("failed to import profile of %s: %w", profile.ModelID, err)
+		}
+		imported = append(imported, profile.ModelID)
+	}
+	return imported, nil
+}
+
+// addLatencyHistogram adds the profile hash fields of the named histogram to fields. Empty
+// histograms add none.
+func addLatencyHistogram(fields map[string]any, name string, h LatencyHistogram) {
+	if h.Count == 0 {
+		return
+	}
+	for i, count := range h.Counts {
+		if i < len(LatencyBucketsMS) {
+			fields[fmt.Sprintf("%s_le_%d", name, LatencyBucketsMS[i])] = count
+		} else {
+			fields[name+"_le_inf"] = count
+		}
+	}
+	fields[name+"_count"] = h.Count
+	fields[name+"_sum_ms"] = h.SumMS
+}
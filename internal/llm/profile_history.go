@@ -0,0 +1,85 @@
+// In file: internal/llm/profile_history.go
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ProfileSnapshot is a model's profile at one point in time. A series of snapshots shows how
+// a model's latency, reliability, and spend trend, where the profile only holds their
+// current, smoothed values.
+type ProfileSnapshot struct {
+	Time             time.Time `json:"time"`
+	Status           string    `json:"status"`
+	AvgLatencyMS     int64     `json:"avg_latency_ms"`
+	P95LatencyMS     int64     `json:"p95_latency_ms"`
+	AvgTTFTMS        int64     `json:"avg_ttft_ms"`
+	ErrorRate        float64   `json:"error_rate"`
+	TotalSuccesses   int64     `json:"total_successes"`
+	TotalFailures    int64     `json:"total_failures"`
+	CostSpentMonthly float64   `json:"cost_spent_monthly"`
+}
+
+func (p *Profiler) getHistoryKey(modelID string) string {
+	return fmt.Sprintf("profile_history:%s", modelID)
+}
+
+// RecordSnapshot appends a snapshot of a model's current profile to its history, and drops
+// the snapshots older than the retention.
+func (p *Profiler) RecordSnapshot(ctx context.Context, modelID string, retention time.Duration) error {
+	profile, err := p.GetProfile(ctx, modelID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	snapshot, err := json.Marshal(ProfileSnapshot{
+		Time:             now,
+		Status:           profile.Status,
+		AvgLatencyMS:     profile.AvgLatencyMS,
+		P95LatencyMS:     profile.Latency.Quantile(0.95),
+		AvgTTFTMS:        profile.AvgTTFTMS,
+		ErrorRate:        profile.ErrorRate,
+		TotalSuccesses:   profile.TotalSuccesses,
+		TotalFailures:    profile.TotalFailures,
+		CostSpentMonthly: profile.CostSpentMonthly,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode profile snapshot: %w", err)
+	}
+
+	key := p.getHistoryKey(modelID)
+	pipe := p.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixMilli()), Member: snapshot})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(now.Add(-retention).UnixMilli(), 10))
+	pipe.Expire(ctx, key, retention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record profile snapshot: %w", err)
+	}
+	return nil
+}
+
+// ProfileHistory returns a model's snapshots taken since the given time, oldest first.
+func (p *Profiler) ProfileHistory(ctx context.Context, modelID string, since time.Time) ([]ProfileSnapshot, error) {
+	members, err := p.rdb.ZRangeByScore(ctx, p.getHistoryKey(modelID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile history: %w", err)
+	}
+	snapshots := make([]ProfileSnapshot, 0, len(members))
+	for _, member := range members {
+		var snapshot ProfileSnapshot
+		if err := json.Unmarshal([]byte(member), &snapshot); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,168 @@
+// In file: internal/llm/profile_updates.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// profileUpdateQueueSize bounds the success updates waiting to be applied.
+	profileUpdateQueueSize = 10000
+	// profileUpdateBatchSize bounds the updates applied in one Redis round trip.
+	profileUpdateBatchSize = 100
+	// profileUpdateTimeout bounds applying one batch of updates.
+	profileUpdateTimeout = 5 * time.Second
+	// latencyAlpha is the weight of a new observation in the latency EWMA.
+	latencyAlpha = 0.1
+)
+
+// profileUpdate is a successful call waiting to be recorded in its model's profile.
+type profileUpdate struct {
+	modelID string
+	latency time.Duration
+	usage   api.Usage
+	// at is when the call finished, which decides the month its cost is counted in.
+	at time.Time
+}
+
+// successScript applies a successful call to a profile in one atomic step: it folds the
+// latency into the EWMA and the histogram, adds the tokens, marks the model online and
+// records when it was used, recomputes the error rate, and adds the cost to the model's monthly spend. It replaces
+// a WATCH transaction and two further round trips per request.
+//
+// KEYS: profile, monthly cost.
+// ARGV: latency in ms, prompt tokens, completion tokens, cost, cost retention in seconds,
+// latency bucket field, EWMA alpha, time of the call in Unix ms.
+var successScript = redis.NewScript(`
+local latency = tonumber(ARGV[1])
+local alpha = tonumber(ARGV[7])
+local current = tonumber(redis.call('HGET', KEYS[1], 'avg_latency_ms') or '0') or 0
+redis.call('HSET', KEYS[1], 'avg_latency_ms', math.floor(alpha * latency + (1 - alpha) * current))
+
+local successes = redis.call('HINCRBY', KEYS[1], 'total_successes', 1)
+local failures = tonumber(redis.call('HGET', KEYS[1], 'total_failures') or '0') or 0
+redis.call('HINCRBY', KEYS[1], 'total_input_tokens', ARGV[2])
+redis.call('HINCRBY', KEYS[1], 'total_output_tokens', ARGV[3])
+redis.call('HSET', KEYS[1], 'status', 'online')
+redis.call('HSET', KEYS[1], 'last_used', ARGV[8])
+-- Numbers passed to Redis are truncated to integers, so the rate is passed as a string.
+redis.call('HSET', KEYS[1], 'error_rate', tostring(failures / (successes + failures)))
+
+-- The latency histogram, as written by observeLatency.
+redis.call('HINCRBY', KEYS[1], ARGV[6], 1)
+redis.call('HINCRBY', KEYS[1], 'latency_count', 1)
+redis.call('HINCRBY', KEYS[1], 'latency_sum_ms', latency)
+
+redis.call('INCRBYFLOAT', KEYS[2], ARGV[4])
+redis.call('EXPIRE', KEYS[2], ARGV[5])
+return successes
+`)
+
+// enqueueSuccess queues a success update. It never blocks.
+func (p *Profiler) enqueueSuccess(update profileUpdate) {
+	select {
+	case p.updates <- update:
+	default:
+		if dropped := p.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			slog.Warn("Profile update queue is full. Dropping updates", "model", update.modelID, "dropped_total", dropped)
+		}
+	}
+}
+
+// DroppedUpdates returns the number of success updates dropped because the queue was full.
+func (p *Profiler) DroppedUpdates() int64 {
+	return p.dropped.Load()
+}
+
+// Close applies the success updates that are still queued and stops the background writer.
+func (p *Profiler) Close() {
+	p.closeOnce.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+// runUpdates applies queued success updates until Close is called. Updates that queued up
+// while a batch was being applied are sent together in the next round trip.
+func (p *Profiler) runUpdates() {
+	defer close(p.done)
+	batch := make([]profileUpdate, 0, profileUpdateBatchSize)
+	for {
+		select {
+		case update := <-p.updates:
+			batch = append(batch, update)
+			for len(batch) < profileUpdateBatchSize && len(p.updates) > 0 {
+				batch = append(batch, <-p.updates)
+			}
+			batch = p.applyUpdates(batch)
+		case <-p.stop:
+			for {
+				select {
+				case update := <-p.updates:
+					batch = append(batch, update)
+					if len(batch) >= profileUpdateBatchSize {
+						batch = p.applyUpdates(batch)
+					}
+				default:
+					p.applyUpdates(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// applyUpdates runs the success script for each update in a single pipeline and returns an
+// empty slice to reuse. Failed updates are logged and discarded, like failed audit batches.
+func (p *Profiler) applyUpdates(batch []profileUpdate) []profileUpdate {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), profileUpdateTimeout)
+	defer cancel()
+
+	cmds, err := p.runSuccessScripts(ctx, batch)
+	if redis.HasErrorPrefix(err, "NOSCRIPT") {
+		// Redis lost its script cache (e.g. after a restart); load the script and retry.
+		if err = successScript.Load(ctx, p.rdb).Err(); err == nil {
+			cmds, err = p.runSuccessScripts(ctx, batch)
+		}
+	}
+	if err != nil {
+		failed := 0
+		for _, cmd := range cmds {
+			if cmd.Err() != nil {
+				failed++
+			}
+		}
+		slog.Error("Failed to apply profile updates", "failed", failed, "updates", len(batch), "error", err)
+	}
+	return batch[:0]
+}
+
+// runSuccessScripts sends one success script call per update in a pipeline, by its SHA.
+func (p *Profiler) runSuccessScripts(ctx context.Context, batch []profileUpdate) ([]*redis.Cmd, error) {
+	pipe := p.rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(batch))
+	for i, update := range batch {
+		costKey := fmt.Sprintf("cost:%s:%s", update.modelID, update.at.Format("2006-01"))
+		cmds[i] = successScript.EvalSha(ctx, pipe,
+			[]string{p.getProfileKey(update.modelID), costKey},
+			update.latency.Milliseconds(),
+			update.usage.PromptTokens,
+			update.usage.CompletionTokens,
+			CallCost(update.modelID, update.usage),
+			int64(costRetention.Seconds()),
+			latencyBucketField("latency", update.latency),
+			latencyAlpha,
+			update.at.UnixMilli(),
+		)
+	}
+	_, err := pipe.Exec(ctx)
+	return cmds, err
+}
@@ -4,8 +4,11 @@ package llm
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dileep-u-k/llm-gateway/internal/api"
@@ -15,8 +18,10 @@ import (
 
 // ModelProfile tracks performance, cost, and reliability metrics for an LLM.
 type ModelProfile struct {
-	ModelID            string    `json:"model_id" redis:"model_id"`
-	AvgLatencyMS       int64     `json:"avg_latency_ms" redis:"avg_latency_ms"`
+	ModelID      string `json:"model_id" redis:"model_id"`
+	AvgLatencyMS int64  `json:"avg_latency_ms" redis:"avg_latency_ms"`
+	// AvgTTFTMS is the EWMA of the time-to-first-token of streaming requests; 0 until one streams.
+	AvgTTFTMS          int64     `json:"avg_ttft_ms" redis:"avg_ttft_ms"`
 	CostPerInputToken  float64   `json:"cost_per_input_token" redis:"cost_per_input_token"`
 	CostPerOutputToken float64   `json:"cost_per_output_token" redis:"cost_per_output_token"`
 	Status             string    `json:"status" redis:"status"`
@@ -27,6 +32,76 @@ type ModelProfile struct {
 	TotalOutputTokens  int64     `json:"total_output_tokens" redis:"total_output_tokens"`
 	LastHealthCheck    time.Time `json:"last_health_check" redis:"last_health_check"`
 	CostSpentMonthly   float64   `json:"cost_spent_monthly"`
+	// LastUsed is when the model last answered or failed a request.
+	LastUsed time.Time `json:"last_used,omitzero"`
+	// LowConfidence is set by the router's StaleStatsPolicy when the statistics are too old
+	// or too few to be trusted.
+	LowConfidence bool `json:"low_confidence,omitempty"`
+	// Latency is the distribution of full-request latencies; AvgLatencyMS is its EWMA.
+	Latency LatencyHistogram `json:"latency"`
+	// TTFT is the distribution of time-to-first-token for streaming requests.
+	TTFT LatencyHistogram `json:"ttft"`
+}
+
+// LatencyBucketsMS are the upper bounds, in milliseconds, of the latency histogram buckets.
+// A final, implicit bucket holds everything slower than the last bound.
+var LatencyBucketsMS = []int64{100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// LatencyHistogram is a fixed-bucket latency distribution. Counts holds one count per bucket
+// in LatencyBucketsMS plus a final overflow bucket; counts are per bucket, not cumulative.
+type LatencyHistogram struct {
+	Counts []int64 `json:"counts"`
+	Count  int64   `json:"count"`
+	SumMS  int64   `json:"sum_ms"`
+}
+
+// Quantile estimates the q-th quantile (0 < q <= 1) as the upper bound of the bucket that
+// contains it. Observations in the overflow bucket are reported as the largest bound.
+// It returns 0 when the histogram is empty.
+func (h LatencyHistogram) Quantile(q float64) int64 {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.Count)))
+	var seen int64
+	for i, count := range h.Counts {
+		seen += count
+		if seen >= rank && i < len(LatencyBucketsMS) {
+			return LatencyBucketsMS[i]
+		}
+	}
+	return LatencyBucketsMS[len(LatencyBucketsMS)-1]
+}
+
+// latencyBucketField returns the profile hash field counting observations of the named
+// histogram that fall into the bucket for d.
+func latencyBucketField(name string, d time.Duration) string {
+	ms := d.Milliseconds()
+	for _, bound := range LatencyBucketsMS {
+		if ms <= bound {
+			return fmt.Sprintf("%s_le_%d", name, bound)
+		}
+	}
+	return name + "_le_inf"
+}
+
+// observeLatency queues the updates that add d to the named histogram of a profile.
+func observeLatency(ctx context.Context, pipe redis.Pipeliner, key, name string, d time.Duration) {
+	pipe.HIncrBy(ctx, key, latencyBucketField(name, d), 1)
+	pipe.HIncrBy(ctx, key, name+"_count", 1)
+	pipe.HIncrBy(ctx, key, name+"_sum_ms", d.Milliseconds())
+}
+
+// parseLatencyHistogram reads the named histogram from a profile hash.
+func parseLatencyHistogram(data map[string]string, name string) LatencyHistogram {
+	h := LatencyHistogram{Counts: make([]int64, len(LatencyBucketsMS)+1)}
+	for i, bound := range LatencyBucketsMS {
+		h.Counts[i], _ = strconv.ParseInt(data[fmt.Sprintf("%s_le_%d", name, bound)], 10, 64)
+	}
+	h.Counts[len(LatencyBucketsMS)], _ = strconv.ParseInt(data[name+"_le_inf"], 10, 64)
+	h.Count, _ = strconv.ParseInt(data[name+"_count"], 10, 64)
+	h.SumMS, _ = strconv.ParseInt(data[name+"_sum_ms"], 10, 64)
+	return h
 }
 
 var modelCosts = make(map[string]map[string]float64)
@@ -34,16 +109,31 @@ var modelCosts = make(map[string]map[string]float64)
 func InitializeModelCosts(costs map[string]map[string]float64) {
 	modelCosts = costs
 	for modelID, costData := range modelCosts {
-		log.Printf("Loaded cost config for %s: Input=$%.8f/token, Output=$%.8f/token", modelID, costData["input"], costData["output"])
+		slog.Info("Loaded cost config", "model", modelID, "input_cost_per_token", costData["input"], "output_cost_per_token", costData["output"])
 	}
 }
 
 type Profiler struct {
 	rdb *redis.Client
+	// updates holds the success updates waiting to be applied by the background writer.
+	updates   chan profileUpdate
+	dropped   atomic.Int64
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
+// NewProfiler creates a profiler and starts its background writer. Call Close on shutdown
+// so the queued updates are applied.
 func NewProfiler(rdb *redis.Client) *Profiler {
-	return &Profiler{rdb: rdb}
+	p := &Profiler{
+		rdb:     rdb,
+		updates: make(chan profileUpdate, profileUpdateQueueSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go p.runUpdates()
+	return p
 }
 
 func (p *Profiler) getProfileKey(modelID string) string {
@@ -65,6 +155,7 @@ func (p *Profiler) GetProfile(ctx context.Context, modelID string) (*ModelProfil
 	profile := &ModelProfile{}
 	profile.ModelID = modelID
 	profile.AvgLatencyMS, _ = strconv.ParseInt(profileData["avg_latency_ms"], 10, 64)
+	profile.AvgTTFTMS, _ = strconv.ParseInt(profileData["avg_ttft_ms"], 10, 64)
 	profile.CostPerInputToken, _ = strconv.ParseFloat(profileData["cost_per_input_token"], 64)
 	profile.CostPerOutputToken, _ = strconv.ParseFloat(profileData["cost_per_output_token"], 64)
 	profile.Status = profileData["status"]
@@ -74,6 +165,11 @@ func (p *Profiler) GetProfile(ctx context.Context, modelID string) (*ModelProfil
 	profile.TotalInputTokens, _ = strconv.ParseInt(profileData["total_input_tokens"], 10, 64)
 	profile.TotalOutputTokens, _ = strconv.ParseInt(profileData["total_output_tokens"], 10, 64)
 	profile.LastHealthCheck, _ = time.Parse(time.RFC3339Nano, profileData["last_health_check"])
+	if lastUsed, err := strconv.ParseInt(profileData["last_used"], 10, 64); err == nil {
+		profile.LastUsed = time.UnixMilli(lastUsed)
+	}
+	profile.Latency = parseLatencyHistogram(profileData, "latency")
+	profile.TTFT = parseLatencyHistogram(profileData, "ttft")
 
 	costKey := fmt.Sprintf("cost:%s:%s", modelID, time.Now().Format("2006-01"))
 	profile.CostSpentMonthly, _ = p.rdb.Get(ctx, costKey).Float64()
@@ -86,13 +182,13 @@ func (p *Profiler) createDefaultProfile(ctx context.Context, modelID string) (*M
 	if !ok {
 		// Return a zero-cost profile but log a critical error.
 		// This prevents crashes but makes it clear that config is missing.
-		log.Printf("CRITICAL: No cost information for model '%s'. Defaulting to zero cost.", modelID)
+		slog.ErrorContext(ctx, "No cost information for model, defaulting to zero cost", "model", modelID)
 		costs = map[string]float64{"input": 0, "output": 0}
 	}
 
 	profile := &ModelProfile{
 		ModelID:            modelID,
-		AvgLatencyMS:       2000, // Start with a reasonable default latency.
+		AvgLatencyMS:       defaultLatencyMS, // Start with a reasonable default latency.
 		CostPerInputToken:  costs["input"],
 		CostPerOutputToken: costs["output"],
 		Status:             "online",
@@ -118,54 +214,73 @@ func (p *Profiler) createDefaultProfile(ctx context.Context, modelID string) (*M
 	pipe.HSet(ctx, key, "last_health_check", profile.LastHealthCheck.Format(time.RFC3339Nano))
 	_, err := pipe.Exec(ctx)
 
-	log.Printf("Created new, complete profile for %s", modelID)
+	slog.InfoContext(ctx, "Created new model profile", "model", modelID)
 	return profile, err
 }
 
-func (p *Profiler) UpdateProfileOnSuccess(ctx context.Context, modelID string, latency time.Duration, usage api.Usage) {
-	key := p.getProfileKey(modelID)
-	const alpha = 0.1
+// GetStatus returns a model's health status ("online", "degraded", or "offline") without
+// creating a profile. It returns an empty string for models that have no profile yet.
+func (p *Profiler) GetStatus(ctx context.Context, modelID string) (string, error) {
+	status, err := p.rdb.HGet(ctx, p.getProfileKey(modelID), "status").Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return status, err
+}
 
-	err := p.rdb.Watch(ctx, func(tx *redis.Tx) error {
-		currentLatencyStr, err := tx.HGet(ctx, key, "avg_latency_ms").Result()
-		if err != nil && err != redis.Nil {
-			return err
+// CallCost returns the cost in USD of a call to the model with the given token usage.
+// Prompt tokens read from or written to the provider's prompt cache are charged at the
+// model's cached input and cache write prices, reasoning tokens at its reasoning price, and
+// the rest at its input and output prices. Models without configured costs are free.
+func CallCost(modelID string, usage api.Usage) float64 {
+	costs := modelCosts[modelID]
+	price := func(kind, fallback string) float64 {
+		if p, ok := costs[kind]; ok {
+			return p
 		}
-		currentLatency, _ := strconv.ParseInt(currentLatencyStr, 10, 64)
-		newLatency := int64((alpha * float64(latency.Milliseconds())) + ((1.0 - alpha) * float64(currentLatency)))
-		_, err = tx.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.HSet(ctx, key, "avg_latency_ms", newLatency)
-			return nil
-		})
-		return err
-	}, key)
-	if err != nil {
-		log.Printf("Error updating latency for %s: %v", modelID, err)
+		return costs[fallback]
 	}
+	uncachedTokens := usage.PromptTokens - usage.CachedTokens - usage.CacheWriteTokens
+	answerTokens := usage.CompletionTokens - usage.ReasoningTokens
+	return (float64(uncachedTokens) * costs["input"]) +
+		(float64(usage.CachedTokens) * price("cached_input", "input")) +
+		(float64(usage.CacheWriteTokens) * price("cache_write", "input")) +
+		(float64(answerTokens) * costs["output"]) +
+		(float64(usage.ReasoningTokens) * price("reasoning", "output"))
+}
 
-	pipe := p.rdb.Pipeline()
-	successes := pipe.HIncrBy(ctx, key, "total_successes", 1)
-	failures := pipe.HGet(ctx, key, "total_failures")
-	pipe.HIncrBy(ctx, key, "total_input_tokens", int64(usage.PromptTokens))
-	pipe.HIncrBy(ctx, key, "total_output_tokens", int64(usage.CompletionTokens))
-	pipe.HSet(ctx, key, "status", "online")
+// UpdateProfileOnSuccess records a successful call in the model's profile: its latency,
+// token usage, and cost. The update is queued and applied in the background, so Redis
+// latency never adds to the request's; it is dropped if the queue is full.
+func (p *Profiler) UpdateProfileOnSuccess(ctx context.Context, modelID string, latency time.Duration, usage api.Usage) {
+	p.enqueueSuccess(profileUpdate{modelID: modelID, latency: latency, usage: usage, at: time.Now()})
+}
 
-	callCost := (float64(usage.PromptTokens) * modelCosts[modelID]["input"]) + (float64(usage.CompletionTokens) * modelCosts[modelID]["output"])
-	costKey := fmt.Sprintf("cost:%s:%s", modelID, time.Now().Format("2006-01"))
-	pipe.IncrByFloat(ctx, costKey, callCost)
-	pipe.Expire(ctx, costKey, 35*24*time.Hour)
+// ttftScript folds a time-to-first-token into the profile's EWMA and histogram atomically.
+//
+// KEYS: profile. ARGV: time-to-first-token in ms, latency bucket field, EWMA alpha.
+var ttftScript = redis.NewScript(`
+local ttft = tonumber(ARGV[1])
+local alpha = tonumber(ARGV[3])
+local current = tonumber(redis.call('HGET', KEYS[1], 'avg_ttft_ms') or '0') or 0
+if current == 0 then
+	current = ttft
+end
+redis.call('HSET', KEYS[1], 'avg_ttft_ms', math.floor(alpha * ttft + (1 - alpha) * current))
 
-	_, err = pipe.Exec(ctx)
-	if err != nil {
-		log.Printf("Error in success update pipeline for %s: %v", modelID, err)
-		return
-	}
+-- The TTFT histogram, as written by observeLatency.
+redis.call('HINCRBY', KEYS[1], ARGV[2], 1)
+redis.call('HINCRBY', KEYS[1], 'ttft_count', 1)
+redis.call('HINCRBY', KEYS[1], 'ttft_sum_ms', ttft)
+return 1
+`)
 
-	totalFailures, _ := strconv.ParseInt(failures.Val(), 10, 64)
-	totalRequests := successes.Val() + totalFailures
-	if totalRequests > 0 {
-		errorRate := float64(totalFailures) / float64(totalRequests)
-		p.rdb.HSet(ctx, key, "error_rate", errorRate)
+// RecordTimeToFirstToken adds a streaming request's time-to-first-token to the model's profile.
+func (p *Profiler) RecordTimeToFirstToken(ctx context.Context, modelID string, ttft time.Duration) {
+	err := ttftScript.Run(ctx, p.rdb, []string{p.getProfileKey(modelID)},
+		ttft.Milliseconds(), latencyBucketField("ttft", ttft), latencyAlpha).Err()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error recording time to first token", "model", modelID, "error", err)
 	}
 }
 
@@ -175,10 +290,11 @@ func (p *Profiler) UpdateProfileOnFailure(ctx context.Context, modelID string) {
 	failures := pipe.HIncrBy(ctx, key, "total_failures", 1)
 	successes := pipe.HGet(ctx, key, "total_successes")
 	pipe.HSet(ctx, key, "status", "degraded")
+	pipe.HSet(ctx, key, "last_used", time.Now().UnixMilli())
 
 	_, err := pipe.Exec(ctx)
 	if err != nil {
-		log.Printf("Error in failure update pipeline for %s: %v", modelID, err)
+		slog.ErrorContext(ctx, "Error in failure update pipeline", "model", modelID, "error", err)
 		return
 	}
 
@@ -193,20 +309,17 @@ func (p *Profiler) UpdateProfileOnFailure(ctx context.Context, modelID string) {
 // UpdateProfileOnHealthCheck updates status based on a proactive check.
 // *** THIS IS THE FIX ***
 // It now ensures a full profile exists before writing health status to prevent creating partial profiles.
-func (p *Profiler) UpdateProfileOnHealthCheck(ctx context.Context, modelID string, isHealthy bool) {
+// The status is "online", "degraded", or "offline".
+func (p *Profiler) UpdateProfileOnHealthCheck(ctx context.Context, modelID string, status string) {
 	// First, ensure a profile exists. GetProfile will create one if it's missing.
 	// This is the critical fix that prevents the health checker from creating partial, empty profiles.
 	_, err := p.GetProfile(ctx, modelID)
 	if err != nil {
 		// Log the error but continue, as setting the health status is still important.
-		log.Printf("Error ensuring profile exists during health check for %s: %v", modelID, err)
+		slog.ErrorContext(ctx, "Error ensuring profile exists during health check", "model", modelID, "error", err)
 	}
 
 	key := p.getProfileKey(modelID)
-	status := "offline"
-	if isHealthy {
-		status = "online"
-	}
 
 	pipe := p.rdb.Pipeline()
 	pipe.HSet(ctx, key, "status", status)
@@ -214,6 +327,6 @@ func (p *Profiler) UpdateProfileOnHealthCheck(ctx context.Context, modelID strin
 	_, err = pipe.Exec(ctx)
 
 	if err != nil {
-		log.Printf("Error updating health check for %s: %v", modelID, err)
+		slog.ErrorContext(ctx, "Error updating health check", "model", modelID, "error", err)
 	}
 }
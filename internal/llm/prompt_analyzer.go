@@ -2,6 +2,9 @@
 package llm
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"regexp"
 	"strings"
 )
@@ -55,55 +58,183 @@ var (
 )
 
 // PromptAnalyzer service is responsible for determining the complexity of a user's
-// prompt and selecting an appropriate routing preference if none is provided.
-type PromptAnalyzer struct{}
+// prompt and selecting an appropriate routing preference if none is provided. Its scoring
+// comes from config.yaml, and variants of it can be A/B tested on a share of the callers.
+type PromptAnalyzer struct {
+	config     PromptAnalysisConfig
+	scoring    *promptScorer
+	variants   []*promptScorer
+	classifier PreferenceClassifier
+	cache      *DecisionCache
+	version    string
+}
+
+// PromptAnalysis explains how a preference was selected for a prompt.
+type PromptAnalysis struct {
+	Preference string
+	// Score is the prompt's complexity score.
+	Score int
+	// Language is the detected language of the prompt, or "" if it could not be told.
+	Language string
+	// Variant is the scoring variant the prompt was scored with, ControlVariant for the
+	// default scoring, or "" when no variants are configured.
+	Variant string
+	// Backend is the backend that selected the preference, e.g. PromptBackendRules.
+	Backend string
+}
+
+// NewPromptAnalyzer creates a new instance of the PromptAnalyzer with the configured scoring.
+func NewPromptAnalyzer(cfg PromptAnalysisConfig) (*PromptAnalyzer, error) {
+	cfg = cfg.WithDefaults()
+	scoring, err := compilePromptScoring(ControlVariant, 0, cfg.PromptScoring)
+	if err != nil {
+		return nil, err
+	}
+	pa := &PromptAnalyzer{config: cfg, scoring: scoring}
+	seen := map[string]bool{ControlVariant: true}
+	var total float64
+	for _, v := range cfg.Variants {
+		switch {
+		case v.Name == "" || seen[v.Name]:
+			return nil, fmt.Errorf("variant '%s': every variant needs a unique name other than '%s'", v.Name, ControlVariant)
+		case v.Percent <= 0 || v.Percent > 100:
+			return nil, fmt.Errorf("variant '%s': percent must be above 0 and at most 100", v.Name)
+		}
+		seen[v.Name] = true
+		total += v.Percent
+		variant, err := compilePromptScoring(v.Name, v.Percent, v.PromptScoring)
+		if err != nil {
+			return nil, fmt.Errorf("variant '%s': %w", v.Name, err)
+		}
+		pa.variants = append(pa.variants, variant)
+	}
+	if total > 100 {
+		return nil, fmt.Errorf("the variants' percents add up to %g, more than 100", total)
+	}
+	return pa, nil
+}
+
+// SetClassifier makes the analyzer select preferences with classifier, keeping the scoring
+// for the complexity score and for prompts the classifier fails on. It must be called before
+// the analyzer is used.
+func (pa *PromptAnalyzer) SetClassifier(classifier PreferenceClassifier) {
+	pa.classifier = classifier
+}
 
-// NewPromptAnalyzer creates a new instance of the PromptAnalyzer.
-func NewPromptAnalyzer() *PromptAnalyzer {
-	return &PromptAnalyzer{}
+// SetDecisionCache makes the analyzer cache its analysis of each prompt, per scoring variant.
+// It must be called after SetClassifier, and before the analyzer is used.
+func (pa *PromptAnalyzer) SetDecisionCache(cache *DecisionCache) {
+	classifier := ""
+	switch c := pa.classifier.(type) {
+	case *LLMPreferenceClassifier:
+		classifier = c.Backend() + ":" + c.modelID
+	case PreferenceClassifier:
+		classifier = c.Backend()
+	}
+	pa.cache, pa.version = cache, analyzerVersion(pa.config, classifier)
 }
 
-// Analyze is the core classification function. It uses a new, more robust logic flow.
+// Analyze selects a preference for a prompt with the default scoring.
 func (pa *PromptAnalyzer) Analyze(prompt string) string {
-	// 1. Pre-processing: Normalize the prompt.
+	return pa.scoring.analyze(prompt).Preference
+}
+
+// AnalyzeFor selects a preference for a prompt with the classifier, if there is one, or with
+// the scoring variant of unit, the A/B test unit (such as the caller's account) the prompt
+// belongs to.
+func (pa *PromptAnalyzer) AnalyzeFor(ctx context.Context, prompt, unit string) PromptAnalysis {
+	scorer := pa.scorerFor(unit)
+	var key string
+	if pa.cache != nil {
+		key = decisionKey("preference", pa.version+":"+scorer.name, prompt)
+		var cached PromptAnalysis
+		if pa.cache.get(ctx, key, &cached) {
+			slog.DebugContext(ctx, "Preference decision served from cache", "preference", cached.Preference)
+			return cached
+		}
+	}
+	analysis := scorer.analyze(prompt)
+	if len(pa.variants) > 0 {
+		analysis.Variant = scorer.name
+	}
+	if pa.classifier != nil && strings.TrimSpace(prompt) != "" {
+		preference, err := pa.classifier.ClassifyPreference(ctx, prompt)
+		if err != nil {
+			// The scoring's preference stands in for this request only; it is not cached.
+			slog.WarnContext(ctx, "Preference classifier failed; using the scoring's preference", "backend", pa.classifier.Backend(), "error", err)
+			return analysis
+		}
+		analysis.Preference, analysis.Backend = preference, pa.classifier.Backend()
+	}
+	if pa.cache != nil {
+		pa.cache.set(ctx, key, analysis)
+	}
+	return analysis
+}
+
+// scorerFor returns the scoring variant of unit.
+func (pa *PromptAnalyzer) scorerFor(unit string) *promptScorer {
+	if len(pa.variants) == 0 {
+		return pa.scoring
+	}
+	bucket := variantBucket(unit)
+	for _, v := range pa.variants {
+		if bucket < v.percent {
+			return v
+		}
+		bucket -= v.percent
+	}
+	return pa.scoring
+}
+
+// analyze is the core classification function. It uses a new, more robust logic flow.
+func (s *promptScorer) analyze(prompt string) PromptAnalysis {
+	// 1. Pre-processing: Normalize the prompt and pick the archetypes of its language.
 	normalizedPrompt := strings.ToLower(strings.TrimSpace(prompt))
 	if normalizedPrompt == "" {
-		return "cost" // Handle empty prompts gracefully.
+		return PromptAnalysis{Preference: "cost", Backend: PromptBackendRules} // Handle empty prompts gracefully.
 	}
+	language := DetectLanguage(normalizedPrompt)
+	archetypes := s.archetypesFor(language)
+	analysis := PromptAnalysis{Language: language, Backend: PromptBackendRules}
 
 	// 2. High-Priority Override: Handle coding tasks first as they are a distinct category.
-	if codeBlockRegex.MatchString(normalizedPrompt) || codingArchetypes.MatchString(normalizedPrompt) {
-		return "best-for-coding"
+	// Technology names are the same in every language, so the English archetypes always apply.
+	if codeBlockRegex.MatchString(normalizedPrompt) || s.archetypesFor(LanguageEnglish).coding.MatchString(normalizedPrompt) || archetypes.coding.MatchString(normalizedPrompt) {
+		analysis.Preference = "best-for-coding"
+		return analysis
 	}
 
 	// 3. Comprehensive Scoring: For ALL other prompts, calculate a score first.
+	w := s.Weights
 	var complexityScore int
-	complexityScore += len(normalizedPrompt) / 200      // Score for length
-	complexityScore += strings.Count(normalizedPrompt, "\n") * 2 // Score for structure (paragraphs)
-	if mediumComplexityArchetypes.MatchString(normalizedPrompt) {
-		complexityScore += 5
+	complexityScore += len(normalizedPrompt) / w.LengthChars             // Score for length
+	complexityScore += strings.Count(normalizedPrompt, "\n") * w.Newline // Score for structure (paragraphs)
+	if archetypes.medium.MatchString(normalizedPrompt) {
+		complexityScore += w.Medium
 	}
-	if highComplexityArchetypes.MatchString(normalizedPrompt) {
-		complexityScore += 15
+	if archetypes.high.MatchString(normalizedPrompt) {
+		complexityScore += w.High
 	}
-	if ultraComplexityArchetypes.MatchString(normalizedPrompt) {
-		complexityScore += 30
+	if archetypes.ultra.MatchString(normalizedPrompt) {
+		complexityScore += w.Ultra
 	}
+	analysis.Score = complexityScore
 
 	// 4. Final Classification with "Simplicity Filter"
 	// A prompt is only "simple" if it matches a simple pattern AND has a very low complexity score.
 	// This correctly handles your "what are... explain in detail" example.
-	if simpleQueryArchetypes.MatchString(normalizedPrompt) && complexityScore < 5 {
-		return "cost"
-	}
-
-	// Otherwise, classify based on the calculated score.
+	t := s.Thresholds
 	switch {
-	case complexityScore > 25:
-		return "max_quality" // Ultra-Complex
-	case complexityScore > 10:
-		return "default" // Complex
+	case archetypes.simple.MatchString(normalizedPrompt) && complexityScore < t.SimpleBelow:
+		analysis.Preference = "cost"
+	// Otherwise, classify based on the calculated score.
+	case complexityScore > t.MaxQualityAbove:
+		analysis.Preference = "max_quality" // Ultra-Complex
+	case complexityScore > t.DefaultAbove:
+		analysis.Preference = "default" // Complex
 	default:
-		return "balanced" // Medium
+		analysis.Preference = "balanced" // Medium
 	}
-}
\ No newline at end of file
+	return analysis
+}
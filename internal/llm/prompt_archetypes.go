@@ -0,0 +1,91 @@
+// In file: internal/llm/prompt_archetypes.go
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// archetypePack holds the archetype patterns of one language, which the PromptAnalyzer
+// scores a prompt in that language with.
+type archetypePack struct {
+	coding *regexp.Regexp
+	simple *regexp.Regexp
+	medium *regexp.Regexp
+	high   *regexp.Regexp
+	ultra  *regexp.Regexp
+}
+
+var englishArchetypes = archetypePack{
+	coding: codingArchetypes,
+	simple: simpleQueryArchetypes,
+	medium: mediumComplexityArchetypes,
+	high:   highComplexityArchetypes,
+	ultra:  ultraComplexityArchetypes,
+}
+
+// wordArchetypes matches any of the phrases as whole words. Go's \b only knows ASCII
+// letters, so `\bqué\b` would not even match "qué es".
+func wordArchetypes(phrases ...string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|\P{L})(?:` + strings.Join(phrases, "|") + `)(?:\P{L}|$)`)
+}
+
+// archetypePacks are the archetypes of the languages other than English. A prompt in a
+// language without one is scored with the English archetypes, which still recognise
+// technology names, and by its length and structure.
+var archetypePacks = map[string]archetypePack{
+	LanguageSpanish: {
+		coding: wordArchetypes(`(?:escribe|escribir|crea|crear|genera|generar|implementa|implementar|corrige|corregir|depura|depurar|refactoriza|optimiza|muéstrame)\P{L}.*(?:código|script|función|clase|método|api|endpoint|consulta|algoritmo|prueba unitaria)`),
+		simple: regexp.MustCompile(`(?i)^¿?(qué|quién|quiénes|cuál|cuáles|dónde|cuándo)\s(es|era|son|fue|fueron)\s|^(enumera|define|lista)\s`),
+		medium: wordArchetypes(`explica`, `explícame`, `resume`, `resúmeme`, `describe`, `cómo (se|funciona|hago|puedo)`, `dame una visión general de`, `profundiza en`),
+		high:   wordArchetypes(`compara`, `analiza el (impacto|efecto)`, `evalúa`, `cuáles son las (ventajas y desventajas|pros y contras)`, `discute las implicaciones de`),
+		ultra: wordArchetypes(`diseña una?`, `crea un plan (completo|detallado)`, `desarrolla (una estrategia|un plan de negocio)`, `inventa`, `redacta`, `escribe un informe detallado`, `propón una solución`,
+			`poema`, `cuento`, `letra de (una )?canción`, `guion`, `actúa como`, `eres un`, `imagina que eres`,
+			`resuelve la ecuación`, `demuestra el teorema`, `analiza este conjunto de datos`),
+	},
+	LanguageFrench: {
+		coding: wordArchetypes(`(?:écris|écrire|crée|créer|génère|générer|implémente|implémenter|corrige|corriger|débogue|déboguer|refactorise|optimise|optimiser|montre-moi)\P{L}.*(?:code|script|fonction|classe|méthode|api|endpoint|requête|algorithme|test unitaire)`),
+		simple: regexp.MustCompile(`(?i)^(qu'est-ce qu(e|')|qui (est|était|sont|étaient)\s|quel(le)?s? (est|sont|était)\s|où (est|sont|se trouve)\s|quand\s|liste\s|définis\s)`),
+		medium: wordArchetypes(`explique`, `expliquez`, `explique-moi`, `résume`, `résumez`, `décris`, `décrivez`, `comment (fonctionne|faire|puis-je)`, `donne-moi un aperçu de`, `développe sur`),
+		high:   wordArchetypes(`compare`, `comparez`, `analyse l'(impact|effet)`, `évalue`, `évaluez`, `quels sont les (avantages et (les )?inconvénients|pour et (le )?contre)`, `discute des implications de`),
+		ultra: wordArchetypes(`conçois une?`, `crée un plan (complet|détaillé)`, `élabore (une stratégie|un plan d'affaires)`, `invente`, `rédige`, `écris un rapport détaillé`, `propose une solution`,
+			`poème`, `paroles de chanson`, `scénario`, `fais comme si tu étais`, `tu es une?`, `imagine que tu es`,
+			`résous l'équation`, `démontre le théorème`, `analyse ce jeu de données`),
+	},
+	LanguageGerman: {
+		coding: wordArchetypes(`(?:schreibe|schreib|erstelle|generiere|implementiere|behebe|debugge|refaktoriere|optimiere|zeig mir)\P{L}.*(?:code|skript|script|funktion|klasse|methode|api|endpunkt|abfrage|algorithmus|unit-test|dockerfile)`,
+			`(?:code|skript|funktion|klasse|methode|abfrage|algorithmus)\P{L}.*(?:schreiben|erstellen|generieren|implementieren|beheben|debuggen|optimieren)`),
+		simple: regexp.MustCompile(`(?i)^(was|wer|welche[rs]?|wo|wann)\s(ist|war|sind|waren)\s|^(liste|definiere|nenne)\s`),
+		medium: wordArchetypes(`erkläre`, `erklär`, `fasse\P{L}.*zusammen`, `beschreibe`, `wie (funktioniert|kann ich|macht man)`, `gib mir einen überblick über`),
+		high:   wordArchetypes(`vergleiche`, `analysiere die (auswirkungen|wirkung)`, `bewerte`, `vor- und nachteile`, `diskutiere die folgen`),
+		ultra: wordArchetypes(`entwirf`, `erstelle einen (umfassenden|detaillierten) plan`, `entwickle (eine strategie|einen geschäftsplan|ein framework)`, `erfinde`, `verfasse`, `schreibe einen ausführlichen bericht`, `schlage eine lösung vor`,
+			`gedicht`, `kurzgeschichte`, `songtext`, `drehbuch`, `handle als`, `du bist eine?`, `stell dir vor, du bist`,
+			`löse die gleichung`, `beweise den satz`, `analysiere diesen datensatz`),
+	},
+	LanguagePortuguese: {
+		coding: wordArchetypes(`(?:escreva|escreve|crie|cria|gere|gera|implemente|corrija|depure|refatore|otimize|mostre-me|me mostre)\P{L}.*(?:código|script|função|classe|método|api|endpoint|consulta|algoritmo|teste unitário)`),
+		simple: regexp.MustCompile(`(?i)^(o que|quem|qual|quais|onde|quando)\s(é|era|são|foi|foram)\s|^(liste|defina)\s`),
+		medium: wordArchetypes(`explique`, `explica`, `resuma`, `descreva`, `como (funciona|faço|posso)`, `(dê-me|me dê) uma visão geral de`),
+		high:   wordArchetypes(`compare`, `analise o (impacto|efeito)`, `avalie`, `quais são as (vantagens e desvantagens|prós e contras)`, `discuta as implicações de`),
+		ultra: wordArchetypes(`projete`, `crie um plano (completo|detalhado)`, `desenvolva (uma estratégia|um plano de negócios)`, `invente`, `redija`, `escreva um relatório detalhado`, `proponha uma solução`,
+			`poema`, `conto`, `letra de música`, `roteiro`, `aja como`, `você é uma?`, `imagine que você é`,
+			`resolva a equação`, `prove o teorema`, `analise este conjunto de dados`),
+	},
+	LanguageItalian: {
+		coding: wordArchetypes(`(?:scrivi|crea|genera|implementa|correggi|debugga|rifattorizza|ottimizza|mostrami)\P{L}.*(?:codice|script|funzione|classe|metodo|api|endpoint|query|algoritmo|test unitario)`),
+		simple: regexp.MustCompile(`(?i)^(cos'è|cosa (è|sono)|chi (è|era|sono|fu)|quale? (è|era)|quali sono|dove (è|si trova)|quando)\s|^(elenca|definisci)\s`),
+		medium: wordArchetypes(`spiega`, `spiegami`, `riassumi`, `descrivi`, `come (funziona|faccio|posso)`, `dammi una panoramica di`, `approfondisci`),
+		high:   wordArchetypes(`confronta`, `analizza l'(impatto|effetto)`, `valuta`, `quali sono i (pro e (i )?contro|vantaggi e (gli )?svantaggi)`, `discuti le implicazioni di`),
+		ultra: wordArchetypes(`progetta`, `crea un piano (completo|dettagliato)`, `sviluppa (una strategia|un business plan)`, `inventa`, `redigi`, `scrivi un rapporto dettagliato`, `proponi una soluzione`,
+			`poesia`, `racconto`, `testo di una canzone`, `sceneggiatura`, `agisci come`, `sei una?`, `immagina di essere`,
+			`risolvi l'equazione`, `dimostra il teorema`, `analizza questo dataset`),
+	},
+}
+
+// archetypesFor returns the archetypes to score a prompt in the given language with.
+func archetypesFor(language string) archetypePack {
+	if pack, ok := archetypePacks[language]; ok {
+		return pack
+	}
+	return englishArchetypes
+}
@@ -0,0 +1,230 @@
+// In file: internal/llm/prompt_scoring.go
+package llm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ControlVariant is the name reported for callers scored with the default scoring while
+// variants are being tested.
+const ControlVariant = "control"
+
+// PromptScoring tunes how the PromptAnalyzer scores a prompt's complexity and maps the score
+// to a routing preference. Unset settings keep their built-in values.
+type PromptScoring struct {
+	Weights    PromptScoreWeights    `yaml:"weights"`
+	Thresholds PromptScoreThresholds `yaml:"thresholds"`
+	// Archetypes replace the built-in archetype patterns of a language, by ISO 639-1 code.
+	Archetypes map[string]ArchetypePatterns `yaml:"archetypes"`
+}
+
+// PromptScoreWeights are the points each signal adds to a prompt's complexity score.
+type PromptScoreWeights struct {
+	// LengthChars is how many bytes of prompt add one point.
+	LengthChars int `yaml:"length_chars"`
+	// Newline is added for every line break, as structured prompts tend to be harder.
+	Newline int `yaml:"newline"`
+	// Medium, High, and Ultra are added when the prompt matches an archetype of that
+	// complexity.
+	Medium int `yaml:"medium"`
+	High   int `yaml:"high"`
+	Ultra  int `yaml:"ultra"`
+}
+
+// PromptScoreThresholds map a complexity score to a preference: "cost" for a simple question
+// scoring below SimpleBelow, "max_quality" above MaxQualityAbove, "default" above
+// DefaultAbove, and "balanced" otherwise.
+type PromptScoreThresholds struct {
+	SimpleBelow     int `yaml:"simple_below"`
+	DefaultAbove    int `yaml:"default_above"`
+	MaxQualityAbove int `yaml:"max_quality_above"`
+}
+
+// ArchetypePatterns are the regular expressions, in Go's syntax, of each kind of archetype.
+// They are matched against the lowercased prompt. A kind left empty keeps its built-in
+// patterns.
+type ArchetypePatterns struct {
+	Coding []string `yaml:"coding"`
+	Simple []string `yaml:"simple"`
+	Medium []string `yaml:"medium"`
+	High   []string `yaml:"high"`
+	Ultra  []string `yaml:"ultra"`
+}
+
+// PromptScoringVariant is an alternative scoring tried on a share of the callers, to A/B test
+// a change before making it the default. Its unset settings are those of the default
+// scoring.
+type PromptScoringVariant struct {
+	Name string `yaml:"name"`
+	// Percent is the share of callers, from 0 to 100, scored with the variant.
+	Percent       float64 `yaml:"percent"`
+	PromptScoring `yaml:",inline"`
+}
+
+// PromptAnalysisConfig is the `prompt_analysis` section of config.yaml: the default scoring,
+// the variants being tested against it, and the backend selecting preferences.
+type PromptAnalysisConfig struct {
+	PromptScoring `yaml:",inline"`
+	Variants      []PromptScoringVariant `yaml:"variants"`
+	// Backend selects the preference of a prompt: PromptBackendRules (the default) with the
+	// scoring, or PromptBackendLLM or PromptBackendHTTP with a classifier. The scoring still
+	// reports a complexity score, and decides when the classifier fails.
+	Backend    string                 `yaml:"backend"`
+	Classifier PromptClassifierConfig `yaml:"classifier"`
+}
+
+// defaultPromptScoring is the built-in scoring.
+var defaultPromptScoring = PromptScoring{
+	Weights:    PromptScoreWeights{LengthChars: 200, Newline: 2, Medium: 5, High: 15, Ultra: 30},
+	Thresholds: PromptScoreThresholds{SimpleBelow: 5, DefaultAbove: 10, MaxQualityAbove: 25},
+}
+
+// WithDefaults fills in the settings that were left unset, the variants' from the default
+// scoring.
+func (c PromptAnalysisConfig) WithDefaults() PromptAnalysisConfig {
+	c.PromptScoring = c.PromptScoring.inherit(defaultPromptScoring)
+	variants := make([]PromptScoringVariant, len(c.Variants))
+	for i, v := range c.Variants {
+		v.PromptScoring = v.PromptScoring.inherit(c.PromptScoring)
+		variants[i] = v
+	}
+	c.Variants = variants
+	if c.Backend == "" {
+		c.Backend = PromptBackendRules
+	}
+	if c.Classifier.Timeout == 0 {
+		c.Classifier.Timeout = 2 * time.Second
+	}
+	if c.Classifier.CacheTTL == 0 {
+		c.Classifier.CacheTTL = 24 * time.Hour
+	}
+	return c
+}
+
+// Validate reports settings that cannot work.
+func (c PromptAnalysisConfig) Validate() error {
+	switch {
+	case c.Backend != PromptBackendRules && c.Backend != PromptBackendLLM && c.Backend != PromptBackendHTTP:
+		return fmt.Errorf("unknown backend '%s' (expected %s, %s, or %s)", c.Backend, PromptBackendRules, PromptBackendLLM, PromptBackendHTTP)
+	case c.Backend == PromptBackendHTTP && c.Classifier.URL == "":
+		return fmt.Errorf("the http backend needs a classifier url")
+	case c.Classifier.MinConfidence < 0 || c.Classifier.MinConfidence > 1:
+		return fmt.Errorf("classifier min_confidence must be between 0 and 1")
+	case c.Classifier.Timeout < 0 || c.Classifier.CacheTTL < 0:
+		return fmt.Errorf("classifier timeout and cache_ttl must not be negative")
+	}
+	_, err := NewPromptAnalyzer(c)
+	return err
+}
+
+// inherit returns the scoring with its unset settings taken from base. Archetypes are
+// inherited kind by kind.
+func (s PromptScoring) inherit(base PromptScoring) PromptScoring {
+	w := &s.Weights
+	for _, f := range []struct{ v, base *int }{
+		{&w.LengthChars, &base.Weights.LengthChars}, {&w.Newline, &base.Weights.Newline},
+		{&w.Medium, &base.Weights.Medium}, {&w.High, &base.Weights.High}, {&w.Ultra, &base.Weights.Ultra},
+		{&s.Thresholds.SimpleBelow, &base.Thresholds.SimpleBelow}, {&s.Thresholds.DefaultAbove, &base.Thresholds.DefaultAbove},
+		{&s.Thresholds.MaxQualityAbove, &base.Thresholds.MaxQualityAbove},
+	} {
+		if *f.v == 0 {
+			*f.v = *f.base
+		}
+	}
+	archetypes := make(map[string]ArchetypePatterns, len(base.Archetypes)+len(s.Archetypes))
+	for language, patterns := range base.Archetypes {
+		archetypes[language] = patterns
+	}
+	for language, patterns := range s.Archetypes {
+		inherited := archetypes[language]
+		for _, kind := range []struct{ v, base *[]string }{
+			{&patterns.Coding, &inherited.Coding}, {&patterns.Simple, &inherited.Simple},
+			{&patterns.Medium, &inherited.Medium}, {&patterns.High, &inherited.High}, {&patterns.Ultra, &inherited.Ultra},
+		} {
+			if len(*kind.v) == 0 {
+				*kind.v = *kind.base
+			}
+		}
+		archetypes[language] = patterns
+	}
+	s.Archetypes = archetypes
+	return s
+}
+
+// promptScorer is a PromptScoring with its archetypes compiled.
+type promptScorer struct {
+	name    string
+	percent float64
+	PromptScoring
+	archetypes map[string]archetypePack
+}
+
+// compilePromptScoring checks a scoring whose defaults are filled in and compiles its
+// archetypes over the built-in ones.
+func compilePromptScoring(name string, percent float64, s PromptScoring) (*promptScorer, error) {
+	w, t := s.Weights, s.Thresholds
+	switch {
+	case w.LengthChars < 0 || w.Newline < 0 || w.Medium < 0 || w.High < 0 || w.Ultra < 0:
+		return nil, fmt.Errorf("weights must not be negative")
+	case t.SimpleBelow < 0 || t.DefaultAbove < 0 || t.MaxQualityAbove < t.DefaultAbove:
+		return nil, fmt.Errorf("thresholds must not be negative, and max_quality_above must not be below default_above")
+	}
+	scorer := &promptScorer{name: name, percent: percent, PromptScoring: s, archetypes: make(map[string]archetypePack, len(s.Archetypes))}
+	for language, patterns := range s.Archetypes {
+		if !ruleLanguage.MatchString(language) {
+			return nil, fmt.Errorf("archetypes: language '%s' is not a two-letter ISO 639-1 code", language)
+		}
+		pack := archetypesFor(language)
+		for _, kind := range []struct {
+			name     string
+			patterns []string
+			re       **regexp.Regexp
+		}{
+			{"coding", patterns.Coding, &pack.coding}, {"simple", patterns.Simple, &pack.simple},
+			{"medium", patterns.Medium, &pack.medium}, {"high", patterns.High, &pack.high}, {"ultra", patterns.Ultra, &pack.ultra},
+		} {
+			if len(kind.patterns) == 0 {
+				continue
+			}
+			re, err := compileArchetypes(kind.patterns)
+			if err != nil {
+				return nil, fmt.Errorf("archetypes %s %s: %w", language, kind.name, err)
+			}
+			*kind.re = re
+		}
+		scorer.archetypes[language] = pack
+	}
+	return scorer, nil
+}
+
+// compileArchetypes compiles patterns into one expression matching any of them.
+func compileArchetypes(patterns []string) (*regexp.Regexp, error) {
+	alternatives := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		alternatives[i] = "(?:" + pattern + ")"
+	}
+	return regexp.Compile(strings.Join(alternatives, "|"))
+}
+
+// archetypesFor returns the archetypes the scorer scores a prompt in the given language with.
+func (s *promptScorer) archetypesFor(language string) archetypePack {
+	if pack, ok := s.archetypes[language]; ok {
+		return pack
+	}
+	return archetypesFor(language)
+}
+
+// variantBucket places an A/B test unit, such as a caller's account, in [0, 100), so the
+// same unit always gets the same variant.
+func variantBucket(unit string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(unit))
+	return float64(h.Sum32()%10000) / 100
+}
@@ -0,0 +1,49 @@
+// In file: internal/llm/provider_error.go
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is a provider's non-2xx answer to a call.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	// Attempt and Attempts number the call among the client's retries.
+	Attempt  int
+	Attempts int
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (attempt %d/%d): status %d, body: %s", e.Provider, e.Attempt, e.Attempts, e.StatusCode, e.Body)
+}
+
+// IsRetryable reports whether a failed call may succeed on another model: the provider failed
+// internally, was overloaded or rate limited, timed out, or could not be reached. Errors in
+// the request or its content, which every model would fail on, are not retryable.
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Internal, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
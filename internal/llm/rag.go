@@ -8,13 +8,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dileep-u-k/llm-gateway/internal/config"
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
 )
 
 // =================================================================================
@@ -22,7 +30,7 @@ import (
 // =================================================================================
 
 const (
-	// Default values for configuration if not set in the .env file.
+	// Default values for settings missing from the configuration file.
 	defaultEmbeddingModel = "text-embedding-3-small"
 	defaultOpenAIAPIURL   = "https://api.openai.com/v1/embeddings"
 
@@ -32,10 +40,18 @@ const (
 	embeddingCacheTTL    = 7 * 24 * time.Hour // Cache embeddings for a week.
 	responseCacheTTL     = 24 * time.Hour     // Cache final responses for a day.
 
+	// pineconeUpsertBatchSize mirrors the ingestor's batch size for upsert calls.
+	pineconeUpsertBatchSize = 100
+	// pineconeListPageSize is the largest page Pinecone returns when listing vector IDs.
+	pineconeListPageSize = 100
+	// pineconeDeleteBatchSize is the most IDs Pinecone deletes in one request.
+	pineconeDeleteBatchSize = 1000
+
 )
 
 // Config holds all the configuration for the RAG service.
-// Loading from the environment makes the service portable and easy to configure.
+// Keys come from the environment; every other setting from the `rag` section of the
+// configuration file.
 type Config struct {
 	OpenAIKey      string
 	PineconeKey    string
@@ -43,31 +59,76 @@ type Config struct {
 	RedisAddr      string
 	EmbeddingModel string
 	OpenAIAPIURL   string
+	// EmbeddingCost is the embedding price in USD per million tokens, or 0 if unknown.
+	EmbeddingCost float64
+	// EmbeddingBatchWindow is how long a query embedding waits for concurrent ones to be
+	// sent with it in a single API call. A negative window sends every query on its own.
+	EmbeddingBatchWindow time.Duration
+}
+
+// RAGSettings is the `rag` section of the configuration file.
+type RAGSettings struct {
+	EmbeddingModel    string `yaml:"embedding_model"`
+	EmbeddingAPIURL   string `yaml:"embedding_api_url"`
+	PineconeIndexHost string `yaml:"pinecone_index_host"`
+	// EmbeddingCost overrides the built-in price of the embedding model, in USD per million tokens.
+	EmbeddingCost float64 `yaml:"embedding_cost"`
+	// EmbeddingBatchWindow is how long concurrent query embeddings are collected into one batch.
+	EmbeddingBatchWindow time.Duration `yaml:"embedding_batch_window"`
+}
+
+// embeddingCosts are the published prices of OpenAI's embedding models, in USD per million tokens.
+var embeddingCosts = map[string]float64{
+	"text-embedding-3-small": 0.02,
+	"text-embedding-3-large": 0.13,
+	"text-embedding-ada-002": 0.10,
 }
 
-// LoadConfig loads configuration from environment variables.
+// LoadConfig loads the RAG configuration shared by the gateway and the offline tools.
+// Without a configuration file, only the environment is used.
 func LoadConfig() (*Config, error) {
-	cfg := &Config{
-		OpenAIKey:      os.Getenv("OPENAI_API_KEY"),
-		PineconeKey:    os.Getenv("PINECONE_API_KEY"),
-		PineconeHost:   os.Getenv("PINECONE_INDEX_HOST"),
-		RedisAddr:      os.Getenv("REDIS_ADDR"),
-		EmbeddingModel: getEnv("EMBEDDING_MODEL", defaultEmbeddingModel),
-		OpenAIAPIURL:   getEnv("OPENAI_API_URL", defaultOpenAIAPIURL),
+	cfg, err := LoadSettings()
+	if err != nil {
+		return nil, err
 	}
-
 	if cfg.OpenAIKey == "" || cfg.PineconeKey == "" || cfg.PineconeHost == "" || cfg.RedisAddr == "" {
-		return nil, errors.New("OPENAI_API_KEY, PINECONE_API_KEY, PINECONE_INDEX_HOST, and REDIS_ADDR must be set")
+		return nil, errors.New("OPENAI_API_KEY, PINECONE_API_KEY, REDIS_ADDR, and rag.pinecone_index_host must be set")
 	}
 	return cfg, nil
 }
 
-// getEnv is a helper to read an env var or return a default.
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// LoadSettings loads the RAG configuration like LoadConfig but without requiring keys or
+// addresses, for tools that only inspect it, such as the ingestor's dry run.
+func LoadSettings() (*Config, error) {
+	var file struct {
+		RAG RAGSettings `yaml:"rag"`
+	}
+	if err := config.Load(&file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
 	}
-	return fallback
+	cfg := &Config{
+		OpenAIKey:      os.Getenv("OPENAI_API_KEY"),
+		PineconeKey:    os.Getenv("PINECONE_API_KEY"),
+		PineconeHost:   config.Legacy(file.RAG.PineconeIndexHost, "PINECONE_INDEX_HOST", "rag.pinecone_index_host"),
+		RedisAddr:      os.Getenv("REDIS_ADDR"),
+		EmbeddingModel: config.Legacy(file.RAG.EmbeddingModel, "EMBEDDING_MODEL", "rag.embedding_model"),
+		OpenAIAPIURL:   config.Legacy(file.RAG.EmbeddingAPIURL, "OPENAI_API_URL", "rag.embedding_api_url"),
+	}
+	if cfg.EmbeddingModel == "" {
+		cfg.EmbeddingModel = defaultEmbeddingModel
+	}
+	if cfg.OpenAIAPIURL == "" {
+		cfg.OpenAIAPIURL = defaultOpenAIAPIURL
+	}
+	cfg.EmbeddingCost = file.RAG.EmbeddingCost
+	if cfg.EmbeddingCost == 0 {
+		cfg.EmbeddingCost = embeddingCosts[cfg.EmbeddingModel]
+	}
+	cfg.EmbeddingBatchWindow = file.RAG.EmbeddingBatchWindow
+	if cfg.EmbeddingBatchWindow == 0 {
+		cfg.EmbeddingBatchWindow = defaultEmbeddingBatchWindow
+	}
+	return cfg, nil
 }
 
 // =================================================================================
@@ -80,6 +141,10 @@ type RAGService struct {
 	config      *Config
 	httpClient  *http.Client
 	redisClient *redis.Client
+	// embeddingLimiter throttles embedding requests when a rate limit is set.
+	embeddingLimiter *rate.Limiter
+	// embeddings coalesces concurrent query embeddings into batches; nil when batching is off.
+	embeddings *embeddingBatcher
 }
 
 // NewRAGService is the constructor for our RAG service.
@@ -94,90 +159,128 @@ func NewRAGService(cfg *Config) (*RAGService, error) {
 		return nil, fmt.Errorf("could not connect to Redis: %w", err)
 	}
 
-	return &RAGService{
+	s := &RAGService{
 		config: cfg,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second, // Set a reasonable timeout for external API calls.
+			Timeout:   30 * time.Second, // Set a reasonable timeout for external API calls.
+			Transport: telemetry.Transport(),
 		},
 		redisClient: rdb,
-	}, nil
+	}
+	if cfg.EmbeddingBatchWindow >= 0 {
+		s.embeddings = newEmbeddingBatcher(cfg.EmbeddingBatchWindow, embeddingBatchSize, s.embedBatch)
+	}
+	return s, nil
+}
+
+// SetEmbeddingRateLimit throttles embedding requests to requestsPerMinute, shared by every
+// goroutine using the service, so bulk ingestion stays under the provider's rate limit.
+// Zero removes the limit. It must be called before the service is used.
+func (s *RAGService) SetEmbeddingRateLimit(requestsPerMinute int) {
+	if requestsPerMinute <= 0 {
+		s.embeddingLimiter = nil
+		return
+	}
+	s.embeddingLimiter = rate.NewLimiter(rate.Limit(float64(requestsPerMinute)/60), 1)
 }
 
 // GetEmbedding retrieves a vector embedding for a given text string.
 // It implements a caching layer to avoid re-calculating embeddings for the same text,
 // saving both time and money on API calls.
 func (s *RAGService) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	ctx, span := telemetry.StartSpan(ctx, "rag.embedding", attribute.String("embedding.model", s.config.EmbeddingModel))
+	embedding, err := s.getEmbedding(ctx, text)
+	telemetry.EndSpan(span, err)
+	return embedding, err
+}
+
+func (s *RAGService) getEmbedding(ctx context.Context, text string) ([]float32, error) {
 	// 1. Check cache first.
-	cacheKey := embeddingCachePrefix + GenerateCacheKey(text)
+	cacheKey := s.embeddingCacheKey(s.config.EmbeddingModel, text)
 	cachedEmbedding, err := s.redisClient.Get(ctx, cacheKey).Bytes()
 	if err == nil {
 		// Cache hit!
-		var embedding []float32
-		if err := json.Unmarshal(cachedEmbedding, &embedding); err == nil {
-			log.Println("Embedding cache HIT")
-			return embedding, nil
+		var entry embeddingCacheEntry
+		if err := json.Unmarshal(cachedEmbedding, &entry); err == nil && len(entry.Embedding) > 0 {
+			slog.DebugContext(ctx, "Embedding cache HIT")
+			s.recordCacheLookup(ctx, CacheEmbedding, true)
+			return entry.Embedding, nil
 		}
-		log.Printf("Error unmarshalling cached embedding: %v", err) // Log error but proceed to fetch fresh.
+		slog.WarnContext(ctx, "Error unmarshalling cached embedding", "error", err) // Log error but proceed to fetch fresh.
 	} else if err != redis.Nil {
-		log.Printf("Redis GET error for embedding: %v", err) // Log error but proceed.
-	}
-	log.Println("Embedding cache MISS")
-
-	// 2. If cache miss, call the API.
-	type APIRequest struct {
-		Input string `json:"input"`
-		Model string `json:"model"`
-	}
-	type APIResponse struct {
-		Data []struct {
-			Embedding []float32 `json:"embedding"`
-		} `json:"data"`
+		slog.WarnContext(ctx, "Redis GET error for embedding", "error", err) // Log error but proceed.
 	}
+	slog.DebugContext(ctx, "Embedding cache MISS")
+	s.recordCacheLookup(ctx, CacheEmbedding, false)
 
-	payload := APIRequest{Input: text, Model: s.config.EmbeddingModel}
-	payloadBytes, err := json.Marshal(payload)
+	// 2. If cache miss, call the API, together with any concurrent misses.
+	embedding, err := s.embedText(ctx, text)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal OpenAI request: %w", err)
+		return nil, fmt.Errorf("OpenAI embedding API request failed: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.OpenAIAPIURL, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OpenAI request: %w", err)
+	// 3. Store the new embedding in the cache before returning.
+	if err := s.storeEmbedding(ctx, s.config.EmbeddingModel, text, embedding); err != nil {
+		slog.WarnContext(ctx, "Failed to set embedding cache in Redis", "error", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.config.OpenAIKey)
 
-	body, err := s.doRequestWithRetry(req)
-	if err != nil {
-		return nil, fmt.Errorf("OpenAI embedding API request failed: %w", err)
-	}
+	return embedding, nil
+}
 
-	var apiResp APIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal OpenAI response: %w", err)
+// embedText embeds a single text, in a batch with concurrent requests when batching is on.
+func (s *RAGService) embedText(ctx context.Context, text string) ([]float32, error) {
+	if s.embeddings != nil {
+		return s.embeddings.Embed(ctx, text)
 	}
-	if len(apiResp.Data) == 0 {
-		return nil, errors.New("no embedding data returned from API")
+	embeddings, err := s.embedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
 	}
-	embedding := apiResp.Data[0].Embedding
+	return embeddings[0], nil
+}
 
-	// 3. Store the new embedding in the cache before returning.
-	embeddingBytes, err := json.Marshal(embedding)
-	if err != nil {
-		log.Printf("Error marshalling embedding for cache: %v", err)
-	} else {
-		err := s.redisClient.Set(ctx, cacheKey, embeddingBytes, embeddingCacheTTL).Err()
-		if err != nil {
-			log.Printf("Failed to set embedding cache in Redis: %v", err)
+// RetrievedContext is what the knowledge base returned for a prompt.
+type RetrievedContext struct {
+	// Text is the concatenated text of the matched chunks.
+	Text string
+	// Topic is the topic of the top match, and Score its confidence score.
+	Topic string
+	Score float64
+	// Chunks is the number of matched chunks.
+	Chunks int
+}
+
+// RetrievalScope restricts retrieval to the documents a caller may read: the shared knowledge
+// base, plus documents uploaded by Owner and, within a tenant, those in its Namespace.
+type RetrievalScope struct {
+	Owner     string
+	Namespace string
+}
+
+// pineconeFilter matches vectors whose owner and namespace metadata are either absent,
+// which marks shared knowledge-base content, or equal to the scope's.
+func (scope RetrievalScope) pineconeFilter() map[string]any {
+	matchOrShared := func(field, value string) map[string]any {
+		shared := map[string]any{field: map[string]any{"$exists": false}}
+		if value == "" {
+			return shared
 		}
+		return map[string]any{"$or": []any{shared, map[string]any{field: map[string]any{"$eq": value}}}}
 	}
+	return map[string]any{"$and": []any{matchOrShared("owner", scope.Owner), matchOrShared("namespace", scope.Namespace)}}
+}
 
-	return embedding, nil
+// QueryPinecone queries the Pinecone index to find the most relevant document chunks
+// the scope may read.
+func (s *RAGService) QueryPinecone(ctx context.Context, embedding []float32, topK int, scope RetrievalScope) (RetrievedContext, error) {
+	ctx, span := telemetry.StartSpan(ctx, "rag.pinecone_query", attribute.Int("rag.top_k", topK))
+	retrieved, err := s.queryPinecone(ctx, embedding, topK, scope)
+	span.SetAttributes(attribute.Float64("rag.top_score", retrieved.Score), attribute.Int("rag.chunks", retrieved.Chunks))
+	telemetry.EndSpan(span, err)
+	return retrieved, err
 }
 
-// QueryPinecone queries the Pinecone index to find the most relevant document chunks.
-// It returns the concatenated context text, the topic of the top match, and its confidence score.
-func (s *RAGService) QueryPinecone(ctx context.Context, embedding []float32, topK int) (string, string, float64, error) {
+func (s *RAGService) queryPinecone(ctx context.Context, embedding []float32, topK int, scope RetrievalScope) (RetrievedContext, error) {
 	type Match struct {
 		Score    float64 `json:"score"`
 		Metadata struct {
@@ -189,41 +292,43 @@ func (s *RAGService) QueryPinecone(ctx context.Context, embedding []float32, top
 		Matches []Match `json:"matches"`
 	}
 	type APIRequest struct {
-		Vector          []float32 `json:"vector"`
-		TopK            int       `json:"topK"`
-		IncludeMetadata bool      `json:"includeMetadata"`
+		Vector          []float32      `json:"vector"`
+		TopK            int            `json:"topK"`
+		IncludeMetadata bool           `json:"includeMetadata"`
+		Filter          map[string]any `json:"filter"`
 	}
 
 	payload := APIRequest{
 		Vector:          embedding,
 		TopK:            topK,
 		IncludeMetadata: true,
+		Filter:          scope.pineconeFilter(),
 	}
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", "", 0.0, fmt.Errorf("failed to marshal Pinecone request: %w", err)
+		return RetrievedContext{}, fmt.Errorf("failed to marshal Pinecone request: %w", err)
 	}
 
 	queryURL := s.config.PineconeHost + "/query"
 	req, err := http.NewRequestWithContext(ctx, "POST", queryURL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		return "", "", 0.0, fmt.Errorf("failed to create Pinecone request: %w", err)
+		return RetrievedContext{}, fmt.Errorf("failed to create Pinecone request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Api-Key", s.config.PineconeKey)
 
 	body, err := s.doRequestWithRetry(req)
 	if err != nil {
-		return "", "", 0.0, fmt.Errorf("pinecone query API request failed: %w", err)
+		return RetrievedContext{}, fmt.Errorf("pinecone query API request failed: %w", err)
 	}
 
 	var apiResp APIResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return "", "", 0.0, fmt.Errorf("failed to unmarshal Pinecone response: %w", err)
+		return RetrievedContext{}, fmt.Errorf("failed to unmarshal Pinecone response: %w", err)
 	}
 
 	if len(apiResp.Matches) == 0 {
-		return "", "", 0.0, nil // No matches found is not an error, just an empty result.
+		return RetrievedContext{}, nil // No matches found is not an error, just an empty result.
 	}
 
 	// Build the context from all matched documents.
@@ -235,7 +340,7 @@ func (s *RAGService) QueryPinecone(ctx context.Context, embedding []float32, top
 
 	// The top match determines the primary topic and score.
 	topMatch := apiResp.Matches[0]
-	return strings.TrimSpace(contextBuilder.String()), topMatch.Metadata.Topic, topMatch.Score, nil
+	return RetrievedContext{Text: strings.TrimSpace(contextBuilder.String()), Topic: topMatch.Metadata.Topic, Score: topMatch.Score, Chunks: len(apiResp.Matches)}, nil
 }
 
 // =================================================================================
@@ -247,10 +352,11 @@ func (s *RAGService) QueryPinecone(ctx context.Context, embedding []float32, top
 func (s *RAGService) CheckCache(ctx context.Context, prompt string) (string, bool) {
 	cacheKey := responseCachePrefix + GenerateCacheKey(prompt)
 	val, err := s.redisClient.Get(ctx, cacheKey).Result()
+	s.recordCacheLookup(ctx, CacheResponse, err == nil)
 	if err == redis.Nil {
 		return "", false // Cache miss.
 	} else if err != nil {
-		log.Printf("Redis GET error for response cache: %v", err)
+		slog.WarnContext(ctx, "Redis GET error for response cache", "error", err)
 		return "", false // Treat error as a cache miss.
 	}
 	return val, true // Cache hit.
@@ -261,7 +367,7 @@ func (s *RAGService) SetCache(ctx context.Context, prompt, response string) {
 	cacheKey := responseCachePrefix + GenerateCacheKey(prompt)
 	err := s.redisClient.Set(ctx, cacheKey, response, responseCacheTTL).Err()
 	if err != nil {
-		log.Printf("Redis SET error for response cache: %v", err)
+		slog.WarnContext(ctx, "Redis SET error for response cache", "error", err)
 	}
 }
 
@@ -271,107 +377,261 @@ func (s *RAGService) SetCache(ctx context.Context, prompt, response string) {
 
 // doRequestWithRetry is a robust utility to perform an HTTP request with automatic retries.
 // It uses exponential backoff to gracefully handle transient network or API errors.
-// CORRECTED: This is the robust, production-grade retry function.
+// Rate-limited responses (429) are retried after the delay the API asks for, on a separate
+// budget, so throttling under load does not use up the retries meant for failures.
 func (s *RAGService) doRequestWithRetry(req *http.Request) ([]byte, error) {
+	// The body is read once, so every attempt can send it again.
+	var bodyBytes []byte
+	if req.Body != nil {
+		var err error
+		if bodyBytes, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+	}
+
 	var lastErr error
 	delay := initialRetryDelay
-	for i := 0; i < maxRetries; i++ {
-		// IMPORTANT: For retries, the request body must be readable multiple times.
-		// We ensure this by setting GetBody, which the http.Client will use on retries.
-		if req.Body != nil {
-			bodyBytes, err := io.ReadAll(req.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read request body: %w", err)
-			}
-			req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes)) // Restore body for the first attempt
-			req.GetBody = func() (io.ReadCloser, error) {
-				return io.NopCloser(bytes.NewBuffer(bodyBytes)), nil // Provide fresh body for retries
-			}
+	failures, rateLimited := 0, 0
+	for {
+		if bodyBytes != nil {
+			req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 		}
-
+		wait := delay
 		resp, err := s.httpClient.Do(req)
 		if err != nil {
-			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", i+1, maxRetries, err)
-			log.Println(lastErr)
-			time.Sleep(delay)
-			delay *= 2
-			continue
-		}
-
-		body, readErr := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if readErr != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", readErr)
+			lastErr = fmt.Errorf("request failed (attempt %d/%d): %w", failures+1, maxRetries, err)
+		} else {
+			body, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if readErr != nil {
+				return nil, fmt.Errorf("failed to read response body: %w", readErr)
+			}
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return body, nil
+			}
+			lastErr = fmt.Errorf("API error (attempt %d/%d): status %d, body: %s", failures+1, maxRetries, resp.StatusCode, string(body))
+			wait = RetryAfter(resp.Header, delay)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				if rateLimited == maxRateLimitRetries {
+					return nil, fmt.Errorf("still rate limited after %d retries: %w", rateLimited, lastErr)
+				}
+				rateLimited++
+				slog.WarnContext(req.Context(), "Rate limited, retrying", "url", req.URL.Redacted(), "retry_after", wait)
+				if err := sleepBeforeRetry(req.Context(), wait); err != nil {
+					return nil, err
+				}
+				delay *= 2
+				continue
+			}
+			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				return nil, lastErr // Do not retry on other client errors.
+			}
 		}
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return body, nil
+		failures++
+		if failures == maxRetries {
+			return nil, lastErr
 		}
-		lastErr = fmt.Errorf("API error (attempt %d/%d): status %d, body: %s", i+1, maxRetries, resp.StatusCode, string(body))
-		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-			return nil, lastErr // Do not retry on client errors like 4xx.
+		slog.WarnContext(req.Context(), "Retrying request", "url", req.URL.Redacted(), "error", lastErr)
+		if err := sleepBeforeRetry(req.Context(), wait); err != nil {
+			return nil, err
 		}
-		time.Sleep(delay)
 		delay *= 2
 	}
-	return nil, lastErr
 }
 
 // RetrieveContext is a high-level method that gets an embedding and queries Pinecone.
-func (s *RAGService) RetrieveContext(ctx context.Context, text string, topK int) (string, float64, error) {
+func (s *RAGService) RetrieveContext(ctx context.Context, text string, topK int, scope RetrievalScope) (RetrievedContext, error) {
 	embedding, err := s.GetEmbedding(ctx, text)
 	if err != nil {
-		return "", 0.0, fmt.Errorf("failed to get embedding for RAG context: %w", err)
+		return RetrievedContext{}, fmt.Errorf("failed to get embedding for RAG context: %w", err)
 	}
 
-	// The QueryPinecone function now returns context, topic, and score. We only need context and score here.
-	contextText, _, score, err := s.QueryPinecone(ctx, embedding, topK)
+	retrieved, err := s.QueryPinecone(ctx, embedding, topK, scope)
 	if err != nil {
-		return "", 0.0, fmt.Errorf("failed to query pinecone for RAG context: %w", err)
+		return RetrievedContext{}, fmt.Errorf("failed to query pinecone for RAG context: %w", err)
 	}
 
-	return contextText, score, nil
+	return retrieved, nil
 }
 
 // GenerateVectorsForChunks is a new batch-processing method for the ingestor.
+// Vector IDs start with TopicVectorPrefix(topic), so a topic's vectors can be listed.
 func (s *RAGService) GenerateVectorsForChunks(ctx context.Context, chunks []string, topic string) ([]Vector, error) {
-	// This logic is moved from the ingestor to ensure consistency.
-	// It calls the OpenAI batch embedding endpoint.
-	type APIRequest struct {
-		Input []string `json:"input"`
-		Model string   `json:"model"`
+	vectors, err := s.GenerateVectorsForSource(ctx, chunks, topic, "")
+	if err != nil {
+		return nil, err
 	}
-	type APIResponse struct {
-		Data []struct {
-			Embedding []float32 `json:"embedding"`
-		} `json:"data"`
+	for i := range vectors {
+		vectors[i].ID = TopicVectorID(topic, chunks[i])
 	}
+	return vectors, nil
+}
 
-	payload := APIRequest{Input: chunks, Model: s.config.EmbeddingModel}
-	payloadBytes, _ := json.Marshal(payload)
-	req, _ := http.NewRequestWithContext(ctx, "POST", s.config.OpenAIAPIURL, bytes.NewBuffer(payloadBytes))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.config.OpenAIKey)
-	body, err := s.doRequestWithRetry(req)
+// TopicVectorID is the ID of the vector the ingestor writes for a chunk of a topic.
+func TopicVectorID(topic, chunk string) string {
+	return TopicVectorPrefix(topic) + GenerateCacheKey(topic+"::"+chunk)
+}
+
+// TopicVectorPrefix is the ID prefix of the vectors the ingestor writes for a topic.
+// Vectors ingested from a CMS webhook are keyed by their source instead and never carry it.
+func TopicVectorPrefix(topic string) string {
+	return topic + "#"
+}
+
+// GenerateVectorsForSource embeds chunks that belong to a single source document.
+// When source is set it is stored in the vector metadata and folded into the vector ID,
+// which allows every chunk of a document to be replaced or deleted as a unit.
+func (s *RAGService) GenerateVectorsForSource(ctx context.Context, chunks []string, topic, source string) ([]Vector, error) {
+	// This logic is moved from the ingestor to ensure consistency.
+	// It calls the OpenAI batch embedding endpoint.
+	embeddings, err := s.embedBatch(ctx, chunks)
 	if err != nil {
 		return nil, err
 	}
-	var apiResp APIResponse
-	if json.Unmarshal(body, &apiResp) != nil {
-		return nil, fmt.Errorf("failed to unmarshal OpenAI embedding response")
-	}
-	if len(apiResp.Data) != len(chunks) {
-		return nil, errors.New("mismatch between chunks and embeddings count")
-	}
 	vectors := make([]Vector, len(chunks))
 	for i, chunk := range chunks {
 		vectors[i] = Vector{
 			ID:     GenerateCacheKey(topic + "::" + chunk), // Using the central helper
-			Values: apiResp.Data[i].Embedding,
+			Values: embeddings[i],
 			Metadata: map[string]interface{}{
 				"text":  chunk,
 				"topic": topic,
 			},
 		}
+		if source != "" {
+			vectors[i].ID = GenerateCacheKey(source + "::" + chunk)
+			vectors[i].Metadata["source"] = source
+		}
 	}
 	return vectors, nil
 }
+
+// =================================================================================
+// Vector Store Writes
+// =================================================================================
+
+// UpsertVectors writes vectors to the Pinecone index in batches.
+// It is used for online ingestion, where the gateway keeps the knowledge base fresh
+// without waiting for a manual run of the offline ingestor.
+func (s *RAGService) UpsertVectors(ctx context.Context, vectors []Vector) error {
+	type APIRequest struct {
+		Vectors []Vector `json:"vectors"`
+	}
+	for j := 0; j < len(vectors); j += pineconeUpsertBatchSize {
+		end := j + pineconeUpsertBatchSize
+		if end > len(vectors) {
+			end = len(vectors)
+		}
+		payloadBytes, err := json.Marshal(APIRequest{Vectors: vectors[j:end]})
+		if err != nil {
+			return fmt.Errorf("failed to marshal Pinecone upsert request: %w", err)
+		}
+		if _, err := s.doPineconeRequest(ctx, "/vectors/upsert", payloadBytes); err != nil {
+			return fmt.Errorf("pinecone upsert API request failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteVectorsBySource removes every vector whose "source" metadata matches the given source.
+// This is how stale chunks of an edited or removed document are purged before re-ingestion.
+func (s *RAGService) DeleteVectorsBySource(ctx context.Context, source string) error {
+	payload := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"source": map[string]string{"$eq": source},
+		},
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Pinecone delete request: %w", err)
+	}
+	if _, err := s.doPineconeRequest(ctx, "/vectors/delete", payloadBytes); err != nil {
+		return fmt.Errorf("pinecone delete API request failed: %w", err)
+	}
+	return nil
+}
+
+// ListVectorIDs returns the IDs of every vector in the index whose ID starts with prefix.
+// Listing by prefix is only supported by serverless indexes.
+func (s *RAGService) ListVectorIDs(ctx context.Context, prefix string) ([]string, error) {
+	var page struct {
+		Vectors []struct {
+			ID string `json:"id"`
+		} `json:"vectors"`
+		Pagination struct {
+			Next string `json:"next"`
+		} `json:"pagination"`
+	}
+	var ids []string
+	query := url.Values{"prefix": {prefix}, "limit": {strconv.Itoa(pineconeListPageSize)}}
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", s.config.PineconeHost+"/vectors/list?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Pinecone request: %w", err)
+		}
+		req.Header.Set("Api-Key", s.config.PineconeKey)
+		body, err := s.doRequestWithRetry(req)
+		if err != nil {
+			return nil, fmt.Errorf("pinecone list API request failed: %w", err)
+		}
+		page.Vectors, page.Pagination.Next = nil, ""
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse Pinecone list response: %w", err)
+		}
+		for _, v := range page.Vectors {
+			ids = append(ids, v.ID)
+		}
+		if page.Pagination.Next == "" {
+			return ids, nil
+		}
+		query.Set("paginationToken", page.Pagination.Next)
+	}
+}
+
+// DeleteVectors removes vectors by ID.
+func (s *RAGService) DeleteVectors(ctx context.Context, ids []string) error {
+	for j := 0; j < len(ids); j += pineconeDeleteBatchSize {
+		end := min(j+pineconeDeleteBatchSize, len(ids))
+		payloadBytes, err := json.Marshal(map[string][]string{"ids": ids[j:end]})
+		if err != nil {
+			return fmt.Errorf("failed to marshal Pinecone delete request: %w", err)
+		}
+		if _, err := s.doPineconeRequest(ctx, "/vectors/delete", payloadBytes); err != nil {
+			return fmt.Errorf("pinecone delete API request failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// PingPinecone checks that the Pinecone index is reachable and accepts the configured API key.
+// Unlike other Pinecone calls it does not retry, so health probes fail fast.
+func (s *RAGService) PingPinecone(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.PineconeHost+"/describe_index_stats", bytes.NewBufferString("{}"))
+	if err != nil {
+		return fmt.Errorf("failed to create Pinecone request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Api-Key", s.config.PineconeKey)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pinecone is unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("pinecone returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// doPineconeRequest sends an authenticated JSON POST to the given path of the Pinecone index.
+func (s *RAGService) doPineconeRequest(ctx context.Context, path string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.PineconeHost+path, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pinecone request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Api-Key", s.config.PineconeKey)
+	return s.doRequestWithRetry(req)
+}
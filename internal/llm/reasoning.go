@@ -0,0 +1,46 @@
+// In file: internal/llm/reasoning.go
+package llm
+
+import "strings"
+
+// The levels of ReasoningEffort.
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// MinThinkingBudget is the smallest budget Anthropic's extended thinking accepts.
+const MinThinkingBudget = 1024
+
+// thinkingBudgets is the extended thinking budget each reasoning effort stands for.
+var thinkingBudgets = map[string]int{
+	ReasoningEffortLow:    MinThinkingBudget,
+	ReasoningEffortMedium: 4096,
+	ReasoningEffortHigh:   16384,
+}
+
+// thinkingBudget returns the extended thinking budget the config asks for, or 0 to not
+// think. An explicit budget takes precedence over the effort.
+func (c *GenerationConfig) thinkingBudget() int {
+	if c.ThinkingBudget > 0 {
+		return max(c.ThinkingBudget, MinThinkingBudget)
+	}
+	return thinkingBudgets[c.ReasoningEffort]
+}
+
+// isOpenAIReasoningModel reports whether an OpenAI model is a reasoning model (the o-series),
+// which takes a reasoning effort and max_completion_tokens but no sampling parameters.
+func isOpenAIReasoningModel(modelID string) bool {
+	return len(modelID) > 1 && modelID[0] == 'o' && modelID[1] >= '0' && modelID[1] <= '9' ||
+		strings.HasPrefix(modelID, "gpt-5")
+}
+
+// estimateReasoningTokens estimates the tokens of a model's visible thinking, for providers
+// that bill it as output without counting it separately. It never exceeds the output.
+func estimateReasoningTokens(thinking string, completionTokens int) int {
+	if thinking == "" {
+		return 0
+	}
+	return min(EstimateTokens(thinking), completionTokens)
+}
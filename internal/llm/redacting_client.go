@@ -0,0 +1,155 @@
+// In file: internal/llm/redacting_client.go
+package llm
+
+import (
+	"context"
+
+	"github.com/dileep-u-k/llm-gateway/internal/pii"
+	"github.com/dileep-u-k/llm-gateway/internal/tools"
+)
+
+// RedactingClient wraps an LLMClient so PII in the messages is detected and, in mask mode,
+// replaced with placeholders before the provider sees it. Placeholders in the response,
+// including tool call arguments, are replaced with the original values again.
+//
+// Placeholders are tracked in the request's pii.Session, so they stay consistent across
+// tool-use rounds and the handler can audit what was found. Calls made without a session
+// (e.g. health checks) get a throwaway one.
+type RedactingClient struct {
+	next     LLMClient
+	detector *pii.Detector
+	mask     bool
+}
+
+// Statically verify that RedactingClient implements the LLMClient interface.
+var _ LLMClient = (*RedactingClient)(nil)
+
+// NewRedactingClient wraps a client. With mask set to false, PII is only recorded.
+func NewRedactingClient(next LLMClient, detector *pii.Detector, mask bool) *RedactingClient {
+	return &RedactingClient{next: next, detector: detector, mask: mask}
+}
+
+// Generate redacts the messages, calls the wrapped client, and restores the response.
+func (c *RedactingClient) Generate(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (*GenerationResult, error) {
+	session := sessionFor(ctx)
+	result, err := c.next.Generate(ctx, c.redact(session, messages), config, availableTools)
+	if err != nil || !c.mask {
+		return result, err
+	}
+	result.Content = session.Unmask(result.Content)
+	result.ToolCalls = unmaskToolCalls(session, result.ToolCalls)
+	return result, nil
+}
+
+// GenerateStream redacts the messages and restores placeholders in the streamed response.
+func (c *RedactingClient) GenerateStream(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (<-chan *StreamingResult, error) {
+	session := sessionFor(ctx)
+	upstream, err := c.next.GenerateStream(ctx, c.redact(session, messages), config, availableTools)
+	if err != nil || !c.mask {
+		return upstream, err
+	}
+
+	out := newStreamChannel(config)
+	go func() {
+		defer close(out)
+		unmasker := session.NewStreamUnmasker()
+		consumerGone := false
+		send := func(chunk *StreamingResult) {
+			if consumerGone {
+				return
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				consumerGone = true
+			}
+		}
+		for chunk := range upstream {
+			if chunk.ContentDelta != "" {
+				chunk.ContentDelta = unmasker.Write(chunk.ContentDelta)
+			}
+			// Tool call arguments arrive in fragments, so their placeholders are only restored
+			// once the handler has reassembled them; see UnmaskToolCalls.
+			// Anything held back must be emitted before the stream reports usage or an error.
+			if chunk.Usage != nil || chunk.Err != nil {
+				if rest := unmasker.Flush(); rest != "" {
+					send(&StreamingResult{ContentDelta: rest})
+				}
+			}
+			if chunk.ContentDelta == "" && chunk.ToolCallChunk == nil && chunk.Usage == nil && chunk.Err == nil && chunk.Deprecation == nil {
+				continue
+			}
+			send(chunk)
+		}
+		if rest := unmasker.Flush(); rest != "" {
+			send(&StreamingResult{ContentDelta: rest})
+		}
+	}()
+	return out, nil
+}
+
+// redact returns a copy of the messages with PII masked, or the messages unchanged in
+// detect mode. Detected entities are recorded in the session either way.
+func (c *RedactingClient) redact(session *pii.Session, messages []Message) []Message {
+	redacted := make([]Message, len(messages))
+	for i, msg := range messages {
+		redacted[i] = msg
+		redacted[i].Content = c.redactText(session, msg.Content)
+		// Earlier tool calls carry the restored values, so they are masked again when resent.
+		if len(msg.ToolCalls) > 0 {
+			redacted[i].ToolCalls = make([]*tools.ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				masked := *call
+				masked.Function.Arguments = c.redactText(session, call.Function.Arguments)
+				redacted[i].ToolCalls[j] = &masked
+			}
+		}
+	}
+	return redacted
+}
+
+func (c *RedactingClient) redactText(session *pii.Session, text string) string {
+	matches := c.detector.Detect(text)
+	if len(matches) == 0 {
+		return text
+	}
+	if !c.mask {
+		session.Record(matches)
+		return text
+	}
+	return session.Mask(text, matches)
+}
+
+// UnmaskToolCalls restores placeholders in the arguments of tool calls assembled from a stream,
+// so tools receive the real values. It is a no-op when the request has no PII session.
+func UnmaskToolCalls(ctx context.Context, calls []*tools.ToolCall) []*tools.ToolCall {
+	session := pii.SessionFrom(ctx)
+	if session == nil {
+		return calls
+	}
+	return unmaskToolCalls(session, calls)
+}
+
+func unmaskToolCalls(session *pii.Session, calls []*tools.ToolCall) []*tools.ToolCall {
+	for _, call := range calls {
+		call.Function.Arguments = session.Unmask(call.Function.Arguments)
+	}
+	return calls
+}
+
+func sessionFor(ctx context.Context) *pii.Session {
+	if session := pii.SessionFrom(ctx); session != nil {
+		return session
+	}
+	return pii.NewSession()
+}
+
+// Ping pings the wrapped client; a ping sends no messages to redact.
+func (c *RedactingClient) Ping(ctx context.Context, modelID string) error {
+	return Ping(ctx, c.next, modelID)
+}
+
+// CountTokens counts the messages as the provider would receive them, redacted.
+func (c *RedactingClient) CountTokens(ctx context.Context, modelID string, messages []Message) (int, error) {
+	return CountTokens(ctx, c.next, modelID, c.redact(sessionFor(ctx), messages))
+}
@@ -0,0 +1,146 @@
+// In file: internal/llm/request_policy.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxRateLimitRetries bounds how often a rate-limited (429) call is retried. These retries
+	// do not count against the retries for failures.
+	maxRateLimitRetries = 6
+	// maxRetryAfter caps the wait a Retry-After header can impose on a single retry.
+	maxRetryAfter = 2 * time.Minute
+)
+
+// RequestPolicy controls how long a provider call may take and how it is retried. Fields
+// left unset inherit from the broader policy: model, then provider, then default.
+type RequestPolicy struct {
+	// Timeout bounds a single HTTP request to the provider, including reading the response.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxRetries is how many times a failed call is retried after the first attempt. Client
+	// errors (4xx) are never retried.
+	MaxRetries *int `yaml:"max_retries"`
+	// InitialRetryDelay is the wait before the first retry; it doubles for every later one.
+	InitialRetryDelay time.Duration `yaml:"initial_retry_delay"`
+}
+
+// RequestPolicies is the `request_policy` section of config.yaml.
+type RequestPolicies struct {
+	Default   RequestPolicy            `yaml:"default"`
+	Providers map[string]RequestPolicy `yaml:"providers"`
+	Models    map[string]RequestPolicy `yaml:"models"`
+}
+
+// DefaultRequestPolicy returns the policy used when config.yaml sets none.
+func DefaultRequestPolicy() RequestPolicy {
+	retries := defaultMaxRetries
+	return RequestPolicy{Timeout: defaultTimeout, MaxRetries: &retries, InitialRetryDelay: initialRetryDelay}
+}
+
+// Merge returns the policy with the fields set in override replacing its own.
+func (p RequestPolicy) Merge(override RequestPolicy) RequestPolicy {
+	if override.Timeout > 0 {
+		p.Timeout = override.Timeout
+	}
+	if override.MaxRetries != nil {
+		p.MaxRetries = override.MaxRetries
+	}
+	if override.InitialRetryDelay > 0 {
+		p.InitialRetryDelay = override.InitialRetryDelay
+	}
+	return p
+}
+
+// Validate reports settings that cannot work.
+func (p RequestPolicy) Validate() error {
+	if p.Timeout < 0 || p.InitialRetryDelay < 0 {
+		return fmt.Errorf("timeout and initial_retry_delay must not be negative")
+	}
+	if p.MaxRetries != nil && *p.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	return nil
+}
+
+// attempts returns the total number of attempts a call may make.
+func (p RequestPolicy) attempts() int {
+	if p.MaxRetries == nil {
+		return defaultMaxRetries + 1
+	}
+	return *p.MaxRetries + 1
+}
+
+// RequestPolicyFor returns the effective request policy of a model.
+func (c *RouterConfig) RequestPolicyFor(modelID string) RequestPolicy {
+	policy := DefaultRequestPolicy().Merge(c.RequestPolicies.Default)
+	if provider, ok := c.RequestPolicies.Providers[c.ProviderOf(modelID)]; ok {
+		policy = policy.Merge(provider)
+	}
+	if model, ok := c.RequestPolicies.Models[modelID]; ok {
+		policy = policy.Merge(model)
+	}
+	return policy
+}
+
+// ValidateRequestPolicies checks every policy in the request_policy section.
+func (c *RouterConfig) ValidateRequestPolicies() error {
+	if err := c.RequestPolicies.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for provider, policy := range c.RequestPolicies.Providers {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("provider %s: %w", provider, err)
+		}
+	}
+	for modelID, policy := range c.RequestPolicies.Models {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("model %s: %w", modelID, err)
+		}
+	}
+	return nil
+}
+
+// withTimeout bounds a call made through an SDK rather than the policy's HTTP client.
+func (p RequestPolicy) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.Timeout)
+}
+
+// sleepBeforeRetry waits for the retry delay, returning early with the context's error if
+// the request is cancelled in the meantime.
+func sleepBeforeRetry(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryAfter returns how long a response asks the client to wait before retrying, from its
+// Retry-After header in seconds or as an HTTP date, capped at maxRetryAfter. It returns
+// fallback when the header is missing or invalid.
+func RetryAfter(header http.Header, fallback time.Duration) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return fallback
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = time.Until(at)
+	} else {
+		return fallback
+	}
+	return min(max(wait, 0), maxRetryAfter)
+}
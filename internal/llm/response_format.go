@@ -0,0 +1,64 @@
+// In file: internal/llm/response_format.go
+package llm
+
+import "encoding/json"
+
+// The types of ResponseFormat.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// defaultResponseFormatName labels a schema the caller left unnamed.
+const defaultResponseFormatName = "response"
+
+// anyObjectSchema is the schema of a json_object answer.
+var anyObjectSchema = json.RawMessage(`{"type":"object"}`)
+
+// ResponseFormat constrains the answer to JSON, using each provider's own mechanism: OpenAI's
+// json_schema mode, a forced tool whose input is the answer for Anthropic, a response schema
+// for Gemini, and JSON mode for Mistral, which takes no schema.
+type ResponseFormat struct {
+	// Type is ResponseFormatText, ResponseFormatJSONObject, or ResponseFormatJSONSchema.
+	Type string
+	// Name labels the schema for providers that ask for one.
+	Name string
+	// Schema is the JSON Schema of a json_schema answer. Its root must be an object.
+	Schema json.RawMessage
+}
+
+// isJSON reports whether the format asks for JSON at all.
+func (f *ResponseFormat) isJSON() bool {
+	return f != nil && (f.Type == ResponseFormatJSONObject || f.Type == ResponseFormatJSONSchema)
+}
+
+func (f *ResponseFormat) name() string {
+	if f.Name == "" {
+		return defaultResponseFormatName
+	}
+	return f.Name
+}
+
+// schema returns the format's schema, or one that accepts any object for json_object.
+func (f *ResponseFormat) schema() json.RawMessage {
+	if f.Type == ResponseFormatJSONSchema && len(f.Schema) > 0 {
+		return f.Schema
+	}
+	return anyObjectSchema
+}
+
+// jsonFormat returns the JSON format the answer must take, or nil for free text. JSONMode
+// alone counts as a json_object format.
+func (c *GenerationConfig) jsonFormat() *ResponseFormat {
+	if c == nil {
+		return nil
+	}
+	if c.ResponseFormat.isJSON() {
+		return c.ResponseFormat
+	}
+	if c.JSONMode {
+		return &ResponseFormat{Type: ResponseFormatJSONObject}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// In file: internal/llm/rotating_client.go
+package llm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dileep-u-k/llm-gateway/internal/tools"
+)
+
+// RotatingClient wraps a provider client whose API key can change while the gateway runs.
+// When the key is rotated, a new client is built and used for every later call; calls in
+// flight finish on the client they started with.
+type RotatingClient struct {
+	build func(apiKey string) (LLMClient, error)
+
+	mu      sync.RWMutex
+	current LLMClient
+}
+
+// Statically verify that RotatingClient implements the LLMClient interface.
+var _ LLMClient = (*RotatingClient)(nil)
+
+// NewRotatingClient builds the initial client from the API key. The build function is used
+// again for every rotated key.
+func NewRotatingClient(apiKey string, build func(apiKey string) (LLMClient, error)) (*RotatingClient, error) {
+	client, err := build(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatingClient{build: build, current: client}, nil
+}
+
+// Rotate replaces the client with one that uses the new API key. If the new client cannot
+// be built, the previous one stays in use.
+func (c *RotatingClient) Rotate(apiKey string) error {
+	client, err := c.build(apiKey)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.current = client
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *RotatingClient) client() LLMClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+func (c *RotatingClient) Generate(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (*GenerationResult, error) {
+	return c.client().Generate(ctx, messages, config, availableTools)
+}
+
+func (c *RotatingClient) GenerateStream(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (<-chan *StreamingResult, error) {
+	return c.client().GenerateStream(ctx, messages, config, availableTools)
+}
+
+func (c *RotatingClient) Ping(ctx context.Context, modelID string) error {
+	return Ping(ctx, c.client(), modelID)
+}
+
+func (c *RotatingClient) Forward(ctx context.Context, body []byte) (*ForwardedResponse, error) {
+	return Forward(ctx, c.client(), body)
+}
+
+func (c *RotatingClient) CountTokens(ctx context.Context, modelID string, messages []Message) (int, error) {
+	return CountTokens(ctx, c.client(), modelID, messages)
+}
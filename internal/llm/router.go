@@ -5,9 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // =================================================================================
@@ -16,16 +20,54 @@ import (
 
 // RoutingStrategy defines the weights for scoring models based on a preference.
 type RoutingStrategy struct {
-	UseCodingScore bool    `yaml:"use_coding_score"`
-	QualityWeight  float64 `yaml:"quality_weight"`
-	CostWeight     float64 `yaml:"cost_weight"`
-	LatencyWeight  float64 `yaml:"latency_weight"`
+	UseCodingScore bool `yaml:"use_coding_score"`
+	// UseTTFT scores latency by time-to-first-token instead of total latency, for interactive
+	// use. It only applies when every contender has streamed, so all are measured alike.
+	UseTTFT       bool    `yaml:"use_ttft"`
+	QualityWeight float64 `yaml:"quality_weight"`
+	CostWeight    float64 `yaml:"cost_weight"`
+	LatencyWeight float64 `yaml:"latency_weight"`
+	// Exploration occasionally routes to contenders other than the best one.
+	Exploration ExplorationPolicy `yaml:"exploration"`
 }
 
 // ModelMetadata holds static, configured information about a model.
 type ModelMetadata struct {
 	QualityScore float64 `yaml:"quality_score"`
 	CodingScore  float64 `yaml:"coding_score"`
+	// ContextWindow is the maximum number of tokens (input + output) the model accepts.
+	ContextWindow int `yaml:"context_window"`
+	// Provider is the model's provider or family (e.g. "openai"). If empty, it is inferred
+	// from the model ID.
+	Provider string `yaml:"provider"`
+	// Enabled puts the model into rotation. It defaults to true; set it to false to keep a
+	// model configured without routing to it.
+	Enabled *bool `yaml:"enabled"`
+	// Costs are the model's prices, used for routing, budgets, and cost reports.
+	Costs *ModelCosts `yaml:"costs"`
+	// BudgetUSD caps the model's monthly spend. The router skips the model once it is reached.
+	BudgetUSD float64 `yaml:"budget_usd"`
+	// SupportsTools marks a model that can call tools. Requests answered by the tool loop are
+	// only routed to such models.
+	SupportsTools bool `yaml:"supports_tools"`
+}
+
+// ModelCosts are a model's prices in USD per million tokens.
+type ModelCosts struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+	// Reasoning is the price of reasoning tokens, for providers that charge them apart from
+	// the answer. It defaults to the output price.
+	Reasoning float64 `yaml:"reasoning"`
+	// CachedInput is the price of prompt tokens read from the provider's prompt cache, and
+	// CacheWrite that of prompt tokens written to it. Both default to the input price.
+	CachedInput float64 `yaml:"cached_input"`
+	CacheWrite  float64 `yaml:"cache_write"`
+}
+
+// IsEnabled reports whether the model is in rotation.
+func (m ModelMetadata) IsEnabled() bool {
+	return m.Enabled == nil || *m.Enabled
 }
 
 // RouterConfig holds the complete configuration for the router.
@@ -33,6 +75,55 @@ type RouterConfig struct {
 	Thresholds map[string]interface{}     `yaml:"pre_check_thresholds"`
 	Models     map[string]ModelMetadata   `yaml:"models"`
 	Strategies map[string]RoutingStrategy `yaml:"strategies"`
+	Failover   FailoverPolicy             `yaml:"failover"`
+	// Budget throttles models as they near their monthly budget.
+	Budget BudgetPolicy `yaml:"budget_policy"`
+	// StaleStats decays the statistics of idle models and explores low-confidence ones.
+	StaleStats StaleStatsPolicy `yaml:"stale_stats"`
+	// RequestPolicies sets provider call timeouts and retries per provider and model.
+	RequestPolicies RequestPolicies `yaml:"request_policy"`
+	// Transport tunes the connection pool shared by the provider clients.
+	Transport TransportConfig `yaml:"http_transport"`
+	// Aliases maps alternative and retired model names to the models that serve them.
+	Aliases map[string]ModelAlias `yaml:"aliases"`
+}
+
+// thresholdKinds lists the pre-check thresholds the gateway reads and the YAML type each is
+// read as. A value of any other type would make the type assertion that reads it panic.
+var thresholdKinds = []struct{ key, kind string }{
+	{"max_error_rate", "decimal"},
+	{"min_request_count", "integer"},
+	{"health_check_staleness", "duration"},
+	{"relevance_threshold", "decimal"},
+}
+
+// ValidateThresholds checks that every threshold the gateway reads is present and has the
+// type it is read as. It reports all problems at once.
+func (c *RouterConfig) ValidateThresholds() error {
+	var errs []error
+	for _, t := range thresholdKinds {
+		value, ok := c.Thresholds[t.key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s is missing", t.key))
+			continue
+		}
+		switch t.kind {
+		case "decimal":
+			if _, ok := value.(float64); !ok {
+				errs = append(errs, fmt.Errorf("%s must be a decimal number such as 0.5, got %v", t.key, value))
+			}
+		case "integer":
+			if _, ok := value.(int); !ok {
+				errs = append(errs, fmt.Errorf("%s must be a whole number such as 20, got %v", t.key, value))
+			}
+		case "duration":
+			s, ok := value.(string)
+			if _, err := time.ParseDuration(s); !ok || err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a quoted duration such as \"5m\", got %v", t.key, value))
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // =================================================================================
@@ -53,67 +144,121 @@ func NewRouter(profiler *Profiler, config *RouterConfig) *Router {
 	}
 }
 
+// CostCapError reports that every model the router could otherwise select is estimated to
+// cost more than the request's cost cap. Cheapest is the least expensive of them.
+type CostCapError struct {
+	MaxCostUSD      float64
+	CheapestModel   string
+	CheapestCostUSD float64
+}
+
+func (e *CostCapError) Error() string {
+	return fmt.Sprintf("every suitable model is estimated to cost more than the cap of $%.6f; the cheapest, %s, is estimated at $%.6f", e.MaxCostUSD, e.CheapestModel, e.CheapestCostUSD)
+}
+
 // contender holds the profile and metadata for a model that has passed pre-checks.
 type contender struct {
 	Profile       *ModelProfile
 	Metadata      ModelMetadata
 	EstimatedCost float64
+	// BudgetPenalty is the share of its score the model loses for nearing its budget.
+	BudgetPenalty float64
+	// LatencyMS is the latency the model is scored by: its total latency or its TTFT.
+	LatencyMS float64
 }
 
 // SelectOptimalModel is the core routing algorithm.
 // It now uses a two-pass approach:
 // 1. Filter models that pass pre-checks to create a pool of "contenders".
 // 2. Normalize and score the contenders to find the best one.
-func (r *Router) SelectOptimalModel(ctx context.Context, availableModels []string, preference string, promptTokens int, modelBudgets map[string]float64) (string, error) {
-	log.Printf("--- Starting Model Selection (Preference: '%s') ---", preference)
+//
+// A positive maxCostUSD filters out models whose call is estimated to cost more. If that
+// leaves no contender, the error is a *CostCapError.
+func (r *Router) SelectOptimalModel(ctx context.Context, availableModels []string, preference string, promptTokens int, modelBudgets map[string]float64, maxCostUSD float64) (string, error) {
+	ctx, span := telemetry.StartSpan(ctx, "router.select_model",
+		attribute.String("router.preference", preference),
+		attribute.Int("router.prompt_tokens", promptTokens),
+		attribute.Int("router.candidates", len(availableModels)),
+	)
+	modelID, err := r.selectOptimalModel(ctx, availableModels, preference, promptTokens, modelBudgets, maxCostUSD)
+	span.SetAttributes(attribute.String("router.selected_model", modelID))
+	telemetry.EndSpan(span, err)
+	return modelID, err
+}
+
+func (r *Router) selectOptimalModel(ctx context.Context, availableModels []string, preference string, promptTokens int, modelBudgets map[string]float64, maxCostUSD float64) (string, error) {
+	slog.DebugContext(ctx, "Starting model selection", "preference", preference)
 
 	// --- Pass 1: Filter models and create a pool of contenders ---
 	contenders := make(map[string]contender)
+	var overCap *CostCapError
+	now := time.Now()
+	disabled := r.disabledModels(ctx)
 	for _, modelID := range availableModels {
+		if disabled[modelID] {
+			slog.DebugContext(ctx, "Filtered model", "candidate", modelID, "reason", "Model is disabled by an operator.")
+			continue
+		}
 		profile, err := r.profiler.GetProfile(ctx, modelID)
 		if err != nil {
-			log.Printf("Could not get profile for model %s, skipping: %v", modelID, err)
+			slog.WarnContext(ctx, "Could not get model profile, skipping", "candidate", modelID, "error", err)
 			continue
 		}
+		r.config.StaleStats.Apply(profile, now)
 
 		monthlyBudget := modelBudgets[modelID]
 		if ok, reason := r.passesPreChecks(profile, monthlyBudget); !ok {
-			log.Printf("- Filtering Model: %s | Reason: %s", modelID, reason)
+			slog.DebugContext(ctx, "Filtered model", "candidate", modelID, "reason", reason)
 			continue
 		}
 
 		modelMeta, ok := r.config.Models[profile.ModelID]
 		if !ok {
-			log.Printf("- Filtering Model: %s | Reason: Model metadata not found in config.", modelID)
+			slog.DebugContext(ctx, "Filtered model", "candidate", modelID, "reason", "Model metadata not found in config.")
 			continue
 		}
 
 		// Estimate cost for this specific call for scoring purposes.
-		estimatedOutputTokens := promptTokens * 2 // A simple heuristic.
-		estimatedCost := (float64(promptTokens) * profile.CostPerInputToken) + (float64(estimatedOutputTokens) * profile.CostPerOutputToken)
+		estimatedCost := estimateCallCost(profile, promptTokens)
+		if maxCostUSD > 0 && estimatedCost > maxCostUSD {
+			slog.DebugContext(ctx, "Filtered model", "candidate", modelID, "reason", "Estimated cost exceeds the request's cap.", "estimated_cost", estimatedCost, "max_cost_usd", maxCostUSD)
+			if overCap == nil || estimatedCost < overCap.CheapestCostUSD {
+				overCap = &CostCapError{MaxCostUSD: maxCostUSD, CheapestModel: modelID, CheapestCostUSD: estimatedCost}
+			}
+			continue
+		}
 
 		contenders[modelID] = contender{
 			Profile:       profile,
 			Metadata:      modelMeta,
 			EstimatedCost: estimatedCost,
+			BudgetPenalty: r.config.Budget.State(modelID, profile.CostSpentMonthly, monthlyBudget).Penalty,
 		}
-		log.Printf("- Model %s is a contender.", modelID)
+		slog.DebugContext(ctx, "Model is a contender", "candidate", modelID)
 	}
 
 	if len(contenders) == 0 {
+		if overCap != nil {
+			return "", overCap
+		}
 		return "", errors.New("no suitable, healthy, and in-budget model found after filtering")
 	}
 
 	// If there's only one contender, select it immediately.
 	if len(contenders) == 1 {
 		for modelID := range contenders {
-			log.Printf("🏆 Only one contender found. Selecting: %s", modelID)
+			slog.InfoContext(ctx, "Only one contender found", "selected_model", modelID)
 			return modelID, nil
 		}
 	}
 
+	// A share of the requests refreshes the statistics of a model they cannot be trusted for.
+	if modelID := r.config.StaleStats.explore(ctx, contenders); modelID != "" {
+		return modelID, nil
+	}
+
 	// --- Pass 2: Normalize and score the contenders ---
-	strategy, err := r.getStrategy(preference, contenders)
+	strategy, err := r.getStrategy(ctx, preference, contenders)
 	if err != nil {
 		return "", err
 	}
@@ -121,13 +266,27 @@ func (r *Router) SelectOptimalModel(ctx context.Context, availableModels []strin
 	bestModel := ""
 	bestScore := -1.0
 
+	useTTFT := strategy.UseTTFT
+	for _, c := range contenders {
+		useTTFT = useTTFT && c.Profile.AvgTTFTMS > 0
+	}
+	for modelID, c := range contenders {
+		c.LatencyMS = float64(c.Profile.AvgLatencyMS)
+		if useTTFT {
+			c.LatencyMS = float64(c.Profile.AvgTTFTMS)
+		}
+		contenders[modelID] = c
+	}
+
 	// Calculate min/max values across contenders for normalization.
 	minCost, maxCost, minLatency, maxLatency := getNormalizationBounds(contenders)
+	explores := strategy.Exploration.explores()
+	sampled := explores && strategy.Exploration.Mode == ExploreThompson
 
 	for modelID, c := range contenders {
-		score := r.calculateNormalizedScore(c, strategy, minCost, maxCost, minLatency, maxLatency)
-		log.Printf("- Scoring Model: %s | Latency: %dms | Est. Cost: %.6f | Quality: %.2f | Final Score: %.4f",
-			modelID, c.Profile.AvgLatencyMS, c.EstimatedCost, c.Metadata.QualityScore, score)
+		score := r.calculateNormalizedScore(c, strategy, minCost, maxCost, minLatency, maxLatency, sampled)
+		slog.DebugContext(ctx, "Scored model", "candidate", modelID, "latency_ms", c.LatencyMS, "ttft", useTTFT,
+			"estimated_cost", c.EstimatedCost, "quality", c.Metadata.QualityScore, "budget_penalty", c.BudgetPenalty, "score", score)
 
 		if score > bestScore {
 			bestScore = score
@@ -140,13 +299,32 @@ func (r *Router) SelectOptimalModel(ctx context.Context, availableModels []strin
 		return "", errors.New("failed to select a model after scoring")
 	}
 
-	log.Printf("🏆 Best model selected: %s (Score: %.4f)", bestModel, bestScore)
+	if explores && strategy.Exploration.Mode == ExploreEpsilonGreedy {
+		return exploreOtherThan(ctx, contenders, bestModel), nil
+	}
+	slog.InfoContext(ctx, "Best model selected", "selected_model", bestModel, "score", bestScore, "thompson_sampled", sampled)
 	return bestModel, nil
 }
 
+// EstimateCost estimates what a call to a model with a prompt of promptTokens costs, the same
+// way the router does when it selects a model.
+func (r *Router) EstimateCost(ctx context.Context, modelID string, promptTokens int) (float64, error) {
+	profile, err := r.profiler.GetProfile(ctx, modelID)
+	if err != nil {
+		return 0, err
+	}
+	return estimateCallCost(profile, promptTokens), nil
+}
+
+// estimateCallCost estimates the cost of a call, assuming the answer is twice as long as the prompt.
+func estimateCallCost(profile *ModelProfile, promptTokens int) float64 {
+	estimatedOutputTokens := promptTokens * 2 // A simple heuristic.
+	return (float64(promptTokens) * profile.CostPerInputToken) + (float64(estimatedOutputTokens) * profile.CostPerOutputToken)
+}
+
 // getStrategy retrieves the appropriate routing strategy based on the preference.
 // It also handles the dynamic logic for "smart-balanced".
-func (r *Router) getStrategy(preference string, contenders map[string]contender) (RoutingStrategy, error) {
+func (r *Router) getStrategy(ctx context.Context, preference string, contenders map[string]contender) (RoutingStrategy, error) {
 	// The "smart-balanced" strategy has dynamic weights based on the prompt size.
 	if preference == "smart-balanced" {
 		// Example dynamic logic: for cheap requests, prioritize speed; for expensive ones, quality.
@@ -158,10 +336,10 @@ func (r *Router) getStrategy(preference string, contenders map[string]contender)
 		avgCost /= float64(len(contenders))
 
 		if avgCost < 0.001 { // For cheap requests, prioritize speed.
-			log.Println("Smart-balanced mode: Prioritizing latency for low-cost request.")
+			slog.DebugContext(ctx, "Smart-balanced mode: prioritizing latency for low-cost request")
 			return r.config.Strategies["latency-focused-balanced"], nil
 		} else { // For expensive requests, prioritize quality.
-			log.Println("Smart-balanced mode: Prioritizing quality for high-cost request.")
+			slog.DebugContext(ctx, "Smart-balanced mode: prioritizing quality for high-cost request")
 			return r.config.Strategies["quality-focused-balanced"], nil
 		}
 	}
@@ -169,7 +347,7 @@ func (r *Router) getStrategy(preference string, contenders map[string]contender)
 	strategy, ok := r.config.Strategies[preference]
 	if !ok {
 		// Fallback to the default strategy if the preference is unknown.
-		log.Printf("Warning: preference '%s' not found, falling back to 'default' strategy.", preference)
+		slog.WarnContext(ctx, "Preference not found, falling back to the default strategy", "preference", preference)
 		strategy, ok = r.config.Strategies["default"]
 		if !ok {
 			return RoutingStrategy{}, errors.New("default strategy not found in configuration")
@@ -179,14 +357,15 @@ func (r *Router) getStrategy(preference string, contenders map[string]contender)
 }
 
 // calculateNormalizedScore computes a model's score using linear normalization.
-// This ensures that weights have a predictable, proportional impact.
-func (r *Router) calculateNormalizedScore(c contender, strategy RoutingStrategy, minCost, maxCost, minLatency, maxLatency float64) float64 {
+// This ensures that weights have a predictable, proportional impact. With sampleReliability,
+// the reliability is drawn from the model's success and failure counts (Thompson sampling).
+func (r *Router) calculateNormalizedScore(c contender, strategy RoutingStrategy, minCost, maxCost, minLatency, maxLatency float64, sampleReliability bool) float64 {
 	// --- Normalize Component Factors (so that 1.0 is best, 0.0 is worst) ---
 
 	// Latency: Lower is better.
 	latencyFactor := 0.5 // Default to average if min/max are the same
 	if maxLatency > minLatency {
-		latencyFactor = (maxLatency - float64(c.Profile.AvgLatencyMS)) / (maxLatency - minLatency)
+		latencyFactor = (maxLatency - c.LatencyMS) / (maxLatency - minLatency)
 	}
 
 	// Cost: Lower is better.
@@ -203,12 +382,16 @@ func (r *Router) calculateNormalizedScore(c contender, strategy RoutingStrategy,
 
 	// Reliability: Higher is better.
 	reliabilityFactor := 1.0 - c.Profile.ErrorRate
+	if sampleReliability {
+		reliabilityFactor = sampledReliability(c.Profile)
+	}
 
 	// --- Final Weighted Score Calculation ---
-	// The reliability factor acts as a multiplier on the weighted average of other factors.
+	// The reliability factor acts as a multiplier on the weighted average of other factors,
+	// and a model nearing its budget loses its penalty's share of the result.
 	score := ((strategy.QualityWeight * qualityFactor) +
 		(strategy.CostWeight * costFactor) +
-		(strategy.LatencyWeight * latencyFactor)) * reliabilityFactor
+		(strategy.LatencyWeight * latencyFactor)) * reliabilityFactor * (1 - c.BudgetPenalty)
 
 	return score
 }
@@ -227,7 +410,7 @@ func getNormalizationBounds(contenders map[string]contender) (minCost, maxCost,
 		if c.EstimatedCost > maxCost {
 			maxCost = c.EstimatedCost
 		}
-		latency := float64(c.Profile.AvgLatencyMS)
+		latency := c.LatencyMS
 		if latency < minLatency {
 			minLatency = latency
 		}
@@ -0,0 +1,64 @@
+// In file: internal/llm/routing_log.go
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// routingLogKey is the Redis list of the most recent routing decisions, newest first.
+const routingLogKey = "routing_decisions"
+
+// routingLogSize is how many routing decisions are kept.
+const routingLogSize = 100
+
+// RoutingDecision is the routing outcome of one answered request: the model it was
+// answered by and why. The recent decisions of every replica are kept for the dashboard.
+type RoutingDecision struct {
+	Time             time.Time `json:"time"`
+	Model            string    `json:"model"`
+	Intent           string    `json:"intent"`
+	Preference       string    `json:"preference,omitempty"`
+	PreferenceSource string    `json:"preference_source,omitempty"`
+	// FailoverFrom is the model the request was first routed to, if it failed over.
+	FailoverFrom string  `json:"failover_from,omitempty"`
+	Stream       bool    `json:"stream,omitempty"`
+	LatencyMS    int64   `json:"latency_ms"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// RecordRoutingDecision adds a decision to the recent ones, dropping the oldest past
+// routingLogSize. Failures are only logged, as the log is informational.
+func (p *Profiler) RecordRoutingDecision(ctx context.Context, decision RoutingDecision) {
+	data, err := json.Marshal(decision)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to encode routing decision", "error", err)
+		return
+	}
+	pipe := p.rdb.TxPipeline()
+	pipe.LPush(ctx, routingLogKey, data)
+	pipe.LTrim(ctx, routingLogKey, 0, routingLogSize-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to record routing decision", "error", err)
+	}
+}
+
+// RecentRoutingDecisions returns up to limit of the most recent routing decisions, newest first.
+func (p *Profiler) RecentRoutingDecisions(ctx context.Context, limit int) ([]RoutingDecision, error) {
+	members, err := p.rdb.LRange(ctx, routingLogKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing decisions: %w", err)
+	}
+	decisions := make([]RoutingDecision, 0, len(members))
+	for _, member := range members {
+		var decision RoutingDecision
+		if err := json.Unmarshal([]byte(member), &decision); err != nil {
+			continue
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, nil
+}
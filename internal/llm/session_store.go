@@ -0,0 +1,305 @@
+// In file: internal/llm/session_store.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Session records which model a conversation is pinned to.
+type Session struct {
+	ModelID string
+	// IsForced is true when the user explicitly locked the conversation to ModelID.
+	IsForced bool
+	// Turns counts the messages served by ModelID since it was pinned, excluding the first.
+	Turns int
+	// SystemPrompt is prepended to every turn of the conversation. Re-pinning keeps it.
+	SystemPrompt string
+}
+
+// Session expiry modes.
+const (
+	// SessionExpirySliding extends a session's lifetime on every message.
+	SessionExpirySliding = "sliding"
+	// SessionExpiryFixed expires a session a fixed time after it was created.
+	SessionExpiryFixed = "fixed"
+)
+
+// SessionPolicy controls how long sessions live and how sticky dynamic sessions are.
+// It is configured in the `sessions` section of config.yaml and can be overridden per tenant.
+type SessionPolicy struct {
+	// TTL is how long a session lives (since creation or since its last message; see Expiry).
+	TTL time.Duration `yaml:"ttl"`
+	// Expiry is SessionExpirySliding or SessionExpiryFixed.
+	Expiry string `yaml:"expiry"`
+	// RerouteEveryTurns keeps a dynamic session on its pinned model for this many turns before
+	// the router is consulted again. 1 re-routes every message.
+	RerouteEveryTurns int `yaml:"reroute_every_turns"`
+	// PinDynamicSessions controls whether routed (non-forced) conversations are pinned at all.
+	PinDynamicSessions *bool `yaml:"pin_dynamic_sessions"`
+}
+
+// DefaultSessionPolicy returns the gateway's historical behaviour: a one-hour sliding TTL,
+// with dynamic sessions pinned but re-routed on every message.
+func DefaultSessionPolicy() SessionPolicy {
+	pin := true
+	return SessionPolicy{
+		TTL:                time.Hour,
+		Expiry:             SessionExpirySliding,
+		RerouteEveryTurns:  1,
+		PinDynamicSessions: &pin,
+	}
+}
+
+// Merge returns a copy of the policy with every field that is set in override replaced.
+func (p SessionPolicy) Merge(override *SessionPolicy) SessionPolicy {
+	if override == nil {
+		return p
+	}
+	if override.TTL > 0 {
+		p.TTL = override.TTL
+	}
+	if override.Expiry != "" {
+		p.Expiry = override.Expiry
+	}
+	if override.RerouteEveryTurns > 0 {
+		p.RerouteEveryTurns = override.RerouteEveryTurns
+	}
+	if override.PinDynamicSessions != nil {
+		p.PinDynamicSessions = override.PinDynamicSessions
+	}
+	return p
+}
+
+// Validate checks the policy for configuration mistakes.
+func (p SessionPolicy) Validate() error {
+	if p.Expiry != SessionExpirySliding && p.Expiry != SessionExpiryFixed {
+		return fmt.Errorf("invalid session expiry '%s' (expected '%s' or '%s')", p.Expiry, SessionExpirySliding, SessionExpiryFixed)
+	}
+	if p.TTL <= 0 {
+		return fmt.Errorf("session ttl must be positive, got %s", p.TTL)
+	}
+	return nil
+}
+
+// IsSliding reports whether sessions are extended on every message.
+func (p SessionPolicy) IsSliding() bool {
+	return p.Expiry == SessionExpirySliding
+}
+
+// PinsDynamicSessions reports whether routed conversations are pinned.
+func (p SessionPolicy) PinsDynamicSessions() bool {
+	return p.PinDynamicSessions == nil || *p.PinDynamicSessions
+}
+
+// SessionStore persists conversation-to-model pinning. Implementations must be safe for
+// concurrent use. Expiry is driven by the caller's SessionPolicy, so each call that can
+// change a session's lifetime takes the TTL to apply.
+type SessionStore interface {
+	// Get returns the session of a conversation, or nil if there is none.
+	Get(ctx context.Context, conversationID string) (*Session, error)
+	// Pin stores the session of a conversation and resets its turn count. A positive ttl
+	// resets the expiry; a zero ttl keeps the existing expiry (for fixed-expiry sessions).
+	Pin(ctx context.Context, conversationID string, session Session, ttl time.Duration) error
+	// SetSystemPrompt stores the conversation's persistent system prompt. The ttl has the same
+	// meaning as for Pin.
+	SetSystemPrompt(ctx context.Context, conversationID, systemPrompt string, ttl time.Duration) error
+	// RecordTurn increments the number of turns served by the pinned model.
+	RecordTurn(ctx context.Context, conversationID string) error
+	// Refresh resets the expiry of an existing session.
+	Refresh(ctx context.Context, conversationID string, ttl time.Duration) error
+}
+
+// =================================================================================
+// Redis Implementation
+// =================================================================================
+
+// RedisSessionStore keeps each session in a Redis hash, shared by all gateway replicas.
+type RedisSessionStore struct {
+	rdb *redis.Client
+}
+
+// Statically verify that RedisSessionStore implements the SessionStore interface.
+var _ SessionStore = (*RedisSessionStore)(nil)
+
+// NewRedisSessionStore creates a session store backed by Redis.
+func NewRedisSessionStore(rdb *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{rdb: rdb}
+}
+
+func (s *RedisSessionStore) getSessionKey(conversationID string) string {
+	return fmt.Sprintf("session:%s", conversationID)
+}
+
+// Get reads a session from Redis.
+func (s *RedisSessionStore) Get(ctx context.Context, conversationID string) (*Session, error) {
+	data, err := s.rdb.HGetAll(ctx, s.getSessionKey(conversationID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", conversationID, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	turns, _ := strconv.Atoi(data["turns"])
+	return &Session{ModelID: data["model_id"], IsForced: data["is_forced"] == "true", Turns: turns, SystemPrompt: data["system_prompt"]}, nil
+}
+
+// Pin writes a session to Redis and, if ttl is positive, resets its TTL.
+func (s *RedisSessionStore) Pin(ctx context.Context, conversationID string, session Session, ttl time.Duration) error {
+	key := s.getSessionKey(conversationID)
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, key, "model_id", session.ModelID, "is_forced", fmt.Sprintf("%v", session.IsForced), "turns", 0)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to pin session %s: %w", conversationID, err)
+	}
+	return nil
+}
+
+// SetSystemPrompt writes the system prompt into the session hash and, if ttl is positive, resets its TTL.
+func (s *RedisSessionStore) SetSystemPrompt(ctx context.Context, conversationID, systemPrompt string, ttl time.Duration) error {
+	key := s.getSessionKey(conversationID)
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, key, "system_prompt", systemPrompt)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store system prompt for session %s: %w", conversationID, err)
+	}
+	return nil
+}
+
+// RecordTurn increments the session's turn counter.
+func (s *RedisSessionStore) RecordTurn(ctx context.Context, conversationID string) error {
+	return s.rdb.HIncrBy(ctx, s.getSessionKey(conversationID), "turns", 1).Err()
+}
+
+// Refresh resets a session's TTL.
+func (s *RedisSessionStore) Refresh(ctx context.Context, conversationID string, ttl time.Duration) error {
+	return s.rdb.Expire(ctx, s.getSessionKey(conversationID), ttl).Err()
+}
+
+// =================================================================================
+// In-Memory Implementation
+// =================================================================================
+
+// InMemorySessionStore keeps sessions in process memory. It is intended for single-replica
+// deployments, local development, and tests; sessions are lost on restart and are not
+// shared between replicas.
+type InMemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]inMemorySession
+	// nextSweep is when writes next remove every expired session, so sessions that are never
+	// read again do not accumulate.
+	nextSweep time.Time
+}
+
+// inMemorySweepInterval is how often the in-memory store removes expired sessions.
+const inMemorySweepInterval = time.Minute
+
+type inMemorySession struct {
+	session   Session
+	expiresAt time.Time
+}
+
+// Statically verify that InMemorySessionStore implements the SessionStore interface.
+var _ SessionStore = (*InMemorySessionStore)(nil)
+
+// NewInMemorySessionStore creates an empty in-memory session store.
+func NewInMemorySessionStore() *InMemorySessionStore {
+	return &InMemorySessionStore{
+		sessions: make(map[string]inMemorySession),
+	}
+}
+
+// Get returns a session if it exists and has not expired. Expired sessions are removed when
+// they are read, and periodically by writes.
+func (s *InMemorySessionStore) Get(_ context.Context, conversationID string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.sessions[conversationID]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.sessions, conversationID)
+		return nil, nil
+	}
+	session := entry.session
+	return &session, nil
+}
+
+// Pin stores a session and, if ttl is positive, resets its expiry.
+func (s *InMemorySessionStore) Pin(_ context.Context, conversationID string, session Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked()
+	session.Turns = 0
+	entry, exists := s.sessions[conversationID]
+	if ttl > 0 || !exists {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	session.SystemPrompt = entry.session.SystemPrompt
+	entry.session = session
+	s.sessions[conversationID] = entry
+	return nil
+}
+
+// SetSystemPrompt stores the system prompt and, if ttl is positive, resets the session's expiry.
+func (s *InMemorySessionStore) SetSystemPrompt(_ context.Context, conversationID, systemPrompt string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked()
+	entry, exists := s.sessions[conversationID]
+	if ttl > 0 || !exists {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	entry.session.SystemPrompt = systemPrompt
+	s.sessions[conversationID] = entry
+	return nil
+}
+
+// RecordTurn increments the session's turn counter.
+func (s *InMemorySessionStore) RecordTurn(_ context.Context, conversationID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.sessions[conversationID]; ok {
+		entry.session.Turns++
+		s.sessions[conversationID] = entry
+	}
+	return nil
+}
+
+// Refresh extends the expiry of an existing session.
+func (s *InMemorySessionStore) Refresh(_ context.Context, conversationID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.sessions[conversationID]; ok {
+		entry.expiresAt = time.Now().Add(ttl)
+		s.sessions[conversationID] = entry
+	}
+	return nil
+}
+
+// sweepLocked removes every expired session, at most once per inMemorySweepInterval.
+// The caller must hold s.mu.
+func (s *InMemorySessionStore) sweepLocked() {
+	now := time.Now()
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(inMemorySweepInterval)
+	for conversationID, entry := range s.sessions {
+		if now.After(entry.expiresAt) {
+			delete(s.sessions, conversationID)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// In file: internal/llm/stale_stats.go
+package llm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// defaultLatencyMS is the latency a model is assumed to have before it has been measured.
+const defaultLatencyMS = 2000
+
+// StaleStatsPolicy regresses the statistics of idle models toward the priors a new profile
+// starts with, and marks profiles whose statistics cannot be trusted as low confidence. It
+// is configured in the `stale_stats` section of config.yaml.
+type StaleStatsPolicy struct {
+	// HalfLife is how long a model must go unused for its latency and error rate to move
+	// halfway back to the priors (2000ms and no errors). Zero turns decay off.
+	HalfLife time.Duration `yaml:"half_life"`
+	// LowConfidenceAfter marks profiles of models unused for longer as low confidence.
+	LowConfidenceAfter time.Duration `yaml:"low_confidence_after"`
+	// MinRequests marks profiles measured over fewer requests as low confidence.
+	MinRequests int64 `yaml:"min_requests"`
+	// ExploreRate is the share of routed requests, between 0 and 1, sent to a low-confidence
+	// contender rather than the best one, so its statistics are measured again.
+	ExploreRate float64 `yaml:"explore_rate"`
+}
+
+// Validate reports settings that cannot work.
+func (p StaleStatsPolicy) Validate() error {
+	if p.HalfLife < 0 || p.LowConfidenceAfter < 0 || p.MinRequests < 0 {
+		return errors.New("half_life, low_confidence_after, and min_requests must not be negative")
+	}
+	if p.ExploreRate < 0 || p.ExploreRate > 1 {
+		return errors.New("explore_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// Apply decays the statistics of a profile by how long its model has been idle, and sets
+// its LowConfidence. Profiles that do not record when their model was last used, such as
+// those written before it was recorded, are not decayed.
+func (p StaleStatsPolicy) Apply(profile *ModelProfile, now time.Time) {
+	requests := profile.TotalSuccesses + profile.TotalFailures
+	profile.LowConfidence = p.MinRequests > 0 && requests < p.MinRequests
+	if profile.LastUsed.IsZero() {
+		return
+	}
+	idle := now.Sub(profile.LastUsed)
+	if p.LowConfidenceAfter > 0 && idle > p.LowConfidenceAfter {
+		profile.LowConfidence = true
+	}
+	if p.HalfLife <= 0 || idle <= 0 {
+		return
+	}
+	weight := math.Pow(0.5, float64(idle)/float64(p.HalfLife))
+	profile.AvgLatencyMS = int64(weight*float64(profile.AvgLatencyMS) + (1-weight)*defaultLatencyMS)
+	profile.ErrorRate *= weight
+}
+
+// explore picks a low-confidence contender for ExploreRate of the requests. It returns ""
+// when the request is not explored or there is no such contender.
+func (p StaleStatsPolicy) explore(ctx context.Context, contenders map[string]contender) string {
+	if p.ExploreRate <= 0 || rand.Float64() >= p.ExploreRate {
+		return ""
+	}
+	var lowConfidence []string
+	for modelID, c := range contenders {
+		if c.Profile.LowConfidence {
+			lowConfidence = append(lowConfidence, modelID)
+		}
+	}
+	if len(lowConfidence) == 0 {
+		return ""
+	}
+	modelID := lowConfidence[rand.IntN(len(lowConfidence))]
+	slog.InfoContext(ctx, "Exploring a low-confidence model", "selected_model", modelID)
+	return modelID
+}
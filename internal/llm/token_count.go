@@ -0,0 +1,90 @@
+// In file: internal/llm/token_count.go
+package llm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrCountUnsupported is returned by CountTokens for clients whose provider cannot count
+// tokens.
+var ErrCountUnsupported = errors.New("the client cannot count tokens")
+
+// TokenCounter is implemented by clients whose provider counts the input tokens of a request
+// exactly, without generating: Anthropic's count_tokens endpoint and Gemini's countTokens.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, modelID string, messages []Message) (int, error)
+}
+
+// CountTokens counts the input tokens of messages through client, or returns
+// ErrCountUnsupported if its provider cannot count them.
+func CountTokens(ctx context.Context, client LLMClient, modelID string, messages []Message) (int, error) {
+	if counter, ok := client.(TokenCounter); ok {
+		return counter.CountTokens(ctx, modelID, messages)
+	}
+	return 0, ErrCountUnsupported
+}
+
+// pretokenizer splits text the way BPE tokenizers do before merging: contractions, words
+// with their leading space, runs of up to three digits, punctuation runs, and whitespace.
+var pretokenizer = regexp.MustCompile(`'(?i:[sdmt]|ll|ve|re)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// wordCharsPerToken is how many characters of a word each provider's tokenizer typically
+// packs into one token. Anthropic's and Mistral's vocabularies are smaller than OpenAI's
+// and Gemini's, so their words split sooner.
+var wordCharsPerToken = map[string]float64{
+	"openai":    4.6,
+	"google":    4.6,
+	"anthropic": 4.0,
+	"mistral":   3.8,
+}
+
+// defaultWordCharsPerToken is used for providers without a known tokenizer.
+const defaultWordCharsPerToken = 4.0
+
+// EstimateMessageTokensFor estimates the input tokens of messages for a provider's tokenizer
+// family, for providers that cannot count them. It splits the text like a BPE tokenizer and
+// estimates how many tokens each piece becomes, which is much closer than EstimateTokens
+// for code, numbers, and non-Latin scripts.
+func EstimateMessageTokensFor(provider string, messages []Message) int {
+	charsPerToken, ok := wordCharsPerToken[provider]
+	if !ok {
+		charsPerToken = defaultWordCharsPerToken
+	}
+	total := 0
+	for _, msg := range messages {
+		total += messageOverheadTokens
+		for _, piece := range pretokenizer.FindAllString(msg.Content, -1) {
+			total += pieceTokens(piece, charsPerToken)
+		}
+	}
+	return total
+}
+
+// pieceTokens estimates the tokens of one pre-tokenized piece.
+func pieceTokens(piece string, charsPerToken float64) int {
+	word := strings.TrimLeftFunc(piece, unicode.IsSpace)
+	if word == "" {
+		// A whitespace run is a single token.
+		return 1
+	}
+	last, _ := utf8.DecodeLastRuneInString(word)
+	switch {
+	case unicode.IsDigit(last):
+		return 1
+	case unicode.In(last, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		// Ideographic scripts take about one token per character.
+		return utf8.RuneCountInString(word)
+	case !unicode.IsLetter(last):
+		// Punctuation and symbols merge in pairs at best.
+		return (utf8.RuneCountInString(word) + 1) / 2
+	}
+	// A word's first characters, with a leading space or symbol, make one token; longer
+	// words split into further tokens of typical length.
+	letters := utf8.RuneCountInString(strings.TrimLeftFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+	return 1 + int(float64(letters-1)/charsPerToken)
+}
@@ -0,0 +1,19 @@
+// In file: internal/llm/tool_models.go
+package llm
+
+// SupportsTools reports whether a model is configured as able to call tools.
+func (r *Router) SupportsTools(modelID string) bool {
+	return r.config.Models[modelID].SupportsTools
+}
+
+// ToolModels returns the models, in the given order, that can call tools, so a request
+// answered by the tool loop is only routed among them.
+func (r *Router) ToolModels(models []string) []string {
+	var toolModels []string
+	for _, modelID := range models {
+		if r.SupportsTools(modelID) {
+			toolModels = append(toolModels, modelID)
+		}
+	}
+	return toolModels
+}
@@ -0,0 +1,118 @@
+// In file: internal/llm/traced_client.go
+package llm
+
+import (
+	"context"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+	"github.com/dileep-u-k/llm-gateway/internal/tools"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedClient wraps an LLMClient so every provider call is recorded as a trace span,
+// including the model, the number of messages and tools sent, and the token usage.
+type TracedClient struct {
+	next    LLMClient
+	modelID string
+}
+
+// Statically verify that TracedClient implements the LLMClient interface.
+var _ LLMClient = (*TracedClient)(nil)
+
+// NewTracedClient wraps a client for the given model with tracing.
+func NewTracedClient(next LLMClient, modelID string) *TracedClient {
+	return &TracedClient{next: next, modelID: modelID}
+}
+
+func (c *TracedClient) startSpan(ctx context.Context, name string, messages []Message, availableTools []tools.Tool) (context.Context, trace.Span) {
+	return telemetry.StartSpan(ctx, name,
+		attribute.String("llm.model", c.modelID),
+		attribute.Int("llm.messages", len(messages)),
+		attribute.Int("llm.tools", len(availableTools)),
+	)
+}
+
+// Generate calls the wrapped client inside an "llm.generate" span.
+func (c *TracedClient) Generate(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (*GenerationResult, error) {
+	ctx, span := c.startSpan(ctx, "llm.generate", messages, availableTools)
+	result, err := c.next.Generate(ctx, messages, config, availableTools)
+	if result != nil {
+		span.SetAttributes(
+			attribute.Int("llm.usage.prompt_tokens", result.Usage.PromptTokens),
+			attribute.Int("llm.usage.completion_tokens", result.Usage.CompletionTokens),
+			attribute.Int("llm.tool_calls", len(result.ToolCalls)),
+		)
+	}
+	telemetry.EndSpan(span, err)
+	return result, err
+}
+
+// GenerateStream calls the wrapped client inside an "llm.generate_stream" span, which stays
+// open until the stream is closed so it covers the full time spent generating.
+func (c *TracedClient) GenerateStream(ctx context.Context, messages []Message, config *GenerationConfig, availableTools []tools.Tool) (<-chan *StreamingResult, error) {
+	ctx, span := c.startSpan(ctx, "llm.generate_stream", messages, availableTools)
+	upstream, err := c.next.GenerateStream(ctx, messages, config, availableTools)
+	if err != nil {
+		telemetry.EndSpan(span, err)
+		return nil, err
+	}
+
+	out := newStreamChannel(config)
+	go func() {
+		defer close(out)
+		var streamErr error
+		consumerGone := false
+		for chunk := range upstream {
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+			}
+			if chunk.Usage != nil {
+				span.SetAttributes(
+					attribute.Int("llm.usage.prompt_tokens", chunk.Usage.PromptTokens),
+					attribute.Int("llm.usage.completion_tokens", chunk.Usage.CompletionTokens),
+				)
+			}
+			if consumerGone {
+				continue // Drain the upstream so it can finish.
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				consumerGone = true
+				streamErr = ctx.Err()
+			}
+		}
+		telemetry.EndSpan(span, streamErr)
+	}()
+	return out, nil
+}
+
+// Ping pings the wrapped client inside an "llm.ping" span.
+func (c *TracedClient) Ping(ctx context.Context, modelID string) error {
+	ctx, span := telemetry.StartSpan(ctx, "llm.ping", attribute.String("llm.model", c.modelID))
+	err := Ping(ctx, c.next, modelID)
+	telemetry.EndSpan(span, err)
+	return err
+}
+
+// Forward forwards through the wrapped client inside an "llm.forward" span, which ends when
+// the provider's response headers arrive.
+func (c *TracedClient) Forward(ctx context.Context, body []byte) (*ForwardedResponse, error) {
+	ctx, span := telemetry.StartSpan(ctx, "llm.forward", attribute.String("llm.model", c.modelID))
+	resp, err := Forward(ctx, c.next, body)
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	}
+	telemetry.EndSpan(span, err)
+	return resp, err
+}
+
+// CountTokens counts through the wrapped client inside an "llm.count_tokens" span.
+func (c *TracedClient) CountTokens(ctx context.Context, modelID string, messages []Message) (int, error) {
+	ctx, span := telemetry.StartSpan(ctx, "llm.count_tokens", attribute.String("llm.model", c.modelID))
+	count, err := CountTokens(ctx, c.next, modelID, messages)
+	telemetry.EndSpan(span, err)
+	return count, err
+}
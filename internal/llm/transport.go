@@ -0,0 +1,118 @@
+// In file: internal/llm/transport.go
+package llm
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+// TransportConfig tunes the HTTP connection pool shared by the provider clients. Go's default
+// transport keeps only two idle connections per host, so under load most provider calls pay
+// for a new TCP and TLS handshake. Fields left unset take the defaults.
+type TransportConfig struct {
+	// MaxIdleConns bounds the idle connections kept across all providers.
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// MaxIdleConnsPerHost bounds the idle connections kept to each provider.
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host"`
+	// MaxConnsPerHost bounds all connections to each provider; 0 is unlimited.
+	MaxConnsPerHost int `yaml:"max_conns_per_host"`
+	// IdleConnTimeout is how long an idle connection is kept open.
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout"`
+	// DialTimeout bounds establishing a TCP connection.
+	DialTimeout time.Duration `yaml:"dial_timeout"`
+	// KeepAlive is the interval of TCP keep-alive probes on open connections.
+	KeepAlive time.Duration `yaml:"keep_alive"`
+	// TLSHandshakeTimeout bounds the TLS handshake of a new connection.
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout"`
+	// DisableHTTP2 keeps connections on HTTP/1.1. HTTP/2 multiplexes concurrent calls to a
+	// provider over one connection and is used whenever the provider supports it.
+	DisableHTTP2 bool `yaml:"disable_http2"`
+}
+
+// DefaultTransportConfig returns the connection pool settings used when config.yaml sets none.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 50,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         10 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// Merge returns the settings with the fields set in override replacing its own.
+func (c TransportConfig) Merge(override TransportConfig) TransportConfig {
+	if override.MaxIdleConns > 0 {
+		c.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.MaxIdleConnsPerHost > 0 {
+		c.MaxIdleConnsPerHost = override.MaxIdleConnsPerHost
+	}
+	if override.MaxConnsPerHost > 0 {
+		c.MaxConnsPerHost = override.MaxConnsPerHost
+	}
+	if override.IdleConnTimeout > 0 {
+		c.IdleConnTimeout = override.IdleConnTimeout
+	}
+	if override.DialTimeout > 0 {
+		c.DialTimeout = override.DialTimeout
+	}
+	if override.KeepAlive > 0 {
+		c.KeepAlive = override.KeepAlive
+	}
+	if override.TLSHandshakeTimeout > 0 {
+		c.TLSHandshakeTimeout = override.TLSHandshakeTimeout
+	}
+	c.DisableHTTP2 = c.DisableHTTP2 || override.DisableHTTP2
+	return c
+}
+
+// Validate reports settings that cannot work.
+func (c TransportConfig) Validate() error {
+	if c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 || c.MaxConnsPerHost < 0 {
+		return fmt.Errorf("connection limits must not be negative")
+	}
+	if c.IdleConnTimeout < 0 || c.DialTimeout < 0 || c.KeepAlive < 0 || c.TLSHandshakeTimeout < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+	return nil
+}
+
+// NewTransport creates a pooled transport with the given settings, merged over the defaults.
+// Share one transport between clients so they share its idle connections. Outbound requests
+// are traced like those of telemetry.Transport.
+func NewTransport(cfg TransportConfig) http.RoundTripper {
+	cfg = DefaultTransportConfig().Merge(cfg)
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: cfg.KeepAlive}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	if cfg.DisableHTTP2 {
+		// A non-nil, empty map is how net/http is told not to negotiate HTTP/2.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return telemetry.WrapTransport(transport)
+}
+
+// clientTransport returns the transport a provider client should use: the shared one it was
+// given, or the traced default transport.
+func clientTransport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		return telemetry.Transport()
+	}
+	return transport
+}
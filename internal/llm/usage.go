@@ -0,0 +1,88 @@
+// In file: internal/llm/usage.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// getUsageKey returns the hash holding an account's usage counters for a month.
+func (p *Profiler) getUsageKey(account, month string) string {
+	return fmt.Sprintf("usage:%s:%s", month, account)
+}
+
+// getUsageAccountsKey returns the set of accounts that made requests during a month.
+func (p *Profiler) getUsageAccountsKey(month string) string {
+	return fmt.Sprintf("usage:%s:accounts", month)
+}
+
+// RecordAccountUsage adds a request's tokens and cost to the account's counters for the
+// current month and returns the updated totals.
+func (p *Profiler) RecordAccountUsage(ctx context.Context, account string, usage api.Usage, cost float64) (*api.AccountUsage, error) {
+	month := time.Now().UTC().Format("2006-01")
+	key := p.getUsageKey(account, month)
+	accountsKey := p.getUsageAccountsKey(month)
+
+	pipe := p.rdb.TxPipeline()
+	pipe.HIncrBy(ctx, key, "requests", 1)
+	pipe.HIncrBy(ctx, key, "prompt_tokens", int64(usage.PromptTokens))
+	pipe.HIncrBy(ctx, key, "completion_tokens", int64(usage.CompletionTokens))
+	pipe.HIncrByFloat(ctx, key, "cost_usd", cost)
+	totals := pipe.HGetAll(ctx, key)
+	pipe.SAdd(ctx, accountsKey, account)
+	pipe.Expire(ctx, key, costRetention)
+	pipe.Expire(ctx, accountsKey, costRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to record usage for %s: %w", account, err)
+	}
+	return parseAccountUsage(account, month, totals.Val()), nil
+}
+
+// GetAccountUsage returns an account's usage for a month ("2006-01").
+func (p *Profiler) GetAccountUsage(ctx context.Context, account, month string) (*api.AccountUsage, error) {
+	data, err := p.rdb.HGetAll(ctx, p.getUsageKey(account, month)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseAccountUsage(account, month, data), nil
+}
+
+// ListAccountUsage returns the usage of every account active during a month, most expensive first.
+func (p *Profiler) ListAccountUsage(ctx context.Context, month string) ([]*api.AccountUsage, error) {
+	accounts, err := p.rdb.SMembers(ctx, p.getUsageAccountsKey(month)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := p.rdb.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(accounts))
+	for i, account := range accounts {
+		cmds[i] = pipe.HGetAll(ctx, p.getUsageKey(account, month))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	usages := make([]*api.AccountUsage, 0, len(accounts))
+	for i, account := range accounts {
+		usages = append(usages, parseAccountUsage(account, month, cmds[i].Val()))
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].CostUSD > usages[j].CostUSD })
+	return usages, nil
+}
+
+func parseAccountUsage(account, month string, data map[string]string) *api.AccountUsage {
+	usage := &api.AccountUsage{Account: account, Month: month}
+	usage.Requests, _ = strconv.ParseInt(data["requests"], 10, 64)
+	usage.PromptTokens, _ = strconv.ParseInt(data["prompt_tokens"], 10, 64)
+	usage.CompletionTokens, _ = strconv.ParseInt(data["completion_tokens"], 10, 64)
+	usage.CostUSD, _ = strconv.ParseFloat(data["cost_usd"], 64)
+	return usage
+}
@@ -0,0 +1,120 @@
+// In file: internal/logging/logging.go
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Correlation field names. Every log record made with a request's context carries the
+// fields that have been attached to it, so a request can be followed across all its logs.
+const (
+	RequestIDKey      = "request_id"
+	UserIDKey         = "user_id"
+	ConversationIDKey = "conversation_id"
+	ModelKey          = "model"
+	IntentKey         = "intent"
+)
+
+type fieldsKey struct{}
+
+// level is the minimum level of the default logger. It can be changed after Setup, once the
+// configuration has been loaded.
+var level slog.LevelVar
+
+// Setup installs a JSON logger on stdout as the default slog logger, at the level named by
+// LOG_LEVEL (debug, info, warn, or error; info if unset) until the configured level is set.
+// Output from the standard log package is routed through the same logger, at info level.
+func Setup() {
+	SetLevel(os.Getenv("LOG_LEVEL"))
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: &level})
+	slog.SetDefault(slog.New(&contextHandler{Handler: handler}))
+}
+
+// SetLevel changes the minimum level of the logger installed by Setup.
+func SetLevel(name string) {
+	level.Set(ParseLevel(name))
+}
+
+// ParseLevel maps a level name to a slog level, defaulting to info.
+func ParseLevel(name string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithFields returns a copy of ctx whose log records also carry the given key/value pairs.
+// Fields with an empty value are skipped, and a later value for a key replaces an earlier one.
+func WithFields(ctx context.Context, keyValues ...string) context.Context {
+	existing, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	fields := make([]slog.Attr, 0, len(existing)+len(keyValues)/2)
+	for _, field := range existing {
+		if !containsKey(keyValues, field.Key) {
+			fields = append(fields, field)
+		}
+	}
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if keyValues[i+1] != "" {
+			fields = append(fields, slog.String(keyValues[i], keyValues[i+1]))
+		}
+	}
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Field returns the value of a correlation field attached to ctx, or "".
+func Field(ctx context.Context, key string) string {
+	fields, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	for _, field := range fields {
+		if field.Key == key {
+			return field.Value.String()
+		}
+	}
+	return ""
+}
+
+func containsKey(keyValues []string, key string) bool {
+	for i := 0; i < len(keyValues); i += 2 {
+		if keyValues[i] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// contextHandler adds the correlation fields and the active trace and span IDs from a
+// record's context, so logs can be joined with traces.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields, ok := ctx.Value(fieldsKey{}).([]slog.Attr); ok {
+		record.AddAttrs(fields...)
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanCtx.TraceID().String()),
+			slog.String("span_id", spanCtx.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
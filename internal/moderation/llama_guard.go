@@ -0,0 +1,143 @@
+// In file: internal/moderation/llama_guard.go
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+// LlamaGuardConfig points at a Llama Guard model served behind an OpenAI-compatible chat
+// completions endpoint (e.g. vLLM or a hosted inference API).
+type LlamaGuardConfig struct {
+	URL   string `yaml:"url"`
+	Model string `yaml:"model"`
+}
+
+// llamaGuardCategories maps Llama Guard 3's hazard codes to category names.
+var llamaGuardCategories = map[string]string{
+	"S1":  "violent_crimes",
+	"S2":  "non_violent_crimes",
+	"S3":  "sex_related_crimes",
+	"S4":  "child_sexual_exploitation",
+	"S5":  "defamation",
+	"S6":  "specialized_advice",
+	"S7":  "privacy",
+	"S8":  "intellectual_property",
+	"S9":  "indiscriminate_weapons",
+	"S10": "hate",
+	"S11": "suicide_and_self_harm",
+	"S12": "sexual_content",
+	"S13": "elections",
+	"S14": "code_interpreter_abuse",
+}
+
+// LlamaGuardModerator classifies content with Llama Guard. Llama Guard gives a verdict rather
+// than scores, so violated categories score 1 and every other category 0.
+type LlamaGuardModerator struct {
+	config     LlamaGuardConfig
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Statically verify that LlamaGuardModerator implements the Moderator interface.
+var _ Moderator = (*LlamaGuardModerator)(nil)
+
+func NewLlamaGuardModerator(config LlamaGuardConfig, apiKey string) *LlamaGuardModerator {
+	if config.Model == "" {
+		config.Model = "meta-llama/Llama-Guard-3-8B"
+	}
+	return &LlamaGuardModerator{
+		config:     config,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 15 * time.Second, Transport: telemetry.Transport()},
+	}
+}
+
+// Score sends the conversation to Llama Guard, whose chat template classifies the last turn:
+// the user's prompt for input moderation, or the assistant's answer for output moderation.
+func (m *LlamaGuardModerator) Score(ctx context.Context, prompt, answer string) (map[string]float64, error) {
+	messages := []map[string]string{{"role": "user", "content": prompt}}
+	if answer != "" {
+		messages = append(messages, map[string]string{"role": "assistant", "content": answer})
+	}
+	payload, err := json.Marshal(map[string]interface{}{"model": m.config.Model, "messages": messages, "temperature": 0})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", m.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("llama guard API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode llama guard response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return nil, errors.New("llama guard response has no choices")
+	}
+	return parseLlamaGuardVerdict(result.Choices[0].Message.Content)
+}
+
+// parseLlamaGuardVerdict parses "safe", or "unsafe" followed by a line of comma-separated
+// hazard codes such as "S1,S10".
+func parseLlamaGuardVerdict(verdict string) (map[string]float64, error) {
+	lines := strings.Split(strings.TrimSpace(verdict), "\n")
+	scores := make(map[string]float64, len(llamaGuardCategories))
+	for _, category := range llamaGuardCategories {
+		scores[category] = 0
+	}
+
+	switch strings.TrimSpace(lines[0]) {
+	case "safe":
+		return scores, nil
+	case "unsafe":
+		if len(lines) < 2 {
+			scores["unspecified"] = 1
+			return scores, nil
+		}
+		for _, code := range strings.Split(lines[1], ",") {
+			code = strings.TrimSpace(code)
+			if category, ok := llamaGuardCategories[code]; ok {
+				scores[category] = 1
+			} else {
+				scores[strings.ToLower(code)] = 1
+			}
+		}
+		return scores, nil
+	default:
+		return nil, fmt.Errorf("unexpected llama guard verdict '%s'", lines[0])
+	}
+}
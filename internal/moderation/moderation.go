@@ -0,0 +1,108 @@
+// In file: internal/moderation/moderation.go
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dileep-u-k/llm-gateway/internal/api"
+)
+
+// Moderation providers.
+const (
+	ProviderNone       = "none"
+	ProviderOpenAI     = "openai"
+	ProviderLlamaGuard = "llama_guard"
+)
+
+// Stages at which content is moderated.
+const (
+	StageInput  = "input"
+	StageOutput = "output"
+)
+
+// Config is the `moderation` section of config.yaml.
+type Config struct {
+	// Provider is ProviderNone (the default), ProviderOpenAI, or ProviderLlamaGuard.
+	Provider string `yaml:"provider"`
+	// CheckInput and CheckOutput select whether prompts and final answers are moderated.
+	CheckInput  bool `yaml:"check_input"`
+	CheckOutput bool `yaml:"check_output"`
+	// DefaultThreshold is the score at or above which a category blocks content, unless the
+	// category has its own threshold.
+	DefaultThreshold float64 `yaml:"default_threshold"`
+	// Thresholds overrides the threshold of individual categories. A threshold above 1
+	// effectively allows the category.
+	Thresholds map[string]float64 `yaml:"thresholds"`
+	// LlamaGuard configures the Llama Guard provider.
+	LlamaGuard LlamaGuardConfig `yaml:"llama_guard"`
+}
+
+// Moderator scores content against a provider's safety categories.
+type Moderator interface {
+	// Score returns a score between 0 and 1 for each category. For output moderation, answer
+	// is the model's answer to prompt; for input moderation it is empty.
+	Score(ctx context.Context, prompt, answer string) (map[string]float64, error)
+}
+
+// Policy applies per-category thresholds to a moderator's scores.
+type Policy struct {
+	moderator Moderator
+	config    Config
+}
+
+// NewPolicy creates a policy for the configured provider. The API key is used by the
+// OpenAI provider and, if set, sent to the Llama Guard endpoint.
+func NewPolicy(config Config, apiKey string) (*Policy, error) {
+	if config.DefaultThreshold <= 0 {
+		config.DefaultThreshold = 0.5
+	}
+	var moderator Moderator
+	switch config.Provider {
+	case ProviderNone, "":
+		return nil, errors.New("moderation is disabled")
+	case ProviderOpenAI:
+		if apiKey == "" {
+			return nil, errors.New("the openai moderation provider needs OPENAI_API_KEY or MODERATION_API_KEY")
+		}
+		moderator = NewOpenAIModerator(apiKey)
+	case ProviderLlamaGuard:
+		if config.LlamaGuard.URL == "" {
+			return nil, errors.New("the llama_guard moderation provider needs llama_guard.url")
+		}
+		moderator = NewLlamaGuardModerator(config.LlamaGuard, apiKey)
+	default:
+		return nil, fmt.Errorf("unknown moderation provider '%s'", config.Provider)
+	}
+	return &Policy{moderator: moderator, config: config}, nil
+}
+
+// ChecksInput reports whether prompts are moderated.
+func (p *Policy) ChecksInput() bool { return p.config.CheckInput }
+
+// ChecksOutput reports whether final answers are moderated.
+func (p *Policy) ChecksOutput() bool { return p.config.CheckOutput }
+
+// Check moderates content at the given stage.
+func (p *Policy) Check(ctx context.Context, stage, prompt, answer string) (*api.ModerationCheck, error) {
+	scores, err := p.moderator.Score(ctx, prompt, answer)
+	if err != nil {
+		return nil, fmt.Errorf("%s moderation failed: %w", stage, err)
+	}
+
+	verdict := &api.ModerationCheck{Stage: stage, Scores: scores}
+	for category, score := range scores {
+		threshold, ok := p.config.Thresholds[category]
+		if !ok {
+			threshold = p.config.DefaultThreshold
+		}
+		if score >= threshold {
+			verdict.Categories = append(verdict.Categories, category)
+		}
+	}
+	sort.Strings(verdict.Categories)
+	verdict.Blocked = len(verdict.Categories) > 0
+	return verdict, nil
+}
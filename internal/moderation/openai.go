@@ -0,0 +1,81 @@
+// In file: internal/moderation/openai.go
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+const (
+	openAIModerationURL   = "https://api.openai.com/v1/moderations"
+	openAIModerationModel = "omni-moderation-latest"
+)
+
+// OpenAIModerator scores content with OpenAI's moderation endpoint, whose categories are
+// e.g. "violence", "self-harm", and "hate/threatening".
+type OpenAIModerator struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Statically verify that OpenAIModerator implements the Moderator interface.
+var _ Moderator = (*OpenAIModerator)(nil)
+
+func NewOpenAIModerator(apiKey string) *OpenAIModerator {
+	return &OpenAIModerator{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport()},
+	}
+}
+
+// Score moderates the answer when there is one, and the prompt otherwise.
+func (m *OpenAIModerator) Score(ctx context.Context, prompt, answer string) (map[string]float64, error) {
+	input := prompt
+	if answer != "" {
+		input = answer
+	}
+	payload, err := json.Marshal(map[string]string{"model": openAIModerationModel, "input": input})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIModerationURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai moderation API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Results []struct {
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return nil, errors.New("moderation response has no results")
+	}
+	return result.Results[0].CategoryScores, nil
+}
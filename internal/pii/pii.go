@@ -0,0 +1,192 @@
+// In file: internal/pii/pii.go
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redaction modes.
+const (
+	// ModeOff disables PII detection.
+	ModeOff = "off"
+	// ModeDetect records what PII a request contains without changing it.
+	ModeDetect = "detect"
+	// ModeMask replaces PII with placeholders before it reaches a provider and restores it in
+	// the response.
+	ModeMask = "mask"
+)
+
+// Built-in entity types.
+const (
+	EntityEmail      = "EMAIL"
+	EntityPhone      = "PHONE"
+	EntityCreditCard = "CREDIT_CARD"
+)
+
+// Config is the `pii` section of config.yaml.
+type Config struct {
+	// Mode is ModeOff (the default), ModeDetect, or ModeMask.
+	Mode string `yaml:"mode"`
+	// Entities selects the built-in entity types to detect. All of them are used when empty.
+	Entities []string `yaml:"entities"`
+	// Custom adds operator-defined entities, such as employee or account numbers.
+	Custom []CustomEntity `yaml:"custom"`
+}
+
+// CustomEntity is an operator-defined entity type matched by a regular expression.
+type CustomEntity struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// Match is one occurrence of an entity in a text.
+type Match struct {
+	Entity     string
+	Start, End int
+}
+
+// Recognizer finds entities of one kind in a text. Regex recognizers are built in; an NER
+// model can be plugged in by implementing this interface.
+type Recognizer interface {
+	Entity() string
+	Find(text string) []Match
+}
+
+// regexRecognizer matches an entity with a regular expression and an optional validator
+// that rejects false positives.
+type regexRecognizer struct {
+	entity   string
+	re       *regexp.Regexp
+	validate func(string) bool
+}
+
+// Statically verify that regexRecognizer implements the Recognizer interface.
+var _ Recognizer = (*regexRecognizer)(nil)
+
+func (r *regexRecognizer) Entity() string { return r.entity }
+
+func (r *regexRecognizer) Find(text string) []Match {
+	var matches []Match
+	for _, loc := range r.re.FindAllStringIndex(text, -1) {
+		if r.validate != nil && !r.validate(text[loc[0]:loc[1]]) {
+			continue
+		}
+		matches = append(matches, Match{Entity: r.entity, Start: loc[0], End: loc[1]})
+	}
+	return matches
+}
+
+// builtinRecognizers returns the recognizers for the built-in entity types.
+func builtinRecognizers() map[string]Recognizer {
+	return map[string]Recognizer{
+		EntityEmail: &regexRecognizer{
+			entity: EntityEmail,
+			re:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		EntityPhone: &regexRecognizer{
+			entity:   EntityPhone,
+			re:       regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{2,4}\)[\s.\-]?)?\d{2,4}[\s.\-]\d{3,4}[\s.\-]?\d{3,4}`),
+			validate: func(s string) bool { n := countDigits(s); return n >= 9 && n <= 15 },
+		},
+		EntityCreditCard: &regexRecognizer{
+			entity:   EntityCreditCard,
+			re:       regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+			validate: luhnValid,
+		},
+	}
+}
+
+// Detector finds PII using a set of recognizers.
+type Detector struct {
+	recognizers []Recognizer
+}
+
+// NewDetector builds a detector for the configured built-in and custom entities.
+func NewDetector(config Config) (*Detector, error) {
+	builtins := builtinRecognizers()
+	names := config.Entities
+	if len(names) == 0 {
+		names = []string{EntityCreditCard, EntityEmail, EntityPhone}
+	}
+
+	d := &Detector{}
+	for _, name := range names {
+		r, ok := builtins[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown PII entity '%s'", name)
+		}
+		d.recognizers = append(d.recognizers, r)
+	}
+	for _, custom := range config.Custom {
+		if custom.Name == "" {
+			return nil, fmt.Errorf("custom PII entity with pattern '%s' has no name", custom.Pattern)
+		}
+		re, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for PII entity '%s': %w", custom.Name, err)
+		}
+		d.recognizers = append(d.recognizers, &regexRecognizer{entity: strings.ToUpper(custom.Name), re: re})
+	}
+	return d, nil
+}
+
+// AddRecognizer registers an additional recognizer, such as an NER model.
+func (d *Detector) AddRecognizer(r Recognizer) {
+	d.recognizers = append(d.recognizers, r)
+}
+
+// Detect returns the non-overlapping entities in a text, in order. Where matches overlap,
+// the earlier recognizer wins, which is why credit cards are checked before phone numbers.
+func (d *Detector) Detect(text string) []Match {
+	var all []Match
+	for _, r := range d.recognizers {
+		all = append(all, r.Find(text)...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+
+	var matches []Match
+	end := 0
+	for _, m := range all {
+		if m.Start < end {
+			continue
+		}
+		matches = append(matches, m)
+		end = m.End
+	}
+	return matches
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum used by payment cards.
+func luhnValid(s string) bool {
+	sum, double, digits := 0, false, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
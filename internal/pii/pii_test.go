@@ -0,0 +1,119 @@
+// In file: internal/pii/pii_test.go
+package pii
+
+import "testing"
+
+func TestDetectorDetect(t *testing.T) {
+	type found struct{ entity, value string }
+	tests := []struct {
+		name   string
+		config Config
+		text   string
+		want   []found
+	}{
+		{
+			name: "email",
+			text: "mail jane.doe@example.com now",
+			want: []found{{EntityEmail, "jane.doe@example.com"}},
+		},
+		{
+			name: "phone",
+			text: "call +1 415-555-0132 today",
+			want: []found{{EntityPhone, "+1 415-555-0132"}},
+		},
+		{
+			name: "credit card wins over phone",
+			text: "card 4111 1111 1111 1111 please",
+			want: []found{{EntityCreditCard, "4111 1111 1111 1111"}},
+		},
+		{
+			name: "number failing the Luhn check",
+			text: "ref 4111111111111112",
+		},
+		{
+			name: "short number",
+			text: "order 12345 shipped",
+		},
+		{
+			name: "several entities in order",
+			text: "4111111111111111 belongs to a@b.io",
+			want: []found{{EntityCreditCard, "4111111111111111"}, {EntityEmail, "a@b.io"}},
+		},
+		{
+			name:   "selected and custom entities",
+			config: Config{Entities: []string{"email"}, Custom: []CustomEntity{{Name: "employee_id", Pattern: `EMP-\d{6}`}}},
+			text:   "EMP-123456 at +1 415-555-0132 or jane@example.com",
+			want:   []found{{"EMPLOYEE_ID", "EMP-123456"}, {EntityEmail, "jane@example.com"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDetector(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches := d.Detect(tt.text)
+			if len(matches) != len(tt.want) {
+				t.Fatalf("Detect() = %v, want %d matches", matches, len(tt.want))
+			}
+			for i, m := range matches {
+				got := found{m.Entity, tt.text[m.Start:m.End]}
+				if got != tt.want[i] {
+					t.Errorf("match %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamUnmasker(t *testing.T) {
+	tests := []struct {
+		name       string
+		chunks     []string
+		wantWrites []string
+		wantFlush  string
+	}{
+		{
+			name:       "whole placeholder",
+			chunks:     []string{"Hi [EMAIL_1]!"},
+			wantWrites: []string{"Hi jane@example.com!"},
+		},
+		{
+			name:       "placeholder split across chunks",
+			chunks:     []string{"Hi [EMA", "IL_", "1] bye"},
+			wantWrites: []string{"Hi ", "", "jane@example.com bye"},
+		},
+		{
+			name:       "unknown placeholder",
+			chunks:     []string{"[PHONE_9] ok"},
+			wantWrites: []string{"[PHONE_9] ok"},
+		},
+		{
+			name:       "unclosed bracket is flushed",
+			chunks:     []string{"see [note"},
+			wantWrites: []string{"see "},
+			wantFlush:  "[note",
+		},
+		{
+			name:       "long bracketed text is not held back",
+			chunks:     []string{"[aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+			wantWrites: []string{"[aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := NewSession()
+			text := "jane@example.com"
+			session.Mask(text, []Match{{Entity: EntityEmail, Start: 0, End: len(text)}})
+			u := session.NewStreamUnmasker()
+			for i, chunk := range tt.chunks {
+				if got := u.Write(chunk); got != tt.wantWrites[i] {
+					t.Errorf("Write(%q) = %q, want %q", chunk, got, tt.wantWrites[i])
+				}
+			}
+			if got := u.Flush(); got != tt.wantFlush {
+				t.Errorf("Flush() = %q, want %q", got, tt.wantFlush)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// In file: internal/pii/session.go
+package pii
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// placeholderRegex matches the placeholders written by Session.Mask.
+var placeholderRegex = regexp.MustCompile(`\[[A-Z_]+_\d+\]`)
+
+// maxPlaceholderLen bounds how much trailing text a StreamUnmasker holds back while waiting
+// for the rest of a placeholder.
+const maxPlaceholderLen = 40
+
+// Session holds the placeholders of one request. Every provider call made while serving the
+// request shares it, so a value keeps the same placeholder across tool-use rounds, and
+// placeholders the model echoes back can be restored.
+type Session struct {
+	mu       sync.Mutex
+	byValue  map[string]string
+	byHolder map[string]string
+	counts   map[string]int
+	found    map[string]int
+}
+
+// NewSession starts an empty session.
+func NewSession() *Session {
+	return &Session{
+		byValue:  make(map[string]string),
+		byHolder: make(map[string]string),
+		counts:   make(map[string]int),
+		found:    make(map[string]int),
+	}
+}
+
+// Mask replaces every match in text with a placeholder such as "[EMAIL_1]".
+func (s *Session) Mask(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		value := text[m.Start:m.End]
+		holder, ok := s.byValue[value]
+		if !ok {
+			s.counts[m.Entity]++
+			holder = fmt.Sprintf("[%s_%d]", m.Entity, s.counts[m.Entity])
+			s.byValue[value] = holder
+			s.byHolder[holder] = value
+		}
+		b.WriteString(text[last:m.Start])
+		b.WriteString(holder)
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Record counts detected entities for the audit log, without masking them.
+func (s *Session) Record(matches []Match) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range matches {
+		s.found[m.Entity]++
+	}
+}
+
+// Unmask restores the original values of the session's placeholders in text.
+func (s *Session) Unmask(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.byHolder) == 0 {
+		return text
+	}
+	return placeholderRegex.ReplaceAllStringFunc(text, func(holder string) string {
+		if value, ok := s.byHolder[holder]; ok {
+			return value
+		}
+		return holder
+	})
+}
+
+// Findings returns how many entities of each type were detected or masked.
+// Masked values are counted once each, however often they were sent.
+func (s *Session) Findings() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	findings := make(map[string]int, len(s.counts)+len(s.found))
+	for entity, n := range s.counts {
+		findings[entity] += n
+	}
+	for entity, n := range s.found {
+		findings[entity] += n
+	}
+	return findings
+}
+
+// StreamUnmasker restores placeholders in streamed text, where a placeholder may be split
+// across chunks. Text that could be the start of a placeholder is held back until it is
+// complete.
+type StreamUnmasker struct {
+	session *Session
+	pending string
+}
+
+// NewStreamUnmasker creates an unmasker for one stream.
+func (s *Session) NewStreamUnmasker() *StreamUnmasker {
+	return &StreamUnmasker{session: s}
+}
+
+// Write adds a chunk and returns the text that is safe to emit.
+func (u *StreamUnmasker) Write(chunk string) string {
+	u.pending += chunk
+	cut := len(u.pending)
+	if i := strings.LastIndexByte(u.pending, '['); i >= 0 && !strings.Contains(u.pending[i:], "]") && len(u.pending)-i < maxPlaceholderLen {
+		cut = i
+	}
+	out := u.session.Unmask(u.pending[:cut])
+	u.pending = u.pending[cut:]
+	return out
+}
+
+// Flush returns whatever text is still held back.
+func (u *StreamUnmasker) Flush() string {
+	out := u.session.Unmask(u.pending)
+	u.pending = ""
+	return out
+}
+
+type sessionKey struct{}
+
+// WithSession attaches a session to the context of a request.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFrom returns the request's session, or nil if it has none.
+func SessionFrom(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
@@ -0,0 +1,234 @@
+// In file: internal/ratelimit/concurrency.go
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Priority is the quality-of-service tier of a request.
+type Priority string
+
+const (
+	// PriorityInteractive is for user-facing traffic, such as chat. It is the default.
+	PriorityInteractive Priority = "interactive"
+	// PriorityBatch is for background jobs. Batch requests only get a slot no interactive
+	// request is waiting for, and are the first to be shed when the queue is full.
+	PriorityBatch Priority = "batch"
+)
+
+// Validate reports an unknown priority. The empty priority is PriorityInteractive.
+func (p Priority) Validate() error {
+	switch p {
+	case "", PriorityInteractive, PriorityBatch:
+		return nil
+	}
+	return fmt.Errorf("unknown priority '%s' (expected '%s' or '%s')", p, PriorityInteractive, PriorityBatch)
+}
+
+// rank orders priorities, most urgent first.
+func (p Priority) rank() int {
+	if p == PriorityBatch {
+		return 1
+	}
+	return 0
+}
+
+// ErrSaturated is returned when a request cannot get a slot: every slot is taken and the
+// queue is full, the request waited in the queue for longer than allowed, or it was shed
+// from the queue to make room for a more urgent request.
+var ErrSaturated = errors.New("too many requests in flight")
+
+// ConcurrencyConfig bounds how many requests the gateway works on at once, gateway-wide and
+// per model, so a traffic spike queues or is turned away instead of exhausting Redis
+// connections, provider quotas, and memory. This limit is local to each replica.
+type ConcurrencyConfig struct {
+	// MaxInFlight bounds the requests in flight across all models; 0 is unlimited.
+	MaxInFlight int `yaml:"max_in_flight"`
+	// Models bounds the requests in flight per model. Models not listed are unlimited.
+	Models map[string]int `yaml:"models"`
+	// QueueDepth is how many requests may wait for a slot of each limit; when it is full,
+	// further batch requests are rejected at once, and interactive ones shed a queued batch
+	// request if there is one. 0 rejects without waiting.
+	QueueDepth int `yaml:"queue_depth"`
+	// QueueTimeout is the longest a request waits for a slot before it is rejected; 0 waits
+	// for as long as the caller does.
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+	// RetryAfter is the delay suggested to rejected callers.
+	RetryAfter time.Duration `yaml:"retry_after"`
+}
+
+// defaultConcurrencyRetryAfter is the delay suggested to rejected callers by default.
+const defaultConcurrencyRetryAfter = time.Second
+
+// Validate reports settings that cannot work.
+func (c ConcurrencyConfig) Validate() error {
+	if c.MaxInFlight < 0 || c.QueueDepth < 0 {
+		return fmt.Errorf("max_in_flight and queue_depth must not be negative")
+	}
+	if c.QueueTimeout < 0 || c.RetryAfter < 0 {
+		return fmt.Errorf("queue_timeout and retry_after must not be negative")
+	}
+	for modelID, limit := range c.Models {
+		if limit < 0 {
+			return fmt.Errorf("the limit of model %s must not be negative", modelID)
+		}
+	}
+	return nil
+}
+
+// ConcurrencyLimiter hands out in-flight slots under a global limit and per-model limits.
+type ConcurrencyLimiter struct {
+	global     *semaphore
+	models     map[string]*semaphore
+	retryAfter time.Duration
+}
+
+// NewConcurrencyLimiter creates a limiter from validated settings.
+func NewConcurrencyLimiter(cfg ConcurrencyConfig) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		global:     newSemaphore(cfg.MaxInFlight, cfg.QueueDepth, cfg.QueueTimeout),
+		models:     make(map[string]*semaphore),
+		retryAfter: cfg.RetryAfter,
+	}
+	if l.retryAfter == 0 {
+		l.retryAfter = defaultConcurrencyRetryAfter
+	}
+	for modelID, limit := range cfg.Models {
+		l.models[modelID] = newSemaphore(limit, cfg.QueueDepth, cfg.QueueTimeout)
+	}
+	return l
+}
+
+// Acquire takes a slot under the global limit, waiting in its queue behind more urgent
+// requests if needed. The returned function releases the slot and must be called exactly once.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, priority Priority) (func(), error) {
+	return l.global.acquire(ctx, priority)
+}
+
+// AcquireModel takes a slot under the limit of a model, like Acquire.
+func (l *ConcurrencyLimiter) AcquireModel(ctx context.Context, modelID string, priority Priority) (func(), error) {
+	return l.models[modelID].acquire(ctx, priority)
+}
+
+// RetryAfter is the delay suggested to callers rejected with ErrSaturated.
+func (l *ConcurrencyLimiter) RetryAfter() time.Duration {
+	return l.retryAfter
+}
+
+// semaphore is a counting semaphore with a bounded, prioritized wait queue. A released slot
+// goes to the longest-waiting interactive request, and only when none waits to a batch one.
+// A nil semaphore is unlimited.
+type semaphore struct {
+	limit        int
+	queueDepth   int
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	active int
+	queues [2]*list.List // Waiters of each priority, indexed by Priority.rank.
+}
+
+// waiter is a request queued for a slot.
+type waiter struct {
+	// ready receives nil when the waiter is handed a slot, or ErrSaturated when it is shed.
+	ready chan error
+	elem  *list.Element
+}
+
+// newSemaphore returns a semaphore with limit slots, or nil if limit is 0.
+func newSemaphore(limit, queueDepth int, queueTimeout time.Duration) *semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return &semaphore{
+		limit:        limit,
+		queueDepth:   queueDepth,
+		queueTimeout: queueTimeout,
+		queues:       [2]*list.List{list.New(), list.New()},
+	}
+}
+
+func (s *semaphore) acquire(ctx context.Context, priority Priority) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	s.mu.Lock()
+	if s.active < s.limit {
+		s.active++
+		s.mu.Unlock()
+		return s.release, nil
+	}
+	if s.queued() >= s.queueDepth {
+		batch := s.queues[PriorityBatch.rank()]
+		if priority == PriorityBatch || batch.Len() == 0 {
+			s.mu.Unlock()
+			return nil, ErrSaturated
+		}
+		// Make room for an interactive request by shedding the most recently queued batch one.
+		shed := batch.Remove(batch.Back()).(*waiter)
+		shed.ready <- ErrSaturated
+	}
+	w := &waiter{ready: make(chan error, 1)}
+	w.elem = s.queues[priority.rank()].PushBack(w)
+	s.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if s.queueTimeout > 0 {
+		timer := time.NewTimer(s.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case err = <-w.ready:
+		if err != nil {
+			return nil, err
+		}
+		return s.release, nil
+	case <-timeout:
+		err = ErrSaturated
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case granted := <-w.ready:
+		// The slot was handed over (or the waiter shed) just as it gave up.
+		if granted == nil {
+			s.releaseLocked()
+		}
+	default:
+		s.queues[priority.rank()].Remove(w.elem)
+	}
+	return nil, err
+}
+
+// release frees a slot, handing it straight to the next waiter if there is one.
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+// releaseLocked is release for a caller holding s.mu.
+func (s *semaphore) releaseLocked() {
+	for _, queue := range s.queues {
+		if front := queue.Front(); front != nil {
+			queue.Remove(front).(*waiter).ready <- nil
+			return
+		}
+	}
+	s.active--
+}
+
+// queued returns the number of waiting requests. The caller must hold s.mu.
+func (s *semaphore) queued() int {
+	return s.queues[0].Len() + s.queues[1].Len()
+}
@@ -0,0 +1,241 @@
+// In file: internal/ratelimit/concurrency_test.go
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until n requests are queued on s.
+func waitQueued(t *testing.T, s *semaphore, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		queued := s.queued()
+		s.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// state returns the active and queued requests of s.
+func state(s *semaphore) (active, queued int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active, s.queued()
+}
+
+func TestSemaphoreAcquire(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int
+		queueDepth int
+		held       int
+		priority   Priority
+		wantErr    error
+	}{
+		{name: "unlimited", limit: 0, held: 5, priority: PriorityInteractive},
+		{name: "free slot", limit: 2, held: 1, priority: PriorityInteractive},
+		{name: "no queue", limit: 1, held: 1, priority: PriorityInteractive, wantErr: ErrSaturated},
+		{name: "batch without queue", limit: 1, held: 1, priority: PriorityBatch, wantErr: ErrSaturated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSemaphore(tt.limit, tt.queueDepth, 0)
+			for i := 0; i < tt.held; i++ {
+				if _, err := s.acquire(context.Background(), PriorityInteractive); err != nil {
+					t.Fatalf("acquire %d: %v", i, err)
+				}
+			}
+			release, err := s.acquire(context.Background(), tt.priority)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				release()
+			}
+		})
+	}
+}
+
+func TestSemaphoreReleaseHandsOffByPriority(t *testing.T) {
+	s := newSemaphore(1, 4, 0)
+	release, err := s.acquire(context.Background(), PriorityInteractive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+	enqueue := func(priority Priority, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.acquire(context.Background(), priority)
+			if err != nil {
+				t.Errorf("%s: %v", priority, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+			release()
+		}()
+		waitQueued(t, s, queued)
+	}
+	// Batch requests queue first, yet the interactive one is served before them.
+	enqueue(PriorityBatch, 1)
+	enqueue(PriorityBatch, 2)
+	enqueue(PriorityInteractive, 3)
+	release()
+	wg.Wait()
+
+	want := []Priority{PriorityInteractive, PriorityBatch, PriorityBatch}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+	if active, queued := state(s); active != 0 || queued != 0 {
+		t.Fatalf("active = %d, queued = %d after all releases", active, queued)
+	}
+}
+
+func TestSemaphoreShedding(t *testing.T) {
+	tests := []struct {
+		name        string
+		queued      Priority
+		arriving    Priority
+		wantShed    bool
+		wantArrival error
+	}{
+		{name: "interactive sheds batch", queued: PriorityBatch, arriving: PriorityInteractive, wantShed: true},
+		{name: "batch is rejected", queued: PriorityBatch, arriving: PriorityBatch, wantArrival: ErrSaturated},
+		{name: "interactive is not shed", queued: PriorityInteractive, arriving: PriorityInteractive, wantArrival: ErrSaturated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSemaphore(1, 1, 0)
+			release, err := s.acquire(context.Background(), PriorityInteractive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			queuedErr := make(chan error, 1)
+			go func() {
+				release, err := s.acquire(context.Background(), tt.queued)
+				if err == nil {
+					release()
+				}
+				queuedErr <- err
+			}()
+			waitQueued(t, s, 1)
+
+			arrivalErr := make(chan error, 1)
+			go func() {
+				release, err := s.acquire(context.Background(), tt.arriving)
+				if err == nil {
+					release()
+				}
+				arrivalErr <- err
+			}()
+			if tt.wantShed {
+				if err := <-queuedErr; !errors.Is(err, ErrSaturated) {
+					t.Fatalf("queued request err = %v, want %v", err, ErrSaturated)
+				}
+				release()
+				if err := <-arrivalErr; err != nil {
+					t.Fatalf("arriving request err = %v, want nil", err)
+				}
+			} else {
+				if err := <-arrivalErr; !errors.Is(err, tt.wantArrival) {
+					t.Fatalf("arriving request err = %v, want %v", err, tt.wantArrival)
+				}
+				release()
+				if err := <-queuedErr; err != nil {
+					t.Fatalf("queued request err = %v, want nil", err)
+				}
+			}
+			if active, queued := state(s); active != 0 || queued != 0 {
+				t.Fatalf("active = %d, queued = %d after all releases", active, queued)
+			}
+		})
+	}
+}
+
+func TestSemaphoreGivingUp(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueTimeout time.Duration
+		cancel       bool
+		wantErr      error
+	}{
+		{name: "queue timeout", queueTimeout: 10 * time.Millisecond, wantErr: ErrSaturated},
+		{name: "caller cancels", cancel: true, wantErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSemaphore(1, 1, tt.queueTimeout)
+			release, err := s.acquire(context.Background(), PriorityInteractive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			if _, err := s.acquire(ctx, PriorityInteractive); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if active, queued := state(s); active != 1 || queued != 0 {
+				t.Fatalf("active = %d, queued = %d after giving up, want 1 and 0", active, queued)
+			}
+			release()
+			if active, _ := state(s); active != 0 {
+				t.Fatalf("active = %d after release, want 0", active)
+			}
+		})
+	}
+}
+
+// TestSemaphoreTimeoutRace hands a slot to a waiter that has already timed out, and checks
+// that the waiter passes the slot on instead of leaking it.
+func TestSemaphoreTimeoutRace(t *testing.T) {
+	s := newSemaphore(1, 1, 10*time.Millisecond)
+	if _, err := s.acquire(context.Background(), PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.acquire(context.Background(), PriorityInteractive)
+		done <- err
+	}()
+	waitQueued(t, s, 1)
+
+	// Hold the lock past the timeout, so the waiter gives up and blocks on the lock, then
+	// release the first slot to it.
+	s.mu.Lock()
+	time.Sleep(100 * time.Millisecond)
+	s.releaseLocked()
+	s.mu.Unlock()
+
+	if err := <-done; !errors.Is(err, ErrSaturated) {
+		t.Fatalf("err = %v, want %v", err, ErrSaturated)
+	}
+	if active, queued := state(s); active != 0 || queued != 0 {
+		t.Fatalf("active = %d, queued = %d, want the handed-over slot freed", active, queued)
+	}
+}
@@ -0,0 +1,142 @@
+// In file: internal/ratelimit/limiter.go
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// window is the length of a rate limit window. Limits are expressed per minute.
+const window = time.Minute
+
+// Limits caps how much a single account may use per minute. A zero limit is unlimited.
+type Limits struct {
+	RequestsPerMinute int `yaml:"rpm"`
+	TokensPerMinute   int `yaml:"tpm"`
+}
+
+// Enabled reports whether any limit is set.
+func (l Limits) Enabled() bool {
+	return l.RequestsPerMinute > 0 || l.TokensPerMinute > 0
+}
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed bool
+	// Limit names the limit that was hit: "rpm" or "tpm".
+	Limit string
+	// RetryAfter is how long the caller should wait before the request would be allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter enforces per-account limits with a sliding window counter shared through Redis,
+// so every replica sees the same usage. The window is approximated from the counts of the
+// current and previous fixed windows, weighting the previous one by how much of it still
+// overlaps the sliding window.
+type Limiter struct {
+	rdb *redis.Client
+}
+
+func NewLimiter(rdb *redis.Client) *Limiter {
+	return &Limiter{rdb: rdb}
+}
+
+// slidingWindowScript atomically checks a sliding window counter and adds ARGV[3] to it if the
+// result stays within the limit. It returns {allowed, current count, previous count}.
+//
+// KEYS[1]: the current window's counter. KEYS[2]: the previous window's counter.
+// ARGV[1]: the limit. ARGV[2]: the weight of the previous window. ARGV[3]: the increment.
+// ARGV[4]: the counter TTL in seconds.
+var slidingWindowScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+local limit = tonumber(ARGV[1])
+local increment = tonumber(ARGV[3])
+local estimate = previous * tonumber(ARGV[2]) + current
+if (increment > 0 and estimate + increment > limit) or (increment == 0 and estimate >= limit) then
+	return {0, current, previous}
+end
+if increment > 0 then
+	current = redis.call("INCRBY", KEYS[1], increment)
+	redis.call("EXPIRE", KEYS[1], ARGV[4])
+end
+return {1, current, previous}
+`)
+
+// Allow checks a new request against the account's limits and, if it is allowed, counts it.
+// The token limit is checked against the tokens already used; a request's own tokens are
+// only known once it finishes and are added with RecordTokens.
+func (l *Limiter) Allow(ctx context.Context, account string, limits Limits) (Decision, error) {
+	now := time.Now()
+	if limits.TokensPerMinute > 0 {
+		decision, err := l.check(ctx, account, "tpm", limits.TokensPerMinute, 0, now)
+		if err != nil || !decision.Allowed {
+			return decision, err
+		}
+	}
+	if limits.RequestsPerMinute > 0 {
+		return l.check(ctx, account, "rpm", limits.RequestsPerMinute, 1, now)
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// RecordTokens adds the tokens a finished request used to the account's token window.
+func (l *Limiter) RecordTokens(ctx context.Context, account string, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	key := l.windowKey(account, "tpm", time.Now())
+	pipe := l.rdb.Pipeline()
+	pipe.IncrBy(ctx, key, int64(tokens))
+	pipe.Expire(ctx, key, 2*window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record tokens for %s: %w", account, err)
+	}
+	return nil
+}
+
+func (l *Limiter) check(ctx context.Context, account, limit string, max, increment int, now time.Time) (Decision, error) {
+	elapsed := float64(now.UnixNano()%int64(window)) / float64(window)
+	previousWeight := 1 - elapsed
+	keys := []string{l.windowKey(account, limit, now), l.windowKey(account, limit, now.Add(-window))}
+
+	res, err := slidingWindowScript.Run(ctx, l.rdb, keys, max, previousWeight, increment, int(2*window/time.Second)).Int64Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("rate limit check failed for %s: %w", account, err)
+	}
+	if res[0] == 1 {
+		return Decision{Allowed: true}, nil
+	}
+	return Decision{
+		Limit:      limit,
+		RetryAfter: retryAfter(float64(max), float64(res[1]), float64(res[2]), float64(increment), elapsed),
+	}, nil
+}
+
+// retryAfter estimates when the sliding window will have room again. If the current window
+// alone is over the limit, that is when it ends; otherwise it is when enough of the previous
+// window has slid out.
+func retryAfter(max, current, previous, increment, elapsed float64) time.Duration {
+	room := max - current - increment
+	if increment == 0 {
+		room = max - current
+	}
+	remaining := 1 - elapsed
+	if room > 0 && previous > 0 {
+		// Solve previous*(1-f) + current + increment <= max for the elapsed fraction f.
+		needed := 1 - room/previous
+		if needed > elapsed {
+			remaining = needed - elapsed
+		}
+	}
+	return time.Duration(math.Ceil(remaining*window.Seconds())) * time.Second
+}
+
+// windowKey returns the counter of the fixed window containing t.
+func (l *Limiter) windowKey(account, limit string, t time.Time) string {
+	return fmt.Sprintf("ratelimit:%s:%s:%d", limit, account, t.Unix()/int64(window/time.Second))
+}
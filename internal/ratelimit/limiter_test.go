@@ -0,0 +1,30 @@
+// In file: internal/ratelimit/limiter_test.go
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name                                       string
+		max, current, previous, increment, elapsed float64
+		want                                       time.Duration
+	}{
+		{name: "current window over the limit", max: 10, current: 10, previous: 5, increment: 1, elapsed: 0.25, want: 45 * time.Second},
+		{name: "previous window slides out", max: 10, current: 4, previous: 10, increment: 1, elapsed: 0.25, want: 15 * time.Second},
+		{name: "token limit without increment", max: 100, current: 50, previous: 100, elapsed: 0.25, want: 15 * time.Second},
+		{name: "token limit reached in current window", max: 100, current: 100, previous: 100, elapsed: 0.5, want: 30 * time.Second},
+		{name: "no previous window", max: 10, current: 9, increment: 1, elapsed: 0.5, want: 30 * time.Second},
+		{name: "rounded up to a second", max: 10, current: 10, increment: 1, elapsed: 0.995, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryAfter(tt.max, tt.current, tt.previous, tt.increment, tt.elapsed)
+			if got != tt.want {
+				t.Fatalf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// In file: internal/secrets/aws.go
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+// AWSCredentials are the static or temporary credentials used to sign requests.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials.
+	SessionToken string
+}
+
+// AWSProvider reads secrets from AWS Secrets Manager. A reference is
+// aws-sm://<secret-id>#<field>, where the secret ID is a name or ARN and the field selects a
+// key of a secret stored as JSON.
+type AWSProvider struct {
+	region      string
+	credentials AWSCredentials
+	httpClient  *http.Client
+}
+
+// Statically verify that AWSProvider implements the Provider interface.
+var _ Provider = (*AWSProvider)(nil)
+
+func NewAWSProvider(region string, credentials AWSCredentials) *AWSProvider {
+	return &AWSProvider{
+		region:      region,
+		credentials: credentials,
+		httpClient:  &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport()},
+	}
+}
+
+func (p *AWSProvider) Fetch(ctx context.Context, ref Reference) (string, error) {
+	if p.credentials.AccessKeyID == "" || p.credentials.SecretAccessKey == "" {
+		return "", errors.New("AWS credentials are not configured")
+	}
+	payload, err := json.Marshal(map[string]string{"SecretId": ref.Path})
+	if err != nil {
+		return "", err
+	}
+	host := fmt.Sprintf("secretsmanager.%s.amazonaws.com", p.region)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://"+host+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	p.sign(req, host, payload, time.Now().UTC())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secrets manager API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+	secret := result.SecretString
+	if secret == "" && result.SecretBinary != "" {
+		decoded, err := base64.StdEncoding.DecodeString(result.SecretBinary)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode binary secret: %w", err)
+		}
+		secret = string(decoded)
+	}
+	return selectField(secret, ref.Field)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to the request.
+func (p *AWSProvider) sign(req *http.Request, host string, payload []byte, now time.Time) {
+	const service = "secretsmanager"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.credentials.SessionToken)
+	}
+
+	headers := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         host,
+		"x-amz-date":   amzDate,
+		"x-amz-target": req.Header.Get("X-Amz-Target"),
+	}
+	signedHeaders := []string{"content-type", "host", "x-amz-date"}
+	if p.credentials.SessionToken != "" {
+		headers["x-amz-security-token"] = p.credentials.SessionToken
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	signedHeaders = append(signedHeaders, "x-amz-target")
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method, "/", "", canonicalHeaders.String(), strings.Join(signedHeaders, ";"), hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, p.region, service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, p.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.credentials.AccessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,107 @@
+// In file: internal/secrets/gcp.go
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1"
+
+// GCPProvider reads secrets from Google Cloud Secret Manager, authenticating with Application
+// Default Credentials. A reference is gcp-sm://<project>/<secret>[/<version>]#<field>; the
+// version defaults to "latest".
+type GCPProvider struct {
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	tokenSource oauth2.TokenSource
+}
+
+// Statically verify that GCPProvider implements the Provider interface.
+var _ Provider = (*GCPProvider)(nil)
+
+func NewGCPProvider() *GCPProvider {
+	return &GCPProvider{httpClient: &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport()}}
+}
+
+func (p *GCPProvider) Fetch(ctx context.Context, ref Reference) (string, error) {
+	parts := strings.Split(ref.Path, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", errors.New("gcp-sm references must be gcp-sm://<project>/<secret>[/<version>]")
+	}
+	version := "latest"
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+	token, err := p.token()
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/projects/%s/secrets/%s/versions/%s:access", gcpSecretManagerURL, parts[0], parts[1], version)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secret manager API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode secret manager response: %w", err)
+	}
+	secret, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret payload: %w", err)
+	}
+	return selectField(string(secret), ref.Field)
+}
+
+// token returns an access token from Application Default Credentials, which are looked up
+// on first use so that deployments without GCP references never need them.
+func (p *GCPProvider) token() (*oauth2.Token, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tokenSource == nil {
+		tokenSource, err := google.DefaultTokenSource(context.Background(), "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find Google application default credentials: %w", err)
+		}
+		p.tokenSource = tokenSource
+	}
+	token, err := p.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a Google access token: %w", err)
+	}
+	return token, nil
+}
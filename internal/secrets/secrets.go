@@ -0,0 +1,207 @@
+// In file: internal/secrets/secrets.go
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret reference schemes. A reference is written in place of a secret's value, e.g.
+// OPENAI_API_KEY=vault://secret/llm-gateway#openai_api_key.
+const (
+	SchemeVault = "vault"
+	SchemeAWS   = "aws-sm"
+	SchemeGCP   = "gcp-sm"
+)
+
+// Reference identifies a secret held by a secrets manager.
+type Reference struct {
+	// Scheme selects the secrets manager.
+	Scheme string
+	// Path locates the secret within the manager; its format depends on the scheme.
+	Path string
+	// Field selects one key of a secret stored as a JSON object. When empty, the whole
+	// secret is the value (or, for Vault, its only key).
+	Field string
+}
+
+func (r Reference) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Field != "" {
+		s += "#" + r.Field
+	}
+	return s
+}
+
+// ParseReference parses "scheme://path#field". It reports false for plain values, which are
+// used as the secret itself.
+func ParseReference(value string) (Reference, bool) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	if !ok || (scheme != SchemeVault && scheme != SchemeAWS && scheme != SchemeGCP) {
+		return Reference{}, false
+	}
+	path, field, _ := strings.Cut(rest, "#")
+	return Reference{Scheme: scheme, Path: path, Field: field}, true
+}
+
+// Provider fetches secrets from one secrets manager.
+type Provider interface {
+	Fetch(ctx context.Context, ref Reference) (string, error)
+}
+
+// watch tracks the current value of a referenced secret and who to tell when it changes.
+type watch struct {
+	ref       Reference
+	value     string
+	callbacks []func(string)
+}
+
+// Manager resolves secret references and periodically re-fetches them, so rotated secrets
+// are picked up without a restart.
+type Manager struct {
+	providers map[string]Provider
+
+	mu      sync.Mutex
+	watches map[string]*watch
+}
+
+func NewManager() *Manager {
+	return &Manager{providers: make(map[string]Provider), watches: make(map[string]*watch)}
+}
+
+// Register makes a provider available for references with the given scheme.
+func (m *Manager) Register(scheme string, provider Provider) {
+	m.providers[scheme] = provider
+}
+
+// Resolve returns the secret a value refers to, or the value itself if it is not a reference.
+func (m *Manager) Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	m.mu.Lock()
+	w, ok := m.watches[ref.String()]
+	m.mu.Unlock()
+	if ok {
+		return w.value, nil
+	}
+
+	secret, err := m.fetch(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	m.watches[ref.String()] = &watch{ref: ref, value: secret}
+	m.mu.Unlock()
+	return secret, nil
+}
+
+// Watch calls onChange with the new secret whenever a refresh finds that the secret the
+// value refers to has changed. The value must have been resolved first. Plain values never
+// change, so watching them is a no-op.
+func (m *Manager) Watch(value string, onChange func(string)) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if w, ok := m.watches[ref.String()]; ok {
+		w.callbacks = append(w.callbacks, onChange)
+	}
+}
+
+// Run re-fetches every resolved secret at the given interval until the context is cancelled.
+// A secret that cannot be fetched keeps its previous value.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.refresh(ctx)
+		}
+	}
+}
+
+func (m *Manager) refresh(ctx context.Context) {
+	m.mu.Lock()
+	watches := make([]*watch, 0, len(m.watches))
+	for _, w := range m.watches {
+		watches = append(watches, w)
+	}
+	m.mu.Unlock()
+
+	for _, w := range watches {
+		secret, err := m.fetch(ctx, w.ref)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to refresh secret, keeping the previous value", "secret", w.ref.String(), "error", err)
+			continue
+		}
+		m.mu.Lock()
+		changed := secret != w.value
+		w.value = secret
+		callbacks := append([]func(string){}, w.callbacks...)
+		m.mu.Unlock()
+		if !changed {
+			continue
+		}
+		slog.InfoContext(ctx, "Secret rotated", "secret", w.ref.String())
+		for _, callback := range callbacks {
+			callback(secret)
+		}
+	}
+}
+
+func (m *Manager) fetch(ctx context.Context, ref Reference) (string, error) {
+	provider, ok := m.providers[ref.Scheme]
+	if !ok {
+		return "", fmt.Errorf("secret '%s': the %s secrets manager is not configured", ref, ref.Scheme)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	secret, err := provider.Fetch(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch secret '%s': %w", ref, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("secret '%s' is empty", ref)
+	}
+	return secret, nil
+}
+
+// selectField returns the named field of a secret stored as a JSON object, or the whole
+// secret when no field is named.
+func selectField(secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", errors.New("secret is not a JSON object, so it has no fields")
+	}
+	return fieldValue(fields, field)
+}
+
+// fieldValue returns a string field of a secret's key/value pairs.
+func fieldValue(fields map[string]interface{}, field string) (string, error) {
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field '%s'", field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("secret field '%s' is not a string", field)
+	}
+	return s, nil
+}
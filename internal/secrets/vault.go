@@ -0,0 +1,83 @@
+// In file: internal/secrets/vault.go
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+// VaultProvider reads secrets from HashiCorp Vault's KV version 2 secrets engine. A
+// reference is vault://<mount>/<path>#<field>, e.g. vault://secret/llm-gateway#openai_api_key.
+type VaultProvider struct {
+	addr       string
+	token      string
+	namespace  string
+	httpClient *http.Client
+}
+
+// Statically verify that VaultProvider implements the Provider interface.
+var _ Provider = (*VaultProvider)(nil)
+
+func NewVaultProvider(addr, token, namespace string) *VaultProvider {
+	return &VaultProvider{
+		addr:       strings.TrimSuffix(addr, "/"),
+		token:      token,
+		namespace:  namespace,
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport()},
+	}
+}
+
+func (p *VaultProvider) Fetch(ctx context.Context, ref Reference) (string, error) {
+	mount, path, ok := strings.Cut(ref.Path, "/")
+	if !ok || path == "" {
+		return "", errors.New("vault references must be vault://<mount>/<path>")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/v1/%s/data/%s", p.addr, mount, path), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault API error: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	field := ref.Field
+	if field == "" {
+		if len(result.Data.Data) != 1 {
+			return "", errors.New("vault secret has several fields; select one with #<field>")
+		}
+		for name := range result.Data.Data {
+			field = name
+		}
+	}
+	return fieldValue(result.Data.Data, field)
+}
@@ -2,6 +2,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -41,6 +42,7 @@ func (ct *CalculatorTool) Definition() Tool {
 				"operator": {
 					Type:        "string",
 					Description: "The operator to use. Must be one of '+', '-', '*', '/'.",
+					Enum:        []string{"+", "-", "*", "/"},
 				},
 				"operand2": {
 					Type:        "number",
@@ -54,7 +56,7 @@ func (ct *CalculatorTool) Definition() Tool {
 
 // Execute runs the tool's actual logic. It takes the structured arguments
 // provided by the LLM and performs the requested calculation.
-func (ct *CalculatorTool) Execute(arguments string) (string, error) {
+func (ct *CalculatorTool) Execute(_ context.Context, arguments string) (string, error) {
 	// Unmarshal the JSON arguments string from the LLM into our new, structured Go type.
 	var args struct {
 		Operand1 float64 `json:"operand1"`
@@ -0,0 +1,52 @@
+// In file: internal/tools/code_interpreter_sandbox_linux.go
+
+//go:build linux
+
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// sandboxNamespaces gives a snippet its own network (with no interfaces up), mounts,
+// process tree, IPC objects, and hostname.
+const sandboxNamespaces = syscall.CLONE_NEWNET | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+
+// isolate makes cmd run as the given user in fresh namespaces, and hands the snippet's
+// working directory and files over to that user.
+func isolate(cmd *exec.Cmd, uid, gid int, paths ...string) error {
+	for _, path := range paths {
+		if err := os.Chown(path, uid, gid); err != nil {
+			return err
+		}
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: sandboxNamespaces,
+		Credential: &syscall.Credential{
+			Uid:    uint32(uid),
+			Gid:    uint32(gid),
+			Groups: []uint32{},
+		},
+		Pdeathsig: syscall.SIGKILL,
+	}
+	return nil
+}
+
+// checkIsolation verifies that snippets can be isolated by running a no-op under isolate.
+func checkIsolation(cfg CodeInterpreterConfig) error {
+	if os.Geteuid() != 0 {
+		return errors.New("switching to the sandbox user and creating namespaces requires root")
+	}
+	cmd := exec.Command("sh", "-c", "true")
+	if err := isolate(cmd, cfg.SandboxUID, cfg.SandboxGID); err != nil {
+		return err
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("isolated test process failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+// In file: internal/tools/code_interpreter_sandbox_other.go
+
+//go:build !linux
+
+package tools
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isolate is never reached off Linux, where checkIsolation always fails.
+func isolate(cmd *exec.Cmd, uid, gid int, paths ...string) error {
+	return errors.New("built-in snippet isolation is only supported on Linux")
+}
+
+// checkIsolation reports that the built-in isolation is unavailable on this platform.
+func checkIsolation(cfg CodeInterpreterConfig) error {
+	return errors.New("built-in snippet isolation is only supported on Linux")
+}
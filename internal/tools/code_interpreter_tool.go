@@ -0,0 +1,288 @@
+// In file: internal/tools/code_interpreter_tool.go
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Code Interpreter Tool Implementation ---
+
+// CodeInterpreterConfig controls the sandbox that snippets are executed in.
+type CodeInterpreterConfig struct {
+	// Timeout is the wall-clock limit for a single execution. CPU time is capped to the same value.
+	Timeout time.Duration `yaml:"timeout"`
+	// MemoryLimitMB caps the address space (Python) or heap (JavaScript) of the snippet.
+	MemoryLimitMB int `yaml:"memory_limit_mb"`
+	// MaxOutputBytes caps the combined stdout/stderr that is returned to the LLM.
+	MaxOutputBytes int `yaml:"max_output_bytes"`
+	// PythonPath and NodePath are the interpreter binaries.
+	PythonPath string `yaml:"python_path"`
+	NodePath   string `yaml:"node_path"`
+	// SandboxCommand, if set, is a jail wrapper (e.g. nsjail, or gVisor's `runsc do`) that each
+	// snippet runs under instead of the built-in isolation. It must give the snippet its own
+	// user, network, and filesystem view, and expose the snippet's working directory.
+	SandboxCommand []string `yaml:"sandbox_command"`
+	// The built-in isolation runs each snippet as a user of its own, taken from the
+	// SandboxUsers user IDs starting at SandboxUID, so concurrent snippets cannot read or
+	// overwrite each other's files; at most SandboxUsers snippets run at once. The users
+	// need not exist, but must not be used by anything else. SandboxGID is their group,
+	// nogroup (65534) by default.
+	SandboxUID   int `yaml:"sandbox_uid"`
+	SandboxUsers int `yaml:"sandbox_users"`
+	SandboxGID   int `yaml:"sandbox_gid"`
+}
+
+// DefaultCodeInterpreterConfig returns conservative limits suitable for short analysis snippets.
+func DefaultCodeInterpreterConfig() CodeInterpreterConfig {
+	return CodeInterpreterConfig{
+		Timeout:        10 * time.Second,
+		MemoryLimitMB:  256,
+		MaxOutputBytes: 8 * 1024,
+		PythonPath:     "python3",
+		NodePath:       "node",
+		SandboxUID:     100000,
+		SandboxUsers:   64,
+		SandboxGID:     65534,
+	}
+}
+
+// maxSnippetProcesses caps the processes and threads of a snippet's user, so a fork bomb is
+// contained. Node needs about a dozen threads of its own.
+const maxSnippetProcesses = 64
+
+// CodeInterpreterTool executes Python or JavaScript snippets written by the LLM.
+//
+// Each snippet runs in a fresh subprocess inside a throwaway working directory, with
+// only PATH, HOME, and TMPDIR in its environment (so the gateway's secrets are not
+// inherited), no stdin, and shell-enforced resource limits (CPU time, memory, file
+// size). The process is killed when the timeout expires and output beyond
+// MaxOutputBytes is discarded.
+//
+// Unless a SandboxCommand is configured, the snippet is isolated on Linux by running it
+// as a user of its own in its own network, mount, PID, IPC, and UTS namespaces: it has no
+// network access, its working directory is private to it, and it can only read other
+// files that are world-readable, so secrets such as a local .env file must not be. This needs the gateway to run
+// as root; NewCodeInterpreterTool refuses to create the tool when the isolation is
+// unavailable rather than run snippets as the gateway's own user.
+//
+// The sandbox requires `sh` and the interpreters to be present, so this tool is opt-in
+// and unavailable in the default scratch-based Docker image.
+type CodeInterpreterTool struct {
+	config CodeInterpreterConfig
+	// users holds the sandbox user IDs not taken by a running snippet.
+	users chan int
+}
+
+// Statically verify that CodeInterpreterTool implements the ToolExecutor interface.
+var _ ToolExecutor = (*CodeInterpreterTool)(nil)
+
+// NewCodeInterpreterTool creates the tool, filling unset limits from DefaultCodeInterpreterConfig.
+func NewCodeInterpreterTool(cfg CodeInterpreterConfig) (*CodeInterpreterTool, error) {
+	defaults := DefaultCodeInterpreterConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.MemoryLimitMB <= 0 {
+		cfg.MemoryLimitMB = defaults.MemoryLimitMB
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = defaults.MaxOutputBytes
+	}
+	if cfg.PythonPath == "" {
+		cfg.PythonPath = defaults.PythonPath
+	}
+	if cfg.NodePath == "" {
+		cfg.NodePath = defaults.NodePath
+	}
+	if cfg.SandboxUID <= 0 {
+		cfg.SandboxUID = defaults.SandboxUID
+	}
+	if cfg.SandboxUsers <= 0 {
+		cfg.SandboxUsers = defaults.SandboxUsers
+	}
+	if cfg.SandboxGID <= 0 {
+		cfg.SandboxGID = defaults.SandboxGID
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		return nil, errors.New("code interpreter requires 'sh' to enforce resource limits")
+	}
+	if len(cfg.SandboxCommand) > 0 {
+		if _, err := exec.LookPath(cfg.SandboxCommand[0]); err != nil {
+			return nil, fmt.Errorf("code interpreter sandbox command not found: %w", err)
+		}
+	} else if err := checkIsolation(cfg); err != nil {
+		return nil, fmt.Errorf("code interpreter cannot isolate snippets (configure a sandbox command instead): %w", err)
+	}
+	ci := &CodeInterpreterTool{config: cfg, users: make(chan int, cfg.SandboxUsers)}
+	for i := 0; i < cfg.SandboxUsers; i++ {
+		ci.users <- cfg.SandboxUID + i
+	}
+	return ci, nil
+}
+
+// Definition describes the tool to the LLM.
+func (ci *CodeInterpreterTool) Definition() Tool {
+	return NewFunctionTool(
+		"execute_code",
+		fmt.Sprintf("Executes a short Python or JavaScript program and returns its printed output. "+
+			"Use it for calculations, data analysis, or text processing beyond basic arithmetic. "+
+			"The program runs in an empty temporary directory with limited memory and must finish within %s. "+
+			"Always print the final result.", ci.config.Timeout),
+		JSONSchema{
+			Type: "object",
+			Properties: map[string]*JSONSchema{
+				"language": {
+					Type:        "string",
+					Description: "The language of the program. Must be 'python' or 'javascript'.",
+					Enum:        []string{"python", "javascript"},
+				},
+				"code": {
+					Type:        "string",
+					Description: "The complete program source code.",
+				},
+			},
+			Required: []string{"language", "code"},
+		},
+	)
+}
+
+// Execute runs the snippet in the sandbox and reports its output and exit status.
+func (ci *CodeInterpreterTool) Execute(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		Language string `json:"language"`
+		Code     string `json:"code"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments for code interpreter: %w", err)
+	}
+	if strings.TrimSpace(args.Code) == "" {
+		return "Error: No code was provided.", nil
+	}
+
+	// 1. Write the snippet into a throwaway working directory.
+	workDir, err := os.MkdirTemp("", "code-interpreter-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	var interpreter, scriptName string
+	var interpreterArgs []string
+	// ulimit values: -t is CPU seconds, -v is KiB of address space, -d is KiB of data segment,
+	// -f is 512-byte blocks written, and -u (bash) or -p (dash, ash) is processes of the user.
+	// The umask keeps files the snippet writes outside its working directory private too.
+	limits := []string{
+		fmt.Sprintf("ulimit -t %d", int(ci.config.Timeout.Seconds())+1),
+		"ulimit -f 2048",
+		fmt.Sprintf("{ ulimit -u %[1]d || ulimit -p %[1]d; } 2>/dev/null", maxSnippetProcesses),
+		"umask 077",
+	}
+	switch strings.ToLower(args.Language) {
+	case "python", "py":
+		interpreter, scriptName = ci.config.PythonPath, "main.py"
+		interpreterArgs = []string{"-I"} // Isolated mode: ignore PYTHON* env vars and user site-packages.
+		limits = append(limits, fmt.Sprintf("ulimit -v %d", ci.config.MemoryLimitMB*1024))
+	case "javascript", "js", "node":
+		// V8 reserves far more virtual memory than it uses, so cap the data segment, which only
+		// counts memory actually made writable, instead of the address space. The heap is capped
+		// below it so ordinary allocation failures surface as JavaScript out-of-memory errors.
+		interpreter, scriptName = ci.config.NodePath, "main.js"
+		interpreterArgs = []string{fmt.Sprintf("--max-old-space-size=%d", ci.config.MemoryLimitMB*3/4)}
+		limits = append(limits, fmt.Sprintf("ulimit -d %d", ci.config.MemoryLimitMB*1024))
+	default:
+		return fmt.Sprintf("Error: Unsupported language '%s'. Please use 'python' or 'javascript'.", args.Language), nil
+	}
+	scriptPath := filepath.Join(workDir, scriptName)
+	if err := os.WriteFile(scriptPath, []byte(args.Code), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write snippet: %w", err)
+	}
+
+	// 2. Run the interpreter under the resource limits. If any limit cannot be applied,
+	// the `&&` chain stops and the snippet never runs.
+	ctx, cancel := context.WithTimeout(ctx, ci.config.Timeout)
+	defer cancel()
+
+	script := strings.Join(limits, " && ") + ` && exec "$0" "$@"`
+	cmdArgs := append([]string{"-c", script, interpreter}, interpreterArgs...)
+	cmdArgs = append(cmdArgs, scriptPath)
+	var cmd *exec.Cmd
+	if sandbox := ci.config.SandboxCommand; len(sandbox) > 0 {
+		cmd = exec.CommandContext(ctx, sandbox[0], append(append(sandbox[1:len(sandbox):len(sandbox)], "sh"), cmdArgs...)...)
+	} else {
+		var uid int
+		select {
+		case uid = <-ci.users:
+		case <-ctx.Done():
+			return fmt.Sprintf("Error: Execution timed out after %s while waiting for other programs to finish.", ci.config.Timeout), nil
+		}
+		defer func() { ci.users <- uid }()
+		cmd = exec.CommandContext(ctx, "sh", cmdArgs...)
+		if err := isolate(cmd, uid, ci.config.SandboxGID, workDir, scriptPath); err != nil {
+			return "", fmt.Errorf("failed to prepare sandbox: %w", err)
+		}
+	}
+	cmd.Dir = workDir
+	cmd.Env = []string{"PATH=/usr/local/bin:/usr/bin:/bin", "HOME=" + workDir, "TMPDIR=" + workDir}
+	cmd.WaitDelay = time.Second
+
+	output := &cappedBuffer{limit: ci.config.MaxOutputBytes}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	runErr := cmd.Run()
+
+	// 3. Report the outcome in a form the LLM can reason about.
+	result := output.String()
+	if output.truncated {
+		result += "\n... (output truncated)"
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Sprintf("Error: Execution timed out after %s.\nOutput:\n%s", ci.config.Timeout, result), nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		return fmt.Sprintf("Error: Program exited with code %d.\nOutput:\n%s", exitErr.ExitCode(), result), nil
+	}
+	if runErr != nil {
+		return "", fmt.Errorf("failed to run %s: %w", interpreter, runErr)
+	}
+	if result == "" {
+		return "The program ran successfully but printed nothing.", nil
+	}
+	return result, nil
+}
+
+// cappedBuffer is an io.Writer that keeps at most `limit` bytes and silently drops the rest,
+// so a runaway snippet cannot exhaust the gateway's memory.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if remaining <= 0 {
+		b.truncated = b.truncated || len(p) > 0
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		b.buf.Write(p[:remaining])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
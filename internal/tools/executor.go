@@ -1,6 +1,8 @@
 // In file: internal/tools/executor.go
 package tools
 
+import "context"
+
 // ToolExecutor defines the standard interface for any tool that can be
 // executed by the gateway's agentic core.
 //
@@ -15,5 +17,6 @@ type ToolExecutor interface {
 	// Execute runs the actual logic of the tool. It receives the arguments
 	// as a JSON string, which the LLM generates based on the tool's schema.
 	// It returns a string result, which will be sent back to the LLM.
-	Execute(arguments string) (string, error)
+	// The context carries the request's deadline and trace to any outbound calls.
+	Execute(ctx context.Context, arguments string) (string, error)
 }
\ No newline at end of file
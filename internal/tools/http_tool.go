@@ -0,0 +1,199 @@
+// In file: internal/tools/http_tool.go
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+)
+
+// --- Generic HTTP Tool Implementation ---
+
+const (
+	// defaultHTTPToolTimeout is used when a tool definition does not set timeout_seconds.
+	defaultHTTPToolTimeout = 15 * time.Second
+	// maxHTTPToolResponseChars caps how much of the upstream response is handed to the LLM.
+	maxHTTPToolResponseChars = 4000
+)
+
+// urlPlaceholderRegex matches `{param}` placeholders in a tool's URL template.
+var urlPlaceholderRegex = regexp.MustCompile(`\{([a-zA-Z0-9_]+)\}`)
+
+// HTTPToolConfig is the YAML definition of a tool that calls an HTTP endpoint.
+// It lets operators expose simple integrations to the LLM without writing Go code.
+//
+// Header values and auth credentials are expanded with environment variables
+// (e.g., "${JIRA_TOKEN}"), so secrets never have to be written into config.yaml.
+type HTTPToolConfig struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Parameters  JSONSchema `yaml:"parameters"`
+	// URL may contain `{param}` placeholders that are filled from the LLM's arguments.
+	URL            string            `yaml:"url"`
+	Method         string            `yaml:"method"`
+	Headers        map[string]string `yaml:"headers"`
+	Auth           HTTPToolAuth      `yaml:"auth"`
+	TimeoutSeconds int               `yaml:"timeout_seconds"`
+}
+
+// HTTPToolAuth describes how an HTTP tool authenticates to its endpoint.
+type HTTPToolAuth struct {
+	// Type is one of "none", "bearer", "basic", or "api_key".
+	Type     string `yaml:"type"`
+	Token    string `yaml:"token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Header is the header name used by the "api_key" auth type (default "X-Api-Key").
+	Header string `yaml:"header"`
+}
+
+// HTTPTool is a ToolExecutor whose behaviour is entirely defined by an HTTPToolConfig.
+type HTTPTool struct {
+	config     HTTPToolConfig
+	httpClient *http.Client
+}
+
+// Statically verify that HTTPTool implements the ToolExecutor interface.
+var _ ToolExecutor = (*HTTPTool)(nil)
+
+// NewHTTPTool validates a tool definition and creates a tool from it.
+func NewHTTPTool(cfg HTTPToolConfig) (*HTTPTool, error) {
+	if cfg.Name == "" || cfg.URL == "" {
+		return nil, errors.New("http tool requires a name and a url")
+	}
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("http tool '%s' has an invalid url: %w", cfg.Name, err)
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	switch cfg.Auth.Type {
+	case "", "none", "bearer", "basic", "api_key":
+	default:
+		return nil, fmt.Errorf("http tool '%s' has unsupported auth type '%s'", cfg.Name, cfg.Auth.Type)
+	}
+	if cfg.Parameters.Type == "" {
+		cfg.Parameters.Type = "object"
+	}
+
+	timeout := defaultHTTPToolTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return &HTTPTool{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: timeout, Transport: telemetry.Transport()},
+	}, nil
+}
+
+// Definition describes the tool to the LLM exactly as configured.
+func (ht *HTTPTool) Definition() Tool {
+	return NewFunctionTool(ht.config.Name, ht.config.Description, ht.config.Parameters)
+}
+
+// Execute maps the LLM's arguments onto the configured request and returns the response body.
+// Arguments referenced by URL placeholders are substituted into the path; the rest are sent
+// as query parameters for GET/DELETE requests and as a JSON body otherwise.
+func (ht *HTTPTool) Execute(ctx context.Context, arguments string) (string, error) {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments for %s: %w", ht.config.Name, err)
+		}
+	}
+
+	// 1. Fill URL placeholders, consuming the arguments they reference.
+	targetURL := urlPlaceholderRegex.ReplaceAllStringFunc(ht.config.URL, func(match string) string {
+		key := match[1 : len(match)-1]
+		value, ok := args[key]
+		if !ok {
+			return match
+		}
+		delete(args, key)
+		return url.PathEscape(fmt.Sprint(value))
+	})
+
+	// 2. Place the remaining arguments in the query string or the body.
+	var body io.Reader
+	if ht.config.Method == http.MethodGet || ht.config.Method == http.MethodDelete {
+		parsed, err := url.Parse(targetURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to build url for %s: %w", ht.config.Name, err)
+		}
+		query := parsed.Query()
+		for k, v := range args {
+			query.Set(k, fmt.Sprint(v))
+		}
+		parsed.RawQuery = query.Encode()
+		targetURL = parsed.String()
+	} else {
+		payload, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode body for %s: %w", ht.config.Name, err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, ht.config.Method, targetURL, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request for %s: %w", ht.config.Name, err)
+	}
+	req.Header.Set("User-Agent", "LLM-Gateway-Agent/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range ht.config.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	ht.applyAuth(req)
+
+	// 3. Call the endpoint and hand a bounded response back to the LLM.
+	resp, err := ht.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call %s: %w", ht.config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPToolResponseChars+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response from %s: %w", ht.config.Name, err)
+	}
+	result := string(respBody)
+	if len(result) > maxHTTPToolResponseChars {
+		result = result[:maxHTTPToolResponseChars] + "... (truncated)"
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Return the failure to the LLM so it can explain it or retry with different arguments.
+		return fmt.Sprintf("Error: %s returned status %d: %s", ht.config.Name, resp.StatusCode, result), nil
+	}
+	return result, nil
+}
+
+// applyAuth adds the configured credentials to the request.
+func (ht *HTTPTool) applyAuth(req *http.Request) {
+	auth := ht.config.Auth
+	switch auth.Type {
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+os.ExpandEnv(auth.Token))
+	case "basic":
+		req.SetBasicAuth(os.ExpandEnv(auth.Username), os.ExpandEnv(auth.Password))
+	case "api_key":
+		header := auth.Header
+		if header == "" {
+			header = "X-Api-Key"
+		}
+		req.Header.Set(header, os.ExpandEnv(auth.Token))
+	}
+}
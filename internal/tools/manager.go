@@ -1,27 +1,86 @@
 // In file: internal/tools/manager.go
 package tools
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
 
-// ToolManager holds a registry of all available tools.
+	"github.com/dileep-u-k/llm-gateway/internal/jsonschema"
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ToolManager holds a registry of all available tools. A nil *ToolManager, as in the minimal
+// profile, behaves as an empty registry.
 type ToolManager struct {
 	tools map[string]ToolExecutor
+	// schemas holds the compiled parameter schema of each tool, which arguments are checked
+	// against before the tool runs.
+	schemas map[string]*jsonschema.Schema
 }
 
 func NewToolManager() *ToolManager {
 	return &ToolManager{
-		tools: make(map[string]ToolExecutor),
+		tools:   make(map[string]ToolExecutor),
+		schemas: make(map[string]*jsonschema.Schema),
 	}
 }
 
+// ArgumentError is returned by Execute when the arguments the model generated do not match
+// the tool's parameter schema. The tool is not run.
+type ArgumentError struct {
+	Tool     string
+	Problems []string
+}
+
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("invalid arguments for tool %s: %s", e.Tool, strings.Join(e.Problems, "; "))
+}
+
+// Feedback is the tool result that reports the problems back to the model, as JSON it can
+// act on to correct its call.
+func (e *ArgumentError) Feedback() string {
+	feedback, _ := json.Marshal(struct {
+		Error    string   `json:"error"`
+		Tool     string   `json:"tool"`
+		Problems []string `json:"problems"`
+		Hint     string   `json:"hint"`
+	}{
+		Error:    "invalid_arguments",
+		Tool:     e.Tool,
+		Problems: e.Problems,
+		Hint:     "The tool was not run. Call it again with arguments that match its parameters.",
+	})
+	return string(feedback)
+}
+
 // Register adds a new tool to the manager's registry.
 func (tm *ToolManager) Register(tool ToolExecutor) {
-	name := tool.Definition().Function.Name
+	definition := tool.Definition()
+	name := definition.Function.Name
 	tm.tools[name] = tool
+	delete(tm.schemas, name)
+	raw, err := json.Marshal(definition.Function.Parameters)
+	if err == nil {
+		var schema *jsonschema.Schema
+		if schema, err = jsonschema.Compile(raw); err == nil {
+			tm.schemas[name] = schema
+		}
+	}
+	if err != nil {
+		slog.Warn("Tool parameter schema cannot be compiled. Its arguments will not be validated", "tool", name, "error", err)
+	}
 }
 
 // GetDefinitions returns a slice of all registered tool definitions.
 func (tm *ToolManager) GetDefinitions() []Tool {
+	if tm == nil {
+		return nil
+	}
 	defs := make([]Tool, 0, len(tm.tools))
 	for _, tool := range tm.tools {
 		defs = append(defs, tool.Definition())
@@ -29,16 +88,66 @@ func (tm *ToolManager) GetDefinitions() []Tool {
 	return defs
 }
 
-// Execute runs a tool by name with the given arguments.
-func (tm *ToolManager) Execute(name, arguments string) (string, error) {
-	tool, ok := tm.tools[name]
+// GetDefinitionsFor returns the definitions of the registered tools permitted by the policy.
+func (tm *ToolManager) GetDefinitionsFor(policy ToolPolicy) []Tool {
+	if tm == nil {
+		return nil
+	}
+	defs := make([]Tool, 0, len(tm.tools))
+	for name, tool := range tm.tools {
+		if policy.Permits(name) {
+			defs = append(defs, tool.Definition())
+		}
+	}
+	return defs
+}
+
+// Execute runs a tool by name with the given arguments, in its own trace span.
+func (tm *ToolManager) Execute(ctx context.Context, name, arguments string) (string, error) {
+	ctx, span := telemetry.StartSpan(ctx, "tool.execute", attribute.String("tool.name", name))
+	if !tm.HasTool(name) {
+		err := fmt.Errorf("tool '%s' not found", name)
+		telemetry.EndSpan(span, err)
+		return "", err
+	}
+	if err := tm.validateArguments(name, arguments); err != nil {
+		telemetry.EndSpan(span, err)
+		return "", err
+	}
+	result, err := tm.tools[name].Execute(ctx, arguments)
+	telemetry.EndSpan(span, err)
+	return result, err
+}
+
+// validateArguments checks a tool's arguments against its parameter schema. Models send no
+// arguments at all for a tool without required parameters, which counts as an empty object.
+func (tm *ToolManager) validateArguments(name, arguments string) error {
+	schema, ok := tm.schemas[name]
 	if !ok {
-		return "", fmt.Errorf("tool '%s' not found", name)
+		return nil
 	}
-	return tool.Execute(arguments)
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	if problems := schema.ValidateJSON(arguments); len(problems) > 0 {
+		return &ArgumentError{Tool: name, Problems: problems}
+	}
+	return nil
+}
+
+// HasTool reports whether a tool is registered under name.
+func (tm *ToolManager) HasTool(name string) bool {
+	if tm == nil {
+		return false
+	}
+	_, ok := tm.tools[name]
+	return ok
 }
 
 // ToolCount returns the number of registered tools.
 func (tm *ToolManager) ToolCount() int {
+	if tm == nil {
+		return 0
+	}
 	return len(tm.tools)
 }
\ No newline at end of file
@@ -2,6 +2,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
 )
 
 // --- News Tool Implementation ---
@@ -35,7 +38,8 @@ func NewNewsTool(apiKey string) (*NewsTool, error) {
 	return &NewsTool{
 		apiKey: apiKey,
 		httpClient: &http.Client{
-			Timeout: 20 * time.Second, // Set a reasonable timeout.
+			Timeout:   20 * time.Second, // Set a reasonable timeout.
+			Transport: telemetry.Transport(),
 		},
 	}, nil
 }
@@ -55,8 +59,9 @@ func (nt *NewsTool) Definition() Tool {
 					Description: "The topic or keyword to search for in the news, e.g., 'artificial intelligence' or 'latest space missions'.",
 				},
 				"category": {
-					Type: "string",
+					Type:        "string",
 					Description: `The category of news. Must be one of: business, entertainment, general, health, science, sports, technology.`,
+					Enum:        []string{"business", "entertainment", "general", "health", "science", "sports", "technology"},
 				},
 				"country": {
 					Type:        "string",
@@ -71,7 +76,7 @@ func (nt *NewsTool) Definition() Tool {
 
 // Execute runs the tool's logic. It builds a request to the NewsAPI,
 // parses the response, and formats it into a clean summary for the LLM.
-func (nt *NewsTool) Execute(arguments string) (string, error) {
+func (nt *NewsTool) Execute(ctx context.Context, arguments string) (string, error) {
 	// 1. Unmarshal the LLM's arguments into a structured Go type.
 	var args struct {
 		Query    string `json:"query"`
@@ -99,7 +104,7 @@ func (nt *NewsTool) Execute(arguments string) (string, error) {
 	base.RawQuery = params.Encode()
 
 	// 3. Make the external API call using the configured HTTP client.
-	req, err := http.NewRequest("GET", base.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", base.String(), nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create news API request: %w", err)
 	}
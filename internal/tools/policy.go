@@ -0,0 +1,62 @@
+// In file: internal/tools/policy.go
+package tools
+
+import (
+	"sort"
+	"strings"
+)
+
+// ToolPolicy decides which registered tools may be exposed to, and executed for, a request.
+//
+// A nil Allowed list means "every tool"; a non-nil but empty list means "no tools".
+// Denied always wins over Allowed, so a tenant can block a tool even if a request asks for it.
+type ToolPolicy struct {
+	Allowed []string `yaml:"tools_allowed"`
+	Denied  []string `yaml:"tools_denied"`
+}
+
+// Permits reports whether the named tool may be used under this policy.
+func (p ToolPolicy) Permits(name string) bool {
+	for _, denied := range p.Denied {
+		if denied == name {
+			return false
+		}
+	}
+	if p.Allowed == nil {
+		return true
+	}
+	for _, allowed := range p.Allowed {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Restrict narrows the policy to the given allow list. It can only remove tools, never add
+// them back, so a request-level list can never widen what its tenant is permitted to use.
+func (p ToolPolicy) Restrict(allowed []string) ToolPolicy {
+	if allowed == nil {
+		return p
+	}
+	narrowed := make([]string, 0, len(allowed))
+	for _, name := range allowed {
+		if p.Permits(name) {
+			narrowed = append(narrowed, name)
+		}
+	}
+	return ToolPolicy{Allowed: narrowed, Denied: p.Denied}
+}
+
+// String renders the policy in a stable form, suitable for inclusion in cache keys.
+func (p ToolPolicy) String() string {
+	allowed := "*"
+	if p.Allowed != nil {
+		sorted := append([]string(nil), p.Allowed...)
+		sort.Strings(sorted)
+		allowed = strings.Join(sorted, ",")
+	}
+	denied := append([]string(nil), p.Denied...)
+	sort.Strings(denied)
+	return "allow=" + allowed + ";deny=" + strings.Join(denied, ",")
+}
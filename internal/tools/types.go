@@ -44,6 +44,10 @@ type JSONSchema struct {
 	Properties map[string]*JSONSchema `json:"properties,omitempty"`
 	// Required is a list of parameter names that are mandatory for a function call.
 	Required []string `json:"required,omitempty"`
+	// Enum lists the only values a string parameter may take.
+	Enum []string `json:"enum,omitempty"`
+	// Items describes the elements of an array.
+	Items *JSONSchema `json:"items,omitempty"`
 }
 
 // ToolCall represents a request *from* the LLM to execute a specific tool with given arguments.
@@ -2,12 +2,15 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/dileep-u-k/llm-gateway/internal/telemetry"
 )
 
 // --- Weather Tool Implementation ---
@@ -28,7 +31,8 @@ var _ ToolExecutor = (*WeatherTool)(nil)
 func NewWeatherTool() *WeatherTool {
 	return &WeatherTool{
 		httpClient: &http.Client{
-			Timeout: 15 * time.Second, // Set a reasonable timeout.
+			Timeout:   15 * time.Second, // Set a reasonable timeout.
+			Transport: telemetry.Transport(),
 		},
 	}
 }
@@ -56,7 +60,7 @@ func (wt *WeatherTool) Definition() Tool {
 
 // Execute runs the tool's actual logic. It takes the arguments provided by the LLM,
 // calls an external API, and returns the result as a simple string.
-func (wt *WeatherTool) Execute(arguments string) (string, error) {
+func (wt *WeatherTool) Execute(ctx context.Context, arguments string) (string, error) {
 	// Unmarshal the JSON arguments string from the LLM into a Go struct for type-safe access.
 	var args struct {
 		Location string `json:"location"`
@@ -72,7 +76,7 @@ func (wt *WeatherTool) Execute(arguments string) (string, error) {
 	// We use the configured httpClient with a timeout for this call.
 	url := fmt.Sprintf("https://wttr.in/%s?format=3", strings.ReplaceAll(args.Location, " ", "+"))
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create weather API request: %w", err)
 	}
@@ -14,6 +14,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"slices"
 )
 
 // ComponentVersions holds the version strings for different logical parts of the application.
@@ -37,17 +38,94 @@ var ComponentVersions = struct {
 	PromptLogic: "v1.0",
 }
 
+// CacheKeyParams captures the request parameters that change what a model would answer.
+// Two requests with the same prompt but different parameters must never share a cache entry.
+type CacheKeyParams struct {
+	// Model is the explicitly requested model, if any. Routed requests leave this empty.
+	Model string
+	// Forced reports whether the model was forced by the caller rather than chosen by the router.
+	Forced bool
+	// Preference is the requested routing strategy, which influences the selected model tier.
+	Preference  string
+	Temperature *float32
+	TopP        *float32
+	MaxTokens   int
+	// ToolPolicy is the effective tool policy, so tool results are never served to a
+	// caller who is not permitted to run that tool.
+	ToolPolicy string
+	// ClientToolsHash identifies the caller's own tools offered to the model, if any.
+	ClientToolsHash string
+	// HistoryHash identifies the conversation history the prompt is answered in.
+	HistoryHash string
+	// SystemPromptHash identifies the system prompt the conversation runs under.
+	SystemPromptHash string
+	// ResponseSchemaHash identifies the JSON Schema the answer must conform to, if any.
+	ResponseSchemaHash string
+	// ResponseFormat is the requested response format type, if any.
+	ResponseFormat string
+	// Logprobs and TopLogprobs decide whether the cached answer carries log probabilities.
+	Logprobs    bool
+	TopLogprobs int
+	// Seed is the requested sampling seed, if any.
+	Seed *int64
+	// Stop lists the requested stop sequences.
+	Stop             []string
+	FrequencyPenalty *float32
+	PresencePenalty  *float32
+	TopK             *int
+	// ReasoningEffort and ThinkingBudget are the requested reasoning controls, if any.
+	ReasoningEffort string
+	ThinkingBudget  int
+	// Ensemble describes the requested ensemble generation, if any.
+	Ensemble string
+	// ExcludeModels are the models the router must not select, so a regenerated answer is
+	// never the cached answer of an excluded model.
+	ExcludeModels []string
+	// RAG describes the requested retrieval options, if any.
+	RAG string
+	// RAGScope is the user and tenant retrieval is restricted to, since each can read
+	// different uploaded documents.
+	RAGScope string
+	// MaxCostUSD is the requested cost cap, which can rule out the model an answer was cached from.
+	MaxCostUSD float64
+}
+
+// String renders the parameters in a stable, compact form suitable for hashing.
+// Unset pointer values are rendered as "-" so that nil and 0 remain distinct.
+func (p CacheKeyParams) String() string {
+	formatFloat := func(f *float32) string {
+		if f == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%g", *f)
+	}
+	seed, topK := "-", "-"
+	if p.Seed != nil {
+		seed = fmt.Sprintf("%d", *p.Seed)
+	}
+	if p.TopK != nil {
+		topK = fmt.Sprintf("%d", *p.TopK)
+	}
+	return fmt.Sprintf("m=%s|f=%t|p=%s|t=%s|tp=%s|mt=%d|tools=%s|ct=%s|h=%s|sp=%s|rs=%s|rf=%s|lp=%t/%d|seed=%s|stop=%q|fp=%s|pp=%s|tk=%s|re=%s|tb=%d|ens=%s|ex=%q|mc=%g|rag=%s|rscope=%s",
+		p.Model, p.Forced, p.Preference, formatFloat(p.Temperature), formatFloat(p.TopP), p.MaxTokens, p.ToolPolicy, p.ClientToolsHash, p.HistoryHash, p.SystemPromptHash, p.ResponseSchemaHash, p.ResponseFormat, p.Logprobs, p.TopLogprobs, seed, p.Stop,
+		formatFloat(p.FrequencyPenalty), formatFloat(p.PresencePenalty), topK, p.ReasoningEffort, p.ThinkingBudget, p.Ensemble, slices.Sorted(slices.Values(p.ExcludeModels)), p.MaxCostUSD, p.RAG, p.RAGScope)
+}
+
 // GenerateVersionedCacheKey creates a consistent, version-aware key for caching LLM responses.
 //
-// It combines a prefix, a hash of the user's prompt, and the current versions of all
-// logical components. This ensures that if the prompt or any underlying logic changes,
-// a new cache key is generated, effectively invalidating the old cache entry.
+// It combines a prefix, a hash of the user's prompt and generation parameters, and the
+// current versions of all logical components. This ensures that if the prompt, the
+// requested model or sampling settings, or any underlying logic changes, a new cache key
+// is generated, effectively invalidating the old cache entry.
 //
 // Example output: "llmcache:a1b2c3d4...:tv1.0_rv1.0_pv1.0"
-func GenerateVersionedCacheKey(prefix, prompt string) string {
-	// 1. Hash the prompt to create a fixed-length, unique identifier for the user's input.
+func GenerateVersionedCacheKey(prefix, prompt string, params CacheKeyParams) string {
+	// 1. Hash the prompt together with the generation parameters to create a fixed-length,
+	//    unique identifier for the user's input.
 	hasher := sha256.New()
 	hasher.Write([]byte(prompt))
+	hasher.Write([]byte{0}) // Separator so the prompt can never bleed into the parameters.
+	hasher.Write([]byte(params.String()))
 	promptHash := hex.EncodeToString(hasher.Sum(nil))
 
 	// 2. Create a compact string representing the current state of all components.
@@ -59,4 +137,4 @@ func GenerateVersionedCacheKey(prefix, prompt string) string {
 
 	// 3. Combine all parts into the final cache key.
 	return fmt.Sprintf("%s:%s:%s", prefix, promptHash, versionString)
-}
\ No newline at end of file
+}